@@ -0,0 +1,183 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+// Analysis metrics are an opt-in mode, enabled by setting SOONG_ANALYSIS_METRICS=true, that
+// records how long each module spends in each mutator and in GenerateAndroidBuildActions, and how
+// many variants, rules and build actions it creates.  The metrics are written as a
+// SoongAnalysisMetrics proto to soong_analysis_metrics.pb in the output directory, with the modules
+// and mutators that took the longest first, to find the causes of long analysis times.  soong_ui
+// adds them to the soong metrics it uploads for the build.
+//
+// Variants of a module are aggregated into a single entry.  A mutator that runs before a module is
+// split into variants is recorded once.
+
+func init() {
+	RegisterSingletonType("analysis_metrics", analysisMetricsSingletonFactory)
+}
+
+const analysisMetricsFileName = "soong_analysis_metrics.pb"
+
+var analysisMetricsEnabledKey = NewOnceKey("analysisMetricsEnabled")
+
+// AnalysisMetricsEnabled returns true if the time spent analyzing each module should be recorded.
+func (c *config) AnalysisMetricsEnabled() bool {
+	return c.Once(analysisMetricsEnabledKey, func() interface{} {
+		return c.IsEnvTrue("SOONG_ANALYSIS_METRICS")
+	}).(bool)
+}
+
+// moduleAnalysisMetrics are the metrics recorded for a single variant of a module.
+type moduleAnalysisMetrics struct {
+	mutatorTimes             map[string]time.Duration
+	generateBuildActionsTime time.Duration
+	rules, buildActions      int
+}
+
+func (m *ModuleBase) analysisMetricsForModule() *moduleAnalysisMetrics {
+	if m.analysisMetrics == nil {
+		m.analysisMetrics = &moduleAnalysisMetrics{
+			mutatorTimes: make(map[string]time.Duration),
+		}
+	}
+	return m.analysisMetrics
+}
+
+// recordMutatorTime records the time spent in a mutator since start.  It is only called from the
+// mutator running on the module, so it doesn't need to be locked.
+func (m *ModuleBase) recordMutatorTime(mutator string, start time.Time) {
+	m.analysisMetricsForModule().mutatorTimes[mutator] += time.Since(start)
+}
+
+// recordGenerateBuildActions records the time spent in GenerateAndroidBuildActions since start, and
+// the rules and build actions created by the module.
+func (m *ModuleBase) recordGenerateBuildActions(ctx *moduleContext, start time.Time) {
+	metrics := m.analysisMetricsForModule()
+	metrics.generateBuildActionsTime += time.Since(start)
+	metrics.rules += ctx.ruleCount
+	metrics.buildActions += ctx.buildActionCount
+}
+
+func analysisMetricsSingletonFactory() Singleton {
+	return &analysisMetricsSingleton{}
+}
+
+type analysisMetricsSingleton struct{}
+
+func (s *analysisMetricsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().AnalysisMetricsEnabled() {
+		return
+	}
+
+	type moduleKey struct{ name, dir string }
+	type moduleEntry struct {
+		metrics      *soong_metrics_proto.ModuleAnalysisMetrics
+		mutatorTimes map[string]uint64
+	}
+	modules := make(map[moduleKey]*moduleEntry)
+	mutators := make(map[string]uint64)
+
+	ctx.VisitAllModules(func(module Module) {
+		key := moduleKey{ctx.ModuleName(module), ctx.ModuleDir(module)}
+		entry := modules[key]
+		if entry == nil {
+			entry = &moduleEntry{
+				metrics: &soong_metrics_proto.ModuleAnalysisMetrics{
+					Name:       proto.String(key.name),
+					Dir:        proto.String(key.dir),
+					ModuleType: proto.String(ctx.ModuleType(module)),
+				},
+				mutatorTimes: make(map[string]uint64),
+			}
+			modules[key] = entry
+		}
+		entry.metrics.Variants = proto.Uint32(entry.metrics.GetVariants() + 1)
+
+		metrics := module.base().analysisMetrics
+		if metrics == nil {
+			return
+		}
+		for mutator, d := range metrics.mutatorTimes {
+			entry.mutatorTimes[mutator] += uint64(d.Nanoseconds())
+			mutators[mutator] += uint64(d.Nanoseconds())
+		}
+		entry.metrics.GenerateBuildActionsTime = proto.Uint64(entry.metrics.GetGenerateBuildActionsTime() +
+			uint64(metrics.generateBuildActionsTime.Nanoseconds()))
+		entry.metrics.Rules = proto.Uint32(entry.metrics.GetRules() + uint32(metrics.rules))
+		entry.metrics.BuildActions = proto.Uint32(entry.metrics.GetBuildActions() + uint32(metrics.buildActions))
+	})
+
+	var analysisMetrics soong_metrics_proto.SoongAnalysisMetrics
+	for _, entry := range modules {
+		var mutatorTime uint64
+		for _, t := range entry.mutatorTimes {
+			mutatorTime += t
+		}
+		entry.metrics.MutatorTime = proto.Uint64(mutatorTime)
+		entry.metrics.MutatorTimes = sortedMutatorAnalysisMetrics(entry.mutatorTimes)
+		entry.metrics.TotalTime = proto.Uint64(mutatorTime + entry.metrics.GetGenerateBuildActionsTime())
+		analysisMetrics.Modules = append(analysisMetrics.Modules, entry.metrics)
+	}
+	sort.Slice(analysisMetrics.Modules, func(i, j int) bool {
+		a, b := analysisMetrics.Modules[i], analysisMetrics.Modules[j]
+		if a.GetTotalTime() != b.GetTotalTime() {
+			return a.GetTotalTime() > b.GetTotalTime()
+		}
+		if a.GetName() != b.GetName() {
+			return a.GetName() < b.GetName()
+		}
+		return a.GetDir() < b.GetDir()
+	})
+	analysisMetrics.Mutators = sortedMutatorAnalysisMetrics(mutators)
+
+	buf, err := proto.Marshal(&analysisMetrics)
+	if err != nil {
+		ctx.Errorf("proto marshal of %s failed: %s", analysisMetricsFileName, err)
+		return
+	}
+	// The metrics describe this run of soong_build and are not an input to any build action.
+	outputPath := PathForOutput(ctx, analysisMetricsFileName)
+	if err := WriteFileToOutputDir(outputPath, buf, 0666); err != nil {
+		ctx.Errorf("Writing %s to %s failed: %s", analysisMetricsFileName, outputPath, err)
+	}
+}
+
+// sortedMutatorAnalysisMetrics returns the times spent in each mutator, the slowest first.
+func sortedMutatorAnalysisMetrics(times map[string]uint64) []*soong_metrics_proto.MutatorAnalysisMetrics {
+	var ret []*soong_metrics_proto.MutatorAnalysisMetrics
+	for mutator, t := range times {
+		ret = append(ret, &soong_metrics_proto.MutatorAnalysisMetrics{
+			Name: proto.String(mutator),
+			Time: proto.Uint64(t),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.GetTime() != b.GetTime() {
+			return a.GetTime() > b.GetTime()
+		}
+		return a.GetName() < b.GetName()
+	})
+	return ret
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+var analysisMetricsFixtureFactory = licenseMetadataFixtureFactory.Extend(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterSingletonType("analysis_metrics", analysisMetricsSingletonFactory)
+	}),
+	FixtureWithRootAndroidBp(`
+		test {
+			name: "foo",
+		}
+	`),
+)
+
+func TestAnalysisMetrics(t *testing.T) {
+	result := analysisMetricsFixtureFactory.RunTest(t,
+		FixtureMergeEnv(map[string]string{"SOONG_ANALYSIS_METRICS": "true"}),
+	)
+
+	data, err := ioutil.ReadFile(PathForOutput(PathContextForTesting(result.Config), analysisMetricsFileName).String())
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", analysisMetricsFileName, err)
+	}
+	var metrics soong_metrics_proto.SoongAnalysisMetrics
+	if err := proto.Unmarshal(data, &metrics); err != nil {
+		t.Fatalf("failed to parse %s: %s", analysisMetricsFileName, err)
+	}
+
+	var foo *soong_metrics_proto.ModuleAnalysisMetrics
+	for _, module := range metrics.Modules {
+		if module.GetName() == "foo" {
+			foo = module
+		}
+	}
+	if foo == nil {
+		t.Fatalf("expected metrics for foo, got %s", proto.MarshalTextString(&metrics))
+	}
+
+	if foo.GetModuleType() != "test" || foo.GetDir() != "." || foo.GetVariants() != 1 {
+		t.Errorf("expected a single variant of the test module foo in ., got %s", proto.MarshalTextString(foo))
+	}
+	// foo creates its output and installs it.
+	if foo.GetBuildActions() != 2 {
+		t.Errorf("expected 2 build actions, got %d", foo.GetBuildActions())
+	}
+	if !hasMutatorAnalysisMetrics(foo.GetMutatorTimes(), "arch") {
+		t.Errorf("expected time spent in the arch mutator, got %v", foo.GetMutatorTimes())
+	}
+	if foo.GetTotalTime() != foo.GetMutatorTime()+foo.GetGenerateBuildActionsTime() {
+		t.Errorf("expected total time %d to be the sum of the mutator time %d and the "+
+			"GenerateAndroidBuildActions time %d", foo.GetTotalTime(), foo.GetMutatorTime(), foo.GetGenerateBuildActionsTime())
+	}
+
+	if !hasMutatorAnalysisMetrics(metrics.GetMutators(), "arch") {
+		t.Errorf("expected the arch mutator in the mutator metrics, got %v", metrics.Mutators)
+	}
+}
+
+func hasMutatorAnalysisMetrics(mutators []*soong_metrics_proto.MutatorAnalysisMetrics, name string) bool {
+	for _, mutator := range mutators {
+		if mutator.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalysisMetricsDisabled(t *testing.T) {
+	result := analysisMetricsFixtureFactory.RunTest(t)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a").Module()
+	if metrics := foo.base().analysisMetrics; metrics != nil {
+		t.Errorf("expected no analysis metrics to be recorded, got %v", metrics)
+	}
+}
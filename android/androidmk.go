@@ -156,6 +156,58 @@ func (a *AndroidMkEntries) AddStrings(name string, value ...string) {
 	a.EntryMap[name] = append(a.EntryMap[name], value...)
 }
 
+// writeDist writes the Make rules to copy the files selected by a dist configuration to the dist
+// directory into the header.
+func (a *AndroidMkEntries) writeDist(mod blueprint.Module, dist Dist) {
+	if len(dist.Targets) == 0 {
+		return
+	}
+
+	var distFiles Paths
+	if dist.Tag != nil {
+		var err error
+		if distFiles, err = mod.(Module).base().distFilesForTag(*dist.Tag); err != nil {
+			// This was checked in ModuleBase.GenerateBuildActions
+			panic(err)
+		}
+	} else if a.DistFile.Valid() {
+		distFiles = Paths{a.DistFile.Path()}
+	} else if a.OutputFile.Valid() {
+		distFiles = Paths{a.OutputFile.Path()}
+	}
+
+	goals := strings.Join(dist.Targets, " ")
+	for _, distFile := range distFiles {
+		dest := filepath.Base(distFile.String())
+
+		if dist.Dest != nil {
+			var err error
+			if dest, err = validateSafePath(*dist.Dest); err != nil {
+				// This was checked in ModuleBase.GenerateBuildActions
+				panic(err)
+			}
+		}
+
+		if dist.Suffix != nil {
+			ext := filepath.Ext(dest)
+			suffix := *dist.Suffix
+			dest = strings.TrimSuffix(dest, ext) + suffix + ext
+		}
+
+		if dist.Dir != nil {
+			var err error
+			if dest, err = validateSafePath(*dist.Dir, dest); err != nil {
+				// This was checked in ModuleBase.GenerateBuildActions
+				panic(err)
+			}
+		}
+
+		fmt.Fprintln(&a.header, ".PHONY:", goals)
+		fmt.Fprintf(&a.header, "$(call dist-for-goals,%s,%s:%s)\n",
+			goals, distFile.String(), dest)
+	}
+}
+
 func (a *AndroidMkEntries) fillInEntries(config Config, bpPath string, mod blueprint.Module) {
 	a.EntryMap = make(map[string][]string)
 	amod := mod.(Module).base()
@@ -169,41 +221,9 @@ func (a *AndroidMkEntries) fillInEntries(config Config, bpPath string, mod bluep
 	a.Target_required = append(a.Target_required, amod.commonProperties.Target_required...)
 
 	// Fill in the header part.
-	if len(amod.commonProperties.Dist.Targets) > 0 {
-		distFile := a.DistFile
-		if !distFile.Valid() {
-			distFile = a.OutputFile
-		}
-		if distFile.Valid() {
-			dest := filepath.Base(distFile.String())
-
-			if amod.commonProperties.Dist.Dest != nil {
-				var err error
-				if dest, err = validateSafePath(*amod.commonProperties.Dist.Dest); err != nil {
-					// This was checked in ModuleBase.GenerateBuildActions
-					panic(err)
-				}
-			}
-
-			if amod.commonProperties.Dist.Suffix != nil {
-				ext := filepath.Ext(dest)
-				suffix := *amod.commonProperties.Dist.Suffix
-				dest = strings.TrimSuffix(dest, ext) + suffix + ext
-			}
-
-			if amod.commonProperties.Dist.Dir != nil {
-				var err error
-				if dest, err = validateSafePath(*amod.commonProperties.Dist.Dir, dest); err != nil {
-					// This was checked in ModuleBase.GenerateBuildActions
-					panic(err)
-				}
-			}
-
-			goals := strings.Join(amod.commonProperties.Dist.Targets, " ")
-			fmt.Fprintln(&a.header, ".PHONY:", goals)
-			fmt.Fprintf(&a.header, "$(call dist-for-goals,%s,%s:%s)\n",
-				goals, distFile.String(), dest)
-		}
+	a.writeDist(mod, amod.commonProperties.Dist)
+	for _, dist := range amod.commonProperties.Dists {
+		a.writeDist(mod, dist)
 	}
 
 	fmt.Fprintln(&a.header, "\ninclude $(CLEAR_VARS)")
@@ -15,8 +15,10 @@
 package android
 
 import (
+	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -76,3 +78,134 @@ func TestAndroidMkSingleton_PassesUpdatedAndroidMkDataToCustomCallback(t *testin
 	assertEqual([]string{"baz"}, m.data.Host_required)
 	assertEqual([]string{"qux"}, m.data.Target_required)
 }
+
+type distTestModule struct {
+	ModuleBase
+	outputFile Path
+	logs       Paths
+}
+
+func (m *distTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.outputFile = PathForModuleOut(ctx, "foo.jar")
+	m.logs = Paths{PathForModuleOut(ctx, "a.log"), PathForModuleOut(ctx, "b.log")}
+}
+
+func (m *distTestModule) OutputFiles(tag string) (Paths, error) {
+	switch tag {
+	case "":
+		return Paths{m.outputFile}, nil
+	case ".logs":
+		return m.logs, nil
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
+
+func (m *distTestModule) AndroidMkEntries() []AndroidMkEntries {
+	return []AndroidMkEntries{{
+		Class:      "JAVA_LIBRARIES",
+		OutputFile: OptionalPathForPath(m.outputFile),
+	}}
+}
+
+func distTestModuleFactory() Module {
+	module := &distTestModule{}
+	InitAndroidModule(module)
+	return module
+}
+
+var distTestFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterModuleType("dist_test", distTestModuleFactory))
+
+func TestAndroidMkDists(t *testing.T) {
+	result := distTestFixtureFactory.RunTestWithBp(t, `
+		dist_test {
+			name: "foo",
+			dist: {
+				targets: ["droidcore"],
+				suffix: "_dev",
+			},
+			dists: [
+				{
+					targets: ["sdk", "win_sdk"],
+					dir: "sdk",
+					dest: "foo-sdk.jar",
+				},
+				{
+					targets: ["logs"],
+					tag: ".logs",
+				},
+			],
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "").Module()
+	entries := AndroidMkEntriesForTest(t, result.Config, "", foo)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 AndroidMkEntries, got %d", len(entries))
+	}
+
+	var got []string
+	for _, line := range strings.Split(entries[0].header.String(), "\n") {
+		if strings.HasPrefix(line, "$(call dist-for-goals") {
+			got = append(got, strings.Replace(line, buildDir, "out", -1))
+		}
+	}
+	want := []string{
+		"$(call dist-for-goals,droidcore,out/.intermediates/foo/foo.jar:foo_dev.jar)",
+		"$(call dist-for-goals,sdk win_sdk,out/.intermediates/foo/foo.jar:sdk/foo-sdk.jar)",
+		"$(call dist-for-goals,logs,out/.intermediates/foo/a.log:a.log)",
+		"$(call dist-for-goals,logs,out/.intermediates/foo/b.log:b.log)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected dist rules:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestAndroidMkDistsErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name: "unknown tag",
+			bp: `dist_test {
+				name: "foo",
+				dists: [{ targets: ["logs"], tag: ".unknown" }],
+			}`,
+			error: `dists\[0\].tag: unsupported module reference tag ".unknown"`,
+		},
+		{
+			name: "dest with several files",
+			bp: `dist_test {
+				name: "foo",
+				dists: [{ targets: ["logs"], tag: ".logs", dest: "foo.log" }],
+			}`,
+			error: `dists\[0\].dest: cannot be set when tag ".logs" selects more than one output file`,
+		},
+		{
+			name: "invalid dir",
+			bp: `dist_test {
+				name: "foo",
+				dists: [{}, { targets: ["sdk"], dir: "../sdk" }],
+			}`,
+			error: `dists\[1\].dir: Path is outside directory: ../sdk`,
+		},
+		{
+			name: "tag without output file producer",
+			bp: `custom {
+				name: "foo",
+				dist: { targets: ["droidcore"], tag: ".logs" },
+			}`,
+			error: `dist.tag: module does not support dist tags`,
+		},
+	}
+
+	factory := distTestFixtureFactory.Extend(FixtureRegisterModuleType("custom", customModuleFactory))
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			factory.RunTestExpectingError(t, testCase.error, FixtureWithRootAndroidBp(testCase.bp))
+		})
+	}
+}
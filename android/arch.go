@@ -18,6 +18,7 @@ import (
 	"encoding"
 	"fmt"
 	"reflect"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -31,12 +32,13 @@ const COMMON_VARIANT = "common"
 var (
 	archTypeList []ArchType
 
-	Arm    = newArch("arm", "lib32")
-	Arm64  = newArch("arm64", "lib64")
-	Mips   = newArch("mips", "lib32")
-	Mips64 = newArch("mips64", "lib64")
-	X86    = newArch("x86", "lib32")
-	X86_64 = newArch("x86_64", "lib64")
+	Arm     = newArch("arm", "lib32")
+	Arm64   = newArch("arm64", "lib64")
+	Mips    = newArch("mips", "lib32")
+	Mips64  = newArch("mips64", "lib64")
+	Riscv64 = newArch("riscv64", "lib64")
+	X86     = newArch("x86", "lib32")
+	X86_64  = newArch("x86_64", "lib64")
 
 	Common = ArchType{
 		Name: COMMON_VARIANT,
@@ -44,12 +46,13 @@ var (
 )
 
 var archTypeMap = map[string]ArchType{
-	"arm":    Arm,
-	"arm64":  Arm64,
-	"mips":   Mips,
-	"mips64": Mips64,
-	"x86":    X86,
-	"x86_64": X86_64,
+	"arm":     Arm,
+	"arm64":   Arm64,
+	"mips":    Mips,
+	"mips64":  Mips64,
+	"riscv64": Riscv64,
+	"x86":     X86,
+	"x86_64":  X86_64,
 }
 
 /*
@@ -70,6 +73,9 @@ module {
         mips64: {
             // Host or device variants with mips64 architecture
         },
+        riscv64: {
+            // Host or device variants with riscv64 architecture
+        },
         x86: {
             // Host or device variants with x86 architecture
         },
@@ -134,12 +140,18 @@ var archVariants = map[ArchType][]string{
 	Arm64: {
 		"armv8_a",
 		"armv8_2a",
+		"armv9_a",
 		"cortex-a53",
 		"cortex-a55",
 		"cortex-a72",
 		"cortex-a73",
 		"cortex-a75",
 		"cortex-a76",
+		"cortex-a510",
+		"cortex-a710",
+		"cortex-a715",
+		"cortex-x2",
+		"cortex-x3",
 		"kryo",
 		"kryo385",
 		"exynos-m1",
@@ -158,9 +170,12 @@ var archVariants = map[ArchType][]string{
 		"mips64r6",
 	},
 	X86: {
+		"alderlake",
 		"amberlake",
 		"atom",
 		"broadwell",
+		"goldmont",
+		"goldmont-plus",
 		"haswell",
 		"icelake",
 		"ivybridge",
@@ -170,12 +185,16 @@ var archVariants = map[ArchType][]string{
 		"skylake",
 		"stoneyridge",
 		"tigerlake",
+		"tremont",
 		"whiskeylake",
 		"x86_64",
 	},
 	X86_64: {
+		"alderlake",
 		"amberlake",
 		"broadwell",
+		"goldmont",
+		"goldmont-plus",
 		"haswell",
 		"icelake",
 		"ivybridge",
@@ -185,6 +204,7 @@ var archVariants = map[ArchType][]string{
 		"skylake",
 		"stoneyridge",
 		"tigerlake",
+		"tremont",
 		"whiskeylake",
 	},
 }
@@ -193,6 +213,9 @@ var archFeatures = map[ArchType][]string{
 	Arm: {
 		"neon",
 	},
+	Arm64: {
+		"sve2",
+	},
 	Mips: {
 		"dspr2",
 		"rev6",
@@ -224,6 +247,7 @@ var archFeatures = map[ArchType][]string{
 		"avx2",
 		"avx512",
 		"popcnt",
+		"movbe",
 	},
 }
 
@@ -239,6 +263,11 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"neon",
 		},
 	},
+	Arm64: {
+		"armv9-a": {
+			"sve2",
+		},
+	},
 	Mips: {
 		"mips32r2dspr2_fp": {
 			"dspr2",
@@ -253,6 +282,17 @@ var archFeatureMap = map[ArchType]map[string][]string{
 		},
 	},
 	X86: {
+		"alderlake": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"avx",
+			"avx2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"amberlake": {
 			"ssse3",
 			"sse4",
@@ -277,6 +317,24 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"aes_ni",
 			"popcnt",
 		},
+		"goldmont": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
+		"goldmont-plus": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"haswell": {
 			"ssse3",
 			"sse4",
@@ -366,6 +424,15 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"aes_ni",
 			"popcnt",
 		},
+		"tremont": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"whiskeylake": {
 			"ssse3",
 			"sse4",
@@ -386,6 +453,17 @@ var archFeatureMap = map[ArchType]map[string][]string{
 		},
 	},
 	X86_64: {
+		"alderlake": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"avx",
+			"avx2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"amberlake": {
 			"ssse3",
 			"sse4",
@@ -406,6 +484,24 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"aes_ni",
 			"popcnt",
 		},
+		"goldmont": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
+		"goldmont-plus": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"haswell": {
 			"ssse3",
 			"sse4",
@@ -492,6 +588,15 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"aes_ni",
 			"popcnt",
 		},
+		"tremont": {
+			"ssse3",
+			"sse4",
+			"sse4_1",
+			"sse4_2",
+			"aes_ni",
+			"popcnt",
+			"movbe",
+		},
 		"whiskeylake": {
 			"ssse3",
 			"sse4",
@@ -508,6 +613,81 @@ var archFeatureMap = map[ArchType]map[string][]string{
 
 var defaultArchFeatureMap = map[OsType]map[ArchType][]string{}
 
+// RegisterArchVariants registers additional variants of an arch, e.g. a new CPU, so that they can
+// be used as the arch variant of a target and to select arch-variant properties in the form:
+// arch: {
+//     arm64: {
+//         variant: {
+//             key: value,
+//         },
+//     },
+// },
+// It must be called from an init() function, e.g. by the toolchain package of the arch.
+func RegisterArchVariants(arch ArchType, variants ...string) {
+	checkCalledFromInit()
+
+	for _, variant := range variants {
+		checkArchAxisName(arch, variant)
+		archVariants[arch] = append(archVariants[arch], variant)
+	}
+}
+
+// RegisterArchFeatures registers additional features of an arch, e.g. a new instruction set
+// extension, so that they can be enabled for arch variants with RegisterArchVariantFeatures and
+// used to select arch-variant properties in the form:
+// arch: {
+//     arm64: {
+//         feature: {
+//             key: value,
+//         },
+//     },
+// },
+// It must be called from an init() function, e.g. by the toolchain package of the arch.
+func RegisterArchFeatures(arch ArchType, features ...string) {
+	checkCalledFromInit()
+
+	for _, feature := range features {
+		checkArchAxisName(arch, feature)
+		archFeatures[arch] = append(archFeatures[arch], feature)
+	}
+}
+
+// RegisterArchVariantFeatures enables features of an arch for a variant of the arch.  It must be
+// called from an init() function, e.g. by the toolchain package of the arch.
+func RegisterArchVariantFeatures(arch ArchType, variant string, features ...string) {
+	checkCalledFromInit()
+
+	if !InList(variant, archVariants[arch]) {
+		panic(fmt.Errorf("Invalid variant %q for arch %q", variant, arch))
+	}
+	for _, feature := range features {
+		if !InList(feature, archFeatures[arch]) {
+			panic(fmt.Errorf("Invalid feature %q for arch %q variant %q", feature, arch, variant))
+		}
+	}
+
+	if archFeatureMap[arch] == nil {
+		archFeatureMap[arch] = make(map[string][]string)
+	}
+	archFeatureMap[arch][variant] = FirstUniqueStrings(append(archFeatureMap[arch][variant], features...))
+}
+
+// checkArchAxisName panics if a variant or feature name can't be registered for an arch, because
+// its property name would be invalid or would be the same as that of an existing variant or feature.
+func checkArchAxisName(arch ArchType, name string) {
+	field := proptools.FieldNameForProperty(variantReplacer.Replace(name))
+	if name == "" || !archAxisNameRegexp.MatchString(name) {
+		panic(fmt.Errorf("Invalid variant or feature name %q for arch %q", name, arch))
+	}
+	for _, existing := range append(append([]string(nil), archVariants[arch]...), archFeatures[arch]...) {
+		if proptools.FieldNameForProperty(variantReplacer.Replace(existing)) == field {
+			panic(fmt.Errorf("Variant or feature %q for arch %q is already registered", name, arch))
+		}
+	}
+}
+
+var archAxisNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
 func RegisterDefaultArchVariantFeatures(os OsType, arch ArchType, features ...string) {
 	checkCalledFromInit()
 
@@ -603,6 +783,7 @@ var (
 	Linux       = NewOsType("linux_glibc", Host, false)
 	Darwin      = NewOsType("darwin", Host, false)
 	LinuxBionic = NewOsType("linux_bionic", Host, false)
+	LinuxMusl   = NewOsType("linux_musl", HostCross, true)
 	Windows     = NewOsType("windows", HostCross, true)
 	Android     = NewOsType("android", Device, false)
 	Fuchsia     = NewOsType("fuchsia", Device, false)
@@ -614,9 +795,10 @@ var (
 	osArchTypeMap = map[OsType][]ArchType{
 		Linux:       []ArchType{X86, X86_64},
 		LinuxBionic: []ArchType{X86_64},
+		LinuxMusl:   []ArchType{X86, X86_64},
 		Darwin:      []ArchType{X86_64},
 		Windows:     []ArchType{X86, X86_64},
-		Android:     []ArchType{Arm, Arm64, Mips, Mips64, X86, X86_64},
+		Android:     []ArchType{Arm, Arm64, Mips, Mips64, Riscv64, X86, X86_64},
 		Fuchsia:     []ArchType{Arm64, X86_64},
 	}
 )
@@ -661,7 +843,7 @@ func (os OsType) Bionic() bool {
 }
 
 func (os OsType) Linux() bool {
-	return os == Android || os == Linux || os == LinuxBionic
+	return os == Android || os == Linux || os == LinuxBionic || os == LinuxMusl
 }
 
 func NewOsType(name string, class OsClass, defDisabled bool) OsType {
@@ -1376,7 +1558,7 @@ func (m *ModuleBase) setArchProperties(ctx BottomUpMutatorContext) {
 				//     },
 				// },
 				for _, feature := range arch.ArchFeatures {
-					field := proptools.FieldNameForProperty(feature)
+					field := proptools.FieldNameForProperty(variantReplacer.Replace(feature))
 					prefix := "arch." + t.Name + "." + feature
 					m.appendProperties(ctx, genProps, archStruct, field, prefix)
 				}
@@ -1661,6 +1843,8 @@ func getMegaDeviceConfig() []archConfig {
 		{"arm64", "armv8-a", "exynos-m2", []string{"arm64-v8a"}},
 		{"arm64", "armv8-2a", "cortex-a75", []string{"arm64-v8a"}},
 		{"arm64", "armv8-2a", "cortex-a76", []string{"arm64-v8a"}},
+		{"arm64", "armv9-a", "cortex-a715", []string{"arm64-v8a"}},
+		{"arm64", "armv9-a", "cortex-x3", []string{"arm64-v8a"}},
 		{"arm64", "armv8-2a", "kryo385", []string{"arm64-v8a"}},
 		{"mips", "mips32-fp", "", []string{"mips"}},
 		{"mips", "mips32r2-fp", "", []string{"mips"}},
@@ -1672,19 +1856,27 @@ func getMegaDeviceConfig() []archConfig {
 		//{"mips64", "mips64r2", "", []string{"mips64"}},
 		{"mips64", "mips64r6", "", []string{"mips64"}},
 		{"x86", "", "", []string{"x86"}},
+		{"x86", "alderlake", "", []string{"x86"}},
 		{"x86", "atom", "", []string{"x86"}},
+		{"x86", "goldmont", "", []string{"x86"}},
+		{"x86", "goldmont-plus", "", []string{"x86"}},
 		{"x86", "haswell", "", []string{"x86"}},
 		{"x86", "ivybridge", "", []string{"x86"}},
 		{"x86", "sandybridge", "", []string{"x86"}},
 		{"x86", "silvermont", "", []string{"x86"}},
 		{"x86", "stoneyridge", "", []string{"x86"}},
+		{"x86", "tremont", "", []string{"x86"}},
 		{"x86", "x86_64", "", []string{"x86"}},
 		{"x86_64", "", "", []string{"x86_64"}},
+		{"x86_64", "alderlake", "", []string{"x86_64"}},
+		{"x86_64", "goldmont", "", []string{"x86_64"}},
+		{"x86_64", "goldmont-plus", "", []string{"x86_64"}},
 		{"x86_64", "haswell", "", []string{"x86_64"}},
 		{"x86_64", "ivybridge", "", []string{"x86_64"}},
 		{"x86_64", "sandybridge", "", []string{"x86_64"}},
 		{"x86_64", "silvermont", "", []string{"x86_64"}},
 		{"x86_64", "stoneyridge", "", []string{"x86_64"}},
+		{"x86_64", "tremont", "", []string{"x86_64"}},
 	}
 }
 
@@ -1710,6 +1902,12 @@ func decodeArchSettings(os OsType, archConfigs []archConfig) ([]Target, error) {
 	var ret []Target
 
 	for _, config := range archConfigs {
+		// The mega device config includes mips, which can only be built with the mips
+		// toolchains.
+		if !SoongMipsToolchains && (config.arch == Mips.Name || config.arch == Mips64.Name) {
+			continue
+		}
+
 		arch, err := decodeArch(os, config.arch, &config.archVariant,
 			&config.cpuVariant, config.abi)
 		if err != nil {
@@ -1738,6 +1936,9 @@ func decodeArch(os OsType, arch string, archVariant, cpuVariant *string, abi []s
 	if !ok {
 		return Arch{}, fmt.Errorf("unknown arch %q", arch)
 	}
+	if !SoongMipsToolchains && (archType == Mips || archType == Mips64) {
+		return Arch{}, fmt.Errorf("arch %q requires SOONG_MIPS_TOOLCHAINS=true", arch)
+	}
 
 	a := Arch{
 		ArchType:    archType,
@@ -468,3 +468,91 @@ func TestArchMutatorNativeBridge(t *testing.T) {
 		})
 	}
 }
+
+func init() {
+	RegisterArchVariants(Arm64, "test-variant")
+	RegisterArchFeatures(Arm64, "test-feature")
+	RegisterArchVariantFeatures(Arm64, "test-variant", "test-feature")
+}
+
+type archFeatureTestModule struct {
+	ModuleBase
+	props struct {
+		Cflags []string `android:"arch_variant"`
+	}
+}
+
+func (m *archFeatureTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func archFeatureTestModuleFactory() Module {
+	m := &archFeatureTestModule{}
+	m.AddProperties(&m.props)
+	InitAndroidArchModule(m, DeviceSupported, MultilibFirst)
+	return m
+}
+
+func TestRegisteredArchFeatures(t *testing.T) {
+	arch, err := decodeArch(Android, "arm64", stringPtr("test-variant"), nil, []string{"arm64-v8a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if g, w := arch.ArchFeatures, []string{"test-feature"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected arch features %q, got %q", w, g)
+	}
+
+	result := NewFixtureFactory(&buildDir,
+		PrepareForTestWithArchMutator,
+		FixtureRegisterModuleType("test", archFeatureTestModuleFactory),
+		FixtureModifyConfig(func(config Config) {
+			config.Targets[Android] = []Target{{Android, arch, NativeBridgeDisabled, "", ""}}
+		}),
+	).RunTestWithBp(t, `
+		test {
+			name: "foo",
+			arch: {
+				arm64: {
+					test_variant: {
+						cflags: ["-variant"],
+					},
+					test_feature: {
+						cflags: ["-feature"],
+					},
+					sve2: {
+						cflags: ["-sve2"],
+					},
+				},
+			},
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_arm64_test-variant").Module().(*archFeatureTestModule)
+	if g, w := foo.props.Cflags, []string{"-variant", "-feature"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected cflags %q, got %q", w, g)
+	}
+}
+
+func TestCheckArchAxisName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		panics bool
+	}{
+		{name: "new-feature"},
+		{name: "test-variant", panics: true},
+		{name: "test_feature", panics: true},
+		{name: "cortex-a53", panics: true},
+		{name: "Upper", panics: true},
+		{name: "", panics: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != testCase.panics {
+					t.Errorf("expected panic %v, got %v", testCase.panics, r)
+				}
+			}()
+			checkArchAxisName(Arm64, testCase.name)
+		})
+	}
+}
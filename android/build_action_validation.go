@@ -0,0 +1,147 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// Build action validation is an opt-in mode, enabled by setting SOONG_VALIDATE_BUILD_ACTIONS=true,
+// that checks the build actions declared with ctx.Build after they have run.  It fails the build
+// if:
+//  - a declared output of an action was not created by the action,
+//  - a depfile written by an action lists a path outside of the source tree, or
+//  - a file was written next to the declared outputs of an action without being declared as an
+//    output of any action.  Scratch directories like the javac classes directory and files left
+//    over from earlier builds are ignored.
+//
+// The checks are only run when the validate-build-actions phony target is built, which depends on
+// every declared output.  Depfiles of rules that set deps = gcc are consumed by ninja and deleted
+// once they have been read, so only depfiles of rules that leave them on disk are checked.
+
+func init() {
+	pctx.SourcePathVariable("validate_build_actions", "build/soong/scripts/validate_build_actions.py")
+}
+
+var validateBuildActionsRule = pctx.AndroidStaticRule("validateBuildActions",
+	blueprint.RuleParams{
+		Command:     `rm -f $out && ${validate_build_actions} --manifest $in --stamp $out`,
+		CommandDeps: []string{"${validate_build_actions}"},
+		Description: "validate build actions",
+	})
+
+const buildActionValidationManifestFileName = "build_action_validation.json"
+
+// buildActionValidationEntry is the entry for a single build action in the build action
+// validation manifest.
+type buildActionValidationEntry struct {
+	Outputs []string
+	Depfile string `json:",omitempty"`
+}
+
+// buildActionValidationManifest is the contents of the build action validation manifest that is
+// read by validate_build_actions.py.
+type buildActionValidationManifest struct {
+	Actions []buildActionValidationEntry
+}
+
+var buildActionValidationKey = NewOnceKey("buildActionValidation")
+
+type buildActionValidation struct {
+	sync.Mutex
+	actions []buildActionValidationEntry
+	outputs Paths
+}
+
+func buildActionValidationForConfig(config Config) *buildActionValidation {
+	return config.Once(buildActionValidationKey, func() interface{} {
+		return &buildActionValidation{}
+	}).(*buildActionValidation)
+}
+
+// recordBuildActionForValidation records the outputs and depfile of a build action so that they
+// can be validated after the action has run.  It is a no-op unless build action validation is
+// enabled.
+func recordBuildActionForValidation(config Config, params BuildParams) {
+	if !config.ValidateBuildActions() {
+		return
+	}
+
+	// Phony actions don't create their outputs, and error actions never succeed.
+	if params.Rule == Phony || params.Rule == blueprint.Phony || params.Rule == ErrorRule {
+		return
+	}
+
+	outputs := append(params.Outputs.Paths(), params.ImplicitOutputs.Paths()...)
+	if params.Output != nil {
+		outputs = append(outputs, params.Output)
+	}
+	if params.ImplicitOutput != nil {
+		outputs = append(outputs, params.ImplicitOutput)
+	}
+
+	entry := buildActionValidationEntry{Outputs: outputs.Strings()}
+	if params.Depfile != nil {
+		entry.Depfile = params.Depfile.String()
+	}
+
+	validation := buildActionValidationForConfig(config)
+	validation.Lock()
+	defer validation.Unlock()
+	validation.actions = append(validation.actions, entry)
+	validation.outputs = append(validation.outputs, outputs...)
+}
+
+// ValidateBuildActions returns true if the outputs, depfiles and writes of build actions should be
+// validated after the actions have run.
+func (c *config) ValidateBuildActions() bool {
+	return c.IsEnvTrue("SOONG_VALIDATE_BUILD_ACTIONS")
+}
+
+func buildActionValidationSingletonFactory() Singleton {
+	return &buildActionValidationSingleton{}
+}
+
+type buildActionValidationSingleton struct{}
+
+func (s *buildActionValidationSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().ValidateBuildActions() {
+		return
+	}
+
+	validation := buildActionValidationForConfig(ctx.Config())
+	validation.Lock()
+	manifest := buildActionValidationManifest{
+		Actions: append([]buildActionValidationEntry(nil), validation.actions...),
+	}
+	outputs := CopyOfPaths(validation.outputs)
+	validation.Unlock()
+
+	manifestPath := PathForOutput(ctx, buildActionValidationManifestFileName)
+	if !WriteJSONReport(ctx, manifestPath, manifest, "validate-build-actions") {
+		return
+	}
+
+	stamp := PathForOutput(ctx, "build_action_validation.stamp")
+	ctx.Build(pctx, BuildParams{
+		Rule:      validateBuildActionsRule,
+		Input:     manifestPath,
+		Implicits: outputs,
+		Output:    stamp,
+	})
+	ctx.Phony("validate-build-actions", stamp)
+}
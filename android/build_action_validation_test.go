@@ -0,0 +1,104 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+type buildActionValidationTestModule struct {
+	ModuleBase
+}
+
+func buildActionValidationTestModuleFactory() Module {
+	module := &buildActionValidationTestModule{}
+	InitAndroidModule(module)
+	return module
+}
+
+func (m *buildActionValidationTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	ctx.Build(pctx, BuildParams{
+		Rule:           Cp,
+		Input:          PathForModuleSrc(ctx, "in"),
+		Output:         PathForModuleOut(ctx, "out"),
+		ImplicitOutput: PathForModuleOut(ctx, "out.extra"),
+		Depfile:        PathForModuleOut(ctx, "out.d"),
+	})
+	ctx.Build(pctx, BuildParams{
+		Rule:   Phony,
+		Output: PathForModuleOut(ctx, "phony"),
+	})
+}
+
+var buildActionValidationFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterModuleType("test", buildActionValidationTestModuleFactory),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterSingletonType("build_action_validation", buildActionValidationSingletonFactory)
+	}),
+	FixtureWithRootAndroidBp(`
+		test {
+			name: "foo",
+		}
+	`),
+	FixtureAddTextFile("in", ""),
+)
+
+func TestBuildActionValidation(t *testing.T) {
+	result := buildActionValidationFixtureFactory.RunTest(t,
+		FixtureMergeEnv(map[string]string{"SOONG_VALIDATE_BUILD_ACTIONS": "true"}),
+	)
+
+	foo := result.ModuleForTests("foo", "")
+	out := foo.Output("out")
+
+	outputs := []string{out.Output.String(), out.ImplicitOutput.String()}
+
+	validate := result.SingletonForTests("build_action_validation").Rule("validateBuildActions")
+	if got := validate.Implicits.Strings(); !reflect.DeepEqual(got, outputs) {
+		t.Errorf("expected validation to depend on %q, got %q", outputs, got)
+	}
+
+	manifestPath := PathForOutput(PathContextForTesting(result.Config), buildActionValidationManifestFileName)
+	data, err := ioutil.ReadFile(manifestPath.String())
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", buildActionValidationManifestFileName, err)
+	}
+	var manifest buildActionValidationManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("failed to parse %s: %s", buildActionValidationManifestFileName, err)
+	}
+
+	expected := []buildActionValidationEntry{
+		{
+			Outputs: outputs,
+			Depfile: out.Depfile.String(),
+		},
+	}
+	if !reflect.DeepEqual(manifest.Actions, expected) {
+		t.Errorf("expected manifest actions:\n%#v\ngot:\n%#v", expected, manifest.Actions)
+	}
+}
+
+func TestBuildActionValidationDisabled(t *testing.T) {
+	result := buildActionValidationFixtureFactory.RunTest(t)
+
+	validate := result.SingletonForTests("build_action_validation").MaybeRule("validateBuildActions")
+	if validate.Rule != nil {
+		t.Errorf("expected no validation action when SOONG_VALIDATE_BUILD_ACTIONS is not set")
+	}
+}
@@ -406,10 +406,28 @@ func NewConfig(srcDir, buildDir string) (Config, error) {
 		return Config{}, err
 	}
 
+	// Reuse the results of globs from the previous run of soong_build for any directories that
+	// have not changed since.
+	if !config.IsEnvTrue("SOONG_DISABLE_GLOB_CACHE") {
+		config.fs = newGlobCacheFs(config.fs, globCacheFileForConfig(config))
+	}
+
 	if Bool(config.productVariables.GcovCoverage) && Bool(config.productVariables.ClangCoverage) {
 		return Config{}, fmt.Errorf("GcovCoverage and ClangCoverage cannot both be set")
 	}
 
+	if _, err := newPrebuiltPolicy(config); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateModuleOverrideAllowlist(config); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateNdkSysrootHostOs(String(config.productVariables.Ndk_sysroot_host_os)); err != nil {
+		return Config{}, err
+	}
+
 	config.productVariables.Native_coverage = proptools.BoolPtr(
 		Bool(config.productVariables.GcovCoverage) ||
 			Bool(config.productVariables.ClangCoverage))
@@ -619,6 +637,12 @@ func (c *config) PlatformSdkVersion() string {
 	return strconv.Itoa(c.PlatformSdkVersionInt())
 }
 
+// PlatformSdkFinal returns true if the platform SDK is finalized, i.e. this is a release
+// build and the current API level is PlatformSdkVersion rather than a codename.
+func (c *config) PlatformSdkFinal() bool {
+	return Bool(c.productVariables.Platform_sdk_final)
+}
+
 func (c *config) PlatformSdkCodename() string {
 	return String(c.productVariables.Platform_sdk_codename)
 }
@@ -827,8 +851,73 @@ func (c *config) UseRemoteBuild() bool {
 	return c.UseGoma() || c.UseRBE()
 }
 
+// CcWrapper returns the compiler caching wrapper set by the product, a path in the source tree
+// that is prefixed onto C/C++ compile commands. It is not used for remote builds, which already
+// wrap the compiler, or for native coverage builds, where the .gcno files written next to the
+// objects are not restored reliably from a local cache.
+func (c *config) CcWrapper() string {
+	if c.UseRemoteBuild() || Bool(c.productVariables.GcovCoverage) || Bool(c.productVariables.ClangCoverage) {
+		return ""
+	}
+	return String(c.productVariables.CcWrapper)
+}
+
+// RunErrorProne returns whether java modules are checked with Error Prone. The RUN_ERROR_PRONE
+// environment variable overrides the product's setting.
 func (c *config) RunErrorProne() bool {
-	return c.IsEnvTrue("RUN_ERROR_PRONE")
+	if c.IsEnvTrue("RUN_ERROR_PRONE") {
+		return true
+	} else if c.IsEnvFalse("RUN_ERROR_PRONE") {
+		return false
+	}
+	return Bool(c.productVariables.RunErrorProne)
+}
+
+// ErrorProneMode returns whether Error Prone errors fail the build ("errors"), or are only
+// reported as warnings ("warnings"). The ERROR_PRONE_MODE environment variable overrides the
+// product's setting.
+func (c *config) ErrorProneMode() string {
+	if mode := c.Getenv("ERROR_PRONE_MODE"); mode != "" {
+		return mode
+	}
+	return proptools.StringDefault(c.productVariables.ErrorProneMode, "errors")
+}
+
+// ErrorProneReports returns whether Error Prone writes its findings to SARIF reports that are
+// aggregated by the errorprone-report goal. It is set with the ERROR_PRONE_REPORTS environment
+// variable.
+func (c *config) ErrorProneReports() bool {
+	return c.IsEnvTrue("ERROR_PRONE_REPORTS")
+}
+
+// ErrorProneConfig returns the path in the source tree to the file that sets the Error Prone
+// classpath, checks and flags, or an empty string if the product doesn't set one.
+func (c *config) ErrorProneConfig() string {
+	return String(c.productVariables.ErrorProneConfig)
+}
+
+// ErrorPronePlugins returns the java_plugin modules that provide extra Error Prone checks for
+// all device java modules.
+func (c *config) ErrorPronePlugins() []string {
+	return c.productVariables.ErrorPronePlugins
+}
+
+// NullAwayAnnotatedPackages returns the java packages that NullAway treats as annotated for
+// nullness in every module that enables it.
+func (c *config) NullAwayAnnotatedPackages() []string {
+	return c.productVariables.NullAwayAnnotatedPackages
+}
+
+// SandboxInstallActions returns whether the rules that install files run in an sbox sandbox that
+// only contains the file being installed. The SOONG_SANDBOX_INSTALL environment variable overrides
+// the product's setting.
+func (c *config) SandboxInstallActions() bool {
+	if c.IsEnvTrue("SOONG_SANDBOX_INSTALL") {
+		return true
+	} else if c.IsEnvFalse("SOONG_SANDBOX_INSTALL") {
+		return false
+	}
+	return Bool(c.productVariables.SandboxInstallActions)
 }
 
 func (c *config) XrefCorpusName() string {
@@ -858,6 +947,37 @@ func (c *config) TidyChecks() string {
 	return *c.productVariables.TidyChecks
 }
 
+func (c *config) BoardGlobalCflags() []string {
+	return c.productVariables.BoardGlobalCflags
+}
+
+func (c *config) BoardGlobalCppflags() []string {
+	return c.productVariables.BoardGlobalCppflags
+}
+
+func (c *config) BoardGlobalLdflags() []string {
+	return c.productVariables.BoardGlobalLdflags
+}
+
+// CompilerRtArches returns the arches for which device modules use the LLVM runtime instead of
+// the gcc runtime, i.e. link the LLVM unwinder instead of libgcc.
+func (c *config) CompilerRtArches() []string {
+	return c.productVariables.CompilerRtArches
+}
+
+// HostCompilerRtArches returns the arches for which Linux host modules use the LLVM runtime
+// instead of the gcc runtime, i.e. link the compiler-rt builtins and crtbegin and crtend objects
+// instead of the gcc ones.
+func (c *config) HostCompilerRtArches() []string {
+	return c.productVariables.HostCompilerRtArches
+}
+
+// StackProtector returns the stack protector strength for device modules selected by the
+// product, or "" to use the default for each arch.
+func (c *config) StackProtector() string {
+	return String(c.productVariables.StackProtector)
+}
+
 func (c *config) LibartImgHostBaseAddress() string {
 	return "0x60000000"
 }
@@ -949,6 +1069,32 @@ func (c *config) DexpreoptGlobalConfig(ctx PathContext) ([]byte, error) {
 	return ioutil.ReadFile(path)
 }
 
+// ToolchainConfig returns the contents of the file that overrides the built-in cc toolchain
+// flags, or nil if the product doesn't set one.
+func (c *config) ToolchainConfig(ctx PathContext) ([]byte, error) {
+	if c.productVariables.ToolchainConfig == nil {
+		return nil, nil
+	}
+	path := absolutePath(*c.productVariables.ToolchainConfig)
+	ctx.AddNinjaFileDeps(path)
+	return ioutil.ReadFile(path)
+}
+
+// OwnershipRegistry returns the path to the file that lists the teams and bug components modules
+// can be owned by, or an invalid path if the product doesn't set one.
+func (c *config) OwnershipRegistry(ctx PathContext) OptionalPath {
+	if c.productVariables.OwnershipRegistry == nil {
+		return OptionalPath{}
+	}
+	return OptionalPathForPath(PathForSource(ctx, *c.productVariables.OwnershipRegistry))
+}
+
+// RemoteArtifactFetcher returns the path relative to the source tree of the tool that fetches
+// remote artifacts from the content-addressed store, or "" if the product doesn't set one.
+func (c *config) RemoteArtifactFetcher() string {
+	return String(c.productVariables.RemoteArtifactFetcher)
+}
+
 func (c *config) FrameworksBaseDirExists(ctx PathContext) bool {
 	return ExistentPathForSource(ctx, "frameworks", "base").Valid()
 }
@@ -1108,6 +1254,18 @@ func (c *deviceConfig) PgoAdditionalProfileDirs() []string {
 	return c.config.productVariables.PgoAdditionalProfileDirs
 }
 
+// PgoProfileDir returns the directory containing instrumentation profiles named
+// <module>.profdata that are used for the matching modules.
+func (c *deviceConfig) PgoProfileDir() string {
+	return String(c.config.productVariables.PgoProfileDir)
+}
+
+// AfdoProfileDir returns the directory containing AutoFDO sample profiles named
+// <module>.afdo that are used for the matching modules.
+func (c *deviceConfig) AfdoProfileDir() string {
+	return String(c.config.productVariables.AfdoProfileDir)
+}
+
 func (c *deviceConfig) VendorSepolicyDirs() []string {
 	return c.config.productVariables.BoardVendorSepolicyDirs
 }
@@ -1138,6 +1296,11 @@ func (c *deviceConfig) OverrideCertificateFor(name string) (certificatePath stri
 		"invalid override rule %q in PRODUCT_CERTIFICATE_OVERRIDES should be <module_name>:<certificate_module_name>")
 }
 
+func (c *deviceConfig) OverrideLlndkFor(name string) (overrideModule string, overridden bool) {
+	return findOverrideValue(c.config.productVariables.LlndkOverrides, name,
+		"invalid override rule %q in PRODUCT_LLNDK_OVERRIDES should be <llndk_library_name>:<override_llndk_module_name>")
+}
+
 func (c *deviceConfig) OverridePackageNameFor(name string) string {
 	newName, overridden := findOverrideValue(
 		c.config.productVariables.PackageNameOverrides,
@@ -1187,10 +1350,37 @@ func (c *config) CFIEnabledForPath(path string) bool {
 	return HasAnyPrefix(path, c.productVariables.CFIIncludePaths)
 }
 
+// CFIArches returns the arches that CFIIncludePaths applies to.
+func (c *config) CFIArches() []string {
+	if c.productVariables.CFIArches == nil {
+		return []string{"arm64"}
+	}
+	return c.productVariables.CFIArches
+}
+
+func (c *config) SCSDisabledForPath(path string) bool {
+	if c.productVariables.SCSExcludePaths == nil {
+		return false
+	}
+	return HasAnyPrefix(path, c.productVariables.SCSExcludePaths)
+}
+
+func (c *config) SCSEnabledForPath(path string) bool {
+	if c.productVariables.SCSIncludePaths == nil {
+		return false
+	}
+	return HasAnyPrefix(path, c.productVariables.SCSIncludePaths)
+}
+
 func (c *config) VendorConfig(name string) VendorConfig {
 	return soongconfig.Config(c.productVariables.VendorVars[name])
 }
 
+// VendorConfigVariables returns all the soong config variables set in the namespace name.
+func (c *config) VendorConfigVariables(name string) map[string]string {
+	return c.productVariables.VendorVars[name]
+}
+
 func (c *config) NdkAbis() bool {
 	return Bool(c.productVariables.Ndk_abis)
 }
@@ -1203,6 +1393,24 @@ func (c *config) ExcludeDraftNdkApis() bool {
 	return Bool(c.productVariables.Exclude_draft_ndk_apis)
 }
 
+// validateNdkSysrootHostOs returns an error if the Ndk_sysroot_host_os product variable is set to
+// something other than the name of a host OS.
+func validateNdkSysrootHostOs(name string) error {
+	if name == "" {
+		return nil
+	}
+	if os := osByName(name); os.Class != Host && os.Class != HostCross {
+		return fmt.Errorf("Ndk_sysroot_host_os %q is not a host OS", name)
+	}
+	return nil
+}
+
+// NdkSysrootHostOs returns the host OS that the NDK sysroot is being packaged for, or
+// NoOsType if the sysroot should use the default single-host layout.
+func (c *config) NdkSysrootHostOs() OsType {
+	return osByName(String(c.productVariables.Ndk_sysroot_host_os))
+}
+
 func (c *config) FlattenApex() bool {
 	return Bool(c.productVariables.Flatten_apex)
 }
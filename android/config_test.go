@@ -19,6 +19,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func validateConfigAnnotations(configurable jsonConfigurable) (err error) {
@@ -91,3 +93,28 @@ func TestMissingVendorConfig(t *testing.T) {
 		t.Errorf("Expected false")
 	}
 }
+
+func TestValidateNdkSysrootHostOs(t *testing.T) {
+	for _, name := range []string{"", "linux_glibc", "darwin", "windows"} {
+		if err := validateNdkSysrootHostOs(name); err != nil {
+			t.Errorf("unexpected error for %q: %s", name, err)
+		}
+	}
+	for _, name := range []string{"android", "fuchsia", "solaris"} {
+		if err := validateNdkSysrootHostOs(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
+
+func TestNdkSysrootHostOs(t *testing.T) {
+	config := TestConfig(buildDir, nil, "", nil)
+	if os := config.NdkSysrootHostOs(); os != NoOsType {
+		t.Errorf("expected no NDK sysroot host OS by default, got %q", os.Name)
+	}
+
+	config.TestProductVariables.Ndk_sysroot_host_os = proptools.StringPtr("darwin")
+	if os := config.NdkSysrootHostOs(); os != Darwin {
+		t.Errorf("expected NDK sysroot host OS darwin, got %q", os.Name)
+	}
+}
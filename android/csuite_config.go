@@ -14,57 +14,40 @@
 
 package android
 
-import (
-	"fmt"
-	"io"
-)
-
 func init() {
 	RegisterModuleType("csuite_config", CSuiteConfigFactory)
-}
 
-type csuiteConfigProperties struct {
-	// Override the default (AndroidTest.xml) test manifest file name.
-	Test_config *string
+	pctx.SourcePathVariable("CSuiteTestConfigTemplate", "build/make/core/csuite_test_config_template.xml")
 }
 
-type CSuiteConfig struct {
-	ModuleBase
-	properties     csuiteConfigProperties
-	OutputFilePath OutputPath
+var csuiteConfigParams = TestSuiteConfigParams{
+	Suite:                     "csuite",
+	DefaultTestConfigTemplate: "${CSuiteTestConfigTemplate}",
+	AllowedProperties: []string{
+		"test_config_template",
+		"include_filters",
+		"exclude_filters",
+		"target_preparers",
+		"template_values",
+		"app_list",
+		"apps",
+		"copy_apks",
+	},
 }
 
-func (me *CSuiteConfig) GenerateAndroidBuildActions(ctx ModuleContext) {
-	me.OutputFilePath = PathForModuleOut(ctx, me.BaseModuleName()).OutputPath
-}
-
-func (me *CSuiteConfig) AndroidMk() AndroidMkData {
-	androidMkData := AndroidMkData{
-		Class:      "FAKE",
-		Include:    "$(BUILD_SYSTEM)/suite_host_config.mk",
-		OutputFile: OptionalPathForPath(me.OutputFilePath),
-	}
-	androidMkData.Extra = []AndroidMkExtraFunc{
-		func(w io.Writer, outputFile Path) {
-			if me.properties.Test_config != nil {
-				fmt.Fprintf(w, "LOCAL_TEST_CONFIG := %s\n",
-					*me.properties.Test_config)
-			}
-			fmt.Fprintln(w, "LOCAL_COMPATIBILITY_SUITE := csuite")
-		},
-	}
-	return androidMkData
-}
+type CSuiteConfig = TestSuiteConfig
 
 func InitCSuiteConfigModule(me *CSuiteConfig) {
-	me.AddProperties(&me.properties)
+	InitTestSuiteConfigModule(me, csuiteConfigParams)
 }
 
 // csuite_config generates an App Compatibility Test Suite (C-Suite) configuration file from the
-// <test_config> xml file and stores it in a subdirectory of $(HOST_OUT).
+// <test_config> xml file and stores it in a subdirectory of $(HOST_OUT).  Instead of a
+// hand-written <test_config>, the test config can be generated from a template with filters,
+// target preparers and placeholder values set by the module, and a test config can be generated
+// for each app listed in an app list file.  The android_app modules listed in apps are built with
+// the config, and their APKs can be copied into the suite.  Other modules can reference the test
+// configs with ":<name>{.config}" and the copied APKs with ":<name>{.apks}".
 func CSuiteConfigFactory() Module {
-	module := &CSuiteConfig{}
-	InitCSuiteConfigModule(module)
-	InitAndroidArchModule(module, HostSupported, MultilibFirst)
-	return module
+	return TestSuiteConfigFactory(csuiteConfigParams)()
 }
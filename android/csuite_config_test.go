@@ -15,20 +15,36 @@
 package android
 
 import (
+	"bytes"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/google/blueprint"
+)
+
+var csuiteConfigFixtureFactory = NewFixtureFactory(
+	&buildDir,
+	PrepareForTestWithArchMutator,
+	FixtureRegisterModuleType("csuite_config", CSuiteConfigFactory),
 )
 
 func testCSuiteConfig(test *testing.T, bpFileContents string) *TestContext {
-	config := TestArchConfig(buildDir, nil, bpFileContents, nil)
+	return testCSuiteConfigWithFs(test, bpFileContents, nil)
+}
+
+func testCSuiteConfigWithFs(test *testing.T, bpFileContents string, fs map[string][]byte) *TestContext {
+	test.Helper()
+	return csuiteConfigFixtureFactory.RunTest(test,
+		FixtureWithRootAndroidBp(bpFileContents),
+		FixtureMergeMockFs(fs),
+	).TestContext
+}
 
-	ctx := NewTestArchContext()
-	ctx.RegisterModuleType("csuite_config", CSuiteConfigFactory)
-	ctx.Register(config)
-	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
-	FailIfErrored(test, errs)
-	_, errs = ctx.PrepareBuildActions(config)
-	FailIfErrored(test, errs)
-	return ctx
+func testCSuiteConfigError(test *testing.T, pattern, bpFileContents string) {
+	test.Helper()
+	csuiteConfigFixtureFactory.RunTestExpectingError(test, pattern,
+		FixtureWithRootAndroidBp(bpFileContents))
 }
 
 func TestCSuiteConfig(t *testing.T) {
@@ -47,3 +63,355 @@ csuite_config { name: "with_manifest", test_config: "manifest.xml" }
 		t.Errorf("expected plain, got %q", expectedOutputFilename)
 	}
 }
+
+func TestCSuiteConfigGenerated(t *testing.T) {
+	ctx := testCSuiteConfigWithFs(t, `
+csuite_config {
+	name: "generated",
+	test_config_template: "template.xml",
+	include_filters: ["CSuiteTest"],
+	exclude_filters: ["CSuiteTest#flaky"],
+	target_preparers: ["com.android.tradefed.targetprep.InstallApkSetup"],
+	template_values: ["PACKAGE=com.example.app&co"],
+}
+csuite_config {
+	name: "default_template",
+	include_filters: ["CSuiteTest"],
+}
+`, map[string][]byte{
+		"template.xml": nil,
+	})
+
+	variants := ctx.ModuleVariantsForTests("generated")
+	module := ctx.ModuleForTests("generated", variants[0])
+	rule := module.Rule("suiteTestConfig")
+	if rule.Args["template"] != "template.xml" {
+		t.Errorf("expected template template.xml, got %q", rule.Args["template"])
+	}
+	for _, expected := range []string{
+		`s&{MODULE}&generated&g`,
+		`<option name="include-filter" value="CSuiteTest" />`,
+		`<option name="exclude-filter" value="CSuiteTest#flaky" />`,
+		`<target_preparer class="com.android.tradefed.targetprep.InstallApkSetup" />`,
+		`s&{PACKAGE}&com.example.app\&amp;co&g`,
+	} {
+		if !strings.Contains(rule.Args["script"], expected) {
+			t.Errorf("expected script to contain %q, got %q", expected, rule.Args["script"])
+		}
+	}
+	// Only GNU sed supports \n in the replacement.
+	if strings.Contains(rule.Args["script"], `\n`) {
+		t.Errorf("expected script without \\n, got %q", rule.Args["script"])
+	}
+	testConfig := module.Module().(*CSuiteConfig).testConfig
+	if testConfig == nil || testConfig.Base() != "generated.xml" {
+		t.Errorf("expected the generated test config generated.xml, got %v", testConfig)
+	}
+
+	variants = ctx.ModuleVariantsForTests("default_template")
+	rule = ctx.ModuleForTests("default_template", variants[0]).Rule("suiteTestConfig")
+	if rule.Args["template"] != "${CSuiteTestConfigTemplate}" {
+		t.Errorf("expected the default template, got %q", rule.Args["template"])
+	}
+}
+
+func TestCSuiteConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name:  "test config and generated config",
+			bp:    `csuite_config { name: "foo", test_config: "manifest.xml", include_filters: ["CSuiteTest"] }`,
+			error: `"test_config": can't be set together with the properties of a generated test config`,
+		},
+		{
+			name:  "missing value",
+			bp:    `csuite_config { name: "foo", template_values: ["PACKAGE"] }`,
+			error: `invalid entry "PACKAGE", must be <KEY>=<value>`,
+		},
+		{
+			name:  "invalid key",
+			bp:    `csuite_config { name: "foo", template_values: ["package=com.example.app"] }`,
+			error: `invalid key "package", must be upper case`,
+		},
+		{
+			name:  "reserved key",
+			bp:    `csuite_config { name: "foo", template_values: ["MODULE=bar"] }`,
+			error: `key "MODULE" is set by the build`,
+		},
+		{
+			name:  "missing app list",
+			bp:    `csuite_config { name: "foo", app_list: "apps.txt" }`,
+			error: `file "apps.txt" does not exist`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCSuiteConfigError(t, testCase.error, testCase.bp)
+		})
+	}
+}
+
+func TestParseCSuiteAppList(t *testing.T) {
+	apps, err := parseSuiteConfigAppList([]byte(`
+# Top apps
+com.example.app
+prebuilts/apps/Other.apk
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []suiteConfigApp{
+		{name: "com.example.app", packageName: "com.example.app"},
+		{name: "Other", apk: "prebuilts/apps/Other.apk"},
+	}
+	if !reflect.DeepEqual(apps, expected) {
+		t.Errorf("expected %#v, got %#v", expected, apps)
+	}
+
+	for _, testCase := range []struct {
+		list  string
+		error string
+	}{
+		{"com.example.app\nnot a package\n", `line 2: "not a package" is neither a package name nor an APK path`},
+		{"com.example.app\ncom.example.app\n", `line 2: app "com.example.app" is listed more than once`},
+	} {
+		_, err := parseSuiteConfigAppList([]byte(testCase.list))
+		if err == nil || err.Error() != testCase.error {
+			t.Errorf("expected error %q, got %v", testCase.error, err)
+		}
+	}
+}
+
+func TestCSuiteConfigAppList(t *testing.T) {
+	ctx := testCSuiteConfigWithFs(t, `
+csuite_config {
+	name: "apps",
+	app_list: "apps.txt",
+	include_filters: ["CSuiteTest"],
+}
+`, map[string][]byte{
+		"apps.txt": []byte("com.example.app\nprebuilts/apps/Other.apk\n"),
+	})
+
+	variants := ctx.ModuleVariantsForTests("apps")
+	module := ctx.ModuleForTests("apps", variants[0])
+
+	app := module.Output("apps/apps_com.example.app.config")
+	for _, expected := range []string{
+		`s&{PACKAGE}&com.example.app&g`,
+		`s&{APK}&&g`,
+		`<option name="include-filter" value="CSuiteTest" />`,
+	} {
+		if !strings.Contains(app.Args["script"], expected) {
+			t.Errorf("expected script to contain %q, got %q", expected, app.Args["script"])
+		}
+	}
+
+	apk := module.Output("apps/apps_Other.config")
+	if !strings.Contains(apk.Args["script"], `s&{APK}&prebuilts/apps/Other.apk&g`) {
+		t.Errorf("expected script to set the APK path, got %q", apk.Args["script"])
+	}
+
+	csuiteConfig := module.Module().(*CSuiteConfig)
+	if len(csuiteConfig.appTestConfigs) != 2 {
+		t.Errorf("expected 2 app test configs, got %v", csuiteConfig.appTestConfigs)
+	}
+	if csuiteConfig.testConfig != nil {
+		t.Errorf("expected no test config for the module, got %v", csuiteConfig.testConfig)
+	}
+}
+
+type csuiteTestApp struct {
+	ModuleBase
+	apk Path
+}
+
+func csuiteTestAppFactory() Module {
+	m := &csuiteTestApp{}
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+func (m *csuiteTestApp) GenerateAndroidBuildActions(ctx ModuleContext) {
+	apk := PathForModuleOut(ctx, ctx.ModuleName()+".apk")
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: apk,
+	})
+	m.apk = apk
+}
+
+func (m *csuiteTestApp) OutputFile() Path {
+	return m.apk
+}
+
+type csuiteTestLibrary struct {
+	ModuleBase
+}
+
+func csuiteTestLibraryFactory() Module {
+	m := &csuiteTestLibrary{}
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+func (m *csuiteTestLibrary) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+var csuiteConfigAppsFixtureFactory = csuiteConfigFixtureFactory.Extend(
+	FixtureRegisterModuleType("android_app", csuiteTestAppFactory),
+	FixtureRegisterModuleType("java_library", csuiteTestLibraryFactory),
+)
+
+func TestCSuiteConfigApps(t *testing.T) {
+	result := csuiteConfigAppsFixtureFactory.RunTestWithBp(t, `
+		csuite_config {
+			name: "apps",
+			apps: ["App1", "App2"],
+		}
+
+		csuite_config {
+			name: "copied_apps",
+			apps: ["App1"],
+			copy_apks: true,
+		}
+
+		android_app {
+			name: "App1",
+		}
+
+		android_app {
+			name: "App2",
+		}
+	`)
+
+	variants := result.ModuleVariantsForTests("apps")
+	apps := result.ModuleForTests("apps", variants[0])
+	var deps []string
+	result.VisitDirectDeps(apps.Module(), func(dep blueprint.Module) {
+		if _, ok := dep.(*csuiteTestApp); ok {
+			deps = append(deps, result.ModuleName(dep))
+		}
+	})
+	if expected := []string{"App1", "App2"}; !reflect.DeepEqual(deps, expected) {
+		t.Errorf("expected app dependencies %q, got %q", expected, deps)
+	}
+	if apks := apps.Module().(*CSuiteConfig).appApks; len(apks) != 0 {
+		t.Errorf("expected no copied APKs without copy_apks, got %v", apks)
+	}
+
+	variants = result.ModuleVariantsForTests("copied_apps")
+	copiedApps := result.ModuleForTests("copied_apps", variants[0])
+	app1 := result.ModuleForTests("App1", "android_common").Module().(*csuiteTestApp)
+	copyApk := copiedApps.Output("apks/App1.apk")
+	if copyApk.Input.String() != app1.apk.String() {
+		t.Errorf("expected APK to be copied from %s, got %s", app1.apk, copyApk.Input)
+	}
+
+	data := AndroidMkDataForTest(t, result.Config, "", copiedApps.Module())
+	var buf bytes.Buffer
+	for _, extra := range data.Extra {
+		extra(&buf, nil)
+	}
+	expected := "LOCAL_COMPATIBILITY_SUPPORT_FILES += " + copyApk.Output.String() + ":App1.apk\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %q in the AndroidMk output, got %q", expected, buf.String())
+	}
+}
+
+func TestCSuiteConfigOutputFiles(t *testing.T) {
+	result := csuiteConfigAppsFixtureFactory.Extend(
+		FixtureRegisterModuleType("filegroup", FileGroupFactory),
+		FixtureMergeMockFs(map[string][]byte{
+			"manifest.xml": nil,
+			"apps.txt":     []byte("com.example.app\n"),
+		}),
+	).RunTestWithBp(t, `
+		csuite_config {
+			name: "generated",
+			include_filters: ["CSuiteTest"],
+			apps: ["App1"],
+			copy_apks: true,
+		}
+
+		csuite_config {
+			name: "with_manifest",
+			test_config: "manifest.xml",
+		}
+
+		csuite_config {
+			name: "app_list",
+			app_list: "apps.txt",
+		}
+
+		android_app {
+			name: "App1",
+		}
+
+		filegroup {
+			name: "configs",
+			srcs: [":generated{.config}", ":with_manifest{.config}", ":app_list{.config}"],
+		}
+
+		filegroup {
+			name: "apks",
+			srcs: [":generated{.apks}"],
+		}
+	`)
+
+	generated := result.ModuleForTests("generated", result.ModuleVariantsForTests("generated")[0])
+	appList := result.ModuleForTests("app_list", result.ModuleVariantsForTests("app_list")[0])
+
+	configs := result.ModuleForTests("configs", "").Module().(*fileGroup).Srcs()
+	expected := []string{
+		generated.Output("generated.xml").Output.String(),
+		"manifest.xml",
+		appList.Output("apps/app_list_com.example.app.config").Output.String(),
+	}
+	if got := configs.Strings(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected configs %q, got %q", expected, got)
+	}
+
+	apks := result.ModuleForTests("apks", "").Module().(*fileGroup).Srcs()
+	expected = []string{generated.Output("apks/App1.apk").Output.String()}
+	if got := apks.Strings(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected APKs %q, got %q", expected, got)
+	}
+
+	outputFile, err := generated.Module().(*CSuiteConfig).OutputFiles("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(outputFile) != 1 || outputFile[0].Base() != "generated" {
+		t.Errorf("expected the suite config file generated, got %q", outputFile)
+	}
+
+	if _, err := generated.Module().(*CSuiteConfig).OutputFiles(".plan"); err == nil {
+		t.Errorf("expected an error for an unsupported tag")
+	}
+}
+
+func TestCSuiteConfigAppsErrors(t *testing.T) {
+	csuiteConfigAppsFixtureFactory.RunTestExpectingError(t, `depends on undefined module "Missing"`,
+		FixtureWithRootAndroidBp(`
+			csuite_config {
+				name: "apps",
+				apps: ["Missing"],
+			}
+		`))
+
+	csuiteConfigAppsFixtureFactory.RunTestExpectingError(t, `module "NotAnApp" is not an android_app`,
+		FixtureWithRootAndroidBp(`
+			csuite_config {
+				name: "apps",
+				apps: ["NotAnApp"],
+			}
+
+			java_library {
+				name: "NotAnApp",
+			}
+		`))
+}
@@ -17,6 +17,8 @@ package android
 import (
 	"github.com/google/blueprint"
 	_ "github.com/google/blueprint/bootstrap"
+
+	"android/soong/shared"
 )
 
 var (
@@ -59,6 +61,48 @@ var (
 		},
 		"cpFlags")
 
+	// Copy and symlink rules that run in an sbox sandbox that only contains the input, used for
+	// rules that write into the install directories when SOONG_SANDBOX_INSTALL=true.  The rspfile
+	// is the input manifest that sbox uses to populate the sandbox, and outDir is not cleaned by
+	// sbox as it is shared with other rules.  inputs is $in relative to the source tree, which is
+	// where the input sandbox mirrors them.
+	sboxCp = pctx.AndroidStaticRule("sboxCp",
+		blueprint.RuleParams{
+			Command: "rm -f $out && ${sbox} --sandbox-path ${sboxSandboxPath} " +
+				"--output-root $outDir --keep-output-root --input-manifest $manifest " +
+				"-c 'cp $cpPreserveSymlinks $cpFlags $inputs __SBOX_OUT_DIR__/$outName' __SBOX_OUT_DIR__/$outName",
+			CommandDeps:    []string{"${sbox}"},
+			Rspfile:        "$manifest",
+			RspfileContent: "$inputs",
+			Description:    "cp $out",
+		},
+		"cpFlags", "inputs", "outDir", "outName", "manifest")
+
+	sboxCpExecutable = pctx.AndroidStaticRule("sboxCpExecutable",
+		blueprint.RuleParams{
+			Command: "rm -f $out && ${sbox} --sandbox-path ${sboxSandboxPath} " +
+				"--output-root $outDir --keep-output-root --input-manifest $manifest " +
+				"-c 'cp $cpPreserveSymlinks $cpFlags $inputs __SBOX_OUT_DIR__/$outName && " +
+				"chmod +x __SBOX_OUT_DIR__/$outName' __SBOX_OUT_DIR__/$outName",
+			CommandDeps:    []string{"${sbox}"},
+			Rspfile:        "$manifest",
+			RspfileContent: "$inputs",
+			Description:    "cp $out",
+		},
+		"cpFlags", "inputs", "outDir", "outName", "manifest")
+
+	sboxSymlink = pctx.AndroidStaticRule("sboxSymlink",
+		blueprint.RuleParams{
+			Command: "rm -f $out && ${sbox} --sandbox-path ${sboxSandboxPath} " +
+				"--output-root $outDir --keep-output-root --input-manifest $manifest " +
+				"-c 'ln -f -s $fromPath __SBOX_OUT_DIR__/$outName' __SBOX_OUT_DIR__/$outName",
+			CommandDeps:    []string{"${sbox}"},
+			Rspfile:        "$manifest",
+			RspfileContent: "$inputs",
+			Description:    "symlink $out",
+		},
+		"fromPath", "inputs", "outDir", "outName", "manifest")
+
 	// A timestamp touch rule.
 	Touch = pctx.AndroidStaticRule("Touch",
 		blueprint.RuleParams{
@@ -107,6 +151,19 @@ var (
 	highmemPool = blueprint.NewBuiltinPool("highmem_pool")
 )
 
+// sandboxedInstallRules maps the rules used to install and package files to the equivalent rules
+// that run in an sbox sandbox.
+var sandboxedInstallRules = map[blueprint.Rule]blueprint.Rule{
+	Cp:           sboxCp,
+	CpExecutable: sboxCpExecutable,
+	Symlink:      sboxSymlink,
+}
+
 func init() {
 	pctx.Import("github.com/google/blueprint/bootstrap")
+
+	pctx.HostBinToolVariable("sbox", "sbox")
+	pctx.VariableFunc("sboxSandboxPath", func(ctx PackageVarContext) string {
+		return shared.TempDirForOutDir(PathForOutput(ctx).String())
+	})
 }
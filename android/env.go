@@ -33,6 +33,10 @@ var originalEnv map[string]string
 var SoongDelveListen string
 var SoongDelvePath string
 
+// SoongMipsToolchains is true when SOONG_MIPS_TOOLCHAINS=true. The mips toolchains are
+// registered during init(), so it has to be read before NewConfig.
+var SoongMipsToolchains bool
+
 func init() {
 	// Delve support needs to read this environment variable very early, before NewConfig has created a way to
 	// access originalEnv with dependencies.  Store the value where soong_build can find it, it will manually
@@ -40,6 +44,8 @@ func init() {
 	SoongDelveListen = os.Getenv("SOONG_DELVE")
 	SoongDelvePath, _ = exec.LookPath("dlv")
 
+	SoongMipsToolchains = os.Getenv("SOONG_MIPS_TOOLCHAINS") == "true"
+
 	originalEnv = make(map[string]string)
 	for _, env := range os.Environ() {
 		idx := strings.IndexRune(env, '=')
@@ -15,6 +15,7 @@
 package android
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"text/template"
@@ -39,6 +40,21 @@ type fileGroupProperties struct {
 	// Create a make variable with the specified name that contains the list of files in the
 	// filegroup, relative to the root of the source tree.
 	Export_to_make_var *string
+
+	// renames lists files in srcs that are exposed to other modules under a different relative
+	// path, which replaces the need for a genrule that copies the file to rename it.
+	Renames []FileGroupRename
+}
+
+// FileGroupRename is the configuration for exposing a file in a filegroup under a different
+// relative path.
+type FileGroupRename struct {
+	// the path of the file as it would otherwise be exposed by the filegroup, i.e. relative to
+	// path if it is set, or relative to the module directory or the module that produced it.
+	Src *string
+
+	// the relative path that the file is exposed under.
+	Dest *string
 }
 
 type fileGroup struct {
@@ -51,7 +67,8 @@ var _ SourceFileProducer = (*fileGroup)(nil)
 
 // filegroup contains a list of files that are referenced by other modules
 // properties (such as "srcs") using the syntax ":<name>". filegroup are
-// also be used to export files across package boundaries.
+// also be used to export files across package boundaries.  The renames
+// property exposes files to other modules under a different relative path.
 func FileGroupFactory() Module {
 	module := &fileGroup{}
 	module.AddProperties(&module.properties)
@@ -65,6 +82,79 @@ func (fg *fileGroup) GenerateAndroidBuildActions(ctx ModuleContext) {
 	if fg.properties.Path != nil {
 		fg.srcs = PathsWithModuleSrcSubDir(ctx, fg.srcs, String(fg.properties.Path))
 	}
+
+	if len(fg.properties.Renames) > 0 {
+		fg.srcs = fg.renameSrcs(ctx)
+	}
+}
+
+// renameSrcs returns the srcs of the filegroup with each file listed in the renames property
+// replaced by a copy of the file whose relative path is the dest of the rename.
+func (fg *fileGroup) renameSrcs(ctx ModuleContext) Paths {
+	srcs := append(Paths{}, fg.srcs...)
+	renamed := make(map[string]bool)
+	dests := make(map[string]bool)
+	for i, rename := range fg.properties.Renames {
+		property := fmt.Sprintf("renames[%d]", i)
+		src, dest := String(rename.Src), String(rename.Dest)
+		if src == "" {
+			ctx.PropertyErrorf(property+".src", "must be set")
+			continue
+		}
+		if dest == "" {
+			ctx.PropertyErrorf(property+".dest", "must be set")
+			continue
+		}
+		if _, err := validateSafePath(dest); err != nil {
+			ctx.PropertyErrorf(property+".dest", "%s", err)
+			continue
+		}
+		if renamed[src] {
+			ctx.PropertyErrorf(property+".src", "%q is renamed more than once", src)
+			continue
+		}
+		renamed[src] = true
+
+		index := indexPathListByRel(src, srcs)
+		if index == -1 {
+			ctx.PropertyErrorf(property+".src", "%q is not in srcs", src)
+			continue
+		}
+
+		out := PathForModuleOut(ctx, "renamed").Join(ctx, dest)
+		ctx.Build(pctx, BuildParams{
+			Rule:   Cp,
+			Input:  srcs[index],
+			Output: out,
+		})
+		srcs[index] = out
+		dests[out.Rel()] = true
+	}
+
+	// Renaming a file to the relative path of another file would make the two indistinguishable to
+	// consumers that install files by their relative paths.
+	count := make(map[string]int)
+	for _, src := range srcs {
+		count[src.Rel()]++
+	}
+	for _, dest := range SortedStringKeys(dests) {
+		if count[dest] > 1 {
+			ctx.PropertyErrorf("renames", "more than one file is exposed as %q", dest)
+		}
+	}
+
+	return srcs
+}
+
+// indexPathListByRel returns the index of the first path in list whose relative path is rel, or -1
+// if there is none.
+func indexPathListByRel(rel string, list Paths) int {
+	for i, path := range list {
+		if path.Rel() == rel {
+			return i
+		}
+	}
+	return -1
 }
 
 func (fg *fileGroup) Srcs() Paths {
@@ -0,0 +1,141 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"reflect"
+	"testing"
+)
+
+var fileGroupFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterModuleType("filegroup", FileGroupFactory),
+	FixtureMergeMockFs(map[string][]byte{
+		"a.txt":     nil,
+		"sub/b.txt": nil,
+		"dir/c.txt": nil,
+	}),
+)
+
+func TestFileGroupRenames(t *testing.T) {
+	result := fileGroupFixtureFactory.RunTestWithBp(t, `
+		filegroup {
+			name: "foo",
+			srcs: ["a.txt", "sub/b.txt", ":bar"],
+			renames: [
+				{
+					src: "a.txt",
+					dest: "x/renamed_a.txt",
+				},
+				{
+					src: "c.txt",
+					dest: "d.txt",
+				},
+			],
+		}
+
+		filegroup {
+			name: "bar",
+			srcs: ["dir/c.txt"],
+			path: "dir",
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "")
+	srcs := foo.Module().(*fileGroup).Srcs()
+
+	renamedA := foo.Output("x/renamed_a.txt")
+	renamedC := foo.Output("d.txt")
+
+	if g, w := srcs.Strings(), []string{renamedA.Output.String(), "sub/b.txt", renamedC.Output.String()}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want srcs %q, got %q", w, g)
+	}
+
+	var rels []string
+	for _, src := range srcs {
+		rels = append(rels, src.Rel())
+	}
+	if g, w := rels, []string{"x/renamed_a.txt", "sub/b.txt", "d.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want rels %q, got %q", w, g)
+	}
+
+	if g, w := renamedA.Input.String(), "a.txt"; g != w {
+		t.Errorf("want renamed_a.txt to be copied from %q, got %q", w, g)
+	}
+	if g, w := renamedC.Input.String(), "dir/c.txt"; g != w {
+		t.Errorf("want d.txt to be copied from %q, got %q", w, g)
+	}
+}
+
+func TestFileGroupRenamesErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "missing src",
+			bp: `
+				filegroup {
+					name: "foo",
+					srcs: ["a.txt"],
+					renames: [{src: "b.txt", dest: "c.txt"}],
+				}
+			`,
+			err: `renames\[0\]\.src: "b.txt" is not in srcs`,
+		},
+		{
+			name: "dest outside directory",
+			bp: `
+				filegroup {
+					name: "foo",
+					srcs: ["a.txt"],
+					renames: [{src: "a.txt", dest: "../c.txt"}],
+				}
+			`,
+			err: `renames\[0\]\.dest: Path is outside directory`,
+		},
+		{
+			name: "renamed twice",
+			bp: `
+				filegroup {
+					name: "foo",
+					srcs: ["a.txt"],
+					renames: [
+						{src: "a.txt", dest: "b.txt"},
+						{src: "a.txt", dest: "c.txt"},
+					],
+				}
+			`,
+			err: `renames\[1\]\.src: "a.txt" is renamed more than once`,
+		},
+		{
+			name: "dest conflicts",
+			bp: `
+				filegroup {
+					name: "foo",
+					srcs: ["a.txt", "sub/b.txt"],
+					renames: [{src: "a.txt", dest: "sub/b.txt"}],
+				}
+			`,
+			err: `renames: more than one file is exposed as "sub/b.txt"`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			fileGroupFixtureFactory.RunTestExpectingError(t, test.err, FixtureWithRootAndroidBp(test.bp))
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+// Provides support for creating test fixtures on which tests can be run.
+//
+// A test fixture is made up of the module types, singletons and mutators registered with the test
+// context, the contents of the mock file system, including the root Android.bp file, the
+// environment and any changes made to the config.  Each part is contributed by a FixturePreparer,
+// and preparers can be grouped together so that a module package can share a single preparer,
+// e.g.
+//
+//   var PrepareForTestWithFooBuildComponents = android.GroupFixturePreparers(
+//       android.PrepareForTestWithArchMutator,
+//       android.FixtureRegisterWithContext(RegisterFooBuildComponents),
+//   )
+//
+// A FixtureFactory combines the preparers that are common to a set of tests and runs each test
+// with any additional preparers the test needs, e.g.
+//
+//   var fooFixtureFactory = android.NewFixtureFactory(&buildDir, PrepareForTestWithFooBuildComponents)
+//
+//   func TestFoo(t *testing.T) {
+//       result := fooFixtureFactory.RunTest(t,
+//           android.FixtureWithRootAndroidBp(`foo { name: "foo" }`),
+//           android.FixtureAddTextFile("foo/input.txt", "input"),
+//       )
+//       foo := result.ModuleForTests("foo", "android_common")
+//       ...
+//   }
+//
+// Preparers are applied in order, so a later preparer can override the file contents set by an
+// earlier one.
+
+// FixturePreparer contributes to a test fixture.
+type FixturePreparer interface {
+	prepare(f *fixture)
+}
+
+type fixturePreparerFunc func(f *fixture)
+
+func (p fixturePreparerFunc) prepare(f *fixture) {
+	p(f)
+}
+
+type fixturePreparers []FixturePreparer
+
+func (p fixturePreparers) prepare(f *fixture) {
+	for _, preparer := range p {
+		preparer.prepare(f)
+	}
+}
+
+// GroupFixturePreparers returns a FixturePreparer that applies each of preparers in order.
+func GroupFixturePreparers(preparers ...FixturePreparer) FixturePreparer {
+	return fixturePreparers(append([]FixturePreparer(nil), preparers...))
+}
+
+// FixtureRegisterWithContext returns a FixturePreparer that registers build components with the
+// test context, e.g. FixtureRegisterWithContext(RegisterPackageBuildComponents).
+func FixtureRegisterWithContext(register func(ctx RegistrationContext)) FixturePreparer {
+	return fixturePreparerFunc(func(f *fixture) {
+		f.registrations = append(f.registrations, register)
+	})
+}
+
+// FixtureRegisterModuleType returns a FixturePreparer that registers a single module type with the
+// test context.
+func FixtureRegisterModuleType(name string, factory ModuleFactory) FixturePreparer {
+	return FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType(name, factory)
+	})
+}
+
+// FixtureWithRootAndroidBp returns a FixturePreparer that sets the contents of the root Android.bp
+// file.
+func FixtureWithRootAndroidBp(contents string) FixturePreparer {
+	return FixtureAddTextFile("Android.bp", contents)
+}
+
+// FixtureAddTextFile returns a FixturePreparer that adds a file with the given contents to the mock
+// file system.
+func FixtureAddTextFile(path string, contents string) FixturePreparer {
+	return FixtureMergeMockFs(map[string][]byte{path: []byte(contents)})
+}
+
+// FixtureMergeMockFs returns a FixturePreparer that adds the files in fs to the mock file system,
+// replacing any existing files with the same paths.
+func FixtureMergeMockFs(fs map[string][]byte) FixturePreparer {
+	return fixturePreparerFunc(func(f *fixture) {
+		for path, contents := range fs {
+			f.fs[path] = contents
+		}
+	})
+}
+
+// FixtureMergeEnv returns a FixturePreparer that adds the variables in env to the environment of
+// the config.
+func FixtureMergeEnv(env map[string]string) FixturePreparer {
+	return fixturePreparerFunc(func(f *fixture) {
+		for k, v := range env {
+			f.env[k] = v
+		}
+	})
+}
+
+// FixtureModifyConfig returns a FixturePreparer that calls modify with the config after it has
+// been created and before the test context is registered, e.g. to change
+// config.TestProductVariables.
+func FixtureModifyConfig(modify func(config Config)) FixturePreparer {
+	return fixturePreparerFunc(func(f *fixture) {
+		f.configModifiers = append(f.configModifiers, modify)
+	})
+}
+
+// PrepareForTestWithArchMutator creates the config with TestArchConfig and registers the arch
+// mutator, so that modules are split into their arch variants.
+var PrepareForTestWithArchMutator FixturePreparer = fixturePreparerFunc(func(f *fixture) {
+	f.arch = true
+})
+
+// fixture holds the state contributed by the FixturePreparers of a test.
+type fixture struct {
+	fs              map[string][]byte
+	env             map[string]string
+	arch            bool
+	registrations   []func(ctx RegistrationContext)
+	configModifiers []func(config Config)
+}
+
+// FixtureFactory runs tests against fixtures made up of its own preparers and the preparers passed
+// to each test.
+type FixtureFactory interface {
+	// Extend returns a FixtureFactory that applies preparers after the preparers of this one.
+	Extend(preparers ...FixturePreparer) FixtureFactory
+
+	// RunTest creates a fixture from the preparers, then parses the Android.bp files and prepares
+	// the build actions, failing the test if there are any errors.
+	RunTest(t *testing.T, preparers ...FixturePreparer) *TestResult
+
+	// RunTestWithBp is RunTest with FixtureWithRootAndroidBp(bp).
+	RunTestWithBp(t *testing.T, bp string) *TestResult
+
+	// RunTestExpectingError is RunTest, except that the test fails unless parsing or preparing
+	// the build actions reports an error that matches pattern.
+	RunTestExpectingError(t *testing.T, pattern string, preparers ...FixturePreparer) *TestResult
+}
+
+// NewFixtureFactory returns a FixtureFactory with the given preparers.  buildDirSupplier points at
+// the build directory of the tests, which is usually only set once TestMain has run.
+func NewFixtureFactory(buildDirSupplier *string, preparers ...FixturePreparer) FixtureFactory {
+	return &fixtureFactory{
+		buildDirSupplier: buildDirSupplier,
+		preparers:        append([]FixturePreparer(nil), preparers...),
+	}
+}
+
+type fixtureFactory struct {
+	buildDirSupplier *string
+	preparers        []FixturePreparer
+}
+
+func (f *fixtureFactory) Extend(preparers ...FixturePreparer) FixtureFactory {
+	all := append([]FixturePreparer(nil), f.preparers...)
+	return NewFixtureFactory(f.buildDirSupplier, append(all, preparers...)...)
+}
+
+func (f *fixtureFactory) RunTest(t *testing.T, preparers ...FixturePreparer) *TestResult {
+	t.Helper()
+	result, errs := f.run(preparers)
+	FailIfErrored(t, errs)
+	return result
+}
+
+func (f *fixtureFactory) RunTestWithBp(t *testing.T, bp string) *TestResult {
+	t.Helper()
+	return f.RunTest(t, FixtureWithRootAndroidBp(bp))
+}
+
+func (f *fixtureFactory) RunTestExpectingError(t *testing.T, pattern string,
+	preparers ...FixturePreparer) *TestResult {
+
+	t.Helper()
+	result, errs := f.run(preparers)
+	FailIfNoMatchingErrors(t, pattern, errs)
+	return result
+}
+
+// run creates the fixture and returns the errors from parsing the Android.bp files or, if there
+// were none, from preparing the build actions.
+func (f *fixtureFactory) run(preparers []FixturePreparer) (*TestResult, []error) {
+	fixture := &fixture{
+		fs: map[string][]byte{
+			"Android.bp": nil,
+		},
+		env: map[string]string{},
+	}
+	GroupFixturePreparers(f.preparers...).prepare(fixture)
+	GroupFixturePreparers(preparers...).prepare(fixture)
+
+	var config Config
+	var ctx *TestContext
+	if fixture.arch {
+		config = TestArchConfig(*f.buildDirSupplier, fixture.env, "", fixture.fs)
+		ctx = NewTestArchContext()
+	} else {
+		config = TestConfig(*f.buildDirSupplier, fixture.env, "", fixture.fs)
+		ctx = NewTestContext()
+	}
+
+	for _, modify := range fixture.configModifiers {
+		modify(config)
+	}
+	for _, register := range fixture.registrations {
+		register(ctx)
+	}
+	ctx.Register(config)
+
+	result := &TestResult{
+		TestContext: ctx,
+		Config:      config,
+	}
+
+	_, errs := ctx.ParseBlueprintsFiles("Android.bp")
+	if len(errs) > 0 {
+		return result, errs
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	return result, errs
+}
+
+// TestResult is the result of running a test against a fixture.
+type TestResult struct {
+	*TestContext
+
+	Config Config
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fixtureTestModule struct {
+	ModuleBase
+	properties struct {
+		Srcs []string
+	}
+
+	srcs Paths
+}
+
+func fixtureTestModuleFactory() Module {
+	m := &fixtureTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, HostAndDeviceSupported, MultilibCommon)
+	return m
+}
+
+func (m *fixtureTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.srcs = PathsForModuleSrc(ctx, m.properties.Srcs)
+	if ctx.Config().Getenv("FIXTURE_TEST_FAIL") == "true" {
+		ctx.ModuleErrorf("failed because FIXTURE_TEST_FAIL is set")
+	}
+}
+
+var prepareForFixtureTest = GroupFixturePreparers(
+	PrepareForTestWithArchMutator,
+	FixtureRegisterModuleType("fixture_test", fixtureTestModuleFactory),
+)
+
+var fixtureTestFactory = NewFixtureFactory(&buildDir, prepareForFixtureTest)
+
+func TestFixture(t *testing.T) {
+	result := fixtureTestFactory.RunTest(t,
+		FixtureWithRootAndroidBp(`
+			fixture_test {
+				name: "foo",
+				srcs: ["a.txt"],
+			}
+		`),
+		FixtureAddTextFile("dir/Android.bp", `
+			fixture_test {
+				name: "bar",
+				srcs: ["b.txt"],
+			}
+		`),
+		FixtureModifyConfig(func(config Config) {
+			config.TestProductVariables.DeviceName = stringPtr("fixture_device")
+		}),
+	)
+
+	if g, w := result.Config.DeviceName(), "fixture_device"; g != w {
+		t.Errorf("expected device name %q, got %q", w, g)
+	}
+
+	foo := result.ModuleForTests("foo", "android_common").Module().(*fixtureTestModule)
+	if g, w := NormalizePathsForTesting(foo.srcs), []string{"a.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected foo srcs %q, got %q", w, g)
+	}
+
+	bar := result.ModuleForTests("bar", "android_common").Module().(*fixtureTestModule)
+	if g, w := NormalizePathsForTesting(bar.srcs), []string{"dir/b.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected bar srcs %q, got %q", w, g)
+	}
+}
+
+func TestFixtureExtend(t *testing.T) {
+	var order []string
+	record := func(name string) FixturePreparer {
+		return FixtureModifyConfig(func(config Config) {
+			order = append(order, name)
+		})
+	}
+
+	factory := NewFixtureFactory(&buildDir, prepareForFixtureTest, record("factory"))
+	extended := factory.Extend(record("extended"))
+
+	extended.RunTest(t,
+		FixtureWithRootAndroidBp(`fixture_test { name: "first" }`),
+		FixtureWithRootAndroidBp(`fixture_test { name: "second" }`),
+		GroupFixturePreparers(record("test"), record("group")),
+	)
+	if g, w := order, []string{"factory", "extended", "test", "group"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected preparers to be applied in order %q, got %q", w, g)
+	}
+
+	order = nil
+	factory.RunTestWithBp(t, `fixture_test { name: "foo" }`)
+	if g, w := order, []string{"factory"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected Extend not to modify the original factory, got preparers %q", g)
+	}
+}
+
+func TestFixtureExpectingError(t *testing.T) {
+	fixtureTestFactory.RunTestExpectingError(t, `failed because FIXTURE_TEST_FAIL is set`,
+		FixtureWithRootAndroidBp(`fixture_test { name: "foo" }`),
+		FixtureMergeEnv(map[string]string{"FIXTURE_TEST_FAIL": "true"}),
+	)
+
+	fixtureTestFactory.RunTestExpectingError(t, `unrecognized property "unknown"`,
+		FixtureWithRootAndroidBp(`fixture_test { name: "foo", unknown: true }`),
+	)
+}
@@ -0,0 +1,245 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+// The glob cache persists the results of every glob evaluated by soong_build, along with a
+// fingerprint of each directory the glob depended on, so that the next run of soong_build can
+// skip re-walking directories that have not changed.  A cached result is only used if every one
+// of its dependencies still has the fingerprint it had when the glob was evaluated, which is the
+// same set of directories that ninja uses to decide whether soong_build needs to rerun.
+
+const globCacheFileName = ".glob_cache.json"
+
+// globCacheVersion is stored in the cache file and must be incremented whenever the format of
+// the cache file or the meaning of its fingerprints changes.
+const globCacheVersion = 1
+
+type globCacheFile struct {
+	Version int
+	Entries []*globCacheEntry
+}
+
+type globCacheEntry struct {
+	Pattern  string
+	Excludes []string `json:",omitempty"`
+	Follow   bool
+
+	Matches []string `json:",omitempty"`
+	Deps    []string `json:",omitempty"`
+
+	// Fingerprints contains one fingerprint for each entry in Deps.
+	Fingerprints []globFingerprint `json:",omitempty"`
+}
+
+func (e *globCacheEntry) key() string {
+	return globCacheKey(e.Pattern, e.Excludes, pathtools.ShouldFollowSymlinks(e.Follow))
+}
+
+func globCacheKey(pattern string, excludes []string, follow pathtools.ShouldFollowSymlinks) string {
+	followString := "nofollow"
+	if follow {
+		followString = "follow"
+	}
+	return strings.Join(append([]string{pattern, followString}, excludes...), "\x00")
+}
+
+// globFingerprint identifies the state of a directory that a glob depended on.  A directory's
+// modification time changes whenever an entry is added to, removed from or renamed within it,
+// which is exactly the set of changes that can affect the result of a glob.
+type globFingerprint struct {
+	Exists  bool
+	ModTime int64 `json:",omitempty"`
+	Size    int64 `json:",omitempty"`
+}
+
+// globCacheFs is a pathtools.FileSystem that answers calls to Glob from a persisted cache when
+// possible, and passes all other calls through to the wrapped pathtools.FileSystem.
+type globCacheFs struct {
+	pathtools.FileSystem
+
+	lock sync.Mutex
+
+	// primed contains the entries loaded from the cache file at startup, which have not yet been
+	// validated.
+	primed map[string]*globCacheEntry
+
+	// entries contains the entries that have been used or evaluated during this run, and will be
+	// written out by save.
+	entries map[string]*globCacheEntry
+
+	hits, misses int
+}
+
+var _ pathtools.FileSystem = (*globCacheFs)(nil)
+
+// newGlobCacheFs returns a globCacheFs that wraps fs and is primed from the cache file at
+// cacheFile.  A missing, unreadable or out of date cache file results in an empty cache.
+func newGlobCacheFs(fs pathtools.FileSystem, cacheFile string) *globCacheFs {
+	c := &globCacheFs{
+		FileSystem: fs,
+		primed:     make(map[string]*globCacheEntry),
+		entries:    make(map[string]*globCacheEntry),
+	}
+
+	data, err := ioutil.ReadFile(absolutePath(cacheFile))
+	if err != nil {
+		return c
+	}
+
+	var file globCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != globCacheVersion {
+		return c
+	}
+
+	for _, entry := range file.Entries {
+		if len(entry.Fingerprints) == len(entry.Deps) {
+			c.primed[entry.key()] = entry
+		}
+	}
+
+	return c
+}
+
+func (c *globCacheFs) Glob(pattern string, excludes []string,
+	follow pathtools.ShouldFollowSymlinks) (matches, deps []string, err error) {
+
+	key := globCacheKey(pattern, excludes, follow)
+
+	c.lock.Lock()
+	entry := c.entries[key]
+	if entry == nil {
+		entry = c.primed[key]
+		delete(c.primed, key)
+	}
+	c.lock.Unlock()
+
+	if entry != nil && c.valid(entry) {
+		c.lock.Lock()
+		c.entries[key] = entry
+		c.hits++
+		c.lock.Unlock()
+		return CopyOf(entry.Matches), CopyOf(entry.Deps), nil
+	}
+
+	matches, deps, err = c.FileSystem.Glob(pattern, excludes, follow)
+	if err != nil {
+		return matches, deps, err
+	}
+
+	entry = &globCacheEntry{
+		Pattern:      pattern,
+		Excludes:     CopyOf(excludes),
+		Follow:       bool(follow),
+		Matches:      CopyOf(matches),
+		Deps:         CopyOf(deps),
+		Fingerprints: make([]globFingerprint, len(deps)),
+	}
+	for i, dep := range deps {
+		entry.Fingerprints[i] = c.fingerprint(dep)
+	}
+
+	c.lock.Lock()
+	c.entries[key] = entry
+	c.misses++
+	c.lock.Unlock()
+
+	return matches, deps, nil
+}
+
+// valid returns true if every dependency of the entry still has the fingerprint it had when the
+// entry was evaluated.
+func (c *globCacheFs) valid(entry *globCacheEntry) bool {
+	for i, dep := range entry.Deps {
+		if c.fingerprint(dep) != entry.Fingerprints[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *globCacheFs) fingerprint(path string) globFingerprint {
+	info, err := c.FileSystem.Stat(path)
+	if err != nil {
+		return globFingerprint{}
+	}
+	return globFingerprint{
+		Exists:  true,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+}
+
+// save writes the entries that were used or evaluated during this run to cacheFile.  Entries
+// that were loaded from the previous cache file but not used are dropped, so the cache does not
+// grow without bound as globs are removed from Android.bp files.
+func (c *globCacheFs) save(cacheFile string) error {
+	c.lock.Lock()
+	file := globCacheFile{
+		Version: globCacheVersion,
+		Entries: make([]*globCacheEntry, 0, len(c.entries)),
+	}
+	for _, key := range SortedStringKeys(c.entries) {
+		file.Entries = append(file.Entries, c.entries[key])
+	}
+	c.lock.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it into place so that an interrupted build never
+	// leaves a truncated cache file behind.
+	cacheFile = absolutePath(cacheFile)
+	tmpFile := cacheFile + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tmpFile, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, cacheFile)
+}
+
+// globCacheFileForConfig returns the path to the glob cache file for the given config.
+func globCacheFileForConfig(c *config) string {
+	return filepath.Join(c.buildDir, globCacheFileName)
+}
+
+// SaveGlobCache writes out the results of the globs evaluated during this run so that they can
+// be reused by the next run of soong_build.  It is a no-op if the glob cache is disabled.
+func (c *config) SaveGlobCache() error {
+	if cache, ok := c.fs.(*globCacheFs); ok {
+		return cache.save(globCacheFileForConfig(c))
+	}
+	return nil
+}
+
+// Fs returns the pathtools.FileSystem that should be used to access the source tree, which
+// includes the glob cache if it is enabled.
+func (c *config) Fs() pathtools.FileSystem {
+	return c.fs
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+func TestGlobCache(t *testing.T) {
+	root := filepath.Join(buildDir, "glob_cache_test")
+	cacheFile := filepath.Join(root, "out", globCacheFileName)
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile := func(name string) {
+		t.Helper()
+		path := filepath.Join(root, "src", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	glob := func(cache *globCacheFs, pattern string) []string {
+		t.Helper()
+		matches, _, err := cache.Glob(pattern, nil, pathtools.FollowSymlinks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	checkMatches := func(got []string, want ...string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected matches %q, got %q", want, got)
+		}
+	}
+
+	checkStats := func(cache *globCacheFs, hits, misses int) {
+		t.Helper()
+		if cache.hits != hits || cache.misses != misses {
+			t.Errorf("expected %d hits and %d misses, got %d hits and %d misses",
+				hits, misses, cache.hits, cache.misses)
+		}
+	}
+
+	writeFile("a.txt")
+	writeFile("b.cpp")
+	writeFile("sub/c.txt")
+
+	// The first run has no cache file and must evaluate every glob.
+	cache := newGlobCacheFs(pathtools.NewOsFs(root), cacheFile)
+	checkMatches(glob(cache, "src/*.txt"), "src/a.txt")
+	checkMatches(glob(cache, "src/**/*.txt"), "src/a.txt", "src/sub/c.txt")
+	checkMatches(glob(cache, "src/*.txt"), "src/a.txt")
+	checkStats(cache, 1, 2)
+	if err := cache.save(cacheFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second run with no changes is answered entirely from the cache.
+	cache = newGlobCacheFs(pathtools.NewOsFs(root), cacheFile)
+	checkMatches(glob(cache, "src/*.txt"), "src/a.txt")
+	checkMatches(glob(cache, "src/**/*.txt"), "src/a.txt", "src/sub/c.txt")
+	checkStats(cache, 2, 0)
+	if err := cache.save(cacheFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding a file to a subdirectory only invalidates the globs that walked that subdirectory.
+	writeFile("sub/d.txt")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "src/sub"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	cache = newGlobCacheFs(pathtools.NewOsFs(root), cacheFile)
+	checkMatches(glob(cache, "src/*.txt"), "src/a.txt")
+	checkMatches(glob(cache, "src/**/*.txt"), "src/a.txt", "src/sub/c.txt", "src/sub/d.txt")
+	checkStats(cache, 1, 1)
+}
+
+func TestGlobCacheIgnoresBadCacheFile(t *testing.T) {
+	root := filepath.Join(buildDir, "glob_cache_bad_test")
+	cacheFile := filepath.Join(root, globCacheFileName)
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, contents := range []string{"not json", `{"Version": 0, "Entries": [{"Pattern": "*"}]}`} {
+		if err := ioutil.WriteFile(cacheFile, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+		cache := newGlobCacheFs(pathtools.NewOsFs(root), cacheFile)
+		if len(cache.primed) != 0 {
+			t.Errorf("expected cache file %q to be ignored, got %d entries", contents, len(cache.primed))
+		}
+	}
+}
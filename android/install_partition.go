@@ -0,0 +1,150 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/blueprint"
+)
+
+// Install partitions let packages add new partitions that modules can be installed into, without
+// changing the set of partitions that PathForModuleInstall knows about.  A package registers the
+// partition from an init function:
+//
+//   func init() {
+//       android.RegisterInstallPartition(android.InstallPartition{
+//           Name:    "system_dlkm",
+//           MakeVar: "TARGET_OUT_SYSTEM_DLKM",
+//       })
+//   }
+//
+// and modules select it with the install_partition property:
+//
+//   prebuilt_kernel_modules {
+//       name: "foo",
+//       install_partition: "system_dlkm",
+//   }
+
+func init() {
+	RegisterMakeVarsProvider(pctx, installPartitionMakeVarsProvider)
+}
+
+// InstallPartition describes a partition registered with RegisterInstallPartition.
+type InstallPartition struct {
+	// Name is the name of the partition, which modules set in their install_partition property.
+	Name string
+
+	// Path is the install root of the partition relative to the product out directory.  Defaults
+	// to Name.
+	Path string
+
+	// Filter returns true if the files of a variant of a module that sets install_partition should
+	// be installed into the partition.  Variants that are filtered out are installed where they
+	// would be without install_partition.  Filter must not call ctx.InstallBypassMake.  Defaults
+	// to device variants that are not installed into data, testcases, ramdisk or recovery.
+	Filter func(ctx ModuleInstallPathContext) bool
+
+	// MakeVar is the name of the Make variable that is set to the install root of the partition,
+	// for example TARGET_OUT_SYSTEM_DLKM.  No Make variable is exported if it is empty.
+	MakeVar string
+
+	// BypassMake installs the files of modules in the partition from Soong instead of Make, for
+	// partitions that Make doesn't know how to install into.
+	BypassMake bool
+}
+
+// path returns the install root of the partition relative to the product out directory.
+func (p *InstallPartition) path() string {
+	if p.Path != "" {
+		return p.Path
+	}
+	return p.Name
+}
+
+// includes returns true if the files of the module variant should be installed into the partition.
+func (p *InstallPartition) includes(ctx ModuleInstallPathContext) bool {
+	if p.Filter != nil {
+		return p.Filter(ctx)
+	}
+	return ctx.Device() && !ctx.InstallInData() && !ctx.InstallInTestcases() &&
+		!ctx.InstallInRamdisk() && !ctx.InstallInRecovery()
+}
+
+var installPartitionNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinInstallPartitions are the partitions that modulePartition already knows about, which
+// can't be registered again.
+var builtinInstallPartitions = []string{
+	"data", "odm", "product", "ramdisk", "recovery", "root", "system", "system_ext", "testcases",
+	"vendor",
+}
+
+var installPartitions = make(map[string]*InstallPartition)
+
+// RegisterInstallPartition registers a partition that modules can be installed into by setting
+// install_partition to its name.  It must be called from an init function.
+func RegisterInstallPartition(partition InstallPartition) {
+	checkCalledFromInit()
+	registerInstallPartition(partition)
+}
+
+func registerInstallPartition(partition InstallPartition) {
+	if !installPartitionNameRegexp.MatchString(partition.Name) {
+		panic(fmt.Errorf("invalid install partition name %q", partition.Name))
+	}
+	if InList(partition.Name, builtinInstallPartitions) {
+		panic(fmt.Errorf("install partition %q is a builtin partition", partition.Name))
+	}
+	if _, exists := installPartitions[partition.Name]; exists {
+		panic(fmt.Errorf("install partition %q is already registered", partition.Name))
+	}
+	if _, err := validatePath(partition.path()); err != nil {
+		panic(fmt.Errorf("invalid path of install partition %q: %s", partition.Name, err))
+	}
+	installPartitions[partition.Name] = &partition
+}
+
+// determineInstallPartition returns the partition registered with the name set in the
+// install_partition property of the module, or nil if it isn't set.  The partition is looked up
+// the first time a context is created for the module, so that an unknown partition is only
+// reported once.
+func determineInstallPartition(m *ModuleBase, ctx blueprint.EarlyModuleContext) *InstallPartition {
+	if m.installPartitionDetermined {
+		return m.installPartition
+	}
+	m.installPartitionDetermined = true
+
+	name := String(m.commonProperties.Install_partition)
+	if name == "" {
+		return nil
+	}
+	m.installPartition = installPartitions[name]
+	if m.installPartition == nil {
+		ctx.PropertyErrorf("install_partition", "unknown partition %q", name)
+	}
+	return m.installPartition
+}
+
+// installPartitionMakeVarsProvider exports the install roots of the registered partitions to Make.
+func installPartitionMakeVarsProvider(ctx MakeVarsContext) {
+	for _, name := range SortedStringKeys(installPartitions) {
+		partition := installPartitions[name]
+		if partition.MakeVar != "" {
+			ctx.StrictRaw(partition.MakeVar, "$(PRODUCT_OUT)/"+partition.path())
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+// registerInstallPartitionForTest registers an install partition and returns a function that
+// unregisters it.
+func registerInstallPartitionForTest(partition InstallPartition) func() {
+	registerInstallPartition(partition)
+	return func() {
+		delete(installPartitions, partition.Name)
+	}
+}
+
+func TestPathForModuleInstallWithInstallPartition(t *testing.T) {
+	defer registerInstallPartitionForTest(InstallPartition{
+		Name: "test_dlkm",
+	})()
+	defer registerInstallPartitionForTest(InstallPartition{
+		Name: "test_filtered",
+		Path: "vendor/filtered",
+		Filter: func(ctx ModuleInstallPathContext) bool {
+			return !ctx.InstallInRoot()
+		},
+	})()
+
+	testConfig := pathTestConfig("")
+	deviceTarget := Target{Os: Android}
+
+	testCases := []struct {
+		name           string
+		partition      string
+		inData         bool
+		inRoot         bool
+		inSanitizerDir bool
+		out            string
+	}{
+		{
+			name:      "default path",
+			partition: "test_dlkm",
+			out:       "target/product/test_device/test_dlkm/lib/modules/foo.ko",
+		},
+		{
+			name:      "default filter excludes data",
+			partition: "test_dlkm",
+			inData:    true,
+			out:       "target/product/test_device/data/lib/modules/foo.ko",
+		},
+		{
+			name:           "sanitizer dir",
+			partition:      "test_dlkm",
+			inSanitizerDir: true,
+			out:            "target/product/test_device/data/asan/test_dlkm/lib/modules/foo.ko",
+		},
+		{
+			name:      "custom path",
+			partition: "test_filtered",
+			out:       "target/product/test_device/vendor/filtered/lib/modules/foo.ko",
+		},
+		{
+			name:      "custom filter",
+			partition: "test_filtered",
+			inRoot:    true,
+			out:       "target/product/test_device/root/lib/modules/foo.ko",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &moduleInstallPathContextImpl{
+				baseModuleContext: baseModuleContext{
+					os:     deviceTarget.Os,
+					target: deviceTarget,
+					earlyModuleContext: earlyModuleContext{
+						partition: installPartitions[tc.partition],
+					},
+				},
+				inData:         tc.inData,
+				inRoot:         tc.inRoot,
+				inSanitizerDir: tc.inSanitizerDir,
+			}
+			ctx.baseModuleContext.config = testConfig
+
+			output := PathForModuleInstall(ctx, "lib", "modules", "foo.ko")
+			if output.basePath.path != tc.out {
+				t.Errorf("expected %q, got %q", tc.out, output.basePath.path)
+			}
+		})
+	}
+}
+
+func TestInstallPartitionProperty(t *testing.T) {
+	defer registerInstallPartitionForTest(InstallPartition{
+		Name: "test_dlkm",
+	})()
+
+	result := licenseMetadataFixtureFactory.RunTest(t,
+		FixtureWithRootAndroidBp(`
+			test {
+				name: "foo",
+				install_partition: "test_dlkm",
+			}
+		`),
+	)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a")
+	foo.Output("target/product/test_device/test_dlkm/bin/foo")
+
+	licenseMetadataFixtureFactory.RunTestExpectingError(t,
+		`install_partition: unknown partition "missing_dlkm"`,
+		FixtureWithRootAndroidBp(`
+			test {
+				name: "foo",
+				install_partition: "missing_dlkm",
+			}
+		`),
+	)
+}
+
+func TestRegisterInstallPartitionErrors(t *testing.T) {
+	defer registerInstallPartitionForTest(InstallPartition{
+		Name: "test_dlkm",
+	})()
+
+	testCases := []struct {
+		name      string
+		partition InstallPartition
+	}{
+		{
+			name:      "invalid name",
+			partition: InstallPartition{Name: "Test-DLKM"},
+		},
+		{
+			name:      "builtin",
+			partition: InstallPartition{Name: "vendor"},
+		},
+		{
+			name:      "duplicate",
+			partition: InstallPartition{Name: "test_dlkm"},
+		},
+		{
+			name:      "invalid path",
+			partition: InstallPartition{Name: "test_escape", Path: "../escape"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registerInstallPartition to panic")
+				}
+			}()
+			registerInstallPartition(tc.partition)
+		})
+	}
+
+	t.Run("not called from init", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected RegisterInstallPartition to panic")
+			}
+			delete(installPartitions, "test_not_init")
+		}()
+		RegisterInstallPartition(InstallPartition{Name: "test_not_init"})
+	})
+}
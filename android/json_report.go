@@ -0,0 +1,76 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+func init() {
+	RegisterMakeVarsProvider(pctx, jsonReportsMakeVars)
+}
+
+var jsonReportsOnceKey = NewOnceKey("json_reports")
+
+// jsonReports maps the phony goals of the json reports to the reports that are disted for them.
+type jsonReports map[string]Paths
+
+var jsonReportsLock sync.Mutex
+
+func getJSONReports(config Config) jsonReports {
+	return config.Once(jsonReportsOnceKey, func() interface{} {
+		return make(jsonReports)
+	}).(jsonReports)
+}
+
+// WriteJSONReport writes v as indented json to path, which must be in the output directory, and
+// makes it a dependency of the phony goal that is disted with it.  It returns false after
+// reporting an error if the report couldn't be written.
+func WriteJSONReport(ctx SingletonContext, path OutputPath, v interface{}, phony string) bool {
+	buf, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		ctx.Errorf("JSON marshal of %s failed: %s", path.Rel(), err)
+		return false
+	}
+	if err := WriteFileToOutputDir(path, buf, 0666); err != nil {
+		ctx.Errorf("Writing %s failed: %s", path, err)
+		return false
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: path,
+	})
+	ctx.Phony(phony, path)
+
+	reports := getJSONReports(ctx.Config())
+	jsonReportsLock.Lock()
+	defer jsonReportsLock.Unlock()
+	reports[phony] = append(reports[phony], path)
+	return true
+}
+
+// jsonReportsMakeVars dists the json reports for their phony goals, keeping their paths relative
+// to the output directory so that reports with the same name don't collide.
+func jsonReportsMakeVars(ctx MakeVarsContext) {
+	reports := getJSONReports(ctx.Config())
+	for _, phony := range SortedStringKeys(reports) {
+		for _, path := range SortedUniquePaths(reports[phony]) {
+			ctx.DistForGoalWithFilename(phony, path, path.Rel())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterLicenseBuildComponents(InitRegistrationContext)
+}
+
+func RegisterLicenseBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("license", LicenseFactory)
+}
+
+type licenseProperties struct {
+	// Specifies the kinds of license that apply, e.g.
+	// "SPDX-license-identifier-Apache-2.0".
+	License_kinds []string
+
+	// Specifies a short copyright notice to use for the license.
+	Copyright_notice *string
+
+	// Specifies the paths or labels of the files containing the text of the license.
+	License_text []string `android:"path"`
+}
+
+type licenseModule struct {
+	ModuleBase
+
+	properties licenseProperties
+
+	licenseTexts Paths
+}
+
+// LicenseModule is implemented by license modules, and gives other modules access to
+// the license metadata of the license modules they reference through their licenses
+// property.
+type LicenseModule interface {
+	Module
+
+	// LicenseKinds returns the kinds of license that apply.
+	LicenseKinds() []string
+
+	// LicenseTexts returns the paths to the files containing the text of the license.
+	LicenseTexts() Paths
+
+	// CopyrightNotice returns the short copyright notice of the license, if any.
+	CopyrightNotice() string
+}
+
+var _ LicenseModule = (*licenseModule)(nil)
+
+func (m *licenseModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	if len(m.properties.License_kinds) == 0 && len(m.properties.License_text) == 0 {
+		ctx.ModuleErrorf("at least one of license_kinds or license_text is required")
+	}
+	m.licenseTexts = PathsForModuleSrc(ctx, m.properties.License_text)
+}
+
+func (m *licenseModule) LicenseKinds() []string {
+	return append([]string(nil), m.properties.License_kinds...)
+}
+
+func (m *licenseModule) LicenseTexts() Paths {
+	return append(Paths{}, m.licenseTexts...)
+}
+
+func (m *licenseModule) CopyrightNotice() string {
+	return String(m.properties.Copyright_notice)
+}
+
+// license describes the license kinds and license text that apply to the modules
+// that reference it through their licenses property.
+func LicenseFactory() Module {
+	module := &licenseModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+type licenseDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+// LicenseDepTag is the dependency tag used for dependencies on license modules.
+var LicenseDepTag = licenseDependencyTag{}
+
+// AddLicenseDependencies adds dependencies from the current module to the license
+// modules named in licenses.
+func AddLicenseDependencies(ctx BottomUpMutatorContext, licenses []string) {
+	ctx.AddDependency(ctx.Module(), LicenseDepTag, licenses...)
+}
+
+func registerLicenseDepsMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("license_deps", licenseDepsMutator).Parallel()
+}
+
+// licenseDepsMutator adds dependencies on the license modules named in the licenses property
+// of every module.
+func licenseDepsMutator(ctx BottomUpMutatorContext) {
+	if m, ok := ctx.Module().(Module); ok {
+		AddLicenseDependencies(ctx, m.base().commonProperties.Licenses)
+	}
+}
+
+// LicenseModulesForModule returns the license modules that the current module depends
+// on through LicenseDepTag, i.e. the license modules named in its licenses property.
+func LicenseModulesForModule(ctx ModuleContext) []LicenseModule {
+	return append([]LicenseModule(nil), ctx.Module().base().licenseModules...)
+}
+
+// licenseModulesForModule returns the license modules that the current module depends
+// on through LicenseDepTag, reporting an error on the licenses property for any
+// dependency that is not a license module.
+func licenseModulesForModule(ctx ModuleContext) []LicenseModule {
+	var licenses []LicenseModule
+	ctx.VisitDirectDepsWithTag(LicenseDepTag, func(dep Module) {
+		if license, ok := dep.(LicenseModule); ok {
+			licenses = append(licenses, license)
+		} else {
+			ctx.PropertyErrorf("licenses", "module %q is not a license module",
+				ctx.OtherModuleName(dep))
+		}
+	})
+	return licenses
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"github.com/google/blueprint"
+)
+
+// The license metadata of a module is made up of the license modules named in its licenses
+// property, its notice file, and the license metadata of the dependencies that are linked into it
+// or installed with it, so that the license metadata of e.g. a binary includes the licenses of the
+// static libraries linked into it but not those of the host tools run to build it.  The
+// license_metadata singleton writes the license metadata of each installed file to a
+// <installed file>.meta_lic file in out/soong/license_metadata, from which packaging rules can
+// generate NOTICE files.
+
+func init() {
+	RegisterSingletonType("license_metadata", licenseMetadataSingletonFactory)
+}
+
+// LicenseMetadata is the license metadata of a module, including the license metadata of its
+// dependencies.
+type LicenseMetadata struct {
+	// The kinds of license that apply, e.g. "SPDX-license-identifier-Apache-2.0".
+	Kinds []string
+
+	// The files containing the text of the licenses and notices that apply.
+	Texts Paths
+
+	// The short copyright notices of the licenses that apply.
+	CopyrightNotices []string
+}
+
+// IncludeInLicenseMetadataTag can be implemented by a dependency tag to include the license
+// metadata of the dependency in the license metadata of the module, e.g. for a library that is
+// linked into the module.  The license metadata of dependencies with other tags, e.g. host tools
+// that are only run during the build, is not included.
+type IncludeInLicenseMetadataTag interface {
+	blueprint.DependencyTag
+
+	// IncludeInLicenseMetadata returns true if the license metadata of the dependency applies to
+	// the module.
+	IncludeInLicenseMetadata() bool
+}
+
+// LicenseMetadataForModule returns the license metadata of a module, which is available once the
+// build actions of the module have been generated.
+func LicenseMetadataForModule(module Module) LicenseMetadata {
+	return module.base().licenseMetadata
+}
+
+// buildLicenseMetadata returns the license metadata of the current module from its license
+// modules, its notice file and the license metadata of its direct dependencies whose tags
+// implement IncludeInLicenseMetadataTag.
+func buildLicenseMetadata(ctx ModuleContext, licenses []LicenseModule, notice OptionalPath) LicenseMetadata {
+	var ret LicenseMetadata
+	for _, license := range licenses {
+		ret.Kinds = append(ret.Kinds, license.LicenseKinds()...)
+		ret.Texts = append(ret.Texts, license.LicenseTexts()...)
+		if copyright := license.CopyrightNotice(); copyright != "" {
+			ret.CopyrightNotices = append(ret.CopyrightNotices, copyright)
+		}
+	}
+	if notice.Valid() {
+		ret.Texts = append(ret.Texts, notice.Path())
+	}
+
+	ctx.VisitDirectDepsBlueprint(func(dep blueprint.Module) {
+		module, ok := dep.(Module)
+		if !ok || !module.Enabled() {
+			return
+		}
+		tag, ok := ctx.OtherModuleDependencyTag(dep).(IncludeInLicenseMetadataTag)
+		if !ok || !tag.IncludeInLicenseMetadata() {
+			return
+		}
+		metadata := LicenseMetadataForModule(module)
+		ret.Kinds = append(ret.Kinds, metadata.Kinds...)
+		ret.Texts = append(ret.Texts, metadata.Texts...)
+		ret.CopyrightNotices = append(ret.CopyrightNotices, metadata.CopyrightNotices...)
+	})
+
+	ret.Kinds = FirstUniqueStrings(ret.Kinds)
+	ret.Texts = FirstUniquePaths(ret.Texts)
+	ret.CopyrightNotices = FirstUniqueStrings(ret.CopyrightNotices)
+	return ret
+}
+
+// LicenseMetadataPathForInstalledFile returns the path to the license metadata file that the
+// license_metadata singleton writes for an installed file.
+func LicenseMetadataPathForInstalledFile(ctx PathContext, installed Path) OutputPath {
+	return PathForOutput(ctx, "license_metadata", installed.Rel()+".meta_lic")
+}
+
+// installedFileLicenseMetadata is the contents of the license metadata file of an installed file.
+type installedFileLicenseMetadata struct {
+	Module           string
+	InstalledFile    string
+	LicenseKinds     []string
+	LicenseTexts     []string
+	CopyrightNotices []string
+}
+
+func licenseMetadataSingletonFactory() Singleton {
+	return &licenseMetadataSingleton{}
+}
+
+type licenseMetadataSingleton struct{}
+
+func (s *licenseMetadataSingleton) GenerateBuildActions(ctx SingletonContext) {
+	seen := make(map[string]bool)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+		metadata := LicenseMetadataForModule(module)
+		for _, installed := range module.base().installFiles {
+			if seen[installed.String()] {
+				continue
+			}
+			seen[installed.String()] = true
+
+			WriteJSONReport(ctx, LicenseMetadataPathForInstalledFile(ctx, installed), installedFileLicenseMetadata{
+				Module:           ctx.ModuleName(module),
+				InstalledFile:    installed.Rel(),
+				LicenseKinds:     metadata.Kinds,
+				LicenseTexts:     metadata.Texts.Strings(),
+				CopyrightNotices: metadata.CopyrightNotices,
+			}, "license-metadata")
+		}
+	})
+}
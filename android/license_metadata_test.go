@@ -0,0 +1,173 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type licenseMetadataTestDepTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (licenseMetadataTestDepTag) IncludeInLicenseMetadata() bool { return true }
+
+var _ IncludeInLicenseMetadataTag = licenseMetadataTestDepTag{}
+
+type licenseMetadataTestToolDepTag struct {
+	blueprint.BaseDependencyTag
+}
+
+type licenseMetadataTestModule struct {
+	ModuleBase
+	properties struct {
+		Deps  []string
+		Tools []string
+	}
+}
+
+func licenseMetadataTestModuleFactory() Module {
+	m := &licenseMetadataTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, DeviceSupported, MultilibFirst)
+	return m
+}
+
+func (m *licenseMetadataTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), licenseMetadataTestDepTag{}, m.properties.Deps...)
+	ctx.AddDependency(ctx.Module(), licenseMetadataTestToolDepTag{}, m.properties.Tools...)
+}
+
+func (m *licenseMetadataTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	out := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: out,
+	})
+	ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), out)
+}
+
+var licenseMetadataFixtureFactory = NewFixtureFactory(
+	&buildDir,
+	PrepareForTestWithArchMutator,
+	FixtureRegisterWithContext(RegisterLicenseBuildComponents),
+	FixtureRegisterModuleType("test", licenseMetadataTestModuleFactory),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterSingletonType("license_metadata", licenseMetadataSingletonFactory)
+	}),
+)
+
+func TestLicenseMetadata(t *testing.T) {
+	result := licenseMetadataFixtureFactory.RunTest(t,
+		FixtureWithRootAndroidBp(`
+			license {
+				name: "apache",
+				license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+				license_text: ["LICENSE"],
+			}
+
+			license {
+				name: "bsd",
+				license_kinds: ["SPDX-license-identifier-BSD"],
+				copyright_notice: "Copyright (C) The BSD Authors",
+			}
+
+			license {
+				name: "gpl",
+				license_kinds: ["SPDX-license-identifier-GPL"],
+			}
+
+			test {
+				name: "bin",
+				licenses: ["apache"],
+				deps: ["lib"],
+				tools: ["tool"],
+			}
+
+			test {
+				name: "tool",
+				licenses: ["gpl"],
+			}
+		`),
+		FixtureAddTextFile("lib/Android.bp", `
+			test {
+				name: "lib",
+				licenses: ["bsd", "apache"],
+			}
+		`),
+		FixtureAddTextFile("lib/NOTICE", "lib notice"),
+	)
+
+	variant := "android_arm64_armv8-a"
+	bin := result.ModuleForTests("bin", variant).Module()
+	want := LicenseMetadata{
+		Kinds:            []string{"SPDX-license-identifier-Apache-2.0", "SPDX-license-identifier-BSD"},
+		Texts:            PathsForTesting("LICENSE", "lib/NOTICE"),
+		CopyrightNotices: []string{"Copyright (C) The BSD Authors"},
+	}
+	got := LicenseMetadataForModule(bin)
+	if !reflect.DeepEqual(got.Kinds, want.Kinds) {
+		t.Errorf("expected license kinds %q, got %q", want.Kinds, got.Kinds)
+	}
+	if g, w := got.Texts.Strings(), want.Texts.Strings(); !reflect.DeepEqual(g, w) {
+		t.Errorf("expected license texts %q, got %q", w, g)
+	}
+	if !reflect.DeepEqual(got.CopyrightNotices, want.CopyrightNotices) {
+		t.Errorf("expected copyright notices %q, got %q", want.CopyrightNotices, got.CopyrightNotices)
+	}
+
+	installed := bin.base().installFiles[0]
+	metadataFile := LicenseMetadataPathForInstalledFile(PathContextForTesting(result.Config), installed)
+	result.SingletonForTests("license_metadata").Output(metadataFile.Rel())
+
+	data, err := ioutil.ReadFile(metadataFile.String())
+	if err != nil {
+		t.Fatalf("failed to read the license metadata file: %s", err)
+	}
+	var metadata installedFileLicenseMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatalf("failed to parse the license metadata file: %s", err)
+	}
+	wantMetadata := installedFileLicenseMetadata{
+		Module:           "bin",
+		InstalledFile:    "target/product/test_device/system/bin/bin",
+		LicenseKinds:     want.Kinds,
+		LicenseTexts:     want.Texts.Strings(),
+		CopyrightNotices: want.CopyrightNotices,
+	}
+	if !reflect.DeepEqual(metadata, wantMetadata) {
+		t.Errorf("expected license metadata %#v, got %#v", wantMetadata, metadata)
+	}
+}
+
+func TestLicenseMetadataNotALicense(t *testing.T) {
+	licenseMetadataFixtureFactory.RunTestExpectingError(t,
+		`licenses: module "lib" is not a license module`,
+		FixtureWithRootAndroidBp(`
+			test {
+				name: "bin",
+				licenses: ["lib"],
+			}
+
+			test {
+				name: "lib",
+			}
+		`))
+}
@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/scanner"
+	"time"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -78,6 +79,10 @@ type EarlyModuleContext interface {
 	SystemExtSpecific() bool
 	Platform() bool
 
+	// installPartition returns the partition registered with RegisterInstallPartition that the
+	// module sets in install_partition, or nil.
+	installPartition() *InstallPartition
+
 	Config() Config
 	DeviceConfig() DeviceConfig
 
@@ -205,6 +210,14 @@ type ModuleContext interface {
 
 	GetMissingDependencies() []string
 	Namespace() blueprint.Namespace
+
+	// AddContentHashInput adds an input that is not captured by the properties or source files of
+	// the module, for example the version of a tool that it runs, to the content hash of the module.
+	AddContentHashInput(name, value string)
+
+	// ModuleInfoJSON returns the module-info.json entry of the current variant, in which the module
+	// type can set e.g. the class of the module and the test configs of a test.
+	ModuleInfoJSON() *ModuleInfoJSON
 }
 
 type Module interface {
@@ -232,6 +245,8 @@ type Module interface {
 	Enabled() bool
 	Target() Target
 	Owner() string
+	Team() string
+	BugComponent() string
 	InstallInData() bool
 	InstallInTestcases() bool
 	InstallInSanitizerDir() bool
@@ -315,6 +330,30 @@ type nameProperties struct {
 	Name *string
 }
 
+// Dist is the configuration for copying the outputs of a module to the dist directory.
+type Dist struct {
+	// copy the output of this module to the $DIST_DIR when `dist` is specified on the
+	// command line and  any of these targets are also on the command line, or otherwise
+	// built
+	Targets []string `android:"arch_variant"`
+
+	// The name of the output artifact. This defaults to the basename of the output of
+	// the module.
+	Dest *string `android:"arch_variant"`
+
+	// The directory within the dist directory to store the artifact. Defaults to the
+	// top level directory ("").
+	Dir *string `android:"arch_variant"`
+
+	// A suffix to add to the artifact file name (before any extension).
+	Suffix *string `android:"arch_variant"`
+
+	// A tag to select the output files of the module to copy, as in ":module{.tag}"
+	// references.  The module must implement OutputFileProducer.  Defaults to the output
+	// file of the module.
+	Tag *string `android:"arch_variant"`
+}
+
 type commonProperties struct {
 	// emit build rules for this module
 	//
@@ -398,6 +437,13 @@ type commonProperties struct {
 	// vendor who owns this module
 	Owner *string
 
+	// team that owns this module, one of the teams in the product's ownership registry
+	Team *string
+
+	// id of the component to file bugs against this module in.  Defaults to the first bug
+	// component of the team.
+	Bug_component *string
+
 	// whether this module is specific to an SoC (System-On-a-Chip). When set to true,
 	// it is installed into /vendor (or /system/vendor if vendor partition does not exist).
 	// Use `soc_specific` instead for better meaning.
@@ -422,6 +468,10 @@ type commonProperties struct {
 	// (or /system/system_ext if system_ext partition does not exist).
 	System_ext_specific *bool
 
+	// name of a partition registered with RegisterInstallPartition to install this module into,
+	// instead of the partition selected by the properties above.
+	Install_partition *string
+
 	// Whether this module is installed to recovery partition
 	Recovery *bool
 
@@ -449,23 +499,19 @@ type commonProperties struct {
 	// relative path to a file to include in the list of notices for the device
 	Notice *string `android:"path"`
 
-	Dist struct {
-		// copy the output of this module to the $DIST_DIR when `dist` is specified on the
-		// command line and  any of these targets are also on the command line, or otherwise
-		// built
-		Targets []string `android:"arch_variant"`
+	// names of the license modules that apply to this module.  The license metadata of the
+	// module is made up of these licenses, the notice file and the license metadata of the
+	// dependencies of the module.
+	Licenses []string
 
-		// The name of the output artifact. This defaults to the basename of the output of
-		// the module.
-		Dest *string `android:"arch_variant"`
+	// configuration to distribute output files from this module to the distribution
+	// directory (default: $OUT/dist, configurable with $DIST_DIR)
+	Dist Dist `android:"arch_variant"`
 
-		// The directory within the dist directory to store the artifact. Defaults to the
-		// top level directory ("").
-		Dir *string `android:"arch_variant"`
-
-		// A suffix to add to the artifact file name (before any extension).
-		Suffix *string `android:"arch_variant"`
-	} `android:"arch_variant"`
+	// a list of configurations to distribute output files from this module to the
+	// distribution directory (default: $OUT/dist, configurable with $DIST_DIR), so that
+	// different outputs can be copied to different dist goals
+	Dists []Dist `android:"arch_variant"`
 
 	// The OsType of artifacts that this module variant is responsible for creating.
 	//
@@ -720,12 +766,27 @@ type ModuleBase struct {
 	// The primary visibility property, may be nil, that controls access to the module.
 	primaryVisibilityProperty visibilityProperty
 
+	// The namespace the module is defined in, set by the NameResolver.
+	namespace *Namespace
+
 	noAddressSanitizer bool
 	installFiles       Paths
 	checkbuildFiles    Paths
 	noticeFile         OptionalPath
 	phonies            map[string]Paths
 
+	// The license modules named in the licenses property, and the license metadata of the
+	// module including that of its dependencies.
+	licenseModules  []LicenseModule
+	licenseMetadata LicenseMetadata
+
+	// The module-info.json entry of the variant.
+	moduleInfoJSON *ModuleInfoJSON
+
+	// The partition named by install_partition, looked up by determineInstallPartition.
+	installPartition           *InstallPartition
+	installPartitionDetermined bool
+
 	// Used by buildTargetSingleton to create checkbuild and per-directory build targets
 	// Only set on the final variant of each module
 	installTarget    WritablePath
@@ -744,6 +805,12 @@ type ModuleBase struct {
 	initRcPaths         Paths
 	vintfFragmentsPaths Paths
 
+	// The inputs to the content hash of the module, set once its build actions are generated.
+	contentHashState *moduleContentHashState
+
+	// Time spent analyzing the module, only recorded if analysis metrics are enabled.
+	analysisMetrics *moduleAnalysisMetrics
+
 	prefer32 func(ctx BaseModuleContext, base *ModuleBase, class OsClass) bool
 }
 
@@ -1034,6 +1101,14 @@ func (m *ModuleBase) Owner() string {
 	return String(m.commonProperties.Owner)
 }
 
+func (m *ModuleBase) Team() string {
+	return String(m.commonProperties.Team)
+}
+
+func (m *ModuleBase) BugComponent() string {
+	return String(m.commonProperties.Bug_component)
+}
+
 func (m *ModuleBase) NoticeFile() OptionalPath {
 	return m.noticeFile
 }
@@ -1192,6 +1267,7 @@ func (m *ModuleBase) earlyModuleContextFactory(ctx blueprint.EarlyModuleContext)
 	return earlyModuleContext{
 		EarlyModuleContext: ctx,
 		kind:               determineModuleKind(m, ctx),
+		partition:          determineInstallPartition(m, ctx),
 		config:             ctx.Config().(Config),
 	}
 }
@@ -1207,6 +1283,71 @@ func (m *ModuleBase) baseModuleContextFactory(ctx blueprint.BaseModuleContext) b
 	}
 }
 
+// checkDistProperties checks the properties of a dist configuration that will be used later in
+// androidmk.go.
+func checkDistProperties(ctx ModuleContext, property string, dist *Dist) {
+	if dist.Dest != nil {
+		_, err := validateSafePath(*dist.Dest)
+		if err != nil {
+			ctx.PropertyErrorf(property+".dest", "%s", err.Error())
+		}
+	}
+	if dist.Dir != nil {
+		_, err := validateSafePath(*dist.Dir)
+		if err != nil {
+			ctx.PropertyErrorf(property+".dir", "%s", err.Error())
+		}
+	}
+	if dist.Suffix != nil {
+		if strings.Contains(*dist.Suffix, "/") {
+			ctx.PropertyErrorf(property+".suffix", "Suffix may not contain a '/' character.")
+		}
+	}
+}
+
+// HasDist returns true if the module has dist or dists properties that copy its outputs to the
+// dist directory.
+func (m *ModuleBase) HasDist() bool {
+	return len(m.commonProperties.Dist.Targets) > 0 || len(m.commonProperties.Dists) > 0
+}
+
+// checkDistTags checks that the output files selected by the tags of the dist configurations
+// are available, so that androidmk.go can rely on them.
+func (m *ModuleBase) checkDistTags(ctx ModuleContext) {
+	check := func(property string, dist *Dist) {
+		if dist.Tag == nil || len(dist.Targets) == 0 {
+			return
+		}
+		paths, err := m.distFilesForTag(*dist.Tag)
+		if err != nil {
+			ctx.PropertyErrorf(property+".tag", "%s", err.Error())
+		} else if len(paths) > 1 && dist.Dest != nil {
+			ctx.PropertyErrorf(property+".dest",
+				"cannot be set when tag %q selects more than one output file", *dist.Tag)
+		}
+	}
+	check("dist", &m.commonProperties.Dist)
+	for i := range m.commonProperties.Dists {
+		check(fmt.Sprintf("dists[%d]", i), &m.commonProperties.Dists[i])
+	}
+}
+
+// distFilesForTag returns the output files of the module for the tag of a dist configuration.
+func (m *ModuleBase) distFilesForTag(tag string) (Paths, error) {
+	producer, ok := m.module.(OutputFileProducer)
+	if !ok {
+		return nil, fmt.Errorf("module does not support dist tags, as it does not produce tagged output files")
+	}
+	paths, err := producer.OutputFiles(tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no output files for tag %q", tag)
+	}
+	return paths, nil
+}
+
 func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext) {
 	ctx := &moduleContext{
 		module:            m.module,
@@ -1254,23 +1395,11 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 	ctx.Variable(pctx, "moduleDescSuffix", s)
 
 	// Some common property checks for properties that will be used later in androidmk.go
-	if m.commonProperties.Dist.Dest != nil {
-		_, err := validateSafePath(*m.commonProperties.Dist.Dest)
-		if err != nil {
-			ctx.PropertyErrorf("dist.dest", "%s", err.Error())
-		}
-	}
-	if m.commonProperties.Dist.Dir != nil {
-		_, err := validateSafePath(*m.commonProperties.Dist.Dir)
-		if err != nil {
-			ctx.PropertyErrorf("dist.dir", "%s", err.Error())
-		}
-	}
-	if m.commonProperties.Dist.Suffix != nil {
-		if strings.Contains(*m.commonProperties.Dist.Suffix, "/") {
-			ctx.PropertyErrorf("dist.suffix", "Suffix may not contain a '/' character.")
-		}
+	checkDistProperties(ctx, "dist", &m.commonProperties.Dist)
+	for i := range m.commonProperties.Dists {
+		checkDistProperties(ctx, fmt.Sprintf("dists[%d]", i), &m.commonProperties.Dists[i])
 	}
+	validateOwnership(ctx, m.Team(), m.BugComponent())
 
 	if m.Enabled() {
 		// ensure all direct android.Module deps are enabled
@@ -1288,7 +1417,21 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			m.noticeFile = ExistentPathForSource(ctx, noticePath)
 		}
 
-		m.module.GenerateAndroidBuildActions(ctx)
+		m.licenseModules = licenseModulesForModule(ctx)
+		m.licenseMetadata = buildLicenseMetadata(ctx, m.licenseModules, m.noticeFile)
+
+		if ctx.Config().AnalysisMetricsEnabled() {
+			start := time.Now()
+			m.module.GenerateAndroidBuildActions(ctx)
+			m.recordGenerateBuildActions(ctx, start)
+		} else {
+			m.module.GenerateAndroidBuildActions(ctx)
+		}
+		if ctx.Failed() {
+			return
+		}
+
+		m.checkDistTags(ctx)
 		if ctx.Failed() {
 			return
 		}
@@ -1300,6 +1443,9 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 		for k, v := range ctx.phonies {
 			m.phonies[k] = append(m.phonies[k], v...)
 		}
+
+		m.moduleInfoJSON = ctx.ModuleInfoJSON()
+		finalizeModuleInfoJSON(ctx, m.moduleInfoJSON, m.installFiles)
 	} else if ctx.Config().AllowMissingDependencies() {
 		// If the module is not enabled it will not create any build rules, nothing will call
 		// ctx.GetMissingDependencies(), and blueprint will consider the missing dependencies to be unhandled
@@ -1318,13 +1464,15 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 	m.buildParams = ctx.buildParams
 	m.ruleParams = ctx.ruleParams
 	m.variables = ctx.variables
+	m.contentHashState = newModuleContentHashState(ctx)
 }
 
 type earlyModuleContext struct {
 	blueprint.EarlyModuleContext
 
-	kind   moduleKind
-	config Config
+	kind      moduleKind
+	partition *InstallPartition
+	config    Config
 }
 
 func (e *earlyModuleContext) Glob(globPattern string, excludes []string) Paths {
@@ -1396,6 +1544,10 @@ func (e *earlyModuleContext) SystemExtSpecific() bool {
 	return e.kind == systemExtSpecificModule
 }
 
+func (e *earlyModuleContext) installPartition() *InstallPartition {
+	return e.partition
+}
+
 type baseModuleContext struct {
 	bp blueprint.BaseModuleContext
 	earlyModuleContext
@@ -1439,6 +1591,15 @@ type moduleContext struct {
 	module          Module
 	phonies         map[string]Paths
 
+	contentHashSources Paths
+	contentHashInputs  map[string]string
+
+	moduleInfoJSON *ModuleInfoJSON
+
+	// The number of rules and build actions created by the module, for analysis metrics.
+	ruleCount        int
+	buildActionCount int
+
 	// For tests
 	buildParams []BuildParams
 	ruleParams  map[blueprint.Rule]blueprint.RuleParams
@@ -1528,6 +1689,7 @@ func (m *moduleContext) Rule(pctx PackageContext, name string, params blueprint.
 	}
 
 	rule := m.bp.Rule(pctx.PackageContext, name, params, argNames...)
+	m.ruleCount++
 
 	if m.config.captureBuild {
 		m.ruleParams[rule] = params
@@ -1546,17 +1708,87 @@ func (m *moduleContext) Build(pctx PackageContext, params BuildParams) {
 			m.ModuleName(), strings.Join(missingDeps, ", ")))
 	}
 
+	if m.Config().SandboxInstallActions() {
+		params = m.sandboxInstallBuildParams(params)
+	}
+
 	if m.config.captureBuild {
 		m.buildParams = append(m.buildParams, params)
 	}
 
+	recordBuildActionForValidation(m.config, params)
+	m.buildActionCount++
+	m.contentHashSources = append(m.contentHashSources, contentHashSourcesOfBuildParams(params)...)
+
 	m.bp.Build(pctx.PackageContext, convertBuildParams(params))
 }
 
+// sandboxInstallBuildParams changes a rule that copies or symlinks a file into an install
+// directory, either to install it or to package it, to run in an sbox sandbox that only contains
+// the inputs of the rule, so that it can't depend on undeclared files.  The sandbox mirrors the
+// inputs at their path relative to the source tree, so rules with inputs outside of it, which
+// happens when OUT_DIR is, are left unsandboxed.
+func (m *moduleContext) sandboxInstallBuildParams(params BuildParams) BuildParams {
+	sboxRule, ok := sandboxedInstallRules[params.Rule]
+	if !ok {
+		return params
+	}
+	out, ok := params.Output.(InstallPath)
+	if !ok || len(params.Outputs) > 0 || params.ImplicitOutput != nil || len(params.ImplicitOutputs) > 0 {
+		return params
+	}
+
+	var inputs []string
+	for _, input := range append(Paths{params.Input}, params.Inputs...) {
+		if input == nil {
+			continue
+		}
+		rel, ok := sandboxInputPath(input)
+		if !ok {
+			return params
+		}
+		inputs = append(inputs, rel)
+	}
+
+	args := make(map[string]string)
+	for k, v := range params.Args {
+		args[k] = v
+	}
+	args["inputs"] = strings.Join(inputs, " ")
+	args["outDir"] = filepath.Dir(out.String())
+	args["outName"] = out.Base()
+	args["manifest"] = PathForModuleOut(m, "sbox_install", out.path+".rsp").String()
+
+	params.Rule = sboxRule
+	params.Args = args
+	return params
+}
+
+// sandboxInputPath returns the path of input relative to the source tree, and false if it is
+// outside of the source tree.
+func sandboxInputPath(input Path) (string, bool) {
+	path := input.String()
+	if !filepath.IsAbs(path) {
+		return path, true
+	}
+	rel, err := filepath.Rel(absSrcDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
 func (m *moduleContext) Phony(name string, deps ...Path) {
 	addPhony(m.config, name, deps...)
 }
 
+func (m *moduleContext) ModuleInfoJSON() *ModuleInfoJSON {
+	if m.moduleInfoJSON == nil {
+		m.moduleInfoJSON = &ModuleInfoJSON{}
+	}
+	return m.moduleInfoJSON
+}
+
 func (m *moduleContext) GetMissingDependencies() []string {
 	var missingDeps []string
 	missingDeps = append(missingDeps, m.Module().base().commonProperties.MissingDeps...)
@@ -1857,6 +2089,9 @@ func (m *moduleContext) InstallInRoot() bool {
 }
 
 func (m *moduleContext) InstallBypassMake() bool {
+	if p := m.installPartition(); p != nil && p.BypassMake && m.Device() && p.includes(m) {
+		return true
+	}
 	return m.module.InstallBypassMake()
 }
 
@@ -0,0 +1,168 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// The content hash of a module is a stable hash of the inputs that determine the build actions of
+// the module and their results:
+//  - the Go type of the module and the target it is built for,
+//  - the values of its properties after all mutators have run,
+//  - the contents of every source file used as an input to one of its build actions, and
+//  - any other inputs that the module reported with ModuleContext.AddContentHashInput, for example
+//    the versions of the tools it runs.
+// The hash does not cover the dependencies of the module; callers that need a hash of everything
+// that affects a module can combine the hashes of the module and its transitive dependencies.
+//
+// The hash is stable across runs of soong_build, so it can be used to skip work whose inputs have
+// not changed, or to attribute a change in an output to the modules whose inputs changed.
+
+// moduleContentHashVersion is included in every content hash and must be incremented whenever the
+// set of inputs to the hash or their encoding changes.
+const moduleContentHashVersion = 1
+
+// moduleContentHashState holds the inputs to the content hash of a module that are collected while
+// its build actions are generated, and the hash once it has been computed.
+type moduleContentHashState struct {
+	sources Paths
+	inputs  map[string]string
+
+	once sync.Once
+	hash string
+	err  error
+}
+
+// moduleContentHashSource is the encoding of a source file in the inputs to a content hash.
+type moduleContentHashSource struct {
+	Path   string
+	Sha256 string
+}
+
+// moduleContentHashInputs is the encoding of the inputs to a content hash.
+type moduleContentHashInputs struct {
+	Version    int
+	Type       string
+	Target     string
+	Properties []interface{}
+	Sources    []moduleContentHashSource
+	Inputs     map[string]string
+}
+
+// ModuleContentHash returns a stable hash of the inputs of the module: its properties, source files
+// and tool versions.  It can be called from singletons, or from modules on their dependencies, but
+// only after the build actions of the module have been generated.  It returns an error if the
+// hash is not available or a source file of the module cannot be read.
+func ModuleContentHash(ctx PathContext, module Module) (string, error) {
+	m := module.base()
+	state := m.contentHashState
+	if state == nil {
+		return "", fmt.Errorf("content hash of module %q is not available before its build actions are generated",
+			m.Name())
+	}
+
+	state.once.Do(func() {
+		state.hash, state.err = computeModuleContentHash(ctx, module, state)
+	})
+	return state.hash, state.err
+}
+
+func computeModuleContentHash(ctx PathContext, module Module, state *moduleContentHashState) (string, error) {
+	m := module.base()
+	inputs := moduleContentHashInputs{
+		Version:    moduleContentHashVersion,
+		Type:       fmt.Sprintf("%T", module),
+		Target:     m.Target().String(),
+		Properties: m.GetProperties(),
+		Inputs:     state.inputs,
+	}
+
+	for _, source := range state.sources {
+		digest, err := sourceFileDigest(ctx, source)
+		if err != nil {
+			return "", fmt.Errorf("content hash of module %q: %s", m.Name(), err)
+		}
+		inputs.Sources = append(inputs.Sources, moduleContentHashSource{
+			Path:   source.String(),
+			Sha256: digest,
+		})
+	}
+
+	buf, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("content hash of module %q: %s", m.Name(), err)
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// sourceFileDigest returns the hex encoded sha256 of the contents of a source file.
+func sourceFileDigest(ctx PathContext, path Path) (string, error) {
+	f, err := ctx.Config().fs.Open(path.String())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading %s: %s", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentHashSourcesOfBuildParams returns the source files that are inputs to a build action.
+// Order-only dependencies don't affect the results of the action and are not included.
+func contentHashSourcesOfBuildParams(params BuildParams) Paths {
+	var paths Paths
+	if params.Input != nil {
+		paths = append(paths, params.Input)
+	}
+	paths = append(paths, params.Inputs...)
+	if params.Implicit != nil {
+		paths = append(paths, params.Implicit)
+	}
+	paths = append(paths, params.Implicits...)
+
+	var sources Paths
+	for _, path := range paths {
+		if _, ok := path.(SourcePath); ok {
+			sources = append(sources, path)
+		}
+	}
+	return sources
+}
+
+// newModuleContentHashState returns the inputs to the content hash collected by a moduleContext,
+// with the source files sorted and deduplicated.
+func newModuleContentHashState(ctx *moduleContext) *moduleContentHashState {
+	return &moduleContentHashState{
+		sources: SortedUniquePaths(ctx.contentHashSources),
+		inputs:  ctx.contentHashInputs,
+	}
+}
+
+func (m *moduleContext) AddContentHashInput(name, value string) {
+	if m.contentHashInputs == nil {
+		m.contentHashInputs = make(map[string]string)
+	}
+	m.contentHashInputs[name] = value
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type contentHashTestModule struct {
+	ModuleBase
+	properties struct {
+		Srcs         []string `android:"path"`
+		Deps         []string
+		Tool_version string
+	}
+
+	depHashes map[string]string
+}
+
+var contentHashTestDepTag = struct{ blueprint.BaseDependencyTag }{}
+
+func contentHashTestModuleFactory() Module {
+	m := &contentHashTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *contentHashTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), contentHashTestDepTag, m.properties.Deps...)
+}
+
+func (m *contentHashTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	ctx.Build(pctx, BuildParams{
+		Rule:   Cp,
+		Inputs: PathsForModuleSrc(ctx, m.properties.Srcs),
+		Output: PathForModuleOut(ctx, "out"),
+	})
+	ctx.AddContentHashInput("tool", m.properties.Tool_version)
+
+	m.depHashes = make(map[string]string)
+	ctx.VisitDirectDepsWithTag(contentHashTestDepTag, func(dep Module) {
+		hash, err := ModuleContentHash(ctx, dep)
+		if err != nil {
+			ctx.ModuleErrorf("%s", err)
+			return
+		}
+		m.depHashes[ctx.OtherModuleName(dep)] = hash
+	})
+}
+
+var contentHashFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterModuleType("test", contentHashTestModuleFactory),
+)
+
+func contentHashForTest(t *testing.T, bp string, srcContents string) (foo, bar string) {
+	t.Helper()
+	result := contentHashFixtureFactory.RunTest(t,
+		FixtureWithRootAndroidBp(bp),
+		FixtureAddTextFile("a.txt", srcContents),
+		FixtureAddTextFile("b.txt", "b"),
+	)
+
+	fooModule := result.ModuleForTests("foo", "").Module()
+	foo, err := ModuleContentHash(PathContextForTesting(result.Config), fooModule)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	barModule := result.ModuleForTests("bar", "").Module().(*contentHashTestModule)
+	return foo, barModule.depHashes["foo"]
+}
+
+func TestModuleContentHash(t *testing.T) {
+	const bp = `
+		test {
+			name: "foo",
+			srcs: ["a.txt", "b.txt"],
+			tool_version: "1",
+		}
+
+		test {
+			name: "bar",
+			deps: ["foo"],
+		}
+	`
+
+	hash, depHash := contentHashForTest(t, bp, "a")
+	if len(hash) != 64 {
+		t.Errorf("expected a sha256 hex digest, got %q", hash)
+	}
+	if depHash != hash {
+		t.Errorf("expected the hash seen by a dependent module %q to match %q", depHash, hash)
+	}
+
+	if again, _ := contentHashForTest(t, bp, "a"); again != hash {
+		t.Errorf("expected the hash to be stable, got %q and %q", hash, again)
+	}
+
+	changes := []struct {
+		name        string
+		bp          string
+		srcContents string
+	}{
+		{
+			name:        "source contents",
+			bp:          bp,
+			srcContents: "changed",
+		},
+		{
+			name: "properties",
+			bp: `
+				test {
+					name: "foo",
+					srcs: ["a.txt"],
+					tool_version: "1",
+				}
+
+				test {
+					name: "bar",
+					deps: ["foo"],
+				}
+			`,
+			srcContents: "a",
+		},
+		{
+			name: "tool version",
+			bp: `
+				test {
+					name: "foo",
+					srcs: ["a.txt", "b.txt"],
+					tool_version: "2",
+				}
+
+				test {
+					name: "bar",
+					deps: ["foo"],
+				}
+			`,
+			srcContents: "a",
+		},
+	}
+
+	for _, change := range changes {
+		t.Run(change.name, func(t *testing.T) {
+			if changed, _ := contentHashForTest(t, change.bp, change.srcContents); changed == hash {
+				t.Errorf("expected a change in %s to change the hash %q", change.name, hash)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"github.com/google/blueprint"
+)
+
+// The module_info_json singleton writes out/soong/module-info.json, which has the same format as
+// the module-info.json file that Make writes into the product out directory, but is generated from
+// the Soong modules alone, so that it is available in builds without Make and to IDE tooling.
+
+func init() {
+	RegisterSingletonType("module_info_json", moduleInfoJSONSingletonFactory)
+}
+
+const moduleInfoJSONFileName = "module-info.json"
+
+// ModuleInfoJSON is the information about a module that is written to module-info.json.  Module
+// types fill in the class, tags, compatibility suites and test configs of each variant with
+// ModuleContext.ModuleInfoJSON, and the information of all of the variants of a module is merged
+// into a single entry.
+type ModuleInfoJSON struct {
+	Class               []string `json:"class"`
+	Path                []string `json:"path"`
+	Tags                []string `json:"tags"`
+	Installed           []string `json:"installed"`
+	CompatibilitySuites []string `json:"compatibility_suites"`
+	TestConfig          []string `json:"test_config"`
+	Dependencies        []string `json:"dependencies"`
+	ModuleName          string   `json:"module_name"`
+}
+
+// finalizeModuleInfoJSON fills in the fields of the module-info.json entry of the current variant
+// that are common to all module types once its build actions have been generated.  Like Make, the
+// tags default to optional, and the dependencies on license modules and on other variants of the
+// same module are left out.
+func finalizeModuleInfoJSON(ctx *moduleContext, info *ModuleInfoJSON, installFiles Paths) {
+	info.ModuleName = ctx.ModuleName()
+	info.Path = append(info.Path, ctx.ModuleDir())
+	info.Installed = append(info.Installed, installFiles.Strings()...)
+	if len(info.Tags) == 0 {
+		info.Tags = []string{"optional"}
+	}
+	ctx.VisitDirectDepsBlueprint(func(dep blueprint.Module) {
+		if ctx.OtherModuleDependencyTag(dep) == LicenseDepTag {
+			return
+		}
+		if name := ctx.OtherModuleName(dep); name != ctx.ModuleName() {
+			info.Dependencies = append(info.Dependencies, name)
+		}
+	})
+}
+
+func moduleInfoJSONSingletonFactory() Singleton {
+	return &moduleInfoJSONSingleton{}
+}
+
+type moduleInfoJSONSingleton struct{}
+
+func (s *moduleInfoJSONSingleton) GenerateBuildActions(ctx SingletonContext) {
+	infos := make(map[string]*ModuleInfoJSON)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+
+		variant := module.base().moduleInfoJSON
+		if variant == nil {
+			return
+		}
+		name := ctx.ModuleName(module)
+		info, ok := infos[name]
+		if !ok {
+			info = &ModuleInfoJSON{ModuleName: name}
+			infos[name] = info
+		}
+
+		info.Class = append(info.Class, variant.Class...)
+		info.Path = append(info.Path, variant.Path...)
+		info.Tags = append(info.Tags, variant.Tags...)
+		info.Installed = append(info.Installed, variant.Installed...)
+		info.CompatibilitySuites = append(info.CompatibilitySuites, variant.CompatibilitySuites...)
+		info.TestConfig = append(info.TestConfig, variant.TestConfig...)
+		info.Dependencies = append(info.Dependencies, variant.Dependencies...)
+	})
+
+	for _, info := range infos {
+		info.Class = moduleInfoJSONList(info.Class)
+		info.Path = moduleInfoJSONList(info.Path)
+		info.Tags = moduleInfoJSONList(info.Tags)
+		info.Installed = moduleInfoJSONList(info.Installed)
+		info.CompatibilitySuites = moduleInfoJSONList(info.CompatibilitySuites)
+		info.TestConfig = moduleInfoJSONList(info.TestConfig)
+		info.Dependencies = moduleInfoJSONList(info.Dependencies)
+	}
+
+	WriteJSONReport(ctx, PathForOutput(ctx, moduleInfoJSONFileName), infos, "soong-module-info")
+}
+
+// moduleInfoJSONList removes the empty and duplicate entries of a module-info.json list, and
+// returns an empty rather than a nil list so that it is written as [] like Make does.
+func moduleInfoJSONList(list []string) []string {
+	ret := []string{}
+	for _, s := range FirstUniqueStrings(list) {
+		if s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
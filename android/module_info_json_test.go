@@ -0,0 +1,106 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestModuleInfoJSON(t *testing.T) {
+	result := NewFixtureFactory(&buildDir,
+		PrepareForTestWithArchMutator,
+		FixtureRegisterWithContext(RegisterLicenseBuildComponents),
+		FixtureRegisterModuleType("csuite_config", CSuiteConfigFactory),
+		FixtureRegisterModuleType("test", licenseMetadataTestModuleFactory),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterSingletonType("module_info_json", moduleInfoJSONSingletonFactory)
+		}),
+	).RunTest(t,
+		FixtureAddTextFile("foo/Android.bp", `
+			license {
+				name: "apache",
+				license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+			}
+
+			test {
+				name: "bin",
+				licenses: ["apache"],
+				deps: ["lib"],
+				tools: ["tool"],
+			}
+
+			test {
+				name: "lib",
+			}
+
+			test {
+				name: "tool",
+			}
+		`),
+		FixtureAddTextFile("suite/Android.bp", `
+			csuite_config {
+				name: "suite_config",
+				test_suites: ["device-tests"],
+				include_filters: ["CSuiteTest"],
+			}
+		`),
+	)
+
+	result.SingletonForTests("module_info_json").Output(moduleInfoJSONFileName)
+
+	data, err := ioutil.ReadFile(PathForOutput(PathContextForTesting(result.Config), moduleInfoJSONFileName).String())
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", moduleInfoJSONFileName, err)
+	}
+	var infos map[string]ModuleInfoJSON
+	if err := json.Unmarshal(data, &infos); err != nil {
+		t.Fatalf("failed to parse %s: %s", moduleInfoJSONFileName, err)
+	}
+
+	bin := result.ModuleForTests("bin", "android_arm64_armv8-a").Module()
+	suiteConfigVariants := result.ModuleVariantsForTests("suite_config")
+	suiteConfig := result.ModuleForTests("suite_config", suiteConfigVariants[0]).Module().(*CSuiteConfig)
+
+	expected := map[string]ModuleInfoJSON{
+		"bin": {
+			Class:               []string{},
+			Path:                []string{"foo"},
+			Tags:                []string{"optional"},
+			Installed:           bin.base().installFiles.Strings(),
+			CompatibilitySuites: []string{},
+			TestConfig:          []string{},
+			Dependencies:        []string{"lib", "tool"},
+			ModuleName:          "bin",
+		},
+		"suite_config": {
+			Class:               []string{"FAKE"},
+			Path:                []string{"suite"},
+			Tags:                []string{"optional"},
+			Installed:           []string{},
+			CompatibilitySuites: []string{"csuite", "device-tests"},
+			TestConfig:          []string{suiteConfig.testConfig.String()},
+			Dependencies:        []string{},
+			ModuleName:          "suite_config",
+		},
+	}
+	for name, want := range expected {
+		if got := infos[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected module-info.json entry for %s:\n%#v\ngot:\n%#v", name, want, got)
+		}
+	}
+}
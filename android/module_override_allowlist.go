@@ -0,0 +1,143 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The module override allowlist gives a product central control over the modules that replace
+// other modules, either as override modules (e.g. override_android_app) or as prebuilts that are
+// used instead of their source modules because they set prefer: true.  Prebuilts selected by the
+// product's prebuilt policy are already controlled by the product and are always allowed.
+//
+// Every active override is listed in module_overrides.json for release review.  If the product
+// sets EnforceModuleOverrideAllowlist, each override must also be listed in
+// ModuleOverrideAllowlist as "<overriding module>:<overridden module>", for example:
+//
+//   "EnforceModuleOverrideAllowlist": true,
+//   "ModuleOverrideAllowlist": ["MyOverrideApp:App", "prebuilt_libfoo:libfoo"],
+
+func init() {
+	RegisterSingletonType("module_overrides", moduleOverridesSingletonFactory)
+}
+
+const moduleOverridesFileName = "module_overrides.json"
+
+const (
+	moduleOverrideKindOverrideModule = "override_module"
+	moduleOverrideKindPrebuilt       = "prebuilt"
+)
+
+// validateModuleOverrideAllowlist returns an error if an entry of the product's
+// ModuleOverrideAllowlist is invalid.
+func validateModuleOverrideAllowlist(c *config) error {
+	for _, entry := range c.productVariables.ModuleOverrideAllowlist {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid ModuleOverrideAllowlist entry %q, must be "+
+				"<overriding module>:<overridden module>", entry)
+		}
+	}
+	return nil
+}
+
+// EnforceModuleOverrideAllowlist returns true if every override of a module must be listed in the
+// product's ModuleOverrideAllowlist.
+func (c *config) EnforceModuleOverrideAllowlist() bool {
+	return Bool(c.productVariables.EnforceModuleOverrideAllowlist)
+}
+
+// moduleOverrideAllowed returns true if the product's ModuleOverrideAllowlist allows the module
+// named overriding to override the module named overridden.
+func (c *config) moduleOverrideAllowed(overriding, overridden string) bool {
+	return InList(overriding+":"+overridden, c.productVariables.ModuleOverrideAllowlist)
+}
+
+// moduleOverride is an entry in the module override report.
+type moduleOverride struct {
+	Kind      string
+	Module    string
+	Dir       string
+	Overrides string
+	Reason    string `json:",omitempty"`
+	Allowed   bool
+}
+
+func moduleOverridesSingletonFactory() Singleton {
+	return &moduleOverridesSingleton{}
+}
+
+type moduleOverridesSingleton struct{}
+
+// GenerateBuildActions reports every active override of a module in module_overrides.json, and
+// reports errors for overrides that are not allowed if the allowlist is enforced.
+func (s *moduleOverridesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	config := ctx.Config()
+	seen := make(map[moduleOverride]bool)
+	overrides := []moduleOverride{}
+	ctx.VisitAllModules(func(module Module) {
+		var override moduleOverride
+		if o, ok := module.(OverrideModule); ok {
+			base := String(o.getOverrideModuleProperties().Base)
+			override = moduleOverride{
+				Kind:      moduleOverrideKindOverrideModule,
+				Module:    ctx.ModuleName(module),
+				Overrides: base,
+				Allowed:   config.moduleOverrideAllowed(ctx.ModuleName(module), base),
+			}
+		} else if m, ok := module.(PrebuiltInterface); ok && m.Prebuilt() != nil {
+			p := m.Prebuilt()
+			if !p.properties.UsePrebuilt || !p.properties.SourceExists {
+				return
+			}
+			source := m.base().BaseModuleName()
+			override = moduleOverride{
+				Kind:      moduleOverrideKindPrebuilt,
+				Module:    ctx.ModuleName(module),
+				Overrides: source,
+				Reason:    p.selectionReason,
+				Allowed: !p.preferredOverSource ||
+					config.moduleOverrideAllowed(ctx.ModuleName(module), source),
+			}
+		} else {
+			return
+		}
+		override.Dir = ctx.ModuleDir(module)
+
+		// Variants of a module override the same module, only report each override once.
+		if seen[override] {
+			return
+		}
+		seen[override] = true
+		overrides = append(overrides, override)
+
+		if !override.Allowed && config.EnforceModuleOverrideAllowlist() {
+			ctx.ModuleErrorf(module, "overrides %q, but %q is not in ModuleOverrideAllowlist",
+				override.Overrides, override.Module+":"+override.Overrides)
+		}
+	})
+
+	sort.SliceStable(overrides, func(i, j int) bool {
+		if overrides[i].Module != overrides[j].Module {
+			return overrides[i].Module < overrides[j].Module
+		}
+		return overrides[i].Dir < overrides[j].Dir
+	})
+
+	WriteJSONReport(ctx, PathForOutput(ctx, moduleOverridesFileName), overrides, "module-overrides")
+}
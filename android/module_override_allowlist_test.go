@@ -0,0 +1,150 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+var moduleOverridesFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterWithContext(registerTestPrebuiltBuildComponents),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterSingletonType("module_overrides", moduleOverridesSingletonFactory)
+	}),
+	FixtureWithRootAndroidBp(`
+		source {
+			name: "foo",
+		}
+
+		override_source {
+			name: "foo_override",
+			base: "foo",
+		}
+
+		source {
+			name: "bar",
+		}
+
+		source {
+			name: "baz",
+		}
+	`),
+	FixtureAddTextFile("prebuilts/Android.bp", `
+		prebuilt {
+			name: "bar",
+			prefer: true,
+			srcs: ["prebuilt_file"],
+		}
+
+		prebuilt {
+			name: "baz",
+			srcs: ["prebuilt_file"],
+		}
+	`),
+	FixtureMergeMockFs(map[string][]byte{
+		"prebuilts/prebuilt_file": nil,
+		"source_file":             nil,
+	}),
+)
+
+func moduleOverridesForTest(t *testing.T, result *TestResult) []moduleOverride {
+	t.Helper()
+	result.SingletonForTests("module_overrides").Output(moduleOverridesFileName)
+	data, err := ioutil.ReadFile(PathForOutput(PathContextForTesting(result.Config), moduleOverridesFileName).String())
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", moduleOverridesFileName, err)
+	}
+	var overrides []moduleOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		t.Fatalf("failed to parse %s: %s", moduleOverridesFileName, err)
+	}
+	return overrides
+}
+
+func TestModuleOverridesReport(t *testing.T) {
+	result := moduleOverridesFixtureFactory.RunTest(t,
+		FixtureModifyConfig(func(config Config) {
+			config.TestProductVariables.ModuleOverrideAllowlist = []string{"foo_override:foo"}
+			config.TestProductVariables.PrebuiltPolicyPrebuiltModules = []string{"baz"}
+		}),
+	)
+
+	expected := []moduleOverride{
+		{
+			Kind:      moduleOverrideKindOverrideModule,
+			Module:    "foo_override",
+			Dir:       ".",
+			Overrides: "foo",
+			Allowed:   true,
+		},
+		{
+			Kind:      moduleOverrideKindPrebuilt,
+			Module:    "prebuilt_bar",
+			Dir:       "prebuilts",
+			Overrides: "bar",
+			Reason:    "prefer is true",
+			Allowed:   false,
+		},
+		{
+			Kind:      moduleOverrideKindPrebuilt,
+			Module:    "prebuilt_baz",
+			Dir:       "prebuilts",
+			Overrides: "baz",
+			Reason:    `"baz" is in PrebuiltPolicyPrebuiltModules`,
+			Allowed:   true,
+		},
+	}
+	if overrides := moduleOverridesForTest(t, result); !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("expected module overrides:\n%#v\ngot:\n%#v", expected, overrides)
+	}
+}
+
+func TestModuleOverrideAllowlistEnforced(t *testing.T) {
+	enforce := FixtureModifyConfig(func(config Config) {
+		config.TestProductVariables.EnforceModuleOverrideAllowlist = proptools.BoolPtr(true)
+		config.TestProductVariables.ModuleOverrideAllowlist = []string{"foo_override:foo"}
+	})
+
+	moduleOverridesFixtureFactory.RunTestExpectingError(t,
+		`overrides "bar", but "prebuilt_bar:bar" is not in ModuleOverrideAllowlist`,
+		enforce)
+
+	moduleOverridesFixtureFactory.RunTest(t,
+		enforce,
+		FixtureModifyConfig(func(config Config) {
+			config.TestProductVariables.ModuleOverrideAllowlist = append(
+				config.TestProductVariables.ModuleOverrideAllowlist, "prebuilt_bar:bar")
+		}),
+	)
+}
+
+func TestModuleOverrideAllowlistErrors(t *testing.T) {
+	for _, entry := range []string{"foo", "foo:", ":foo", "foo:bar:baz"} {
+		t.Run(entry, func(t *testing.T) {
+			config := TestConfig(buildDir, nil, "", nil)
+			config.TestProductVariables.ModuleOverrideAllowlist = []string{entry}
+
+			err := validateModuleOverrideAllowlist(config.config)
+			if err == nil {
+				t.Errorf("expected an error for entry %q", entry)
+			}
+		})
+	}
+}
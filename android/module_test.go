@@ -15,7 +15,11 @@
 package android
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func TestSrcIsModule(t *testing.T) {
@@ -187,3 +191,137 @@ func TestErrorDependsOnDisabledModule(t *testing.T) {
 	_, errs = ctx.PrepareBuildActions(config)
 	FailIfNoMatchingErrors(t, `module "foo": depends on disabled module "bar"`, errs)
 }
+
+type sandboxInstallTestModule struct {
+	ModuleBase
+}
+
+func sandboxInstallTestModuleFactory() Module {
+	m := &sandboxInstallTestModule{}
+	InitAndroidArchModule(m, DeviceSupported, MultilibFirst)
+	return m
+}
+
+func (m *sandboxInstallTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	out := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: out,
+	})
+	installed := ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), out)
+	ctx.InstallSymlink(PathForModuleInstall(ctx, "bin"), ctx.ModuleName()+"_link", installed)
+}
+
+func TestSandboxedInstall(t *testing.T) {
+	bp := `
+		sandbox_install_test {
+			name: "foo",
+		}
+	`
+
+	testCases := []struct {
+		name          string
+		env           map[string]string
+		productConfig *bool
+		sandboxed     bool
+	}{
+		{
+			name:      "default",
+			sandboxed: false,
+		},
+		{
+			name:          "product",
+			productConfig: proptools.BoolPtr(true),
+			sandboxed:     true,
+		},
+		{
+			name:      "env",
+			env:       map[string]string{"SOONG_SANDBOX_INSTALL": "true"},
+			sandboxed: true,
+		},
+		{
+			name:          "env overrides product",
+			env:           map[string]string{"SOONG_SANDBOX_INSTALL": "false"},
+			productConfig: proptools.BoolPtr(true),
+			sandboxed:     false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			result := licenseMetadataFixtureFactory.RunTest(t,
+				FixtureRegisterModuleType("sandbox_install_test", sandboxInstallTestModuleFactory),
+				FixtureWithRootAndroidBp(bp),
+				FixtureMergeEnv(test.env),
+				FixtureModifyConfig(func(config Config) {
+					config.TestProductVariables.SandboxInstallActions = test.productConfig
+				}),
+			)
+
+			foo := result.ModuleForTests("foo", "android_arm64_armv8-a")
+			install := foo.Output("target/product/test_device/system/bin/foo")
+			symlink := foo.Output("target/product/test_device/system/bin/foo_link")
+
+			if !test.sandboxed {
+				if install.Rule != Cp {
+					t.Errorf("expected install rule %q, got %q", Cp, install.Rule)
+				}
+				if symlink.Rule != Symlink {
+					t.Errorf("expected symlink rule %q, got %q", Symlink, symlink.Rule)
+				}
+				return
+			}
+
+			if install.Rule != sboxCp {
+				t.Errorf("expected install rule %q, got %q", sboxCp, install.Rule)
+			}
+			if symlink.Rule != sboxSymlink {
+				t.Errorf("expected symlink rule %q, got %q", sboxSymlink, symlink.Rule)
+			}
+			if got, want := symlink.Args["fromPath"], "foo"; got != want {
+				t.Errorf("expected symlink fromPath %q, got %q", want, got)
+			}
+			if got, want := symlink.Args["outName"], "foo_link"; got != want {
+				t.Errorf("expected symlink outName %q, got %q", want, got)
+			}
+			if got, want := install.Args["outDir"], filepath.Dir(install.Output.String()); got != want {
+				t.Errorf("expected outDir %q, got %q", want, got)
+			}
+			if got, want := install.Args["outName"], "foo"; got != want {
+				t.Errorf("expected outName %q, got %q", want, got)
+			}
+			if got, want := install.Args["inputs"], install.Input.String(); got != want {
+				t.Errorf("expected inputs %q, got %q", want, got)
+			}
+			if got, want := install.Args["manifest"], "/sbox_install/target/product/test_device/system/bin/foo.rsp"; !strings.HasSuffix(got, want) {
+				t.Errorf("expected manifest ending in %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestSandboxInputPath(t *testing.T) {
+	defer func(orig string) { absSrcDir = orig }(absSrcDir)
+	absSrcDir = "/src"
+
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+		ok    bool
+	}{
+		{name: "relative", input: "out/soong/foo", want: "out/soong/foo", ok: true},
+		{name: "absolute out dir in source tree", input: "/src/out/soong/foo", want: "out/soong/foo", ok: true},
+		{name: "absolute out dir outside source tree", input: "/out/soong/foo", ok: false},
+		{name: "absolute out dir next to source tree", input: "/src2/soong/foo", ok: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := sandboxInputPath(testPath{basePath{path: test.input, rel: test.input}})
+			if ok != test.ok || got != test.want {
+				t.Errorf("expected %q, %v, got %q, %v", test.want, test.ok, got, ok)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package android
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -154,6 +155,7 @@ var preDeps = []RegisterMutatorFunc{
 
 var postDeps = []RegisterMutatorFunc{
 	registerPathDepsMutator,
+	registerLicenseDepsMutator,
 	RegisterPrebuiltsPostDepsMutators,
 	RegisterVisibilityRuleEnforcer,
 	RegisterNeverallowMutator,
@@ -234,6 +236,9 @@ func (x *registerMutatorsContext) BottomUp(name string, m BottomUpMutator) Mutat
 				baseModuleContext: a.base().baseModuleContextFactory(ctx),
 				finalPhase:        finalPhase,
 			}
+			if actx.Config().AnalysisMetricsEnabled() {
+				defer a.base().recordMutatorTime(name, time.Now())
+			}
 			m(actx)
 		}
 	}
@@ -249,6 +254,9 @@ func (x *registerMutatorsContext) TopDown(name string, m TopDownMutator) Mutator
 				bp:                ctx,
 				baseModuleContext: a.base().baseModuleContextFactory(ctx),
 			}
+			if actx.Config().AnalysisMetricsEnabled() {
+				defer a.base().recordMutatorTime(name, time.Now())
+			}
 			m(actx)
 		}
 	}
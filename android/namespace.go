@@ -28,6 +28,7 @@ import (
 
 func init() {
 	RegisterModuleType("soong_namespace", NamespaceFactory)
+	RegisterSingletonType("soong_namespaces", namespaceListingSingletonFactory)
 }
 
 // threadsafe sorted list
@@ -176,6 +177,7 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 		if err != nil {
 			return nil, []error{err}
 		}
+		newNamespace.base().namespace = newNamespace.namespace
 		return nil, nil
 	}
 
@@ -196,6 +198,7 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 		// inform the module whether its namespace is one that we want to export to Make
 		amod.base().commonProperties.NamespaceExportedToMake = ns.exportToKati
 		amod.base().commonProperties.DebugName = module.Name()
+		amod.base().namespace = ns
 	}
 
 	return ns, nil
@@ -295,10 +298,13 @@ func (r *NameResolver) chooseId(namespace *Namespace) {
 func (r *NameResolver) MissingDependencyError(depender string, dependerNamespace blueprint.Namespace, depName string) (err error) {
 	text := fmt.Sprintf("%q depends on undefined module %q", depender, depName)
 
-	_, _, isAbs := r.parseFullyQualifiedName(depName)
+	nsName, _, isAbs := r.parseFullyQualifiedName(depName)
 	if isAbs {
 		// if the user gave a fully-qualified name, we don't need to look for other
 		// modules that they might have been referring to
+		if _, found := r.namespaceAt(nsName); !found {
+			text += fmt.Sprintf("\nNamespace %q does not exist", nsName)
+		}
 		return fmt.Errorf(text)
 	}
 
@@ -320,6 +326,17 @@ func (r *NameResolver) MissingDependencyError(depender string, dependerNamespace
 		}
 		text += fmt.Sprintf("\nModule %q is defined in namespace %q which can read these %v namespaces: %q", depender, dependerNs.Path, len(importedNames), importedNames)
 		text += fmt.Sprintf("\nModule %q can be found in these namespaces: %q", depName, foundInNamespaces)
+
+		// Explain when a namespace that defines the module is imported indirectly, as imports
+		// are not transitive.
+		for _, path := range foundInNamespaces {
+			ns, _ := r.namespaceAt(path)
+			if chain := importChain(dependerNs, ns); len(chain) > 2 {
+				text += fmt.Sprintf("\nNamespace %q is only imported indirectly by namespace %q, "+
+					"through the import chain %s, and imports are not transitive",
+					path, dependerNs.Path, formatImportChain(chain))
+			}
+		}
 	}
 
 	return fmt.Errorf(text)
@@ -363,6 +380,59 @@ func NewNamespace(path string) *Namespace {
 	return &Namespace{Path: path, moduleContainer: blueprint.NewSimpleNameInterface()}
 }
 
+// imports returns the namespaces imported by the namespace, once they have been resolved by the
+// namespace mutator.
+func (n *Namespace) imports() []*Namespace {
+	var ret []*Namespace
+	for _, visible := range n.visibleNamespaces {
+		// visibleNamespaces also holds the namespace itself and the root namespace.
+		if visible != n && visible.Path != "." {
+			ret = append(ret, visible)
+		}
+	}
+	return ret
+}
+
+// importChain returns the shortest chain of imports that leads from namespace from to namespace
+// to, starting with from and ending with to, or nil if there is none.  A chain from a namespace
+// to itself is an import cycle.
+func importChain(from, to *Namespace) []*Namespace {
+	// Breadth first search, so that cycles in the imports are only visited once.
+	previous := map[*Namespace]*Namespace{}
+	if from != to {
+		previous[from] = nil
+	}
+	queue := []*Namespace{from}
+	for len(queue) > 0 {
+		ns := queue[0]
+		queue = queue[1:]
+		for _, imp := range ns.imports() {
+			if _, visited := previous[imp]; visited {
+				continue
+			}
+			previous[imp] = ns
+			if imp == to {
+				chain := []*Namespace{to}
+				for n := ns; n != from; n = previous[n] {
+					chain = append([]*Namespace{n}, chain...)
+				}
+				return append([]*Namespace{from}, chain...)
+			}
+			queue = append(queue, imp)
+		}
+	}
+	return nil
+}
+
+// formatImportChain returns a chain of imports in the form "a" -> "b" -> "c".
+func formatImportChain(chain []*Namespace) string {
+	paths := make([]string, len(chain))
+	for i, ns := range chain {
+		paths[i] = strconv.Quote(ns.Path)
+	}
+	return strings.Join(paths, " -> ")
+}
+
 var _ blueprint.Namespace = (*Namespace)(nil)
 
 type namespaceProperties struct {
@@ -425,3 +495,68 @@ func namespaceMutator(ctx BottomUpMutatorContext) {
 		module.resolver.chooseId(module.namespace)
 	}
 }
+
+const namespaceListingFileName = "soong_namespaces.json"
+
+// namespaceListing describes a namespace in the soong_namespaces.json debug output.
+type namespaceListing struct {
+	Path             string
+	Imports          []string
+	Exported_to_make bool
+
+	// The shortest chain of imports that leads from the namespace back to itself, if any.
+	Import_cycle []string `json:",omitempty"`
+
+	// The modules defined in the namespace, which are visible to the namespaces that import it.
+	Modules []string
+}
+
+func namespaceListingSingletonFactory() Singleton {
+	return &namespaceListingSingleton{}
+}
+
+// namespaceListingSingleton writes soong_namespaces.json, which lists the imports and the modules
+// of each namespace, to help debug soong_namespace setups.
+type namespaceListingSingleton struct{}
+
+func (s *namespaceListingSingleton) GenerateBuildActions(ctx SingletonContext) {
+	listings := make(map[*Namespace]*namespaceListing)
+	ctx.VisitAllModules(func(module Module) {
+		ns := module.base().namespace
+		if ns == nil {
+			return
+		}
+		listing, ok := listings[ns]
+		if !ok {
+			listing = &namespaceListing{
+				Path:             ns.Path,
+				Imports:          []string{},
+				Exported_to_make: ns.exportToKati,
+				Modules:          []string{},
+			}
+			for _, imp := range ns.imports() {
+				listing.Imports = append(listing.Imports, imp.Path)
+			}
+			for _, n := range importChain(ns, ns) {
+				listing.Import_cycle = append(listing.Import_cycle, n.Path)
+			}
+			listings[ns] = listing
+		}
+		if _, ok := module.(*NamespaceModule); !ok {
+			listing.Modules = append(listing.Modules, ctx.ModuleName(module))
+		}
+	})
+
+	var report []*namespaceListing
+	for _, listing := range listings {
+		if len(listing.Modules) > 0 {
+			listing.Modules = SortedUniqueStrings(listing.Modules)
+		}
+		report = append(report, listing)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Path < report[j].Path
+	})
+
+	WriteJSONReport(ctx, PathForOutput(ctx, namespaceListingFileName), report, "soong-namespaces")
+}
@@ -15,7 +15,9 @@
 package android
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -488,13 +490,127 @@ func TestNamespaceImportsNotTransitive(t *testing.T) {
 	expectedErrors := []error{
 		errors.New(`dir3/Android.bp:5:4: "c" depends on undefined module "a"
 Module "c" is defined in namespace "dir3" which can read these 3 namespaces: ["dir3" "dir2" "."]
-Module "a" can be found in these namespaces: ["dir1"]`),
+Module "a" can be found in these namespaces: ["dir1"]
+Namespace "dir1" is only imported indirectly by namespace "dir3", through the import chain "dir3" -> "dir2" -> "dir1", and imports are not transitive`),
 	}
 	if len(errs) != 1 || errs[0].Error() != expectedErrors[0].Error() {
 		t.Errorf("Incorrect errors. Expected:\n%v\n, got:\n%v\n", expectedErrors, errs)
 	}
 }
 
+func TestFullyQualifiedReferenceToNonexistentNamespace(t *testing.T) {
+	_, errs := setupTestExpectErrs(
+		map[string]string{
+			"dir1": `
+			test_module {
+				name: "a",
+				deps: ["//dir2:b"],
+			}
+			`,
+		},
+	)
+
+	expectedErrors := []error{
+		errors.New(`dir1/Android.bp:2:4: "a" depends on undefined module "//dir2:b"
+Namespace "dir2" does not exist`),
+	}
+	if len(errs) != 1 || errs[0].Error() != expectedErrors[0].Error() {
+		t.Errorf("Incorrect errors. Expected:\n%v\n, got:\n%v\n", expectedErrors, errs)
+	}
+}
+
+func TestNamespaceListing(t *testing.T) {
+	bps := map[string]string{
+		"dir1": `
+			soong_namespace {
+				imports: ["dir2"],
+			}
+			test_module {
+				name: "a",
+			}
+			test_module {
+				name: "b",
+			}
+			`,
+		"dir2": `
+			soong_namespace {
+				imports: ["dir1", "dir3"],
+			}
+			`,
+		"dir3": `
+			soong_namespace {
+			}
+			test_module {
+				name: "a",
+			}
+			`,
+		"dir4": `
+			test_module {
+				name: "c",
+			}
+			`,
+	}
+	files := map[string][]byte{"Android.bp": nil}
+	for dir, text := range bps {
+		files[filepath.Join(dir, "Android.bp")] = []byte(text)
+	}
+
+	config := TestConfig(buildDir, nil, "", files)
+	ctx := NewTestContext()
+	ctx.RegisterModuleType("test_module", newTestModule)
+	ctx.RegisterModuleType("soong_namespace", NamespaceFactory)
+	ctx.RegisterSingletonType("soong_namespaces", namespaceListingSingletonFactory)
+	ctx.PreArchMutators(RegisterNamespaceMutator)
+	ctx.Register(config)
+
+	_, errs := ctx.ParseBlueprintsFiles("Android.bp")
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	ctx.SingletonForTests("soong_namespaces").Output(namespaceListingFileName)
+	data, err := ioutil.ReadFile(PathForOutput(PathContextForTesting(config), namespaceListingFileName).String())
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", namespaceListingFileName, err)
+	}
+	var listing []namespaceListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		t.Fatalf("failed to parse %s: %s", namespaceListingFileName, err)
+	}
+
+	expected := []namespaceListing{
+		{
+			Path:             ".",
+			Imports:          []string{},
+			Exported_to_make: true,
+			Modules:          []string{"c"},
+		},
+		{
+			Path:             "dir1",
+			Imports:          []string{"dir2"},
+			Exported_to_make: true,
+			Import_cycle:     []string{"dir1", "dir2", "dir1"},
+			Modules:          []string{"a", "b"},
+		},
+		{
+			Path:             "dir2",
+			Imports:          []string{"dir1", "dir3"},
+			Exported_to_make: true,
+			Import_cycle:     []string{"dir2", "dir1", "dir2"},
+			Modules:          []string{},
+		},
+		{
+			Path:             "dir3",
+			Imports:          []string{},
+			Exported_to_make: true,
+			Modules:          []string{"a"},
+		},
+	}
+	if !reflect.DeepEqual(listing, expected) {
+		t.Errorf("expected namespace listing:\n%#v\ngot:\n%#v", expected, listing)
+	}
+}
+
 func TestTwoNamepacesInSameDir(t *testing.T) {
 	_, errs := setupTestExpectErrs(
 		map[string]string{
@@ -0,0 +1,223 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+// This file implements the validation of the team and bug_component properties of modules
+// against the ownership registry of the product, and the ownership report that maps modules to
+// their owners, so that build breakages can be routed to the team that owns the broken module.
+// The registry is a json file pointed to by the OwnershipRegistry product variable, for example:
+//
+//   {
+//     "Teams": {
+//       "android-build": {
+//         "Bug_components": ["119452", "381517"]
+//       }
+//     }
+//   }
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+func init() {
+	RegisterSingletonType("ownership", OwnershipSingleton)
+}
+
+type ownershipTeam struct {
+	// The ids of the components to file bugs against the modules of the team in, the first one
+	// is the default.
+	Bug_components []string
+}
+
+type ownershipRegistry struct {
+	Teams map[string]ownershipTeam
+}
+
+// parseOwnershipRegistry parses and validates the contents of an ownership registry file.
+func parseOwnershipRegistry(data []byte) (*ownershipRegistry, error) {
+	registry := &ownershipRegistry{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(registry); err != nil {
+		return nil, err
+	}
+
+	for name, team := range registry.Teams {
+		if len(team.Bug_components) == 0 {
+			return nil, fmt.Errorf("team %q: at least one bug component is required", name)
+		}
+		for _, component := range team.Bug_components {
+			if err := checkBugComponent(component); err != nil {
+				return nil, fmt.Errorf("team %q: %s", name, err)
+			}
+		}
+	}
+
+	return registry, nil
+}
+
+func checkBugComponent(component string) error {
+	if _, err := strconv.ParseUint(component, 10, 64); err != nil {
+		return fmt.Errorf("invalid bug component %q, must be a number", component)
+	}
+	return nil
+}
+
+// hasBugComponent returns whether component is one of the bug components of a team.
+func (r *ownershipRegistry) hasBugComponent(component string) bool {
+	for _, team := range r.Teams {
+		if InList(component, team.Bug_components) {
+			return true
+		}
+	}
+	return false
+}
+
+var ownershipRegistryKey = NewOnceKey("ownershipRegistry")
+
+type loadedOwnershipRegistry struct {
+	registry *ownershipRegistry
+	err      error
+}
+
+// loadOwnershipRegistry returns the ownership registry of the product, or nil if the product
+// doesn't set one.
+func loadOwnershipRegistry(ctx PathContext) (*ownershipRegistry, error) {
+	loaded := ctx.Config().Once(ownershipRegistryKey, func() interface{} {
+		path := ctx.Config().OwnershipRegistry(ctx)
+		if !path.Valid() {
+			return loadedOwnershipRegistry{}
+		}
+		data, err := ReadSourceFile(ctx, path.Path())
+		if err != nil {
+			return loadedOwnershipRegistry{nil, fmt.Errorf("ownership registry: %s", err)}
+		}
+		registry, err := parseOwnershipRegistry(data)
+		if err != nil {
+			return loadedOwnershipRegistry{nil, fmt.Errorf("ownership registry %s: %s", path, err)}
+		}
+		return loadedOwnershipRegistry{registry, nil}
+	}).(loadedOwnershipRegistry)
+
+	return loaded.registry, loaded.err
+}
+
+// validateOwnership checks the team and bug_component properties of a module against the
+// ownership registry.  Teams are only checked if the product sets a registry.
+func validateOwnership(ctx ModuleContext, team, component string) {
+	if component != "" {
+		if err := checkBugComponent(component); err != nil {
+			ctx.PropertyErrorf("bug_component", "%s", err)
+			return
+		}
+	}
+	if team == "" && component == "" {
+		return
+	}
+
+	registry, err := loadOwnershipRegistry(ctx)
+	if err != nil {
+		ctx.ModuleErrorf("%s", err)
+		return
+	}
+	if registry == nil {
+		return
+	}
+
+	if team != "" {
+		t, ok := registry.Teams[team]
+		if !ok {
+			ctx.PropertyErrorf("team", "unknown team %q, must be one of the teams in %s", team,
+				ctx.Config().OwnershipRegistry(ctx))
+			return
+		}
+		if component != "" && !InList(component, t.Bug_components) {
+			ctx.PropertyErrorf("bug_component", "bug component %q is not one of the bug components "+
+				"of team %q: %q", component, team, t.Bug_components)
+		}
+	} else if !registry.hasBugComponent(component) {
+		ctx.PropertyErrorf("bug_component", "bug component %q is not one of the bug components "+
+			"of any team in %s", component, ctx.Config().OwnershipRegistry(ctx))
+	}
+}
+
+// moduleOwnership is the entry of a module in the ownership report.
+type moduleOwnership struct {
+	Name          string
+	Team          string `json:",omitempty"`
+	Owner         string `json:",omitempty"`
+	Bug_component string `json:",omitempty"`
+}
+
+const ownershipReportFileName = "module_ownership.json"
+
+func OwnershipSingleton() Singleton {
+	return &ownershipSingleton{}
+}
+
+// ownershipSingleton writes the owners of all modules that set team, owner or bug_component to
+// $OUT_DIR/soong/module_ownership.json.  Modules without a bug_component are reported with the default
+// bug component of their team.
+type ownershipSingleton struct{}
+
+func (s *ownershipSingleton) GenerateBuildActions(ctx SingletonContext) {
+	registry, err := loadOwnershipRegistry(ctx)
+	if err != nil {
+		ctx.Errorf("%s", err)
+		return
+	}
+
+	owners := make(map[string]moduleOwnership)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+		if module.Team() == "" && module.Owner() == "" && module.BugComponent() == "" {
+			return
+		}
+		name := ctx.ModuleName(module)
+		if _, ok := owners[name]; ok {
+			return
+		}
+
+		ownership := moduleOwnership{
+			Name:          name,
+			Team:          module.Team(),
+			Owner:         module.Owner(),
+			Bug_component: module.BugComponent(),
+		}
+		if ownership.Bug_component == "" && registry != nil {
+			if team, ok := registry.Teams[ownership.Team]; ok {
+				ownership.Bug_component = team.Bug_components[0]
+			}
+		}
+		owners[name] = ownership
+	})
+
+	var report []moduleOwnership
+	for _, ownership := range owners {
+		report = append(report, ownership)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Name < report[j].Name
+	})
+
+	WriteJSONReport(ctx, PathForOutput(ctx, ownershipReportFileName), report, "ownership-report")
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+const testOwnershipRegistry = `{
+	"Teams": {
+		"android-build": {"Bug_components": ["119452", "381517"]},
+		"android-media": {"Bug_components": ["1344"]}
+	}
+}`
+
+func TestParseOwnershipRegistry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected *ownershipRegistry
+		err      string
+	}{
+		{
+			name:  "valid",
+			input: `{"Teams": {"android-build": {"Bug_components": ["119452"]}}}`,
+			expected: &ownershipRegistry{
+				Teams: map[string]ownershipTeam{
+					"android-build": {Bug_components: []string{"119452"}},
+				},
+			},
+		},
+		{
+			name:  "unknown field",
+			input: `{"Teams": {"android-build": {"Owners": ["foo"]}}}`,
+			err:   `unknown field "Owners"`,
+		},
+		{
+			name:  "no bug components",
+			input: `{"Teams": {"android-build": {}}}`,
+			err:   `team "android-build": at least one bug component is required`,
+		},
+		{
+			name:  "invalid bug component",
+			input: `{"Teams": {"android-build": {"Bug_components": ["build"]}}}`,
+			err:   `team "android-build": invalid bug component "build", must be a number`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			registry, err := parseOwnershipRegistry([]byte(testCase.input))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Errorf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(registry, testCase.expected) {
+				t.Errorf("expected %#v, got %#v", testCase.expected, registry)
+			}
+		})
+	}
+}
+
+func testOwnershipContext(bp string, registry bool) (*TestContext, Config) {
+	fs := map[string][]byte{
+		"build/ownership.json": []byte(testOwnershipRegistry),
+	}
+	config := TestArchConfig(buildDir, nil, bp, fs)
+	if registry {
+		config.TestProductVariables.OwnershipRegistry = proptools.StringPtr("build/ownership.json")
+	}
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("filegroup", FileGroupFactory)
+	ctx.RegisterSingletonType("ownership", OwnershipSingleton)
+	ctx.Register(config)
+	return ctx, config
+}
+
+func TestOwnershipValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bp       string
+		registry bool
+		err      string
+	}{
+		{
+			name: "valid",
+			bp: `filegroup {
+				name: "foo",
+				team: "android-build",
+				bug_component: "381517",
+			}`,
+			registry: true,
+		},
+		{
+			name: "unknown team",
+			bp: `filegroup {
+				name: "foo",
+				team: "android-foo",
+			}`,
+			registry: true,
+			err:      `unknown team "android-foo"`,
+		},
+		{
+			name: "unknown team without registry",
+			bp: `filegroup {
+				name: "foo",
+				team: "android-foo",
+			}`,
+		},
+		{
+			name: "bug component of another team",
+			bp: `filegroup {
+				name: "foo",
+				team: "android-build",
+				bug_component: "1344",
+			}`,
+			registry: true,
+			err:      `bug component "1344" is not one of the bug components of team "android-build"`,
+		},
+		{
+			name: "unregistered bug component",
+			bp: `filegroup {
+				name: "foo",
+				bug_component: "42",
+			}`,
+			registry: true,
+			err:      `bug component "42" is not one of the bug components of any team`,
+		},
+		{
+			name: "invalid bug component",
+			bp: `filegroup {
+				name: "foo",
+				bug_component: "build",
+			}`,
+			err: `invalid bug component "build", must be a number`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, config := testOwnershipContext(testCase.bp, testCase.registry)
+			_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+			FailIfErrored(t, errs)
+			_, errs = ctx.PrepareBuildActions(config)
+			if testCase.err != "" {
+				FailIfNoMatchingErrors(t, testCase.err, errs)
+			} else {
+				FailIfErrored(t, errs)
+			}
+		})
+	}
+}
+
+func TestOwnershipReport(t *testing.T) {
+	ctx, config := testOwnershipContext(`
+		filegroup {
+			name: "foo",
+			team: "android-build",
+		}
+
+		filegroup {
+			name: "bar",
+			owner: "acme",
+			bug_component: "1344",
+		}
+
+		filegroup {
+			name: "baz",
+		}
+	`, true)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	ctx.SingletonForTests("ownership").Output(ownershipReportFileName)
+
+	data, err := ioutil.ReadFile(filepath.Join(buildDir, ownershipReportFileName))
+	if err != nil {
+		t.Fatalf("failed to read the ownership report: %s", err)
+	}
+	var report []moduleOwnership
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("failed to parse the ownership report: %s", err)
+	}
+
+	expected := []moduleOwnership{
+		{Name: "bar", Owner: "acme", Bug_component: "1344"},
+		{Name: "foo", Team: "android-build", Bug_component: "119452"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected ownership report %#v, got %#v", expected, report)
+	}
+}
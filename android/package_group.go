@@ -0,0 +1,199 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+func init() {
+	RegisterPackageGroupBuildComponents(InitRegistrationContext)
+}
+
+// Register the package_group module type.
+func RegisterPackageGroupBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("package_group", PackageGroupFactory)
+}
+
+type packageGroupProperties struct {
+	// List of packages in the group, each one of:
+	// * "//some/package": the package some/package.
+	// * "//some/package/...": the package some/package and all of its subpackages.
+	// * "//...": all packages.
+	// A package prefixed with '-' is excluded from the group, even if it matches another entry.
+	Packages []string
+
+	// List of other package groups whose packages are also in the group, as //<package>:<name>
+	// or :<name> for package groups in the same package.
+	Includes []string
+}
+
+type packageGroupModule struct {
+	ModuleBase
+
+	properties packageGroupProperties
+}
+
+func (g *packageGroupModule) GenerateAndroidBuildActions(ModuleContext) {
+	// Nothing to do.
+}
+
+// A packageGroup is the parsed form of a package_group module.
+type packageGroup struct {
+	id       qualifiedModuleName
+	included compositeRule
+	excluded compositeRule
+	includes []qualifiedModuleName
+}
+
+var packageGroupMapKey = NewOnceKey("packageGroupMap")
+
+// The map from the qualifiedModuleName of each package_group to its packageGroup.
+func packageGroupMap(config Config) *sync.Map {
+	return config.Once(packageGroupMapKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// Parses the properties of a package_group and stores the result for use when enforcing the
+// visibility rules that reference it.  Called from the visibility rule gatherer.
+func (g *packageGroupModule) gatherPackageGroup(ctx BottomUpMutatorContext) {
+	group := &packageGroup{id: g.qualifiedModuleId(ctx)}
+
+	for _, spec := range g.properties.Packages {
+		rule, excluded, ok := parsePackageSpec(spec)
+		if !ok {
+			ctx.PropertyErrorf("packages", "invalid package %q must match //<package>, "+
+				"//<package>/... or //..., optionally prefixed with -", spec)
+			continue
+		}
+		if excluded {
+			group.excluded = append(group.excluded, rule)
+		} else {
+			group.included = append(group.included, rule)
+		}
+	}
+
+	for _, include := range g.properties.Includes {
+		id, ok := splitPackageGroupReference(include, ctx.ModuleDir())
+		if !ok {
+			ctx.PropertyErrorf("includes", "invalid package_group %q must match //<package>:<name> or :<name>",
+				include)
+			continue
+		}
+		group.includes = append(group.includes, id)
+	}
+
+	packageGroupMap(ctx.Config()).Store(group.id, group)
+}
+
+var packageSpecPkgRegexp = regexp.MustCompile(`^[^/:.][^/:]*(?:/[^/:]+)*$`)
+
+// parsePackageSpec parses an entry of the packages property of a package_group into the
+// visibility rule that matches the same packages, and whether the packages are excluded.
+func parsePackageSpec(spec string) (rule visibilityRule, excluded bool, ok bool) {
+	if strings.HasPrefix(spec, "-") {
+		excluded = true
+		spec = spec[1:]
+	}
+	if !strings.HasPrefix(spec, "//") {
+		return nil, false, false
+	}
+	pkg := strings.TrimPrefix(spec, "//")
+
+	if pkg == "..." {
+		return publicRule{}, excluded, true
+	}
+	if strings.HasSuffix(pkg, "/...") {
+		pkg = strings.TrimSuffix(pkg, "/...")
+		rule = subpackagesRule{pkg}
+	} else {
+		rule = packageRule{pkg}
+	}
+	if !packageSpecPkgRegexp.MatchString(pkg) {
+		return nil, false, false
+	}
+	return rule, excluded, true
+}
+
+var packageGroupReferenceRegexp = regexp.MustCompile(`^(?:` + packagePattern + `)?` + namePattern + `$`)
+
+// splitPackageGroupReference returns the qualifiedModuleName of the package_group referenced by
+// //<package>:<name>, or :<name> for a package group in currentPkg.
+func splitPackageGroupReference(reference string, currentPkg string) (qualifiedModuleName, bool) {
+	matches := packageGroupReferenceRegexp.FindStringSubmatch(reference)
+	if matches == nil {
+		return qualifiedModuleName{}, false
+	}
+	pkg := matches[1]
+	if pkg == "" {
+		pkg = currentPkg
+	}
+	return qualifiedModuleName{pkg: pkg, name: matches[2]}, true
+}
+
+// A packageGroupRule is a visibility rule that matches modules in the packages of a package_group.
+type packageGroupRule struct {
+	group  qualifiedModuleName
+	groups *sync.Map
+}
+
+func (r packageGroupRule) matches(m qualifiedModuleName) bool {
+	return packageGroupMatches(r.groups, r.group, m, make(map[qualifiedModuleName]bool))
+}
+
+// packageGroupMatches returns whether m is in the packages of the package group id, or of any of
+// the package groups it includes.  Unknown package groups match nothing, and visited guards
+// against cycles of includes.
+func packageGroupMatches(groups *sync.Map, id qualifiedModuleName, m qualifiedModuleName,
+	visited map[qualifiedModuleName]bool) bool {
+
+	if visited[id] {
+		return false
+	}
+	visited[id] = true
+
+	value, ok := groups.Load(id)
+	if !ok {
+		return false
+	}
+	group := value.(*packageGroup)
+
+	if group.included.matches(m) && !group.excluded.matches(m) {
+		return true
+	}
+	for _, include := range group.includes {
+		if packageGroupMatches(groups, include, m, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r packageGroupRule) String() string {
+	return r.group.String()
+}
+
+// A package_group is a named set of packages that can be referenced from visibility properties
+// as //<package>:<name>, or :<name> from the same package, instead of listing all of the packages
+// in each visibility property.
+func PackageGroupFactory() Module {
+	module := &packageGroupModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
@@ -853,6 +853,18 @@ func ExistentPathForSource(ctx PathContext, pathComponents ...string) OptionalPa
 	return OptionalPathForPath(path)
 }
 
+// ReadSourceFile returns the contents of a file in the source tree. A dependency is added so
+// that the ninja file will be regenerated if the file changes.
+func ReadSourceFile(ctx PathContext, path Path) ([]byte, error) {
+	ctx.AddNinjaFileDeps(path.String())
+	r, err := ctx.Config().fs.Open(path.String())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
 func (p SourcePath) String() string {
 	return filepath.Join(p.config.srcDir, p.path)
 }
@@ -1108,6 +1120,22 @@ func pathForModule(ctx ModuleContext) OutputPath {
 func PathForVndkRefAbiDump(ctx ModuleContext, version, fileName string,
 	isNdk, isLlndkOrVndk, isGzip bool) OptionalPath {
 
+	var dirName string
+	if isNdk {
+		dirName = "ndk"
+	} else if isLlndkOrVndk {
+		dirName = "vndk"
+	} else {
+		dirName = "platform" // opt-in libs
+	}
+
+	return PathForRefAbiDump(ctx, dirName, version, fileName, isGzip)
+}
+
+// PathForRefAbiDump returns an OptionalPath representing the path of the reference abi dump for
+// the given module in the reference abi dump directory dirName, for example "vndk" or "vendor".
+// This is not guaranteed to be valid.
+func PathForRefAbiDump(ctx ModuleContext, dirName, version, fileName string, isGzip bool) OptionalPath {
 	arches := ctx.DeviceConfig().Arches()
 	if len(arches) == 0 {
 		panic("device build with no primary arch")
@@ -1118,15 +1146,6 @@ func PathForVndkRefAbiDump(ctx ModuleContext, version, fileName string,
 		archNameAndVariant += "_" + currentArch.ArchVariant
 	}
 
-	var dirName string
-	if isNdk {
-		dirName = "ndk"
-	} else if isLlndkOrVndk {
-		dirName = "vndk"
-	} else {
-		dirName = "platform" // opt-in libs
-	}
-
 	binderBitness := ctx.DeviceConfig().BinderBitness()
 
 	var ext string
@@ -1141,6 +1160,13 @@ func PathForVndkRefAbiDump(ctx ModuleContext, version, fileName string,
 		fileName+ext)
 }
 
+// PathForRefAbiLockFile returns an OptionalPath representing the path of the file that lists the
+// libraries whose ABI is locked in the reference abi dump directory dirName for the given
+// version.  This is not guaranteed to be valid.
+func PathForRefAbiLockFile(ctx PathContext, dirName, version string) OptionalPath {
+	return ExistentPathForSource(ctx, "prebuilts", "abi-dumps", dirName, version, "abi.lock")
+}
+
 // PathForModuleOut returns a Path representing the paths... under the module's
 // output directory.
 func PathForModuleOut(ctx ModuleContext, paths ...string) ModuleOutPath {
@@ -1326,7 +1352,9 @@ func InstallPathToOnDevicePath(ctx PathContext, path InstallPath) string {
 
 func modulePartition(ctx ModuleInstallPathContext) string {
 	var partition string
-	if ctx.InstallInData() {
+	if p := ctx.installPartition(); p != nil && p.includes(ctx) {
+		partition = p.path()
+	} else if ctx.InstallInData() {
 		partition = "data"
 	} else if ctx.InstallInTestcases() {
 		partition = "testcases"
@@ -69,6 +69,13 @@ type Prebuilt struct {
 
 	srcsSupplier     PrebuiltSrcsSupplier
 	srcsPropertyName string
+
+	// selectionReason explains why the prebuilt is or isn't used, for the prebuilt selection report.
+	selectionReason string
+
+	// preferredOverSource is true if the prebuilt is used instead of an enabled source module only
+	// because it sets prefer: true.
+	preferredOverSource bool
 }
 
 func (p *Prebuilt) Name(name string) string {
@@ -230,12 +237,12 @@ func PrebuiltSelectModuleMutator(ctx TopDownMutatorContext) {
 			panic(fmt.Errorf("prebuilt module did not have InitPrebuiltModule called on it"))
 		}
 		if !p.properties.SourceExists {
-			p.properties.UsePrebuilt = p.usePrebuilt(ctx, nil)
+			p.properties.UsePrebuilt = p.usePrebuilt(ctx, m, nil)
 		}
 	} else if s, ok := ctx.Module().(Module); ok {
 		ctx.VisitDirectDepsWithTag(PrebuiltDepTag, func(m Module) {
 			p := m.(PrebuiltInterface).Prebuilt()
-			if p.usePrebuilt(ctx, s) {
+			if p.usePrebuilt(ctx, m, s) {
 				p.properties.UsePrebuilt = true
 				s.SkipInstall()
 			}
@@ -269,18 +276,40 @@ func PrebuiltPostDepsMutator(ctx BottomUpMutatorContext) {
 }
 
 // usePrebuilt returns true if a prebuilt should be used instead of the source module.  The prebuilt
-// will be used if it is marked "prefer" or if the source module is disabled.
-func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module) bool {
+// will be used if the source module doesn't exist or is disabled, if the product's prebuilt policy
+// selects it, or if it is marked "prefer" and the policy doesn't make a choice.
+func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, prebuilt Module, source Module) bool {
+	usePrebuilt, preferred, reason := p.selectPrebuilt(ctx, prebuilt, source)
+	p.selectionReason = reason
+	p.preferredOverSource = preferred
+	return usePrebuilt
+}
+
+// selectPrebuilt returns whether the prebuilt should be used, whether it is only used because it is
+// marked "prefer", and the reason for the choice.
+func (p *Prebuilt) selectPrebuilt(ctx TopDownMutatorContext, prebuilt Module,
+	source Module) (usePrebuilt bool, preferred bool, reason string) {
+
 	if p.srcsSupplier != nil && len(p.srcsSupplier()) == 0 {
-		return false
+		return false, false, "prebuilt has no srcs"
 	}
 
-	// TODO: use p.Properties.Name and ctx.ModuleDir to override preference
-	if Bool(p.properties.Prefer) {
-		return true
+	if source == nil {
+		return true, false, "source module does not exist"
+	} else if !source.Enabled() {
+		return true, false, "source module is disabled"
+	}
+
+	names := []string{prebuilt.base().BaseModuleName(), ctx.OtherModuleName(prebuilt)}
+	dirs := []string{ctx.OtherModuleDir(prebuilt), ctx.ModuleDir()}
+	if usePrebuilt, reason, ok := ctx.Config().prebuiltPolicy().selection(names, dirs); ok {
+		return usePrebuilt, false, reason
 	}
 
-	return source == nil || !source.Enabled()
+	if Bool(p.properties.Prefer) {
+		return true, true, "prefer is true"
+	}
+	return false, false, "prefer is false"
 }
 
 func (p *Prebuilt) SourceExists() bool {
@@ -0,0 +1,179 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The prebuilt policy lets a product choose between the prebuilt and source versions of modules
+// without editing the prebuilt modules' prefer properties, e.g. to use source for a handful of
+// modules and prebuilts for everything else:
+//
+//   "PrebuiltPolicyDefault": "prebuilt",
+//   "PrebuiltPolicySourceModules": ["libfoo", "vendor/bar"],
+//
+// Entries in the PrebuiltPolicySourceModules and PrebuiltPolicyPrebuiltModules lists are either
+// module names, or directories, which contain a "/" and match every module in that directory or
+// its subdirectories.  An entry matches a prebuilt if it matches the name or directory of either
+// the prebuilt or the source module.  The lists take precedence over PrebuiltPolicyDefault, which
+// takes precedence over the prefer property of the prebuilt.  A prebuilt is still never used if
+// it has no srcs, and is always used if there is no enabled source module.
+
+func init() {
+	RegisterSingletonType("prebuilt_selection", prebuiltSelectionSingletonFactory)
+}
+
+const (
+	prebuiltPolicyDefaultSource   = "source"
+	prebuiltPolicyDefaultPrebuilt = "prebuilt"
+)
+
+const prebuiltSelectionFileName = "prebuilt_selection.json"
+
+type prebuiltPolicy struct {
+	defaultPolicy   string
+	sourceModules   []string
+	prebuiltModules []string
+}
+
+// newPrebuiltPolicy returns the prebuilt policy of the product, or an error if the product
+// variables that configure it are invalid.
+func newPrebuiltPolicy(c *config) (*prebuiltPolicy, error) {
+	policy := &prebuiltPolicy{
+		defaultPolicy:   String(c.productVariables.PrebuiltPolicyDefault),
+		sourceModules:   c.productVariables.PrebuiltPolicySourceModules,
+		prebuiltModules: c.productVariables.PrebuiltPolicyPrebuiltModules,
+	}
+
+	switch policy.defaultPolicy {
+	case "", prebuiltPolicyDefaultSource, prebuiltPolicyDefaultPrebuilt:
+	default:
+		return nil, fmt.Errorf("PrebuiltPolicyDefault must be %q or %q, found %q",
+			prebuiltPolicyDefaultSource, prebuiltPolicyDefaultPrebuilt, policy.defaultPolicy)
+	}
+
+	for _, entry := range policy.sourceModules {
+		if InList(entry, policy.prebuiltModules) {
+			return nil, fmt.Errorf("%q is in both PrebuiltPolicySourceModules and PrebuiltPolicyPrebuiltModules",
+				entry)
+		}
+	}
+
+	return policy, nil
+}
+
+var prebuiltPolicyKey = NewOnceKey("prebuiltPolicy")
+
+// prebuiltPolicy returns the product's policy for choosing between prebuilt and source modules.
+func (c *config) prebuiltPolicy() *prebuiltPolicy {
+	return c.Once(prebuiltPolicyKey, func() interface{} {
+		policy, err := newPrebuiltPolicy(c)
+		if err != nil {
+			// NewConfig has already validated the policy.
+			panic(err)
+		}
+		return policy
+	}).(*prebuiltPolicy)
+}
+
+// prebuiltPolicyMatch returns the first entry of list that matches any of the given module names
+// or directories.
+func prebuiltPolicyMatch(list []string, names, dirs []string) (string, bool) {
+	for _, entry := range list {
+		if strings.Contains(entry, "/") {
+			entryDir := strings.TrimSuffix(entry, "/")
+			for _, dir := range dirs {
+				if dir == entryDir || strings.HasPrefix(dir, entryDir+"/") {
+					return entry, true
+				}
+			}
+		} else if InList(entry, names) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// selection returns whether the policy selects the prebuilt or the source module for a module
+// with the given names and directories, along with the reason.  It returns ok == false if the
+// policy doesn't make a choice.
+func (p *prebuiltPolicy) selection(names, dirs []string) (usePrebuilt bool, reason string, ok bool) {
+	if entry, ok := prebuiltPolicyMatch(p.sourceModules, names, dirs); ok {
+		return false, fmt.Sprintf("%q is in PrebuiltPolicySourceModules", entry), true
+	}
+	if entry, ok := prebuiltPolicyMatch(p.prebuiltModules, names, dirs); ok {
+		return true, fmt.Sprintf("%q is in PrebuiltPolicyPrebuiltModules", entry), true
+	}
+	switch p.defaultPolicy {
+	case prebuiltPolicyDefaultSource:
+		return false, "PrebuiltPolicyDefault is source", true
+	case prebuiltPolicyDefaultPrebuilt:
+		return true, "PrebuiltPolicyDefault is prebuilt", true
+	}
+	return false, "", false
+}
+
+// prebuiltSelection is an entry in the prebuilt selection report.
+type prebuiltSelection struct {
+	Name         string
+	Dir          string
+	SourceExists bool
+	UsePrebuilt  bool
+	Reason       string
+}
+
+func prebuiltSelectionSingletonFactory() Singleton {
+	return &prebuiltSelectionSingleton{}
+}
+
+type prebuiltSelectionSingleton struct{}
+
+// GenerateBuildActions writes a report of the choices made between prebuilt and source modules,
+// and why they were made, to prebuilt_selection.json.
+func (s *prebuiltSelectionSingleton) GenerateBuildActions(ctx SingletonContext) {
+	seen := make(map[prebuiltSelection]bool)
+	selections := []prebuiltSelection{}
+	ctx.VisitAllModules(func(module Module) {
+		m, ok := module.(PrebuiltInterface)
+		if !ok || m.Prebuilt() == nil {
+			return
+		}
+		p := m.Prebuilt()
+		selection := prebuiltSelection{
+			Name:         m.base().BaseModuleName(),
+			Dir:          ctx.ModuleDir(module),
+			SourceExists: p.properties.SourceExists,
+			UsePrebuilt:  p.properties.UsePrebuilt,
+			Reason:       p.selectionReason,
+		}
+		// Variants of a module normally make the same choice, only report each choice once.
+		if !seen[selection] {
+			seen[selection] = true
+			selections = append(selections, selection)
+		}
+	})
+
+	sort.SliceStable(selections, func(i, j int) bool {
+		if selections[i].Name != selections[j].Name {
+			return selections[i].Name < selections[j].Name
+		}
+		return selections[i].Dir < selections[j].Dir
+	})
+
+	WriteJSONReport(ctx, PathForOutput(ctx, prebuiltSelectionFileName), selections, "prebuilt-selection")
+}
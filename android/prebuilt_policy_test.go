@@ -0,0 +1,204 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+var prebuiltPolicyFixtureFactory = NewFixtureFactory(&buildDir,
+	FixtureRegisterWithContext(registerTestPrebuiltBuildComponents),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterSingletonType("prebuilt_selection", prebuiltSelectionSingletonFactory)
+	}),
+	FixtureWithRootAndroidBp(`
+		source {
+			name: "foo",
+			deps: [":bar"],
+		}
+
+		source {
+			name: "bar",
+		}
+	`),
+	FixtureMergeMockFs(map[string][]byte{
+		"prebuilts/bar/prebuilt_file": nil,
+		"source_file":                 nil,
+	}),
+)
+
+func TestPrebuiltPolicy(t *testing.T) {
+	testCases := []struct {
+		name            string
+		prefer          bool
+		defaultPolicy   string
+		sourceModules   []string
+		prebuiltModules []string
+
+		prebuilt bool
+		reason   string
+	}{
+		{
+			name:     "no policy",
+			prebuilt: false,
+			reason:   "prefer is false",
+		},
+		{
+			name:     "no policy prefer",
+			prefer:   true,
+			prebuilt: true,
+			reason:   "prefer is true",
+		},
+		{
+			name:          "default prebuilt",
+			defaultPolicy: "prebuilt",
+			prebuilt:      true,
+			reason:        "PrebuiltPolicyDefault is prebuilt",
+		},
+		{
+			name:          "default source overrides prefer",
+			prefer:        true,
+			defaultPolicy: "source",
+			prebuilt:      false,
+			reason:        "PrebuiltPolicyDefault is source",
+		},
+		{
+			name:          "source module name overrides default",
+			defaultPolicy: "prebuilt",
+			sourceModules: []string{"baz", "bar"},
+			prebuilt:      false,
+			reason:        `"bar" is in PrebuiltPolicySourceModules`,
+		},
+		{
+			name:            "entry without a slash is a module name",
+			defaultPolicy:   "source",
+			prebuiltModules: []string{"prebuilts"},
+			prebuilt:        false,
+			reason:          "PrebuiltPolicyDefault is source",
+		},
+		{
+			name:            "prebuilt directory overrides default",
+			defaultPolicy:   "source",
+			prebuiltModules: []string{"prebuilts/"},
+			prebuilt:        true,
+			reason:          `"prebuilts/" is in PrebuiltPolicyPrebuiltModules`,
+		},
+		{
+			name:            "source list takes precedence over prebuilt list",
+			sourceModules:   []string{"bar"},
+			prebuiltModules: []string{"prebuilts/bar"},
+			prebuilt:        false,
+			reason:          `"bar" is in PrebuiltPolicySourceModules`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			result := prebuiltPolicyFixtureFactory.RunTest(t,
+				FixtureModifyConfig(func(config Config) {
+					if test.defaultPolicy != "" {
+						config.TestProductVariables.PrebuiltPolicyDefault = proptools.StringPtr(test.defaultPolicy)
+					}
+					config.TestProductVariables.PrebuiltPolicySourceModules = test.sourceModules
+					config.TestProductVariables.PrebuiltPolicyPrebuiltModules = test.prebuiltModules
+				}),
+				FixtureAddTextFile("prebuilts/bar/Android.bp", `
+					prebuilt {
+						name: "bar",
+						prefer: `+strconv.FormatBool(test.prefer)+`,
+						srcs: ["prebuilt_file"],
+					}
+				`),
+			)
+
+			foo := result.ModuleForTests("foo", "")
+			var dependsOnPrebuilt bool
+			result.VisitDirectDeps(foo.Module(), func(m blueprint.Module) {
+				if _, ok := m.(*prebuiltModule); ok {
+					dependsOnPrebuilt = true
+				}
+			})
+			if dependsOnPrebuilt != test.prebuilt {
+				t.Errorf("expected prebuilt to be used: %t, got %t", test.prebuilt, dependsOnPrebuilt)
+			}
+
+			result.SingletonForTests("prebuilt_selection").Output(prebuiltSelectionFileName)
+			data, err := ioutil.ReadFile(PathForOutput(PathContextForTesting(result.Config), prebuiltSelectionFileName).String())
+			if err != nil {
+				t.Fatalf("failed to read %s: %s", prebuiltSelectionFileName, err)
+			}
+			var selections []prebuiltSelection
+			if err := json.Unmarshal(data, &selections); err != nil {
+				t.Fatalf("failed to parse %s: %s", prebuiltSelectionFileName, err)
+			}
+
+			expected := []prebuiltSelection{
+				{
+					Name:         "bar",
+					Dir:          "prebuilts/bar",
+					SourceExists: true,
+					UsePrebuilt:  test.prebuilt,
+					Reason:       test.reason,
+				},
+			}
+			if !reflect.DeepEqual(selections, expected) {
+				t.Errorf("expected prebuilt selections:\n%#v\ngot:\n%#v", expected, selections)
+			}
+		})
+	}
+}
+
+func TestPrebuiltPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name            string
+		defaultPolicy   string
+		sourceModules   []string
+		prebuiltModules []string
+		err             string
+	}{
+		{
+			name:          "bad default",
+			defaultPolicy: "both",
+			err:           `PrebuiltPolicyDefault must be "source" or "prebuilt", found "both"`,
+		},
+		{
+			name:            "conflicting entries",
+			sourceModules:   []string{"foo", "bar"},
+			prebuiltModules: []string{"bar"},
+			err:             `"bar" is in both PrebuiltPolicySourceModules and PrebuiltPolicyPrebuiltModules`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			config := TestConfig(buildDir, nil, "", nil)
+			config.TestProductVariables.PrebuiltPolicyDefault = proptools.StringPtr(test.defaultPolicy)
+			config.TestProductVariables.PrebuiltPolicySourceModules = test.sourceModules
+			config.TestProductVariables.PrebuiltPolicyPrebuiltModules = test.prebuiltModules
+
+			_, err := newPrebuiltPolicy(config.config)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
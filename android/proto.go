@@ -101,7 +101,8 @@ type ProtoProperties struct {
 		// Proto generator type.  C++: full or lite.  Java: micro, nano, stream, or lite.
 		Type *string `android:"arch_variant"`
 
-		// Proto plugin to use as the generator.  Must be a cc_binary_host module.
+		// Proto plugin to use as the generator.  Must be a cc_binary_host module.  For C++,
+		// grpc-cpp generates gRPC services in addition to the messages using grpc_cpp_plugin.
 		Plugin *string `android:"arch_variant"`
 
 		// list of directories that will be added to the protoc include paths.
@@ -104,6 +104,9 @@ func (ctx *Context) Register() {
 
 	registerMutators(ctx.Context, preArch, preDeps, postDeps, finalDeps)
 
+	// Register build action validation after other singletons so it can validate their build actions
+	ctx.RegisterSingletonType("build_action_validation", SingletonFactoryAdaptor(buildActionValidationSingletonFactory))
+
 	// Register phony just before makevars so it can write out its phony rules as Make rules
 	ctx.RegisterSingletonType("phony", SingletonFactoryAdaptor(phonySingletonFactory))
 
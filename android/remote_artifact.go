@@ -0,0 +1,280 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// Remote artifacts are files that are fetched from a content-addressed store at build time instead
+// of being checked into the source tree.  They are identified by the digest of their contents, and
+// the fetched file is verified against the digest before it is used, e.g.
+//
+//   remote_artifact {
+//       name: "Foo.apk",
+//       digest: "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+//   }
+//
+//   android_app_import {
+//       name: "Foo",
+//       apk: ":Foo.apk",
+//   }
+//
+// Modules can also reference remote artifacts directly with PathForRemoteArtifact.  Each artifact
+// is fetched once, however many modules reference it, by the tool that the RemoteArtifactFetcher
+// product variable points to.  It is run as
+// "<fetcher> --digest sha256:<hex> --output <file>".
+
+func init() {
+	RegisterRemoteArtifactBuildComponents(InitRegistrationContext)
+}
+
+func RegisterRemoteArtifactBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("remote_artifact", RemoteArtifactFactory)
+	ctx.RegisterSingletonType("remote_artifacts", remoteArtifactsSingletonFactory)
+}
+
+var (
+	remoteArtifactFetch = pctx.AndroidStaticRule("remoteArtifactFetch",
+		blueprint.RuleParams{
+			Command:     "rm -f $out && $fetcher --digest $digest --output $out",
+			Description: "fetch $digest",
+		},
+		"fetcher", "digest")
+
+	remoteArtifactVerify = pctx.AndroidStaticRule("remoteArtifactVerify",
+		blueprint.RuleParams{
+			Command: `rm -f $out && hash=$$(sha256sum $in | cut -d' ' -f1) && ` +
+				`if [ "$$hash" != "$hash" ]; then ` +
+				`echo "$in: expected sha256 $hash, got $$hash" >&2; exit 1; fi && ` +
+				`cp $in $out`,
+			Description: "verify $out",
+		},
+		"hash")
+)
+
+const remoteArtifactDigestAlgorithm = "sha256"
+
+var remoteArtifactHashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// parseRemoteArtifactDigest returns the hash of a digest of the form "sha256:<hex>".
+func parseRemoteArtifactDigest(digest string) (string, error) {
+	algorithm, hash := "", digest
+	if i := strings.IndexByte(digest, ':'); i >= 0 {
+		algorithm, hash = digest[:i], digest[i+1:]
+	}
+	if algorithm != remoteArtifactDigestAlgorithm {
+		return "", fmt.Errorf("digest %q must be of the form %q", digest,
+			remoteArtifactDigestAlgorithm+":<hex>")
+	}
+	if !remoteArtifactHashRegexp.MatchString(hash) {
+		return "", fmt.Errorf("digest %q must have a hash of 64 lower case hex digits", digest)
+	}
+	return hash, nil
+}
+
+// RemoteArtifactPath is a Path to a file that is fetched from a content-addressed store and
+// verified against the digest of its contents.
+type RemoteArtifactPath struct {
+	OutputPath
+
+	hash string
+}
+
+var _ Path = RemoteArtifactPath{}
+
+// Digest returns the digest of the contents of the file, in the form "sha256:<hex>".
+func (p RemoteArtifactPath) Digest() string {
+	return remoteArtifactDigestAlgorithm + ":" + p.hash
+}
+
+// PathForRemoteArtifact returns a Path to the file with the given digest, in the form
+// "sha256:<hex>", and name, and arranges for the build to fetch and verify the file.
+func PathForRemoteArtifact(ctx PathContext, digest string, name string) RemoteArtifactPath {
+	hash, err := parseRemoteArtifactDigest(digest)
+	if err != nil {
+		reportPathError(ctx, err)
+		return RemoteArtifactPath{OutputPath: PathForOutput(ctx, "remote_artifacts", "invalid", name)}
+	}
+	if strings.Contains(name, "/") {
+		reportPathErrorf(ctx, "remote artifact name %q must not contain a /", name)
+	}
+
+	path := RemoteArtifactPath{
+		OutputPath: PathForOutput(ctx, "remote_artifacts", remoteArtifactDigestAlgorithm, hash, name),
+		hash:       hash,
+	}
+	remoteArtifactsForConfig(ctx.Config()).add(path)
+	return path
+}
+
+var remoteArtifactsKey = NewOnceKey("remoteArtifacts")
+
+// remoteArtifacts holds the remote artifacts referenced by the build, so that the
+// remote_artifacts singleton can create a single fetch rule for each of them.
+type remoteArtifacts struct {
+	sync.Mutex
+	paths map[string]RemoteArtifactPath
+}
+
+func remoteArtifactsForConfig(config Config) *remoteArtifacts {
+	return config.Once(remoteArtifactsKey, func() interface{} {
+		return &remoteArtifacts{paths: make(map[string]RemoteArtifactPath)}
+	}).(*remoteArtifacts)
+}
+
+func (r *remoteArtifacts) add(path RemoteArtifactPath) {
+	r.Lock()
+	defer r.Unlock()
+	r.paths[path.String()] = path
+}
+
+// sorted returns the remote artifacts ordered by path.
+func (r *remoteArtifacts) sorted() []RemoteArtifactPath {
+	r.Lock()
+	defer r.Unlock()
+	keys := make([]string, 0, len(r.paths))
+	for key := range r.paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ret := make([]RemoteArtifactPath, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, r.paths[key])
+	}
+	return ret
+}
+
+func remoteArtifactsSingletonFactory() Singleton {
+	return &remoteArtifactsSingleton{}
+}
+
+type remoteArtifactsSingleton struct{}
+
+func (s *remoteArtifactsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	artifacts := remoteArtifactsForConfig(ctx.Config()).sorted()
+	if len(artifacts) == 0 {
+		return
+	}
+
+	fetcherPath := ctx.Config().RemoteArtifactFetcher()
+	if fetcherPath == "" {
+		ctx.Errorf("remote artifacts are referenced by the build, but the product doesn't set " +
+			"RemoteArtifactFetcher to the tool that fetches them")
+		return
+	}
+	fetcherOptionalPath := ExistentPathForSource(ctx, fetcherPath)
+	if !fetcherOptionalPath.Valid() {
+		ctx.Errorf("RemoteArtifactFetcher %q doesn't exist", fetcherPath)
+		return
+	}
+	fetcher := fetcherOptionalPath.Path()
+
+	var outputs Paths
+	for _, artifact := range artifacts {
+		fetched := PathForOutput(ctx, "remote_artifacts", "fetched", remoteArtifactDigestAlgorithm,
+			artifact.hash, artifact.Base())
+		ctx.Build(pctx, BuildParams{
+			Rule:     remoteArtifactFetch,
+			Output:   fetched,
+			Implicit: fetcher,
+			Args: map[string]string{
+				"fetcher": fetcher.String(),
+				"digest":  artifact.Digest(),
+			},
+		})
+		ctx.Build(pctx, BuildParams{
+			Rule:   remoteArtifactVerify,
+			Input:  fetched,
+			Output: artifact,
+			Args: map[string]string{
+				"hash": artifact.hash,
+			},
+		})
+		outputs = append(outputs, artifact)
+	}
+
+	// Allow all of the remote artifacts to be fetched ahead of a build with m remote-artifacts.
+	ctx.Phony("remote-artifacts", outputs...)
+}
+
+type remoteArtifactProperties struct {
+	// The digest of the contents of the artifact, in the form "sha256:<hex>".
+	Digest *string
+
+	// The name of the fetched file.  Defaults to the name of the module.
+	Filename *string
+}
+
+// RemoteArtifact is a module for a single file that is fetched from a content-addressed store.
+// Other modules can reference the file with ":<name>" in their source properties.
+type RemoteArtifact struct {
+	ModuleBase
+
+	properties remoteArtifactProperties
+
+	outputFile RemoteArtifactPath
+}
+
+var _ SourceFileProducer = (*RemoteArtifact)(nil)
+var _ OutputFileProducer = (*RemoteArtifact)(nil)
+
+func RemoteArtifactFactory() Module {
+	module := &RemoteArtifact{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+func (r *RemoteArtifact) GenerateAndroidBuildActions(ctx ModuleContext) {
+	digest := String(r.properties.Digest)
+	if digest == "" {
+		ctx.PropertyErrorf("digest", "missing digest")
+		return
+	}
+	if _, err := parseRemoteArtifactDigest(digest); err != nil {
+		ctx.PropertyErrorf("digest", "%s", err)
+		return
+	}
+
+	filename := proptools.StringDefault(r.properties.Filename, ctx.ModuleName())
+	if strings.Contains(filename, "/") {
+		ctx.PropertyErrorf("filename", "filename %q must not contain a /", filename)
+		return
+	}
+
+	r.outputFile = PathForRemoteArtifact(ctx, digest, filename)
+}
+
+func (r *RemoteArtifact) Srcs() Paths {
+	return Paths{r.outputFile}
+}
+
+func (r *RemoteArtifact) OutputFiles(tag string) (Paths, error) {
+	switch tag {
+	case "":
+		return Paths{r.outputFile}, nil
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+const testRemoteArtifactHash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+type remoteArtifactTestModule struct {
+	ModuleBase
+	properties struct {
+		Srcs []string `android:"path"`
+	}
+
+	srcs   Paths
+	direct RemoteArtifactPath
+}
+
+func remoteArtifactTestModuleFactory() Module {
+	m := &remoteArtifactTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *remoteArtifactTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.srcs = PathsForModuleSrc(ctx, m.properties.Srcs)
+	m.direct = PathForRemoteArtifact(ctx, "sha256:"+testRemoteArtifactHash, "Foo.apk")
+}
+
+const testRemoteArtifactFetcher = "build/tools/fetch_artifact"
+
+var prepareForTestWithRemoteArtifactFetcher = GroupFixturePreparers(
+	FixtureAddTextFile(testRemoteArtifactFetcher, ""),
+	FixtureModifyConfig(func(config Config) {
+		config.TestProductVariables.RemoteArtifactFetcher = proptools.StringPtr(testRemoteArtifactFetcher)
+	}),
+)
+
+var remoteArtifactFixtureFactory = NewFixtureFactory(
+	&buildDir,
+	FixtureRegisterWithContext(RegisterRemoteArtifactBuildComponents),
+	FixtureRegisterModuleType("test", remoteArtifactTestModuleFactory),
+	prepareForTestWithRemoteArtifactFetcher,
+)
+
+func TestRemoteArtifact(t *testing.T) {
+	result := remoteArtifactFixtureFactory.RunTestWithBp(t, `
+		remote_artifact {
+			name: "foo",
+			digest: "sha256:`+testRemoteArtifactHash+`",
+			filename: "Foo.apk",
+		}
+
+		test {
+			name: "bar",
+			srcs: [":foo"],
+		}
+	`)
+
+	wantPath := "remote_artifacts/sha256/" + testRemoteArtifactHash + "/Foo.apk"
+
+	bar := result.ModuleForTests("bar", "").Module().(*remoteArtifactTestModule)
+	if g, w := NormalizePathsForTesting(bar.srcs), []string{wantPath}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected srcs %q, got %q", w, g)
+	}
+	if g, w := NormalizePathForTesting(bar.direct), wantPath; g != w {
+		t.Errorf("expected direct path %q, got %q", w, g)
+	}
+	if g, w := bar.direct.Digest(), "sha256:"+testRemoteArtifactHash; g != w {
+		t.Errorf("expected digest %q, got %q", w, g)
+	}
+
+	// The artifact is referenced twice, but must only be fetched once.
+	singleton := result.SingletonForTests("remote_artifacts")
+	var fetches, verifies int
+	for _, output := range singleton.AllOutputs() {
+		switch singleton.Output(output).Rule {
+		case remoteArtifactFetch:
+			fetches++
+		case remoteArtifactVerify:
+			verifies++
+		}
+	}
+	if fetches != 1 || verifies != 1 {
+		t.Errorf("expected 1 fetch and 1 verify rule, got %d and %d", fetches, verifies)
+	}
+
+	verify := singleton.Output(wantPath)
+	if g, w := verify.Args["hash"], testRemoteArtifactHash; g != w {
+		t.Errorf("expected verified hash %q, got %q", w, g)
+	}
+	fetch := singleton.Output("remote_artifacts/fetched/sha256/" + testRemoteArtifactHash + "/Foo.apk")
+	if g, w := fetch.Args["digest"], "sha256:"+testRemoteArtifactHash; g != w {
+		t.Errorf("expected fetched digest %q, got %q", w, g)
+	}
+	if g, w := verify.Input.String(), fetch.Output.String(); g != w {
+		t.Errorf("expected the verify rule to check the fetched file %q, got %q", w, g)
+	}
+	if g, w := fetch.Args["fetcher"], testRemoteArtifactFetcher; g != w {
+		t.Errorf("expected fetcher %q, got %q", w, g)
+	}
+	if g, w := fetch.Implicit.String(), testRemoteArtifactFetcher; g != w {
+		t.Errorf("expected the fetch rule to depend on the fetcher %q, got %q", w, g)
+	}
+}
+
+func TestRemoteArtifactFetcherErrors(t *testing.T) {
+	bp := `
+		remote_artifact {
+			name: "foo",
+			digest: "sha256:` + testRemoteArtifactHash + `",
+		}
+	`
+	fixtureFactory := NewFixtureFactory(
+		&buildDir,
+		FixtureRegisterWithContext(RegisterRemoteArtifactBuildComponents),
+		FixtureWithRootAndroidBp(bp),
+	)
+
+	t.Run("unset", func(t *testing.T) {
+		fixtureFactory.RunTestExpectingError(t,
+			`the product doesn't set RemoteArtifactFetcher to the tool that fetches them`)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		fixtureFactory.RunTestExpectingError(t, `RemoteArtifactFetcher "build/tools/fetch_artifact" doesn't exist`,
+			FixtureModifyConfig(func(config Config) {
+				config.TestProductVariables.RemoteArtifactFetcher = proptools.StringPtr(testRemoteArtifactFetcher)
+			}))
+	})
+}
+
+func TestRemoteArtifactErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bp    string
+		error string
+	}{
+		{
+			name:  "missing digest",
+			bp:    `remote_artifact { name: "foo" }`,
+			error: `digest: missing digest`,
+		},
+		{
+			name:  "unsupported algorithm",
+			bp:    `remote_artifact { name: "foo", digest: "md5:d41d8cd98f00b204e9800998ecf8427e" }`,
+			error: `must be of the form "sha256:<hex>"`,
+		},
+		{
+			name:  "invalid hash",
+			bp:    `remote_artifact { name: "foo", digest: "sha256:ABCD" }`,
+			error: `must have a hash of 64 lower case hex digits`,
+		},
+		{
+			name: "filename with directory",
+			bp: `remote_artifact {
+				name: "foo",
+				digest: "sha256:` + testRemoteArtifactHash + `",
+				filename: "dir/Foo.apk",
+			}`,
+			error: `filename: filename "dir/Foo.apk" must not contain a /`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			remoteArtifactFixtureFactory.RunTestExpectingError(t, testCase.error,
+				FixtureWithRootAndroidBp(testCase.bp))
+		})
+	}
+}
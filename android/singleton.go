@@ -157,6 +157,7 @@ func (s *singletonContextAdaptor) Build(pctx PackageContext, params BuildParams)
 	if s.Config().captureBuild {
 		s.buildParams = append(s.buildParams, params)
 	}
+	recordBuildActionForValidation(s.Config(), params)
 	bparams := convertBuildParams(params)
 	s.SingletonContext.Build(pctx.PackageContext, bparams)
 
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 	"text/scanner"
 
 	"github.com/google/blueprint"
@@ -35,6 +36,8 @@ func init() {
 	RegisterModuleType("soong_config_module_type", soongConfigModuleTypeFactory)
 	RegisterModuleType("soong_config_string_variable", soongConfigStringVariableDummyFactory)
 	RegisterModuleType("soong_config_bool_variable", soongConfigBoolVariableDummyFactory)
+	RegisterModuleType("soong_config_int_variable", soongConfigIntVariableDummyFactory)
+	RegisterModuleType("soong_config_list_variable", soongConfigListVariableDummyFactory)
 }
 
 type soongConfigModuleTypeImport struct {
@@ -233,7 +236,20 @@ type soongConfigStringVariableDummyModule struct {
 
 type soongConfigBoolVariableDummyModule struct {
 	ModuleBase
-	properties soongconfig.VariableProperties
+	properties     soongconfig.VariableProperties
+	boolProperties soongconfig.BoolVariableProperties
+}
+
+type soongConfigIntVariableDummyModule struct {
+	ModuleBase
+	properties    soongconfig.VariableProperties
+	intProperties soongconfig.IntVariableProperties
+}
+
+type soongConfigListVariableDummyModule struct {
+	ModuleBase
+	properties     soongconfig.VariableProperties
+	listProperties soongconfig.ListVariableProperties
 }
 
 // soong_config_string_variable defines a variable and a set of possible string values for use
@@ -249,7 +265,27 @@ func soongConfigStringVariableDummyFactory() Module {
 // in a soong_config_module_type definition.
 func soongConfigBoolVariableDummyFactory() Module {
 	module := &soongConfigBoolVariableDummyModule{}
-	module.AddProperties(&module.properties)
+	module.AddProperties(&module.properties, &module.boolProperties)
+	initAndroidModuleBase(module)
+	return module
+}
+
+// soong_config_int_variable defines a variable with integer values for use in a
+// soong_config_module_type definition.  The %s in the properties set for the variable are
+// replaced by the value.
+func soongConfigIntVariableDummyFactory() Module {
+	module := &soongConfigIntVariableDummyModule{}
+	module.AddProperties(&module.properties, &module.intProperties)
+	initAndroidModuleBase(module)
+	return module
+}
+
+// soong_config_list_variable defines a variable with a space separated list of values for use in
+// a soong_config_module_type definition.  Each entry with a %s in the list properties set for the
+// variable is replaced by an entry for each value in the list.
+func soongConfigListVariableDummyFactory() Module {
+	module := &soongConfigListVariableDummyModule{}
+	module.AddProperties(&module.properties, &module.listProperties)
 	initAndroidModuleBase(module)
 	return module
 }
@@ -266,6 +302,18 @@ func (m *soongConfigBoolVariableDummyModule) Name() string {
 func (*soongConfigBoolVariableDummyModule) Nameless()                                     {}
 func (*soongConfigBoolVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
 
+func (m *soongConfigIntVariableDummyModule) Name() string {
+	return m.properties.Name
+}
+func (*soongConfigIntVariableDummyModule) Nameless()                                     {}
+func (*soongConfigIntVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+func (m *soongConfigListVariableDummyModule) Name() string {
+	return m.properties.Name
+}
+func (*soongConfigListVariableDummyModule) Nameless()                                     {}
+func (*soongConfigListVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
 func importModuleTypes(ctx LoadHookContext, from string, moduleTypes ...string) {
 	from = filepath.Clean(from)
 	if filepath.Ext(from) != ".bp" {
@@ -324,6 +372,8 @@ func loadSoongConfigModuleTypeDefinition(ctx LoadHookContext, from string) map[s
 			return (map[string]blueprint.ModuleFactory)(nil)
 		}
 
+		soongConfigDeclarationsForConfig(ctx.Config()).add(mtDef.Declarations())
+
 		globalModuleTypes := ctx.moduleFactories()
 
 		factories := make(map[string]blueprint.ModuleFactory)
@@ -378,3 +428,106 @@ func soongConfigModuleFactory(factory blueprint.ModuleFactory,
 		return factory
 	}
 }
+
+var soongConfigDeclarationsKey = NewOnceKey("soongConfigDeclarations")
+
+// soongConfigDeclarations holds the variables declared for the config namespaces of the
+// soong_config_module_type modules that have been loaded.
+type soongConfigDeclarations struct {
+	sync.Mutex
+	namespaces map[string]map[string]soongconfig.Declaration
+}
+
+func soongConfigDeclarationsForConfig(config Config) *soongConfigDeclarations {
+	return config.Once(soongConfigDeclarationsKey, func() interface{} {
+		return &soongConfigDeclarations{namespaces: make(map[string]map[string]soongconfig.Declaration)}
+	}).(*soongConfigDeclarations)
+}
+
+func (d *soongConfigDeclarations) add(namespaces map[string]map[string]soongconfig.Declaration) {
+	d.Lock()
+	defer d.Unlock()
+	for namespace, declarations := range namespaces {
+		if d.namespaces[namespace] == nil {
+			d.namespaces[namespace] = make(map[string]soongconfig.Declaration)
+		}
+		for name, declaration := range declarations {
+			d.namespaces[namespace][name] = declaration
+		}
+	}
+}
+
+// soongConfigDeclaration returns the declaration of the variable name in namespace, or reports an
+// error if no soong_config_module_type in the namespace reads a declared variable with that name.
+func soongConfigDeclaration(ctx BaseModuleContext, namespace, name string) (soongconfig.Declaration, bool) {
+	d := soongConfigDeclarationsForConfig(ctx.Config())
+	d.Lock()
+	declaration, ok := d.namespaces[namespace][name]
+	d.Unlock()
+	if !ok {
+		ctx.ModuleErrorf("soong config namespace %s: variable %q is not declared by a soong_config_module_type in the namespace",
+			namespace, name)
+	}
+	return declaration, ok
+}
+
+// The following functions give module code typed access to the Soong config variables in a
+// namespace.  The variables must be declared and read by a soong_config_module_type in the
+// namespace, and the functions return the declared default value if the variable is not set.  An
+// error is reported if the value is not valid for the declaration of the variable.
+
+// SoongConfigBool returns the value of the variable name in namespace, which must be declared with
+// soong_config_bool_variable.
+func SoongConfigBool(ctx BaseModuleContext, namespace, name string) bool {
+	declaration, ok := soongConfigDeclaration(ctx, namespace, name)
+	if !ok {
+		return false
+	}
+	value, err := declaration.Bool(ctx.Config().VendorConfig(namespace))
+	if err != nil {
+		ctx.ModuleErrorf("soong config namespace %s: %s", namespace, err)
+	}
+	return value
+}
+
+// SoongConfigInt returns the value of the variable name in namespace, which must be declared with
+// soong_config_int_variable.
+func SoongConfigInt(ctx BaseModuleContext, namespace, name string) int64 {
+	declaration, ok := soongConfigDeclaration(ctx, namespace, name)
+	if !ok {
+		return 0
+	}
+	value, err := declaration.Int(ctx.Config().VendorConfig(namespace))
+	if err != nil {
+		ctx.ModuleErrorf("soong config namespace %s: %s", namespace, err)
+	}
+	return value
+}
+
+// SoongConfigEnum returns the value of the variable name in namespace, which must be declared with
+// soong_config_string_variable.
+func SoongConfigEnum(ctx BaseModuleContext, namespace, name string) string {
+	declaration, ok := soongConfigDeclaration(ctx, namespace, name)
+	if !ok {
+		return ""
+	}
+	value, err := declaration.Enum(ctx.Config().VendorConfig(namespace))
+	if err != nil {
+		ctx.ModuleErrorf("soong config namespace %s: %s", namespace, err)
+	}
+	return value
+}
+
+// SoongConfigList returns the values of the variable name in namespace, which must be declared
+// with soong_config_list_variable.
+func SoongConfigList(ctx BaseModuleContext, namespace, name string) []string {
+	declaration, ok := soongConfigDeclaration(ctx, namespace, name)
+	if !ok {
+		return nil
+	}
+	value, err := declaration.List(ctx.Config().VendorConfig(namespace))
+	if err != nil {
+		ctx.ModuleErrorf("soong config namespace %s: %s", namespace, err)
+	}
+	return value
+}
@@ -141,3 +141,266 @@ func TestSoongConfigModule(t *testing.T) {
 		})
 	})
 }
+
+func testSoongConfigTypedVariables(bp string, vars map[string]string) (*TestContext, []error) {
+	configBp := `
+		soong_config_module_type {
+			name: "acme_test_defaults",
+			module_type: "test_defaults",
+			config_namespace: "acme",
+			variables: ["board", "feature", "width", "codecs"],
+			properties: ["cflags"],
+		}
+
+		soong_config_string_variable {
+			name: "board",
+			values: ["soc_a", "soc_b"],
+			default: "soc_b",
+		}
+
+		soong_config_bool_variable {
+			name: "feature",
+			default: true,
+		}
+
+		soong_config_int_variable {
+			name: "width",
+			default: 100,
+		}
+
+		soong_config_list_variable {
+			name: "codecs",
+			values: ["h264", "vp9"],
+			default: ["h264"],
+		}
+	`
+
+	config := TestConfig(buildDir, nil, configBp+bp, nil)
+	config.TestProductVariables.VendorVars = map[string]map[string]string{
+		"acme": vars,
+	}
+
+	ctx := NewTestContext()
+	ctx.RegisterModuleType("soong_config_module_type", soongConfigModuleTypeFactory)
+	ctx.RegisterModuleType("soong_config_string_variable", soongConfigStringVariableDummyFactory)
+	ctx.RegisterModuleType("soong_config_bool_variable", soongConfigBoolVariableDummyFactory)
+	ctx.RegisterModuleType("soong_config_int_variable", soongConfigIntVariableDummyFactory)
+	ctx.RegisterModuleType("soong_config_list_variable", soongConfigListVariableDummyFactory)
+	ctx.RegisterModuleType("test_defaults", soongConfigTestModuleFactory)
+	ctx.RegisterModuleType("typed_test", soongConfigTypedTestModuleFactory)
+	ctx.Register(config)
+
+	_, errs := ctx.ParseBlueprintsFiles("Android.bp")
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
+
+// soongConfigTypedTestModule reads the acme variables with the typed accessors.
+type soongConfigTypedTestModule struct {
+	ModuleBase
+	properties struct {
+		Bool_variable string
+	}
+
+	board   string
+	feature bool
+	width   int64
+	codecs  []string
+}
+
+func soongConfigTypedTestModuleFactory() Module {
+	m := &soongConfigTypedTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *soongConfigTypedTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.board = SoongConfigEnum(ctx, "acme", "board")
+	m.feature = SoongConfigBool(ctx, "acme", m.properties.Bool_variable)
+	m.width = SoongConfigInt(ctx, "acme", "width")
+	m.codecs = SoongConfigList(ctx, "acme", "codecs")
+}
+
+func TestSoongConfigTypedAccessors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		vars    map[string]string
+		board   string
+		feature bool
+		width   int64
+		codecs  []string
+	}{
+		{
+			name:    "defaults",
+			board:   "soc_b",
+			feature: true,
+			width:   100,
+			codecs:  []string{"h264"},
+		},
+		{
+			name: "set",
+			vars: map[string]string{
+				"board":   "soc_a",
+				"feature": "maybe",
+				"width":   "200",
+				"codecs":  "h264 vp9",
+			},
+			board:   "soc_a",
+			feature: false,
+			width:   200,
+			codecs:  []string{"h264", "vp9"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, errs := testSoongConfigTypedVariables(`
+				typed_test {
+					name: "foo",
+					bool_variable: "feature",
+				}
+			`, testCase.vars)
+			FailIfErrored(t, errs)
+
+			foo := ctx.ModuleForTests("foo", "").Module().(*soongConfigTypedTestModule)
+			if g, w := foo.board, testCase.board; g != w {
+				t.Errorf("wanted board %q, got %q", w, g)
+			}
+			if g, w := foo.feature, testCase.feature; g != w {
+				t.Errorf("wanted feature %v, got %v", w, g)
+			}
+			if g, w := foo.width, testCase.width; g != w {
+				t.Errorf("wanted width %d, got %d", w, g)
+			}
+			if g, w := foo.codecs, testCase.codecs; !reflect.DeepEqual(g, w) {
+				t.Errorf("wanted codecs %q, got %q", w, g)
+			}
+		})
+	}
+
+	t.Run("undeclared", func(t *testing.T) {
+		_, errs := testSoongConfigTypedVariables(`
+			typed_test {
+				name: "foo",
+				bool_variable: "unknown",
+			}
+		`, nil)
+		FailIfNoMatchingErrors(t, `variable "unknown" is not declared by a soong_config_module_type`, errs)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, errs := testSoongConfigTypedVariables(`
+			typed_test {
+				name: "foo",
+				bool_variable: "width",
+			}
+		`, nil)
+		FailIfNoMatchingErrors(t, `variable "width" is not declared with soong_config_bool_variable`, errs)
+	})
+}
+
+func TestSoongConfigModuleTypedVariables(t *testing.T) {
+	bp := `
+		acme_test_defaults {
+			name: "foo",
+			soong_config_variables: {
+				board: {
+					soc_a: {
+						cflags: ["-DSOC_A"],
+					},
+					soc_b: {
+						cflags: ["-DSOC_B"],
+					},
+				},
+				feature: {
+					cflags: ["-DFEATURE"],
+				},
+				width: {
+					cflags: ["-DWIDTH=%s"],
+				},
+				codecs: {
+					cflags: ["-DCODECS", "-DCODEC_%s"],
+				},
+			},
+		}
+	`
+
+	testCases := []struct {
+		name string
+		vars map[string]string
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"-DSOC_B", "-DFEATURE", "-DWIDTH=100", "-DCODECS", "-DCODEC_h264"},
+		},
+		{
+			name: "set",
+			vars: map[string]string{
+				"board":   "soc_a",
+				"feature": "false",
+				"width":   "200",
+				"codecs":  "h264 vp9",
+			},
+			want: []string{"-DSOC_A", "-DWIDTH=200", "-DCODECS", "-DCODEC_h264", "-DCODEC_vp9"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, errs := testSoongConfigTypedVariables(bp, testCase.vars)
+			FailIfErrored(t, errs)
+
+			foo := ctx.ModuleForTests("foo", "").Module().(*soongConfigTestModule)
+			if g, w := foo.props.Cflags, testCase.want; !reflect.DeepEqual(g, w) {
+				t.Errorf("wanted foo cflags %q, got %q", w, g)
+			}
+		})
+	}
+}
+
+func TestSoongConfigModuleInvalidValues(t *testing.T) {
+	bp := `
+		acme_test_defaults {
+			name: "foo",
+		}
+	`
+
+	testCases := []struct {
+		name string
+		vars map[string]string
+		err  string
+	}{
+		{
+			name: "unknown string value",
+			vars: map[string]string{"board": "soc_c"},
+			err:  `unknown value "soc_c" for variable "board", must be one of \["soc_a" "soc_b"\]`,
+		},
+		{
+			name: "invalid bool value",
+			vars: map[string]string{"feature": "maybe"},
+			err:  `invalid value "maybe" for bool variable "feature"`,
+		},
+		{
+			name: "invalid int value",
+			vars: map[string]string{"width": "wide"},
+			err:  `invalid value "wide" for int variable "width"`,
+		},
+		{
+			name: "unknown list value",
+			vars: map[string]string{"codecs": "h264 av1"},
+			err:  `unknown value "av1" in list variable "codecs"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, errs := testSoongConfigTypedVariables(bp, testCase.vars)
+			FailIfNoMatchingErrors(t, testCase.err, errs)
+		})
+	}
+}
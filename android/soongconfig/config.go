@@ -14,7 +14,11 @@
 
 package soongconfig
 
-import "strings"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type SoongConfig interface {
 	// Bool interprets the variable named `name` as a boolean, returning true if, after
@@ -49,3 +53,74 @@ func (c soongConfig) IsSet(name string) bool {
 	_, ok := c[name]
 	return ok
 }
+
+// The following functions return the value of a typed variable, or its default value if the
+// variable is not set or is empty, and an error if the value is not valid for the type.
+
+// BoolValue returns the value of the bool variable name, which must be one of "1", "y", "yes",
+// "on", "true", "0", "n", "no", "off" or "false" after lowercasing.
+func BoolValue(config SoongConfig, name string, defaultValue bool) (bool, error) {
+	v := config.String(name)
+	switch strings.ToLower(v) {
+	case "":
+		return defaultValue, nil
+	case "1", "y", "yes", "on", "true":
+		return true, nil
+	case "0", "n", "no", "off", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value %q for bool variable %q", v, name)
+}
+
+// IntValue returns the value of the int variable name.
+func IntValue(config SoongConfig, name string, defaultValue int64) (int64, error) {
+	v := config.String(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.ParseInt(v, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for int variable %q", v, name)
+	}
+	return i, nil
+}
+
+// EnumValue returns the value of the variable name, which must be one of values.
+func EnumValue(config SoongConfig, name string, values []string, defaultValue string) (string, error) {
+	v := config.String(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	if !inList(v, values) {
+		return "", fmt.Errorf("unknown value %q for variable %q, must be one of %q", v, name, values)
+	}
+	return v, nil
+}
+
+// ListValue returns the space separated values of the list variable name.  If values is not
+// empty each entry of the list must be one of values.
+func ListValue(config SoongConfig, name string, values []string, defaultValue []string) ([]string, error) {
+	v := config.String(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	list := strings.Fields(v)
+	if len(values) > 0 {
+		for _, entry := range list {
+			if !inList(entry, values) {
+				return nil, fmt.Errorf("unknown value %q in list variable %q, must be one of %q",
+					entry, name, values)
+			}
+		}
+	}
+	return list, nil
+}
+
+func inList(s string, list []string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package soongconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func checkValueError(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	} else if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got %v", want, err)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	config := Config(map[string]string{
+		"yes":     "Yes",
+		"off":     "off",
+		"maybe":   "maybe",
+		"width":   "0x20",
+		"wide":    "wide",
+		"board":   "soc_a",
+		"codecs":  " h264  vp9 ",
+		"unknown": "soc_c",
+		"empty":   "",
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		tests := []struct {
+			name, variable string
+			defaultValue   bool
+			want           bool
+			err            string
+		}{
+			{name: "true", variable: "yes", want: true},
+			{name: "false", variable: "off", defaultValue: true, want: false},
+			{name: "unset", variable: "unset", defaultValue: true, want: true},
+			{name: "empty", variable: "empty", defaultValue: true, want: true},
+			{name: "invalid", variable: "maybe", err: `invalid value "maybe" for bool variable "maybe"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := BoolValue(config, tt.variable, tt.defaultValue)
+				checkValueError(t, err, tt.err)
+				if err == nil && got != tt.want {
+					t.Errorf("BoolValue() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("int", func(t *testing.T) {
+		tests := []struct {
+			name, variable string
+			defaultValue   int64
+			want           int64
+			err            string
+		}{
+			{name: "hex", variable: "width", want: 32},
+			{name: "unset", variable: "unset", defaultValue: 100, want: 100},
+			{name: "invalid", variable: "wide", err: `invalid value "wide" for int variable "wide"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := IntValue(config, tt.variable, tt.defaultValue)
+				checkValueError(t, err, tt.err)
+				if err == nil && got != tt.want {
+					t.Errorf("IntValue() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("enum", func(t *testing.T) {
+		values := []string{"soc_a", "soc_b"}
+		tests := []struct {
+			name, variable string
+			defaultValue   string
+			want           string
+			err            string
+		}{
+			{name: "set", variable: "board", defaultValue: "soc_b", want: "soc_a"},
+			{name: "unset", variable: "unset", defaultValue: "soc_b", want: "soc_b"},
+			{name: "unknown", variable: "unknown", err: `unknown value "soc_c" for variable "unknown"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := EnumValue(config, tt.variable, values, tt.defaultValue)
+				checkValueError(t, err, tt.err)
+				if err == nil && got != tt.want {
+					t.Errorf("EnumValue() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		tests := []struct {
+			name, variable string
+			values         []string
+			defaultValue   []string
+			want           []string
+			err            string
+		}{
+			{name: "set", variable: "codecs", values: []string{"h264", "vp9"}, want: []string{"h264", "vp9"}},
+			{name: "any value", variable: "codecs", want: []string{"h264", "vp9"}},
+			{name: "unset", variable: "unset", defaultValue: []string{"h264"}, want: []string{"h264"}},
+			{name: "unknown", variable: "codecs", values: []string{"h264"},
+				err: `unknown value "vp9" in list variable "codecs"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := ListValue(config, tt.variable, tt.values, tt.defaultValue)
+				checkValueError(t, err, tt.err)
+				if err == nil && !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ListValue() = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -86,6 +87,10 @@ func processImportModuleDef(v *SoongConfigDefinition, def *parser.Module) (errs
 		return processStringVariableDef(v, def)
 	case "soong_config_bool_variable":
 		return processBoolVariableDef(v, def)
+	case "soong_config_int_variable":
+		return processIntVariableDef(v, def)
+	case "soong_config_list_variable":
+		return processListVariableDef(v, def)
 	default:
 		// Unknown module types will be handled when the file is parsed as a normal
 		// Android.bp file.
@@ -186,6 +191,27 @@ type VariableProperties struct {
 
 type StringVariableProperties struct {
 	Values []string
+
+	// the value used when the variable is not set, one of values.
+	Default *string
+}
+
+type BoolVariableProperties struct {
+	// the value used when the variable is not set.
+	Default *bool
+}
+
+type IntVariableProperties struct {
+	// the value used when the variable is not set.
+	Default *int64
+}
+
+type ListVariableProperties struct {
+	// the values that the entries of the list can have, any value is allowed if empty.
+	Values []string
+
+	// the list used when the variable is not set.
+	Default []string
 }
 
 func processStringVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
@@ -200,22 +226,75 @@ func processStringVariableDef(v *SoongConfigDefinition, def *parser.Module) (err
 		return []error{fmt.Errorf("values property must be set")}
 	}
 
+	defaultValue := proptools.String(stringProps.Default)
+	if stringProps.Default != nil && !inList(defaultValue, stringProps.Values) {
+		return []error{fmt.Errorf("default %q of variable %q must be one of %q", defaultValue,
+			base.variable, stringProps.Values)}
+	}
+
 	v.variables[base.variable] = &stringVariable{
-		baseVariable: base,
-		values:       CanonicalizeToProperties(stringProps.Values),
+		baseVariable:   base,
+		values:         CanonicalizeToProperties(stringProps.Values),
+		declaredValues: stringProps.Values,
+		defaultValue:   defaultValue,
 	}
 
 	return nil
 }
 
 func processBoolVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
-	base, errs := processVariableDef(def)
+	boolProps := &BoolVariableProperties{}
+
+	base, errs := processVariableDef(def, boolProps)
 	if len(errs) > 0 {
 		return errs
 	}
 
 	v.variables[base.variable] = &boolVariable{
 		baseVariable: base,
+		defaultValue: proptools.Bool(boolProps.Default),
+	}
+
+	return nil
+}
+
+func processIntVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
+	intProps := &IntVariableProperties{}
+
+	base, errs := processVariableDef(def, intProps)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	v.variables[base.variable] = &intVariable{
+		baseVariable: base,
+		defaultValue: intProps.Default,
+	}
+
+	return nil
+}
+
+func processListVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
+	listProps := &ListVariableProperties{}
+
+	base, errs := processVariableDef(def, listProps)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if len(listProps.Values) > 0 {
+		for _, d := range listProps.Default {
+			if !inList(d, listProps.Values) {
+				return []error{fmt.Errorf("default %q of variable %q must be one of %q", d,
+					base.variable, listProps.Values)}
+			}
+		}
+	}
+
+	v.variables[base.variable] = &listVariable{
+		baseVariable: base,
+		values:       listProps.Values,
+		defaultValue: listProps.Default,
 	}
 
 	return nil
@@ -248,6 +327,77 @@ type SoongConfigDefinition struct {
 	variables map[string]soongConfigVariable
 }
 
+// Declarations returns the variables declared with soong_config_*_variable modules that the
+// module types read, keyed by the config namespace of the module types and the variable name.
+func (d *SoongConfigDefinition) Declarations() map[string]map[string]Declaration {
+	ret := make(map[string]map[string]Declaration)
+	for _, moduleType := range d.ModuleTypes {
+		for _, name := range moduleType.variableNames {
+			v, ok := d.variables[name]
+			if !ok {
+				continue
+			}
+			if ret[moduleType.ConfigNamespace] == nil {
+				ret[moduleType.ConfigNamespace] = make(map[string]Declaration)
+			}
+			ret[moduleType.ConfigNamespace][name] = Declaration{v}
+		}
+	}
+	return ret
+}
+
+// Declaration is a variable declared with a soong_config_string_variable,
+// soong_config_bool_variable, soong_config_int_variable or soong_config_list_variable module.
+// It gives module code the value of the variable, taking its declared values and default into
+// account.
+type Declaration struct {
+	variable soongConfigVariable
+}
+
+// Bool returns the value of a variable declared with soong_config_bool_variable.
+func (d Declaration) Bool(config SoongConfig) (bool, error) {
+	b, ok := d.variable.(*boolVariable)
+	if !ok {
+		return false, d.typeError("soong_config_bool_variable")
+	}
+	return BoolValue(config, b.variable, b.defaultValue)
+}
+
+// Int returns the value of a variable declared with soong_config_int_variable.
+func (d Declaration) Int(config SoongConfig) (int64, error) {
+	i, ok := d.variable.(*intVariable)
+	if !ok {
+		return 0, d.typeError("soong_config_int_variable")
+	}
+	var defaultValue int64
+	if i.defaultValue != nil {
+		defaultValue = *i.defaultValue
+	}
+	return IntValue(config, i.variable, defaultValue)
+}
+
+// Enum returns the value of a variable declared with soong_config_string_variable.
+func (d Declaration) Enum(config SoongConfig) (string, error) {
+	s, ok := d.variable.(*stringVariable)
+	if !ok {
+		return "", d.typeError("soong_config_string_variable")
+	}
+	return EnumValue(config, s.variable, s.declaredValues, s.defaultValue)
+}
+
+// List returns the values of a variable declared with soong_config_list_variable.
+func (d Declaration) List(config SoongConfig) ([]string, error) {
+	l, ok := d.variable.(*listVariable)
+	if !ok {
+		return nil, d.typeError("soong_config_list_variable")
+	}
+	return ListValue(config, l.variable, l.values, l.defaultValue)
+}
+
+func (d Declaration) typeError(moduleType string) error {
+	return fmt.Errorf("variable %q is not declared with %s", d.variable.variableProperty(), moduleType)
+}
+
 // CreateProperties returns a reflect.Value of a newly constructed type that contains the desired
 // property layout for the Soong config variables, with each possible value an interface{} that
 // contains a nil pointer to another newly constructed type that contains the affectable properties.
@@ -469,7 +619,10 @@ func (c *baseVariable) variableProperty() string {
 
 type stringVariable struct {
 	baseVariable
-	values []string
+	// values holds the declared values canonicalized to property names.
+	values         []string
+	declaredValues []string
+	defaultValue   string
 }
 
 func (s *stringVariable) variableValuesType() reflect.Type {
@@ -492,8 +645,12 @@ func (s *stringVariable) initializeProperties(v reflect.Value, typ reflect.Type)
 }
 
 func (s *stringVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
-	for j, v := range s.values {
-		if config.String(s.variable) == v {
+	value, err := EnumValue(config, s.variable, s.declaredValues, s.defaultValue)
+	if err != nil {
+		return nil, err
+	}
+	for j, v := range s.declaredValues {
+		if value == v {
 			return values.Field(j).Interface(), nil
 		}
 	}
@@ -503,6 +660,7 @@ func (s *stringVariable) PropertiesToApply(config SoongConfig, values reflect.Va
 
 type boolVariable struct {
 	baseVariable
+	defaultValue bool
 }
 
 func (b boolVariable) variableValuesType() reflect.Type {
@@ -514,7 +672,11 @@ func (b boolVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
 }
 
 func (b boolVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
-	if config.Bool(b.variable) {
+	value, err := BoolValue(config, b.variable, b.defaultValue)
+	if err != nil {
+		return nil, err
+	}
+	if value {
 		return values.Interface(), nil
 	}
 
@@ -537,8 +699,12 @@ func (s *valueVariable) PropertiesToApply(config SoongConfig, values reflect.Val
 	if !config.IsSet(s.variable) {
 		return nil, nil
 	}
-	configValue := config.String(s.variable)
+	return applyValueVariable(s.variable, values, config.String(s.variable))
+}
 
+// applyValueVariable replaces the %s in the string and list of strings properties in values with
+// the value of a variable.
+func applyValueVariable(variable string, values reflect.Value, configValue string) (interface{}, error) {
 	propStruct := values.Elem().Elem()
 	for i := 0; i < propStruct.NumField(); i++ {
 		field := propStruct.Field(i)
@@ -553,19 +719,115 @@ func (s *valueVariable) PropertiesToApply(config SoongConfig, values reflect.Val
 		case reflect.String:
 			err := printfIntoProperty(field, configValue)
 			if err != nil {
-				return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", s.variable, propStruct.Type().Field(i).Name, err)
+				return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", variable, propStruct.Type().Field(i).Name, err)
 			}
 		case reflect.Slice:
 			for j := 0; j < field.Len(); j++ {
 				err := printfIntoProperty(field.Index(j), configValue)
 				if err != nil {
-					return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", s.variable, propStruct.Type().Field(i).Name, err)
+					return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", variable, propStruct.Type().Field(i).Name, err)
 				}
 			}
 		case reflect.Bool:
 			// Nothing to do
 		default:
-			return nil, fmt.Errorf("soong_config_variables.%s.%s: unsupported property type %q", s.variable, propStruct.Type().Field(i).Name, kind)
+			return nil, fmt.Errorf("soong_config_variables.%s.%s: unsupported property type %q", variable, propStruct.Type().Field(i).Name, kind)
+		}
+	}
+
+	return values.Interface(), nil
+}
+
+type intVariable struct {
+	baseVariable
+	defaultValue *int64
+}
+
+func (s *intVariable) variableValuesType() reflect.Type {
+	return emptyInterfaceType
+}
+
+func (s *intVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
+	v.Set(reflect.Zero(typ))
+}
+
+func (s *intVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	if config.String(s.variable) == "" && s.defaultValue == nil {
+		return nil, nil
+	}
+	var defaultValue int64
+	if s.defaultValue != nil {
+		defaultValue = *s.defaultValue
+	}
+	value, err := IntValue(config, s.variable, defaultValue)
+	if err != nil {
+		return nil, err
+	}
+	return applyValueVariable(s.variable, values, strconv.FormatInt(value, 10))
+}
+
+type listVariable struct {
+	baseVariable
+	values       []string
+	defaultValue []string
+}
+
+func (s *listVariable) variableValuesType() reflect.Type {
+	return emptyInterfaceType
+}
+
+func (s *listVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
+	v.Set(reflect.Zero(typ))
+}
+
+// PropertiesToApply replaces each entry of the list of strings properties that contains %s with
+// an entry for each value in the list.
+func (s *listVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	list, err := ListValue(config, s.variable, s.values, s.defaultValue)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	propStruct := values.Elem().Elem()
+	for i := 0; i < propStruct.NumField(); i++ {
+		field := propStruct.Field(i)
+		switch field.Kind() {
+		case reflect.Slice:
+			var expanded []string
+			for j := 0; j < field.Len(); j++ {
+				entry := field.Index(j)
+				if !strings.Contains(entry.String(), "%") {
+					expanded = append(expanded, entry.String())
+					continue
+				}
+				for _, value := range list {
+					v := reflect.New(entry.Type()).Elem()
+					v.Set(entry)
+					if err := printfIntoProperty(v, value); err != nil {
+						return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", s.variable, propStruct.Type().Field(i).Name, err)
+					}
+					expanded = append(expanded, v.String())
+				}
+			}
+			field.Set(reflect.ValueOf(expanded))
+		case reflect.String, reflect.Ptr:
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() || field.Elem().Kind() != reflect.String {
+					continue
+				}
+				field = field.Elem()
+			}
+			if strings.Contains(field.String(), "%") {
+				return nil, fmt.Errorf("soong_config_variables.%s.%s: list variables only support %%s in list properties",
+					s.variable, propStruct.Type().Field(i).Name)
+			}
+		case reflect.Bool:
+			// Nothing to do
+		default:
+			return nil, fmt.Errorf("soong_config_variables.%s.%s: unsupported property type %q", s.variable, propStruct.Type().Field(i).Name, field.Kind())
 		}
 	}
 
@@ -0,0 +1,482 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// This file implements the module types that add a test config to a compatibility suite, like
+// csuite_config and vts_config.  A new suite config module type only needs to be registered with
+// a TestSuiteConfigFactory for its TestSuiteConfigParams.
+
+var suiteTestConfig = pctx.AndroidStaticRule("suiteTestConfig",
+	blueprint.RuleParams{
+		Command:     "sed $script $template > $out",
+		CommandDeps: []string{"$template"},
+		Description: "suite config $out",
+	},
+	"script", "template")
+
+// TestSuiteConfigParams describe a suite config module type.
+type TestSuiteConfigParams struct {
+	// Suite is the compatibility suite the test configs are added to.
+	Suite string
+
+	// OutputDir is the subdirectory of the module's output directory the config is stored in.
+	OutputDir string
+
+	// DefaultTestConfigTemplate is the template of generated test configs for modules that
+	// don't set test_config_template.
+	DefaultTestConfigTemplate string
+
+	// AllowedProperties lists the properties the module type supports in addition to
+	// test_config, out of test_suites, test_config_template, include_filters, exclude_filters,
+	// target_preparers, template_values, app_list, apps and copy_apks.
+	AllowedProperties []string
+}
+
+type testSuiteConfigProperties struct {
+	// Override the default (AndroidTest.xml) test manifest file name.
+	Test_config *string
+
+	// Additional test suites to add the test to.
+	Test_suites []string `android:"arch_variant"`
+
+	// Template used to generate the test config, with {MODULE}, {EXTRA_CONFIGS} and the
+	// {<KEY>} placeholders set by template_values.  Defaults to the template of the suite.
+	Test_config_template *string `android:"path"`
+
+	// List of test filters to include in the generated test config.
+	Include_filters []string
+
+	// List of test filters to exclude from the generated test config.
+	Exclude_filters []string
+
+	// List of classes of target preparers to add to the generated test config.
+	Target_preparers []string
+
+	// List of <KEY>=<value> entries, where <value> replaces the {<KEY>} placeholders in the
+	// test config template.
+	Template_values []string
+
+	// File listing the package name or the path to the APK of an app on each line.  A test
+	// config is generated from the test config template for each app, with {PACKAGE} or {APK}
+	// replaced by the package name or the APK path of the app.
+	App_list *string
+
+	// List of android_app modules tested by the suite.  The apps are dependencies of the module,
+	// so they must exist and are built with the module.
+	Apps []string
+
+	// Whether to copy the APKs of the apps in apps into the suite.
+	Copy_apks *bool
+}
+
+type TestSuiteConfig struct {
+	ModuleBase
+	params         TestSuiteConfigParams
+	properties     testSuiteConfigProperties
+	OutputFilePath OutputPath
+
+	// The properties the module type supports, a subset of properties.
+	allowedProperties interface{}
+
+	// The test config generated from the test config template, if any.
+	testConfig Path
+
+	// The test config set by test_config, if it exists in the module directory.
+	testConfigSrc OptionalPath
+
+	// The test configs generated for the apps in the app list, if any.
+	appTestConfigs WritablePaths
+
+	// The APKs of the apps in apps copied into the suite, if copy_apks is set.
+	appApks WritablePaths
+}
+
+type suiteConfigAppDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var suiteConfigAppTag = suiteConfigAppDependencyTag{}
+
+// suiteConfigAppModule is implemented by the android_app modules that can be listed in apps.
+type suiteConfigAppModule interface {
+	Module
+	OutputFile() Path
+}
+
+// copyAllowedProperties copies the supported properties over the full set of properties, the
+// others are left unset.
+func (me *TestSuiteConfig) copyAllowedProperties() {
+	allowed := reflect.ValueOf(me.allowedProperties).Elem()
+	properties := reflect.ValueOf(&me.properties).Elem()
+	for i := 0; i < allowed.NumField(); i++ {
+		properties.FieldByName(allowed.Type().Field(i).Name).Set(allowed.Field(i))
+	}
+}
+
+func (me *TestSuiteConfig) DepsMutator(ctx BottomUpMutatorContext) {
+	me.copyAllowedProperties()
+
+	// The suite config is a host module, the apps are built for the device.
+	ctx.AddFarVariationDependencies(ctx.Config().AndroidCommonTarget.Variations(),
+		suiteConfigAppTag, me.properties.Apps...)
+}
+
+func (me *TestSuiteConfig) GenerateAndroidBuildActions(ctx ModuleContext) {
+	me.copyAllowedProperties()
+
+	me.appApks = me.appDependencyApks(ctx)
+
+	me.OutputFilePath = PathForModuleOut(ctx, me.params.OutputDir, me.BaseModuleName()).OutputPath
+
+	if me.properties.App_list != nil {
+		me.appTestConfigs = me.generateAppTestConfigs(ctx)
+	} else if me.generatesTestConfig() {
+		if me.properties.Test_config != nil {
+			ctx.PropertyErrorf("test_config", "can't be set together with the properties of a "+
+				"generated test config")
+			return
+		}
+		output := PathForModuleOut(ctx, ctx.ModuleName()+".xml")
+		me.generateTestConfig(ctx, output, me.templateReplacements(ctx, nil))
+		me.testConfig = output
+	} else if me.properties.Test_config != nil {
+		me.testConfigSrc = ExistentPathForSource(ctx, ctx.ModuleDir(), *me.properties.Test_config)
+	}
+
+	me.fillModuleInfoJSON(ctx.ModuleInfoJSON())
+}
+
+func (me *TestSuiteConfig) generatesTestConfig() bool {
+	return me.properties.Test_config_template != nil ||
+		len(me.properties.Include_filters) > 0 ||
+		len(me.properties.Exclude_filters) > 0 ||
+		len(me.properties.Target_preparers) > 0 ||
+		len(me.properties.Template_values) > 0
+}
+
+var suiteConfigTemplateKeyRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// templateReplacements returns the placeholders and their sed escaped replacements for the test
+// config template, in pairs.  The placeholders in reserved are set for each generated config.
+func (me *TestSuiteConfig) templateReplacements(ctx ModuleContext, reserved []string) []string {
+	var extraConfigs []string
+	for _, filter := range me.properties.Include_filters {
+		extraConfigs = append(extraConfigs,
+			fmt.Sprintf(`<option name="include-filter" value="%s" />`, suiteConfigXmlEscape(filter)))
+	}
+	for _, filter := range me.properties.Exclude_filters {
+		extraConfigs = append(extraConfigs,
+			fmt.Sprintf(`<option name="exclude-filter" value="%s" />`, suiteConfigXmlEscape(filter)))
+	}
+	for _, class := range me.properties.Target_preparers {
+		extraConfigs = append(extraConfigs,
+			fmt.Sprintf(`<target_preparer class="%s" />`, suiteConfigXmlEscape(class)))
+	}
+
+	// The extra configs are separated by spaces rather than newlines, as only GNU sed accepts
+	// \n in the replacement and the template is also expanded on Darwin hosts.
+	replacements := []string{
+		"MODULE", suiteConfigSedEscape(ctx.ModuleName()),
+		"EXTRA_CONFIGS", strings.Join(suiteConfigSedEscapeList(extraConfigs), " "),
+	}
+	for _, entry := range me.properties.Template_values {
+		i := strings.Index(entry, "=")
+		if i == -1 {
+			ctx.PropertyErrorf("template_values", "invalid entry %q, must be <KEY>=<value>", entry)
+			continue
+		}
+		key, value := entry[:i], entry[i+1:]
+		if !suiteConfigTemplateKeyRegexp.MatchString(key) {
+			ctx.PropertyErrorf("template_values", "invalid key %q, must be upper case", key)
+			continue
+		}
+		if key == "MODULE" || key == "EXTRA_CONFIGS" || InList(key, reserved) {
+			ctx.PropertyErrorf("template_values", "key %q is set by the build", key)
+			continue
+		}
+		replacements = append(replacements, key, suiteConfigSedEscape(suiteConfigXmlEscape(value)))
+	}
+	return replacements
+}
+
+// generateTestConfig writes a test config from the test config template of the module.
+func (me *TestSuiteConfig) generateTestConfig(ctx ModuleContext, output WritablePath, replacements []string) {
+	var script []string
+	for i := 0; i < len(replacements); i += 2 {
+		script = append(script, fmt.Sprintf("s&{%s}&%s&g", replacements[i], replacements[i+1]))
+	}
+
+	template := me.params.DefaultTestConfigTemplate
+	var implicits Paths
+	if me.properties.Test_config_template != nil {
+		templatePath := PathForModuleSrc(ctx, *me.properties.Test_config_template)
+		template = templatePath.String()
+		implicits = append(implicits, templatePath)
+	}
+
+	ctx.Build(pctx, BuildParams{
+		Rule:        suiteTestConfig,
+		Description: me.params.Suite + " config",
+		Output:      output,
+		Implicits:   implicits,
+		Args: map[string]string{
+			"script":   proptools.NinjaAndShellEscape(strings.Join(script, ";")),
+			"template": template,
+		},
+	})
+}
+
+// suiteConfigApp is an entry of the app list of a suite config module.
+type suiteConfigApp struct {
+	// name identifies the test config generated for the app.
+	name string
+	// Either the package name or the path to the APK of the app.
+	packageName string
+	apk         string
+}
+
+var suiteConfigPackageNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+
+// parseSuiteConfigAppList parses an app list file, which lists the package name or the path to the APK
+// of an app on each line.  Empty lines and lines starting with '#' are ignored.
+func parseSuiteConfigAppList(data []byte) ([]suiteConfigApp, error) {
+	var apps []suiteConfigApp
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var app suiteConfigApp
+		if strings.HasSuffix(line, ".apk") {
+			app = suiteConfigApp{name: strings.TrimSuffix(filepath.Base(line), ".apk"), apk: line}
+		} else if suiteConfigPackageNameRegexp.MatchString(line) {
+			app = suiteConfigApp{name: line, packageName: line}
+		} else {
+			return nil, fmt.Errorf("line %d: %q is neither a package name nor an APK path", i+1, line)
+		}
+
+		if seen[app.name] {
+			return nil, fmt.Errorf("line %d: app %q is listed more than once", i+1, app.name)
+		}
+		seen[app.name] = true
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// generateAppTestConfigs writes a test config for each app listed in the app list file of the
+// module, with {PACKAGE} or {APK} replaced by the package name or the APK path of the app.
+func (me *TestSuiteConfig) generateAppTestConfigs(ctx ModuleContext) WritablePaths {
+	if me.properties.Test_config != nil {
+		ctx.PropertyErrorf("test_config", "can't be set together with app_list")
+		return nil
+	}
+
+	path := ExistentPathForSource(ctx, ctx.ModuleDir(), *me.properties.App_list)
+	if !path.Valid() {
+		ctx.PropertyErrorf("app_list", "file %q does not exist", *me.properties.App_list)
+		return nil
+	}
+	data, err := ReadSourceFile(ctx, path.Path())
+	if err != nil {
+		ctx.PropertyErrorf("app_list", "%s", err)
+		return nil
+	}
+	apps, err := parseSuiteConfigAppList(data)
+	if err != nil {
+		ctx.PropertyErrorf("app_list", "%s: %s", path, err)
+		return nil
+	}
+
+	replacements := me.templateReplacements(ctx, []string{"PACKAGE", "APK"})
+
+	var outputs WritablePaths
+	for _, app := range apps {
+		appReplacements := append([]string{
+			"PACKAGE", suiteConfigSedEscape(suiteConfigXmlEscape(app.packageName)),
+			"APK", suiteConfigSedEscape(suiteConfigXmlEscape(app.apk)),
+		}, replacements...)
+		output := PathForModuleOut(ctx, "apps", ctx.ModuleName()+"_"+app.name+".config")
+		me.generateTestConfig(ctx, output, appReplacements)
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+// appDependencyApks checks that the modules listed in apps are apps, and copies their APKs into
+// the module's output directory if copy_apks is set.
+func (me *TestSuiteConfig) appDependencyApks(ctx ModuleContext) WritablePaths {
+	var apks WritablePaths
+	ctx.VisitDirectDepsWithTag(suiteConfigAppTag, func(dep Module) {
+		app, ok := dep.(suiteConfigAppModule)
+		if !ok || app.OutputFile() == nil || app.OutputFile().Ext() != ".apk" {
+			ctx.PropertyErrorf("apps", "module %q is not an android_app", ctx.OtherModuleName(dep))
+			return
+		}
+		if !Bool(me.properties.Copy_apks) {
+			return
+		}
+		apk := PathForModuleOut(ctx, "apks", ctx.OtherModuleName(dep)+".apk")
+		ctx.Build(pctx, BuildParams{
+			Rule:        Cp,
+			Description: me.params.Suite + " app " + ctx.OtherModuleName(dep),
+			Input:       app.OutputFile(),
+			Output:      apk,
+		})
+		apks = append(apks, apk)
+	})
+	return apks
+}
+
+func suiteConfigXmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// suiteConfigSedEscape escapes s for the replacement of a sed s command that uses & as the delimiter.
+func suiteConfigSedEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, "&", `\&`, -1)
+}
+
+func suiteConfigSedEscapeList(list []string) []string {
+	ret := make([]string, len(list))
+	for i, s := range list {
+		ret[i] = suiteConfigSedEscape(s)
+	}
+	return ret
+}
+
+func (me *TestSuiteConfig) AndroidMk() AndroidMkData {
+	androidMkData := AndroidMkData{
+		Class:      "FAKE",
+		Include:    "$(BUILD_SYSTEM)/suite_host_config.mk",
+		OutputFile: OptionalPathForPath(me.OutputFilePath),
+	}
+	androidMkData.Extra = []AndroidMkExtraFunc{
+		func(w io.Writer, outputFile Path) {
+			if me.testConfig != nil {
+				fmt.Fprintf(w, "LOCAL_TEST_CONFIG := %s\n", me.testConfig.String())
+			} else if me.properties.Test_config != nil {
+				fmt.Fprintf(w, "LOCAL_TEST_CONFIG := %s\n",
+					*me.properties.Test_config)
+			}
+			for _, config := range me.appTestConfigs {
+				fmt.Fprintf(w, "LOCAL_COMPATIBILITY_SUPPORT_FILES += %s:%s\n", config.String(), config.Base())
+			}
+			for _, apk := range me.appApks {
+				fmt.Fprintf(w, "LOCAL_COMPATIBILITY_SUPPORT_FILES += %s:%s\n", apk.String(), apk.Base())
+			}
+			fmt.Fprintln(w, "LOCAL_COMPATIBILITY_SUITE :=",
+				strings.Join(append([]string{me.params.Suite}, me.properties.Test_suites...), " "))
+		},
+	}
+	return androidMkData
+}
+
+var _ OutputFileProducer = (*TestSuiteConfig)(nil)
+
+// OutputFiles returns the files of the suite config with the given tag:
+//  - "" is the suite config file,
+//  - ".config" are the test configs, generated from the test config template or test_config,
+//  - ".apks" are the APKs of the apps copied into the suite.
+func (me *TestSuiteConfig) OutputFiles(tag string) (Paths, error) {
+	switch tag {
+	case "":
+		return Paths{me.OutputFilePath}, nil
+	case ".config":
+		return me.testConfigs(), nil
+	case ".apks":
+		return me.appApks.Paths(), nil
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
+
+// testConfigs returns the test configs of the module, which are either generated or set by
+// test_config.
+func (me *TestSuiteConfig) testConfigs() Paths {
+	if me.testConfig != nil {
+		return Paths{me.testConfig}
+	}
+	if len(me.appTestConfigs) > 0 {
+		return me.appTestConfigs.Paths()
+	}
+	if me.testConfigSrc.Valid() {
+		return Paths{me.testConfigSrc.Path()}
+	}
+	return nil
+}
+
+// fillModuleInfoJSON fills in the module-info.json entry of the suite config.
+func (me *TestSuiteConfig) fillModuleInfoJSON(info *ModuleInfoJSON) {
+	info.Class = append(info.Class, "FAKE")
+	info.CompatibilitySuites = append(info.CompatibilitySuites, me.params.Suite)
+	info.CompatibilitySuites = append(info.CompatibilitySuites, me.properties.Test_suites...)
+	if me.testConfig != nil {
+		info.TestConfig = append(info.TestConfig, me.testConfig.String())
+	} else if me.properties.Test_config != nil {
+		info.TestConfig = append(info.TestConfig, *me.properties.Test_config)
+	}
+}
+
+// InitTestSuiteConfigModule adds the properties the module type described by params supports to a
+// suite config module.
+func InitTestSuiteConfigModule(me *TestSuiteConfig, params TestSuiteConfigParams) {
+	me.params = params
+
+	for _, name := range params.AllowedProperties {
+		if _, ok := reflect.TypeOf(me.properties).FieldByName(proptools.FieldNameForProperty(name)); !ok {
+			panic(fmt.Errorf("unknown suite config property %q", name))
+		}
+	}
+
+	typ, _ := proptools.FilterPropertyStruct(reflect.TypeOf(me.properties),
+		func(field reflect.StructField, prefix string) (bool, reflect.StructField) {
+			name := proptools.PropertyNameForField(field.Name)
+			return name == "test_config" || InList(name, params.AllowedProperties), field
+		})
+	me.allowedProperties = reflect.New(typ).Interface()
+	me.AddProperties(me.allowedProperties)
+}
+
+// TestSuiteConfigFactory returns the factory of a module type that generates configuration files of
+// the suite described by params from the <test_config> xml file, and stores them in a
+// subdirectory of $(HOST_OUT).
+func TestSuiteConfigFactory(params TestSuiteConfigParams) ModuleFactory {
+	return func() Module {
+		module := &TestSuiteConfig{}
+		InitTestSuiteConfigModule(module, params)
+		InitAndroidArchModule(module, HostSupported, MultilibFirst)
+		return module
+	}
+}
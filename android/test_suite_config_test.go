@@ -0,0 +1,94 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var perfConfigParams = TestSuiteConfigParams{
+	Suite:             "perf",
+	OutputDir:         "perf",
+	AllowedProperties: []string{"test_suites"},
+}
+
+func testTestSuiteConfigContext(bpFileContents string) (*TestContext, Config) {
+	config := TestArchConfig(buildDir, nil, bpFileContents, nil)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("perf_config", TestSuiteConfigFactory(perfConfigParams))
+	ctx.Register(config)
+	return ctx, config
+}
+
+func TestTestSuiteConfig(t *testing.T) {
+	ctx, config := testTestSuiteConfigContext(`
+perf_config {
+	name: "foo",
+	test_config: "foo.xml",
+	test_suites: ["general-tests"],
+}
+`)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	variants := ctx.ModuleVariantsForTests("foo")
+	module := ctx.ModuleForTests("foo", variants[0]).Module().(*TestSuiteConfig)
+
+	if got, expected := module.OutputFilePath.Rel(), "perf/foo"; got != expected {
+		t.Errorf("expected output file %q, got %q", expected, got)
+	}
+
+	data := AndroidMkDataForTest(t, config, "", module)
+	var buf bytes.Buffer
+	for _, extra := range data.Extra {
+		extra(&buf, nil)
+	}
+	for _, expected := range []string{
+		"LOCAL_TEST_CONFIG := foo.xml\n",
+		"LOCAL_COMPATIBILITY_SUITE := perf general-tests\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in the AndroidMk output, got %q", expected, buf.String())
+		}
+	}
+}
+
+func TestTestSuiteConfigDisallowedProperty(t *testing.T) {
+	ctx, _ := testTestSuiteConfigContext(`
+perf_config {
+	name: "foo",
+	include_filters: ["foo"],
+}
+`)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfNoMatchingErrors(t, `unrecognized property "include_filters"`, errs)
+}
+
+func TestTestSuiteConfigUnknownAllowedProperty(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a panic for an unknown allowed property")
+		}
+	}()
+	TestSuiteConfigFactory(TestSuiteConfigParams{
+		Suite:             "perf",
+		AllowedProperties: []string{"foo"},
+	})()
+}
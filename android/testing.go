@@ -37,7 +37,7 @@ func NewTestContext() *TestContext {
 
 	ctx.SetNameInterface(nameResolver)
 
-	ctx.postDeps = append(ctx.postDeps, registerPathDepsMutator)
+	ctx.postDeps = append(ctx.postDeps, registerPathDepsMutator, registerLicenseDepsMutator)
 
 	return ctx
 }
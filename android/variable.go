@@ -15,10 +15,12 @@
 package android
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/google/blueprint/proptools"
 )
@@ -238,6 +240,10 @@ type productVariables struct {
 	EnableCFI       *bool    `json:",omitempty"`
 	CFIExcludePaths []string `json:",omitempty"`
 	CFIIncludePaths []string `json:",omitempty"`
+	CFIArches       []string `json:",omitempty"`
+
+	SCSExcludePaths []string `json:",omitempty"`
+	SCSIncludePaths []string `json:",omitempty"`
 
 	DisableScudo *bool `json:",omitempty"`
 
@@ -290,6 +296,8 @@ type productVariables struct {
 	NamespacesToExport []string `json:",omitempty"`
 
 	PgoAdditionalProfileDirs []string `json:",omitempty"`
+	PgoProfileDir            *string  `json:",omitempty"`
+	AfdoProfileDir           *string  `json:",omitempty"`
 
 	VndkUseCoreVariant         *bool `json:",omitempty"`
 	VndkSnapshotBuildArtifacts *bool `json:",omitempty"`
@@ -302,23 +310,56 @@ type productVariables struct {
 
 	BoardVndkRuntimeDisable *bool `json:",omitempty"`
 
+	BoardGlobalCflags   []string `json:",omitempty"`
+	BoardGlobalCppflags []string `json:",omitempty"`
+	BoardGlobalLdflags  []string `json:",omitempty"`
+
+	StackProtector *string `json:",omitempty"`
+
+	CompilerRtArches     []string `json:",omitempty"`
+	HostCompilerRtArches []string `json:",omitempty"`
+
 	VendorVars map[string]map[string]string `json:",omitempty"`
 
-	Ndk_abis               *bool `json:",omitempty"`
-	Exclude_draft_ndk_apis *bool `json:",omitempty"`
+	Ndk_abis               *bool   `json:",omitempty"`
+	Exclude_draft_ndk_apis *bool   `json:",omitempty"`
+	Ndk_sysroot_host_os    *string `json:",omitempty"`
 
 	Flatten_apex *bool `json:",omitempty"`
 	Aml_abis     *bool `json:",omitempty"`
 
 	DexpreoptGlobalConfig *string `json:",omitempty"`
 
+	ToolchainConfig *string `json:",omitempty"`
+	CcWrapper       *string `json:",omitempty"`
+
+	OwnershipRegistry *string `json:",omitempty"`
+
+	RemoteArtifactFetcher *string `json:",omitempty"`
+
 	ManifestPackageNameOverrides []string `json:",omitempty"`
 	CertificateOverrides         []string `json:",omitempty"`
 	PackageNameOverrides         []string `json:",omitempty"`
+	LlndkOverrides               []string `json:",omitempty"`
 
 	EnforceSystemCertificate          *bool    `json:",omitempty"`
 	EnforceSystemCertificateAllowList []string `json:",omitempty"`
 
+	RunErrorProne             *bool    `json:",omitempty"`
+	ErrorProneMode            *string  `json:",omitempty"`
+	ErrorProneConfig          *string  `json:",omitempty"`
+	ErrorPronePlugins         []string `json:",omitempty"`
+	NullAwayAnnotatedPackages []string `json:",omitempty"`
+
+	SandboxInstallActions *bool `json:",omitempty"`
+
+	PrebuiltPolicyDefault         *string  `json:",omitempty"`
+	PrebuiltPolicySourceModules   []string `json:",omitempty"`
+	PrebuiltPolicyPrebuiltModules []string `json:",omitempty"`
+
+	EnforceModuleOverrideAllowlist *bool    `json:",omitempty"`
+	ModuleOverrideAllowlist        []string `json:",omitempty"`
+
 	ProductHiddenAPIStubs       []string `json:",omitempty"`
 	ProductHiddenAPIStubsSystem []string `json:",omitempty"`
 	ProductHiddenAPIStubsTest   []string `json:",omitempty"`
@@ -338,6 +379,24 @@ type productVariables struct {
 	InstallExtraFlattenedApexes *bool `json:",omitempty"`
 
 	BoardUsesRecoveryAsBoot *bool `json:",omitempty"`
+
+	// The values of the product variables registered with RegisterProductVariable, by field name.
+	RegisteredVariables map[string]interface{} `json:"-"`
+}
+
+func (v *productVariables) UnmarshalJSON(data []byte) error {
+	// Decode the built-in variables with the default decoder.
+	type builtinProductVariables productVariables
+	if err := json.Unmarshal(data, (*builtinProductVariables)(v)); err != nil {
+		return err
+	}
+
+	registered, err := decodeRegisteredProductVariables(data, productVariableRegistry.variables)
+	if err != nil {
+		return err
+	}
+	v.RegisteredVariables = registered
+	return nil
 }
 
 func boolPtr(v bool) *bool {
@@ -414,6 +473,10 @@ func VariableMutator(mctx BottomUpMutatorContext) {
 
 		// Check that the variable was set for the product
 		val := reflect.ValueOf(mctx.Config().productVariables).FieldByName(name)
+		if registered, ok := mctx.Config().productVariables.RegisteredVariables[name]; ok {
+			val = reflect.New(reflect.TypeOf(registered))
+			val.Elem().Set(reflect.ValueOf(registered))
+		}
 		if !val.IsValid() || val.Kind() != reflect.Ptr || val.IsNil() {
 			continue
 		}
@@ -551,7 +614,7 @@ func initProductVariableModule(m Module) {
 
 	// Allow tests to override the default product variables
 	if base.variableProperties == nil {
-		base.variableProperties = defaultProductVariables
+		base.variableProperties = productVariableRegistry.properties()
 	}
 	// Filter the product variables properties to the ones that exist on this module
 	base.variableProperties = createVariableProperties(m.GetProperties(), base.variableProperties)
@@ -649,3 +712,173 @@ func fieldExistsByNameRecursive(t reflect.Type, prefix, name string) bool {
 		return exists
 	}
 }
+
+// ProductVariableType is the type of the value of a product variable registered with
+// RegisterProductVariable.
+type ProductVariableType int
+
+const (
+	// The properties of a bool variable are applied when it is true, with %d replaced by 1.
+	ProductVariableBool ProductVariableType = iota
+	// The properties of an int variable are applied when it is set, with %d replaced by its value.
+	ProductVariableInt
+	// The properties of a string variable are applied when it is set, with %s replaced by its
+	// value.
+	ProductVariableString
+)
+
+func (t ProductVariableType) String() string {
+	switch t {
+	case ProductVariableBool:
+		return "bool"
+	case ProductVariableInt:
+		return "int"
+	case ProductVariableString:
+		return "string"
+	default:
+		panic(fmt.Errorf("unknown product variable type %d", int(t)))
+	}
+}
+
+type registeredProductVariable struct {
+	// The name of the field of the variable, e.g. Acme_feature.
+	field      string
+	typ        ProductVariableType
+	properties reflect.Type
+}
+
+// productVariableRegistries hold the product variables registered by module packages, and create
+// the product_variables property struct that includes them.
+type productVariableRegistries struct {
+	variables []registeredProductVariable
+
+	once               sync.Once
+	variableProperties interface{}
+}
+
+var productVariableRegistry = &productVariableRegistries{}
+
+// RegisterProductVariable registers a product variable for use in product_variables blocks, so
+// that module packages can add product variables without editing this file.  name is the name of
+// the variable in product_variables blocks, e.g. "acme_feature", and the value of the variable is
+// read from the key with the name in field form, e.g. "Acme_feature", in the product variables
+// file.  properties is a pointer to a property struct with the properties the variable can set,
+// e.g. &struct{ Cflags []string }{}.  It must be called from an init() function.
+func RegisterProductVariable(name string, typ ProductVariableType, properties interface{}) {
+	productVariableRegistry.register(name, typ, properties)
+}
+
+func (r *productVariableRegistries) register(name string, typ ProductVariableType, properties interface{}) {
+	if r.variableProperties != nil {
+		panic(fmt.Errorf("product variable %q registered after the product variable properties were created", name))
+	}
+
+	field := proptools.FieldNameForProperty(name)
+	_, isVariable := reflect.TypeOf(productVariables{}).FieldByName(field)
+	_, isProperty := reflect.TypeOf(variableProperties{}.Product_variables).FieldByName(field)
+	if isVariable || isProperty {
+		panic(fmt.Errorf("product variable %q is already a built-in product variable", name))
+	}
+	for _, v := range r.variables {
+		if v.field == field {
+			panic(fmt.Errorf("product variable %q is already registered", name))
+		}
+	}
+
+	if typ < ProductVariableBool || typ > ProductVariableString {
+		panic(fmt.Errorf("product variable %q has unknown type %d", name, int(typ)))
+	}
+
+	propertiesType := reflect.TypeOf(properties)
+	if propertiesType.Kind() != reflect.Ptr || propertiesType.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("properties of product variable %q must be a pointer to a struct, got %s",
+			name, propertiesType))
+	}
+
+	r.variables = append(r.variables, registeredProductVariable{
+		field:      field,
+		typ:        typ,
+		properties: propertiesType.Elem(),
+	})
+}
+
+// properties returns the product_variables property struct with the built-in and the registered
+// product variables.
+func (r *productVariableRegistries) properties() interface{} {
+	r.once.Do(func() {
+		r.variableProperties = createRegisteredVariableProperties(defaultProductVariables, r.variables)
+	})
+	return r.variableProperties
+}
+
+// createRegisteredVariableProperties returns a copy of the property struct productVariables with a
+// field in Product_variables for each of variables.
+func createRegisteredVariableProperties(productVariables interface{},
+	variables []registeredProductVariable) interface{} {
+
+	if len(variables) == 0 {
+		return productVariables
+	}
+
+	productVariablesField := reflect.TypeOf(productVariables).Field(0)
+	var fields []reflect.StructField
+	for i := 0; i < productVariablesField.Type.NumField(); i++ {
+		fields = append(fields, productVariablesField.Type.Field(i))
+	}
+	for _, v := range variables {
+		fields = append(fields, reflect.StructField{
+			Name: v.field,
+			Type: v.properties,
+		})
+	}
+	productVariablesField.Type = reflect.StructOf(fields)
+
+	return reflect.Zero(reflect.StructOf([]reflect.StructField{productVariablesField})).Interface()
+}
+
+// decodeRegisteredProductVariables decodes the values of the variables that are set in the product
+// variables file data.
+func decodeRegisteredProductVariables(data []byte,
+	variables []registeredProductVariable) (map[string]interface{}, error) {
+
+	if len(variables) == 0 {
+		return nil, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]interface{})
+	for _, v := range variables {
+		data, ok := raw[v.field]
+		if !ok || string(data) == "null" {
+			continue
+		}
+
+		var value interface{}
+		var err error
+		switch v.typ {
+		case ProductVariableBool:
+			var b bool
+			err = json.Unmarshal(data, &b)
+			value = b
+		case ProductVariableInt:
+			var i int
+			err = json.Unmarshal(data, &i)
+			value = i
+		case ProductVariableString:
+			var s string
+			err = json.Unmarshal(data, &s)
+			value = s
+		default:
+			panic(fmt.Errorf("unknown product variable type %d", int(v.typ)))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("product variable %s must be a %s: %s", v.field, v.typ, err)
+		}
+		ret[v.field] = value
+	}
+	return ret, nil
+}
@@ -17,6 +17,7 @@ package android
 import (
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/blueprint/proptools"
@@ -335,3 +336,149 @@ func BenchmarkSliceToTypeArray(b *testing.B) {
 		})
 	}
 }
+
+type registeredProductVariablesTestProperties struct {
+	Cflags []string
+}
+
+type registeredProductVariablesTestModule struct {
+	ModuleBase
+	properties registeredProductVariablesTestProperties
+}
+
+func (m *registeredProductVariablesTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func TestRegisteredProductVariables(t *testing.T) {
+	registry := &productVariableRegistries{}
+	registry.register("acme_feature", ProductVariableBool, &struct{ Cflags []string }{})
+	registry.register("acme_disabled", ProductVariableBool, &struct{ Cflags []string }{})
+	registry.register("acme_level", ProductVariableInt, &struct{ Cflags []string }{})
+	registry.register("acme_name", ProductVariableString, &struct {
+		Cflags []string
+		Srcs   []string
+	}{})
+	registry.register("acme_unset", ProductVariableString, &struct{ Cflags []string }{})
+
+	bp := `
+		test {
+			name: "foo",
+			product_variables: {
+				acme_feature: {
+					cflags: ["-DACME_FEATURE=%d"],
+				},
+				acme_disabled: {
+					cflags: ["-DACME_DISABLED"],
+				},
+				acme_level: {
+					cflags: ["-DACME_LEVEL=%d"],
+				},
+				acme_name: {
+					cflags: ["-DACME_NAME=%s"],
+				},
+				acme_unset: {
+					cflags: ["-DACME_UNSET"],
+				},
+			},
+		}
+	`
+
+	values, err := decodeRegisteredProductVariables([]byte(`{
+		"Acme_feature": true,
+		"Acme_disabled": false,
+		"Acme_level": 3,
+		"Acme_name": "foo",
+		"Acme_unset": null
+	}`), registry.variables)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	config := TestConfig(buildDir, nil, bp, nil)
+	config.TestProductVariables.RegisteredVariables = values
+
+	ctx := NewTestContext()
+	ctx.RegisterModuleType("test", func() Module {
+		m := &registeredProductVariablesTestModule{}
+		m.AddProperties(&m.properties)
+		m.variableProperties = registry.properties()
+		InitAndroidModule(m)
+		return m
+	})
+	ctx.PreDepsMutators(func(ctx RegisterMutatorsContext) {
+		ctx.BottomUp("variable", VariableMutator).Parallel()
+	})
+	ctx.Register(config)
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	foo := ctx.ModuleForTests("foo", "").Module().(*registeredProductVariablesTestModule)
+	want := []string{"-DACME_FEATURE=1", "-DACME_LEVEL=3", "-DACME_NAME=foo"}
+	if g, w := foo.properties.Cflags, want; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected cflags %q, got %q", w, g)
+	}
+}
+
+func TestDecodeRegisteredProductVariablesError(t *testing.T) {
+	registry := &productVariableRegistries{}
+	registry.register("acme_level", ProductVariableInt, &struct{ Cflags []string }{})
+
+	_, err := decodeRegisteredProductVariables([]byte(`{"Acme_level": "high"}`), registry.variables)
+	if err == nil || !strings.Contains(err.Error(), "product variable Acme_level must be") {
+		t.Errorf("expected an error for an invalid int value, got %v", err)
+	}
+}
+
+func TestRegisterProductVariableErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		register func(r *productVariableRegistries)
+	}{
+		{
+			name: "built-in property",
+			register: func(r *productVariableRegistries) {
+				r.register("eng", ProductVariableBool, &struct{ Cflags []string }{})
+			},
+		},
+		{
+			name: "built-in variable",
+			register: func(r *productVariableRegistries) {
+				r.register("deviceName", ProductVariableString, &struct{ Cflags []string }{})
+			},
+		},
+		{
+			name: "duplicate",
+			register: func(r *productVariableRegistries) {
+				r.register("acme_feature", ProductVariableBool, &struct{ Cflags []string }{})
+				r.register("acme_feature", ProductVariableBool, &struct{ Cflags []string }{})
+			},
+		},
+		{
+			name: "not a pointer",
+			register: func(r *productVariableRegistries) {
+				r.register("acme_feature", ProductVariableBool, struct{ Cflags []string }{})
+			},
+		},
+		{
+			name: "after properties",
+			register: func(r *productVariableRegistries) {
+				r.properties()
+				r.register("acme_feature", ProductVariableBool, &struct{ Cflags []string }{})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected a panic")
+				}
+			}()
+			testCase.register(&productVariableRegistries{})
+		})
+	}
+}
@@ -213,8 +213,11 @@ func RegisterVisibilityRuleChecker(ctx RegisterMutatorsContext) {
 // having to process multiple variants for each module. This goes after defaults expansion to gather
 // the complete visibility lists from flat lists and after the package info is gathered to ensure
 // that default_visibility is available.
+//
+// The package_group references are checked once all the package groups have been gathered.
 func RegisterVisibilityRuleGatherer(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("visibilityRuleGatherer", visibilityRuleGatherer).Parallel()
+	ctx.BottomUp("packageGroupReferenceChecker", packageGroupReferenceChecker).Parallel()
 }
 
 // This must be registered after the deps have been resolved.
@@ -300,6 +303,10 @@ func visibilityRuleGatherer(ctx BottomUpMutatorContext) {
 	qualifiedModuleId := m.qualifiedModuleId(ctx)
 	currentPkg := qualifiedModuleId.pkg
 
+	if g, ok := m.(*packageGroupModule); ok {
+		g.gatherPackageGroup(ctx)
+	}
+
 	// Parse the visibility rules that control access to the module and store them by id
 	// for use when enforcing the rules.
 	primaryProperty := m.base().primaryVisibilityProperty
@@ -350,7 +357,9 @@ func parseRules(ctx BaseModuleContext, currentPkg, property string, visibility [
 			case "__subpackages__":
 				r = subpackagesRule{pkg}
 			default:
-				continue
+				// Any other name may reference a package_group, which is only known once all of
+				// them have been gathered, see packageGroupReferenceChecker.
+				r = packageGroupRule{qualifiedModuleName{pkg, name}, packageGroupMap(ctx.Config())}
 			}
 		}
 
@@ -377,6 +386,46 @@ func parseRules(ctx BaseModuleContext, currentPkg, property string, visibility [
 	return rules
 }
 
+// Checks that the package groups referenced by the includes property of a package_group exist,
+// and drops the rules of a module that don't name a package_group, which are ignored like before
+// package groups existed.
+func packageGroupReferenceChecker(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok {
+		return
+	}
+
+	groups := packageGroupMap(ctx.Config())
+	if g, ok := m.(*packageGroupModule); ok {
+		for _, include := range g.properties.Includes {
+			if id, ok := splitPackageGroupReference(include, ctx.ModuleDir()); ok {
+				if _, ok := groups.Load(id); !ok {
+					ctx.PropertyErrorf("includes", "unknown package_group %q", include)
+				}
+			}
+		}
+	}
+
+	qualifiedModuleId := m.qualifiedModuleId(ctx)
+	value, ok := moduleToVisibilityRuleMap(ctx.Config()).Load(qualifiedModuleId)
+	if !ok {
+		return
+	}
+	rules := value.(compositeRule)
+	known := make(compositeRule, 0, len(rules))
+	for _, r := range rules {
+		if r, ok := r.(packageGroupRule); ok {
+			if _, ok := groups.Load(r.group); !ok {
+				continue
+			}
+		}
+		known = append(known, r)
+	}
+	if len(known) != len(rules) {
+		moduleToVisibilityRuleMap(ctx.Config()).Store(qualifiedModuleId, known)
+	}
+}
+
 func isAllowedFromOutsideVendor(pkg string, name string) bool {
 	if pkg == "vendor" {
 		if name == "__subpackages__" {
@@ -1137,6 +1137,178 @@ var visibilityTests = []struct {
 				}`),
 		},
 	},
+	{
+		name: "package_group visibility",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//groups:friends"],
+				}`),
+			"groups/Blueprints": []byte(`
+				package_group {
+					name: "friends",
+					packages: [
+						"//other/...",
+						"-//other/excluded",
+					],
+					includes: [":more_friends"],
+				}
+
+				package_group {
+					name: "more_friends",
+					packages: ["//more"],
+				}`),
+			"other/nested/Blueprints": []byte(`
+				mock_library {
+					name: "libnested",
+					deps: ["libexample"],
+				}`),
+			"other/excluded/Blueprints": []byte(`
+				mock_library {
+					name: "libexcluded",
+					deps: ["libexample"],
+				}`),
+			"more/Blueprints": []byte(`
+				mock_library {
+					name: "libmore",
+					deps: ["libexample"],
+				}`),
+			"more/nested/Blueprints": []byte(`
+				mock_library {
+					name: "libmorenested",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libexcluded" variant "android_common": depends on //top:libexample which is not visible to this module`,
+			`module "libmorenested" variant "android_common": depends on //top:libexample which is not visible to this module`,
+		},
+		effectiveVisibility: map[qualifiedModuleName][]string{
+			qualifiedModuleName{pkg: "top", name: "libexample"}: {"//groups:friends"},
+		},
+	},
+	{
+		name: "package_group in default_visibility",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				package {
+					default_visibility: [":friends"],
+				}
+
+				package_group {
+					name: "friends",
+					packages: ["//other"],
+				}
+
+				mock_library {
+					name: "libexample",
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+			"outsider/Blueprints": []byte(`
+				mock_library {
+					name: "liboutsider",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "liboutsider" variant "android_common": depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "package_group includes cycle",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: [":a"],
+				}
+
+				package_group {
+					name: "a",
+					includes: [":b"],
+				}
+
+				package_group {
+					name: "b",
+					packages: ["//other"],
+					includes: [":a"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+			"outsider/Blueprints": []byte(`
+				mock_library {
+					name: "liboutsider",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "liboutsider" variant "android_common": depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "package_group unknown",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//groups:nobody"],
+				}`),
+			"groups/Blueprints": []byte(`
+				mock_library {
+					name: "nobody",
+					deps: ["libexample"],
+				}`),
+		},
+		// A rule that doesn't name a package_group is ignored.
+		expectedErrors: []string{
+			`module "nobody" variant "android_common": depends on //top:libexample which is not visible to this module`,
+		},
+		effectiveVisibility: map[qualifiedModuleName][]string{
+			qualifiedModuleName{pkg: "top", name: "libexample"}: {"//top"},
+		},
+	},
+	{
+		name: "package_group unknown include",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				package_group {
+					name: "friends",
+					includes: [":nobody"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "friends": includes: unknown package_group ":nobody"`,
+		},
+	},
+	{
+		name: "package_group invalid",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				package_group {
+					name: "friends",
+					packages: [
+						"other",
+						"//other/...",
+						"//...",
+						"-//other:lib",
+					],
+					includes: ["friends"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "friends": packages: invalid package "other"`,
+			`module "friends": packages: invalid package "-//other:lib"`,
+			`module "friends": includes: invalid package_group "friends"`,
+		},
+	},
 }
 
 func TestVisibility(t *testing.T) {
@@ -1175,6 +1347,7 @@ func testVisibility(buildDir string, fs map[string][]byte) (*TestContext, []erro
 
 	// Order of the following method calls is significant.
 	RegisterPackageBuildComponents(ctx)
+	RegisterPackageGroupBuildComponents(ctx)
 	registerTestPrebuiltBuildComponents(ctx)
 	ctx.PreArchMutators(RegisterVisibilityRuleChecker)
 	ctx.PreArchMutators(RegisterDefaultsPreArchMutators)
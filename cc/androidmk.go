@@ -245,6 +245,9 @@ func (library *libraryDecorator) AndroidMkEntries(ctx AndroidMkContext, entries
 	entries.ExtraEntries = append(entries.ExtraEntries, func(entries *android.AndroidMkEntries) {
 		library.androidMkWriteExportedFlags(entries)
 		library.androidMkEntriesWriteAdditionalDependenciesForSourceAbiDiff(entries)
+		if library.stubsSymbolsCheck.Valid() {
+			entries.AddStrings("LOCAL_ADDITIONAL_DEPENDENCIES", library.stubsSymbolsCheck.String())
+		}
 
 		_, _, ext := android.SplitFileExt(entries.OutputFile.Path().Base())
 
@@ -391,6 +394,11 @@ func (fuzz *fuzzBinary) AndroidMkEntries(ctx AndroidMkContext, entries *android.
 			filepath.Dir(fuzz.dictionary.String())+":"+fuzz.dictionary.Base())
 	}
 
+	if fuzz.options != nil {
+		fuzzFiles = append(fuzzFiles,
+			filepath.Dir(fuzz.options.String())+":"+fuzz.options.Base())
+	}
+
 	if fuzz.config != nil {
 		fuzzFiles = append(fuzzFiles,
 			filepath.Dir(fuzz.config.String())+":config.json")
@@ -596,6 +604,11 @@ func (p *prebuiltLibraryLinker) AndroidMkEntries(ctx AndroidMkContext, entries *
 	if p.shared() {
 		ctx.subAndroidMk(entries, &p.prebuiltLinker)
 		androidMkWriteAllowUndefinedSymbols(p.baseLinker, entries)
+		if len(p.sourceAbiChecks) > 0 {
+			entries.ExtraEntries = append(entries.ExtraEntries, func(entries *android.AndroidMkEntries) {
+				entries.AddStrings("LOCAL_ADDITIONAL_DEPENDENCIES", p.sourceAbiChecks.Strings()...)
+			})
+		}
 	}
 }
 
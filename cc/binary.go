@@ -191,6 +191,10 @@ func (binary *binaryDecorator) isDependencyRoot() bool {
 	return true
 }
 
+func (binary *binaryDecorator) moduleInfoJSON(ctx ModuleContext, info *android.ModuleInfoJSON) {
+	info.Class = append(info.Class, "EXECUTABLES")
+}
+
 func NewBinary(hod android.HostOrDeviceSupported) (*Module, *binaryDecorator) {
 	module := newModule(hod, android.MultilibFirst)
 	binary := &binaryDecorator{
@@ -216,6 +220,12 @@ func (binary *binaryDecorator) linkerInit(ctx BaseModuleContext) {
 			if binary.Properties.Static_executable == nil && ctx.Config().HostStaticBinaries() {
 				binary.Properties.Static_executable = BoolPtr(true)
 			}
+		} else if ctx.Os() == android.LinuxMusl {
+			// musl host tools are built as static executables so that they can be distributed
+			// without depending on the libc of the machine they run on.
+			if binary.Properties.Static_executable == nil {
+				binary.Properties.Static_executable = BoolPtr(true)
+			}
 		} else if !ctx.Fuchsia() {
 			// Static executables are not supported on Darwin or Windows
 			binary.Properties.Static_executable = nil
@@ -26,6 +26,7 @@ import (
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/pathtools"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/cc/config"
@@ -58,7 +59,7 @@ var (
 
 	ccNoDeps = pctx.AndroidStaticRule("ccNoDeps",
 		blueprint.RuleParams{
-			Command:     "$relPwd $ccCmd -c $cFlags -o $out $in",
+			Command:     "$relPwd ${config.CcWrapper}$ccCmd -c $cFlags -o $out $in",
 			CommandDeps: []string{"$ccCmd"},
 		},
 		"ccCmd", "cFlags")
@@ -161,6 +162,39 @@ var (
 			Command: "rm -f $out && touch $out",
 		})
 
+	// Rule to check that a prebuilt shared library exports the same symbols as its source module.
+	prebuiltSymbolsCheck = pctx.AndroidStaticRule("prebuiltSymbolsCheck",
+		blueprint.RuleParams{
+			Command: "${config.ClangBin}/llvm-nm -D --defined-only --format=posix ${in} | cut -d' ' -f1 | sort -u > ${out}.prebuilt && " +
+				"${config.ClangBin}/llvm-nm -D --defined-only --format=posix ${source} | cut -d' ' -f1 | sort -u > ${out}.source && " +
+				"(diff -u ${out}.source ${out}.prebuilt " +
+				"|| (echo 'error: the symbols exported by the prebuilt ${in} differ from its source module ${source}' && exit 1)) && " +
+				"touch ${out}",
+			CommandDeps: []string{"${config.ClangBin}/llvm-nm"},
+		},
+		"source")
+
+	// Rule to check that a shared library defines every symbol listed for its stubs.
+	stubSymbolsCheck = pctx.AndroidStaticRule("stubSymbolsCheck",
+		blueprint.RuleParams{
+			Command: "${config.ClangBin}/llvm-nm -D --defined-only --format=posix ${lib} | cut -d' ' -f1 | sed 's/@.*//' | sort -u > ${out}.defined && " +
+				"sort -u ${in} | comm -23 - ${out}.defined > ${out}.missing && " +
+				"(test ! -s ${out}.missing || " +
+				"(echo 'error: symbols listed in ${symbolFile} are not defined by ${lib}:' && cat ${out}.missing && exit 1)) && " +
+				"touch ${out}",
+			CommandDeps: []string{"${config.ClangBin}/llvm-nm"},
+		},
+		"lib", "symbolFile")
+
+	// Rule to check that one of the sources of a module includes a header.
+	headerIncludedCheck = pctx.AndroidStaticRule("headerIncludedCheck",
+		blueprint.RuleParams{
+			Command: "(grep -qsF ${patterns} ${in} " +
+				"|| (echo 'error: ${header} is listed in generated_header_exports but no source includes it' && exit 1)) && " +
+				"touch ${out}",
+		},
+		"patterns", "header")
+
 	_ = pctx.SourcePathVariable("tocPath", "build/soong/scripts/toc.sh")
 
 	toc = pctx.AndroidStaticRule("toc",
@@ -241,6 +275,15 @@ var (
 		},
 		"allowFlags", "referenceDump", "libName", "arch", "createReferenceDumpFlags")
 
+	prebuiltAbiDumpDiff = pctx.AndroidStaticRule("prebuiltAbiDumpDiff",
+		blueprint.RuleParams{
+			Command: "($sAbiDiffer -lib ${libName} -arch ${arch} -o ${out} -new ${in} -old ${sourceDump}) " +
+				"|| (echo 'error: the header ABI dump of the prebuilt ${libName} differs from its source module' " +
+				"&& exit 1)",
+			CommandDeps: []string{"$sAbiDiffer"},
+		},
+		"libName", "arch", "sourceDump")
+
 	unzipRefSAbiDump = pctx.AndroidStaticRule("unzipRefSAbiDump",
 		blueprint.RuleParams{
 			Command: "gunzip -c $in > $out",
@@ -312,6 +355,7 @@ type builderFlags struct {
 	tidyFlags     string
 	sAbiFlags     string
 	aidlFlags     string
+	aidlDeps      android.Paths
 	rsFlags       string
 	toolchain     config.Toolchain
 	tidy          bool
@@ -325,8 +369,11 @@ type builderFlags struct {
 
 	groupStaticLibs bool
 
+	thinArchive bool
+
 	stripKeepSymbols              bool
 	stripKeepSymbolsList          string
+	stripKeepSymbolsListFile      android.OptionalPath
 	stripKeepSymbolsAndDebugFrame bool
 	stripKeepMiniDebugInfo        bool
 	stripAddGnuDebuglink          bool
@@ -335,8 +382,12 @@ type builderFlags struct {
 	proto            android.ProtoFlags
 	protoC           bool
 	protoOptionsFile bool
+	protoGrpc        bool
 
 	yacc *YaccProperties
+	lex  *LexProperties
+
+	clangModuleCache *clangModuleCache
 }
 
 type Objects struct {
@@ -435,6 +486,9 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 	cppflags += " ${config.NoOverrideClangGlobalCflags}"
 	toolingCppflags += " ${config.NoOverrideClangGlobalCflags}"
 
+	// Rebuild the objects when the compiler caching wrapper changes.
+	ccWrapper := config.CcWrapperPath(ctx)
+
 	for i, srcFile := range srcFiles {
 		objFile := android.ObjPathWithExt(ctx, subdir, srcFile, "o")
 
@@ -520,14 +574,19 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
 
+		implicits := cFlagsDeps
+		if ccWrapper.Valid() {
+			implicits = append(android.Paths{ccWrapper.Path()}, cFlagsDeps...)
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
 			Description:     ccDesc + " " + srcFile.Rel(),
 			Output:          objFile,
 			ImplicitOutputs: implicitOutputs,
 			Input:           srcFile,
-			Implicits:       cFlagsDeps,
-			OrderOnly:       pathDeps,
+			Implicits:       implicits,
+			OrderOnly:       flags.clangModuleCache.orderOnly(pathDeps, objFile),
 			Args: map[string]string{
 				"cFlags": moduleFlags,
 				"ccCmd":  ccCmd,
@@ -542,7 +601,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 				Output:      kytheFile,
 				Input:       srcFile,
 				Implicits:   cFlagsDeps,
-				OrderOnly:   pathDeps,
+				OrderOnly:   flags.clangModuleCache.orderOnly(pathDeps, kytheFile),
 				Args: map[string]string{
 					"cFlags": moduleFlags,
 				},
@@ -563,7 +622,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 				// support exporting dependencies.
 				Implicit:  objFile,
 				Implicits: cFlagsDeps,
-				OrderOnly: pathDeps,
+				OrderOnly: flags.clangModuleCache.orderOnly(pathDeps, tidyFile),
 				Args: map[string]string{
 					"cFlags":    moduleToolingFlags,
 					"tidyFlags": flags.tidyFlags,
@@ -586,7 +645,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 				Input:       srcFile,
 				Implicit:    objFile,
 				Implicits:   cFlagsDeps,
-				OrderOnly:   pathDeps,
+				OrderOnly:   flags.clangModuleCache.orderOnly(pathDeps, sAbiDumpFile),
 				Args: map[string]string{
 					"cFlags":     moduleToolingFlags,
 					"exportDirs": flags.sAbiFlags,
@@ -611,6 +670,10 @@ func TransformObjToStaticLib(ctx android.ModuleContext, objFiles android.Paths,
 
 	arCmd := "${config.ClangBin}/llvm-ar"
 	arFlags := "crsPD"
+	if flags.thinArchive {
+		// A thin archive references the object files instead of copying them.
+		arFlags += "T"
+	}
 	if !ctx.Darwin() {
 		arFlags += " -format=gnu"
 	}
@@ -756,14 +819,14 @@ func UnzipRefDump(ctx android.ModuleContext, zippedRefDump android.Path, baseNam
 }
 
 func SourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceDump android.Path,
-	baseName, exportedHeaderFlags string, isLlndk, isNdk, isVndkExt bool) android.OptionalPath {
+	baseName, exportedHeaderFlags string, isLlndk, isNdk, isVndkExt, adviceOnly bool) android.OptionalPath {
 
 	outputFile := android.PathForModuleOut(ctx, baseName+".abidiff")
 	libName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
 	createReferenceDumpFlags := ""
 
 	localAbiCheckAllowFlags := append([]string(nil), abiCheckAllowFlags...)
-	if exportedHeaderFlags == "" {
+	if exportedHeaderFlags == "" || adviceOnly {
 		localAbiCheckAllowFlags = append(localAbiCheckAllowFlags, "-advice-only")
 	}
 	if isLlndk || isNdk {
@@ -825,6 +888,79 @@ func TransformSharedObjectToToc(ctx android.ModuleContext, inputFile android.Pat
 	})
 }
 
+// Generate a rule that checks that a prebuilt shared library exports the same symbols as the
+// shared library built from source.
+func TransformPrebuiltSymbolsCheck(ctx android.ModuleContext, prebuilt, source android.Path,
+	outputFile android.WritablePath) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        prebuiltSymbolsCheck,
+		Description: "check prebuilt symbols " + outputFile.Base(),
+		Output:      outputFile,
+		Input:       prebuilt,
+		Implicit:    source,
+		Args: map[string]string{
+			"source": source.String(),
+		},
+	})
+}
+
+// Generate a rule that compares the header ABI dump of a prebuilt shared library with the header
+// ABI dump of the shared library built from source.
+func TransformPrebuiltAbiDumpDiff(ctx android.ModuleContext, prebuiltDump, sourceDump android.Path,
+	outputFile android.WritablePath) {
+
+	libName := strings.TrimSuffix(outputFile.Base(), filepath.Ext(outputFile.Base()))
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        prebuiltAbiDumpDiff,
+		Description: "header-abi-diff " + outputFile.Base(),
+		Output:      outputFile,
+		Input:       prebuiltDump,
+		Implicit:    sourceDump,
+		Args: map[string]string{
+			"libName":    libName,
+			"arch":       ctx.Arch().ArchType.Name,
+			"sourceDump": sourceDump.String(),
+		},
+	})
+}
+
+// Generate a rule that checks that a shared library defines every symbol in the symbol list
+// generated from its stubs symbol file.
+func TransformStubSymbolsCheck(ctx android.ModuleContext, symbolList, symbolFile, lib android.Path,
+	outputFile android.WritablePath) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        stubSymbolsCheck,
+		Description: "check stub symbols " + lib.Base(),
+		Output:      outputFile,
+		Input:       symbolList,
+		Implicit:    lib,
+		Args: map[string]string{
+			"lib":        lib.String(),
+			"symbolFile": symbolFile.String(),
+		},
+	})
+}
+
+// Generate a rule that checks that one of srcFiles includes header, spelled as in #include
+// directives.  Only the sources themselves are scanned, not the headers they include.
+func TransformHeaderIncludedCheck(ctx android.ModuleContext, srcFiles android.Paths, header string,
+	outputFile android.WritablePath) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        headerIncludedCheck,
+		Description: "check header included " + header,
+		Output:      outputFile,
+		Inputs:      srcFiles,
+		Args: map[string]string{
+			"patterns": "-e " + proptools.ShellEscape(`"`+header+`"`) +
+				" -e " + proptools.ShellEscape("<"+header+">"),
+			"header": header,
+		},
+	})
+}
+
 // Generate a rule for compiling multiple .o files to a .o using ld partial linking
 func TransformObjsToObj(ctx android.ModuleContext, objFiles android.Paths,
 	flags builderFlags, outputFile android.WritablePath, deps android.Paths) {
@@ -885,6 +1021,11 @@ func TransformStrip(ctx android.ModuleContext, inputFile android.Path,
 	if flags.stripKeepSymbolsList != "" {
 		args += " -k" + flags.stripKeepSymbolsList
 	}
+	var implicits android.Paths
+	if flags.stripKeepSymbolsListFile.Valid() {
+		args += " -K " + flags.stripKeepSymbolsListFile.String()
+		implicits = append(implicits, flags.stripKeepSymbolsListFile.Path())
+	}
 	if flags.stripKeepSymbolsAndDebugFrame {
 		args += " --keep-symbols-and-debug-frame"
 	}
@@ -897,6 +1038,7 @@ func TransformStrip(ctx android.ModuleContext, inputFile android.Path,
 		Description: "strip " + outputFile.Base(),
 		Output:      outputFile,
 		Input:       inputFile,
+		Implicits:   implicits,
 		Args: map[string]string{
 			"crossCompile": crossCompile,
 			"args":         args,
@@ -128,6 +128,10 @@ type PathDeps struct {
 	StaticLibObjs      Objects
 	WholeStaticLibObjs Objects
 
+	// The whole_static_libs dependency paths through which the objects of each static library
+	// in WholeStaticLibObjs were included, keyed by the name of the static library
+	WholeStaticLibPaths map[string][]string
+
 	// Paths to generated source files
 	GeneratedSources android.Paths
 	GeneratedHeaders android.Paths
@@ -150,6 +154,16 @@ type PathDeps struct {
 
 	// Path to the dynamic linker binary
 	DynamicLinker android.OptionalPath
+
+	// Paths to the ABI dump and ABI diff of the implementation library of an LLNDK stub
+	LlndkImplAbiChecks android.Paths
+
+	// Include directories exported by the implementation library of an LLNDK stub
+	LlndkImplIncludeDirs, LlndkImplSystemIncludeDirs android.Paths
+
+	// Macros defined or undefined by the flags that the implementation library of an LLNDK
+	// stub exports and by its cflags
+	LlndkImplFlags []string
 }
 
 // LocalOrGlobalFlags contains flags that need to have values set globally by the build system or locally by the module
@@ -171,12 +185,13 @@ type Flags struct {
 	Local  LocalOrGlobalFlags
 	Global LocalOrGlobalFlags
 
-	aidlFlags     []string // Flags that apply to aidl source files
-	rsFlags       []string // Flags that apply to renderscript source files
-	libFlags      []string // Flags to add libraries early to the link order
-	extraLibFlags []string // Flags to add libraries late in the link order after LdFlags
-	TidyFlags     []string // Flags that apply to clang-tidy
-	SAbiFlags     []string // Flags that apply to header-abi-dumper
+	aidlFlags     []string      // Flags that apply to aidl source files
+	aidlDeps      android.Paths // Files depended on by aidl flags
+	rsFlags       []string      // Flags that apply to renderscript source files
+	libFlags      []string      // Flags to add libraries early to the link order
+	extraLibFlags []string      // Flags to add libraries late in the link order after LdFlags
+	TidyFlags     []string      // Flags that apply to clang-tidy
+	SAbiFlags     []string      // Flags that apply to header-abi-dumper
 
 	// Global include flags that apply to C, C++, and assembly source files
 	// These must be after any module include flags, which will be in CommonFlags.
@@ -200,8 +215,13 @@ type Flags struct {
 	proto            android.ProtoFlags
 	protoC           bool // Whether to use C instead of C++
 	protoOptionsFile bool // Whether to look for a .options file next to the .proto
+	protoGrpc        bool // Whether to generate gRPC C++ services
 
 	Yacc *YaccProperties
+	Lex  *LexProperties
+
+	// The Clang module cache of a module compiled with clang_modules, shared by its compiles
+	clangModuleCache *clangModuleCache
 }
 
 // Properties used to compile all C or C++ modules
@@ -323,6 +343,7 @@ type ModuleContextIntf interface {
 	isVndk() bool
 	isVndkSp() bool
 	isVndkExt() bool
+	isVendorAvailable() bool
 	inProduct() bool
 	inVendor() bool
 	inRamdisk() bool
@@ -396,6 +417,12 @@ type linker interface {
 	linkerSpecifiedDeps(specifiedDeps specifiedDeps) specifiedDeps
 }
 
+// moduleInfoJSONLinker is implemented by the linkers that fill in the module-info.json entry of
+// the module, e.g. with its class and the test config of a test.
+type moduleInfoJSONLinker interface {
+	moduleInfoJSON(ctx ModuleContext, info *android.ModuleInfoJSON)
+}
+
 type specifiedDeps struct {
 	sharedLibs       []string
 	systemSharedLibs []string // Note nil and [] are semantically distinct.
@@ -936,6 +963,12 @@ func (c *Module) HasVendorVariant() bool {
 	return c.IsVndk() || Bool(c.VendorProperties.Vendor_available)
 }
 
+// Returns true when this module is vendor_available but not a VNDK library, so that its vendor
+// variant is only used by vendor modules.
+func (c *Module) isVendorAvailable() bool {
+	return Bool(c.VendorProperties.Vendor_available) && !c.IsVndk()
+}
+
 const (
 	// VendorVariationPrefix is the variant prefix used for /vendor code that compiles
 	// against the VNDK.
@@ -1180,6 +1213,10 @@ func (ctx *moduleContextImpl) isVndkExt() bool {
 	return ctx.mod.isVndkExt()
 }
 
+func (ctx *moduleContextImpl) isVendorAvailable() bool {
+	return ctx.mod.isVendorAvailable()
+}
+
 func (ctx *moduleContextImpl) mustUseVendorVariant() bool {
 	return ctx.mod.MustUseVendorVariant()
 }
@@ -1491,6 +1528,9 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 	for _, feature := range c.features {
 		flags = feature.flags(ctx, flags)
 	}
+	if c.linker != nil && c.linker.useClangLld(ctx) {
+		flags = linkerBackendFlags(ctx, flags)
+	}
 	if ctx.Failed() {
 		return
 	}
@@ -1576,6 +1616,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		// dependencies.
 		c.SkipInstall()
 	}
+
+	if linker, ok := c.linker.(moduleInfoJSONLinker); ok {
+		linker.moduleInfoJSON(ctx, ctx.ModuleInfoJSON())
+	}
 }
 
 func (c *Module) toolchain(ctx android.BaseModuleContext) config.Toolchain {
@@ -2175,6 +2219,32 @@ func checkDoubleLoadableLibraries(ctx android.TopDownMutatorContext) {
 	}
 }
 
+// addWholeStaticLibPaths records the whole_static_libs dependency paths to the static libraries
+// whose objects are included by including the objects of a whole static library, and reports an
+// error if any of them were already included through another path.
+func addWholeStaticLibPaths(ctx android.ModuleContext, depPaths *PathDeps, dep *Module, depName string,
+	staticLib libraryInterface) {
+
+	paths := map[string][]string{
+		dep.BaseModuleName(): {depName},
+	}
+	for lib, path := range staticLib.getWholeStaticLibPaths() {
+		paths[lib] = append([]string{depName}, path...)
+	}
+
+	if depPaths.WholeStaticLibPaths == nil {
+		depPaths.WholeStaticLibPaths = make(map[string][]string)
+	}
+	for _, lib := range android.SortedStringKeys(paths) {
+		if existing, ok := depPaths.WholeStaticLibPaths[lib]; ok {
+			ctx.ModuleErrorf("the objects of %q are included more than once through whole_static_libs, "+
+				"via %s and via %s", lib, strings.Join(existing, " -> "), strings.Join(paths[lib], " -> "))
+			continue
+		}
+		depPaths.WholeStaticLibPaths[lib] = paths[lib]
+	}
+}
+
 // Convert dependencies to paths.  Returns a PathDeps containing paths
 func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	var depPaths PathDeps
@@ -2245,10 +2315,32 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			return
 		}
 
-		if depTag == android.ProtoPluginDepTag {
+		if depTag == android.ProtoPluginDepTag || depTag == prebuiltSourceDepTag {
 			return
 		}
 		if depTag == llndkImplDep {
+			if impl, ok := ccDep.(*Module); ok {
+				if library, ok := impl.linker.(*libraryDecorator); ok {
+					if library.sAbiOutputFile.Valid() {
+						depPaths.LlndkImplAbiChecks = append(depPaths.LlndkImplAbiChecks,
+							library.sAbiOutputFile.Path())
+					}
+					if library.sAbiDiff.Valid() {
+						depPaths.LlndkImplAbiChecks = append(depPaths.LlndkImplAbiChecks,
+							library.sAbiDiff.Path())
+					}
+					depPaths.LlndkImplIncludeDirs = append(depPaths.LlndkImplIncludeDirs,
+						library.exportedDirs()...)
+					depPaths.LlndkImplSystemIncludeDirs = append(depPaths.LlndkImplSystemIncludeDirs,
+						library.exportedSystemDirs()...)
+					for _, flag := range append(library.exportedFlags(), library.baseCompiler.Properties.Cflags...) {
+						if strings.HasPrefix(flag, "-D") || strings.HasPrefix(flag, "-U") {
+							depPaths.LlndkImplFlags = append(depPaths.LlndkImplFlags, flag)
+						}
+					}
+					depPaths.LlndkImplFlags = android.FirstUniqueStrings(depPaths.LlndkImplFlags)
+				}
+			}
 			return
 		}
 
@@ -2466,6 +2558,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 					ctx.AddMissingDependencies(missingDeps)
 				}
 				depPaths.WholeStaticLibObjs = depPaths.WholeStaticLibObjs.Append(staticLib.objs())
+				addWholeStaticLibPaths(ctx, &depPaths, ccWholeStaticLib, depName, staticLib)
 			} else {
 				ctx.ModuleErrorf(
 					"non-cc.Modules cannot be included as whole static libraries.", depName)
@@ -2688,6 +2781,13 @@ func (c *Module) OutputFiles(tag string) (android.Paths, error) {
 		}
 		return android.Paths{}, nil
 	default:
+		if tagged, ok := c.linker.(interface {
+			taggedOutputFiles(tag string) (android.Paths, bool)
+		}); ok {
+			if paths, ok := tagged.taggedOutputFiles(tag); ok {
+				return paths, nil
+			}
+		}
 		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 	}
 }
@@ -2740,7 +2840,7 @@ func (c *Module) object() bool {
 func (c *Module) getMakeLinkType(actx android.ModuleContext) string {
 	if c.UseVndk() {
 		if lib, ok := c.linker.(*llndkStubDecorator); ok {
-			if Bool(lib.Properties.Vendor_available) {
+			if lib.availableTo(c) {
 				return "native:vndk"
 			}
 			return "native:vndk_private"
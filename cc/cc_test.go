@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"android/soong/android"
+	"android/soong/cc/config"
 )
 
 var buildDir string
@@ -428,6 +429,8 @@ func TestVndk(t *testing.T) {
 	checkVndkLibrariesOutput(t, ctx, "vndkprivate.libraries.txt", []string{"libft2.so", "libvndk-private.so", "libvndk_sp_private-x.so"})
 	checkVndkLibrariesOutput(t, ctx, "vndksp.libraries.txt", []string{"libc++.so", "libvndk_sp-x.so", "libvndk_sp_private-x.so"})
 	checkVndkLibrariesOutput(t, ctx, "vndkcorevariant.libraries.txt", nil)
+
+	checkVndkOutput(t, ctx, "vndk/llndk.libraries.VER.txt", []string{"libc.so", "libdl.so", "libm.so"})
 }
 
 func TestVndkWithHostSupported(t *testing.T) {
@@ -958,6 +961,66 @@ func TestVendorSnapshot(t *testing.T) {
 	}
 }
 
+func TestVendorSnapshotLlndk(t *testing.T) {
+	bp := `
+	cc_library {
+		name: "libllndk",
+		stubs: { versions: ["1"] },
+		nocrt: true,
+	}
+
+	llndk_library {
+		name: "libllndk",
+		export_include_dirs: ["include"],
+	}
+`
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
+
+	snapshotVariantPath := filepath.Join(buildDir, "vendor-snapshot", "arm64")
+	snapshotSingleton := ctx.SingletonForTests("vendor-snapshot")
+
+	for _, arch := range [][]string{
+		[]string{"arm64", "armv8-a"},
+		[]string{"arm", "armv7-a-neon"},
+	} {
+		archType := arch[0]
+		archVariant := arch[1]
+		archDir := fmt.Sprintf("arch-%s-%s", archType, archVariant)
+
+		// LLNDK stubs are captured for each version in llndk/{version} instead of shared.
+		for _, version := range []string{"current", "1"} {
+			variant := fmt.Sprintf("android_vendor.VER_%s_%s_shared", archType, archVariant)
+			if version != "current" {
+				variant += "_" + version
+			}
+			llndkDir := filepath.Join(snapshotVariantPath, archDir, "llndk", version)
+			checkSnapshot(t, ctx, snapshotSingleton, "libllndk.llndk", "libllndk.so", llndkDir, variant)
+
+			versionScript := snapshotSingleton.Output(filepath.Join(llndkDir, "libllndk.map.txt"))
+			stub := ctx.ModuleForTests("libllndk.llndk", variant).Module().(*Module).linker.(*llndkStubDecorator)
+			if versionScript.Input.String() != stub.versionScriptPath.String() {
+				t.Errorf("The input of the version script snapshot must be %q, but %q",
+					stub.versionScriptPath.String(), versionScript.Input.String())
+			}
+
+			jsonFile := snapshotSingleton.Output(filepath.Join(llndkDir, "libllndk.so.json"))
+			for _, w := range []string{`"ModuleName":"libllndk"`, `"VersionScript":"libllndk.map.txt"`} {
+				if !strings.Contains(jsonFile.Args["content"], w) {
+					t.Errorf("expected %q in %q", w, jsonFile.Args["content"])
+				}
+			}
+		}
+
+		sharedDir := filepath.Join(snapshotVariantPath, archDir, "shared")
+		if snapshotSingleton.MaybeOutput(filepath.Join(sharedDir, "libllndk.so")).Rule != nil {
+			t.Errorf("LLNDK stubs must not be captured as shared libraries")
+		}
+	}
+}
+
 func TestDoubleLoadableDepError(t *testing.T) {
 	// Check whether an error is emitted when a LLNDK depends on a non-double_loadable VNDK lib.
 	testCcError(t, "module \".*\" variant \".*\": link.* \".*\" which is not LL-NDK, VNDK-SP, .*double_loadable", `
@@ -1807,6 +1870,14 @@ func TestEnforceProductVndkVersion(t *testing.T) {
 			name: "libllndk",
 			symbol_file: "",
 		}
+		cc_library {
+			name: "libllndk_vendor_only",
+		}
+		llndk_library {
+			name: "libllndk_vendor_only",
+			symbol_file: "",
+			product_available: false,
+		}
 		cc_library {
 			name: "libvndk",
 			vendor_available: true,
@@ -1852,6 +1923,7 @@ func TestEnforceProductVndkVersion(t *testing.T) {
 			vendor: true,
 			shared_libs: [
 				"libllndk",
+				"libllndk_vendor_only",
 				"libvndk",
 				"libvndk_sp",
 				"libva",
@@ -1920,6 +1992,24 @@ func TestEnforceProductVndkVersionErrors(t *testing.T) {
 			nocrt: true,
 		}
 	`)
+	testCcErrorProductVndk(t, "Product module that is not VNDK should not link to \".*\" which is marked as `product_available: false`", `
+		cc_library {
+			name: "libprod",
+			product_specific: true,
+			shared_libs: [
+				"libllndk",
+			],
+			nocrt: true,
+		}
+		cc_library {
+			name: "libllndk",
+		}
+		llndk_library {
+			name: "libllndk",
+			symbol_file: "",
+			product_available: false,
+		}
+	`)
 	testCcErrorProductVndk(t, "dependency \".*\" of \".*\" missing variant:\n.*image:product.VER", `
 		cc_library {
 			name: "libprod",
@@ -2503,673 +2593,1792 @@ func TestLlndkLibrary(t *testing.T) {
 	checkEquals(t, "override apiLevel for versioned stubs", "1", params.Args["apiLevel"])
 }
 
-func TestLlndkHeaders(t *testing.T) {
-	ctx := testCc(t, `
-	llndk_headers {
-		name: "libllndk_headers",
-		export_include_dirs: ["my_include"],
-	}
+func TestLlndkLibraryFinalSdk(t *testing.T) {
+	bp := `
 	llndk_library {
 		name: "libllndk",
-		export_llndk_headers: ["libllndk_headers"],
-	}
-	cc_library {
-		name: "libvendor",
-		shared_libs: ["libllndk"],
-		vendor: true,
-		srcs: ["foo.c"],
-		no_libcrt: true,
-		nocrt: true,
-	}
-	`)
-
-	// _static variant is used since _shared reuses *.o from the static variant
-	cc := ctx.ModuleForTests("libvendor", "android_vendor.VER_arm_armv7-a-neon_static").Rule("cc")
-	cflags := cc.Args["cFlags"]
-	if !strings.Contains(cflags, "-Imy_include") {
-		t.Errorf("cflags for libvendor must contain -Imy_include, but was %#v.", cflags)
 	}
-}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.Platform_sdk_final = BoolPtr(true)
+	ctx := testCcWithConfig(t, config)
 
-func checkRuntimeLibs(t *testing.T, expected []string, module *Module) {
-	actual := module.Properties.AndroidMkRuntimeLibs
-	if !reflect.DeepEqual(actual, expected) {
-		t.Errorf("incorrect runtime_libs for shared libs"+
-			"\nactual:   %v"+
-			"\nexpected: %v",
-			actual,
-			expected,
-		)
-	}
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("generate stub")
+	checkEquals(t, "use platform SDK version for stubs of a finalized SDK", "30", params.Args["apiLevel"])
 }
 
-const runtimeLibAndroidBp = `
-	cc_library {
-		name: "libvendor_available1",
-		vendor_available: true,
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
-	}
-	cc_library {
-		name: "libvendor_available2",
-		vendor_available: true,
-		runtime_libs: ["libvendor_available1"],
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
-	}
+func TestLlndkLibraryGenstubFlags(t *testing.T) {
+	ctx := testCc(t, `
 	cc_library {
-		name: "libvendor_available3",
-		vendor_available: true,
-		runtime_libs: ["libvendor_available1"],
-		target: {
-			vendor: {
-				exclude_runtime_libs: ["libvendor_available1"],
-			}
-		},
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
+		name: "libllndk",
+		stubs: { versions: ["1"] },
 	}
-	cc_library {
-		name: "libcore",
-		runtime_libs: ["libvendor_available1"],
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
+	llndk_library {
+		name: "libllndk",
 	}
 	cc_library {
-		name: "libvendor1",
-		vendor: true,
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
+		name: "libllndk_apex",
 	}
-	cc_library {
-		name: "libvendor2",
-		vendor: true,
-		runtime_libs: ["libvendor_available1", "libvendor1"],
-		no_libcrt : true,
-		nocrt : true,
-		system_shared_libs : [],
+	llndk_library {
+		name: "libllndk_apex",
+		include_apex_symbols: true,
+		include_systemapi_symbols: false,
 	}
-`
-
-func TestRuntimeLibs(t *testing.T) {
-	ctx := testCc(t, runtimeLibAndroidBp)
+	`)
 
-	// runtime_libs for core variants use the module names without suffixes.
-	variant := "android_arm64_armv8-a_shared"
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("generate stub")
+	checkEquals(t, "genstub flags of llndk stubs", "--llndk", params.Args["flags"])
 
-	module := ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+	params = ctx.ModuleForTests("libllndk_apex.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("generate stub")
+	checkEquals(t, "genstub flags of llndk stubs with apex symbols and without systemapi symbols",
+		"--llndk --apex --no-systemapi", params.Args["flags"])
 
-	module = ctx.ModuleForTests("libcore", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+	params = ctx.ModuleForTests("libllndk", "android_arm_armv7-a-neon_shared_1").Description("generate stub")
+	checkEquals(t, "genstub flags of apex stubs", "--apex", params.Args["flags"])
+}
 
-	// runtime_libs for vendor variants have '.vendor' suffixes if the modules have both core
-	// and vendor variants.
-	variant = "android_vendor.VER_arm64_armv8-a_shared"
+func TestLlndkLibraryArchSymbolFile(t *testing.T) {
+	bp := `
+	llndk_library {
+		name: "libllndk",
+		symbol_file: "libllndk.map.txt",
+		arch: {
+			arm64: {
+				symbol_file: "libllndk.arm64.map.txt",
+			},
+		},
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"libllndk.map.txt":       nil,
+		"libllndk.arm64.map.txt": nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
 
-	module = ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1.vendor"}, module)
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("generate stub")
+	checkEquals(t, "symbol file of arm llndk stubs", "libllndk.map.txt", params.Input.String())
 
-	module = ctx.ModuleForTests("libvendor2", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1.vendor", "libvendor1"}, module)
+	params = ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm64_armv8-a_shared").Description("generate stub")
+	checkEquals(t, "symbol file of arm64 llndk stubs", "libllndk.arm64.map.txt", params.Input.String())
 }
 
-func TestExcludeRuntimeLibs(t *testing.T) {
-	ctx := testCc(t, runtimeLibAndroidBp)
-
-	variant := "android_arm64_armv8-a_shared"
-	module := ctx.ModuleForTests("libvendor_available3", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+func TestLlndkLibraryNativeCoverage(t *testing.T) {
+	bp := `
+	llndk_library {
+		name: "libllndk",
+	}
+	llndk_library {
+		name: "libllndk_cov",
+		allow_native_coverage: true,
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.GcovCoverage = BoolPtr(true)
+	ctx := testCcWithConfig(t, config)
 
-	variant = "android_vendor.VER_arm64_armv8-a_shared"
-	module = ctx.ModuleForTests("libvendor_available3", variant).Module().(*Module)
-	checkRuntimeLibs(t, nil, module)
+	covVariant := "android_vendor.VER_arm_armv7-a-neon_shared_cov"
+	if inList(covVariant, ctx.ModuleVariantsForTests("libllndk.llndk")) {
+		t.Errorf("unexpected coverage variant of libllndk.llndk")
+	}
+	if !inList(covVariant, ctx.ModuleVariantsForTests("libllndk_cov.llndk")) {
+		t.Errorf("missing coverage variant of libllndk_cov.llndk: %q",
+			ctx.ModuleVariantsForTests("libllndk_cov.llndk"))
+	}
 }
 
-func TestRuntimeLibsNoVndk(t *testing.T) {
-	ctx := testCcNoVndk(t, runtimeLibAndroidBp)
+func TestClangCoverage(t *testing.T) {
+	bp := `
+	cc_binary {
+		name: "bin",
+		srcs: ["foo.c"],
+	}
 
-	// If DeviceVndkVersion is not defined, then runtime_libs are copied as-is.
+	cc_library_static {
+		name: "libprofile-clang-extras",
+		system_shared_libs: [],
+		stl: "none",
+		native_coverage: false,
+	}
 
-	variant := "android_arm64_armv8-a_shared"
+	cc_library_static {
+		name: "libprofile-extras",
+		system_shared_libs: [],
+		stl: "none",
+		native_coverage: false,
+	}
 
-	module := ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+	cc_prebuilt_library_static {
+		name: "libclang_rt.profile-aarch64-android",
+		srcs: ["libclang_rt.profile-aarch64-android.a"],
+		system_shared_libs: [],
+		stl: "none",
+		native_coverage: false,
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"libclang_rt.profile-aarch64-android.a": nil,
+	})
+	config.TestProductVariables.ClangCoverage = BoolPtr(true)
+	config.TestProductVariables.NativeCoveragePaths = []string{"*"}
+	ctx := testCcWithConfig(t, config)
 
-	module = ctx.ModuleForTests("libvendor2", variant).Module().(*Module)
-	checkRuntimeLibs(t, []string{"libvendor_available1", "libvendor1"}, module)
-}
+	bin := ctx.ModuleForTests("bin", "android_arm64_armv8-a_cov").Module().(*Module)
+	if !inList("${config.ClangCoverageCommonFlags}", bin.flags.Local.CommonFlags) {
+		t.Errorf("missing clang coverage flags in %q", bin.flags.Local.CommonFlags)
+	}
+	if !inList("${config.ClangCoverageLdflags}", bin.flags.Local.LdFlags) {
+		t.Errorf("missing clang coverage ldflags in %q", bin.flags.Local.LdFlags)
+	}
 
-func checkStaticLibs(t *testing.T, expected []string, module *Module) {
-	t.Helper()
-	actual := module.Properties.AndroidMkStaticLibs
-	if !reflect.DeepEqual(actual, expected) {
-		t.Errorf("incorrect static_libs"+
-			"\nactual:   %v"+
-			"\nexpected: %v",
-			actual,
-			expected,
-		)
+	link := ctx.ModuleForTests("bin", "android_arm64_armv8-a_cov").Rule("ld")
+	if !strings.Contains(strings.Join(append(link.Inputs.Strings(), link.Implicits.Strings()...), " "),
+		"libclang_rt.profile-aarch64-android") {
+		t.Errorf("missing profile runtime in link of bin: inputs %q implicits %q", link.Inputs, link.Implicits)
 	}
 }
 
-const staticLibAndroidBp = `
+func TestLlndkSdk(t *testing.T) {
+	bp := `
 	cc_library {
-		name: "lib1",
+		name: "libllndk",
+		export_include_dirs: ["include"],
 	}
-	cc_library {
-		name: "lib2",
-		static_libs: ["lib1"],
+	llndk_library {
+		name: "libllndk",
+		symbol_file: "",
+		export_include_dirs: ["include"],
 	}
-`
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"include/libllndk.h": nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
 
-func TestStaticLibDepExport(t *testing.T) {
-	ctx := testCc(t, staticLibAndroidBp)
+	sdkDir := filepath.Join(buildDir, "llndk-sdk")
+	sdkSingleton := ctx.SingletonForTests("llndk-sdk")
+
+	for _, arch := range [][]string{
+		[]string{"arm64", "armv8-a"},
+		[]string{"arm", "armv7-a-neon"},
+	} {
+		variant := fmt.Sprintf("android_vendor.VER_%s_%s_shared", arch[0], arch[1])
+		libDir := filepath.Join(sdkDir, fmt.Sprintf("arch-%s-%s", arch[0], arch[1]), "current")
+		checkSnapshot(t, ctx, sdkSingleton, "libllndk.llndk", "libllndk.so", libDir, variant)
+		sdkSingleton.Output(filepath.Join(libDir, "libllndk.map.txt"))
+		sdkSingleton.Output(filepath.Join(libDir, "libllndk.so.json"))
+	}
+
+	sdkSingleton.Output(filepath.Join(sdkDir, "include", "include", "libllndk.h"))
+	sdkSingleton.Output(filepath.Join(sdkDir, "llndk-sdk-VER.zip"))
+}
+
+func TestLlndkLibraryPreprocessWithImplFlags(t *testing.T) {
+	bp := `
+	cc_library {
+		name: "libllndk",
+		cflags: ["-DLIBLLNDK_CONFIG=1", "-Wall"],
+		export_cflags: ["-DLIBLLNDK_CONFIG=1", "-include libllndk_prefix.h"],
+		export_include_dirs: ["include"],
+	}
+	llndk_library {
+		name: "libllndk",
+		symbol_file: "",
+		export_preprocessed_headers: ["preprocessed"],
+		preprocess_with_impl_flags: true,
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"include/libllndk_config.h": nil,
+		"preprocessed/libllndk.h":   nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
+
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("versioner preprocess")
+	checkEquals(t, "versioner flags of llndk stubs", "-DLIBLLNDK_CONFIG=1 -Iinclude", params.Args["flags"])
+}
+
+func TestLlndkLibraryHeaderCompatibilityCheck(t *testing.T) {
+	bp := `
+	cc_library {
+		name: "libllndk",
+		export_include_dirs: ["impl_include"],
+	}
+	llndk_library {
+		name: "libllndk",
+		symbol_file: "",
+		export_include_dirs: ["include"],
+		header_compatibility_check: true,
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"include/libllndk.h":      nil,
+		"impl_include/libllndk.h": nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
+
+	module := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared")
+	tu := module.Description("generate LLNDK header check libllndk.llndk")
+	checkEquals(t, "header check translation unit", "#include <libllndk.h>", tu.Args["content"])
+
+	check := module.Rule("llndkHeaderCheck")
+	checkEquals(t, "header check input", tu.Output.String(), check.Input.String())
+	checkEquals(t, "header check headers", []string{"include/libllndk.h"}, check.Implicits.Strings())
+	checkEquals(t, "stub includes", "-Iinclude", check.Args["stubIncludes"])
+	checkEquals(t, "implementation includes", "-Iimpl_include", check.Args["implIncludes"])
+	checkEquals(t, "header check intermediates", []string{
+		check.Output.String() + ".stub.ast",
+		check.Output.String() + ".impl.ast",
+		check.Output.String() + ".stub.list",
+		check.Output.String() + ".impl.list",
+		check.Output.String() + ".diff",
+	}, check.ImplicitOutputs.Strings())
+
+	// Vendor modules using the stub depend on the check.
+	exportedDeps := module.Module().(*Module).linker.(exportedFlagsProducer).exportedDeps()
+	if !android.InList(check.Output.String(), exportedDeps.Strings()) {
+		t.Errorf("expected the exported deps of libllndk.llndk to contain %q, got %q",
+			check.Output.String(), exportedDeps.Strings())
+	}
+}
+
+func TestOverrideLlndk(t *testing.T) {
+	bp := `
+	llndk_library {
+		name: "libllndk",
+		symbol_file: "libllndk.map.txt",
+		export_include_dirs: ["include"],
+	}
+	override_llndk {
+		name: "libllndk_device",
+		base: "libllndk",
+		symbol_file: "device/libllndk.map.txt",
+		export_include_dirs: ["device/include"],
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"libllndk.map.txt":        nil,
+		"include/foo.h":           nil,
+		"device/libllndk.map.txt": nil,
+		"device/include/foo.h":    nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.LlndkOverrides = []string{"libllndk:libllndk_device"}
+	ctx := testCcWithConfig(t, config)
+
+	module := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared")
+	params := module.Description("generate stub")
+	checkEquals(t, "symbol file of overridden llndk stubs", "device/libllndk.map.txt", params.Input.String())
+
+	exportedDirs := module.Module().(*Module).linker.(exportedFlagsProducer).exportedDirs().Strings()
+	checkEquals(t, "include dirs of overridden llndk stubs", []string{"device/include"}, exportedDirs)
+}
+
+func TestOverrideLlndkWrongBase(t *testing.T) {
+	bp := `
+	llndk_library {
+		name: "libllndk",
+	}
+	llndk_library {
+		name: "libother",
+	}
+	override_llndk {
+		name: "libother_device",
+		base: "libother",
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.LlndkOverrides = []string{"libllndk:libother_device"}
+	testCcErrorWithConfig(t, `override_llndk module "libother_device" selected by PRODUCT_LLNDK_OVERRIDES overrides "libother"`, config)
+}
+
+func TestLlndkLibraryTypedStubs(t *testing.T) {
+	bp := `
+	llndk_library {
+		name: "libllndk",
+		export_include_dirs: ["include"],
+		typed_stubs: true,
+	}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"include/foo.h":     nil,
+		"include/bar/bar.h": nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
+
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared").Description("generate stub")
+	checkEquals(t, "genstub flags of typed llndk stubs",
+		"--llndk --include bar/bar.h --include foo.h", params.Args["flags"])
+}
+
+func TestLlndkLibraryVersions(t *testing.T) {
+	ctx := testCc(t, `
+	cc_library {
+		name: "libllndk",
+		stubs: { versions: ["1", "2"] },
+	}
+	llndk_library {
+		name: "libllndk",
+		versions: ["29", "30"],
+	}
+	`)
+	actual := ctx.ModuleVariantsForTests("libllndk.llndk")
+	expected := []string{
+		"android_vendor.VER_arm64_armv8-a_shared",
+		"android_vendor.VER_arm64_armv8-a_shared_29",
+		"android_vendor.VER_arm64_armv8-a_shared_30",
+		"android_vendor.VER_arm_armv7-a-neon_shared",
+		"android_vendor.VER_arm_armv7-a-neon_shared_29",
+		"android_vendor.VER_arm_armv7-a-neon_shared_30",
+	}
+	checkEquals(t, "variants for llndk stubs", expected, actual)
+
+	params := ctx.ModuleForTests("libllndk.llndk", "android_vendor.VER_arm_armv7-a-neon_shared_29").Description("generate stub")
+	checkEquals(t, "override apiLevel for versioned stubs", "29", params.Args["apiLevel"])
+}
+
+func TestLlndkProperty(t *testing.T) {
+	ctx := testCc(t, `
+	cc_library_shared {
+		name: "libllndk",
+		export_include_dirs: ["include"],
+		llndk: {
+			symbol_file: "libllndk.map.txt",
+			override_export_include_dirs: ["include_vndk"],
+		},
+	}
+	cc_library_shared {
+		name: "libllndk_default_includes",
+		export_include_dirs: ["include_default"],
+		llndk: {
+			symbol_file: "libllndk_default_includes.map.txt",
+		},
+	}
+	cc_library {
+		name: "libvendor",
+		shared_libs: ["libllndk", "libllndk_default_includes"],
+		vendor: true,
+		srcs: ["foo.c"],
+		no_libcrt: true,
+		nocrt: true,
+	}
+	`)
+	actual := ctx.ModuleVariantsForTests("libllndk.llndk")
+	expected := []string{
+		"android_vendor.VER_arm64_armv8-a_shared",
+		"android_vendor.VER_arm_armv7-a-neon_shared",
+	}
+	checkEquals(t, "variants for llndk stubs", expected, actual)
+
+	cc := ctx.ModuleForTests("libvendor", "android_vendor.VER_arm_armv7-a-neon_static").Rule("cc")
+	cflags := cc.Args["cFlags"]
+	if !strings.Contains(cflags, "-Iinclude_vndk") {
+		t.Errorf("cflags for libvendor must contain -Iinclude_vndk, but was %#v.", cflags)
+	}
+	if strings.Contains(cflags, "-Iinclude ") {
+		t.Errorf("cflags for libvendor must not contain -Iinclude, but was %#v.", cflags)
+	}
+	if !strings.Contains(cflags, "-Iinclude_default") {
+		t.Errorf("cflags for libvendor must contain -Iinclude_default, but was %#v.", cflags)
+	}
+}
+
+func TestLlndkPropertyOnStaticLibrary(t *testing.T) {
+	testCcError(t, `LLNDK stubs are only supported for shared libraries`, `
+	cc_library_static {
+		name: "libllndk",
+		llndk: {
+			symbol_file: "libllndk.map.txt",
+		},
+	}
+	`)
+}
+
+func TestLlndkHeaders(t *testing.T) {
+	ctx := testCc(t, `
+	llndk_headers {
+		name: "libllndk_headers",
+		export_include_dirs: ["my_include"],
+	}
+	llndk_library {
+		name: "libllndk",
+		export_llndk_headers: ["libllndk_headers"],
+	}
+	cc_library {
+		name: "libvendor",
+		shared_libs: ["libllndk"],
+		vendor: true,
+		srcs: ["foo.c"],
+		no_libcrt: true,
+		nocrt: true,
+	}
+	`)
+
+	// _static variant is used since _shared reuses *.o from the static variant
+	cc := ctx.ModuleForTests("libvendor", "android_vendor.VER_arm_armv7-a-neon_static").Rule("cc")
+	cflags := cc.Args["cFlags"]
+	if !strings.Contains(cflags, "-Imy_include") {
+		t.Errorf("cflags for libvendor must contain -Imy_include, but was %#v.", cflags)
+	}
+}
+
+func TestLlndkExportGeneratedHeaders(t *testing.T) {
+	config := TestConfig(buildDir, android.Android, nil, `
+	cc_genrule {
+		name: "libllndk_gen",
+		vendor_available: true,
+		cmd: "touch $(out)",
+		out: ["include/gen.h"],
+		export_include_dirs: ["include"],
+	}
+	llndk_library {
+		name: "libllndk",
+		export_generated_headers: ["libllndk_gen"],
+	}
+	cc_library {
+		name: "libvendor",
+		shared_libs: ["libllndk"],
+		vendor: true,
+		srcs: ["foo.c"],
+		no_libcrt: true,
+		nocrt: true,
+	}
+	`, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+
+	ctx := CreateTestContext()
+	ctx.RegisterModuleType("cc_genrule", genRuleFactory)
+	ctx.Register(config)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	gen := ctx.ModuleForTests("libllndk_gen", "android_vendor.VER_arm_armv7-a-neon")
+	header := gen.Output("include/gen.h").Output.String()
+	includeDir := filepath.Dir(header)
+
+	// _static variant is used since _shared reuses *.o from the static variant
+	cc := ctx.ModuleForTests("libvendor", "android_vendor.VER_arm_armv7-a-neon_static").Rule("cc")
+	if cflags := cc.Args["cFlags"]; !strings.Contains(cflags, "-I"+includeDir) {
+		t.Errorf("cflags for libvendor must contain -I%s, but was %#v.", includeDir, cflags)
+	}
+	if !android.InList(header, cc.OrderOnly.Strings()) {
+		t.Errorf("libvendor must be compiled after %s is generated, but the order-only deps were %q.",
+			header, cc.OrderOnly.Strings())
+	}
+}
+
+func checkRuntimeLibs(t *testing.T, expected []string, module *Module) {
+	actual := module.Properties.AndroidMkRuntimeLibs
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("incorrect runtime_libs for shared libs"+
+			"\nactual:   %v"+
+			"\nexpected: %v",
+			actual,
+			expected,
+		)
+	}
+}
+
+const runtimeLibAndroidBp = `
+	cc_library {
+		name: "libvendor_available1",
+		vendor_available: true,
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+	cc_library {
+		name: "libvendor_available2",
+		vendor_available: true,
+		runtime_libs: ["libvendor_available1"],
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+	cc_library {
+		name: "libvendor_available3",
+		vendor_available: true,
+		runtime_libs: ["libvendor_available1"],
+		target: {
+			vendor: {
+				exclude_runtime_libs: ["libvendor_available1"],
+			}
+		},
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+	cc_library {
+		name: "libcore",
+		runtime_libs: ["libvendor_available1"],
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+	cc_library {
+		name: "libvendor1",
+		vendor: true,
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+	cc_library {
+		name: "libvendor2",
+		vendor: true,
+		runtime_libs: ["libvendor_available1", "libvendor1"],
+		no_libcrt : true,
+		nocrt : true,
+		system_shared_libs : [],
+	}
+`
+
+func TestRuntimeLibs(t *testing.T) {
+	ctx := testCc(t, runtimeLibAndroidBp)
+
+	// runtime_libs for core variants use the module names without suffixes.
+	variant := "android_arm64_armv8-a_shared"
+
+	module := ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+
+	module = ctx.ModuleForTests("libcore", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+
+	// runtime_libs for vendor variants have '.vendor' suffixes if the modules have both core
+	// and vendor variants.
+	variant = "android_vendor.VER_arm64_armv8-a_shared"
+
+	module = ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1.vendor"}, module)
+
+	module = ctx.ModuleForTests("libvendor2", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1.vendor", "libvendor1"}, module)
+}
+
+func TestExcludeRuntimeLibs(t *testing.T) {
+	ctx := testCc(t, runtimeLibAndroidBp)
+
+	variant := "android_arm64_armv8-a_shared"
+	module := ctx.ModuleForTests("libvendor_available3", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+
+	variant = "android_vendor.VER_arm64_armv8-a_shared"
+	module = ctx.ModuleForTests("libvendor_available3", variant).Module().(*Module)
+	checkRuntimeLibs(t, nil, module)
+}
+
+func TestRuntimeLibsNoVndk(t *testing.T) {
+	ctx := testCcNoVndk(t, runtimeLibAndroidBp)
+
+	// If DeviceVndkVersion is not defined, then runtime_libs are copied as-is.
+
+	variant := "android_arm64_armv8-a_shared"
+
+	module := ctx.ModuleForTests("libvendor_available2", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1"}, module)
+
+	module = ctx.ModuleForTests("libvendor2", variant).Module().(*Module)
+	checkRuntimeLibs(t, []string{"libvendor_available1", "libvendor1"}, module)
+}
+
+func checkStaticLibs(t *testing.T, expected []string, module *Module) {
+	t.Helper()
+	actual := module.Properties.AndroidMkStaticLibs
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("incorrect static_libs"+
+			"\nactual:   %v"+
+			"\nexpected: %v",
+			actual,
+			expected,
+		)
+	}
+}
+
+const staticLibAndroidBp = `
+	cc_library {
+		name: "lib1",
+	}
+	cc_library {
+		name: "lib2",
+		static_libs: ["lib1"],
+	}
+`
+
+func TestStaticLibDepExport(t *testing.T) {
+	ctx := testCc(t, staticLibAndroidBp)
 
 	// Check the shared version of lib2.
 	variant := "android_arm64_armv8-a_shared"
 	module := ctx.ModuleForTests("lib2", variant).Module().(*Module)
 	checkStaticLibs(t, []string{"lib1", "libc++demangle", "libclang_rt.builtins-aarch64-android", "libatomic"}, module)
 
-	// Check the static version of lib2.
-	variant = "android_arm64_armv8-a_static"
-	module = ctx.ModuleForTests("lib2", variant).Module().(*Module)
-	// libc++_static is linked additionally.
-	checkStaticLibs(t, []string{"lib1", "libc++_static", "libc++demangle", "libclang_rt.builtins-aarch64-android", "libatomic"}, module)
+	// Check the static version of lib2.
+	variant = "android_arm64_armv8-a_static"
+	module = ctx.ModuleForTests("lib2", variant).Module().(*Module)
+	// libc++_static is linked additionally.
+	checkStaticLibs(t, []string{"lib1", "libc++_static", "libc++demangle", "libclang_rt.builtins-aarch64-android", "libatomic"}, module)
+}
+
+var compilerFlagsTestCases = []struct {
+	in  string
+	out bool
+}{
+	{
+		in:  "a",
+		out: false,
+	},
+	{
+		in:  "-a",
+		out: true,
+	},
+	{
+		in:  "-Ipath/to/something",
+		out: false,
+	},
+	{
+		in:  "-isystempath/to/something",
+		out: false,
+	},
+	{
+		in:  "--coverage",
+		out: false,
+	},
+	{
+		in:  "-include a/b",
+		out: true,
+	},
+	{
+		in:  "-include a/b c/d",
+		out: false,
+	},
+	{
+		in:  "-DMACRO",
+		out: true,
+	},
+	{
+		in:  "-DMAC RO",
+		out: false,
+	},
+	{
+		in:  "-a -b",
+		out: false,
+	},
+	{
+		in:  "-DMACRO=definition",
+		out: true,
+	},
+	{
+		in:  "-DMACRO=defi nition",
+		out: true, // TODO(jiyong): this should be false
+	},
+	{
+		in:  "-DMACRO(x)=x + 1",
+		out: true,
+	},
+	{
+		in:  "-DMACRO=\"defi nition\"",
+		out: true,
+	},
+}
+
+type mockContext struct {
+	BaseModuleContext
+	result bool
+}
+
+func (ctx *mockContext) PropertyErrorf(property, format string, args ...interface{}) {
+	// CheckBadCompilerFlags calls this function when the flag should be rejected
+	ctx.result = false
+}
+
+func TestCompilerFlags(t *testing.T) {
+	for _, testCase := range compilerFlagsTestCases {
+		ctx := &mockContext{result: true}
+		CheckBadCompilerFlags(ctx, "", []string{testCase.in})
+		if ctx.result != testCase.out {
+			t.Errorf("incorrect output:")
+			t.Errorf("     input: %#v", testCase.in)
+			t.Errorf("  expected: %#v", testCase.out)
+			t.Errorf("       got: %#v", ctx.result)
+		}
+	}
+}
+
+func TestVendorPublicLibraries(t *testing.T) {
+	ctx := testCc(t, `
+	cc_library_headers {
+		name: "libvendorpublic_headers",
+		export_include_dirs: ["my_include"],
+	}
+	vendor_public_library {
+		name: "libvendorpublic",
+		symbol_file: "",
+		export_public_headers: ["libvendorpublic_headers"],
+	}
+	cc_library {
+		name: "libvendorpublic",
+		srcs: ["foo.c"],
+		vendor: true,
+		no_libcrt: true,
+		nocrt: true,
+	}
+
+	cc_library {
+		name: "libsystem",
+		shared_libs: ["libvendorpublic"],
+		vendor: false,
+		srcs: ["foo.c"],
+		no_libcrt: true,
+		nocrt: true,
+	}
+	cc_library {
+		name: "libvendor",
+		shared_libs: ["libvendorpublic"],
+		vendor: true,
+		srcs: ["foo.c"],
+		no_libcrt: true,
+		nocrt: true,
+	}
+	`)
+
+	coreVariant := "android_arm64_armv8-a_shared"
+	vendorVariant := "android_vendor.VER_arm64_armv8-a_shared"
+
+	// test if header search paths are correctly added
+	// _static variant is used since _shared reuses *.o from the static variant
+	cc := ctx.ModuleForTests("libsystem", strings.Replace(coreVariant, "_shared", "_static", 1)).Rule("cc")
+	cflags := cc.Args["cFlags"]
+	if !strings.Contains(cflags, "-Imy_include") {
+		t.Errorf("cflags for libsystem must contain -Imy_include, but was %#v.", cflags)
+	}
+
+	// test if libsystem is linked to the stub
+	ld := ctx.ModuleForTests("libsystem", coreVariant).Rule("ld")
+	libflags := ld.Args["libFlags"]
+	stubPaths := getOutputPaths(ctx, coreVariant, []string{"libvendorpublic" + vendorPublicLibrarySuffix})
+	if !strings.Contains(libflags, stubPaths[0].String()) {
+		t.Errorf("libflags for libsystem must contain %#v, but was %#v", stubPaths[0], libflags)
+	}
+
+	// test if libvendor is linked to the real shared lib
+	ld = ctx.ModuleForTests("libvendor", vendorVariant).Rule("ld")
+	libflags = ld.Args["libFlags"]
+	stubPaths = getOutputPaths(ctx, vendorVariant, []string{"libvendorpublic"})
+	if !strings.Contains(libflags, stubPaths[0].String()) {
+		t.Errorf("libflags for libvendor must contain %#v, but was %#v", stubPaths[0], libflags)
+	}
+
+}
+
+func TestRecovery(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "librecovery",
+			recovery: true,
+		}
+		cc_library_shared {
+			name: "librecovery32",
+			recovery: true,
+			compile_multilib:"32",
+		}
+		cc_library_shared {
+			name: "libHalInRecovery",
+			recovery_available: true,
+			vendor: true,
+		}
+	`)
+
+	variants := ctx.ModuleVariantsForTests("librecovery")
+	const arm64 = "android_recovery_arm64_armv8-a_shared"
+	if len(variants) != 1 || !android.InList(arm64, variants) {
+		t.Errorf("variants of librecovery must be \"%s\" only, but was %#v", arm64, variants)
+	}
+
+	variants = ctx.ModuleVariantsForTests("librecovery32")
+	if android.InList(arm64, variants) {
+		t.Errorf("multilib was set to 32 for librecovery32, but its variants has %s.", arm64)
+	}
+
+	recoveryModule := ctx.ModuleForTests("libHalInRecovery", recoveryVariant).Module().(*Module)
+	if !recoveryModule.Platform() {
+		t.Errorf("recovery variant of libHalInRecovery must not specific to device, soc, or product")
+	}
+}
+
+func TestVersionedStubs(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libFoo",
+			srcs: ["foo.c"],
+			stubs: {
+				symbol_file: "foo.map.txt",
+				versions: ["1", "2", "3"],
+			},
+		}
+
+		cc_library_shared {
+			name: "libBar",
+			srcs: ["bar.c"],
+			shared_libs: ["libFoo#1"],
+		}`)
+
+	variants := ctx.ModuleVariantsForTests("libFoo")
+	expectedVariants := []string{
+		"android_arm64_armv8-a_shared",
+		"android_arm64_armv8-a_shared_1",
+		"android_arm64_armv8-a_shared_2",
+		"android_arm64_armv8-a_shared_3",
+		"android_arm_armv7-a-neon_shared",
+		"android_arm_armv7-a-neon_shared_1",
+		"android_arm_armv7-a-neon_shared_2",
+		"android_arm_armv7-a-neon_shared_3",
+	}
+	variantsMismatch := false
+	if len(variants) != len(expectedVariants) {
+		variantsMismatch = true
+	} else {
+		for _, v := range expectedVariants {
+			if !inList(v, variants) {
+				variantsMismatch = false
+			}
+		}
+	}
+	if variantsMismatch {
+		t.Errorf("variants of libFoo expected:\n")
+		for _, v := range expectedVariants {
+			t.Errorf("%q\n", v)
+		}
+		t.Errorf(", but got:\n")
+		for _, v := range variants {
+			t.Errorf("%q\n", v)
+		}
+	}
+
+	libBarLinkRule := ctx.ModuleForTests("libBar", "android_arm64_armv8-a_shared").Rule("ld")
+	libFlags := libBarLinkRule.Args["libFlags"]
+	libFoo1StubPath := "libFoo/android_arm64_armv8-a_shared_1/libFoo.so"
+	if !strings.Contains(libFlags, libFoo1StubPath) {
+		t.Errorf("%q is not found in %q", libFoo1StubPath, libFlags)
+	}
+
+	libBarCompileRule := ctx.ModuleForTests("libBar", "android_arm64_armv8-a_shared").Rule("cc")
+	cFlags := libBarCompileRule.Args["cFlags"]
+	libFoo1VersioningMacro := "-D__LIBFOO_API__=1"
+	if !strings.Contains(cFlags, libFoo1VersioningMacro) {
+		t.Errorf("%q is not found in %q", libFoo1VersioningMacro, cFlags)
+	}
+}
+
+func TestVersioningMacro(t *testing.T) {
+	for _, tc := range []struct{ moduleName, expected string }{
+		{"libc", "__LIBC_API__"},
+		{"libfoo", "__LIBFOO_API__"},
+		{"libfoo@1", "__LIBFOO_1_API__"},
+		{"libfoo-v1", "__LIBFOO_V1_API__"},
+		{"libfoo.v1", "__LIBFOO_V1_API__"},
+	} {
+		checkEquals(t, tc.moduleName, tc.expected, versioningMacroName(tc.moduleName))
+	}
+}
+
+func TestStaticExecutable(t *testing.T) {
+	ctx := testCc(t, `
+		cc_binary {
+			name: "static_test",
+			srcs: ["foo.c", "baz.o"],
+			static_executable: true,
+		}`)
+
+	variant := "android_arm64_armv8-a"
+	binModuleRule := ctx.ModuleForTests("static_test", variant).Rule("ld")
+	libFlags := binModuleRule.Args["libFlags"]
+	systemStaticLibs := []string{"libc.a", "libm.a"}
+	for _, lib := range systemStaticLibs {
+		if !strings.Contains(libFlags, lib) {
+			t.Errorf("Static lib %q was not found in %q", lib, libFlags)
+		}
+	}
+	systemSharedLibs := []string{"libc.so", "libm.so", "libdl.so"}
+	for _, lib := range systemSharedLibs {
+		if strings.Contains(libFlags, lib) {
+			t.Errorf("Shared lib %q was found in %q", lib, libFlags)
+		}
+	}
+}
+
+func TestLinuxMuslStaticExecutable(t *testing.T) {
+	config := TestConfig(buildDir, android.Android, nil, `
+		cc_binary_host {
+			name: "tool",
+			srcs: ["foo.c"],
+			stl: "none",
+			target: {
+				linux_musl: {
+					enabled: true,
+				},
+			},
+		}`, nil)
+	config.Targets[android.LinuxMusl] = []android.Target{
+		{Os: android.LinuxMusl, Arch: android.Arch{ArchType: android.X86_64}},
+	}
+	ctx := testCcWithConfig(t, config)
+
+	// musl host tools are static executables unless static_executable is set to false.
+	musl := ctx.ModuleForTests("tool", "linux_musl_x86_64").Module().(*Module)
+	if !musl.linker.(*binaryDecorator).static() {
+		t.Errorf("expected the linux_musl variant of tool to be a static executable")
+	}
+
+	glibc := ctx.ModuleForTests("tool", "linux_glibc_x86_64").Module().(*Module)
+	if glibc.linker.(*binaryDecorator).static() {
+		t.Errorf("expected the linux_glibc variant of tool not to be a static executable")
+	}
+}
+
+func TestStaticDepsOrderWithStubs(t *testing.T) {
+	ctx := testCc(t, `
+		cc_binary {
+			name: "mybin",
+			srcs: ["foo.c"],
+			static_libs: ["libfooB"],
+			static_executable: true,
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libfooB",
+			srcs: ["foo.c"],
+			shared_libs: ["libfooC"],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libfooC",
+			srcs: ["foo.c"],
+			stl: "none",
+			stubs: {
+				versions: ["1"],
+			},
+		}`)
+
+	mybin := ctx.ModuleForTests("mybin", "android_arm64_armv8-a").Module().(*Module)
+	actual := mybin.depsInLinkOrder
+	expected := getOutputPaths(ctx, "android_arm64_armv8-a_static", []string{"libfooB", "libfooC"})
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("staticDeps orderings were not propagated correctly"+
+			"\nactual:   %v"+
+			"\nexpected: %v",
+			actual,
+			expected,
+		)
+	}
+}
+
+func TestErrorsIfAModuleDependsOnDisabled(t *testing.T) {
+	testCcError(t, `module "libA" .* depends on disabled module "libB"`, `
+		cc_library {
+			name: "libA",
+			srcs: ["foo.c"],
+			shared_libs: ["libB"],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libB",
+			srcs: ["foo.c"],
+			enabled: false,
+			stl: "none",
+		}
+	`)
 }
 
-var compilerFlagsTestCases = []struct {
-	in  string
-	out bool
-}{
-	{
-		in:  "a",
-		out: false,
-	},
-	{
-		in:  "-a",
-		out: true,
-	},
-	{
-		in:  "-Ipath/to/something",
-		out: false,
-	},
-	{
-		in:  "-isystempath/to/something",
-		out: false,
-	},
-	{
-		in:  "--coverage",
-		out: false,
-	},
-	{
-		in:  "-include a/b",
-		out: true,
-	},
-	{
-		in:  "-include a/b c/d",
-		out: false,
-	},
-	{
-		in:  "-DMACRO",
-		out: true,
-	},
-	{
-		in:  "-DMAC RO",
-		out: false,
-	},
-	{
-		in:  "-a -b",
-		out: false,
-	},
-	{
-		in:  "-DMACRO=definition",
-		out: true,
-	},
-	{
-		in:  "-DMACRO=defi nition",
-		out: true, // TODO(jiyong): this should be false
-	},
-	{
-		in:  "-DMACRO(x)=x + 1",
-		out: true,
-	},
-	{
-		in:  "-DMACRO=\"defi nition\"",
-		out: true,
-	},
+// Simple smoke test for the cc_fuzz target that ensures the rule compiles
+// correctly.
+func TestFuzzTarget(t *testing.T) {
+	ctx := testCc(t, `
+		cc_fuzz {
+			name: "fuzz_smoke_test",
+			srcs: ["foo.c"],
+		}`)
+
+	variant := "android_arm64_armv8-a_fuzzer"
+	ctx.ModuleForTests("fuzz_smoke_test", variant).Rule("cc")
+}
+
+func TestStripKeepSymbolsList(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			strip: {
+				keep_symbols_list: ["foo", "bar"],
+				keep_symbols_list_file: "keep_symbols.txt",
+			},
+		}`)
+
+	strip := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared").Rule("strip")
+	if args := strip.Args["args"]; !strings.Contains(args, "-kfoo,bar -K keep_symbols.txt") {
+		t.Errorf("expected strip to keep the listed symbols, got %q", args)
+	}
+	if !android.InList("keep_symbols.txt", strip.Implicits.Strings()) {
+		t.Errorf("expected strip to depend on keep_symbols.txt, got %q", strip.Implicits.Strings())
+	}
+
+	testCcError(t, `strip.keep_symbols_list_file: cannot be used with strip.keep_symbols`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			strip: {
+				keep_symbols: true,
+				keep_symbols_list_file: "keep_symbols.txt",
+			},
+		}`)
+}
+
+func TestFuzzTargetPackaging(t *testing.T) {
+	bp := `
+		cc_fuzz {
+			name: "fuzz_test",
+			srcs: ["foo.c"],
+			corpus: ["corpus/seed1", "corpus/seed2"],
+			dictionary: "fuzz_test.dict",
+			options: "fuzzer.options",
+		}`
+
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+
+	ctx := CreateTestContext()
+	ctx.RegisterSingletonType("cc_fuzz_packaging", fuzzPackagingFactory)
+	ctx.Register(config)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	fuzz := ctx.ModuleForTests("fuzz_test", "android_arm64_armv8-a_fuzzer")
+	options := fuzz.Output("options/fuzz_test.options")
+	if options.Input.String() != "fuzzer.options" {
+		t.Errorf("expected the options file to be copied from fuzzer.options, got %q", options.Input.String())
+	}
+
+	fuzzZip := filepath.Join(buildDir, ".intermediates/fuzz/target/arm64/fuzz_test.zip")
+	packaging := ctx.SingletonForTests("cc_fuzz_packaging").Output(fuzzZip)
+	for _, f := range []string{"fuzz_test.dict", options.Output.String()} {
+		if !android.InList(f, packaging.Implicits.Strings()) {
+			t.Errorf("expected %q to be packaged, got %q", f, packaging.Implicits.Strings())
+		}
+	}
+
+	testCcError(t, `options: Fuzzer options file "fuzzer.txt" does not have '.options' extension`, `
+		cc_fuzz {
+			name: "fuzz_test",
+			srcs: ["foo.c"],
+			options: "fuzzer.txt",
+		}`)
+
+	testCcError(t, `corpus: Corpus file "b/seed" has the same name as "a/seed"`, `
+		cc_fuzz {
+			name: "fuzz_test",
+			srcs: ["foo.c"],
+			corpus: ["a/seed", "b/seed"],
+		}`)
+}
+
+func TestAidl(t *testing.T) {
+}
+
+func assertString(t *testing.T, got, expected string) {
+	t.Helper()
+	if got != expected {
+		t.Errorf("expected %q got %q", expected, got)
+	}
+}
+
+func assertArrayString(t *testing.T, got, expected []string) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Errorf("expected %d (%q) got (%d) %q", len(expected), expected, len(got), got)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("expected %d-th %q (%q) got %q (%q)",
+				i, expected[i], expected, got[i], got)
+			return
+		}
+	}
+}
+
+func assertMapKeys(t *testing.T, m map[string]string, expected []string) {
+	t.Helper()
+	assertArrayString(t, android.SortedStringKeys(m), expected)
+}
+
+func TestDefaults(t *testing.T) {
+	ctx := testCc(t, `
+		cc_defaults {
+			name: "defaults",
+			srcs: ["foo.c"],
+			static: {
+				srcs: ["bar.c"],
+			},
+			shared: {
+				srcs: ["baz.c"],
+			},
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			defaults: ["defaults"],
+		}
+
+		cc_library_shared {
+			name: "libshared",
+			defaults: ["defaults"],
+		}
+
+		cc_library {
+			name: "libboth",
+			defaults: ["defaults"],
+		}
+
+		cc_binary {
+			name: "binary",
+			defaults: ["defaults"],
+		}`)
+
+	pathsToBase := func(paths android.Paths) []string {
+		var ret []string
+		for _, p := range paths {
+			ret = append(ret, p.Base())
+		}
+		return ret
+	}
+
+	shared := ctx.ModuleForTests("libshared", "android_arm64_armv8-a_shared").Rule("ld")
+	if g, w := pathsToBase(shared.Inputs), []string{"foo.o", "baz.o"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("libshared ld rule wanted %q, got %q", w, g)
+	}
+	bothShared := ctx.ModuleForTests("libboth", "android_arm64_armv8-a_shared").Rule("ld")
+	if g, w := pathsToBase(bothShared.Inputs), []string{"foo.o", "baz.o"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("libboth ld rule wanted %q, got %q", w, g)
+	}
+	binary := ctx.ModuleForTests("binary", "android_arm64_armv8-a").Rule("ld")
+	if g, w := pathsToBase(binary.Inputs), []string{"foo.o"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("binary ld rule wanted %q, got %q", w, g)
+	}
+
+	static := ctx.ModuleForTests("libstatic", "android_arm64_armv8-a_static").Rule("ar")
+	if g, w := pathsToBase(static.Inputs), []string{"foo.o", "bar.o"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("libstatic ar rule wanted %q, got %q", w, g)
+	}
+	bothStatic := ctx.ModuleForTests("libboth", "android_arm64_armv8-a_static").Rule("ar")
+	if g, w := pathsToBase(bothStatic.Inputs), []string{"foo.o", "bar.o"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("libboth ar rule wanted %q, got %q", w, g)
+	}
 }
 
-type mockContext struct {
-	BaseModuleContext
-	result bool
-}
+func TestProductVariableDefaults(t *testing.T) {
+	bp := `
+		cc_defaults {
+			name: "libfoo_defaults",
+			srcs: ["foo.c"],
+			cppflags: ["-DFOO"],
+			product_variables: {
+				debuggable: {
+					cppflags: ["-DBAR"],
+				},
+			},
+		}
+
+		cc_library {
+			name: "libfoo",
+			defaults: ["libfoo_defaults"],
+		}
+	`
+
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.Debuggable = BoolPtr(true)
 
-func (ctx *mockContext) PropertyErrorf(property, format string, args ...interface{}) {
-	// CheckBadCompilerFlags calls this function when the flag should be rejected
-	ctx.result = false
-}
+	ctx := CreateTestContext()
+	ctx.PreDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("variable", android.VariableMutator).Parallel()
+	})
+	ctx.Register(config)
 
-func TestCompilerFlags(t *testing.T) {
-	for _, testCase := range compilerFlagsTestCases {
-		ctx := &mockContext{result: true}
-		CheckBadCompilerFlags(ctx, "", []string{testCase.in})
-		if ctx.result != testCase.out {
-			t.Errorf("incorrect output:")
-			t.Errorf("     input: %#v", testCase.in)
-			t.Errorf("  expected: %#v", testCase.out)
-			t.Errorf("       got: %#v", ctx.result)
-		}
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Module().(*Module)
+	if !android.InList("-DBAR", libfoo.flags.Local.CppFlags) {
+		t.Errorf("expected -DBAR in cppflags, got %q", libfoo.flags.Local.CppFlags)
 	}
 }
 
-func TestVendorPublicLibraries(t *testing.T) {
-	ctx := testCc(t, `
-	cc_library_headers {
-		name: "libvendorpublic_headers",
-		export_include_dirs: ["my_include"],
-	}
-	vendor_public_library {
-		name: "libvendorpublic",
-		symbol_file: "",
-		export_public_headers: ["libvendorpublic_headers"],
-	}
-	cc_library {
-		name: "libvendorpublic",
-		srcs: ["foo.c"],
-		vendor: true,
-		no_libcrt: true,
-		nocrt: true,
-	}
+func TestGlobalThinLTO(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+		}
 
-	cc_library {
-		name: "libsystem",
-		shared_libs: ["libvendorpublic"],
-		vendor: false,
-		srcs: ["foo.c"],
-		no_libcrt: true,
-		nocrt: true,
-	}
-	cc_library {
-		name: "libvendor",
-		shared_libs: ["libvendorpublic"],
-		vendor: true,
-		srcs: ["foo.c"],
-		no_libcrt: true,
-		nocrt: true,
-	}
-	`)
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c"],
+			lto: {
+				never: true,
+			},
+		}
+	`
 
-	coreVariant := "android_arm64_armv8-a_shared"
-	vendorVariant := "android_vendor.VER_arm64_armv8-a_shared"
+	config := TestConfig(buildDir, android.Android, map[string]string{"GLOBAL_THINLTO": "true"}, bp, nil)
+	ctx := testCcWithConfig(t, config)
 
-	// test if header search paths are correctly added
-	// _static variant is used since _shared reuses *.o from the static variant
-	cc := ctx.ModuleForTests("libsystem", strings.Replace(coreVariant, "_shared", "_static", 1)).Rule("cc")
-	cflags := cc.Args["cFlags"]
-	if !strings.Contains(cflags, "-Imy_include") {
-		t.Errorf("cflags for libsystem must contain -Imy_include, but was %#v.", cflags)
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Module().(*Module)
+	if !android.InList("-flto=thin -fsplit-lto-unit", libfoo.flags.Local.CFlags) {
+		t.Errorf("expected libfoo to be built with ThinLTO, got cflags %q", libfoo.flags.Local.CFlags)
 	}
-
-	// test if libsystem is linked to the stub
-	ld := ctx.ModuleForTests("libsystem", coreVariant).Rule("ld")
-	libflags := ld.Args["libFlags"]
-	stubPaths := getOutputPaths(ctx, coreVariant, []string{"libvendorpublic" + vendorPublicLibrarySuffix})
-	if !strings.Contains(libflags, stubPaths[0].String()) {
-		t.Errorf("libflags for libsystem must contain %#v, but was %#v", stubPaths[0], libflags)
+	if !android.InList("${config.ThinLTOGlobalLdflags}", libfoo.flags.Local.LdFlags) {
+		t.Errorf("expected libfoo to be linked with the global ThinLTO flags, got ldflags %q",
+			libfoo.flags.Local.LdFlags)
 	}
 
-	// test if libvendor is linked to the real shared lib
-	ld = ctx.ModuleForTests("libvendor", vendorVariant).Rule("ld")
-	libflags = ld.Args["libFlags"]
-	stubPaths = getOutputPaths(ctx, vendorVariant, []string{"libvendorpublic"})
-	if !strings.Contains(libflags, stubPaths[0].String()) {
-		t.Errorf("libflags for libvendor must contain %#v, but was %#v", stubPaths[0], libflags)
+	libbar := ctx.ModuleForTests("libbar", "android_arm64_armv8-a_shared").Module().(*Module)
+	if android.InList("-flto=thin -fsplit-lto-unit", libbar.flags.Local.CFlags) {
+		t.Errorf("expected libbar to opt out of ThinLTO, got cflags %q", libbar.flags.Local.CFlags)
 	}
-
 }
 
-func TestRecovery(t *testing.T) {
-	ctx := testCc(t, `
-		cc_library_shared {
-			name: "librecovery",
-			recovery: true,
+func TestLtoStaticLibVariants(t *testing.T) {
+	bp := `
+		cc_library_static {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			lto: {
+				thin: true,
+			},
 		}
-		cc_library_shared {
-			name: "librecovery32",
-			recovery: true,
-			compile_multilib:"32",
+
+		cc_binary {
+			name: "native_bin",
+			srcs: ["bin.c"],
+			static_libs: ["libfoo"],
+			lto: {
+				never: true,
+			},
 		}
-		cc_library_shared {
-			name: "libHalInRecovery",
-			recovery_available: true,
-			vendor: true,
+
+		cc_binary {
+			name: "thin_bin",
+			srcs: ["bin.c"],
+			static_libs: ["libfoo"],
+			lto: {
+				thin: true,
+			},
 		}
-	`)
+	`
 
-	variants := ctx.ModuleVariantsForTests("librecovery")
-	const arm64 = "android_recovery_arm64_armv8-a_shared"
-	if len(variants) != 1 || !android.InList(arm64, variants) {
-		t.Errorf("variants of librecovery must be \"%s\" only, but was %#v", arm64, variants)
+	ctx := testCc(t, bp)
+
+	native := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+	if android.InList("-flto=thin -fsplit-lto-unit", native.Module().(*Module).flags.Local.CFlags) {
+		t.Errorf("expected default variant of libfoo to be built without LTO, got cflags %q",
+			native.Module().(*Module).flags.Local.CFlags)
 	}
 
-	variants = ctx.ModuleVariantsForTests("librecovery32")
-	if android.InList(arm64, variants) {
-		t.Errorf("multilib was set to 32 for librecovery32, but its variants has %s.", arm64)
+	thin := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static_lto-thin")
+	if !android.InList("-flto=thin -fsplit-lto-unit", thin.Module().(*Module).flags.Local.CFlags) {
+		t.Errorf("expected lto-thin variant of libfoo to be built with ThinLTO, got cflags %q",
+			thin.Module().(*Module).flags.Local.CFlags)
 	}
 
-	recoveryModule := ctx.ModuleForTests("libHalInRecovery", recoveryVariant).Module().(*Module)
-	if !recoveryModule.Platform() {
-		t.Errorf("recovery variant of libHalInRecovery must not specific to device, soc, or product")
+	nativeLib := native.Rule("ar").Output
+	thinLib := thin.Rule("ar").Output
+
+	nativeLink := ctx.ModuleForTests("native_bin", "android_arm64_armv8-a").Rule("ld")
+	if !android.InList(nativeLib.String(), nativeLink.Implicits.Strings()) {
+		t.Errorf("expected native_bin to link %q, got %q", nativeLib, nativeLink.Implicits)
+	}
+	if android.InList(thinLib.String(), nativeLink.Implicits.Strings()) {
+		t.Errorf("expected native_bin not to link %q", thinLib)
+	}
+
+	thinLink := ctx.ModuleForTests("thin_bin", "android_arm64_armv8-a").Rule("ld")
+	if !android.InList(thinLib.String(), thinLink.Implicits.Strings()) {
+		t.Errorf("expected thin_bin to link %q, got %q", thinLib, thinLink.Implicits)
 	}
 }
 
-func TestVersionedStubs(t *testing.T) {
-	ctx := testCc(t, `
-		cc_library_shared {
-			name: "libFoo",
+func TestProductPgoProfiles(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
 			srcs: ["foo.c"],
-			stubs: {
-				symbol_file: "foo.map.txt",
-				versions: ["1", "2", "3"],
-			},
 		}
 
-		cc_library_shared {
-			name: "libBar",
+		cc_library {
+			name: "libbar",
 			srcs: ["bar.c"],
-			shared_libs: ["libFoo#1"],
-		}`)
+		}
 
-	variants := ctx.ModuleVariantsForTests("libFoo")
-	expectedVariants := []string{
-		"android_arm64_armv8-a_shared",
-		"android_arm64_armv8-a_shared_1",
-		"android_arm64_armv8-a_shared_2",
-		"android_arm64_armv8-a_shared_3",
-		"android_arm_armv7-a-neon_shared",
-		"android_arm_armv7-a-neon_shared_1",
-		"android_arm_armv7-a-neon_shared_2",
-		"android_arm_armv7-a-neon_shared_3",
-	}
-	variantsMismatch := false
-	if len(variants) != len(expectedVariants) {
-		variantsMismatch = true
-	} else {
-		for _, v := range expectedVariants {
-			if !inList(v, variants) {
-				variantsMismatch = false
+		cc_library {
+			name: "libbaz",
+			srcs: ["baz.c"],
+		}
+
+		cc_library {
+			name: "libqux",
+			srcs: ["qux.c"],
+			pgo: {
+				enable_profile_use: false,
+			},
+		}
+	`
+
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"pgo/libfoo.profdata": nil,
+		"afdo/libbar.afdo":    nil,
+		"afdo/libqux.afdo":    nil,
+	})
+	config.TestProductVariables.PgoProfileDir = StringPtr("pgo")
+	config.TestProductVariables.AfdoProfileDir = StringPtr("afdo")
+	ctx := testCcWithConfig(t, config)
+
+	checkProfile := func(module, expectedFlag, expectedDep string) {
+		t.Helper()
+		m := ctx.ModuleForTests(module, "android_arm64_armv8-a_shared").Module().(*Module)
+		if expectedFlag == "" {
+			for _, flag := range m.flags.Local.CFlags {
+				if strings.Contains(flag, "-fprofile") {
+					t.Errorf("expected %s not to use a profile, got cflag %q", module, flag)
+				}
 			}
+			return
 		}
-	}
-	if variantsMismatch {
-		t.Errorf("variants of libFoo expected:\n")
-		for _, v := range expectedVariants {
-			t.Errorf("%q\n", v)
+		if !android.InList(expectedFlag, m.flags.Local.CFlags) {
+			t.Errorf("expected %q in %s cflags, got %q", expectedFlag, module, m.flags.Local.CFlags)
 		}
-		t.Errorf(", but got:\n")
-		for _, v := range variants {
-			t.Errorf("%q\n", v)
+		if !android.InList(expectedDep, m.flags.CFlagsDeps.Strings()) {
+			t.Errorf("expected %q in %s cflags deps, got %q", expectedDep, module, m.flags.CFlagsDeps)
 		}
 	}
 
-	libBarLinkRule := ctx.ModuleForTests("libBar", "android_arm64_armv8-a_shared").Rule("ld")
-	libFlags := libBarLinkRule.Args["libFlags"]
-	libFoo1StubPath := "libFoo/android_arm64_armv8-a_shared_1/libFoo.so"
-	if !strings.Contains(libFlags, libFoo1StubPath) {
-		t.Errorf("%q is not found in %q", libFoo1StubPath, libFlags)
-	}
+	checkProfile("libfoo", "-fprofile-use=pgo/libfoo.profdata", "pgo/libfoo.profdata")
+	checkProfile("libbar", "-fprofile-sample-accurate -fprofile-sample-use=afdo/libbar.afdo", "afdo/libbar.afdo")
+	checkProfile("libbaz", "", "")
+	checkProfile("libqux", "", "")
+}
 
-	libBarCompileRule := ctx.ModuleForTests("libBar", "android_arm64_armv8-a_shared").Rule("cc")
-	cFlags := libBarCompileRule.Args["cFlags"]
-	libFoo1VersioningMacro := "-D__LIBFOO_API__=1"
-	if !strings.Contains(cFlags, libFoo1VersioningMacro) {
-		t.Errorf("%q is not found in %q", libFoo1VersioningMacro, cFlags)
+func TestCcWrapper(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c", "bar.s"],
+		}
+	`
+
+	fs := map[string][]byte{
+		"prebuilts/ccache/ccache": nil,
 	}
-}
 
-func TestVersioningMacro(t *testing.T) {
-	for _, tc := range []struct{ moduleName, expected string }{
-		{"libc", "__LIBC_API__"},
-		{"libfoo", "__LIBFOO_API__"},
-		{"libfoo@1", "__LIBFOO_1_API__"},
-		{"libfoo-v1", "__LIBFOO_V1_API__"},
-		{"libfoo.v1", "__LIBFOO_V1_API__"},
-	} {
-		checkEquals(t, tc.moduleName, tc.expected, versioningMacroName(tc.moduleName))
+	checkWrapper := func(t *testing.T, config android.Config, expected bool) {
+		t.Helper()
+		ctx := testCcWithConfig(t, config)
+		libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+		for _, rule := range []string{"cc", "ccNoDeps"} {
+			params := libfoo.Rule(rule)
+			found := android.InList("prebuilts/ccache/ccache", params.Implicits.Strings())
+			if found != expected {
+				t.Errorf("expected wrapper dependency of %s rule %t, got implicits %q", rule, expected, params.Implicits)
+			}
+		}
 	}
+
+	t.Run("product wrapper", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, fs)
+		config.TestProductVariables.CcWrapper = StringPtr("prebuilts/ccache/ccache")
+		checkWrapper(t, config, true)
+	})
+
+	t.Run("remote build", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, fs)
+		config.TestProductVariables.CcWrapper = StringPtr("prebuilts/ccache/ccache")
+		config.TestProductVariables.UseGoma = BoolPtr(true)
+		checkWrapper(t, config, false)
+	})
+
+	t.Run("environment override", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, map[string]string{"CC_WRAPPER": "ccache"}, bp, fs)
+		config.TestProductVariables.CcWrapper = StringPtr("prebuilts/ccache/ccache")
+		checkWrapper(t, config, false)
+	})
 }
 
-func TestStaticExecutable(t *testing.T) {
-	ctx := testCc(t, `
-		cc_binary {
-			name: "static_test",
-			srcs: ["foo.c", "baz.o"],
-			static_executable: true,
-		}`)
+func TestStackProtector(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+		}
 
-	variant := "android_arm64_armv8-a"
-	binModuleRule := ctx.ModuleForTests("static_test", variant).Rule("ld")
-	libFlags := binModuleRule.Args["libFlags"]
-	systemStaticLibs := []string{"libc.a", "libm.a"}
-	for _, lib := range systemStaticLibs {
-		if !strings.Contains(libFlags, lib) {
-			t.Errorf("Static lib %q was not found in %q", lib, libFlags)
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c"],
+			stack_protector: "basic",
+		}
+	`
+
+	checkFlag := func(t *testing.T, ctx *android.TestContext, name, expected string) {
+		t.Helper()
+		module := ctx.ModuleForTests(name, "android_arm64_armv8-a_shared").Module().(*Module)
+		var actual []string
+		for _, flag := range module.flags.Global.CommonFlags {
+			if strings.HasPrefix(flag, "-fstack-protector") {
+				actual = append(actual, flag)
+			}
 		}
-	}
-	systemSharedLibs := []string{"libc.so", "libm.so", "libdl.so"}
-	for _, lib := range systemSharedLibs {
-		if strings.Contains(libFlags, lib) {
-			t.Errorf("Shared lib %q was found in %q", lib, libFlags)
+		var expectedFlags []string
+		if expected != "" {
+			expectedFlags = []string{expected}
 		}
+		checkEquals(t, "stack protector flags of "+name, expectedFlags, actual)
 	}
+
+	t.Run("default", func(t *testing.T) {
+		// The default -fstack-protector-strong comes from the device global cflags.
+		ctx := testCcWithConfig(t, TestConfig(buildDir, android.Android, nil, bp, nil))
+		checkFlag(t, ctx, "libfoo", "")
+		checkFlag(t, ctx, "libbar", "-fstack-protector")
+	})
+
+	t.Run("product", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, nil)
+		config.TestProductVariables.StackProtector = StringPtr("all")
+		ctx := testCcWithConfig(t, config)
+		checkFlag(t, ctx, "libfoo", "-fstack-protector-all")
+		checkFlag(t, ctx, "libbar", "-fstack-protector")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		testCcError(t, `stack_protector: unknown stack protector strength "none"`, `
+			cc_library {
+				name: "libbaz",
+				srcs: ["baz.c"],
+				stack_protector: "none",
+			}
+		`)
+	})
 }
 
-func TestStaticDepsOrderWithStubs(t *testing.T) {
-	ctx := testCc(t, `
+func TestCompilerRtArches(t *testing.T) {
+	bp := `
 		cc_binary {
-			name: "mybin",
+			name: "bin",
 			srcs: ["foo.c"],
-			static_libs: ["libfooB"],
 			static_executable: true,
-			stl: "none",
 		}
 
-		cc_library {
-			name: "libfooB",
+		cc_library_shared {
+			name: "libndk",
 			srcs: ["foo.c"],
-			shared_libs: ["libfooC"],
-			stl: "none",
+			sdk_version: "current",
+			stl: "c++_shared",
 		}
 
-		cc_library {
-			name: "libfooC",
+		cc_binary_host {
+			name: "host_bin",
 			srcs: ["foo.c"],
-			stl: "none",
-			stubs: {
-				versions: ["1"],
-			},
-		}`)
+		}
+	`
 
-	mybin := ctx.ModuleForTests("mybin", "android_arm64_armv8-a").Module().(*Module)
-	actual := mybin.depsInLinkOrder
-	expected := getOutputPaths(ctx, "android_arm64_armv8-a_static", []string{"libfooB", "libfooC"})
+	checkStaticLibs := func(t *testing.T, ctx *android.TestContext, name, variant, expected, unexpected string) {
+		t.Helper()
+		module := ctx.ModuleForTests(name, variant).Module().(*Module)
+		staticLibs := module.Properties.AndroidMkStaticLibs
+		if !inList(expected, staticLibs) {
+			t.Errorf("expected %q in static libs of %s, got %q", expected, name, staticLibs)
+		}
+		if inList(unexpected, staticLibs) {
+			t.Errorf("unexpected %q in static libs of %s %q", unexpected, name, staticLibs)
+		}
+	}
 
-	if !reflect.DeepEqual(actual, expected) {
-		t.Errorf("staticDeps orderings were not propagated correctly"+
-			"\nactual:   %v"+
-			"\nexpected: %v",
-			actual,
-			expected,
-		)
+	hostLdFlags := func(ctx *android.TestContext) string {
+		return ctx.ModuleForTests("host_bin", android.BuildOs.String()+"_x86_64").Rule("ld").Args["ldFlags"]
 	}
-}
 
-func TestErrorsIfAModuleDependsOnDisabled(t *testing.T) {
-	testCcError(t, `module "libA" .* depends on disabled module "libB"`, `
-		cc_library {
-			name: "libA",
-			srcs: ["foo.c"],
-			shared_libs: ["libB"],
-			stl: "none",
+	t.Run("libgcc", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, nil)
+		ctx := testCcWithConfig(t, config)
+		checkStaticLibs(t, ctx, "bin", "android_arm64_armv8-a", "libgcc_stripped", "libunwind_llvm")
+		checkStaticLibs(t, ctx, "libndk", "android_arm64_armv8-a_sdk_shared", "libgcc_stripped", "ndk_libunwind")
+		checkStaticLibs(t, ctx, "libndk", "android_arm_armv7-a-neon_sdk_shared", "ndk_libunwind", "libgcc_stripped")
+		if ldFlags := hostLdFlags(ctx); strings.Contains(ldFlags, "-rtlib=compiler-rt") {
+			t.Errorf("unexpected -rtlib=compiler-rt in host ldflags %q", ldFlags)
 		}
+	})
 
-		cc_library {
-			name: "libB",
-			srcs: ["foo.c"],
-			enabled: false,
-			stl: "none",
+	t.Run("compiler-rt", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, nil)
+		config.TestProductVariables.CompilerRtArches = []string{"arm64", "x86_64"}
+		ctx := testCcWithConfig(t, config)
+		checkStaticLibs(t, ctx, "bin", "android_arm64_armv8-a", "libunwind_llvm", "libgcc_stripped")
+		// The NDK only provides the LLVM unwinder for arm.
+		checkStaticLibs(t, ctx, "libndk", "android_arm64_armv8-a_sdk_shared", "libgcc_stripped", "ndk_libunwind")
+		checkStaticLibs(t, ctx, "libndk", "android_arm_armv7-a-neon_sdk_shared", "ndk_libunwind", "libgcc_stripped")
+		// Device arches don't affect the host runtime.
+		if ldFlags := hostLdFlags(ctx); strings.Contains(ldFlags, "-rtlib=compiler-rt") {
+			t.Errorf("unexpected -rtlib=compiler-rt in host ldflags %q", ldFlags)
 		}
-	`)
+	})
+
+	t.Run("host compiler-rt", func(t *testing.T) {
+		config := TestConfig(buildDir, android.Android, nil, bp, nil)
+		config.TestProductVariables.HostCompilerRtArches = []string{"x86_64"}
+		ctx := testCcWithConfig(t, config)
+		checkStaticLibs(t, ctx, "bin", "android_arm64_armv8-a", "libgcc_stripped", "libunwind_llvm")
+		if ldFlags := hostLdFlags(ctx); !strings.Contains(ldFlags, "-rtlib=compiler-rt") {
+			t.Errorf("expected -rtlib=compiler-rt in host ldflags %q", ldFlags)
+		}
+	})
 }
 
-// Simple smoke test for the cc_fuzz target that ensures the rule compiles
-// correctly.
-func TestFuzzTarget(t *testing.T) {
-	ctx := testCc(t, `
-		cc_fuzz {
-			name: "fuzz_smoke_test",
+func TestClangUnknownFlagsTable(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
 			srcs: ["foo.c"],
-		}`)
+			cflags: ["-fno-foo", "-Wno-psabi"],
+		}
+	`
 
-	variant := "android_arm64_armv8-a_fuzzer"
-	ctx.ModuleForTests("fuzz_smoke_test", variant).Rule("cc")
-}
+	table := filepath.Join(config.ClangDefaultBase, "linux-x86", config.ClangDefaultVersion,
+		"clang_unknown_flags.json")
+	fs := map[string][]byte{
+		table: []byte(`{"Cflags": ["-fno-foo"]}`),
+	}
 
-func TestAidl(t *testing.T) {
-}
+	testConfig := TestConfig(buildDir, android.Android, nil, bp, fs)
+	ctx := testCcWithConfig(t, testConfig)
 
-func assertString(t *testing.T, got, expected string) {
-	t.Helper()
-	if got != expected {
-		t.Errorf("expected %q got %q", expected, got)
+	module := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Module().(*Module)
+	if inList("-fno-foo", module.flags.Local.CFlags) {
+		t.Errorf("expected -fno-foo to be filtered out, got %q", module.flags.Local.CFlags)
+	}
+	// The table replaces the built-in list, so flags it doesn't mention are kept.
+	if !inList("-Wno-psabi", module.flags.Local.CFlags) {
+		t.Errorf("expected -Wno-psabi to be kept, got %q", module.flags.Local.CFlags)
 	}
-}
 
-func assertArrayString(t *testing.T, got, expected []string) {
-	t.Helper()
-	if len(got) != len(expected) {
-		t.Errorf("expected %d (%q) got (%d) %q", len(expected), expected, len(got), got)
-		return
+	var dropped []string
+	getNamedMapForConfig(testConfig, modulesDroppingUnknownFlagsKey).Range(func(key, value interface{}) bool {
+		dropped = append(dropped, key.(string))
+		return true
+	})
+	if len(dropped) != 1 || !strings.HasSuffix(dropped[0], "Android.bp:libfoo:-fno-foo") {
+		t.Errorf("expected libfoo to be reported as dropping -fno-foo, got %q", dropped)
 	}
-	for i := range got {
-		if got[i] != expected[i] {
-			t.Errorf("expected %d-th %q (%q) got %q (%q)",
-				i, expected[i], expected, got[i], got)
-			return
+}
+
+func TestVendorAvailableAbiDump(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			vendor_available: true,
+			srcs: ["foo.c"],
+			export_include_dirs: ["include"],
+			no_libcrt: true,
+			nocrt: true,
+			system_shared_libs: [],
+			stl: "none",
 		}
+	`
+	refDump := "prebuilts/abi-dumps/vendor/VER/64/arm_armv7-a-neon/source-based/libfoo.so.lsdump"
+	lockFile := "prebuilts/abi-dumps/vendor/VER/abi.lock"
+	vendorVariant := "android_vendor.VER_arm_armv7-a-neon_shared"
+
+	testConfig := func(fs map[string][]byte) android.Config {
+		config := TestConfig(buildDir, android.Android, nil, bp, fs)
+		config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+		config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+		return config
 	}
-}
 
-func assertMapKeys(t *testing.T, m map[string]string, expected []string) {
-	t.Helper()
-	assertArrayString(t, android.SortedStringKeys(m), expected)
+	// The vendor variant is dumped, and differences against the reference dump are advice only
+	// until the ABI is locked.
+	ctx := testCcWithConfig(t, testConfig(map[string][]byte{refDump: nil}))
+	libfoo := ctx.ModuleForTests("libfoo", vendorVariant)
+	libfoo.Output("libfoo.so.lsdump")
+	if flags := libfoo.Output("libfoo.so.abidiff").Args["allowFlags"]; !strings.Contains(flags, "-advice-only") {
+		t.Errorf("expected -advice-only in the allow flags of an unlocked library, got %q", flags)
+	}
+
+	// The core variant is not dumped.
+	coreVariant := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared")
+	if output := coreVariant.MaybeOutput("libfoo.so.lsdump"); output.Rule != nil {
+		t.Errorf("expected no ABI dump of the core variant")
+	}
+
+	ctx = testCcWithConfig(t, testConfig(map[string][]byte{
+		refDump:  nil,
+		lockFile: []byte("# Locked libraries\nlibfoo\n"),
+	}))
+	libfoo = ctx.ModuleForTests("libfoo", vendorVariant)
+	if flags := libfoo.Output("libfoo.so.abidiff").Args["allowFlags"]; strings.Contains(flags, "-advice-only") {
+		t.Errorf("expected no -advice-only in the allow flags of a locked library, got %q", flags)
+	}
+
+	testCcErrorWithConfig(t, `ABI is locked in .*abi.lock, but there is no reference ABI dump for libfoo.so`,
+		testConfig(map[string][]byte{
+			lockFile: []byte("libfoo\n"),
+		}))
 }
 
-func TestDefaults(t *testing.T) {
-	ctx := testCc(t, `
-		cc_defaults {
-			name: "defaults",
+func TestLlndkAbiDump(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libllndk",
 			srcs: ["foo.c"],
-			static: {
-				srcs: ["bar.c"],
-			},
-			shared: {
-				srcs: ["baz.c"],
-			},
-		}
-
-		cc_library_static {
-			name: "libstatic",
-			defaults: ["defaults"],
+			export_include_dirs: ["include"],
 		}
-
-		cc_library_shared {
-			name: "libshared",
-			defaults: ["defaults"],
+		llndk_library {
+			name: "libllndk",
+			symbol_file: "",
+			vendor_available: true,
 		}
-
 		cc_library {
-			name: "libboth",
-			defaults: ["defaults"],
+			name: "libllndkprivate",
+			srcs: ["foo.c"],
+			export_include_dirs: ["include"],
 		}
-
-		cc_binary {
-			name: "binary",
-			defaults: ["defaults"],
-		}`)
-
-	pathsToBase := func(paths android.Paths) []string {
-		var ret []string
-		for _, p := range paths {
-			ret = append(ret, p.Base())
+		llndk_library {
+			name: "libllndkprivate",
+			symbol_file: "",
 		}
-		return ret
-	}
-
-	shared := ctx.ModuleForTests("libshared", "android_arm64_armv8-a_shared").Rule("ld")
-	if g, w := pathsToBase(shared.Inputs), []string{"foo.o", "baz.o"}; !reflect.DeepEqual(w, g) {
-		t.Errorf("libshared ld rule wanted %q, got %q", w, g)
-	}
-	bothShared := ctx.ModuleForTests("libboth", "android_arm64_armv8-a_shared").Rule("ld")
-	if g, w := pathsToBase(bothShared.Inputs), []string{"foo.o", "baz.o"}; !reflect.DeepEqual(w, g) {
-		t.Errorf("libboth ld rule wanted %q, got %q", w, g)
-	}
-	binary := ctx.ModuleForTests("binary", "android_arm64_armv8-a").Rule("ld")
-	if g, w := pathsToBase(binary.Inputs), []string{"foo.o"}; !reflect.DeepEqual(w, g) {
-		t.Errorf("binary ld rule wanted %q, got %q", w, g)
-	}
+	`
+	refDumpDir := "prebuilts/abi-dumps/vndk/VER/64/arm_armv7-a-neon/source-based/"
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		refDumpDir + "libllndk.so.lsdump":        nil,
+		refDumpDir + "libllndkprivate.so.lsdump": nil,
+	})
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
 
-	static := ctx.ModuleForTests("libstatic", "android_arm64_armv8-a_static").Rule("ar")
-	if g, w := pathsToBase(static.Inputs), []string{"foo.o", "bar.o"}; !reflect.DeepEqual(w, g) {
-		t.Errorf("libstatic ar rule wanted %q, got %q", w, g)
-	}
-	bothStatic := ctx.ModuleForTests("libboth", "android_arm64_armv8-a_static").Rule("ar")
-	if g, w := pathsToBase(bothStatic.Inputs), []string{"foo.o", "bar.o"}; !reflect.DeepEqual(w, g) {
-		t.Errorf("libboth ar rule wanted %q, got %q", w, g)
+	for _, name := range []string{"libllndk", "libllndkprivate"} {
+		impl := ctx.ModuleForTests(name, "android_arm_armv7-a-neon_shared")
+		abiDump := impl.Output(name + ".so.lsdump").Output
+		abiDiff := impl.Output(name + ".so.abidiff").Output
+
+		// The stub depends on the ABI checks of the implementation, so that an ABI break
+		// fails the build of the vendor modules linking against it.
+		stub := ctx.ModuleForTests(name+llndkLibrarySuffix, "android_vendor.VER_arm_armv7-a-neon_shared")
+		implicits := stub.Rule("ld").Implicits.Strings()
+		for _, check := range []android.Path{abiDump, abiDiff} {
+			if !android.InList(check.String(), implicits) {
+				t.Errorf("expected %s in the link dependencies of %s, got %q", check, name, implicits)
+			}
+		}
 	}
 }
 
-func TestProductVariableDefaults(t *testing.T) {
+func TestDuplicateWholeStaticLibs(t *testing.T) {
 	bp := `
-		cc_defaults {
-			name: "libfoo_defaults",
+		cc_library_static {
+			name: "libc_common",
+			srcs: ["c.c"],
+		}
+
+		cc_library_static {
+			name: "liba",
+			srcs: ["a.c"],
+			whole_static_libs: ["libc_common"],
+		}
+
+		cc_library_static {
+			name: "libb",
+			srcs: ["b.c"],
+			whole_static_libs: ["liba"],
+		}
+	`
+
+	testCc(t, bp+`
+		cc_library_shared {
+			name: "libfoo",
 			srcs: ["foo.c"],
-			cppflags: ["-DFOO"],
-			product_variables: {
-				debuggable: {
-					cppflags: ["-DBAR"],
-				},
-			},
+			whole_static_libs: ["libb"],
 		}
+	`)
 
-		cc_library {
+	testCcError(t, `the objects of "libc_common" are included more than once through whole_static_libs, `+
+		`via liba -> libc_common and via libb -> liba -> libc_common`, bp+`
+		cc_library_shared {
 			name: "libfoo",
-			defaults: ["libfoo_defaults"],
+			srcs: ["foo.c"],
+			whole_static_libs: ["liba", "libb"],
 		}
-	`
+	`)
+}
 
-	config := TestConfig(buildDir, android.Android, nil, bp, nil)
-	config.TestProductVariables.Debuggable = BoolPtr(true)
+func TestHashStyleLdflags(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libold",
+			sdk_version: "21",
+			stl: "none",
+			system_shared_libs: [],
+			nocrt: true,
+		}
 
-	ctx := CreateTestContext()
-	ctx.PreDepsMutators(func(ctx android.RegisterMutatorsContext) {
-		ctx.BottomUp("variable", android.VariableMutator).Parallel()
-	})
-	ctx.Register(config)
+		cc_library_shared {
+			name: "libcurrent",
+			sdk_version: "current",
+			stl: "none",
+			system_shared_libs: [],
+			nocrt: true,
+		}
+	`)
 
-	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
-	android.FailIfErrored(t, errs)
-	_, errs = ctx.PrepareBuildActions(config)
-	android.FailIfErrored(t, errs)
+	checkHashStyle := func(t *testing.T, module, variant string, expected bool) {
+		t.Helper()
+		ldFlags := ctx.ModuleForTests(module, variant).Rule("ld").Args["ldFlags"]
+		if found := strings.Contains(ldFlags, "-Wl,--hash-style=both"); found != expected {
+			t.Errorf("expected -Wl,--hash-style=both in the ldflags of %s %s to be %t, got %q",
+				module, variant, expected, ldFlags)
+		}
+	}
 
-	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static").Module().(*Module)
-	if !android.InList("-DBAR", libfoo.flags.Local.CppFlags) {
-		t.Errorf("expected -DBAR in cppflags, got %q", libfoo.flags.Local.CppFlags)
+	for _, variant := range []string{"android_arm64_armv8-a", "android_arm_armv7-a-neon"} {
+		t.Run(variant, func(t *testing.T) {
+			// The platform variants always use the gnu hash style.
+			checkHashStyle(t, "libold", variant+"_shared", false)
+			// SDK variants that can run on API levels without gnu hash support use both.
+			checkHashStyle(t, "libold", variant+"_sdk_shared", true)
+			checkHashStyle(t, "libcurrent", variant+"_sdk_shared", false)
+		})
 	}
 }
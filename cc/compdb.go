@@ -19,6 +19,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"android/soong/android"
@@ -30,6 +31,11 @@ import (
 // at ${OUT_DIR}/soong/development/ide/compdb/compile_commands.json. It will also symlink it
 // to ${SOONG_LINK_COMPDB_TO} if set. In general this should be created by running
 // make SOONG_GEN_COMPDB=1 nothing to get all targets.
+//
+// By default there is one entry for each source file, using the flags of the first variant of a
+// module that compiles it. SOONG_COMPDB_ALL_VARIANTS=1 creates an entry for each variant instead,
+// and SOONG_COMPDB_MODULE_DIRS can be set to a space separated list of directories to only
+// include the modules in those directories and their subdirectories.
 
 func init() {
 	android.RegisterSingletonType("compdb_generator", compDBGeneratorSingleton)
@@ -49,6 +55,8 @@ const (
 	envVariableGenerateCompdb          = "SOONG_GEN_COMPDB"
 	envVariableGenerateCompdbDebugInfo = "SOONG_GEN_COMPDB_DEBUG"
 	envVariableCompdbLink              = "SOONG_LINK_COMPDB_TO"
+	envVariableCompdbAllVariants       = "SOONG_COMPDB_ALL_VARIANTS"
+	envVariableCompdbModuleDirs        = "SOONG_COMPDB_MODULE_DIRS"
 )
 
 // A compdb entry. The compile_commands.json file is a list of these.
@@ -67,12 +75,19 @@ func (c *compdbGeneratorSingleton) GenerateBuildActions(ctx android.SingletonCon
 	// Instruct the generator to indent the json file for easier debugging.
 	outputCompdbDebugInfo := ctx.Config().IsEnvTrue(envVariableGenerateCompdbDebugInfo)
 
-	// We only want one entry per file. We don't care what module/isa it's from
-	m := make(map[string]compDbEntry)
+	builds := &compdbBuilds{
+		allVariants: ctx.Config().IsEnvTrue(envVariableCompdbAllVariants),
+		seen:        make(map[string]bool),
+	}
+	moduleDirs := strings.Fields(ctx.Config().Getenv(envVariableCompdbModuleDirs))
+
 	ctx.VisitAllModules(func(module android.Module) {
 		if ccModule, ok := module.(*Module); ok {
+			if !compdbIncludesDir(moduleDirs, ctx.ModuleDir(module)) {
+				return
+			}
 			if compiledModule, ok := ccModule.compiler.(CompiledInterface); ok {
-				generateCompdbProject(compiledModule, ctx, ccModule, m)
+				generateCompdbProject(compiledModule, ctx, ccModule, builds)
 			}
 		}
 	})
@@ -87,11 +102,9 @@ func (c *compdbGeneratorSingleton) GenerateBuildActions(ctx android.SingletonCon
 	}
 	defer f.Close()
 
-	v := make([]compDbEntry, 0, len(m))
+	v := append(make([]compDbEntry, 0, len(builds.entries)), builds.entries...)
+	sort.SliceStable(v, func(i, j int) bool { return v[i].File < v[j].File })
 
-	for _, value := range m {
-		v = append(v, value)
-	}
 	var dat []byte
 	if outputCompdbDebugInfo {
 		dat, err = json.MarshalIndent(v, "", " ")
@@ -168,7 +181,41 @@ func getArguments(src android.Path, ctx android.SingletonContext, ccModule *Modu
 	return args
 }
 
-func generateCompdbProject(compiledModule CompiledInterface, ctx android.SingletonContext, ccModule *Module, builds map[string]compDbEntry) {
+// compdbBuilds collects the entries of the compile_commands.json file.
+type compdbBuilds struct {
+	// allVariants adds an entry for each variant that compiles a file instead of only the first.
+	allVariants bool
+
+	entries []compDbEntry
+	seen    map[string]bool
+}
+
+// wants returns true if an entry for the file should be added.
+func (b *compdbBuilds) wants(file string) bool {
+	return b.allVariants || !b.seen[file]
+}
+
+func (b *compdbBuilds) add(entry compDbEntry) {
+	b.seen[entry.File] = true
+	b.entries = append(b.entries, entry)
+}
+
+// compdbIncludesDir returns true if modules in dir should be included in the compile_commands.json
+// file, which is when dirs is empty or dir is one of dirs or a subdirectory of one of them.
+func compdbIncludesDir(dirs []string, dir string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+	for _, d := range dirs {
+		d = filepath.Clean(d)
+		if dir == d || strings.HasPrefix(dir, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func generateCompdbProject(compiledModule CompiledInterface, ctx android.SingletonContext, ccModule *Module, builds *compdbBuilds) {
 	srcs := compiledModule.Srcs()
 	if len(srcs) == 0 {
 		return
@@ -182,13 +229,14 @@ func generateCompdbProject(compiledModule CompiledInterface, ctx android.Singlet
 		cxxPath = filepath.Join(pathToCC, "clang++")
 	}
 	for _, src := range srcs {
-		if _, ok := builds[src.String()]; !ok {
-			builds[src.String()] = compDbEntry{
-				Directory: android.AbsSrcDirForExistingUseCases(),
-				Arguments: getArguments(src, ctx, ccModule, ccPath, cxxPath),
-				File:      src.String(),
-			}
+		if !builds.wants(src.String()) {
+			continue
 		}
+		builds.add(compDbEntry{
+			Directory: android.AbsSrcDirForExistingUseCases(),
+			Arguments: getArguments(src, ctx, ccModule, ccPath, cxxPath),
+			File:      src.String(),
+		})
 	}
 }
 
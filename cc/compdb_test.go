@@ -0,0 +1,58 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+)
+
+func TestCompdbIncludesDir(t *testing.T) {
+	testCases := []struct {
+		dirs     []string
+		dir      string
+		expected bool
+	}{
+		{nil, "system/core", true},
+		{[]string{"system/core"}, "system/core", true},
+		{[]string{"system/core/"}, "system/core/libcutils", true},
+		{[]string{"system/core"}, "system/core_extra", false},
+		{[]string{"frameworks/av", "system/core"}, "frameworks/native", false},
+	}
+
+	for _, tc := range testCases {
+		if actual := compdbIncludesDir(tc.dirs, tc.dir); actual != tc.expected {
+			t.Errorf("compdbIncludesDir(%q, %q): expected %v, got %v", tc.dirs, tc.dir, tc.expected, actual)
+		}
+	}
+}
+
+func TestCompdbBuilds(t *testing.T) {
+	for _, allVariants := range []bool{false, true} {
+		builds := &compdbBuilds{allVariants: allVariants, seen: make(map[string]bool)}
+		for _, file := range []string{"a.cpp", "b.cpp", "a.cpp"} {
+			if builds.wants(file) {
+				builds.add(compDbEntry{File: file})
+			}
+		}
+
+		expected := 2
+		if allVariants {
+			expected = 3
+		}
+		if len(builds.entries) != expected {
+			t.Errorf("allVariants %v: expected %d entries, got %v", allVariants, expected, builds.entries)
+		}
+	}
+}
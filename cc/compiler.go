@@ -25,6 +25,7 @@ import (
 
 	"android/soong/android"
 	"android/soong/cc/config"
+	"android/soong/genrule"
 )
 
 var (
@@ -67,6 +68,11 @@ type BaseCompilerProperties struct {
 	// module.
 	Instruction_set *string `android:"arch_variant"`
 
+	// the stack protector strength to compile the C/C++ module with, one of "basic",
+	// "strong" or "all".  Defaults to the strength selected by the product for device
+	// modules, and to the toolchain's default for host modules.
+	Stack_protector *string `android:"arch_variant"`
+
 	// list of directories relative to the root of the source tree that will
 	// be added to the include path using -I.
 	// If possible, don't use this.  If adding paths from the current directory use
@@ -90,6 +96,13 @@ type BaseCompilerProperties struct {
 	// of genrule modules.
 	Generated_headers []string `android:"arch_variant"`
 
+	// list of the export_headers of the modules in generated_headers that this module uses, as
+	// they are spelled in #include directives.  Each header must be listed in the export_headers
+	// of one of the generated_headers modules, and each generated_headers module that lists
+	// export_headers must export one of them.  Each header must also be included directly by
+	// one of the srcs, which is checked before they are compiled.
+	Generated_header_exports []string `android:"arch_variant"`
+
 	// pass -frtti instead of -fno-rtti
 	Rtti *bool
 
@@ -106,7 +119,8 @@ type BaseCompilerProperties struct {
 	// if set to false, use -std=c++* instead of -std=gnu++*
 	Gnu_extensions *bool
 
-	Yacc *YaccProperties
+	Yacc *YaccProperties `android:"arch_variant"`
+	Lex  *LexProperties  `android:"arch_variant"`
 
 	Aidl struct {
 		// list of directories that will be added to the aidl include paths.
@@ -118,6 +132,27 @@ type BaseCompilerProperties struct {
 
 		// whether to generate traces (for systrace) for this interface
 		Generate_traces *bool
+
+		// list of .aidl files, or modules that produce .aidl files such as filegroups, that are
+		// imported by the aidl sources.  They are not compiled, the directories that their
+		// package paths are relative to are added to the aidl include paths.
+		Imports []string `android:"path"`
+
+		// whether the aidl sources only contain structured parcelables and interfaces.
+		Structured *bool
+
+		// the stability of the interfaces in the aidl sources.  The only supported value is
+		// "vintf", which requires structured to be true.
+		Stability *string
+
+		// the frozen version of a versioned aidl interface to generate code for.  The .aidl
+		// sources are read from <api_dir>/<version>/ instead, at the same package paths, and the
+		// version is returned by the generated getInterfaceVersion().
+		Version *string
+
+		// directory relative to the Blueprints file that contains a directory for each frozen
+		// version of the aidl sources.  Defaults to aidl_api/<module name>.
+		Api_dir *string
 	}
 
 	Renderscript struct {
@@ -177,6 +212,24 @@ type BaseCompilerProperties struct {
 	// Build and link with OpenMP
 	Openmp *bool `android:"arch_variant"`
 
+	Unity struct {
+		// whether to compile the C and C++ sources of the module in unity translation units that
+		// each #include several sources.  Intended to speed up local builds of large modules.
+		Enabled *bool
+
+		// maximum number of sources included in each unity translation unit.  Defaults to 16.
+		Max_srcs *int64
+
+		// list of source files that are compiled separately instead of in a unity translation
+		// unit, for example because they can't be merged with the other sources.
+		Exclude_srcs []string `android:"path"`
+	}
+
+	// Compile with Clang modules, using the module maps exported by dependencies with
+	// export_module_map.  The built Clang modules are cached in a directory of the module, which
+	// its compiles update one at a time.
+	Clang_modules *bool `android:"arch_variant"`
+
 	// Adds __ANDROID_APEX_<APEX_MODULE_NAME>__ macro defined for apex variants in addition to __ANDROID_APEX__
 	Use_apex_name_macro *bool
 }
@@ -192,6 +245,9 @@ type baseCompiler struct {
 	pathDeps   android.Paths
 	flags      builderFlags
 
+	// Headers generated from the sources, for example from .aidl or .proto files
+	generatedSourcesHeaders android.Paths
+
 	// Sources that were passed to the C/C++ compiler
 	srcs android.Paths
 
@@ -229,7 +285,7 @@ func (compiler *baseCompiler) compilerDeps(ctx DepsContext, deps Deps) Deps {
 	deps.GeneratedSources = append(deps.GeneratedSources, compiler.Properties.Generated_sources...)
 	deps.GeneratedHeaders = append(deps.GeneratedHeaders, compiler.Properties.Generated_headers...)
 
-	android.ProtoDeps(ctx, &compiler.Proto)
+	protoPluginDeps(ctx, &compiler.Proto)
 	if compiler.hasSrcExt(".proto") {
 		deps = protoDeps(ctx, deps, &compiler.Proto, Bool(compiler.Properties.Proto.Static))
 	}
@@ -276,6 +332,7 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	flags.Local.YasmFlags = append(flags.Local.YasmFlags, esc(compiler.Properties.Asflags)...)
 
 	flags.Yacc = compiler.Properties.Yacc
+	flags.Lex = compiler.Properties.Lex
 
 	// Include dir cflags
 	localIncludeDirs := android.PathsForModuleSrc(ctx, compiler.Properties.Local_include_dirs)
@@ -350,12 +407,24 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	CheckBadCompilerFlags(ctx, "clang_cflags", compiler.Properties.Clang_cflags)
 	CheckBadCompilerFlags(ctx, "clang_asflags", compiler.Properties.Clang_asflags)
 
-	flags.Local.CFlags = config.ClangFilterUnknownCflags(flags.Local.CFlags)
+	// An error loading the table is reported once by the make vars provider.
+	unknownFlags, _ := config.ClangUnknownFlagsFor(ctx)
+	var dropped, droppedCpp, droppedConly, droppedLd []string
+	flags.Local.CFlags, dropped = unknownFlags.FilterCflags(flags.Local.CFlags)
 	flags.Local.CFlags = append(flags.Local.CFlags, esc(compiler.Properties.Clang_cflags)...)
 	flags.Local.AsFlags = append(flags.Local.AsFlags, esc(compiler.Properties.Clang_asflags)...)
-	flags.Local.CppFlags = config.ClangFilterUnknownCflags(flags.Local.CppFlags)
-	flags.Local.ConlyFlags = config.ClangFilterUnknownCflags(flags.Local.ConlyFlags)
-	flags.Local.LdFlags = config.ClangFilterUnknownCflags(flags.Local.LdFlags)
+	flags.Local.CppFlags, droppedCpp = unknownFlags.FilterCflags(flags.Local.CppFlags)
+	flags.Local.ConlyFlags, droppedConly = unknownFlags.FilterCflags(flags.Local.ConlyFlags)
+	flags.Local.LdFlags, droppedLd = unknownFlags.FilterCflags(flags.Local.LdFlags)
+
+	// Report the flags clang doesn't accept so that they can be removed from the module.
+	module := ctx.BlueprintsFile() + ":" + ctx.ModuleName()
+	dropped = append(dropped, droppedCpp...)
+	dropped = append(dropped, droppedConly...)
+	dropped = append(dropped, droppedLd...)
+	for _, flag := range android.FirstUniqueStrings(dropped) {
+		addToModuleList(ctx, modulesDroppingUnknownFlagsKey, module+":"+flag)
+	}
 
 	target := "-target " + tc.ClangTriple()
 	if ctx.Os().Class == android.Device {
@@ -393,6 +462,28 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 		flags.Global.CommonFlags = append([]string{"${config.ClangExternalCflags}"}, flags.Global.CommonFlags...)
 	}
 
+	if compiler.Properties.Stack_protector != nil {
+		if flag, err := config.StackProtectorCflag(*compiler.Properties.Stack_protector); err != nil {
+			ctx.PropertyErrorf("stack_protector", "%s", err)
+		} else {
+			flags.Global.CommonFlags = append(flags.Global.CommonFlags, flag)
+		}
+	} else if ctx.Device() {
+		if flag, err := config.DeviceStackProtectorCflag(ctx.Config(), ctx.Arch().ArchType); err != nil {
+			ctx.ModuleErrorf("StackProtector product variable: %s", err)
+		} else if flag != "" {
+			flags.Global.CommonFlags = append(flags.Global.CommonFlags, flag)
+		}
+	}
+
+	if config.PollyEnabledForDir(ctx.Config(), modulePath) {
+		flags.Global.CommonFlags = append(flags.Global.CommonFlags, "${config.PollyCflags}")
+	}
+
+	if config.MlgoInlinerEnabledForDir(ctx.Config(), modulePath) {
+		flags.Global.CommonFlags = append(flags.Global.CommonFlags, "${config.MlgoInlinerCflags}")
+	}
+
 	if tc.Bionic() {
 		if Bool(compiler.Properties.Rtti) {
 			flags.Local.CppFlags = append(flags.Local.CppFlags, "-frtti")
@@ -484,6 +575,11 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 			flags.aidlFlags = append(flags.aidlFlags, "-t")
 		}
 
+		flags = aidlInterfaceFlags(ctx, flags, &compiler.Properties)
+		if compiler.Properties.Aidl.Version != nil {
+			compiler.srcsBeforeGen = aidlFrozenSrcs(ctx, compiler.srcsBeforeGen, &compiler.Properties)
+		}
+
 		flags.Local.CommonFlags = append(flags.Local.CommonFlags,
 			"-I"+android.PathForModuleGen(ctx, "aidl").String())
 	}
@@ -515,12 +611,29 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 		flags.Local.CFlags = append(flags.Local.CFlags, "-fopenmp")
 	}
 
+	if Bool(compiler.Properties.Unity.Enabled) {
+		// Unity translation units include their sources relative to the top of the source tree.
+		flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-iquote .")
+	}
+
+	if Bool(compiler.Properties.Clang_modules) {
+		flags.clangModuleCache = &clangModuleCache{path: android.PathForModuleOut(ctx, "clang-module-cache")}
+		flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-fmodules",
+			"-fmodules-cache-path="+flags.clangModuleCache.path.String())
+	}
+
 	// Exclude directories from manual binder interface allowed list.
 	//TODO(b/145621474): Move this check into IInterface.h when clang-tidy no longer uses absolute paths.
 	if android.HasAnyPrefix(ctx.ModuleDir(), allowedManualInterfacePaths) {
 		flags.Local.CFlags = append(flags.Local.CFlags, "-DDO_NOT_CHECK_MANUAL_BINDER_INTERFACES")
 	}
 
+	// Add the flags developers set to debug their local builds last, so that they override
+	// the module's flags.
+	if config.DeveloperCflagsEnabled(ctx.Config()) {
+		flags.Local.CFlags = append(flags.Local.CFlags, "${config.DeveloperCflags}")
+	}
+
 	return flags
 }
 
@@ -565,9 +678,60 @@ func ndkPathDeps(ctx ModuleContext) android.Paths {
 	return nil
 }
 
+// checkGeneratedHeaderExports verifies that the headers in generated_header_exports are exported
+// by the modules in generated_headers, and that the modules in generated_headers that declare
+// export_headers export at least one of them.  It returns the outputs of the rules that check
+// that the sources include each of the headers, which must be built before the sources are
+// compiled.
+func (compiler *baseCompiler) checkGeneratedHeaderExports(ctx ModuleContext) android.Paths {
+	headers := compiler.Properties.Generated_header_exports
+	if len(headers) == 0 {
+		return nil
+	}
+
+	exported := make(map[string]bool)
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		depTag := ctx.OtherModuleDependencyTag(dep)
+		if depTag != genHeaderDepTag && depTag != genHeaderExportDepTag {
+			return
+		}
+		gen, ok := dep.(genrule.HeaderListGenerator)
+		if !ok || len(gen.ExportedHeaders()) == 0 {
+			return
+		}
+		used := false
+		for _, header := range gen.ExportedHeaders() {
+			if inList(header, headers) {
+				exported[header] = true
+				used = true
+			}
+		}
+		if !used {
+			ctx.PropertyErrorf("generated_headers",
+				"module %q does not export any header in generated_header_exports", ctx.OtherModuleName(dep))
+		}
+	})
+
+	var checks android.Paths
+	for _, header := range headers {
+		if !exported[header] {
+			ctx.PropertyErrorf("generated_header_exports",
+				"%q is not in the export_headers of any module in generated_headers", header)
+			continue
+		}
+		if len(compiler.srcsBeforeGen) > 0 {
+			check := android.PathForModuleOut(ctx, "generated_header_exports", header+".checked")
+			TransformHeaderIncludedCheck(ctx, compiler.srcsBeforeGen, header, check)
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
 func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathDeps) Objects {
 	pathDeps := deps.GeneratedDeps
 	pathDeps = append(pathDeps, ndkPathDeps(ctx)...)
+	pathDeps = append(pathDeps, compiler.checkGeneratedHeaderExports(ctx)...)
 
 	buildFlags := flagsToBuilderFlags(flags)
 
@@ -577,11 +741,16 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 	pathDeps = append(pathDeps, genDeps...)
 
 	compiler.pathDeps = pathDeps
+	compiler.generatedSourcesHeaders = genDeps
 	compiler.cFlagsDeps = flags.CFlagsDeps
 
 	// Save src, buildFlags and context
 	compiler.srcs = srcs
 
+	if Bool(compiler.Properties.Unity.Enabled) {
+		srcs = unitySources(ctx, srcs, &compiler.Properties)
+	}
+
 	// Compile files listed in c.Properties.Srcs into objects
 	objs := compileObjs(ctx, buildFlags, "", srcs, pathDeps, compiler.cFlagsDeps)
 
@@ -599,6 +768,31 @@ func compileObjs(ctx android.ModuleContext, flags builderFlags,
 	return TransformSourceToObj(ctx, subdir, srcFiles, flags, pathDeps, cFlagsDeps)
 }
 
+// clangModuleCache is the directory that Clang caches the modules imported by the sources of a
+// module in.  Clang builds the modules into the cache on demand while compiling, so the build
+// actions that use the cache are ordered one after the other to keep them from writing to it at
+// the same time.
+type clangModuleCache struct {
+	path android.ModuleOutPath
+
+	// The output of the last build action that uses the cache.
+	lastWriter android.Path
+}
+
+// orderOnly returns the order-only dependencies of a build action with the output out that uses
+// the cache, which are orderOnly and the last build action that used the cache, and records the
+// build action as the last one.  It returns orderOnly for modules that don't use Clang modules.
+func (c *clangModuleCache) orderOnly(orderOnly android.Paths, out android.Path) android.Paths {
+	if c == nil {
+		return orderOnly
+	}
+	if c.lastWriter != nil {
+		orderOnly = append(android.Paths{c.lastWriter}, orderOnly...)
+	}
+	c.lastWriter = out
+	return orderOnly
+}
+
 var thirdPartyDirPrefixExceptions = []*regexp.Regexp{
 	regexp.MustCompile("^vendor/[^/]*google[^/]*/"),
 	regexp.MustCompile("^hardware/google/"),
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -41,3 +42,60 @@ func TestIsThirdParty(t *testing.T) {
 		}
 	}
 }
+
+func TestUnityBuild(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_static {
+			name: "libfoo",
+			srcs: [
+				"a.cpp",
+				"b.cpp",
+				"c.cpp",
+				"d.c",
+				"e.c",
+				"f.cpp",
+			],
+			unity: {
+				enabled: true,
+				max_srcs: 2,
+				exclude_srcs: ["f.cpp"],
+			},
+		}`)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_static")
+
+	testCases := []struct {
+		unityFile string
+		content   string
+		obj       string
+	}{
+		{"unity/unity_cpp_0.cpp", `#include "a.cpp"\n#include "b.cpp"`, "obj/unity/unity_cpp_0.o"},
+		{"unity/unity_c_0.c", `#include "d.c"\n#include "e.c"`, "obj/unity/unity_c_0.o"},
+	}
+	for _, tc := range testCases {
+		content := libfoo.Output(tc.unityFile).Args["content"]
+		if content != tc.content {
+			t.Errorf("expected content %q for %s, got %q", tc.content, tc.unityFile, content)
+		}
+		libfoo.Output(tc.obj)
+	}
+
+	// c.cpp is the only source left for a second C++ unity translation unit, and f.cpp is
+	// excluded, so they are compiled separately.
+	for _, obj := range []string{"obj/c.o", "obj/f.o"} {
+		cc := libfoo.Output(obj)
+		if !strings.Contains(cc.Args["cFlags"], "-iquote .") {
+			t.Errorf("expected -iquote . in cflags, got %q", cc.Args["cFlags"])
+		}
+	}
+
+	testCcError(t, `unity.max_srcs: must be at least 2`, `
+		cc_library_static {
+			name: "libfoo",
+			srcs: ["a.cpp"],
+			unity: {
+				enabled: true,
+				max_srcs: 1,
+			},
+		}`)
+}
@@ -34,6 +34,14 @@ var (
 		"armv8-2a": []string{
 			"-march=armv8.2a",
 		},
+		"armv9-a": []string{
+			"-march=armv9-a",
+		},
+	}
+
+	// Older clang versions don't know armv9-a, use the armv8.5-a it is based on.
+	arm64Armv9AFallbackCflags = []string{
+		"-march=armv8.5-a",
 	}
 
 	arm64Ldflags = []string{
@@ -45,7 +53,7 @@ var (
 	}
 
 	arm64Lldflags = append(ClangFilterUnknownLldflags(arm64Ldflags),
-		"-Wl,-z,max-page-size=4096")
+		maxPageSizeLdflags(android.Arm64)...)
 
 	arm64Cppflags = []string{}
 
@@ -79,6 +87,43 @@ var (
 		"exynos-m2": []string{
 			"-mcpu=exynos-m2",
 		},
+		"cortex-a510": []string{
+			// Cortex-A510 erratum 2658417: BFMMLA may produce incorrect
+			// results, keep the compiler from emitting BF16 instructions.
+			"-mcpu=cortex-a510+nobf16",
+		},
+		"cortex-a710": []string{
+			// Use the cortex-a510 since it is the little core paired
+			// with the cortex-a710 and is sensitive to ordering.
+			"-mcpu=cortex-a510+nobf16",
+		},
+		"cortex-a715": []string{
+			// Use the cortex-a510 since it is the little core paired
+			// with the cortex-a715 and is sensitive to ordering.
+			"-mcpu=cortex-a510+nobf16",
+		},
+		"cortex-x2": []string{
+			// Use the cortex-a510 since it is the little core paired
+			// with the cortex-x2 and is sensitive to ordering.
+			"-mcpu=cortex-a510+nobf16",
+		},
+		"cortex-x3": []string{
+			// Use the cortex-a510 since it is the little core paired
+			// with the cortex-x3 and is sensitive to ordering.
+			"-mcpu=cortex-a510+nobf16",
+		},
+	}
+
+	// Cpu variants that are only known to newer versions of clang, mapped to the first major
+	// version of clang that supports them and to the flags used with older versions.
+	arm64ClangCpuVariantMinClangMajorVersion = map[string]int{
+		"cortex-a510": 14,
+	}
+	arm64ClangCpuVariantFallbackCflags = map[string][]string{
+		// The cortex-a55 is the closest little core that older clang versions know.
+		"cortex-a510": []string{
+			"-mcpu=cortex-a55",
+		},
 	}
 )
 
@@ -96,13 +141,15 @@ func init() {
 	pctx.StaticVariable("Arm64Lldflags", strings.Join(arm64Lldflags, " "))
 	pctx.StaticVariable("Arm64IncludeFlags", bionicHeaders("arm64"))
 
-	pctx.StaticVariable("Arm64ClangCflags", strings.Join(ClangFilterUnknownCflags(arm64Cflags), " "))
-	pctx.StaticVariable("Arm64ClangLdflags", strings.Join(ClangFilterUnknownCflags(arm64Ldflags), " "))
-	pctx.StaticVariable("Arm64ClangLldflags", strings.Join(ClangFilterUnknownCflags(arm64Lldflags), " "))
-	pctx.StaticVariable("Arm64ClangCppflags", strings.Join(ClangFilterUnknownCflags(arm64Cppflags), " "))
+	clangFilteredCflagsVariable("Arm64ClangCflags", arm64Cflags)
+	clangFilteredCflagsVariable("Arm64ClangLdflags", arm64Ldflags)
+	clangFilteredCflagsVariable("Arm64ClangLldflags", arm64Lldflags)
+	clangFilteredCflagsVariable("Arm64ClangCppflags", arm64Cppflags)
 
 	pctx.StaticVariable("Arm64ClangArmv8ACflags", strings.Join(arm64ArchVariantCflags["armv8-a"], " "))
 	pctx.StaticVariable("Arm64ClangArmv82ACflags", strings.Join(arm64ArchVariantCflags["armv8-2a"], " "))
+	clangVersionGatedVariable("Arm64ClangArmv9ACflags", 14, arm64ArchVariantCflags["armv9-a"],
+		arm64Armv9AFallbackCflags)
 
 	pctx.StaticVariable("Arm64ClangCortexA53Cflags",
 		strings.Join(arm64ClangCpuVariantCflags["cortex-a53"], " "))
@@ -118,26 +165,37 @@ func init() {
 
 	pctx.StaticVariable("Arm64ClangExynosM2Cflags",
 		strings.Join(arm64ClangCpuVariantCflags["exynos-m2"], " "))
+
+	clangVersionGatedVariable("Arm64ClangCortexA510Cflags",
+		arm64ClangCpuVariantMinClangMajorVersion["cortex-a510"],
+		arm64ClangCpuVariantCflags["cortex-a510"],
+		arm64ClangCpuVariantFallbackCflags["cortex-a510"])
 }
 
 var (
 	arm64ClangArchVariantCflagsVar = map[string]string{
 		"armv8-a":  "${config.Arm64ClangArmv8ACflags}",
 		"armv8-2a": "${config.Arm64ClangArmv82ACflags}",
+		"armv9-a":  "${config.Arm64ClangArmv9ACflags}",
 	}
 
 	arm64ClangCpuVariantCflagsVar = map[string]string{
-		"":           "",
-		"cortex-a53": "${config.Arm64ClangCortexA53Cflags}",
-		"cortex-a55": "${config.Arm64ClangCortexA55Cflags}",
-		"cortex-a72": "${config.Arm64ClangCortexA53Cflags}",
-		"cortex-a73": "${config.Arm64ClangCortexA53Cflags}",
-		"cortex-a75": "${config.Arm64ClangCortexA55Cflags}",
-		"cortex-a76": "${config.Arm64ClangCortexA55Cflags}",
-		"kryo":       "${config.Arm64ClangKryoCflags}",
-		"kryo385":    "${config.Arm64ClangCortexA53Cflags}",
-		"exynos-m1":  "${config.Arm64ClangExynosM1Cflags}",
-		"exynos-m2":  "${config.Arm64ClangExynosM2Cflags}",
+		"":            "",
+		"cortex-a53":  "${config.Arm64ClangCortexA53Cflags}",
+		"cortex-a55":  "${config.Arm64ClangCortexA55Cflags}",
+		"cortex-a72":  "${config.Arm64ClangCortexA53Cflags}",
+		"cortex-a73":  "${config.Arm64ClangCortexA53Cflags}",
+		"cortex-a75":  "${config.Arm64ClangCortexA55Cflags}",
+		"cortex-a76":  "${config.Arm64ClangCortexA55Cflags}",
+		"kryo":        "${config.Arm64ClangKryoCflags}",
+		"kryo385":     "${config.Arm64ClangCortexA53Cflags}",
+		"exynos-m1":   "${config.Arm64ClangExynosM1Cflags}",
+		"exynos-m2":   "${config.Arm64ClangExynosM2Cflags}",
+		"cortex-a510": "${config.Arm64ClangCortexA510Cflags}",
+		"cortex-a710": "${config.Arm64ClangCortexA510Cflags}",
+		"cortex-a715": "${config.Arm64ClangCortexA510Cflags}",
+		"cortex-x2":   "${config.Arm64ClangCortexA510Cflags}",
+		"cortex-x3":   "${config.Arm64ClangCortexA510Cflags}",
 	}
 )
 
@@ -201,7 +259,8 @@ func arm64ToolchainFactory(arch android.Arch) Toolchain {
 	switch arch.ArchVariant {
 	case "armv8-a":
 	case "armv8-2a":
-		// Nothing extra for armv8-a/armv8-2a
+	case "armv9-a":
+		// Nothing extra for armv8-a/armv8-2a/armv9-a
 	default:
 		panic(fmt.Sprintf("Unknown ARM architecture version: %q", arch.ArchVariant))
 	}
@@ -214,6 +273,9 @@ func arm64ToolchainFactory(arch android.Arch) Toolchain {
 	switch arch.CpuVariant {
 	case "cortex-a53", "cortex-a72", "cortex-a73", "kryo", "exynos-m1", "exynos-m2":
 		extraLdflags = "-Wl,--fix-cortex-a53-843419"
+	case "cortex-a510", "cortex-a710", "cortex-a715", "cortex-x2", "cortex-x3":
+		// Armv9 cores are not paired with a cortex-a53, and don't need its erratum
+		// 843419 workaround.  The cortex-a510 errata are handled by its cflags.
 	}
 
 	return &toolchainArm64{
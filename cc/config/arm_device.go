@@ -178,15 +178,15 @@ func init() {
 	pctx.StaticVariable("ArmIncludeFlags", bionicHeaders("arm"))
 
 	// Clang cflags
-	pctx.StaticVariable("ArmToolchainClangCflags", strings.Join(ClangFilterUnknownCflags(armToolchainCflags), " "))
-	pctx.StaticVariable("ArmClangCflags", strings.Join(ClangFilterUnknownCflags(armCflags), " "))
-	pctx.StaticVariable("ArmClangLdflags", strings.Join(ClangFilterUnknownCflags(armLdflags), " "))
-	pctx.StaticVariable("ArmClangLldflags", strings.Join(ClangFilterUnknownCflags(armLldflags), " "))
-	pctx.StaticVariable("ArmClangCppflags", strings.Join(ClangFilterUnknownCflags(armCppflags), " "))
+	clangFilteredCflagsVariable("ArmToolchainClangCflags", armToolchainCflags)
+	clangFilteredCflagsVariable("ArmClangCflags", armCflags)
+	clangFilteredCflagsVariable("ArmClangLdflags", armLdflags)
+	clangFilteredCflagsVariable("ArmClangLldflags", armLldflags)
+	clangFilteredCflagsVariable("ArmClangCppflags", armCppflags)
 
 	// Clang ARM vs. Thumb instruction set cflags
-	pctx.StaticVariable("ArmClangArmCflags", strings.Join(ClangFilterUnknownCflags(armArmCflags), " "))
-	pctx.StaticVariable("ArmClangThumbCflags", strings.Join(ClangFilterUnknownCflags(armThumbCflags), " "))
+	clangFilteredCflagsVariable("ArmClangArmCflags", armArmCflags)
+	clangFilteredCflagsVariable("ArmClangThumbCflags", armThumbCflags)
 
 	// Clang arch variant cflags
 	pctx.StaticVariable("ArmClangArmv7ACflags",
@@ -0,0 +1,63 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file selects between the gcc runtime (libgcc, crtbegin.o and crtend.o) and the LLVM
+// runtime (compiler-rt builtins, crtbegin and crtend objects and libunwind). Device modules
+// already link libclang_rt.builtins and bionic's own crtbegin and crtend objects, so the
+// remaining gcc dependency is the unwinder, which libgcc provides on every arch except arm.
+// Linux host modules get the builtins and the crtbegin and crtend objects from the clang
+// driver, which picks the gcc ones by default. Products opt device arches into the LLVM runtime
+// with the CompilerRtArches product variable, and host arches with HostCompilerRtArches.
+
+import (
+	"android/soong/android"
+)
+
+// UseCompilerRt returns whether device modules for arch use only the LLVM runtime.
+func UseCompilerRt(config android.Config, arch android.ArchType) bool {
+	// arm has always used the LLVM unwinder.
+	return arch == android.Arm || android.InList(arch.Name, config.CompilerRtArches())
+}
+
+// StaticUnwinderLibrary returns the unwinder that is linked statically into device modules for
+// arch that are built against the platform.
+func StaticUnwinderLibrary(config android.Config, arch android.ArchType) string {
+	if UseCompilerRt(config, arch) {
+		return "libunwind_llvm"
+	}
+	return "libgcc_stripped"
+}
+
+// NdkStaticUnwinderLibrary returns the unwinder that is linked statically into device modules
+// for arch that are built against the NDK. The NDK only provides the LLVM unwinder for arm, so
+// the other arches keep using libgcc until the NDK ships it for them.
+func NdkStaticUnwinderLibrary(arch android.ArchType) string {
+	if arch == android.Arm {
+		return "ndk_libunwind"
+	}
+	return "libgcc_stripped"
+}
+
+// HostCompilerRtLdflags returns the flags that make the clang driver link the compiler-rt
+// builtins and the compiler-rt crtbegin and crtend objects into Linux host modules for arch,
+// instead of libgcc and the crtbegin.o and crtend.o objects of the gcc toolchain. The host
+// C++ runtime is built against the libgcc unwinder, so it is kept.
+func HostCompilerRtLdflags(config android.Config, arch android.ArchType) []string {
+	if !android.InList(arch.Name, config.HostCompilerRtArches()) {
+		return nil
+	}
+	return []string{"-rtlib=compiler-rt", "-unwindlib=libgcc"}
+}
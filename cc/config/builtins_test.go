@@ -0,0 +1,57 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestNdkStaticUnwinderLibrary(t *testing.T) {
+	testCases := []struct {
+		arch     android.ArchType
+		expected string
+	}{
+		{android.Arm, "ndk_libunwind"},
+		{android.Arm64, "libgcc_stripped"},
+		{android.X86, "libgcc_stripped"},
+		{android.X86_64, "libgcc_stripped"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.arch.String(), func(t *testing.T) {
+			if got := NdkStaticUnwinderLibrary(testCase.arch); got != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestHostCompilerRtLdflags(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	config.TestProductVariables.CompilerRtArches = []string{"x86_64"}
+	if got := HostCompilerRtLdflags(config, android.X86_64); got != nil {
+		t.Errorf("expected no host ldflags for the device CompilerRtArches, got %q", got)
+	}
+
+	config.TestProductVariables.HostCompilerRtArches = []string{"x86_64"}
+	if got := HostCompilerRtLdflags(config, android.X86_64); len(got) == 0 {
+		t.Errorf("expected host ldflags for HostCompilerRtArches")
+	}
+	if got := HostCompilerRtLdflags(config, android.X86); got != nil {
+		t.Errorf("expected no host ldflags for x86, got %q", got)
+	}
+}
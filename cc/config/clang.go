@@ -15,11 +15,18 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
+
+	"android/soong/android"
 )
 
-// Cflags that should be filtered out when compiling with clang
+// Cflags that should be filtered out when compiling with clang. These are used for the flags
+// built into Soong, and for module flags when the clang prebuilt doesn't have an unknown flags
+// table.
 var ClangUnknownCflags = sorted([]string{
 	"-finline-functions",
 	"-finline-limit=64",
@@ -230,6 +237,109 @@ func ClangFilterUnknownLldflags(lldflags []string) []string {
 	return ret
 }
 
+// ClangUnknownFlags lists the flags that a clang version doesn't accept. Each clang prebuilt
+// can check in a table next to the compiler at
+// <ClangBase>/<host>/<ClangVersion>/clang_unknown_flags.json, for example:
+//
+//   {
+//     "Cflags": ["-fno-canonical-system-headers", "-Wno-psabi"]
+//   }
+//
+// The table replaces ClangUnknownCflags when filtering the toolchain and module flags, so that
+// toolchain updates don't have to edit Soong.
+type ClangUnknownFlags struct {
+	Cflags []string
+}
+
+const clangUnknownFlagsFile = "clang_unknown_flags.json"
+
+var builtinClangUnknownFlags = ClangUnknownFlags{
+	Cflags: ClangUnknownCflags,
+}
+
+// parseClangUnknownFlags parses and validates the contents of an unknown flags table.
+func parseClangUnknownFlags(data []byte) (ClangUnknownFlags, error) {
+	var flags ClangUnknownFlags
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&flags); err != nil {
+		return ClangUnknownFlags{}, err
+	}
+
+	for _, flag := range flags.Cflags {
+		if !strings.HasPrefix(flag, "-") {
+			return ClangUnknownFlags{}, fmt.Errorf("flag %q must start with '-'", flag)
+		}
+	}
+
+	flags.Cflags = sorted(flags.Cflags)
+	return flags, nil
+}
+
+var clangUnknownFlagsKey = android.NewOnceKey("clangUnknownFlags")
+
+type loadedClangUnknownFlags struct {
+	flags ClangUnknownFlags
+	err   error
+}
+
+// ClangUnknownFlagsFor returns the unknown flags table of the clang version in use, or the
+// built-in lists if the prebuilt doesn't have one.  The table is only loaded once, and the same
+// error is returned on every call if it couldn't be loaded.
+func ClangUnknownFlagsFor(ctx android.PathContext) (ClangUnknownFlags, error) {
+	loaded := ctx.Config().Once(clangUnknownFlagsKey, func() interface{} {
+		path := android.ExistentPathForSource(ctx, clangBase(ctx.Config()), ctx.Config().PrebuiltOS(),
+			clangVersion(ctx.Config()), clangUnknownFlagsFile)
+		if !path.Valid() {
+			return loadedClangUnknownFlags{builtinClangUnknownFlags, nil}
+		}
+
+		data, err := android.ReadSourceFile(ctx, path.Path())
+		if err != nil {
+			return loadedClangUnknownFlags{builtinClangUnknownFlags, err}
+		}
+		flags, err := parseClangUnknownFlags(data)
+		if err != nil {
+			return loadedClangUnknownFlags{builtinClangUnknownFlags, fmt.Errorf("%s: %s", path, err)}
+		}
+		return loadedClangUnknownFlags{flags, nil}
+	}).(loadedClangUnknownFlags)
+
+	return loaded.flags, loaded.err
+}
+
+// clangFilterUnknownCflagsFor returns cflags without the flags that the unknown flags table of the
+// clang version in use lists.  An error loading the table is reported once by the make vars
+// provider.
+func clangFilterUnknownCflagsFor(ctx android.PathContext, cflags []string) []string {
+	unknownFlags, _ := ClangUnknownFlagsFor(ctx)
+	kept, _ := unknownFlags.FilterCflags(cflags)
+	return kept
+}
+
+// clangFilteredCflagsVariable defines the variable name as cflags filtered through the unknown
+// flags table of the clang version in use.
+func clangFilteredCflagsVariable(name string, cflags []string) {
+	pctx.VariableFunc(name, func(ctx android.PackageVarContext) string {
+		return strings.Join(clangFilterUnknownCflagsFor(ctx, cflags), " ")
+	})
+}
+
+// FilterCflags returns the flags that the clang version accepts, and the flags that were
+// dropped.
+func (t ClangUnknownFlags) FilterCflags(cflags []string) (kept, dropped []string) {
+	kept = make([]string, 0, len(cflags))
+	for _, f := range cflags {
+		if inListSorted(f, t.Cflags) {
+			dropped = append(dropped, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, dropped
+}
+
 func inListSorted(s string, list []string) bool {
 	for _, l := range list {
 		if s == l {
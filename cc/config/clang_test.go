@@ -0,0 +1,80 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseClangUnknownFlags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected ClangUnknownFlags
+		err      string
+	}{
+		{
+			name:  "valid",
+			input: `{"Cflags": ["-mbionic", "-fno-canonical-system-headers"]}`,
+			expected: ClangUnknownFlags{
+				Cflags: []string{"-fno-canonical-system-headers", "-mbionic"},
+			},
+		},
+		{
+			name:  "unknown field",
+			input: `{"Lldflags": ["-foo"]}`,
+			err:   `unknown field "Lldflags"`,
+		},
+		{
+			name:  "not a flag",
+			input: `{"Cflags": ["mbionic"]}`,
+			err:   `flag "mbionic" must start with '-'`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			flags, err := parseClangUnknownFlags([]byte(testCase.input))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Errorf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(flags, testCase.expected) {
+				t.Errorf("expected %#v, got %#v", testCase.expected, flags)
+			}
+		})
+	}
+}
+
+func TestClangUnknownFlagsFilter(t *testing.T) {
+	table := ClangUnknownFlags{
+		Cflags: []string{"-fno-foo", "-mbionic"},
+	}
+
+	kept, dropped := table.FilterCflags([]string{"-O2", "-mbionic", "-Wall", "-fno-foo"})
+	if !reflect.DeepEqual(kept, []string{"-O2", "-Wall"}) {
+		t.Errorf("unexpected kept cflags %q", kept)
+	}
+	if !reflect.DeepEqual(dropped, []string{"-mbionic", "-fno-foo"}) {
+		t.Errorf("unexpected dropped cflags %q", dropped)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	// Flags for both compiling and linking modules with clang source-based coverage.
+	clangCoverageCommonFlags = []string{
+		"-fprofile-instr-generate",
+		"-fcoverage-mapping",
+	}
+
+	// The instrumentation grows stack frames past the limits set by modules.
+	clangCoverageCflags = []string{
+		"-Wno-frame-larger-than=",
+	}
+
+	clangCoverageLdflags = []string{
+		"-fprofile-instr-generate",
+	}
+
+	// Code under these paths is never built with clang coverage, even if it is included by
+	// NativeCoveragePaths. The profile runtime depends on libc, and the dynamic linker runs
+	// before the runtime can be initialized.
+	clangCoverageExcludedPaths = []string{
+		"bionic/libc",
+		"bionic/linker",
+		"external/compiler-rt",
+	}
+)
+
+func init() {
+	pctx.StaticVariable("ClangCoverageCommonFlags", strings.Join(clangCoverageCommonFlags, " "))
+	pctx.StaticVariable("ClangCoverageCflags", strings.Join(clangCoverageCflags, " "))
+	pctx.StaticVariable("ClangCoverageLdflags", strings.Join(clangCoverageLdflags, " "))
+}
+
+// ClangCoverageRuntimeLibrary returns the name of the profile runtime library that modules built
+// with clang coverage for the toolchain link against, or "" if clang coverage is not supported
+// for the toolchain.
+func ClangCoverageRuntimeLibrary(t Toolchain) string {
+	return ProfileRuntimeLibrary(t)
+}
+
+// ClangCoverageExcludedForPath returns whether modules in path must not be built with clang
+// coverage.
+func ClangCoverageExcludedForPath(path string) bool {
+	return android.HasAnyPrefix(path, clangCoverageExcludedPaths)
+}
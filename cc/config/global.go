@@ -15,6 +15,8 @@
 package config
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"android/soong/android"
@@ -119,6 +121,112 @@ var (
 		"-w",
 	}
 
+	// Stack protector strengths that can be selected with the StackProtector product variable
+	// and the stack_protector property.
+	stackProtectorCflags = map[string]string{
+		"basic":  "-fstack-protector",
+		"strong": "-fstack-protector-strong",
+		"all":    "-fstack-protector-all",
+	}
+
+	// The stack protector strength selected by the device global cflags.
+	deviceGlobalStackProtector = "strong"
+
+	// The stack protector strength for device modules of each arch, unless the product selects
+	// a different one.  Arches whose strength differs from deviceGlobalStackProtector add its
+	// flag after the device global cflags.
+	deviceStackProtectorDefaults = map[android.ArchType]string{
+		android.Arm:     "strong",
+		android.Arm64:   "strong",
+		android.Mips:    "strong",
+		android.Mips64:  "strong",
+		android.Riscv64: "strong",
+		android.X86:     "strong",
+		android.X86_64:  "strong",
+	}
+
+	// Flags that developers may not pass with SOONG_EXTRA_CFLAGS, because they change the
+	// outputs of the compile rules or make them depend on files outside the source tree.
+	developerDeniedFlags = []string{
+		"-MD",
+		"-MMD",
+	}
+
+	// Matched by prefix, so that the joined forms of flags that take an argument, like
+	// -o<file>, -include<file> or -L<dir>, are denied as well.
+	developerDeniedFlagPrefixes = []string{
+		"-o",
+		"-save-temps",
+		"-ftime-trace",
+		"--serialize-diagnostics",
+		"-fprofile-generate",
+		"-fprofile-instr-generate",
+		"-fprofile-use",
+		"-fprofile-instr-use",
+		"-fdebug-prefix-map",
+		"-ffile-prefix-map",
+		"-fmacro-prefix-map",
+		"-MF",
+		"-MT",
+		"-MQ",
+		"-include",
+		"-imacros",
+		"-I",
+		"-isystem",
+		"-isysroot",
+		"-iquote",
+		"-idirafter",
+		"-L",
+		"-F",
+		"-B",
+		"--sysroot",
+		"--gcc-toolchain",
+		"-fplugin",
+		"-Xclang",
+		"-Xpreprocessor",
+		"-Wp,",
+	}
+
+	// Polly polyhedral loop optimizations, enabled for the projects listed in POLLY_PROJECTS.
+	pollyCflags = []string{
+		"-mllvm -polly",
+		"-mllvm -polly-vectorizer=stripmine",
+	}
+
+	// ML-guided inlining, enabled for the projects listed in MLGO_INLINER_PROJECTS.
+	mlgoInlinerCflags = []string{
+		"-mllvm -enable-ml-inliner=release",
+	}
+
+	// Flags that products may not add to every module with BOARD_GLOBAL_CFLAGS,
+	// BOARD_GLOBAL_CPPFLAGS or BOARD_GLOBAL_LDFLAGS, because they weaken the security
+	// hardening, hide warnings, or change the ABI of everything built for the device.
+	boardGlobalDeniedFlags = []string{
+		"-w",
+		"-Wno-error",
+		"-fno-stack-protector",
+		"-fno-PIC",
+		"-fno-pic",
+		"-fshort-enums",
+		"-Wl,-z,execstack",
+		"-Wl,-z,lazy",
+		"-Wl,-z,norelro",
+		"-Wl,--no-fatal-warnings",
+	}
+
+	boardGlobalDeniedFlagPrefixes = []string{
+		"-D_FORTIFY_SOURCE",
+		"-U_FORTIFY_SOURCE",
+		"-I",
+		"-isystem",
+		"-L",
+		"-l",
+		"-march=",
+		"-mcpu=",
+		"-fsanitize",
+		"-ftrivial-auto-var-init",
+	}
+
 	CStdVersion               = "gnu99"
 	CppStdVersion             = "gnu++17"
 	ExperimentalCStdVersion   = "gnu11"
@@ -149,15 +257,27 @@ func init() {
 	}
 
 	pctx.StaticVariable("CommonGlobalConlyflags", strings.Join(commonGlobalConlyflags, " "))
-	pctx.StaticVariable("DeviceGlobalCppflags", strings.Join(deviceGlobalCppflags, " "))
-	pctx.StaticVariable("DeviceGlobalLdflags", strings.Join(deviceGlobalLdflags, " "))
-	pctx.StaticVariable("DeviceGlobalLldflags", strings.Join(deviceGlobalLldflags, " "))
+	pctx.VariableFunc("DeviceGlobalCppflags", func(ctx android.PackageVarContext) string {
+		flags := android.CopyOf(deviceGlobalCppflags)
+		flags = append(flags, boardGlobalFlags(ctx, "BOARD_GLOBAL_CPPFLAGS", ctx.Config().BoardGlobalCppflags())...)
+		return strings.Join(flags, " ")
+	})
+	pctx.VariableFunc("DeviceGlobalLdflags", func(ctx android.PackageVarContext) string {
+		flags := android.CopyOf(deviceGlobalLdflags)
+		flags = append(flags, boardGlobalFlags(ctx, "BOARD_GLOBAL_LDFLAGS", ctx.Config().BoardGlobalLdflags())...)
+		return strings.Join(flags, " ")
+	})
+	pctx.VariableFunc("DeviceGlobalLldflags", func(ctx android.PackageVarContext) string {
+		flags := android.CopyOf(deviceGlobalLldflags)
+		flags = append(flags, boardGlobalFlags(ctx, "BOARD_GLOBAL_LDFLAGS", ctx.Config().BoardGlobalLdflags())...)
+		return strings.Join(flags, " ")
+	})
 	pctx.StaticVariable("HostGlobalCppflags", strings.Join(hostGlobalCppflags, " "))
 	pctx.StaticVariable("HostGlobalLdflags", strings.Join(hostGlobalLdflags, " "))
 	pctx.StaticVariable("HostGlobalLldflags", strings.Join(hostGlobalLldflags, " "))
 
 	pctx.VariableFunc("CommonClangGlobalCflags", func(ctx android.PackageVarContext) string {
-		flags := ClangFilterUnknownCflags(commonGlobalCflags)
+		flags := clangFilterUnknownCflagsFor(ctx, commonGlobalCflags)
 		flags = append(flags, "${ClangExtraCflags}")
 
 		// http://b/131390872
@@ -178,22 +298,41 @@ func init() {
 	})
 
 	pctx.VariableFunc("DeviceClangGlobalCflags", func(ctx android.PackageVarContext) string {
-		if ctx.Config().Fuchsia() {
-			return strings.Join(ClangFilterUnknownCflags(deviceGlobalCflags), " ")
-		} else {
-			return strings.Join(append(ClangFilterUnknownCflags(deviceGlobalCflags), "${ClangExtraTargetCflags}"), " ")
+		flags := clangFilterUnknownCflagsFor(ctx, deviceGlobalCflags)
+		if !ctx.Config().Fuchsia() {
+			flags = append(flags, "${ClangExtraTargetCflags}")
 		}
+		flags = append(flags, boardGlobalFlags(ctx, "BOARD_GLOBAL_CFLAGS", ctx.Config().BoardGlobalCflags())...)
+		return strings.Join(flags, " ")
+	})
+	// Extra flags for debugging local builds, see DeveloperCflagsEnabled.
+	pctx.VariableFunc("DeveloperCflags", func(ctx android.PackageVarContext) string {
+		flags := strings.Fields(ctx.Config().Getenv("SOONG_EXTRA_CFLAGS"))
+		for _, flag := range flags {
+			if err := checkDeveloperFlag(flag); err != nil {
+				ctx.Errorf("SOONG_EXTRA_CFLAGS: %s", err)
+			}
+		}
+		return strings.Join(flags, " ")
+	})
+	clangFilteredCflagsVariable("HostClangGlobalCflags", hostGlobalCflags)
+	pctx.VariableFunc("NoOverrideClangGlobalCflags", func(ctx android.PackageVarContext) string {
+		flags := clangFilterUnknownCflagsFor(ctx, noOverrideGlobalCflags)
+		return strings.Join(append(flags, "${ClangExtraNoOverrideCflags}"), " ")
 	})
-	pctx.StaticVariable("HostClangGlobalCflags",
-		strings.Join(ClangFilterUnknownCflags(hostGlobalCflags), " "))
-	pctx.StaticVariable("NoOverrideClangGlobalCflags",
-		strings.Join(append(ClangFilterUnknownCflags(noOverrideGlobalCflags), "${ClangExtraNoOverrideCflags}"), " "))
 
-	pctx.StaticVariable("CommonClangGlobalCppflags",
-		strings.Join(append(ClangFilterUnknownCflags(commonGlobalCppflags), "${ClangExtraCppflags}"), " "))
+	pctx.VariableFunc("CommonClangGlobalCppflags", func(ctx android.PackageVarContext) string {
+		flags := clangFilterUnknownCflagsFor(ctx, commonGlobalCppflags)
+		return strings.Join(append(flags, "${ClangExtraCppflags}"), " ")
+	})
 
 	pctx.StaticVariable("ClangExternalCflags", "${ClangExtraExternalCflags}")
 
+	// Flags for experimental optimizations are filtered through the unknown flags table, so that
+	// they can be dropped when moving to a compiler that doesn't support them.
+	clangFilteredCflagsVariable("PollyCflags", pollyCflags)
+	clangFilteredCflagsVariable("MlgoInlinerCflags", mlgoInlinerCflags)
+
 	// Everything in these lists is a crime against abstraction and dependency tracking.
 	// Do not add anything to this list.
 	pctx.PrefixedExistentPathsForSourcesVariable("CommonGlobalIncludes", "-I",
@@ -220,19 +359,13 @@ func init() {
 		return "${ClangDefaultBase}"
 	})
 	pctx.VariableFunc("ClangVersion", func(ctx android.PackageVarContext) string {
-		if override := ctx.Config().Getenv("LLVM_PREBUILTS_VERSION"); override != "" {
-			return override
-		}
-		return ClangDefaultVersion
+		return clangVersion(ctx.Config())
 	})
 	pctx.StaticVariable("ClangPath", "${ClangBase}/${HostPrebuiltTag}/${ClangVersion}")
 	pctx.StaticVariable("ClangBin", "${ClangPath}/bin")
 
 	pctx.VariableFunc("ClangShortVersion", func(ctx android.PackageVarContext) string {
-		if override := ctx.Config().Getenv("LLVM_RELEASE_VERSION"); override != "" {
-			return override
-		}
-		return ClangDefaultShortVersion
+		return clangShortVersion(ctx.Config())
 	})
 	pctx.StaticVariable("ClangAsanLibDir", "${ClangBase}/linux-x86/${ClangVersion}/lib64/clang/${ClangShortVersion}/lib/linux")
 
@@ -254,9 +387,37 @@ func init() {
 		if override := ctx.Config().Getenv("CC_WRAPPER"); override != "" {
 			return override + " "
 		}
+		if wrapper := ctx.Config().CcWrapper(); wrapper != "" {
+			path := CcWrapperPath(ctx)
+			if !path.Valid() {
+				ctx.Errorf("CcWrapper %q does not exist in the source tree", wrapper)
+				return ""
+			}
+			return path.String() + " "
+		}
 		return ""
 	})
 
+	// ThinLTO cache settings, used when USE_THINLTO_CACHE is set.
+	pctx.VariableFunc("ThinLTOCacheDir", func(ctx android.PackageVarContext) string {
+		if override := ctx.Config().Getenv("THINLTO_CACHE_DIR"); override != "" {
+			return override
+		}
+		return android.PathForOutput(ctx, "thinlto-cache").String()
+	})
+	// Limit the size of the ThinLTO cache to the lesser of 10% of available disk space and 10GB
+	// by default.
+	pctx.VariableFunc("ThinLTOCacheSizePercent", envOverrideFunc("THINLTO_CACHE_SIZE_PERCENT", "10"))
+	pctx.VariableFunc("ThinLTOCacheSizeBytes", envOverrideFunc("THINLTO_CACHE_SIZE_BYTES", "10g"))
+	pctx.StaticVariable("ThinLTOCachePolicy",
+		"cache_size=${ThinLTOCacheSizePercent}%:cache_size_bytes=${ThinLTOCacheSizeBytes}")
+
+	// Tuning flags for modules that are built with ThinLTO because GLOBAL_THINLTO is set. Importing
+	// fewer functions across modules keeps the link time and size increase of enabling ThinLTO
+	// everywhere in check.
+	pctx.VariableFunc("ThinLTOGlobalLdflags", envOverrideFunc("THINLTO_GLOBAL_LDFLAGS",
+		"-Wl,-plugin-opt,-import-instr-limit=5"))
+
 	pctx.VariableFunc("RECXXPool", remoteexec.EnvOverrideFunc("RBE_CXX_POOL", remoteexec.DefaultPool))
 	pctx.VariableFunc("RECXXLinksPool", remoteexec.EnvOverrideFunc("RBE_CXX_LINKS_POOL", remoteexec.DefaultPool))
 	pctx.VariableFunc("RECXXLinksExecStrategy", remoteexec.EnvOverrideFunc("RBE_CXX_LINKS_EXEC_STRATEGY", remoteexec.LocalExecStrategy))
@@ -275,6 +436,176 @@ func bionicHeaders(kernelArch string) string {
 	}, " ")
 }
 
+// checkBoardGlobalFlag returns an error if flag may not be added to the global flags by the product.
+func checkBoardGlobalFlag(flag string) error {
+	if !strings.HasPrefix(flag, "-") {
+		return fmt.Errorf("flag %q must start with '-'", flag)
+	}
+	if android.InList(flag, boardGlobalDeniedFlags) {
+		return fmt.Errorf("flag %q is not allowed", flag)
+	}
+	if android.HasAnyPrefix(flag, boardGlobalDeniedFlagPrefixes) {
+		return fmt.Errorf("flag %q is not allowed", flag)
+	}
+	return nil
+}
+
+// boardGlobalFlagsKey is the once key used to validate the flags of a board global flags
+// variable once per config.
+type boardGlobalFlagsKey string
+
+// boardGlobalFlags returns the flags the product adds to the global flags, reporting an error
+// for each flag that is not allowed. BOARD_GLOBAL_LDFLAGS is added to both the ldflags and the
+// lldflags, so the flags of each variable are only validated by the first caller.
+func boardGlobalFlags(ctx android.PackageVarContext, variable string, flags []string) []string {
+	ctx.Config().Once(android.NewCustomOnceKey(boardGlobalFlagsKey(variable)), func() interface{} {
+		for _, flag := range flags {
+			if err := checkBoardGlobalFlag(flag); err != nil {
+				ctx.Errorf("%s: %s", variable, err)
+			}
+		}
+		return nil
+	})
+	return flags
+}
+
+// checkDeveloperFlag returns an error if flag may not be passed with SOONG_EXTRA_CFLAGS.
+func checkDeveloperFlag(flag string) error {
+	if !strings.HasPrefix(flag, "-") {
+		return fmt.Errorf("flag %q must start with '-'", flag)
+	}
+	if strings.Contains(flag, "$") {
+		return fmt.Errorf("flag %q must not reference variables", flag)
+	}
+	if android.InList(flag, developerDeniedFlags) || android.HasAnyPrefix(flag, developerDeniedFlagPrefixes) {
+		return fmt.Errorf("flag %q is not allowed", flag)
+	}
+	return nil
+}
+
+// DeveloperCflagsEnabled returns whether the SOONG_EXTRA_CFLAGS environment variable adds
+// flags to every C and C++ compile for debugging local builds. Reading it through the config
+// makes soong_build rerun when it changes.
+func DeveloperCflagsEnabled(config android.Config) bool {
+	return config.Getenv("SOONG_EXTRA_CFLAGS") != ""
+}
+
+// optimizationEnabledForDir returns whether dir is in one of the comma separated project
+// directories listed in envVar, or envVar is set to "all".
+func optimizationEnabledForDir(config android.Config, envVar, dir string) bool {
+	projects := config.Getenv(envVar)
+	if projects == "" {
+		return false
+	}
+	dir += "/"
+	for _, project := range strings.Split(projects, ",") {
+		if project == "all" {
+			return true
+		}
+		if project = strings.TrimSuffix(project, "/") + "/"; strings.HasPrefix(dir, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// PollyEnabledForDir returns whether modules in dir are built with Polly optimizations.
+func PollyEnabledForDir(config android.Config, dir string) bool {
+	return optimizationEnabledForDir(config, "POLLY_PROJECTS", dir)
+}
+
+// MlgoInlinerEnabledForDir returns whether modules in dir are built with ML-guided inlining.
+func MlgoInlinerEnabledForDir(config android.Config, dir string) bool {
+	return optimizationEnabledForDir(config, "MLGO_INLINER_PROJECTS", dir)
+}
+
+func clangBase(config android.Config) string {
+	if override := config.Getenv("LLVM_PREBUILTS_BASE"); override != "" {
+		return override
+	}
+	return ClangDefaultBase
+}
+
+func clangVersion(config android.Config) string {
+	if override := config.Getenv("LLVM_PREBUILTS_VERSION"); override != "" {
+		return override
+	}
+	return ClangDefaultVersion
+}
+
+func clangShortVersion(config android.Config) string {
+	if override := config.Getenv("LLVM_RELEASE_VERSION"); override != "" {
+		return override
+	}
+	return ClangDefaultShortVersion
+}
+
+// clangMajorVersionAtLeast returns true if the major version of the clang used for the build is at
+// least major.
+func clangMajorVersionAtLeast(config android.Config, major int) bool {
+	version, err := strconv.Atoi(strings.SplitN(clangShortVersion(config), ".", 2)[0])
+	return err == nil && version >= major
+}
+
+// clangVersionGatedCflags returns flags when the clang of the build is at least the given major
+// version, and fallback, flags that older versions understand, otherwise.
+func clangVersionGatedCflags(config android.Config, major int, flags, fallback []string) string {
+	if clangMajorVersionAtLeast(config, major) {
+		return strings.Join(flags, " ")
+	}
+	return strings.Join(fallback, " ")
+}
+
+// clangVersionGatedVariable defines the variable name with clangVersionGatedCflags, filtered
+// through the unknown flags table.
+func clangVersionGatedVariable(name string, major int, flags, fallback []string) {
+	pctx.VariableFunc(name, func(ctx android.PackageVarContext) string {
+		return clangVersionGatedCflags(ctx.Config(), major, clangFilterUnknownCflagsFor(ctx, flags),
+			clangFilterUnknownCflagsFor(ctx, fallback))
+	})
+}
+
+// StackProtectorCflag returns the flag that selects a stack protector strength.
+func StackProtectorCflag(strength string) (string, error) {
+	if flag, ok := stackProtectorCflags[strength]; ok {
+		return flag, nil
+	}
+	return "", fmt.Errorf("unknown stack protector strength %q, must be one of basic, strong or all", strength)
+}
+
+// DeviceStackProtectorCflag returns the flag that selects the stack protector strength for device
+// modules for arch that don't set the stack_protector property, or "" if it is the strength
+// already selected by the device global cflags.
+func DeviceStackProtectorCflag(config android.Config, arch android.ArchType) (string, error) {
+	strength := config.StackProtector()
+	if strength == "" {
+		strength = deviceStackProtectorDefaults[arch]
+	}
+	if strength == "" || strength == deviceGlobalStackProtector {
+		return "", nil
+	}
+	return StackProtectorCflag(strength)
+}
+
+// CcWrapperPath returns the path to the compiler caching wrapper set by the product, so that
+// compile rules can depend on it. It returns an invalid path when there is no wrapper, when it
+// doesn't exist, or when the CC_WRAPPER environment variable overrides it.
+func CcWrapperPath(ctx android.PathContext) android.OptionalPath {
+	if ctx.Config().Getenv("CC_WRAPPER") != "" {
+		return android.OptionalPath{}
+	}
+	wrapper := ctx.Config().CcWrapper()
+	if wrapper == "" {
+		return android.OptionalPath{}
+	}
+	return android.ExistentPathForSource(ctx, wrapper)
+}
+
+// GlobalThinLTO returns whether device modules are built with ThinLTO unless they opt out.
+func GlobalThinLTO(config android.Config) bool {
+	return config.IsEnvTrue("GLOBAL_THINLTO")
+}
+
 func envOverrideFunc(envVar, defaultVal string) func(ctx android.PackageVarContext) string {
 	return func(ctx android.PackageVarContext) string {
 		if override := ctx.Config().Getenv(envVar); override != "" {
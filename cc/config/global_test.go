@@ -0,0 +1,212 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestCheckBoardGlobalFlag(t *testing.T) {
+	testCases := []struct {
+		flag    string
+		allowed bool
+	}{
+		{"-DHAS_QUIRKY_HARDWARE=1", true},
+		{"-Wno-unused-parameter", true},
+		{"-Wl,--pack-dyn-relocs=android", true},
+		{"HAS_QUIRKY_HARDWARE", false},
+		{"-w", false},
+		{"-Wno-error", false},
+		{"-fno-stack-protector", false},
+		{"-D_FORTIFY_SOURCE=0", false},
+		{"-U_FORTIFY_SOURCE", false},
+		{"-Ivendor/foo/include", false},
+		{"-march=armv8.5-a", false},
+		{"-fsanitize=address", false},
+		{"-Wl,-z,execstack", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.flag, func(t *testing.T) {
+			err := checkBoardGlobalFlag(testCase.flag)
+			if testCase.allowed && err != nil {
+				t.Errorf("expected %q to be allowed, got %s", testCase.flag, err)
+			} else if !testCase.allowed && err == nil {
+				t.Errorf("expected %q to be denied", testCase.flag)
+			}
+		})
+	}
+}
+
+type boardGlobalFlagsTestContext struct {
+	config android.Config
+	errs   []string
+}
+
+func (ctx *boardGlobalFlagsTestContext) Config() android.Config {
+	return ctx.config
+}
+
+func (ctx *boardGlobalFlagsTestContext) AddNinjaFileDeps(deps ...string) {}
+
+func (ctx *boardGlobalFlagsTestContext) Errorf(format string, args ...interface{}) {
+	ctx.errs = append(ctx.errs, fmt.Sprintf(format, args...))
+}
+
+func TestBoardGlobalLdflagsReportedOnce(t *testing.T) {
+	ctx := &boardGlobalFlagsTestContext{config: android.TestConfig("out", nil, "", nil)}
+	ldflags := []string{"-Wl,--pack-dyn-relocs=android", "-Wl,-z,execstack"}
+
+	// BOARD_GLOBAL_LDFLAGS is added to both DeviceGlobalLdflags and DeviceGlobalLldflags.
+	for i := 0; i < 2; i++ {
+		if got := boardGlobalFlags(ctx, "BOARD_GLOBAL_LDFLAGS", ldflags); !reflect.DeepEqual(got, ldflags) {
+			t.Errorf("expected flags %q, got %q", ldflags, got)
+		}
+	}
+
+	expected := []string{`BOARD_GLOBAL_LDFLAGS: flag "-Wl,-z,execstack" is not allowed`}
+	if !reflect.DeepEqual(ctx.errs, expected) {
+		t.Errorf("expected errors %q, got %q", expected, ctx.errs)
+	}
+}
+
+func TestCheckDeveloperFlag(t *testing.T) {
+	testCases := []struct {
+		flag    string
+		allowed bool
+	}{
+		{"-O0", true},
+		{"-g3", true},
+		{"-Wno-error", true},
+		{"-DDEBUG_LOCKING=1", true},
+		{"DEBUG", false},
+		{"-o", false},
+		{"-ofoo.o", false},
+		{"-MF", false},
+		{"-MFfoo.d", false},
+		{"-include", false},
+		{"-includefoo.h", false},
+		{"-isysroot/", false},
+		{"-L/usr/lib", false},
+		{"-F/Library/Frameworks", false},
+		{"-Wp,-MD,foo.d", false},
+		{"-Xpreprocessor", false},
+		{"-save-temps=obj", false},
+		{"-ftime-trace", false},
+		{"-I/usr/include", false},
+		{"-isystem/usr/include", false},
+		{"--sysroot=/", false},
+		{"-fdebug-prefix-map=/a=/b", false},
+		{"-DPATH=${ClangBase}", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.flag, func(t *testing.T) {
+			err := checkDeveloperFlag(testCase.flag)
+			if testCase.allowed && err != nil {
+				t.Errorf("expected %q to be allowed, got %s", testCase.flag, err)
+			} else if !testCase.allowed && err == nil {
+				t.Errorf("expected %q to be denied", testCase.flag)
+			}
+		})
+	}
+}
+
+func TestOptimizationEnabledForDir(t *testing.T) {
+	testCases := []struct {
+		projects string
+		dir      string
+		expected bool
+	}{
+		{"", "external/zlib", false},
+		{"all", "external/zlib", true},
+		{"external/zlib", "external/zlib", true},
+		{"external/zlib/", "external/zlib/contrib", true},
+		{"external/zlib", "external/zlibextra", false},
+		{"bionic,external/zlib", "external/zlib", true},
+		{"bionic,external/zlib", "frameworks/av", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.projects+":"+testCase.dir, func(t *testing.T) {
+			config := android.TestConfig("", map[string]string{"POLLY_PROJECTS": testCase.projects}, "", nil)
+			if got := PollyEnabledForDir(config, testCase.dir); got != testCase.expected {
+				t.Errorf("expected %t, got %t", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestClangMajorVersionAtLeast(t *testing.T) {
+	testCases := []struct {
+		version  string
+		major    int
+		expected bool
+	}{
+		{"", 11, true},
+		{"", 12, false},
+		{"12.0.1", 12, true},
+		{"13.0.0", 12, true},
+		{"invalid", 12, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.version, func(t *testing.T) {
+			config := android.TestConfig("", map[string]string{"LLVM_RELEASE_VERSION": testCase.version}, "", nil)
+			if got := clangMajorVersionAtLeast(config, testCase.major); got != testCase.expected {
+				t.Errorf("expected %t, got %t", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeviceStackProtectorCflag(t *testing.T) {
+	defer func(strength string) {
+		deviceStackProtectorDefaults[android.X86] = strength
+	}(deviceStackProtectorDefaults[android.X86])
+	deviceStackProtectorDefaults[android.X86] = "all"
+
+	testCases := []struct {
+		name     string
+		product  string
+		arch     android.ArchType
+		expected string
+	}{
+		{"arm64 default", "", android.Arm64, ""},
+		{"x86 default", "", android.X86, "-fstack-protector-all"},
+		{"arm64 product", "basic", android.Arm64, "-fstack-protector"},
+		{"x86 product", "strong", android.X86, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := android.TestConfig("out", nil, "", nil)
+			if testCase.product != "" {
+				config.TestProductVariables.StackProtector = &testCase.product
+			}
+			flag, err := DeviceStackProtectorCflag(config, testCase.arch)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if flag != testCase.expected {
+				t.Errorf("expected flag %q, got %q", testCase.expected, flag)
+			}
+		})
+	}
+}
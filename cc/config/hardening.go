@@ -0,0 +1,64 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file holds the policy for which device modules are built with control flow integrity
+// (CFI) and shadow call stack (SCS) by default. Modules can still enable or disable them with
+// their sanitize properties.
+
+import (
+	"android/soong/android"
+)
+
+// CfiSupportedArch returns whether CFI can be enabled for modules built for arch.
+func CfiSupportedArch(arch android.ArchType) bool {
+	switch arch {
+	case android.Mips, android.Mips64:
+		// CFI needs gold linker, and mips toolchain does not have one.
+		return false
+	case android.Arm:
+		// Also disable CFI for arm32 until b/35157333 is fixed.
+		return false
+	}
+	return true
+}
+
+// CfiEnabledByDefault returns whether CFI is enabled for device modules in dir built for arch
+// that don't set sanitize.cfi. It is enabled for the paths in the CFIIncludePaths product
+// variable, except the ones in CFIExcludePaths, for the arches in CFIArches.
+func CfiEnabledByDefault(config android.Config, arch android.ArchType, dir string) bool {
+	return config.EnableCFI() && CfiSupportedArch(arch) &&
+		android.InList(arch.Name, config.CFIArches()) &&
+		config.CFIEnabledForPath(dir) && !config.CFIDisabledForPath(dir)
+}
+
+// CfiDiagEnabledByDefault returns whether modules that are built with CFI by default trap with
+// diagnostics instead of aborting.
+func CfiDiagEnabledByDefault(config android.Config) bool {
+	return android.InList("cfi", config.SanitizeDeviceDiag())
+}
+
+// ScsSupportedArch returns whether SCS can be enabled for modules built for arch.
+func ScsSupportedArch(arch android.ArchType) bool {
+	// SCS is only implemented on AArch64.
+	return arch == android.Arm64
+}
+
+// ScsEnabledByDefault returns whether SCS is enabled for device modules in dir built for arch
+// that don't set sanitize.scs. It is enabled for the paths in the SCSIncludePaths product
+// variable, except the ones in SCSExcludePaths.
+func ScsEnabledByDefault(config android.Config, arch android.ArchType, dir string) bool {
+	return ScsSupportedArch(arch) && config.SCSEnabledForPath(dir) && !config.SCSDisabledForPath(dir)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestHardeningDefaults(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	config.TestProductVariables.CFIIncludePaths = []string{"system/core"}
+	config.TestProductVariables.CFIExcludePaths = []string{"system/core/libcutils"}
+	config.TestProductVariables.SCSIncludePaths = []string{"system/core"}
+	config.TestProductVariables.SCSExcludePaths = []string{"system/core/init"}
+
+	testCases := []struct {
+		name string
+		arch android.ArchType
+		dir  string
+		cfi  bool
+		scs  bool
+	}{
+		{"included", android.Arm64, "system/core/libutils", true, true},
+		{"not included", android.Arm64, "frameworks/base", false, false},
+		{"cfi excluded", android.Arm64, "system/core/libcutils", false, true},
+		{"scs excluded", android.Arm64, "system/core/init", true, false},
+		{"unsupported arch", android.Arm, "system/core/libutils", false, false},
+		{"arch not selected", android.X86_64, "system/core/libutils", false, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := CfiEnabledByDefault(config, testCase.arch, testCase.dir); got != testCase.cfi {
+				t.Errorf("expected cfi %t, got %t", testCase.cfi, got)
+			}
+			if got := ScsEnabledByDefault(config, testCase.arch, testCase.dir); got != testCase.scs {
+				t.Errorf("expected scs %t, got %t", testCase.scs, got)
+			}
+		})
+	}
+
+	config.TestProductVariables.CFIArches = []string{"arm64", "x86_64"}
+	if !CfiEnabledByDefault(config, android.X86_64, "system/core/libutils") {
+		t.Errorf("expected cfi for x86_64 when it is in CFIArches")
+	}
+}
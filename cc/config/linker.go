@@ -0,0 +1,156 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+// LinkerBackend is a linker that modules using the lld flags can be linked with. The backend
+// is selected with the SOONG_LINKER environment variable, a comma separated list of
+// <os>:<linker> or <os>_<arch>:<linker> entries, for example
+// SOONG_LINKER=linux_glibc:mold,linux_glibc_x86:lld. Modules use lld unless an entry matches.
+type LinkerBackend struct {
+	Name string
+
+	// Ldflags selects the backend in the clang driver. They are appended after the global lld
+	// flags, so they override -fuse-ld=lld.
+	Ldflags string
+
+	// PrebuiltDir and Binary locate the linker at <PrebuiltDir>/<host os>/bin/<Binary> in the
+	// source tree.
+	PrebuiltDir string
+	Binary      string
+
+	// HostOnly backends can't be selected for device modules.
+	HostOnly bool
+
+	// UnsupportedFlagPrefixes lists prefixes of lld flags that the backend doesn't accept.
+	// Modules that use them are linked with lld instead.
+	UnsupportedFlagPrefixes []string
+}
+
+var (
+	LldBackend = LinkerBackend{
+		Name: "lld",
+	}
+
+	// mold is experimental, and only used for host modules.
+	MoldBackend = LinkerBackend{
+		Name:        "mold",
+		Ldflags:     "-B${config.MoldBinDir} -fuse-ld=mold",
+		PrebuiltDir: "prebuilts/mold",
+		Binary:      "ld.mold",
+		HostOnly:    true,
+		UnsupportedFlagPrefixes: []string{
+			// mold can't run the LLVM LTO plugin.
+			"-flto",
+			"-Wl,--pack-dyn-relocs",
+			"-Wl,--use-android-relr-tags",
+			"-Wl,-plugin-opt",
+			"-Wl,--thinlto-cache",
+		},
+	}
+
+	linkerBackends = []LinkerBackend{LldBackend, MoldBackend}
+)
+
+func init() {
+	pctx.SourcePathVariable("MoldBinDir", "prebuilts/mold/${HostPrebuiltTag}/bin")
+}
+
+func linkerBackendByName(name string) (LinkerBackend, bool) {
+	for _, backend := range linkerBackends {
+		if backend.Name == name {
+			return backend, true
+		}
+	}
+	return LinkerBackend{}, false
+}
+
+var linkerSelectionKey = android.NewOnceKey("linkerSelection")
+
+type linkerSelection struct {
+	backends map[string]LinkerBackend
+	err      error
+}
+
+// parseLinkerSelection parses the value of SOONG_LINKER into a map from "<os>" or "<os>_<arch>"
+// to the selected backend.
+func parseLinkerSelection(value string) (map[string]LinkerBackend, error) {
+	ret := make(map[string]LinkerBackend)
+	if value == "" {
+		return ret, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid entry %q in SOONG_LINKER, should be <os>[_<arch>]:<linker>", entry)
+		}
+		backend, ok := linkerBackendByName(parts[1])
+		if !ok {
+			return nil, fmt.Errorf("unknown linker %q in SOONG_LINKER", parts[1])
+		}
+		ret[parts[0]] = backend
+	}
+	return ret, nil
+}
+
+// SelectedLinkerBackend returns the linker backend selected by SOONG_LINKER for modules built
+// for os and arch.
+func SelectedLinkerBackend(config android.Config, os android.OsType, arch android.ArchType) (LinkerBackend, error) {
+	selection := config.Once(linkerSelectionKey, func() interface{} {
+		backends, err := parseLinkerSelection(config.Getenv("SOONG_LINKER"))
+		return linkerSelection{backends, err}
+	}).(linkerSelection)
+
+	if selection.err != nil {
+		return LldBackend, selection.err
+	}
+
+	backend, ok := selection.backends[os.Name+"_"+arch.Name]
+	if !ok {
+		backend, ok = selection.backends[os.Name]
+	}
+	if !ok {
+		return LldBackend, nil
+	}
+	if backend.HostOnly && os.Class == android.Device {
+		return LldBackend, fmt.Errorf("linker %q selected by SOONG_LINKER is only supported for host modules", backend.Name)
+	}
+	return backend, nil
+}
+
+// BinaryPath returns the path to the backend's linker, or an invalid path if the linker comes
+// with clang or is missing from the source tree.
+func (backend LinkerBackend) BinaryPath(ctx android.PathContext) android.OptionalPath {
+	if backend.Binary == "" {
+		return android.OptionalPath{}
+	}
+	return android.ExistentPathForSource(ctx, backend.PrebuiltDir, ctx.Config().PrebuiltOS(), "bin", backend.Binary)
+}
+
+// AcceptsFlags returns whether the backend accepts all of flags.
+func (backend LinkerBackend) AcceptsFlags(flags []string) bool {
+	for _, flag := range flags {
+		if android.HasAnyPrefix(flag, backend.UnsupportedFlagPrefixes) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSelectedLinkerBackend(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      string
+		os       android.OsType
+		arch     android.ArchType
+		expected string
+		err      string
+	}{
+		{
+			name:     "default",
+			os:       android.BuildOs,
+			arch:     android.X86_64,
+			expected: "lld",
+		},
+		{
+			name:     "os",
+			env:      "linux_glibc:mold",
+			os:       android.Linux,
+			arch:     android.X86_64,
+			expected: "mold",
+		},
+		{
+			name:     "arch overrides os",
+			env:      "linux_glibc:mold,linux_glibc_x86:lld",
+			os:       android.Linux,
+			arch:     android.X86,
+			expected: "lld",
+		},
+		{
+			name:     "other os",
+			env:      "linux_glibc:mold",
+			os:       android.Android,
+			arch:     android.Arm64,
+			expected: "lld",
+		},
+		{
+			name: "host only",
+			env:  "android:mold",
+			os:   android.Android,
+			arch: android.Arm64,
+			err:  `linker "mold" selected by SOONG_LINKER is only supported for host modules`,
+		},
+		{
+			name: "unknown linker",
+			env:  "linux_glibc:gold",
+			os:   android.Linux,
+			arch: android.X86_64,
+			err:  `unknown linker "gold"`,
+		},
+		{
+			name: "invalid entry",
+			env:  "mold",
+			os:   android.Linux,
+			arch: android.X86_64,
+			err:  `invalid entry "mold"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := android.TestConfig("out", map[string]string{"SOONG_LINKER": testCase.env}, "", nil)
+			backend, err := SelectedLinkerBackend(config, testCase.os, testCase.arch)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Errorf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if backend.Name != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, backend.Name)
+			}
+		})
+	}
+}
+
+func TestLinkerBackendAcceptsFlags(t *testing.T) {
+	if !MoldBackend.AcceptsFlags([]string{"-Wl,--gc-sections", "-fuse-ld=lld"}) {
+		t.Errorf("expected mold to accept plain flags")
+	}
+	if MoldBackend.AcceptsFlags([]string{"-Wl,--gc-sections", "-flto=thin"}) {
+		t.Errorf("expected mold to reject -flto=thin")
+	}
+	if !LldBackend.AcceptsFlags([]string{"-flto=thin", "-Wl,--pack-dyn-relocs=android+relr"}) {
+		t.Errorf("expected lld to accept all flags")
+	}
+}
@@ -15,8 +15,6 @@
 package config
 
 import (
-	"strings"
-
 	"android/soong/android"
 )
 
@@ -55,6 +53,12 @@ const (
 )
 
 func init() {
+	// No supported product builds for mips, only register the toolchain when it has been
+	// enabled with SOONG_MIPS_TOOLCHAINS=true.
+	if !android.SoongMipsToolchains {
+		return
+	}
+
 	pctx.StaticVariable("mips64GccVersion", mips64GccVersion)
 
 	pctx.SourcePathVariable("Mips64GccRoot",
@@ -63,16 +67,15 @@ func init() {
 	pctx.StaticVariable("Mips64IncludeFlags", bionicHeaders("mips"))
 
 	// Clang cflags
-	pctx.StaticVariable("Mips64ClangCflags", strings.Join(ClangFilterUnknownCflags(mips64ClangCflags), " "))
-	pctx.StaticVariable("Mips64ClangLdflags", strings.Join(ClangFilterUnknownCflags(mips64Ldflags), " "))
-	pctx.StaticVariable("Mips64ClangCppflags", strings.Join(ClangFilterUnknownCflags(mips64Cppflags), " "))
+	clangFilteredCflagsVariable("Mips64ClangCflags", mips64ClangCflags)
+	clangFilteredCflagsVariable("Mips64ClangLdflags", mips64Ldflags)
+	clangFilteredCflagsVariable("Mips64ClangCppflags", mips64Cppflags)
 
 	// Extended cflags
 
 	// Architecture variant cflags
 	for variant, cflags := range mips64ArchVariantCflags {
-		pctx.StaticVariable("Mips64"+variant+"VariantClangCflags",
-			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+		clangFilteredCflagsVariable("Mips64"+variant+"VariantClangCflags", cflags)
 	}
 }
 
@@ -143,5 +146,8 @@ func mips64ToolchainFactory(arch android.Arch) Toolchain {
 }
 
 func init() {
+	if !android.SoongMipsToolchains {
+		return
+	}
 	registerToolchainFactory(android.Android, android.Mips64, mips64ToolchainFactory)
 }
@@ -89,6 +89,12 @@ const (
 )
 
 func init() {
+	// No supported product builds for mips, only register the toolchain when it has been
+	// enabled with SOONG_MIPS_TOOLCHAINS=true.
+	if !android.SoongMipsToolchains {
+		return
+	}
+
 	pctx.StaticVariable("mipsGccVersion", mipsGccVersion)
 
 	pctx.SourcePathVariable("MipsGccRoot",
@@ -98,16 +104,15 @@ func init() {
 	pctx.StaticVariable("MipsIncludeFlags", bionicHeaders("mips"))
 
 	// Clang cflags
-	pctx.StaticVariable("MipsClangCflags", strings.Join(ClangFilterUnknownCflags(mipsClangCflags), " "))
-	pctx.StaticVariable("MipsClangLdflags", strings.Join(ClangFilterUnknownCflags(mipsLdflags), " "))
-	pctx.StaticVariable("MipsClangCppflags", strings.Join(ClangFilterUnknownCflags(mipsCppflags), " "))
+	clangFilteredCflagsVariable("MipsClangCflags", mipsClangCflags)
+	clangFilteredCflagsVariable("MipsClangLdflags", mipsLdflags)
+	clangFilteredCflagsVariable("MipsClangCppflags", mipsCppflags)
 
 	// Extended cflags
 
 	// Architecture variant cflags
 	for variant, cflags := range mipsArchVariantCflags {
-		pctx.StaticVariable("Mips"+variant+"VariantClangCflags",
-			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+		clangFilteredCflagsVariable("Mips"+variant+"VariantClangCflags", cflags)
 	}
 }
 
@@ -182,5 +187,8 @@ func mipsToolchainFactory(arch android.Arch) Toolchain {
 }
 
 func init() {
+	if !android.SoongMipsToolchains {
+		return
+	}
 	registerToolchainFactory(android.Android, android.Mips, mipsToolchainFactory)
 }
@@ -0,0 +1,105 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strconv"
+
+	"android/soong/android"
+)
+
+// linkerPacking holds the options for packing the dynamic relocations and hash tables of device
+// modules for an arch. Each option is gated on the first API level whose dynamic linker supports
+// it, so modules that only run on newer API levels automatically get smaller binaries.
+type linkerPacking struct {
+	// relrMinSdkVersion is the first API level that supports SHT_RELR relocations.
+	relrMinSdkVersion int
+
+	// androidRelocsMinSdkVersion is the first API level that supports android packed
+	// relocations.
+	androidRelocsMinSdkVersion int
+
+	// gnuHashMinSdkVersion is the first API level that supports the gnu hash style. Modules
+	// that run on older API levels are linked with both the gnu and sysv hash styles. Zero
+	// if the arch always uses the sysv hash style.
+	gnuHashMinSdkVersion int
+
+	// maxPageSize is the maximum page size to align segments to, or zero for the linker's
+	// default.
+	maxPageSize int
+}
+
+var linkerPackingConfigs = map[android.ArchType]linkerPacking{
+	android.Arm: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+		gnuHashMinSdkVersion:       23,
+	},
+	android.Arm64: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+		gnuHashMinSdkVersion:       23,
+		maxPageSize:                4096,
+	},
+	// The gnu hash style is not supported on MIPS architectures.
+	android.Mips: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+	},
+	android.Mips64: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+	},
+	android.Riscv64: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+		gnuHashMinSdkVersion:       23,
+		maxPageSize:                4096,
+	},
+	android.X86: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+		gnuHashMinSdkVersion:       23,
+	},
+	android.X86_64: {
+		relrMinSdkVersion:          28,
+		androidRelocsMinSdkVersion: 23,
+		gnuHashMinSdkVersion:       23,
+	},
+}
+
+// RelrMinSdkVersion returns the first API level that supports SHT_RELR relocations for arch.
+func RelrMinSdkVersion(arch android.ArchType) int {
+	return linkerPackingConfigs[arch].relrMinSdkVersion
+}
+
+// AndroidRelocsMinSdkVersion returns the first API level that supports android packed
+// relocations for arch.
+func AndroidRelocsMinSdkVersion(arch android.ArchType) int {
+	return linkerPackingConfigs[arch].androidRelocsMinSdkVersion
+}
+
+// GnuHashMinSdkVersion returns the first API level that supports the gnu hash style for arch, or
+// zero if arch always uses the sysv hash style.
+func GnuHashMinSdkVersion(arch android.ArchType) int {
+	return linkerPackingConfigs[arch].gnuHashMinSdkVersion
+}
+
+func maxPageSizeLdflags(arch android.ArchType) []string {
+	if size := linkerPackingConfigs[arch].maxPageSize; size != 0 {
+		return []string{"-Wl,-z,max-page-size=" + strconv.Itoa(size)}
+	}
+	return nil
+}
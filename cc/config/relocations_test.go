@@ -0,0 +1,44 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestGnuHashMinSdkVersion(t *testing.T) {
+	testCases := []struct {
+		arch     android.ArchType
+		expected int
+	}{
+		{android.Arm, 23},
+		{android.Arm64, 23},
+		{android.Mips, 0},
+		{android.Mips64, 0},
+		{android.Riscv64, 23},
+		{android.X86, 23},
+		{android.X86_64, 23},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.arch.String(), func(t *testing.T) {
+			if got := GnuHashMinSdkVersion(testCase.arch); got != testCase.expected {
+				t.Errorf("expected %d, got %d", testCase.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	riscv64Cflags = []string{
+		// Help catch common 32/64-bit errors.
+		"-Werror=implicit-function-declaration",
+		// Bionic implements TLS natively.
+		"-fno-emulated-tls",
+	}
+
+	riscv64Cppflags = []string{}
+
+	riscv64Ldflags = []string{
+		"-Wl,--hash-style=gnu",
+		"-Wl,-z,separate-code",
+	}
+
+	riscv64Lldflags = append(ClangFilterUnknownLldflags(riscv64Ldflags),
+		maxPageSizeLdflags(android.Riscv64)...)
+
+	riscv64ArchVariantCflags = map[string][]string{
+		"": []string{
+			"-march=rv64gc",
+		},
+	}
+)
+
+const (
+	riscv64GccVersion = "9.2"
+)
+
+func init() {
+	pctx.StaticVariable("riscv64GccVersion", riscv64GccVersion)
+
+	pctx.SourcePathVariable("Riscv64GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/riscv64/riscv64-linux-android-${riscv64GccVersion}")
+
+	pctx.StaticVariable("Riscv64Ldflags", strings.Join(riscv64Ldflags, " "))
+	pctx.StaticVariable("Riscv64Lldflags", strings.Join(riscv64Lldflags, " "))
+	pctx.StaticVariable("Riscv64IncludeFlags", bionicHeaders("riscv"))
+
+	// Clang cflags
+	clangFilteredCflagsVariable("Riscv64ClangCflags", riscv64Cflags)
+	clangFilteredCflagsVariable("Riscv64ClangLdflags", riscv64Ldflags)
+	clangFilteredCflagsVariable("Riscv64ClangLldflags", riscv64Lldflags)
+	clangFilteredCflagsVariable("Riscv64ClangCppflags", riscv64Cppflags)
+
+	// Architecture variant cflags
+	for variant, cflags := range riscv64ArchVariantCflags {
+		clangFilteredCflagsVariable("Riscv64"+variant+"VariantClangCflags", cflags)
+	}
+}
+
+type toolchainRiscv64 struct {
+	toolchain64Bit
+
+	toolchainClangCflags string
+}
+
+func (t *toolchainRiscv64) Name() string {
+	return "riscv64"
+}
+
+func (t *toolchainRiscv64) GccRoot() string {
+	return "${config.Riscv64GccRoot}"
+}
+
+func (t *toolchainRiscv64) GccTriple() string {
+	return "riscv64-linux-android"
+}
+
+func (t *toolchainRiscv64) GccVersion() string {
+	return riscv64GccVersion
+}
+
+func (t *toolchainRiscv64) IncludeFlags() string {
+	return "${config.Riscv64IncludeFlags}"
+}
+
+func (t *toolchainRiscv64) ClangTriple() string {
+	return t.GccTriple()
+}
+
+func (t *toolchainRiscv64) ToolchainClangCflags() string {
+	return t.toolchainClangCflags
+}
+
+func (t *toolchainRiscv64) ClangCflags() string {
+	return "${config.Riscv64ClangCflags}"
+}
+
+func (t *toolchainRiscv64) ClangCppflags() string {
+	return "${config.Riscv64ClangCppflags}"
+}
+
+func (t *toolchainRiscv64) ClangLdflags() string {
+	return "${config.Riscv64ClangLdflags}"
+}
+
+func (t *toolchainRiscv64) ClangLldflags() string {
+	return "${config.Riscv64ClangLldflags}"
+}
+
+func (toolchainRiscv64) LibclangRuntimeLibraryArch() string {
+	return "riscv64"
+}
+
+func riscv64ToolchainFactory(arch android.Arch) Toolchain {
+	return &toolchainRiscv64{
+		toolchainClangCflags: "${config.Riscv64" + arch.ArchVariant + "VariantClangCflags}",
+	}
+}
+
+func init() {
+	registerToolchainFactory(android.Android, android.Riscv64, riscv64ToolchainFactory)
+}
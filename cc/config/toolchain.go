@@ -30,6 +30,7 @@ func registerToolchainFactory(os android.OsType, arch android.ArchType, factory
 		toolchainFactories[os] = make(map[android.ArchType]toolchainFactory)
 	}
 	toolchainFactories[os][arch] = factory
+	registerToolchainOverrideVariables(os, arch)
 }
 
 func FindToolchain(os android.OsType, arch android.Arch) Toolchain {
@@ -37,7 +38,7 @@ func FindToolchain(os android.OsType, arch android.Arch) Toolchain {
 	if factory == nil {
 		panic(fmt.Errorf("Toolchain not found for %s arch %q", os.String(), arch.String()))
 	}
-	return factory(arch)
+	return toolchainWithOverrides{factory(arch), toolchainOverrideVarPrefix(os, arch.ArchType)}
 }
 
 type Toolchain interface {
@@ -187,14 +188,31 @@ func LibclangRuntimeLibrary(t Toolchain, library string) string {
 	return "libclang_rt." + library + "-" + arch + "-android"
 }
 
+// LibclangRuntimeLibraryPath returns the path to the static library of a clang runtime library
+// for the toolchain in the clang prebuilts, the same path as ${config.ClangAsanLibDir}/<library>.a.
+func LibclangRuntimeLibraryPath(ctx android.PathContext, t Toolchain, library string) android.Path {
+	config := ctx.Config()
+	return android.PathForSource(ctx, clangBase(config), "linux-x86", clangVersion(config),
+		"lib64", "clang", clangShortVersion(config), "lib", "linux",
+		LibclangRuntimeLibrary(t, library)+".a")
+}
+
 func BuiltinsRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "builtins")
 }
 
+func BuiltinsRuntimeLibraryPath(ctx android.PathContext, t Toolchain) android.Path {
+	return LibclangRuntimeLibraryPath(ctx, t, "builtins")
+}
+
 func AddressSanitizerRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "asan")
 }
 
+func AddressSanitizerRuntimeLibraryPath(ctx android.PathContext, t Toolchain) android.Path {
+	return LibclangRuntimeLibraryPath(ctx, t, "asan")
+}
+
 func HWAddressSanitizerRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "hwasan")
 }
@@ -211,6 +229,10 @@ func UndefinedBehaviorSanitizerMinimalRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "ubsan_minimal")
 }
 
+func UndefinedBehaviorSanitizerMinimalRuntimeLibraryPath(ctx android.PathContext, t Toolchain) android.Path {
+	return LibclangRuntimeLibraryPath(ctx, t, "ubsan_minimal")
+}
+
 func ThreadSanitizerRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "tsan")
 }
@@ -0,0 +1,302 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file implements loading extra toolchain flags from a checked-in json file pointed to by
+// the ToolchainConfig product variable, so that products can tune a toolchain without forking
+// cc/config. The file maps os and arch names to flags that are appended after the built-in
+// flags of that toolchain, for example:
+//
+//   {
+//     "android": {
+//       "arm64": {
+//         "Cflags": ["-mcpu=cortex-a76"],
+//         "Lldflags": ["-Wl,-z,max-page-size=16384"]
+//       }
+//     }
+//   }
+//
+// Board configs can also add flags to a toolchain through the cc_toolchain soong config
+// namespace, with variables named <os>_<arch>_<cflags|cppflags|ldflags|lldflags>, for example:
+//
+//   SOONG_CONFIG_NAMESPACES += cc_toolchain
+//   SOONG_CONFIG_cc_toolchain += android_arm64_lldflags
+//   SOONG_CONFIG_cc_toolchain_android_arm64_lldflags := -Wl,-z,max-page-size=16384
+//
+// These are appended after the flags from the toolchain config file.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+)
+
+type toolchainFlagsOverride struct {
+	Cflags   []string
+	Cppflags []string
+	Ldflags  []string
+	Lldflags []string
+}
+
+// toolchainConfig maps os names to arch names to the extra flags for that toolchain.
+type toolchainConfig map[string]map[string]toolchainFlagsOverride
+
+// parseToolchainConfig parses and validates the contents of a toolchain config file.
+func parseToolchainConfig(data []byte) (toolchainConfig, error) {
+	var config toolchainConfig
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	for osName, archs := range config {
+		os := toolchainOsByName(osName)
+		if os == android.NoOsType {
+			return nil, fmt.Errorf("unknown os %q", osName)
+		}
+		for archName, override := range archs {
+			if !hasToolchainFactory(os, archName) {
+				return nil, fmt.Errorf("no toolchain for os %q arch %q", osName, archName)
+			}
+			for _, flags := range [][]string{override.Cflags, override.Cppflags,
+				override.Ldflags, override.Lldflags} {
+				if err := checkToolchainConfigFlags(flags); err != nil {
+					return nil, fmt.Errorf("%s %s: %s", osName, archName, err)
+				}
+			}
+		}
+	}
+
+	return config, nil
+}
+
+const toolchainSoongConfigNamespace = "cc_toolchain"
+
+// parseToolchainSoongConfig parses and validates the variables in the cc_toolchain soong config
+// namespace.
+func parseToolchainSoongConfig(vars map[string]string) (toolchainConfig, error) {
+	config := make(toolchainConfig)
+
+	// Sort the names so that errors are reported deterministically.
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		i := strings.LastIndex(name, "_")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid variable %q, must be <os>_<arch>_<flags>", name)
+		}
+		target, kind := name[:i], name[i+1:]
+
+		os, arch, ok := toolchainByTargetName(target)
+		if !ok {
+			return nil, fmt.Errorf("variable %q: no toolchain for %q", name, target)
+		}
+
+		flags := strings.Fields(vars[name])
+		if err := checkToolchainConfigFlags(flags); err != nil {
+			return nil, fmt.Errorf("variable %q: %s", name, err)
+		}
+
+		if config[os.Name] == nil {
+			config[os.Name] = make(map[string]toolchainFlagsOverride)
+		}
+		override := config[os.Name][arch.Name]
+		switch kind {
+		case "cflags":
+			override.Cflags = flags
+		case "cppflags":
+			override.Cppflags = flags
+		case "ldflags":
+			override.Ldflags = flags
+		case "lldflags":
+			override.Lldflags = flags
+		default:
+			return nil, fmt.Errorf("variable %q: unknown flags %q, must be one of cflags, cppflags, ldflags or lldflags",
+				name, kind)
+		}
+		config[os.Name][arch.Name] = override
+	}
+
+	return config, nil
+}
+
+// toolchainByTargetName returns the os and arch of the toolchain named <os>_<arch>.
+func toolchainByTargetName(target string) (android.OsType, android.ArchType, bool) {
+	for os, archs := range toolchainFactories {
+		for arch := range archs {
+			if os.Name+"_"+arch.Name == target {
+				return os, arch, true
+			}
+		}
+	}
+	return android.NoOsType, android.ArchType{}, false
+}
+
+// merge appends the flags in other to the flags in config.
+func (config toolchainConfig) merge(other toolchainConfig) toolchainConfig {
+	if config == nil {
+		return other
+	}
+	for osName, archs := range other {
+		if config[osName] == nil {
+			config[osName] = make(map[string]toolchainFlagsOverride)
+		}
+		for archName, override := range archs {
+			existing := config[osName][archName]
+			existing.Cflags = append(existing.Cflags, override.Cflags...)
+			existing.Cppflags = append(existing.Cppflags, override.Cppflags...)
+			existing.Ldflags = append(existing.Ldflags, override.Ldflags...)
+			existing.Lldflags = append(existing.Lldflags, override.Lldflags...)
+			config[osName][archName] = existing
+		}
+	}
+	return config
+}
+
+func checkToolchainConfigFlags(flags []string) error {
+	for _, flag := range flags {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("flag %q must start with '-'", flag)
+		}
+		if strings.Contains(flag, "$") {
+			return fmt.Errorf("flag %q must not reference variables", flag)
+		}
+	}
+	return nil
+}
+
+func toolchainOsByName(name string) android.OsType {
+	for os := range toolchainFactories {
+		if os.Name == name {
+			return os
+		}
+	}
+	return android.NoOsType
+}
+
+func hasToolchainFactory(os android.OsType, archName string) bool {
+	for arch := range toolchainFactories[os] {
+		if arch.Name == archName {
+			return true
+		}
+	}
+	return false
+}
+
+var toolchainConfigKey = android.NewOnceKey("toolchainConfig")
+
+type loadedToolchainConfig struct {
+	config toolchainConfig
+	err    error
+}
+
+// toolchainOverrideFor returns the extra flags the product's toolchain config file and the
+// cc_toolchain soong config namespace set for the toolchain for os and arch.
+func toolchainOverrideFor(ctx android.PackageVarContext, os android.OsType,
+	arch android.ArchType) toolchainFlagsOverride {
+
+	loaded := ctx.Config().Once(toolchainConfigKey, func() interface{} {
+		var config toolchainConfig
+
+		data, err := ctx.Config().ToolchainConfig(ctx)
+		if err != nil {
+			return loadedToolchainConfig{nil, err}
+		}
+		if data != nil {
+			config, err = parseToolchainConfig(data)
+			if err != nil {
+				return loadedToolchainConfig{nil, fmt.Errorf("toolchain config: %s", err)}
+			}
+		}
+
+		soongConfig, err := parseToolchainSoongConfig(
+			ctx.Config().VendorConfigVariables(toolchainSoongConfigNamespace))
+		if err != nil {
+			return loadedToolchainConfig{nil, fmt.Errorf("soong config namespace %s: %s",
+				toolchainSoongConfigNamespace, err)}
+		}
+
+		return loadedToolchainConfig{config.merge(soongConfig), nil}
+	}).(loadedToolchainConfig)
+
+	if loaded.err != nil {
+		ctx.Errorf("%s", loaded.err)
+		return toolchainFlagsOverride{}
+	}
+	return loaded.config[os.Name][arch.Name]
+}
+
+func toolchainOverrideVarPrefix(os android.OsType, arch android.ArchType) string {
+	return "Toolchain" + os.Field + arch.Field + "Override"
+}
+
+// registerToolchainOverrideVariables declares the ninja variables that hold the extra flags for
+// the toolchain for os and arch.
+func registerToolchainOverrideVariables(os android.OsType, arch android.ArchType) {
+	prefix := toolchainOverrideVarPrefix(os, arch)
+
+	pctx.VariableFunc(prefix+"Cflags", func(ctx android.PackageVarContext) string {
+		return strings.Join(toolchainOverrideFor(ctx, os, arch).Cflags, " ")
+	})
+	pctx.VariableFunc(prefix+"Cppflags", func(ctx android.PackageVarContext) string {
+		return strings.Join(toolchainOverrideFor(ctx, os, arch).Cppflags, " ")
+	})
+	pctx.VariableFunc(prefix+"Ldflags", func(ctx android.PackageVarContext) string {
+		return strings.Join(toolchainOverrideFor(ctx, os, arch).Ldflags, " ")
+	})
+	pctx.VariableFunc(prefix+"Lldflags", func(ctx android.PackageVarContext) string {
+		return strings.Join(toolchainOverrideFor(ctx, os, arch).Lldflags, " ")
+	})
+}
+
+// toolchainWithOverrides appends the flags from the product's toolchain config file to the
+// built-in flags of a toolchain.
+type toolchainWithOverrides struct {
+	Toolchain
+	prefix string
+}
+
+func appendOverrideVariable(flags, variable string) string {
+	if flags == "" {
+		return "${config." + variable + "}"
+	}
+	return flags + " ${config." + variable + "}"
+}
+
+func (t toolchainWithOverrides) ClangCflags() string {
+	return appendOverrideVariable(t.Toolchain.ClangCflags(), t.prefix+"Cflags")
+}
+
+func (t toolchainWithOverrides) ClangCppflags() string {
+	return appendOverrideVariable(t.Toolchain.ClangCppflags(), t.prefix+"Cppflags")
+}
+
+func (t toolchainWithOverrides) ClangLdflags() string {
+	return appendOverrideVariable(t.Toolchain.ClangLdflags(), t.prefix+"Ldflags")
+}
+
+func (t toolchainWithOverrides) ClangLldflags() string {
+	return appendOverrideVariable(t.Toolchain.ClangLldflags(), t.prefix+"Lldflags")
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseToolchainConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected toolchainConfig
+		err      string
+	}{
+		{
+			name: "valid",
+			input: `{
+				"android": {
+					"arm64": {
+						"Cflags": ["-mcpu=cortex-a76"],
+						"Lldflags": ["-Wl,-z,max-page-size=16384"]
+					}
+				}
+			}`,
+			expected: toolchainConfig{
+				"android": {
+					"arm64": {
+						Cflags:   []string{"-mcpu=cortex-a76"},
+						Lldflags: []string{"-Wl,-z,max-page-size=16384"},
+					},
+				},
+			},
+		},
+		{
+			name:  "unknown os",
+			input: `{"plan9": {"x86_64": {}}}`,
+			err:   `unknown os "plan9"`,
+		},
+		{
+			name:  "unknown arch",
+			input: `{"darwin": {"arm": {}}}`,
+			err:   `no toolchain for os "darwin" arch "arm"`,
+		},
+		{
+			name:  "unknown field",
+			input: `{"android": {"arm64": {"Asflags": ["-foo"]}}}`,
+			err:   `unknown field "Asflags"`,
+		},
+		{
+			name:  "not a flag",
+			input: `{"android": {"arm64": {"Ldflags": ["libfoo.a"]}}}`,
+			err:   `flag "libfoo.a" must start with '-'`,
+		},
+		{
+			name:  "variable reference",
+			input: `{"android": {"arm64": {"Cflags": ["-I${ClangBase}"]}}}`,
+			err:   `flag "-I${ClangBase}" must not reference variables`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config, err := parseToolchainConfig([]byte(testCase.input))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Errorf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(config, testCase.expected) {
+				t.Errorf("expected %#v, got %#v", testCase.expected, config)
+			}
+		})
+	}
+}
+
+func TestParseToolchainSoongConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		vars     map[string]string
+		expected toolchainConfig
+		err      string
+	}{
+		{
+			name: "valid",
+			vars: map[string]string{
+				"android_arm64_lldflags":    "-Wl,-z,max-page-size=16384",
+				"linux_glibc_x86_64_cflags": "-march=x86-64-v2 -mtune=skylake",
+			},
+			expected: toolchainConfig{
+				"android": {
+					"arm64": {
+						Lldflags: []string{"-Wl,-z,max-page-size=16384"},
+					},
+				},
+				"linux_glibc": {
+					"x86_64": {
+						Cflags: []string{"-march=x86-64-v2", "-mtune=skylake"},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown toolchain",
+			vars: map[string]string{"plan9_x86_64_cflags": "-O3"},
+			err:  `variable "plan9_x86_64_cflags": no toolchain for "plan9_x86_64"`,
+		},
+		{
+			name: "unknown flags",
+			vars: map[string]string{"android_arm64_asflags": "-foo"},
+			err:  `unknown flags "asflags"`,
+		},
+		{
+			name: "not a flag",
+			vars: map[string]string{"android_arm64_ldflags": "libfoo.a"},
+			err:  `flag "libfoo.a" must start with '-'`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config, err := parseToolchainSoongConfig(testCase.vars)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Errorf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(config, testCase.expected) {
+				t.Errorf("expected %#v, got %#v", testCase.expected, config)
+			}
+		})
+	}
+}
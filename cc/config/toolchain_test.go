@@ -0,0 +1,181 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestLibclangRuntimeLibraryPath(t *testing.T) {
+	config := android.TestConfig("out", map[string]string{
+		"LLVM_PREBUILTS_VERSION": "clang-test",
+		"LLVM_RELEASE_VERSION":   "1.2.3",
+	}, "", nil)
+	ctx := android.PathContextForTesting(config)
+
+	arm64 := FindToolchain(android.Android, android.Arch{ArchType: android.Arm64, ArchVariant: "armv8-a"})
+	x86_64 := FindToolchain(android.Linux, android.Arch{ArchType: android.X86_64})
+
+	testCases := []struct {
+		name     string
+		path     android.Path
+		expected string
+	}{
+		{
+			name:     "device builtins",
+			path:     BuiltinsRuntimeLibraryPath(ctx, arm64),
+			expected: "prebuilts/clang/host/linux-x86/clang-test/lib64/clang/1.2.3/lib/linux/libclang_rt.builtins-aarch64-android.a",
+		},
+		{
+			name:     "device ubsan minimal",
+			path:     UndefinedBehaviorSanitizerMinimalRuntimeLibraryPath(ctx, arm64),
+			expected: "prebuilts/clang/host/linux-x86/clang-test/lib64/clang/1.2.3/lib/linux/libclang_rt.ubsan_minimal-aarch64-android.a",
+		},
+		{
+			name:     "host asan",
+			path:     AddressSanitizerRuntimeLibraryPath(ctx, x86_64),
+			expected: "prebuilts/clang/host/linux-x86/clang-test/lib64/clang/1.2.3/lib/linux/libclang_rt.asan-x86_64.a",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.path.String() != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, testCase.path.String())
+			}
+		})
+	}
+}
+
+func TestRiscv64Toolchain(t *testing.T) {
+	config := android.TestConfig("out", map[string]string{
+		"LLVM_PREBUILTS_VERSION": "clang-test",
+		"LLVM_RELEASE_VERSION":   "1.2.3",
+	}, "", nil)
+	ctx := android.PathContextForTesting(config)
+
+	riscv64 := FindToolchain(android.Android, android.Arch{ArchType: android.Riscv64})
+
+	if !riscv64.Is64Bit() {
+		t.Errorf("expected a 64-bit toolchain")
+	}
+	if got, expected := riscv64.ClangTriple(), "riscv64-linux-android"; got != expected {
+		t.Errorf("expected clang triple %q, got %q", expected, got)
+	}
+	if got, expected := riscv64.ToolchainClangCflags(), "${config.Riscv64VariantClangCflags}"; got != expected {
+		t.Errorf("expected toolchain cflags %q, got %q", expected, got)
+	}
+
+	expected := "prebuilts/clang/host/linux-x86/clang-test/lib64/clang/1.2.3/lib/linux/libclang_rt.builtins-riscv64-android.a"
+	if got := BuiltinsRuntimeLibraryPath(ctx, riscv64).String(); got != expected {
+		t.Errorf("expected builtins %q, got %q", expected, got)
+	}
+}
+
+func TestLinuxMuslToolchain(t *testing.T) {
+	config := android.TestConfig("out", map[string]string{
+		"LLVM_PREBUILTS_VERSION": "clang-test",
+		"LLVM_RELEASE_VERSION":   "1.2.3",
+	}, "", nil)
+	ctx := android.PathContextForTesting(config)
+
+	testCases := []struct {
+		arch     android.ArchType
+		triple   string
+		builtins string
+	}{
+		{android.X86, "i686-linux-musl", "libclang_rt.builtins-i386.a"},
+		{android.X86_64, "x86_64-linux-musl", "libclang_rt.builtins-x86_64.a"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.arch.String(), func(t *testing.T) {
+			toolchain := FindToolchain(android.LinuxMusl, android.Arch{ArchType: testCase.arch})
+
+			if toolchain.Bionic() {
+				t.Errorf("expected a non-bionic toolchain")
+			}
+			if got := toolchain.ClangTriple(); got != testCase.triple {
+				t.Errorf("expected clang triple %q, got %q", testCase.triple, got)
+			}
+			if got := BuiltinsRuntimeLibraryPath(ctx, toolchain).Base(); got != testCase.builtins {
+				t.Errorf("expected builtins %q, got %q", testCase.builtins, got)
+			}
+		})
+	}
+}
+
+func TestArm64Armv9CpuVariants(t *testing.T) {
+	for _, cpuVariant := range []string{"cortex-a510", "cortex-a710", "cortex-a715", "cortex-x2", "cortex-x3"} {
+		t.Run(cpuVariant, func(t *testing.T) {
+			toolchain := FindToolchain(android.Android, android.Arch{
+				ArchType:    android.Arm64,
+				ArchVariant: "armv9-a",
+				CpuVariant:  cpuVariant,
+			})
+
+			if got := toolchain.ToolchainClangCflags(); !strings.Contains(got, "${config.Arm64ClangCortexA510Cflags}") {
+				t.Errorf("expected the cortex-a510 cflags, got %q", got)
+			}
+			if got := toolchain.ClangLldflags(); strings.Contains(got, "--fix-cortex-a53-843419") {
+				t.Errorf("expected no cortex-a53 erratum workaround, got %q", got)
+			}
+		})
+	}
+
+	testCases := []struct {
+		version  string
+		expected string
+	}{
+		{"", "-mcpu=cortex-a55"},
+		{"14.0.0", "-mcpu=cortex-a510+nobf16"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run("clang "+testCase.version, func(t *testing.T) {
+			config := android.TestConfig("", map[string]string{"LLVM_RELEASE_VERSION": testCase.version}, "", nil)
+			got := clangVersionGatedCflags(config,
+				arm64ClangCpuVariantMinClangMajorVersion["cortex-a510"],
+				arm64ClangCpuVariantCflags["cortex-a510"],
+				arm64ClangCpuVariantFallbackCflags["cortex-a510"])
+			if got != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestX86ArchVariantClangFallback(t *testing.T) {
+	for variant, fallback := range x86ArchVariantClangFallback {
+		if _, ok := x86ArchVariantMinClangMajorVersion[variant]; !ok {
+			t.Errorf("arch variant %q has a fallback but no minimum clang version", variant)
+		}
+		for _, variantCflags := range []map[string][]string{x86ArchVariantCflags, x86_64ArchVariantCflags} {
+			if _, ok := variantCflags[fallback]; !ok {
+				t.Errorf("fallback %q of arch variant %q has no cflags", fallback, variant)
+			}
+		}
+	}
+
+	config := android.TestConfig("", nil, "", nil)
+	got := clangVersionGatedCflags(config, x86ArchVariantMinClangMajorVersion["alderlake"],
+		x86_64ArchVariantCflags["alderlake"], x86_64ArchVariantCflags["skylake"])
+	if expected := "-march=skylake"; got != expected {
+		t.Errorf("expected %q with the default clang, got %q", expected, got)
+	}
+}
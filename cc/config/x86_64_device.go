@@ -38,9 +38,18 @@ var (
 		"": []string{
 			"-march=x86-64",
 		},
+		"alderlake": []string{
+			"-march=alderlake",
+		},
 		"broadwell": []string{
 			"-march=broadwell",
 		},
+		"goldmont": []string{
+			"-march=goldmont",
+		},
+		"goldmont-plus": []string{
+			"-march=goldmont-plus",
+		},
 
 		"haswell": []string{
 			"-march=core-avx2",
@@ -60,6 +69,9 @@ var (
 		"stoneyridge": []string{
 			"-march=bdver4",
 		},
+		"tremont": []string{
+			"-march=tremont",
+		},
 	}
 
 	x86_64ArchFeatureCflags = map[string][]string{
@@ -78,6 +90,7 @@ var (
 
 		"popcnt": []string{"-mpopcnt"},
 		"aes_ni": []string{"-maes"},
+		"movbe":  []string{"-mmovbe"},
 	}
 )
 
@@ -106,10 +119,10 @@ func init() {
 	pctx.StaticVariable("X86_64IncludeFlags", bionicHeaders("x86"))
 
 	// Clang cflags
-	pctx.StaticVariable("X86_64ClangCflags", strings.Join(ClangFilterUnknownCflags(x86_64Cflags), " "))
-	pctx.StaticVariable("X86_64ClangLdflags", strings.Join(ClangFilterUnknownCflags(x86_64Ldflags), " "))
-	pctx.StaticVariable("X86_64ClangLldflags", strings.Join(ClangFilterUnknownCflags(x86_64Lldflags), " "))
-	pctx.StaticVariable("X86_64ClangCppflags", strings.Join(ClangFilterUnknownCflags(x86_64Cppflags), " "))
+	clangFilteredCflagsVariable("X86_64ClangCflags", x86_64Cflags)
+	clangFilteredCflagsVariable("X86_64ClangLdflags", x86_64Ldflags)
+	clangFilteredCflagsVariable("X86_64ClangLldflags", x86_64Lldflags)
+	clangFilteredCflagsVariable("X86_64ClangCppflags", x86_64Cppflags)
 
 	// Yasm flags
 	pctx.StaticVariable("X86_64YasmFlags", "-f elf64 -m amd64")
@@ -117,9 +130,8 @@ func init() {
 	// Extended cflags
 
 	// Architecture variant cflags
-	for variant, cflags := range x86_64ArchVariantCflags {
-		pctx.StaticVariable("X86_64"+variant+"VariantClangCflags",
-			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+	for variant := range x86_64ArchVariantCflags {
+		x86ArchVariantClangCflagsVariable("X86_64", variant, x86_64ArchVariantCflags)
 	}
 }
 
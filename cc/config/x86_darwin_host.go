@@ -50,12 +50,12 @@ var (
 		"-m64",
 	}
 
-	darwinClangCflags = append(ClangFilterUnknownCflags(darwinCflags), []string{
+	darwinClangCflags = append(darwinCflags, []string{
 		"-integrated-as",
 		"-fstack-protector-strong",
 	}...)
 
-	darwinClangLdflags = ClangFilterUnknownCflags(darwinLdflags)
+	darwinClangLdflags = darwinLdflags
 
 	darwinClangLldflags = ClangFilterUnknownLldflags(darwinClangLdflags)
 
@@ -114,9 +114,9 @@ func init() {
 
 	pctx.StaticVariable("DarwinGccTriple", "i686-apple-darwin11")
 
-	pctx.StaticVariable("DarwinClangCflags", strings.Join(darwinClangCflags, " "))
-	pctx.StaticVariable("DarwinClangLdflags", strings.Join(darwinClangLdflags, " "))
-	pctx.StaticVariable("DarwinClangLldflags", strings.Join(darwinClangLldflags, " "))
+	clangFilteredCflagsVariable("DarwinClangCflags", darwinClangCflags)
+	clangFilteredCflagsVariable("DarwinClangLdflags", darwinClangLdflags)
+	clangFilteredCflagsVariable("DarwinClangLldflags", darwinClangLldflags)
 
 	pctx.StaticVariable("DarwinYasmFlags", "-f macho -m amd64")
 }
@@ -51,10 +51,22 @@ var (
 			"-march=atom",
 			"-mfpmath=sse",
 		},
+		"alderlake": []string{
+			"-march=alderlake",
+			"-mfpmath=sse",
+		},
 		"broadwell": []string{
 			"-march=broadwell",
 			"-mfpmath=sse",
 		},
+		"goldmont": []string{
+			"-march=goldmont",
+			"-mfpmath=sse",
+		},
+		"goldmont-plus": []string{
+			"-march=goldmont-plus",
+			"-mfpmath=sse",
+		},
 		"haswell": []string{
 			"-march=core-avx2",
 			"-mfpmath=sse",
@@ -79,6 +91,22 @@ var (
 			"-march=bdver4",
 			"-mfpmath=sse",
 		},
+		"tremont": []string{
+			"-march=tremont",
+			"-mfpmath=sse",
+		},
+	}
+
+	// Arch variants that are only known to newer versions of clang, mapped to the first major
+	// version of clang that supports them.  Shared with x86_64.
+	x86ArchVariantMinClangMajorVersion = map[string]int{
+		"alderlake": 12,
+	}
+
+	// The closest arch variants that older versions of clang know, used in place of the ones
+	// in x86ArchVariantMinClangMajorVersion.  Shared with x86_64.
+	x86ArchVariantClangFallback = map[string]string{
+		"alderlake": "skylake",
 	}
 
 	x86ArchFeatureCflags = map[string][]string{
@@ -96,6 +124,7 @@ var (
 		// "avx512": []string{"-mavx512"}
 
 		"aes_ni": []string{"-maes"},
+		"movbe":  []string{"-mmovbe"},
 	}
 )
 
@@ -117,10 +146,10 @@ func init() {
 	pctx.StaticVariable("X86IncludeFlags", bionicHeaders("x86"))
 
 	// Clang cflags
-	pctx.StaticVariable("X86ClangCflags", strings.Join(ClangFilterUnknownCflags(x86ClangCflags), " "))
-	pctx.StaticVariable("X86ClangLdflags", strings.Join(ClangFilterUnknownCflags(x86Ldflags), " "))
-	pctx.StaticVariable("X86ClangLldflags", strings.Join(ClangFilterUnknownCflags(x86Lldflags), " "))
-	pctx.StaticVariable("X86ClangCppflags", strings.Join(ClangFilterUnknownCflags(x86Cppflags), " "))
+	clangFilteredCflagsVariable("X86ClangCflags", x86ClangCflags)
+	clangFilteredCflagsVariable("X86ClangLdflags", x86Ldflags)
+	clangFilteredCflagsVariable("X86ClangLldflags", x86Lldflags)
+	clangFilteredCflagsVariable("X86ClangCppflags", x86Cppflags)
 
 	// Yasm flags
 	pctx.StaticVariable("X86YasmFlags", "-f elf32 -m x86")
@@ -128,10 +157,23 @@ func init() {
 	// Extended cflags
 
 	// Architecture variant cflags
-	for variant, cflags := range x86ArchVariantCflags {
-		pctx.StaticVariable("X86"+variant+"VariantClangCflags",
-			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+	for variant := range x86ArchVariantCflags {
+		x86ArchVariantClangCflagsVariable("X86", variant, x86ArchVariantCflags)
+	}
+}
+
+// x86ArchVariantClangCflagsVariable defines the clang cflags variable of an x86 or x86_64 arch
+// variant.  Variants that the clang of the build doesn't support use the cflags of their
+// fallback variant.
+func x86ArchVariantClangCflagsVariable(prefix, variant string, variantCflags map[string][]string) {
+	name := prefix + variant + "VariantClangCflags"
+	minVersion, ok := x86ArchVariantMinClangMajorVersion[variant]
+	if !ok {
+		clangFilteredCflagsVariable(name, variantCflags[variant])
+		return
 	}
+	clangVersionGatedVariable(name, minVersion, variantCflags[variant],
+		variantCflags[x86ArchVariantClangFallback[variant]])
 }
 
 type toolchainX86 struct {
@@ -15,13 +15,11 @@
 package config
 
 import (
-	"strings"
-
 	"android/soong/android"
 )
 
 var (
-	linuxBionicCflags = ClangFilterUnknownCflags([]string{
+	linuxBionicCflags = []string{
 		"-fdiagnostics-color",
 
 		"-Wa,--noexecstack",
@@ -46,9 +44,9 @@ var (
 
 		// This is normally in ClangExtraTargetCflags, but this is considered host
 		"-nostdlibinc",
-	})
+	}
 
-	linuxBionicLdflags = ClangFilterUnknownCflags([]string{
+	linuxBionicLdflags = []string{
 		"-Wl,-z,noexecstack",
 		"-Wl,-z,relro",
 		"-Wl,-z,now",
@@ -60,15 +58,15 @@ var (
 
 		// Use the device gcc toolchain
 		"--gcc-toolchain=${LinuxBionicGccRoot}",
-	})
+	}
 
 	linuxBionicLldflags = ClangFilterUnknownLldflags(linuxBionicLdflags)
 )
 
 func init() {
-	pctx.StaticVariable("LinuxBionicCflags", strings.Join(linuxBionicCflags, " "))
-	pctx.StaticVariable("LinuxBionicLdflags", strings.Join(linuxBionicLdflags, " "))
-	pctx.StaticVariable("LinuxBionicLldflags", strings.Join(linuxBionicLldflags, " "))
+	clangFilteredCflagsVariable("LinuxBionicCflags", linuxBionicCflags)
+	clangFilteredCflagsVariable("LinuxBionicLdflags", linuxBionicLdflags)
+	clangFilteredCflagsVariable("LinuxBionicLldflags", linuxBionicLldflags)
 
 	pctx.StaticVariable("LinuxBionicIncludeFlags", bionicHeaders("x86"))
 
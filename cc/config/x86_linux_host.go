@@ -67,20 +67,20 @@ var (
 		"-m64",
 	}
 
-	linuxClangCflags = append(ClangFilterUnknownCflags(linuxCflags), []string{
+	linuxClangCflags = append(linuxCflags, []string{
 		"--gcc-toolchain=${LinuxGccRoot}",
 		"--sysroot ${LinuxGccRoot}/sysroot",
 		"-fstack-protector-strong",
 	}...)
 
-	linuxClangLdflags = append(ClangFilterUnknownCflags(linuxLdflags), []string{
+	linuxClangLdflags = append(linuxLdflags, []string{
 		"--gcc-toolchain=${LinuxGccRoot}",
 		"--sysroot ${LinuxGccRoot}/sysroot",
 	}...)
 
 	linuxClangLldflags = ClangFilterUnknownLldflags(linuxClangLdflags)
 
-	linuxX86ClangLdflags = append(ClangFilterUnknownCflags(linuxX86Ldflags), []string{
+	linuxX86ClangLdflags = append(linuxX86Ldflags, []string{
 		"-B${LinuxGccRoot}/lib/gcc/${LinuxGccTriple}/${LinuxGccVersion}/32",
 		"-L${LinuxGccRoot}/lib/gcc/${LinuxGccTriple}/${LinuxGccVersion}/32",
 		"-L${LinuxGccRoot}/${LinuxGccTriple}/lib32",
@@ -88,7 +88,7 @@ var (
 
 	linuxX86ClangLldflags = ClangFilterUnknownLldflags(linuxX86ClangLdflags)
 
-	linuxX8664ClangLdflags = append(ClangFilterUnknownCflags(linuxX8664Ldflags), []string{
+	linuxX8664ClangLdflags = append(linuxX8664Ldflags, []string{
 		"-B${LinuxGccRoot}/lib/gcc/${LinuxGccTriple}/${LinuxGccVersion}",
 		"-L${LinuxGccRoot}/lib/gcc/${LinuxGccTriple}/${LinuxGccVersion}",
 		"-L${LinuxGccRoot}/${LinuxGccTriple}/lib64",
@@ -130,18 +130,16 @@ func init() {
 
 	pctx.StaticVariable("LinuxGccTriple", "x86_64-linux")
 
-	pctx.StaticVariable("LinuxClangCflags", strings.Join(linuxClangCflags, " "))
-	pctx.StaticVariable("LinuxClangLdflags", strings.Join(linuxClangLdflags, " "))
-	pctx.StaticVariable("LinuxClangLldflags", strings.Join(linuxClangLldflags, " "))
-
-	pctx.StaticVariable("LinuxX86ClangCflags",
-		strings.Join(ClangFilterUnknownCflags(linuxX86Cflags), " "))
-	pctx.StaticVariable("LinuxX8664ClangCflags",
-		strings.Join(ClangFilterUnknownCflags(linuxX8664Cflags), " "))
-	pctx.StaticVariable("LinuxX86ClangLdflags", strings.Join(linuxX86ClangLdflags, " "))
-	pctx.StaticVariable("LinuxX86ClangLldflags", strings.Join(linuxX86ClangLldflags, " "))
-	pctx.StaticVariable("LinuxX8664ClangLdflags", strings.Join(linuxX8664ClangLdflags, " "))
-	pctx.StaticVariable("LinuxX8664ClangLldflags", strings.Join(linuxX8664ClangLldflags, " "))
+	clangFilteredCflagsVariable("LinuxClangCflags", linuxClangCflags)
+	clangFilteredCflagsVariable("LinuxClangLdflags", linuxClangLdflags)
+	clangFilteredCflagsVariable("LinuxClangLldflags", linuxClangLldflags)
+
+	clangFilteredCflagsVariable("LinuxX86ClangCflags", linuxX86Cflags)
+	clangFilteredCflagsVariable("LinuxX8664ClangCflags", linuxX8664Cflags)
+	clangFilteredCflagsVariable("LinuxX86ClangLdflags", linuxX86ClangLdflags)
+	clangFilteredCflagsVariable("LinuxX86ClangLldflags", linuxX86ClangLldflags)
+	clangFilteredCflagsVariable("LinuxX8664ClangLdflags", linuxX8664ClangLdflags)
+	clangFilteredCflagsVariable("LinuxX8664ClangLldflags", linuxX8664ClangLldflags)
 	// Yasm flags
 	pctx.StaticVariable("LinuxX86YasmFlags", "-f elf32 -m x86")
 	pctx.StaticVariable("LinuxX8664YasmFlags", "-f elf64 -m amd64")
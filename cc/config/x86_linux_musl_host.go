@@ -0,0 +1,272 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"android/soong/android"
+)
+
+var (
+	linuxMuslCflags = []string{
+		"-fdiagnostics-color",
+
+		"-Wa,--noexecstack",
+
+		"-fPIC",
+
+		"-U_FORTIFY_SOURCE",
+		"-D_FORTIFY_SOURCE=2",
+		"-fstack-protector-strong",
+
+		// Workaround differences in inttypes.h between host and target.
+		//See bug 12708004.
+		"-D__STDC_FORMAT_MACROS",
+		"-D__STDC_CONSTANT_MACROS",
+
+		// Don't pick up the build machine's glibc headers
+		"-nostdlibinc",
+	}
+
+	linuxMuslLdflags = []string{
+		"-Wl,-z,noexecstack",
+		"-Wl,-z,relro",
+		"-Wl,-z,now",
+		"-Wl,--build-id=md5",
+		"-Wl,--hash-style=gnu",
+		"-Wl,--no-undefined-version",
+	}
+
+	// Extended cflags
+	linuxMuslX86Cflags = []string{
+		"-msse3",
+		"-mfpmath=sse",
+		"-m32",
+		"-march=prescott",
+		"-D_FILE_OFFSET_BITS=64",
+		"-D_LARGEFILE_SOURCE=1",
+		"--gcc-toolchain=${LinuxMuslX86GccRoot}",
+		"--sysroot ${LinuxMuslX86GccRoot}/${LinuxMuslX86GccTriple}",
+		"-isystem ${LinuxMuslX86GccRoot}/${LinuxMuslX86GccTriple}/include",
+	}
+
+	linuxMuslX8664Cflags = []string{
+		"-m64",
+		"--gcc-toolchain=${LinuxMuslX8664GccRoot}",
+		"--sysroot ${LinuxMuslX8664GccRoot}/${LinuxMuslX8664GccTriple}",
+		"-isystem ${LinuxMuslX8664GccRoot}/${LinuxMuslX8664GccTriple}/include",
+	}
+
+	// The crt objects (crt1.o, crti.o, crtn.o) come from the musl sysroot, and crtbegin.o and
+	// crtend.o come from the gcc toolchain unless the arch uses compiler-rt, see
+	// HostCompilerRtLdflags.
+	linuxMuslX86Ldflags = []string{
+		"-m32",
+		"--gcc-toolchain=${LinuxMuslX86GccRoot}",
+		"--sysroot ${LinuxMuslX86GccRoot}/${LinuxMuslX86GccTriple}",
+		"-B${LinuxMuslX86GccRoot}/${LinuxMuslX86GccTriple}/lib",
+		"-B${LinuxMuslX86GccRoot}/lib/gcc/${LinuxMuslX86GccTriple}/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX86GccRoot}/lib/gcc/${LinuxMuslX86GccTriple}/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX86GccRoot}/${LinuxMuslX86GccTriple}/lib",
+	}
+
+	linuxMuslX8664Ldflags = []string{
+		"-m64",
+		"--gcc-toolchain=${LinuxMuslX8664GccRoot}",
+		"--sysroot ${LinuxMuslX8664GccRoot}/${LinuxMuslX8664GccTriple}",
+		"-B${LinuxMuslX8664GccRoot}/${LinuxMuslX8664GccTriple}/lib",
+		"-B${LinuxMuslX8664GccRoot}/lib/gcc/${LinuxMuslX8664GccTriple}/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX8664GccRoot}/lib/gcc/${LinuxMuslX8664GccTriple}/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX8664GccRoot}/${LinuxMuslX8664GccTriple}/lib",
+	}
+
+	linuxMuslClangCflags = linuxMuslCflags
+
+	linuxMuslClangLdflags = linuxMuslLdflags
+
+	linuxMuslClangLldflags = ClangFilterUnknownLldflags(linuxMuslClangLdflags)
+
+	linuxMuslX86ClangLdflags = linuxMuslX86Ldflags
+
+	linuxMuslX86ClangLldflags = ClangFilterUnknownLldflags(linuxMuslX86ClangLdflags)
+
+	linuxMuslX8664ClangLdflags = linuxMuslX8664Ldflags
+
+	linuxMuslX8664ClangLldflags = ClangFilterUnknownLldflags(linuxMuslX8664ClangLdflags)
+
+	linuxMuslAvailableLibraries = addPrefix([]string{
+		"c",
+		"dl",
+		"m",
+		"pthread",
+		"resolv",
+		"rt",
+		"util",
+	}, "-l")
+)
+
+const (
+	linuxMuslGccVersion = "9.2.0"
+)
+
+func init() {
+	pctx.StaticVariable("LinuxMuslGccVersion", linuxMuslGccVersion)
+
+	pctx.StaticVariable("LinuxMuslX86GccTriple", "i686-linux-musl")
+	pctx.StaticVariable("LinuxMuslX8664GccTriple", "x86_64-linux-musl")
+
+	pctx.SourcePathVariable("LinuxMuslX86GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/${LinuxMuslX86GccTriple}-${LinuxMuslGccVersion}")
+	pctx.SourcePathVariable("LinuxMuslX8664GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/${LinuxMuslX8664GccTriple}-${LinuxMuslGccVersion}")
+
+	clangFilteredCflagsVariable("LinuxMuslClangCflags", linuxMuslClangCflags)
+	clangFilteredCflagsVariable("LinuxMuslClangLdflags", linuxMuslClangLdflags)
+	clangFilteredCflagsVariable("LinuxMuslClangLldflags", linuxMuslClangLldflags)
+
+	clangFilteredCflagsVariable("LinuxMuslX86ClangCflags", linuxMuslX86Cflags)
+	clangFilteredCflagsVariable("LinuxMuslX8664ClangCflags", linuxMuslX8664Cflags)
+	clangFilteredCflagsVariable("LinuxMuslX86ClangLdflags", linuxMuslX86ClangLdflags)
+	clangFilteredCflagsVariable("LinuxMuslX86ClangLldflags", linuxMuslX86ClangLldflags)
+	clangFilteredCflagsVariable("LinuxMuslX8664ClangLdflags", linuxMuslX8664ClangLdflags)
+	clangFilteredCflagsVariable("LinuxMuslX8664ClangLldflags", linuxMuslX8664ClangLldflags)
+	// Yasm flags
+	pctx.StaticVariable("LinuxMuslX86YasmFlags", "-f elf32 -m x86")
+	pctx.StaticVariable("LinuxMuslX8664YasmFlags", "-f elf64 -m amd64")
+}
+
+type toolchainLinuxMusl struct {
+}
+
+type toolchainLinuxMuslX86 struct {
+	toolchain32Bit
+	toolchainLinuxMusl
+}
+
+type toolchainLinuxMuslX8664 struct {
+	toolchain64Bit
+	toolchainLinuxMusl
+}
+
+func (t *toolchainLinuxMuslX86) Name() string {
+	return "x86"
+}
+
+func (t *toolchainLinuxMuslX8664) Name() string {
+	return "x86_64"
+}
+
+func (t *toolchainLinuxMuslX86) GccRoot() string {
+	return "${config.LinuxMuslX86GccRoot}"
+}
+
+func (t *toolchainLinuxMuslX8664) GccRoot() string {
+	return "${config.LinuxMuslX8664GccRoot}"
+}
+
+func (t *toolchainLinuxMuslX86) GccTriple() string {
+	return "${config.LinuxMuslX86GccTriple}"
+}
+
+func (t *toolchainLinuxMuslX8664) GccTriple() string {
+	return "${config.LinuxMuslX8664GccTriple}"
+}
+
+func (t *toolchainLinuxMusl) GccVersion() string {
+	return linuxMuslGccVersion
+}
+
+func (t *toolchainLinuxMusl) IncludeFlags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX86) ClangTriple() string {
+	return "i686-linux-musl"
+}
+
+func (t *toolchainLinuxMuslX86) ClangCflags() string {
+	return "${config.LinuxMuslClangCflags} ${config.LinuxMuslX86ClangCflags}"
+}
+
+func (t *toolchainLinuxMuslX86) ClangCppflags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX8664) ClangTriple() string {
+	return "x86_64-linux-musl"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangCflags() string {
+	return "${config.LinuxMuslClangCflags} ${config.LinuxMuslX8664ClangCflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangCppflags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX86) ClangLdflags() string {
+	return "${config.LinuxMuslClangLdflags} ${config.LinuxMuslX86ClangLdflags}"
+}
+
+func (t *toolchainLinuxMuslX86) ClangLldflags() string {
+	return "${config.LinuxMuslClangLldflags} ${config.LinuxMuslX86ClangLldflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangLdflags() string {
+	return "${config.LinuxMuslClangLdflags} ${config.LinuxMuslX8664ClangLdflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangLldflags() string {
+	return "${config.LinuxMuslClangLldflags} ${config.LinuxMuslX8664ClangLldflags}"
+}
+
+func (t *toolchainLinuxMuslX86) YasmFlags() string {
+	return "${config.LinuxMuslX86YasmFlags}"
+}
+
+func (t *toolchainLinuxMuslX8664) YasmFlags() string {
+	return "${config.LinuxMuslX8664YasmFlags}"
+}
+
+func (toolchainLinuxMuslX86) LibclangRuntimeLibraryArch() string {
+	return "i386"
+}
+
+func (toolchainLinuxMuslX8664) LibclangRuntimeLibraryArch() string {
+	return "x86_64"
+}
+
+func (t *toolchainLinuxMusl) AvailableLibraries() []string {
+	return linuxMuslAvailableLibraries
+}
+
+func (t *toolchainLinuxMusl) Bionic() bool {
+	return false
+}
+
+var toolchainLinuxMuslX86Singleton Toolchain = &toolchainLinuxMuslX86{}
+var toolchainLinuxMuslX8664Singleton Toolchain = &toolchainLinuxMuslX8664{}
+
+func linuxMuslX86ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxMuslX86Singleton
+}
+
+func linuxMuslX8664ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxMuslX8664Singleton
+}
+
+func init() {
+	registerToolchainFactory(android.LinuxMusl, android.X86, linuxMuslX86ToolchainFactory)
+	registerToolchainFactory(android.LinuxMusl, android.X86_64, linuxMuslX8664ToolchainFactory)
+}
@@ -41,7 +41,7 @@ var (
 
 		"--sysroot ${WindowsGccRoot}/${WindowsGccTriple}",
 	}
-	windowsClangCflags = append(ClangFilterUnknownCflags(windowsCflags), []string{}...)
+	windowsClangCflags = append(windowsCflags, []string{}...)
 
 	windowsIncludeFlags = []string{
 		"-isystem ${WindowsGccRoot}/${WindowsGccTriple}/include",
@@ -61,7 +61,7 @@ var (
 	windowsLldflags = []string{
 		"-Wl,--Xlink=-Brepro", // Enable deterministic build
 	}
-	windowsClangLdflags  = append(ClangFilterUnknownCflags(windowsLdflags), []string{}...)
+	windowsClangLdflags  = append(windowsLdflags, []string{}...)
 	windowsClangLldflags = append(ClangFilterUnknownLldflags(windowsClangLdflags), windowsLldflags...)
 
 	windowsX86Cflags = []string{
@@ -78,7 +78,7 @@ var (
 		"-L${WindowsGccRoot}/${WindowsGccTriple}/lib32",
 		"-static-libgcc",
 	}
-	windowsX86ClangLdflags = append(ClangFilterUnknownCflags(windowsX86Ldflags), []string{
+	windowsX86ClangLdflags = append(windowsX86Ldflags, []string{
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/bin",
 		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3/32",
 		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3/32",
@@ -92,7 +92,7 @@ var (
 		"-Wl,--high-entropy-va",
 		"-static-libgcc",
 	}
-	windowsX8664ClangLdflags = append(ClangFilterUnknownCflags(windowsX8664Ldflags), []string{
+	windowsX8664ClangLdflags = append(windowsX8664Ldflags, []string{
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/bin",
 		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3",
 		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3",
@@ -131,19 +131,17 @@ func init() {
 
 	pctx.StaticVariable("WindowsGccTriple", "x86_64-w64-mingw32")
 
-	pctx.StaticVariable("WindowsClangCflags", strings.Join(windowsClangCflags, " "))
-	pctx.StaticVariable("WindowsClangLdflags", strings.Join(windowsClangLdflags, " "))
-	pctx.StaticVariable("WindowsClangLldflags", strings.Join(windowsClangLldflags, " "))
+	clangFilteredCflagsVariable("WindowsClangCflags", windowsClangCflags)
+	clangFilteredCflagsVariable("WindowsClangLdflags", windowsClangLdflags)
+	clangFilteredCflagsVariable("WindowsClangLldflags", windowsClangLldflags)
 	pctx.StaticVariable("WindowsClangCppflags", strings.Join(windowsClangCppflags, " "))
 
-	pctx.StaticVariable("WindowsX86ClangCflags",
-		strings.Join(ClangFilterUnknownCflags(windowsX86Cflags), " "))
-	pctx.StaticVariable("WindowsX8664ClangCflags",
-		strings.Join(ClangFilterUnknownCflags(windowsX8664Cflags), " "))
-	pctx.StaticVariable("WindowsX86ClangLdflags", strings.Join(windowsX86ClangLdflags, " "))
-	pctx.StaticVariable("WindowsX86ClangLldflags", strings.Join(windowsX86ClangLldflags, " "))
-	pctx.StaticVariable("WindowsX8664ClangLdflags", strings.Join(windowsX8664ClangLdflags, " "))
-	pctx.StaticVariable("WindowsX8664ClangLldflags", strings.Join(windowsX8664ClangLldflags, " "))
+	clangFilteredCflagsVariable("WindowsX86ClangCflags", windowsX86Cflags)
+	clangFilteredCflagsVariable("WindowsX8664ClangCflags", windowsX8664Cflags)
+	clangFilteredCflagsVariable("WindowsX86ClangLdflags", windowsX86ClangLdflags)
+	clangFilteredCflagsVariable("WindowsX86ClangLldflags", windowsX86ClangLldflags)
+	clangFilteredCflagsVariable("WindowsX8664ClangLdflags", windowsX8664ClangLdflags)
+	clangFilteredCflagsVariable("WindowsX8664ClangLldflags", windowsX8664ClangLldflags)
 	pctx.StaticVariable("WindowsX86ClangCppflags", strings.Join(windowsX86ClangCppflags, " "))
 	pctx.StaticVariable("WindowsX8664ClangCppflags", strings.Join(windowsX8664ClangCppflags, " "))
 
@@ -20,6 +20,7 @@ import (
 	"github.com/google/blueprint"
 
 	"android/soong/android"
+	"android/soong/cc/config"
 )
 
 type CoverageProperties struct {
@@ -69,6 +70,15 @@ func (cov *coverage) deps(ctx DepsContext, deps Deps) Deps {
 		ctx.AddVariationDependencies([]blueprint.Variation{
 			{Mutator: "link", Variation: "static"},
 		}, coverageDepTag, getClangProfileLibraryName(ctx))
+
+		// Link the profile runtime explicitly, the clang driver doesn't add it when linking
+		// with -nodefaultlibs.
+		if ctx.DeviceConfig().ClangCoverageEnabled() && (!ctx.static() || ctx.staticBinary()) {
+			runtimeLibrary := config.ClangCoverageRuntimeLibrary(ctx.toolchain())
+			if !android.InList(runtimeLibrary, deps.LateStaticLibs) {
+				deps.LateStaticLibs = append(deps.LateStaticLibs, runtimeLibrary)
+			}
+		}
 	}
 	return deps
 }
@@ -92,7 +102,8 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 			// flags that the module may use.
 			flags.Local.CFlags = append(flags.Local.CFlags, "-Wno-frame-larger-than=", "-O0")
 		} else if clangCoverage {
-			flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-fprofile-instr-generate", "-fcoverage-mapping")
+			flags.Local.CommonFlags = append(flags.Local.CommonFlags, "${config.ClangCoverageCommonFlags}")
+			flags.Local.CFlags = append(flags.Local.CFlags, "${config.ClangCoverageCflags}")
 		}
 	}
 
@@ -139,7 +150,7 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 
 			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--wrap,getenv")
 		} else if clangCoverage {
-			flags.Local.LdFlags = append(flags.Local.LdFlags, "-fprofile-instr-generate")
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "${config.ClangCoverageLdflags}")
 
 			coverage := ctx.GetDirectDepWithTag(getClangProfileLibraryName(ctx), coverageDepTag).(*Module)
 			deps.WholeStaticLibs = append(deps.WholeStaticLibs, coverage.OutputFile().Path())
@@ -163,6 +174,8 @@ func (cov *coverage) begin(ctx BaseModuleContext) {
 		// Just turn off for now.
 	} else if !ctx.nativeCoverage() {
 		// Native coverage is not supported for this module type.
+	} else if ctx.DeviceConfig().ClangCoverageEnabled() && config.ClangCoverageRuntimeLibrary(ctx.toolchain()) == "" {
+		// There is no profile runtime for this architecture.
 	} else {
 		// Check if Native_coverage is set to false.  This property defaults to true.
 		needCoverageVariant = BoolDefault(cov.Properties.Native_coverage, true)
@@ -176,6 +189,9 @@ func (cov *coverage) begin(ctx BaseModuleContext) {
 		if needCoverageVariant {
 			// Coverage variant is actually built with coverage if enabled for its module path
 			needCoverageBuild = ctx.DeviceConfig().NativeCoverageEnabledForPath(ctx.ModuleDir())
+			if ctx.DeviceConfig().ClangCoverageEnabled() && config.ClangCoverageExcludedForPath(ctx.ModuleDir()) {
+				needCoverageBuild = false
+			}
 		}
 	}
 
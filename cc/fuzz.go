@@ -55,6 +55,9 @@ type FuzzProperties struct {
 	Data []string `android:"path"`
 	// Optional dictionary to be installed to the fuzz target's output directory.
 	Dictionary *string `android:"path"`
+	// Optional libFuzzer options file to be installed to the fuzz target's output
+	// directory as <module name>.options.
+	Options *string `android:"path"`
 	// Config for running the target on fuzzing infrastructure.
 	Fuzz_config *FuzzConfig
 }
@@ -82,6 +85,7 @@ type fuzzBinary struct {
 
 	Properties            FuzzProperties
 	dictionary            android.Path
+	options               android.Path
 	corpus                android.Paths
 	corpusIntermediateDir android.Path
 	config                android.Path
@@ -206,6 +210,15 @@ func (fuzz *fuzzBinary) install(ctx ModuleContext, file android.Path) {
 	fuzz.binaryDecorator.baseInstaller.install(ctx, file)
 
 	fuzz.corpus = android.PathsForModuleSrc(ctx, fuzz.Properties.Corpus)
+	// The corpus is flattened into a single directory, so the seed files must have unique names.
+	corpusNames := make(map[string]android.Path)
+	for _, entry := range fuzz.corpus {
+		if existing, exists := corpusNames[entry.Base()]; exists {
+			ctx.PropertyErrorf("corpus", "Corpus file %q has the same name as %q",
+				entry.String(), existing.String())
+		}
+		corpusNames[entry.Base()] = entry
+	}
 	builder := android.NewRuleBuilder()
 	intermediateDir := android.PathForModuleOut(ctx, "corpus")
 	for _, entry := range fuzz.corpus {
@@ -236,6 +249,24 @@ func (fuzz *fuzzBinary) install(ctx ModuleContext, file android.Path) {
 		}
 	}
 
+	if fuzz.Properties.Options != nil {
+		options := android.PathForModuleSrc(ctx, *fuzz.Properties.Options)
+		if options.Ext() != ".options" {
+			ctx.PropertyErrorf("options",
+				"Fuzzer options file %q does not have '.options' extension",
+				options.String())
+		}
+		// libFuzzer infrastructure finds the options file by the name of the fuzz target.
+		optionsPath := android.PathForModuleOut(ctx, "options", ctx.ModuleName()+".options")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Cp,
+			Description: "fuzzer options",
+			Input:       options,
+			Output:      optionsPath,
+		})
+		fuzz.options = optionsPath
+	}
+
 	if fuzz.Properties.Fuzz_config != nil {
 		configPath := android.PathForModuleOut(ctx, "config").Join(ctx, "config.json")
 		ctx.Build(pctx, android.BuildParams{
@@ -437,6 +468,11 @@ func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 			files = append(files, fileToZip{fuzzModule.dictionary, ""})
 		}
 
+		// The libFuzzer options.
+		if fuzzModule.options != nil {
+			files = append(files, fileToZip{fuzzModule.options, ""})
+		}
+
 		// Additional fuzz config.
 		if fuzzModule.config != nil {
 			files = append(files, fileToZip{fuzzModule.config, ""})
@@ -16,6 +16,7 @@ package cc
 
 import (
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -34,9 +35,9 @@ func init() {
 var (
 	lex = pctx.AndroidStaticRule("lex",
 		blueprint.RuleParams{
-			Command:     "M4=$m4Cmd $lexCmd -o$out $in",
+			Command:     "M4=$m4Cmd $lexCmd $flags -o$out $in",
 			CommandDeps: []string{"$lexCmd", "$m4Cmd"},
-		})
+		}, "flags")
 
 	sysprop = pctx.AndroidStaticRule("sysprop",
 		blueprint.RuleParams{
@@ -54,10 +55,23 @@ var (
 		"windmcCmd")
 )
 
+// GenFlagsForProperties lists flags that are only used to generate code from a single source file.
+type GenFlagsForProperties struct {
+	// the source file, relative to the Blueprints file
+	Src *string
+
+	// list of flags that will be used for the source file in addition to the module-specific flags
+	Flags []string
+}
+
 type YaccProperties struct {
 	// list of module-specific flags that will be used for .y and .yy compiles
 	Flags []string
 
+	// list of flags that will only be used for a single .y or .yy file, for example to set a
+	// different symbol prefix for each parser in a module with multiple grammars
+	Flags_for []GenFlagsForProperties
+
 	// whether the yacc files will produce a location.hh file
 	Gen_location_hh *bool
 
@@ -65,8 +79,73 @@ type YaccProperties struct {
 	Gen_position_hh *bool
 }
 
+type LexProperties struct {
+	// list of module-specific flags that will be used for .l and .ll compiles
+	Flags []string
+
+	// list of flags that will only be used for a single .l or .ll file, for example to set a
+	// different symbol prefix for each scanner in a module with multiple grammars
+	Flags_for []GenFlagsForProperties
+}
+
+// genFlagsFor maps the paths of source files to the flags listed for them in a flags_for property.
+// Every listed file must be a source of the module variant, files that are only sources of some
+// variants must be listed in the matching arch or target properties.
+type genFlagsFor struct {
+	property string
+	entries  []*genFlagsForEntry
+	byPath   map[string]*genFlagsForEntry
+}
+
+type genFlagsForEntry struct {
+	src   string
+	flags []string
+	used  bool
+}
+
+func newGenFlagsFor(ctx android.ModuleContext, property string, props []GenFlagsForProperties) *genFlagsFor {
+	g := &genFlagsFor{
+		property: property,
+		byPath:   make(map[string]*genFlagsForEntry),
+	}
+	for _, p := range props {
+		src := String(p.Src)
+		if src == "" {
+			ctx.PropertyErrorf(property, "src must be set")
+			continue
+		}
+		path := android.PathForModuleSrc(ctx, src).String()
+		if _, exists := g.byPath[path]; exists {
+			ctx.PropertyErrorf(property, "%q is listed more than once", src)
+			continue
+		}
+		entry := &genFlagsForEntry{src: src, flags: p.Flags}
+		g.entries = append(g.entries, entry)
+		g.byPath[path] = entry
+	}
+	return g
+}
+
+// flagsForSource returns the flags listed for the source file.
+func (g *genFlagsFor) flagsForSource(src android.Path) []string {
+	if entry, ok := g.byPath[src.String()]; ok {
+		entry.used = true
+		return entry.flags
+	}
+	return nil
+}
+
+// reportUnused reports the listed files that are not sources of the module.
+func (g *genFlagsFor) reportUnused(ctx android.ModuleContext) {
+	for _, entry := range g.entries {
+		if !entry.used {
+			ctx.PropertyErrorf(g.property, "%q is not a source of the module", entry.src)
+		}
+	}
+}
+
 func genYacc(ctx android.ModuleContext, rule *android.RuleBuilder, yaccFile android.Path,
-	outFile android.ModuleGenPath, props *YaccProperties) (headerFiles android.Paths) {
+	outFile android.ModuleGenPath, props *YaccProperties, fileFlags []string) (headerFiles android.Paths) {
 
 	outDir := android.PathForModuleGen(ctx, "yacc")
 	headerFile := android.GenPathWithExt(ctx, "yacc", yaccFile, "h")
@@ -102,6 +181,7 @@ func genYacc(ctx android.ModuleContext, rule *android.RuleBuilder, yaccFile andr
 		PrebuiltBuildTool(ctx, "bison").
 		Flag("-d").
 		Flags(flags).
+		Flags(fileFlags).
 		FlagWithOutput("--defines=", headerFile).
 		Flag("-o").Output(outFile).Input(yaccFile)
 
@@ -109,7 +189,7 @@ func genYacc(ctx android.ModuleContext, rule *android.RuleBuilder, yaccFile andr
 }
 
 func genAidl(ctx android.ModuleContext, rule *android.RuleBuilder, aidlFile android.Path,
-	outFile, depFile android.ModuleGenPath, aidlFlags string) android.Paths {
+	outFile, depFile android.ModuleGenPath, aidlFlags string, aidlDeps android.Paths) android.Paths {
 
 	aidlPackage := strings.TrimSuffix(aidlFile.Rel(), aidlFile.Base())
 	baseName := strings.TrimSuffix(aidlFile.Base(), aidlFile.Ext())
@@ -138,6 +218,7 @@ func genAidl(ctx android.ModuleContext, rule *android.RuleBuilder, aidlFile andr
 		Flag("--ninja").
 		Flag(aidlFlags).
 		Input(aidlFile).
+		Implicits(aidlDeps).
 		OutputDir().
 		Output(outFile).
 		ImplicitOutputs(android.WritablePaths{
@@ -153,12 +234,111 @@ func genAidl(ctx android.ModuleContext, rule *android.RuleBuilder, aidlFile andr
 	}
 }
 
-func genLex(ctx android.ModuleContext, lexFile android.Path, outFile android.ModuleGenPath) {
+var aidlVersionRegexp = regexp.MustCompile(`^[1-9][0-9]*$`)
+
+// aidlInterfaceFlags adds the aidl flags for the imports, stability and version of the aidl
+// sources of a module.
+func aidlInterfaceFlags(ctx ModuleContext, flags Flags, properties *BaseCompilerProperties) Flags {
+	if len(properties.Aidl.Imports) > 0 {
+		imports := android.PathsForModuleSrc(ctx, properties.Aidl.Imports)
+		var importDirs []string
+		for _, imp := range imports {
+			if imp.Ext() != ".aidl" {
+				ctx.PropertyErrorf("aidl.imports", "%s is not an .aidl file", imp)
+				continue
+			}
+			// The package path of an imported file is its path relative to the directory it is
+			// imported from, like the package path of an aidl source in genAidl.
+			importDir := strings.TrimSuffix(strings.TrimSuffix(imp.String(), imp.Rel()), "/")
+			if importDir == "" {
+				importDir = "."
+			}
+			importDirs = append(importDirs, importDir)
+		}
+		for _, dir := range android.FirstUniqueStrings(importDirs) {
+			flags.aidlFlags = append(flags.aidlFlags, "-I"+dir)
+		}
+		flags.aidlDeps = append(flags.aidlDeps, imports...)
+	}
+
+	if Bool(properties.Aidl.Structured) {
+		flags.aidlFlags = append(flags.aidlFlags, "--structured")
+	}
+
+	if stability := String(properties.Aidl.Stability); stability != "" {
+		if stability != "vintf" {
+			ctx.PropertyErrorf("aidl.stability", "unsupported stability %q, must be \"vintf\"", stability)
+		} else if !Bool(properties.Aidl.Structured) {
+			ctx.PropertyErrorf("aidl.stability", "requires aidl.structured to be true")
+		} else {
+			flags.aidlFlags = append(flags.aidlFlags, "--stability="+stability)
+		}
+	}
+
+	if version := String(properties.Aidl.Version); version != "" {
+		if !aidlVersionRegexp.MatchString(version) {
+			ctx.PropertyErrorf("aidl.version", "invalid version %q, must be a positive integer", version)
+		} else {
+			flags.aidlFlags = append(flags.aidlFlags, "--version="+version)
+		}
+	}
+
+	return flags
+}
+
+// aidlFrozenSrcs replaces the .aidl sources of a module with the sources of the frozen version of
+// the interface selected by aidl.version, which are at the same package paths in
+// <aidl.api_dir>/<version>/.
+func aidlFrozenSrcs(ctx ModuleContext, srcs android.Paths, properties *BaseCompilerProperties) android.Paths {
+	version := String(properties.Aidl.Version)
+	if !aidlVersionRegexp.MatchString(version) {
+		// The invalid version is reported by aidlInterfaceFlags.
+		return srcs
+	}
+
+	apiDir := filepath.Join("aidl_api", ctx.ModuleName())
+	if properties.Aidl.Api_dir != nil {
+		apiDir = *properties.Aidl.Api_dir
+	}
+	if !android.ExistentPathForSource(ctx, ctx.ModuleDir(), apiDir, version).Valid() {
+		ctx.PropertyErrorf("aidl.version", "version %s is not frozen in %s",
+			version, filepath.Join(ctx.ModuleDir(), apiDir))
+		return srcs
+	}
+	versionDir := android.PathForSource(ctx, ctx.ModuleDir(), apiDir, version)
+
+	ret := make(android.Paths, 0, len(srcs))
+	for _, src := range srcs {
+		if src.Ext() != ".aidl" {
+			ret = append(ret, src)
+			continue
+		}
+		if !android.ExistentPathForSource(ctx, versionDir.String(), src.Rel()).Valid() {
+			ctx.PropertyErrorf("aidl.version", "%s is not in version %s in %s", src.Rel(), version, versionDir)
+			continue
+		}
+		ret = append(ret, versionDir.Join(ctx, src.Rel()))
+	}
+	return ret
+}
+
+func genLex(ctx android.ModuleContext, lexFile android.Path, outFile android.ModuleGenPath,
+	props *LexProperties, fileFlags []string) {
+
+	var flags []string
+	if props != nil {
+		flags = append(flags, props.Flags...)
+	}
+	flags = append(flags, fileFlags...)
+
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        lex,
 		Description: "lex " + lexFile.Rel(),
 		Output:      outFile,
 		Input:       lexFile,
+		Args: map[string]string{
+			"flags": strings.Join(flags, " "),
+		},
 	})
 }
 
@@ -211,9 +391,21 @@ func genSources(ctx android.ModuleContext, srcFiles android.Paths,
 
 	var deps android.Paths
 	var rsFiles android.Paths
+	// Sources generated in addition to the one that replaces a source file.
+	var extraSrcFiles android.Paths
 
 	var aidlRule *android.RuleBuilder
 
+	var yaccFlagsForProps, lexFlagsForProps []GenFlagsForProperties
+	if buildFlags.yacc != nil {
+		yaccFlagsForProps = buildFlags.yacc.Flags_for
+	}
+	if buildFlags.lex != nil {
+		lexFlagsForProps = buildFlags.lex.Flags_for
+	}
+	yaccFlagsFor := newGenFlagsFor(ctx, "yacc.flags_for", yaccFlagsForProps)
+	lexFlagsFor := newGenFlagsFor(ctx, "lex.flags_for", lexFlagsForProps)
+
 	var yaccRule_ *android.RuleBuilder
 	yaccRule := func() *android.RuleBuilder {
 		if yaccRule_ == nil {
@@ -227,23 +419,26 @@ func genSources(ctx android.ModuleContext, srcFiles android.Paths,
 		case ".y":
 			cFile := android.GenPathWithExt(ctx, "yacc", srcFile, "c")
 			srcFiles[i] = cFile
-			deps = append(deps, genYacc(ctx, yaccRule(), srcFile, cFile, buildFlags.yacc)...)
+			deps = append(deps, genYacc(ctx, yaccRule(), srcFile, cFile, buildFlags.yacc,
+				yaccFlagsFor.flagsForSource(srcFile))...)
 		case ".yy":
 			cppFile := android.GenPathWithExt(ctx, "yacc", srcFile, "cpp")
 			srcFiles[i] = cppFile
-			deps = append(deps, genYacc(ctx, yaccRule(), srcFile, cppFile, buildFlags.yacc)...)
+			deps = append(deps, genYacc(ctx, yaccRule(), srcFile, cppFile, buildFlags.yacc,
+				yaccFlagsFor.flagsForSource(srcFile))...)
 		case ".l":
 			cFile := android.GenPathWithExt(ctx, "lex", srcFile, "c")
 			srcFiles[i] = cFile
-			genLex(ctx, srcFile, cFile)
+			genLex(ctx, srcFile, cFile, buildFlags.lex, lexFlagsFor.flagsForSource(srcFile))
 		case ".ll":
 			cppFile := android.GenPathWithExt(ctx, "lex", srcFile, "cpp")
 			srcFiles[i] = cppFile
-			genLex(ctx, srcFile, cppFile)
+			genLex(ctx, srcFile, cppFile, buildFlags.lex, lexFlagsFor.flagsForSource(srcFile))
 		case ".proto":
-			ccFile, headerFile := genProto(ctx, srcFile, buildFlags)
-			srcFiles[i] = ccFile
-			deps = append(deps, headerFile)
+			ccFiles, headerFiles := genProto(ctx, srcFile, buildFlags)
+			srcFiles[i] = ccFiles[0]
+			extraSrcFiles = append(extraSrcFiles, ccFiles[1:]...)
+			deps = append(deps, headerFiles...)
 		case ".aidl":
 			if aidlRule == nil {
 				aidlRule = android.NewRuleBuilder().Sbox(android.PathForModuleGen(ctx, "aidl"))
@@ -251,7 +446,8 @@ func genSources(ctx android.ModuleContext, srcFiles android.Paths,
 			cppFile := android.GenPathWithExt(ctx, "aidl", srcFile, "cpp")
 			depFile := android.GenPathWithExt(ctx, "aidl", srcFile, "cpp.d")
 			srcFiles[i] = cppFile
-			deps = append(deps, genAidl(ctx, aidlRule, srcFile, cppFile, depFile, buildFlags.aidlFlags)...)
+			deps = append(deps, genAidl(ctx, aidlRule, srcFile, cppFile, depFile, buildFlags.aidlFlags,
+				buildFlags.aidlDeps)...)
 		case ".rscript", ".fs":
 			cppFile := rsGeneratedCppFile(ctx, srcFile)
 			rsFiles = append(rsFiles, srcFiles[i])
@@ -267,6 +463,9 @@ func genSources(ctx android.ModuleContext, srcFiles android.Paths,
 		}
 	}
 
+	yaccFlagsFor.reportUnused(ctx)
+	lexFlagsFor.reportUnused(ctx)
+
 	if aidlRule != nil {
 		aidlRule.Build(pctx, ctx, "aidl", "gen aidl")
 	}
@@ -279,5 +478,7 @@ func genSources(ctx android.ModuleContext, srcFiles android.Paths,
 		deps = append(deps, rsGenerateCpp(ctx, rsFiles, buildFlags.rsFlags)...)
 	}
 
+	srcFiles = append(srcFiles, extraSrcFiles...)
+
 	return srcFiles, deps
 }
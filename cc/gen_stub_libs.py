@@ -28,6 +28,7 @@ ALL_ARCHITECTURES = (
     'arm64',
     'mips',
     'mips64',
+    'riscv64',
     'x86',
     'x86_64',
 )
@@ -37,6 +38,10 @@ ALL_ARCHITECTURES = (
 FUTURE_API_LEVEL = 10000
 
 
+# The function that the stubs of typed stub libraries alias.
+TYPED_STUB_TARGET = '__typed_stub'
+
+
 def logger():
     """Return the main logger for this module."""
     return logging.getLogger(__name__)
@@ -108,7 +113,22 @@ def version_is_private(version):
     return version.endswith('_PRIVATE') or version.endswith('_PLATFORM')
 
 
-def should_omit_version(version, arch, api, llndk, apex):
+def symbol_in_variant(tags, llndk, apex, systemapi=True):
+    """Returns True if the symbol is included in the requested stub variant.
+
+    Symbols with neither the llndk nor the apex tag are included in every
+    variant, and symbols with either tag only in the variants named by their
+    tags. Symbols tagged systemapi are only omitted when systemapi is False,
+    so that the stubs of variants that don't opt out are unchanged.
+    """
+    if 'systemapi' in tags and not systemapi:
+        return False
+    if 'llndk' not in tags and 'apex' not in tags:
+        return True
+    return ('llndk' in tags and llndk) or ('apex' in tags and apex)
+
+
+def should_omit_version(version, arch, api, llndk, apex, systemapi=True):
     """Returns True if the version section should be ommitted.
 
     We want to omit any sections that do not have any symbols we'll have in the
@@ -120,11 +140,7 @@ def should_omit_version(version, arch, api, llndk, apex):
     if 'platform-only' in version.tags:
         return True
 
-    no_llndk_no_apex = 'llndk' not in version.tags and 'apex' not in version.tags
-    keep = no_llndk_no_apex or \
-           ('llndk' in version.tags and llndk) or \
-           ('apex' in version.tags and apex)
-    if not keep:
+    if not symbol_in_variant(version.tags, llndk, apex, systemapi):
         return True
     if not symbol_in_arch(version.tags, arch):
         return True
@@ -133,13 +149,9 @@ def should_omit_version(version, arch, api, llndk, apex):
     return False
 
 
-def should_omit_symbol(symbol, arch, api, llndk, apex):
+def should_omit_symbol(symbol, arch, api, llndk, apex, systemapi=True):
     """Returns True if the symbol should be omitted."""
-    no_llndk_no_apex = 'llndk' not in symbol.tags and 'apex' not in symbol.tags
-    keep = no_llndk_no_apex or \
-           ('llndk' in symbol.tags and llndk) or \
-           ('apex' in symbol.tags and apex)
-    if not keep:
+    if not symbol_in_variant(symbol.tags, llndk, apex, systemapi):
         return True
     if not symbol_in_arch(symbol.tags, arch):
         return True
@@ -250,13 +262,15 @@ class Symbol(object):
 
 class SymbolFileParser(object):
     """Parses NDK symbol files."""
-    def __init__(self, input_file, api_map, arch, api, llndk, apex):
+    def __init__(self, input_file, api_map, arch, api, llndk, apex,
+                 systemapi=True):
         self.input_file = input_file
         self.api_map = api_map
         self.arch = arch
         self.api = api
         self.llndk = llndk
         self.apex = apex
+        self.systemapi = systemapi
         self.current_line = None
 
     def parse(self):
@@ -284,11 +298,13 @@ class SymbolFileParser(object):
         symbol_names = set()
         multiply_defined_symbols = set()
         for version in versions:
-            if should_omit_version(version, self.arch, self.api, self.llndk, self.apex):
+            if should_omit_version(version, self.arch, self.api, self.llndk,
+                                   self.apex, self.systemapi):
                 continue
 
             for symbol in version.symbols:
-                if should_omit_symbol(symbol, self.arch, self.api, self.llndk, self.apex):
+                if should_omit_symbol(symbol, self.arch, self.api, self.llndk,
+                                      self.apex, self.systemapi):
                     continue
 
                 if symbol.name in symbol_names:
@@ -372,29 +388,63 @@ class SymbolFileParser(object):
 
 class Generator(object):
     """Output generator that writes stub source files and version scripts."""
-    def __init__(self, src_file, version_script, arch, api, llndk, apex):
+    def __init__(self, src_file, version_script, arch, api, llndk, apex,
+                 systemapi=True, headers=()):
         self.src_file = src_file
         self.version_script = version_script
         self.arch = arch
         self.api = api
         self.llndk = llndk
         self.apex = apex
+        self.systemapi = systemapi
+        self.headers = headers
 
     def write(self, versions):
         """Writes all symbol data to the output files."""
+        if self.headers:
+            for header in self.headers:
+                self.src_file.write('#include <{}>\n'.format(header))
+            self.src_file.write('void {}() {{}}\n'.format(TYPED_STUB_TARGET))
         for version in versions:
             self.write_version(version)
 
+    def write_symbol(self, symbol):
+        """Writes the stub definition of a single symbol.
+
+        When headers are given, the stub takes the type of the declaration from
+        the headers, so that the compiler rejects symbols that the headers no
+        longer declare.
+        """
+        weak = ''
+        if 'weak' in symbol.tags:
+            weak = '__attribute__((weak)) '
+
+        if self.headers:
+            if 'var' in symbol.tags:
+                self.src_file.write('{}__typeof__({}) {};\n'.format(
+                    weak, symbol.name, symbol.name))
+            else:
+                self.src_file.write(
+                    '{}extern __typeof__({}) {} '
+                    '__attribute__((alias("{}")));\n'.format(
+                        weak, symbol.name, symbol.name, TYPED_STUB_TARGET))
+        elif 'var' in symbol.tags:
+            self.src_file.write('{}int {} = 0;\n'.format(weak, symbol.name))
+        else:
+            self.src_file.write('{}void {}() {{}}\n'.format(weak, symbol.name))
+
     def write_version(self, version):
         """Writes a single version block's data to the output files."""
-        if should_omit_version(version, self.arch, self.api, self.llndk, self.apex):
+        if should_omit_version(version, self.arch, self.api, self.llndk,
+                               self.apex, self.systemapi):
             return
 
         section_versioned = symbol_versioned_in_api(version.tags, self.api)
         version_empty = True
         pruned_symbols = []
         for symbol in version.symbols:
-            if should_omit_symbol(symbol, self.arch, self.api, self.llndk, self.apex):
+            if should_omit_symbol(symbol, self.arch, self.api, self.llndk,
+                                  self.apex, self.systemapi):
                 continue
 
             if symbol_versioned_in_api(symbol.tags, self.api):
@@ -410,22 +460,26 @@ class Generator(object):
                 if section_versioned and emit_version:
                     self.version_script.write('        ' + symbol.name + ';\n')
 
-                weak = ''
-                if 'weak' in symbol.tags:
-                    weak = '__attribute__((weak)) '
-
-                if 'var' in symbol.tags:
-                    self.src_file.write('{}int {} = 0;\n'.format(
-                        weak, symbol.name))
-                else:
-                    self.src_file.write('{}void {}() {{}}\n'.format(
-                        weak, symbol.name))
+                self.write_symbol(symbol)
 
             if not version_empty and section_versioned:
                 base = '' if version.base is None else ' ' + version.base
                 self.version_script.write('}' + base + ';\n')
 
 
+def exported_symbols(versions, arch, api, llndk, apex, systemapi=True):
+    """Returns the sorted names of the symbols exported by the variant."""
+    names = []
+    for version in versions:
+        if should_omit_version(version, arch, api, llndk, apex, systemapi):
+            continue
+        for symbol in version.symbols:
+            if should_omit_symbol(symbol, arch, api, llndk, apex, systemapi):
+                continue
+            names.append(symbol.name)
+    return sorted(names)
+
+
 def decode_api_level(api, api_map):
     """Decodes the API level argument into the API level number.
 
@@ -459,10 +513,19 @@ def parse_args():
         '--llndk', action='store_true', help='Use the LLNDK variant.')
     parser.add_argument(
         '--apex', action='store_true', help='Use the APEX variant.')
+    parser.add_argument(
+        '--no-systemapi', action='store_false', dest='systemapi',
+        help='Omit symbols tagged systemapi.')
+    parser.add_argument(
+        '--include', action='append', default=[], dest='headers',
+        help='Header to take the stub prototypes from. May be repeated.')
 
     parser.add_argument(
         '--api-map', type=os.path.realpath, required=True,
         help='Path to the API level map JSON file.')
+    parser.add_argument(
+        '--symbol-list', type=os.path.realpath,
+        help='Path to output the list of exported symbols, one per line.')
 
     parser.add_argument(
         'symbol_file', type=os.path.realpath, help='Path to symbol file.')
@@ -493,16 +556,24 @@ def main():
     with open(args.symbol_file) as symbol_file:
         try:
             versions = SymbolFileParser(symbol_file, api_map, args.arch, api,
-                                        args.llndk, args.apex).parse()
+                                        args.llndk, args.apex,
+                                        args.systemapi).parse()
         except MultiplyDefinedSymbolError as ex:
             sys.exit('{}: error: {}'.format(args.symbol_file, ex))
 
     with open(args.stub_src, 'w') as src_file:
         with open(args.version_script, 'w') as version_file:
             generator = Generator(src_file, version_file, args.arch, api,
-                                  args.llndk, args.apex)
+                                  args.llndk, args.apex, args.systemapi,
+                                  args.headers)
             generator.write(versions)
 
+    if args.symbol_list:
+        with open(args.symbol_list, 'w') as symbol_list_file:
+            for name in exported_symbols(versions, args.arch, api, args.llndk,
+                                         args.apex, args.systemapi):
+                symbol_list_file.write(name + '\n')
+
 
 if __name__ == '__main__':
     main()
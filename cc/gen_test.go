@@ -18,6 +18,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"android/soong/android"
 )
 
 func TestGen(t *testing.T) {
@@ -69,4 +71,201 @@ func TestGen(t *testing.T) {
 
 	})
 
+	t.Run("versioned", func(t *testing.T) {
+		bp := `
+		filegroup {
+			name: "imports",
+			srcs: ["imports/a/IBar.aidl"],
+			path: "imports",
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: [
+				"foo.c",
+				"b.aidl",
+			],
+			aidl: {
+				imports: [":imports"],
+				structured: true,
+				stability: "vintf",
+				version: "2",
+			},
+		}`
+		config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+			"aidl_api/libfoo/2/b.aidl": nil,
+		})
+		ctx := testCcWithConfig(t, config)
+
+		aidl := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared").Rule("aidl")
+
+		aidlCommand := aidl.RuleParams.Command
+		for _, flag := range []string{"-Iimports", "--structured", "--stability=vintf", "--version=2"} {
+			if !strings.Contains(aidlCommand, flag) {
+				t.Errorf("aidl command for b.aidl should contain %q, but was %q", flag, aidlCommand)
+			}
+		}
+
+		if !inList("imports/a/IBar.aidl", aidl.Implicits.Strings()) {
+			t.Errorf("aidl rule should depend on imports/a/IBar.aidl, but implicits were %q", aidl.Implicits.Strings())
+		}
+
+		// The frozen version 2 of b.aidl is compiled instead of the current one.
+		if !inList("aidl_api/libfoo/2/b.aidl", aidl.Implicits.Strings()) {
+			t.Errorf("aidl rule should compile aidl_api/libfoo/2/b.aidl, but inputs were %q", aidl.Implicits.Strings())
+		}
+		if !strings.Contains(aidlCommand, "-Iaidl_api/libfoo/2") {
+			t.Errorf("aidl command for b.aidl should contain %q, but was %q", "-Iaidl_api/libfoo/2", aidlCommand)
+		}
+	})
+
+	t.Run("versioned errors", func(t *testing.T) {
+		testCcError(t, `aidl.stability: requires aidl.structured to be true`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["b.aidl"],
+			aidl: {
+				stability: "vintf",
+			},
+		}`)
+
+		testCcError(t, `aidl.stability: unsupported stability "local"`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["b.aidl"],
+			aidl: {
+				structured: true,
+				stability: "local",
+			},
+		}`)
+
+		testCcError(t, `aidl.version: version 1 is not frozen in aidl_api/libfoo`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["b.aidl"],
+			aidl: {
+				version: "1",
+			},
+		}`)
+
+		testCcError(t, `aidl.version: invalid version "0"`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["b.aidl"],
+			aidl: {
+				version: "0",
+			},
+		}`)
+	})
+
+}
+
+func TestGenFlagsFor(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: [
+				"a.y",
+				"b.y",
+				"a.l",
+				"b.l",
+			],
+			yacc: {
+				flags: ["-Wall"],
+				flags_for: [
+					{
+						src: "b.y",
+						flags: ["--name-prefix=b_"],
+					},
+				],
+			},
+			lex: {
+				flags: ["-8"],
+				flags_for: [
+					{
+						src: "b.l",
+						flags: ["--prefix=b_"],
+					},
+				],
+			},
+		}`)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared")
+
+	yaccCommand := libfoo.Rule("yacc").RuleParams.Command
+	if strings.Count(yaccCommand, "-Wall") != 2 {
+		t.Errorf("yacc command should contain \"-Wall\" for both files, but was %q", yaccCommand)
+	}
+	if strings.Count(yaccCommand, "--name-prefix=b_") != 1 {
+		t.Errorf("yacc command should contain \"--name-prefix=b_\" once, but was %q", yaccCommand)
+	}
+
+	testCases := []struct {
+		out   string
+		flags string
+	}{
+		{"a.c", "-8"},
+		{"b.c", "-8 --prefix=b_"},
+	}
+	for _, tc := range testCases {
+		lex := libfoo.Output("lex/" + tc.out)
+		if lex.Args["flags"] != tc.flags {
+			t.Errorf("expected lex flags %q for %s, got %q", tc.flags, tc.out, lex.Args["flags"])
+		}
+	}
+
+	// Files that are only sources of some variants are listed in the matching arch properties.
+	ctx = testCc(t, `
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["a.l"],
+			arch: {
+				arm: {
+					srcs: ["arm.l"],
+					lex: {
+						flags_for: [
+							{
+								src: "arm.l",
+								flags: ["--prefix=arm_"],
+							},
+						],
+					},
+				},
+			},
+		}`)
+
+	libbar := ctx.ModuleForTests("libbar", "android_arm_armv7-a-neon_shared")
+	if flags := libbar.Output("lex/arm.c").Args["flags"]; flags != "--prefix=arm_" {
+		t.Errorf("expected lex flags %q for arm.c, got %q", "--prefix=arm_", flags)
+	}
+
+	testCcError(t, `yacc.flags_for: "a.y" is listed more than once`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["a.y"],
+			yacc: {
+				flags_for: [
+					{
+						src: "a.y",
+					},
+					{
+						src: "a.y",
+					},
+				],
+			},
+		}`)
+
+	testCcError(t, `lex.flags_for: "b.l" is not a source of the module`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["a.l"],
+			lex: {
+				flags_for: [
+					{
+						src: "b.l",
+						flags: ["--prefix=b_"],
+					},
+				],
+			},
+		}`)
 }
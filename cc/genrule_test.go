@@ -76,3 +76,98 @@ func TestArchGenruleCmd(t *testing.T) {
 		t.Errorf(`want arm64 inputs %v, got %v`, expected, gen.Inputs.Strings())
 	}
 }
+
+func TestGeneratedHeaderExports(t *testing.T) {
+	bp := `
+		cc_genrule {
+			name: "gen_foo",
+			cmd: "touch $(out)",
+			out: ["include/foo/foo.h"],
+			export_headers: ["foo/foo.h"],
+		}
+
+		cc_genrule {
+			name: "gen_bar",
+			cmd: "touch $(out)",
+			out: ["bar.h"],
+			export_headers: ["bar.h"],
+		}
+
+		cc_genrule {
+			name: "gen_undeclared",
+			cmd: "touch $(out)",
+			out: ["baz.h"],
+		}
+	`
+
+	testCases := []struct {
+		name     string
+		lib      string
+		expected string
+	}{
+		{
+			name: "valid",
+			lib: `
+				generated_headers: ["gen_foo", "gen_undeclared"],
+				generated_header_exports: ["foo/foo.h"],
+			`,
+		},
+		{
+			name: "missing",
+			lib: `
+				generated_headers: ["gen_foo", "gen_undeclared"],
+				generated_header_exports: ["foo/foo.h", "baz.h"],
+			`,
+			expected: `generated_header_exports: "baz.h" is not in the export_headers of any module in generated_headers`,
+		},
+		{
+			name: "unused",
+			lib: `
+				generated_headers: ["gen_foo", "gen_bar"],
+				generated_header_exports: ["foo/foo.h"],
+			`,
+			expected: `generated_headers: module "gen_bar" does not export any header in generated_header_exports`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := TestConfig(buildDir, android.Android, nil, bp+`
+				cc_library_static {
+					name: "libfoo",
+					srcs: ["foo.c"],
+			`+tc.lib+`
+				}
+			`, nil)
+			ctx := CreateTestContext()
+			ctx.RegisterModuleType("cc_genrule", genRuleFactory)
+			ctx.Register(config)
+
+			_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+			android.FailIfErrored(t, errs)
+			_, errs = ctx.PrepareBuildActions(config)
+			if tc.expected != "" {
+				android.FailIfNoMatchingErrors(t, tc.expected, errs)
+				return
+			}
+			android.FailIfErrored(t, errs)
+
+			// The generated headers are built, and checked to be included by the sources, before
+			// the sources are compiled.
+			header := ctx.ModuleForTests("gen_foo", "android_arm64_armv8-a").Output("include/foo/foo.h").Output
+			libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+			check := libfoo.Rule("headerIncludedCheck")
+			if check.Args["header"] != "foo/foo.h" || !android.InList("foo.c", check.Inputs.Strings()) {
+				t.Errorf("expected foo.c to be checked for foo/foo.h, got %q in %q",
+					check.Args["header"], check.Inputs.Strings())
+			}
+			cc := libfoo.Rule("cc")
+			for _, dep := range []android.Path{header, check.Output} {
+				if !android.InList(dep.String(), cc.OrderOnly.Strings()) {
+					t.Errorf("expected %q in the order-only dependencies of the compile, got %q",
+						dep.String(), cc.OrderOnly.Strings())
+				}
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"sync"
 
 	"github.com/google/blueprint/pathtools"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/cc/config"
@@ -52,6 +53,11 @@ type LibraryProperties struct {
 		Export_proto_headers *bool
 	}
 
+	// export headers generated from all sources, including .aidl, .proto, .y, .yy and .mc
+	// sources, to modules that depend on this library.  Headers generated from .sysprop sources
+	// are always exported.
+	Export_generated_sources_headers *bool
+
 	Sysprop struct {
 		// Whether platform owns this sysprop library.
 		Platform *bool
@@ -59,6 +65,12 @@ type LibraryProperties struct {
 
 	Static_ndk_lib *bool
 
+	// Build the static library as a thin archive that references the object files instead of
+	// copying them, which saves disk I/O for large intermediate libraries.  Defaults to true if
+	// SOONG_THIN_ARCHIVES is set to true in the environment.  A full archive is always built if
+	// the static library is installed, copied to the dist directory or captured in a snapshot.
+	Thin_archive *bool
+
 	Stubs struct {
 		// Relative path to the symbol map. The symbol map provides the list of
 		// symbols that are exported for stubs variant of this library.
@@ -68,6 +80,9 @@ type LibraryProperties struct {
 		Versions []string
 	}
 
+	// Properties for the LLNDK stubs of this library. Only supported on shared libraries.
+	Llndk llndkLibraryProperties
+
 	// set the name of the output
 	Stem *string `android:"arch_variant"`
 
@@ -109,6 +124,26 @@ type LibraryProperties struct {
 
 	// Inject boringssl hash into the shared library.  This is only intended for use by external/boringssl.
 	Inject_bssl_hash *bool `android:"arch_variant"`
+
+	// Properties for generating the version script of the shared library from the sources, instead
+	// of maintaining it in version_script.  Declarations in the sources of the library and in
+	// generated_version_script.srcs that are annotated with the export macro are exported, all
+	// other symbols are hidden.  For example, with
+	//   #define EXPORT __attribute__((visibility("default")))
+	// only functions and variables declared with EXPORT are exported, the macro is ignored on type
+	// declarations.  Only symbols with C linkage are supported.  Assembly sources are not scanned,
+	// functions defined in assembly are only exported if they are declared with the macro in a
+	// header listed in generated_version_script.srcs.
+	Generated_version_script struct {
+		// Generate the version script from the sources.
+		Enabled *bool
+
+		// Name of the macro that annotates exported declarations.  Defaults to EXPORT.
+		Macro *string
+
+		// Additional files that declare exported symbols, usually the exported headers.
+		Srcs []string `android:"path"`
+	}
 }
 
 type StaticProperties struct {
@@ -165,6 +200,11 @@ type FlagExporterProperties struct {
 	// list of plain cc flags to be used for any module that links against this module.
 	Export_cflags []string  `android:"arch_variant"`
 
+	// a Clang module map file relative to the Blueprints file that describes the exported headers
+	// of this module as Clang modules.  It is passed with -fmodule-map-file to this module and any
+	// module that links against this module, and is used by modules compiled with clang_modules.
+	Export_module_map *string `android:"path,arch_variant"`
+
 	Target struct {
 		Vendor struct {
 			// list of exported include directories, like
@@ -193,13 +233,14 @@ func RegisterLibraryBuildComponents(ctx android.RegistrationContext) {
 // Specifying `host_supported: true` also creates a library that targets the
 // host.
 func LibraryFactory() android.Module {
-	module, _ := NewLibrary(android.HostAndDeviceSupported)
+	module, library := NewLibrary(android.HostAndDeviceSupported)
 	// Can be used as both a static and a shared library.
 	module.sdkMemberTypes = []android.SdkMemberType{
 		sharedLibrarySdkMemberType,
 		staticLibrarySdkMemberType,
 		staticAndSharedLibrarySdkMemberType,
 	}
+	addLlndkStubsHook(module, library)
 	return module.Init()
 }
 
@@ -208,6 +249,7 @@ func LibraryStaticFactory() android.Module {
 	module, library := NewLibrary(android.HostAndDeviceSupported)
 	library.BuildOnlyStatic()
 	module.sdkMemberTypes = []android.SdkMemberType{staticLibrarySdkMemberType}
+	addLlndkStubsHook(module, library)
 	return module.Init()
 }
 
@@ -216,6 +258,7 @@ func LibrarySharedFactory() android.Module {
 	module, library := NewLibrary(android.HostAndDeviceSupported)
 	library.BuildOnlyShared()
 	module.sdkMemberTypes = []android.SdkMemberType{sharedLibrarySdkMemberType}
+	addLlndkStubsHook(module, library)
 	return module.Init()
 }
 
@@ -225,6 +268,7 @@ func LibraryHostStaticFactory() android.Module {
 	module, library := NewLibrary(android.HostSupported)
 	library.BuildOnlyStatic()
 	module.sdkMemberTypes = []android.SdkMemberType{staticLibrarySdkMemberType}
+	addLlndkStubsHook(module, library)
 	return module.Init()
 }
 
@@ -233,6 +277,7 @@ func LibraryHostSharedFactory() android.Module {
 	module, library := NewLibrary(android.HostSupported)
 	library.BuildOnlyShared()
 	module.sdkMemberTypes = []android.SdkMemberType{sharedLibrarySdkMemberType}
+	addLlndkStubsHook(module, library)
 	return module.Init()
 }
 
@@ -263,6 +308,25 @@ func (f *flagExporter) exportExtraFlags(ctx ModuleContext) {
 	f.flags = append(f.flags, f.Properties.Export_cflags...)
 }
 
+// exportedModuleMap returns the Clang module map file set in export_module_map.
+func (f *flagExporter) exportedModuleMap(ctx ModuleContext) android.OptionalPath {
+	if f.Properties.Export_module_map == nil {
+		return android.OptionalPath{}
+	}
+	return android.OptionalPathForPath(android.PathForModuleSrc(ctx, *f.Properties.Export_module_map))
+}
+
+func (f *flagExporter) exportModuleMap(ctx ModuleContext) {
+	if moduleMap := f.exportedModuleMap(ctx); moduleMap.Valid() {
+		if moduleMap.Path().Ext() != ".modulemap" {
+			ctx.PropertyErrorf("export_module_map", "%q is not a .modulemap file", moduleMap)
+			return
+		}
+		f.flags = append(f.flags, "-fmodule-map-file="+moduleMap.String())
+		f.deps = append(f.deps, moduleMap.Path())
+	}
+}
+
 func (f *flagExporter) exportIncludesAsSystem(ctx ModuleContext) {
 	// all dirs are force exported as system
 	f.systemDirs = append(f.systemDirs, f.exportedIncludes(ctx)...)
@@ -346,6 +410,10 @@ type libraryDecorator struct {
 	// to be given
 	wholeStaticMissingDeps []string
 
+	// If we're used as a whole_static_lib, the whole_static_libs dependency paths to the static
+	// libraries whose objects we include
+	wholeStaticLibPaths map[string][]string
+
 	// For whole_static_libs
 	objects Objects
 
@@ -376,6 +444,9 @@ type libraryDecorator struct {
 
 	versionScriptPath android.ModuleGenPath
 
+	// Output of the check that the implementation defines every symbol in stubs.symbol_file
+	stubsSymbolsCheck android.OptionalPath
+
 	post_install_cmds []string
 
 	// If useCoreVariant is true, the vendor variant of a VNDK library is
@@ -563,6 +634,11 @@ func (library *libraryDecorator) compilerFlags(ctx ModuleContext, flags Flags, d
 		flags.Local.YasmFlags = append(flags.Local.YasmFlags, f)
 	}
 
+	if moduleMap := library.flagExporter.exportedModuleMap(ctx); moduleMap.Valid() {
+		flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-fmodule-map-file="+moduleMap.String())
+		flags.CFlagsDeps = append(flags.CFlagsDeps, moduleMap.Path())
+	}
+
 	flags = library.baseCompiler.compilerFlags(ctx, flags, deps)
 	if library.buildStubs() {
 		// Remove -include <file> when compiling stubs. Otherwise, the force included
@@ -599,7 +675,9 @@ func (library *libraryDecorator) classifySourceAbiDump(ctx ModuleContext) string
 	if ctx.isNdk() {
 		return "NDK"
 	}
-	if ctx.isLlndkPublic(ctx.Config()) {
+	// Both public and private LLNDK libraries are dumped, as vendor modules link against
+	// the stubs of either.
+	if ctx.isLlndk(ctx.Config()) {
 		return "LLNDK"
 	}
 	if ctx.useVndk() && ctx.isVndk() && !ctx.isVndkPrivate(ctx.Config()) {
@@ -617,6 +695,9 @@ func (library *libraryDecorator) classifySourceAbiDump(ctx ModuleContext) string
 			}
 		}
 	}
+	if ctx.inVendor() && ctx.isVendorAvailable() {
+		return "VENDOR"
+	}
 	if Bool(enabled) || ctx.hasStubsVariants() {
 		return "PLATFORM"
 	}
@@ -697,6 +778,7 @@ func (library *libraryDecorator) compile(ctx ModuleContext, flags Flags, deps Pa
 
 type libraryInterface interface {
 	getWholeStaticMissingDeps() []string
+	getWholeStaticLibPaths() map[string][]string
 	static() bool
 	shared() bool
 	objs() Objects
@@ -912,17 +994,54 @@ func (library *libraryDecorator) linkStatic(ctx ModuleContext,
 		}
 	}
 
+	builderFlags.thinArchive = library.thinArchive(ctx)
+
 	TransformObjToStaticLib(ctx, library.objects.objFiles, builderFlags, outputFile, objs.tidyFiles)
 
 	library.coverageOutputFile = TransformCoverageFilesToZip(ctx, library.objects, ctx.ModuleName())
 
 	library.wholeStaticMissingDeps = ctx.GetMissingDependencies()
+	library.wholeStaticLibPaths = deps.WholeStaticLibPaths
 
 	ctx.CheckbuildFile(outputFile)
 
 	return outputFile
 }
 
+// thinArchive returns true if the static library should be built as a thin archive.  Thin
+// archives can only be used in the tree that contains the object files, so a full archive is built
+// if the library is used outside of the build.
+func (library *libraryDecorator) thinArchive(ctx ModuleContext) bool {
+	if !proptools.BoolDefault(library.Properties.Thin_archive, ctx.Config().IsEnvTrue("SOONG_THIN_ARCHIVES")) {
+		return false
+	}
+	// Thin archives are not supported in the Darwin archive format.
+	if ctx.Darwin() {
+		return false
+	}
+	// The static library is installed to the NDK sysroot.
+	if Bool(library.Properties.Static_ndk_lib) {
+		return false
+	}
+	// The version symbol is injected into the archive, and the versioned archive is copied to the
+	// dist directory.
+	if Bool(library.baseLinker.Properties.Use_version_lib) {
+		return false
+	}
+	if m, ok := ctx.Module().(*Module); ok && (m.HasDist() || m.IsInAnySdk()) {
+		return false
+	}
+	// Variants that are exported to Make can be installed or copied to the dist directory by it.
+	if m, ok := ctx.Module().(*Module); ok && !m.Properties.HideFromMake && m.IsForPlatform() {
+		return false
+	}
+	// Static libraries of vendor variants are captured in the VNDK and vendor snapshots.
+	if ctx.useVndk() {
+		return false
+	}
+	return true
+}
+
 func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	flags Flags, deps PathDeps, objs Objects) android.Path {
 
@@ -960,6 +1079,11 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 		linkerScriptFlags := "-Wl,--version-script," + library.versionScriptPath.String()
 		flags.Local.LdFlags = append(flags.Local.LdFlags, linkerScriptFlags)
 		linkerDeps = append(linkerDeps, library.versionScriptPath)
+	} else if Bool(library.Properties.Generated_version_script.Enabled) {
+		if versionScript := library.generateVersionScript(ctx); versionScript != nil {
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--version-script,"+versionScript.String())
+			linkerDeps = append(linkerDeps, versionScript)
+		}
 	}
 
 	fileName := library.getLibName(ctx) + flags.Toolchain.ShlibSuffix()
@@ -992,6 +1116,11 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	}
 	library.unstrippedOutputFile = outputFile
 
+	if ctx.Device() && ctx.hasStubsVariants() && !library.buildStubs() &&
+		library.Properties.Stubs.Symbol_file != nil {
+		library.checkStubsSymbols(ctx, library.unstrippedOutputFile)
+	}
+
 	outputFile = maybeInjectBoringSSLHash(ctx, outputFile, library.Properties.Inject_bssl_hash, fileName)
 
 	if Bool(library.baseLinker.Properties.Use_version_lib) {
@@ -1065,13 +1194,25 @@ func (library *libraryDecorator) coverageOutputFilePath() android.OptionalPath {
 	return library.coverageOutputFile
 }
 
-func getRefAbiDumpFile(ctx ModuleContext, vndkVersion, fileName string) android.Path {
+// refAbiDumpDirName returns the name of the directory in prebuilts/abi-dumps that contains the
+// reference ABI dumps of the library.
+func refAbiDumpDirName(ctx ModuleContext) string {
 	// The logic must be consistent with classifySourceAbiDump.
-	isNdk := ctx.isNdk()
-	isLlndkOrVndk := ctx.isLlndkPublic(ctx.Config()) || (ctx.useVndk() && ctx.isVndk())
+	if ctx.isNdk() {
+		return "ndk"
+	} else if ctx.isLlndk(ctx.Config()) || (ctx.useVndk() && ctx.isVndk()) {
+		return "vndk"
+	} else if ctx.inVendor() && ctx.isVendorAvailable() {
+		return "vendor"
+	}
+	return "platform" // opt-in libs
+}
+
+func getRefAbiDumpFile(ctx ModuleContext, vndkVersion, fileName string) android.Path {
+	dirName := refAbiDumpDirName(ctx)
 
-	refAbiDumpTextFile := android.PathForVndkRefAbiDump(ctx, vndkVersion, fileName, isNdk, isLlndkOrVndk, false)
-	refAbiDumpGzipFile := android.PathForVndkRefAbiDump(ctx, vndkVersion, fileName, isNdk, isLlndkOrVndk, true)
+	refAbiDumpTextFile := android.PathForRefAbiDump(ctx, dirName, vndkVersion, fileName, false)
+	refAbiDumpGzipFile := android.PathForRefAbiDump(ctx, dirName, vndkVersion, fileName, true)
 
 	if refAbiDumpTextFile.Valid() {
 		if refAbiDumpGzipFile.Valid() {
@@ -1112,9 +1253,19 @@ func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objec
 		addLsdumpPath(library.classifySourceAbiDump(ctx) + ":" + library.sAbiOutputFile.String())
 
 		refAbiDumpFile := getRefAbiDumpFile(ctx, vndkVersion, fileName)
+		locked := abiLocked(ctx, refAbiDumpDirName(ctx), vndkVersion)
 		if refAbiDumpFile != nil {
+			// Differences in the ABI of vendor_available libraries are only errors once their ABI is
+			// locked for the release.
+			adviceOnly := ctx.inVendor() && ctx.isVendorAvailable() && !locked
 			library.sAbiDiff = SourceAbiDiff(ctx, library.sAbiOutputFile.Path(),
-				refAbiDumpFile, fileName, exportedHeaderFlags, ctx.isLlndk(ctx.Config()), ctx.isNdk(), ctx.isVndkExt())
+				refAbiDumpFile, fileName, exportedHeaderFlags, ctx.isLlndk(ctx.Config()), ctx.isNdk(), ctx.isVndkExt(),
+				adviceOnly)
+		} else if locked {
+			ctx.ModuleErrorf("ABI is locked in %s, but there is no reference ABI dump for %s. "+
+				"Please create it with: development/vndk/tools/header-checker/utils/create_reference_dumps.py -l %s",
+				android.PathForRefAbiLockFile(ctx, refAbiDumpDirName(ctx), vndkVersion), fileName,
+				ctx.baseModuleName())
 		}
 	}
 }
@@ -1132,13 +1283,16 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 
 	library.exportIncludes(ctx)
 	library.exportExtraFlags(ctx)
+	library.exportModuleMap(ctx)
 	library.reexportDirs(deps.ReexportedDirs...)
 	library.reexportSystemDirs(deps.ReexportedSystemDirs...)
 	library.reexportFlags(deps.ReexportedFlags...)
 	library.reexportDeps(deps.ReexportedDeps...)
 	library.addExportedGeneratedHeaders(deps.ReexportedGeneratedHeaders...)
 
-	if Bool(library.Properties.Aidl.Export_aidl_headers) {
+	exportGeneratedSourcesHeaders := Bool(library.Properties.Export_generated_sources_headers)
+
+	if Bool(library.Properties.Aidl.Export_aidl_headers) || exportGeneratedSourcesHeaders {
 		if library.baseCompiler.hasSrcExt(".aidl") {
 			dir := android.PathForModuleGen(ctx, "aidl")
 			library.reexportDirs(dir)
@@ -1149,7 +1303,7 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 		}
 	}
 
-	if Bool(library.Properties.Proto.Export_proto_headers) {
+	if Bool(library.Properties.Proto.Export_proto_headers) || exportGeneratedSourcesHeaders {
 		if library.baseCompiler.hasSrcExt(".proto") {
 			var includes android.Paths
 			if flags.proto.CanonicalPathFromRoot {
@@ -1164,6 +1318,17 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 		}
 	}
 
+	if exportGeneratedSourcesHeaders {
+		if library.baseCompiler.hasSrcExt(".y") || library.baseCompiler.hasSrcExt(".yy") {
+			library.reexportDirs(android.PathForModuleGen(ctx, "yacc", ctx.ModuleDir()))
+		}
+		if library.baseCompiler.hasSrcExt(".mc") {
+			library.reexportDirs(android.PathForModuleGen(ctx, "windmc", ctx.ModuleDir()))
+		}
+		library.reexportDeps(library.baseCompiler.generatedSourcesHeaders...)
+		library.addExportedGeneratedHeaders(library.baseCompiler.generatedSourcesHeaders...)
+	}
+
 	if library.baseCompiler.hasSrcExt(".sysprop") {
 		dir := android.PathForModuleGen(ctx, "sysprop", "include")
 		if library.Properties.Sysprop.Platform != nil {
@@ -1202,6 +1367,10 @@ func (library *libraryDecorator) getWholeStaticMissingDeps() []string {
 	return append([]string(nil), library.wholeStaticMissingDeps...)
 }
 
+func (library *libraryDecorator) getWholeStaticLibPaths() map[string][]string {
+	return library.wholeStaticLibPaths
+}
+
 func (library *libraryDecorator) objs() Objects {
 	return library.objects
 }
@@ -1313,6 +1482,16 @@ func (library *libraryDecorator) header() bool {
 	return !library.static() && !library.shared()
 }
 
+func (library *libraryDecorator) moduleInfoJSON(ctx ModuleContext, info *android.ModuleInfoJSON) {
+	if library.static() {
+		info.Class = append(info.Class, "STATIC_LIBRARIES")
+	} else if library.shared() {
+		info.Class = append(info.Class, "SHARED_LIBRARIES")
+	} else {
+		info.Class = append(info.Class, "HEADER_LIBRARIES")
+	}
+}
+
 func (library *libraryDecorator) setStatic() {
 	library.MutatedProperties.VariantIsStatic = true
 	library.MutatedProperties.VariantIsShared = false
@@ -1336,6 +1515,68 @@ func (library *libraryDecorator) HeaderOnly() {
 	library.MutatedProperties.BuildStatic = false
 }
 
+// versionScriptSourceExts are the extensions of the files that are scanned for exported
+// declarations by generateVersionScript.  Assembly sources are not scanned, the symbols they
+// define are hidden unless they are declared in generated_version_script.srcs.
+var versionScriptSourceExts = []string{".c", ".cc", ".cpp", ".cxx", ".h", ".hh", ".hpp", ".hxx"}
+
+// generateVersionScript generates a version script that exports the declarations annotated with
+// generated_version_script.macro in the sources of the library.
+func (library *libraryDecorator) generateVersionScript(ctx ModuleContext) android.Path {
+	if ctx.Darwin() {
+		ctx.PropertyErrorf("generated_version_script.enabled", "Not supported on Darwin")
+		return nil
+	}
+	if library.baseLinker.Properties.Version_script != nil {
+		ctx.PropertyErrorf("generated_version_script.enabled", "cannot be used with version_script")
+		return nil
+	}
+
+	props := library.Properties.Generated_version_script
+	macro := "EXPORT"
+	if props.Macro != nil {
+		macro = *props.Macro
+	}
+
+	srcs := append([]string(nil), library.baseCompiler.Properties.Srcs...)
+	srcs = append(srcs, library.baseCompiler.Properties.OriginalSrcs...)
+	srcs = append(srcs, library.SharedProperties.Shared.Srcs...)
+	var inputs android.Paths
+	for _, src := range android.PathsForModuleSrcExcludes(ctx, srcs, library.baseCompiler.Properties.Exclude_srcs) {
+		if android.InList(src.Ext(), versionScriptSourceExts) {
+			inputs = append(inputs, src)
+		}
+	}
+	inputs = append(inputs, android.PathsForModuleSrc(ctx, props.Srcs)...)
+
+	libName := library.getLibName(ctx)
+	versionScript := android.PathForModuleGen(ctx, "version_script", libName+".map.txt")
+
+	rule := android.NewRuleBuilder()
+	rule.Command().
+		BuiltTool(ctx, "gen_version_script").
+		FlagWithArg("-lib ", libName).
+		FlagWithArg("-macro ", macro).
+		FlagWithOutput("-o ", versionScript).
+		Inputs(inputs)
+	rule.Build(pctx, ctx, "genVersionScript", "generate version script")
+
+	return versionScript
+}
+
+// checkStubsSymbols creates a rule that fails if any symbol that stubs.symbol_file lists for this
+// architecture, including the symbols that are only in future API levels, is not defined by the
+// implementation library.
+func (library *libraryDecorator) checkStubsSymbols(ctx ModuleContext, lib android.Path) {
+	symbolFile := android.PathForModuleSrc(ctx, String(library.Properties.Stubs.Symbol_file))
+	symbolList := genStubSymbolList(ctx, symbolFile, "current", "--apex")
+
+	check := android.PathForModuleOut(ctx, "stubs_symbols_check", lib.Base()+".check")
+	TransformStubSymbolsCheck(ctx, symbolList, symbolFile, lib, check)
+	ctx.CheckbuildFile(check)
+	library.stubsSymbolsCheck = android.OptionalPathForPath(check)
+}
+
 func (library *libraryDecorator) buildStubs() bool {
 	return library.MutatedProperties.BuildStubs
 }
@@ -1604,12 +1845,24 @@ func VersionMutator(mctx android.BottomUpMutatorContext) {
 		}
 
 		if c, ok := library.(*Module); ok && c.IsStubs() {
+			var versions []string
+			if llndk, ok := c.linker.(*llndkStubDecorator); ok && len(llndk.Properties.Versions) > 0 {
+				versions = llndk.Properties.Versions
+				normalizeVersions(mctx, versions)
+				if mctx.Failed() {
+					return
+				}
+			}
+
 			stubsVersionsLock.Lock()
 			defer stubsVersionsLock.Unlock()
-			// For LLNDK llndk_library, we borrow vstubs.ersions from its implementation library.
-			// Since llndk_library has dependency to its implementation library,
-			// we can safely access stubsVersionsFor() with its baseModuleName.
-			versions := stubsVersionsFor(mctx.Config())[c.BaseModuleName()]
+			if versions == nil {
+				// For LLNDK llndk_library without its own versions, we borrow stubs.versions
+				// from its implementation library. Since llndk_library has dependency to its
+				// implementation library, we can safely access stubsVersionsFor() with its
+				// baseModuleName.
+				versions = stubsVersionsFor(mctx.Config())[c.BaseModuleName()]
+			}
 			// save the list of versions for later use
 			stubsVersionsFor(mctx.Config())[mctx.ModuleName()] = versions
 
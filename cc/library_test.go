@@ -15,7 +15,9 @@
 package cc
 
 import (
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -212,6 +214,46 @@ func TestStubsVersions(t *testing.T) {
 	}
 }
 
+func TestStubsSymbolsCheck(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			stubs: {
+				symbol_file: "libfoo.map.txt",
+				versions: ["29"],
+			},
+		}
+	`
+	config := TestConfig(buildDir, android.Android, nil, bp, map[string][]byte{
+		"libfoo.map.txt": nil,
+	})
+	ctx := testCcWithConfig(t, config)
+
+	impl := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared")
+	symbolList := impl.Rule("genStubSymbolList")
+	if symbolList.Input.String() != "libfoo.map.txt" {
+		t.Errorf("expected symbol list to be generated from libfoo.map.txt, got %q", symbolList.Input)
+	}
+	if symbolList.Args["arch"] != "arm" || symbolList.Args["apiLevel"] != "current" {
+		t.Errorf("expected symbol list for arm at the current API level, got args %q", symbolList.Args)
+	}
+
+	check := impl.Rule("stubSymbolsCheck")
+	if check.Input != symbolList.Output {
+		t.Errorf("expected check of %q, got %q", symbolList.Output, check.Input)
+	}
+	lib := impl.Module().(*Module).UnstrippedOutputFile()
+	if check.Implicit != lib {
+		t.Errorf("expected check to depend on %q, got %q", lib, check.Implicit)
+	}
+
+	stubs := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared_29")
+	if stubs.MaybeRule("stubSymbolsCheck").Rule != nil {
+		t.Errorf("expected no check for the stubs variant")
+	}
+}
+
 func TestStubsVersions_NotSorted(t *testing.T) {
 	bp := `
 		cc_library {
@@ -240,3 +282,215 @@ func TestStubsVersions_ParseError(t *testing.T) {
 
 	testCcError(t, `"libfoo" .*: versions: SDK version should be`, bp)
 }
+
+func TestClangModules(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			export_include_dirs: ["include"],
+			export_module_map: "include/module.modulemap",
+		}
+
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c", "baz.c"],
+			shared_libs: ["libfoo"],
+			clang_modules: true,
+		}
+	`)
+
+	moduleMapFlag := "-fmodule-map-file=include/module.modulemap"
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_static").Rule("cc")
+	if cflags := libfoo.Args["cFlags"]; !strings.Contains(cflags, moduleMapFlag) {
+		t.Errorf("cflags for libfoo must contain %s, but was %#v.", moduleMapFlag, cflags)
+	}
+	if strings.Contains(libfoo.Args["cFlags"], "-fmodules") {
+		t.Errorf("cflags for libfoo must not contain -fmodules, but was %#v.", libfoo.Args["cFlags"])
+	}
+
+	libbar := ctx.ModuleForTests("libbar", "android_arm_armv7-a-neon_static").Output("obj/bar.o")
+	for _, flag := range []string{moduleMapFlag, "-fmodules", "-fmodules-cache-path=" + buildDir + "/.intermediates/libbar/android_arm_armv7-a-neon_static/clang-module-cache"} {
+		if cflags := libbar.Args["cFlags"]; !strings.Contains(cflags, flag) {
+			t.Errorf("cflags for libbar must contain %s, but was %#v.", flag, cflags)
+		}
+	}
+	if !android.InList("include/module.modulemap", libbar.Implicits.Strings()) {
+		t.Errorf("libbar must depend on include/module.modulemap, but implicits were %q", libbar.Implicits.Strings())
+	}
+
+	// The compiles of libbar share the Clang module cache, so they run one after the other.
+	libbarBaz := ctx.ModuleForTests("libbar", "android_arm_armv7-a-neon_static").Output("obj/baz.o")
+	if !android.InList(libbar.Output.String(), libbarBaz.OrderOnly.Strings()) {
+		t.Errorf("the compile of baz.c must be ordered after bar.o, but order-only deps were %q",
+			libbarBaz.OrderOnly.Strings())
+	}
+
+	testCcError(t, `export_module_map: "include/foo.h" is not a .modulemap file`, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			export_module_map: "include/foo.h",
+		}
+	`)
+}
+
+func TestGeneratedVersionScript(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c", "foo.S"],
+			generated_version_script: {
+				enabled: true,
+				macro: "FOO_EXPORT",
+				srcs: ["include/foo.h"],
+			},
+		}
+	`)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared")
+	genVersionScript := libfoo.Output("version_script/libfoo.map.txt")
+	if !strings.Contains(genVersionScript.RuleParams.Command, "-lib libfoo -macro FOO_EXPORT") {
+		t.Errorf("expected version script command to use the library name and macro, got %q",
+			genVersionScript.RuleParams.Command)
+	}
+	if inputs := genVersionScript.Implicits.Strings(); !reflect.DeepEqual(inputs, []string{"foo.c", "include/foo.h"}) {
+		t.Errorf("expected version script inputs [foo.c include/foo.h], got %q", inputs)
+	}
+
+	versionScript := genVersionScript.Output.String()
+	ld := libfoo.Rule("ld")
+	if ldFlags := ld.Args["ldFlags"]; !strings.Contains(ldFlags, "-Wl,--version-script,"+versionScript) {
+		t.Errorf("expected ldflags to contain the generated version script, got %q", ldFlags)
+	}
+	if !android.InList(versionScript, ld.Implicits.Strings()) {
+		t.Errorf("expected ld to depend on the generated version script, got %q", ld.Implicits.Strings())
+	}
+
+	testCcError(t, `generated_version_script.enabled: cannot be used with version_script`, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			version_script: "foo.map.txt",
+			generated_version_script: {
+				enabled: true,
+			},
+		}
+	`)
+}
+
+func TestThinArchive(t *testing.T) {
+	bp := `
+		cc_library_static {
+			name: "libdefault",
+			srcs: ["foo.c"],
+		}
+
+		cc_library_static {
+			name: "libfull",
+			srcs: ["foo.c"],
+			thin_archive: false,
+		}
+
+		cc_library_static {
+			name: "libdist",
+			srcs: ["foo.c"],
+			dist: {
+				targets: ["dist_target"],
+			},
+		}
+
+		cc_library_static {
+			name: "libvendor",
+			srcs: ["foo.c"],
+			vendor: true,
+		}
+
+		cc_binary {
+			name: "lto_binary",
+			srcs: ["foo.c"],
+			static_libs: ["libdefault", "libfull"],
+			lto: {
+				thin: true,
+			},
+		}
+	`
+
+	isThin := func(ctx *android.TestContext, name, variant string) bool {
+		return strings.Contains(ctx.ModuleForTests(name, variant).Rule("ar").Args["arFlags"], "crsPDT")
+	}
+
+	config := TestConfig(buildDir, android.Android, nil, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx := testCcWithConfig(t, config)
+
+	if isThin(ctx, "libdefault", "android_arm64_armv8-a_static_lto-thin") {
+		t.Errorf("expected libdefault to be a full archive without SOONG_THIN_ARCHIVES")
+	}
+
+	config = TestConfig(buildDir, android.Android, map[string]string{"SOONG_THIN_ARCHIVES": "true"}, bp, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	ctx = testCcWithConfig(t, config)
+
+	// The lto-thin variants are hidden from Make.
+	if !isThin(ctx, "libdefault", "android_arm64_armv8-a_static_lto-thin") {
+		t.Errorf("expected libdefault to be a thin archive with SOONG_THIN_ARCHIVES")
+	}
+	if isThin(ctx, "libdefault", "android_arm_armv7-a-neon_static") {
+		t.Errorf("expected libdefault to be a full archive because it is exported to Make")
+	}
+	if isThin(ctx, "libfull", "android_arm64_armv8-a_static_lto-thin") {
+		t.Errorf("expected libfull to be a full archive with thin_archive: false")
+	}
+	if isThin(ctx, "libdist", "android_arm_armv7-a-neon_static") {
+		t.Errorf("expected libdist to be a full archive because it is copied to the dist directory")
+	}
+	if isThin(ctx, "libvendor", "android_vendor.VER_arm_armv7-a-neon_static") {
+		t.Errorf("expected libvendor to be a full archive because it is captured in the vendor snapshot")
+	}
+}
+
+func TestExportGeneratedSourcesHeaders(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["a.aidl", "b.y"],
+			export_generated_sources_headers: true,
+		}
+
+		cc_library_shared {
+			name: "libbaz",
+			srcs: ["a.aidl", "b.y"],
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.c"],
+			shared_libs: ["libfoo", "libbaz"],
+		}
+	`)
+
+	variant := "android_arm_armv7-a-neon_shared"
+	genDir := func(module string) string {
+		return filepath.Join(buildDir, ".intermediates", module, variant, "gen")
+	}
+
+	libbar := ctx.ModuleForTests("libbar", variant).Rule("cc")
+	cflags := libbar.Args["cFlags"]
+	for _, dir := range []string{genDir("libfoo") + "/aidl", genDir("libfoo") + "/yacc"} {
+		if !strings.Contains(cflags, "-I"+dir) {
+			t.Errorf("expected cflags for libbar to contain -I%s, got %q", dir, cflags)
+		}
+	}
+	if strings.Contains(cflags, genDir("libbaz")) {
+		t.Errorf("expected cflags for libbar not to contain the generated headers of libbaz, got %q", cflags)
+	}
+
+	header := genDir("libfoo") + "/yacc/b.h"
+	if !android.InList(header, libbar.OrderOnly.Strings()) {
+		t.Errorf("expected libbar to depend on %s, got %q", header, libbar.OrderOnly.Strings())
+	}
+}
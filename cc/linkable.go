@@ -74,6 +74,15 @@ type DependencyTag struct {
 	FromStatic bool
 }
 
+// IncludeInLicenseMetadata returns true for libraries, objects and generated sources and headers,
+// which are compiled or linked into the module or installed with it.
+func (t DependencyTag) IncludeInLicenseMetadata() bool {
+	return t.Library || t == objDepTag || t == CrtBeginDepTag || t == CrtEndDepTag ||
+		t == runtimeDepTag || t == genSourceDepTag || t.Name == genHeaderDepTag.Name
+}
+
+var _ android.IncludeInLicenseMetadataTag = DependencyTag{}
+
 var (
 	SharedDepTag = DependencyTag{Name: "shared", Library: true, Shared: true}
 	StaticDepTag = DependencyTag{Name: "static", Library: true}
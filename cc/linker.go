@@ -350,6 +350,32 @@ func (linker *baseLinker) useClangLld(ctx ModuleContext) bool {
 	return true
 }
 
+// linkerBackendFlags switches a module that links with lld to the linker selected with
+// SOONG_LINKER. It falls back to lld when the module uses flags the selected linker doesn't
+// accept, or the linker is missing from the source tree.
+func linkerBackendFlags(ctx ModuleContext, flags Flags) Flags {
+	backend, err := config.SelectedLinkerBackend(ctx.Config(), ctx.Os(), ctx.Arch().ArchType)
+	if err != nil {
+		ctx.ModuleErrorf("%s", err)
+		return flags
+	}
+	if backend.Name == config.LldBackend.Name {
+		return flags
+	}
+	if !backend.AcceptsFlags(flags.Global.LdFlags) || !backend.AcceptsFlags(flags.Local.LdFlags) {
+		return flags
+	}
+	binary := backend.BinaryPath(ctx)
+	if !binary.Valid() {
+		return flags
+	}
+
+	flags.Global.LdFlags = append(flags.Global.LdFlags, backend.Ldflags)
+	// Relink when the linker changes.
+	flags.LdFlagsDeps = append(flags.LdFlagsDeps, binary.Path())
+	return flags
+}
+
 // Check whether the SDK version is not older than the specific one
 func CheckSdkVersionAtLeast(ctx ModuleContext, SdkVersion int) bool {
 	if ctx.sdkVersion() == "current" {
@@ -382,13 +408,14 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 		if !BoolDefault(linker.Properties.Pack_relocations, true) {
 			flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--pack-dyn-relocs=none")
 		} else if ctx.Device() {
-			// The SHT_RELR relocations is only supported by API level >= 28.
+			// The SHT_RELR relocations are only supported by newer API levels.
 			// Do not turn this on if older version NDK is used.
-			if !ctx.useSdk() || CheckSdkVersionAtLeast(ctx, 28) {
+			arch := ctx.Arch().ArchType
+			if !ctx.useSdk() || CheckSdkVersionAtLeast(ctx, config.RelrMinSdkVersion(arch)) {
 				flags.Global.LdFlags = append(flags.Global.LdFlags,
 					"-Wl,--pack-dyn-relocs=android+relr",
 					"-Wl,--use-android-relr-tags")
-			} else if CheckSdkVersionAtLeast(ctx, 23) {
+			} else if CheckSdkVersionAtLeast(ctx, config.AndroidRelocsMinSdkVersion(arch)) {
 				flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--pack-dyn-relocs=android")
 			}
 		}
@@ -427,6 +454,11 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 				flags.Global.LdFlags = append(flags.Global.LdFlags, "-lrt")
 			}
 		}
+
+		if ctx.Os().Linux() {
+			flags.Global.LdFlags = append(flags.Global.LdFlags,
+				config.HostCompilerRtLdflags(ctx.Config(), ctx.Arch().ArchType)...)
+		}
 	}
 
 	if ctx.Fuchsia() {
@@ -454,11 +486,11 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 		}
 	}
 
-	if ctx.useSdk() && (ctx.Arch().ArchType != android.Mips && ctx.Arch().ArchType != android.Mips64) {
+	if gnuHashMinSdkVersion := config.GnuHashMinSdkVersion(ctx.Arch().ArchType); ctx.useSdk() &&
+		gnuHashMinSdkVersion != 0 && !CheckSdkVersionAtLeast(ctx, gnuHashMinSdkVersion) {
 		// The bionic linker now has support gnu style hashes (which are much faster!), but shipping
 		// to older devices requires the old style hash. Fortunately, we can build with both and
 		// it'll work anywhere.
-		// This is not currently supported on MIPS architectures.
 		flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,--hash-style=both")
 	}
 
@@ -27,6 +27,36 @@ var llndkImplDep = struct {
 	blueprint.DependencyTag
 }{}
 
+var overrideLlndkDep = struct {
+	blueprint.DependencyTag
+}{}
+
+var (
+	// Parses a translation unit that includes all of the exported headers of an LLNDK stub
+	// once with the include directories of the stub and once with the include directories
+	// of the implementation library, and fails if the stub headers declare anything that the
+	// implementation headers don't.  The translation units are parsed into AST files whose
+	// declarations are then listed, and the lists are written to files instead of being piped
+	// into sort, so that a failure to parse the headers fails the rule.
+	llndkHeaderCheck = pctx.AndroidStaticRule("llndkHeaderCheck",
+		blueprint.RuleParams{
+			Command: "rm -f $out && " +
+				"${config.ClangBin}/clang++ -emit-ast $cFlags $stubIncludes -x c++ $in -o $out.stub.ast && " +
+				"${config.ClangBin}/clang++ -emit-ast $cFlags $implIncludes -x c++ $in -o $out.impl.ast && " +
+				"${config.ClangBin}/clang++ -cc1 -ast-list $out.stub.ast > $out.stub.list && " +
+				"${config.ClangBin}/clang++ -cc1 -ast-list $out.impl.ast > $out.impl.list && " +
+				"sort -u -o $out.stub.list $out.stub.list && " +
+				"sort -u -o $out.impl.list $out.impl.list && " +
+				"comm -23 $out.stub.list $out.impl.list > $out.diff && " +
+				"if [ -s $out.diff ]; then " +
+				"echo \"error: LLNDK stub headers of $libName declare APIs not declared by the implementation:\" && " +
+				"cat $out.diff && exit 1; fi && " +
+				"touch $out",
+			CommandDeps: []string{"${config.ClangBin}/clang++"},
+		},
+		"cFlags", "stubIncludes", "implIncludes", "libName")
+)
+
 var (
 	llndkLibrarySuffix = ".llndk"
 	llndkHeadersSuffix = ".llndk"
@@ -40,12 +70,14 @@ var (
 //     name: "libfoo",
 //     symbol_file: "libfoo.map.txt",
 //     export_include_dirs: ["include_vndk"],
+//     versions: ["29", "30"],
 // }
 //
 type llndkLibraryProperties struct {
-	// Relative path to the symbol map.
+	// Relative path to the symbol map. Can be set per architecture for libraries that
+	// export architecture-specific symbols.
 	// An example file can be seen here: TODO(danalbert): Make an example.
-	Symbol_file *string
+	Symbol_file *string `android:"arch_variant"`
 
 	// Whether to export any headers as -isystem instead of -I. Mainly for use by
 	// bionic/libc.
@@ -63,8 +95,97 @@ type llndkLibraryProperties struct {
 	// libraries. This effectively hides this module from vendors. Default value is true.
 	Vendor_available *bool
 
+	// whether this module can be directly depended upon by libs that are installed to
+	// /product. When set to false, this module can only be depended on by VNDK libraries,
+	// not product libraries. Default value is the value of vendor_available.
+	Product_available *bool
+
 	// list of llndk headers to re-export include directories from.
 	Export_llndk_headers []string `android:"arch_variant"`
+
+	// List of versions to generate stubs for, in addition to the stubs for the current
+	// VNDK version. Defaults to the stubs.versions of the implementation library.
+	Versions []string
+
+	// list of generated headers to re-export to vendor modules that link against the
+	// stub library.
+	Export_generated_headers []string `android:"arch_variant"`
+
+	// Whether to check that everything declared by the exported headers of the stub is
+	// also declared by the exported headers of the implementation library. Default is false.
+	Header_compatibility_check *bool
+
+	// Whether to include the symbols annotated with "# apex" in the symbol map in the
+	// stub library. Default is false.
+	Include_apex_symbols *bool
+
+	// Whether to include the symbols annotated with "# systemapi" in the symbol map in
+	// the stub library. Default is true, like the NDK and APEX stubs.
+	Include_systemapi_symbols *bool
+
+	// Whether to preprocess export_preprocessed_headers with the exported flags, the
+	// macros defined in the cflags and the exported include directories of the
+	// implementation library, for headers that depend on its configuration. Default is
+	// false.
+	Preprocess_with_impl_flags *bool
+
+	// Whether to build a coverage variant of the stub library when native coverage is
+	// enabled, so that instrumented vendor modules can link against it. Default is false.
+	Allow_native_coverage *bool
+
+	// Whether to generate the stubs with the prototypes declared by the headers in
+	// export_include_dirs instead of untyped placeholders, so that symbols in the symbol
+	// map that the headers don't declare fail to compile. Only supported for C
+	// libraries. Default is false.
+	Typed_stubs *bool
+
+	// list of directories relative to the Blueprints file that will be exported by the stub
+	// library instead of export_include_dirs. In the llndk block of a cc_library, the stub
+	// library exports the export_include_dirs of the library unless this is set.
+	Override_export_include_dirs []string
+}
+
+// addLlndkStubsHook adds a load hook that creates the LLNDK stub library of a
+// cc_library from its llndk properties, which are the properties of an llndk_library.
+//
+// Example:
+//
+// cc_library_shared {
+//     name: "libfoo",
+//     srcs: ["foo.cpp"],
+//     export_include_dirs: ["include"],
+//     llndk: {
+//         symbol_file: "libfoo.map.txt",
+//         override_export_include_dirs: ["include_vndk"],
+//     },
+// }
+//
+func addLlndkStubsHook(module *Module, library *libraryDecorator) {
+	android.AddLoadHook(module, func(ctx android.LoadHookContext) {
+		llndk := library.Properties.Llndk
+		if llndk.Symbol_file == nil {
+			return
+		}
+		if !library.buildShared() {
+			ctx.PropertyErrorf("llndk.symbol_file", "LLNDK stubs are only supported for shared libraries")
+			return
+		}
+
+		if llndk.Vendor_available == nil {
+			llndk.Vendor_available = BoolPtr(true)
+		}
+
+		ctx.CreateModule(LlndkLibraryFactory,
+			&struct {
+				Name *string
+			}{
+				Name: StringPtr(ctx.ModuleName()),
+			},
+			&llndk,
+			&FlagExporterProperties{
+				Export_include_dirs: library.flagExporter.Properties.Export_include_dirs,
+			})
+	})
 }
 
 type llndkStubDecorator struct {
@@ -74,23 +195,147 @@ type llndkStubDecorator struct {
 
 	exportHeadersTimestamp android.OptionalPath
 	versionScriptPath      android.ModuleGenPath
+
+	// The override_llndk module selected by PRODUCT_LLNDK_OVERRIDES, resolved once by
+	// overrideModule so that a bad selection is only reported once.
+	override         *overrideLlndkModule
+	overrideResolved bool
+}
+
+// productAvailable returns whether product libraries that are not VNDK can link against
+// the stub library.
+func (stub *llndkStubDecorator) productAvailable() bool {
+	if stub.Properties.Product_available != nil {
+		return Bool(stub.Properties.Product_available)
+	}
+	return Bool(stub.Properties.Vendor_available)
+}
+
+// availableTo returns whether libraries that are not VNDK can link against the image
+// variant m of the stub library.
+func (stub *llndkStubDecorator) availableTo(m *Module) bool {
+	if m.inProduct() {
+		return stub.productAvailable()
+	}
+	return Bool(stub.Properties.Vendor_available)
 }
 
 func (stub *llndkStubDecorator) compilerFlags(ctx ModuleContext, flags Flags, deps PathDeps) Flags {
 	flags = stub.baseCompiler.compilerFlags(ctx, flags, deps)
+	if Bool(stub.Properties.Typed_stubs) {
+		// Typed stubs include the exported headers to take the stub prototypes from.
+		flags.Local.CommonFlags = append(flags.Local.CommonFlags,
+			includeDirsToFlags(stub.exportedIncludes(ctx)))
+	}
 	return addStubLibraryCompilerFlags(flags)
 }
 
-func (stub *llndkStubDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) Objects {
+// apiLevel returns the API level to generate the stub for, which is passed to
+// gen_stub_libs.py as --api. Symbols introduced after this API level are excluded from
+// the stub.
+func (stub *llndkStubDecorator) apiLevel(ctx ModuleContext) string {
+	if stub.stubsVersion() != "" {
+		return stub.stubsVersion()
+	}
+
 	vndkVer := ctx.Module().(*Module).VndkVersion()
-	if !inList(vndkVer, ctx.Config().PlatformVersionActiveCodenames()) || vndkVer == "" {
-		// For non-enforcing devices, vndkVer is empty. Use "current" in that case, too.
-		vndkVer = "current"
+	if inList(vndkVer, ctx.Config().PlatformVersionActiveCodenames()) && vndkVer != "" {
+		return vndkVer
 	}
-	if stub.stubsVersion() != "" {
-		vndkVer = stub.stubsVersion()
+
+	// For non-enforcing devices, vndkVer is empty. Use "current" in that case, too.
+	// "current" is the future API level, which includes symbols that are still in
+	// development. Once the platform SDK is finalized, generate the stub for the
+	// released API level instead so that in-development symbols are excluded.
+	if ctx.Config().PlatformSdkFinal() {
+		return ctx.Config().PlatformSdkVersion()
+	}
+	return "current"
+}
+
+// genstubFlags returns the flags that select the symbols annotated for the LLNDK variant,
+// and for any other variants whose annotations the stub library honors. For typed stubs,
+// it also passes the exported headers to take the stub prototypes from.
+func (stub *llndkStubDecorator) genstubFlags(ctx ModuleContext) string {
+	flags := []string{"--llndk"}
+	if Bool(stub.Properties.Include_apex_symbols) {
+		flags = append(flags, "--apex")
+	}
+	if !BoolDefault(stub.Properties.Include_systemapi_symbols, true) {
+		flags = append(flags, "--no-systemapi")
+	}
+	if Bool(stub.Properties.Typed_stubs) {
+		_, rels := globHeaders(ctx, stub.exportedIncludes(ctx))
+		for _, rel := range rels {
+			flags = append(flags, "--include "+rel)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// globHeaders returns the headers in the given source directories, along with their paths
+// relative to the directory that contains them.
+func globHeaders(ctx ModuleContext, dirs android.Paths) (android.Paths, []string) {
+	var headers android.Paths
+	var rels []string
+	for _, dir := range dirs {
+		for _, header := range ctx.GlobFiles(filepath.Join(dir.String(), "**/*.h"), nil) {
+			rel, err := filepath.Rel(dir.String(), header.String())
+			if err != nil {
+				ctx.ModuleErrorf("filepath.Rel(%q, %q) failed: %s", dir.String(), header.String(), err)
+				continue
+			}
+			headers = append(headers, header)
+			rels = append(rels, rel)
+		}
+	}
+	return headers, rels
+}
+
+// overrideModule returns the override_llndk module that PRODUCT_LLNDK_OVERRIDES selects for
+// the stub library, or nil if there is none.
+func (stub *llndkStubDecorator) overrideModule(ctx ModuleContext) *overrideLlndkModule {
+	if stub.overrideResolved {
+		return stub.override
+	}
+	stub.overrideResolved = true
+	ctx.VisitDirectDepsWithTag(overrideLlndkDep, func(dep android.Module) {
+		if o, ok := dep.(*overrideLlndkModule); ok {
+			if o.GetOverriddenModuleName() != ctx.baseModuleName() {
+				ctx.ModuleErrorf("override_llndk module %q selected by PRODUCT_LLNDK_OVERRIDES overrides %q",
+					ctx.OtherModuleName(dep), o.GetOverriddenModuleName())
+				return
+			}
+			stub.override = o
+		} else {
+			ctx.ModuleErrorf("module %q selected by PRODUCT_LLNDK_OVERRIDES is not an override_llndk module",
+				ctx.OtherModuleName(dep))
+		}
+	})
+	return stub.override
+}
+
+// exportedIncludes returns the include directories exported by the stub library, taking
+// the override_llndk module selected for the product into account.
+func (stub *llndkStubDecorator) exportedIncludes(ctx ModuleContext) android.Paths {
+	if override := stub.overrideModule(ctx); override != nil && override.overridesExportIncludeDirs() {
+		return override.exportIncludeDirs
 	}
-	objs, versionScript := compileStubLibrary(ctx, flags, String(stub.Properties.Symbol_file), vndkVer, "--llndk")
+	if stub.Properties.Override_export_include_dirs != nil {
+		return android.PathsForModuleSrc(ctx, stub.Properties.Override_export_include_dirs)
+	}
+	return stub.flagExporter.exportedIncludes(ctx)
+}
+
+func (stub *llndkStubDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) Objects {
+	var symbolFile android.Path
+	if override := stub.overrideModule(ctx); override != nil && override.symbolFile.Valid() {
+		symbolFile = override.symbolFile.Path()
+	} else {
+		symbolFile = android.PathForModuleSrc(ctx, String(stub.Properties.Symbol_file))
+	}
+	objs, versionScript := compileStubLibraryFromPath(ctx, flags, symbolFile,
+		stub.apiLevel(ctx), stub.genstubFlags(ctx))
 	stub.versionScriptPath = versionScript
 	return objs
 }
@@ -99,6 +344,16 @@ func (stub *llndkStubDecorator) linkerDeps(ctx DepsContext, deps Deps) Deps {
 	headers := addSuffix(stub.Properties.Export_llndk_headers, llndkHeadersSuffix)
 	deps.HeaderLibs = append(deps.HeaderLibs, headers...)
 	deps.ReexportHeaderLibHeaders = append(deps.ReexportHeaderLibHeaders, headers...)
+
+	// The generated headers are re-exported by libraryDecorator.link through
+	// deps.ReexportedGeneratedHeaders.
+	deps.GeneratedHeaders = append(deps.GeneratedHeaders, stub.Properties.Export_generated_headers...)
+	deps.ReexportGeneratedHeaders = append(deps.ReexportGeneratedHeaders,
+		stub.Properties.Export_generated_headers...)
+
+	if override, ok := ctx.DeviceConfig().OverrideLlndkFor(ctx.baseModuleName()); ok {
+		ctx.AddDependency(ctx.Module(), overrideLlndkDep, override)
+	}
 	return deps
 }
 
@@ -112,7 +367,8 @@ func (stub *llndkStubDecorator) linkerFlags(ctx ModuleContext, flags Flags) Flag
 	return stub.libraryDecorator.linkerFlags(ctx, flags)
 }
 
-func (stub *llndkStubDecorator) processHeaders(ctx ModuleContext, srcHeaderDir string, outDir android.ModuleGenPath) android.Path {
+func (stub *llndkStubDecorator) processHeaders(ctx ModuleContext, srcHeaderDir string, outDir android.ModuleGenPath,
+	flags []string) android.Path {
 	srcDir := android.PathForModuleSrc(ctx, srcHeaderDir)
 	srcFiles := ctx.GlobFiles(filepath.Join(srcDir.String(), "**/*.h"), nil)
 
@@ -129,12 +385,17 @@ func (stub *llndkStubDecorator) processHeaders(ctx ModuleContext, srcHeaderDir s
 		installPaths = append(installPaths, outDir.Join(ctx, relHeaderDir, header.Base()))
 	}
 
-	return processHeadersWithVersioner(ctx, srcDir, outDir, srcFiles, installPaths)
+	return processHeadersWithVersioner(ctx, srcDir, outDir, srcFiles, installPaths, flags)
 }
 
 func (stub *llndkStubDecorator) link(ctx ModuleContext, flags Flags, deps PathDeps,
 	objs Objects) android.Path {
 
+	// Make the stub depend on the ABI dump and ABI diff of the implementation library so
+	// that an incompatible change to the implementation fails the build of every vendor
+	// module that links against the stub, instead of only the implementation itself.
+	flags.LdFlagsDeps = append(flags.LdFlagsDeps, deps.LlndkImplAbiChecks...)
+
 	if !Bool(stub.Properties.Unversioned) {
 		linkerScriptFlag := "-Wl,--version-script," + stub.versionScriptPath.String()
 		flags.Local.LdFlags = append(flags.Local.LdFlags, linkerScriptFlag)
@@ -144,9 +405,20 @@ func (stub *llndkStubDecorator) link(ctx ModuleContext, flags Flags, deps PathDe
 	if len(stub.Properties.Export_preprocessed_headers) > 0 {
 		genHeaderOutDir := android.PathForModuleGen(ctx, "include")
 
+		var versionerFlags []string
+		if Bool(stub.Properties.Preprocess_with_impl_flags) {
+			versionerFlags = append(versionerFlags, deps.LlndkImplFlags...)
+			for _, dir := range deps.LlndkImplIncludeDirs {
+				versionerFlags = append(versionerFlags, "-I"+dir.String())
+			}
+			for _, dir := range deps.LlndkImplSystemIncludeDirs {
+				versionerFlags = append(versionerFlags, "-isystem "+dir.String())
+			}
+		}
+
 		var timestampFiles android.Paths
 		for _, dir := range stub.Properties.Export_preprocessed_headers {
-			timestampFiles = append(timestampFiles, stub.processHeaders(ctx, dir, genHeaderOutDir))
+			timestampFiles = append(timestampFiles, stub.processHeaders(ctx, dir, genHeaderOutDir, versionerFlags))
 		}
 
 		if Bool(stub.Properties.Export_headers_as_system) {
@@ -158,6 +430,17 @@ func (stub *llndkStubDecorator) link(ctx ModuleContext, flags Flags, deps PathDe
 		stub.reexportDeps(timestampFiles...)
 	}
 
+	if override := stub.overrideModule(ctx); override != nil && override.overridesExportIncludeDirs() {
+		// Export the include directories of the override_llndk module instead of our own.
+		if Bool(stub.Properties.Export_headers_as_system) {
+			stub.reexportSystemDirs(override.exportIncludeDirs...)
+		} else {
+			stub.reexportDirs(override.exportIncludeDirs...)
+		}
+		stub.libraryDecorator.flagExporter.Properties.Export_include_dirs = []string{}
+		stub.libraryDecorator.flagExporter.Properties.Target.Vendor.Override_export_include_dirs = nil
+	}
+
 	if Bool(stub.Properties.Export_headers_as_system) {
 		stub.exportIncludesAsSystem(ctx)
 		stub.libraryDecorator.flagExporter.Properties.Export_include_dirs = []string{}
@@ -167,11 +450,88 @@ func (stub *llndkStubDecorator) link(ctx ModuleContext, flags Flags, deps PathDe
 		stub.reexportFlags("-D" + versioningMacroName(ctx.baseModuleName()) + "=" + stub.stubsVersion())
 	}
 
-	return stub.libraryDecorator.link(ctx, flags, deps, objs)
+	out := stub.libraryDecorator.link(ctx, flags, deps, objs)
+
+	if Bool(stub.Properties.Header_compatibility_check) {
+		if timestamp := stub.checkHeaderCompatibility(ctx, flags, deps); timestamp != nil {
+			// Vendor modules that use the stub depend on the check through the exported deps.
+			stub.reexportDeps(timestamp)
+		}
+	}
+
+	return out
+}
+
+// checkHeaderCompatibility creates a rule that compares the declarations in the exported
+// headers of the stub with the declarations in the exported headers of the implementation
+// library. Only headers in the source tree are checked.
+func (stub *llndkStubDecorator) checkHeaderCompatibility(ctx ModuleContext, flags Flags,
+	deps PathDeps) android.Path {
+
+	var srcDirs android.Paths
+	for _, dir := range append(stub.exportedDirs(), stub.exportedSystemDirs()...) {
+		if !strings.HasPrefix(dir.String(), android.PathForOutput(ctx).String()) {
+			srcDirs = append(srcDirs, dir)
+		}
+	}
+	headers, rels := globHeaders(ctx, srcDirs)
+	if len(headers) == 0 {
+		return nil
+	}
+	var includes []string
+	for _, rel := range rels {
+		includes = append(includes, "#include <"+rel+">")
+	}
+
+	tu := android.PathForModuleGen(ctx, "header_check", "headers.cpp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Description: "generate LLNDK header check " + ctx.ModuleName(),
+		Output:      tu,
+		Args: map[string]string{
+			"content": strings.Join(includes, "\\n"),
+		},
+	})
+
+	includeFlags := func(dirs, systemDirs android.Paths) string {
+		var flags []string
+		for _, dir := range dirs {
+			flags = append(flags, "-I"+dir.String())
+		}
+		for _, dir := range systemDirs {
+			flags = append(flags, "-isystem "+dir.String())
+		}
+		return strings.Join(flags, " ")
+	}
+
+	// The rule leaves the intermediate files it compares next to the timestamp.
+	var intermediates android.WritablePaths
+	for _, suffix := range []string{".stub.ast", ".impl.ast", ".stub.list", ".impl.list", ".diff"} {
+		intermediates = append(intermediates,
+			android.PathForModuleOut(ctx, "header_check", "header_check.timestamp"+suffix))
+	}
+
+	builderFlags := flagsToBuilderFlags(flags)
+	timestamp := android.PathForModuleOut(ctx, "header_check", "header_check.timestamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            llndkHeaderCheck,
+		Description:     "check LLNDK headers " + ctx.ModuleName(),
+		Input:           tu,
+		Output:          timestamp,
+		ImplicitOutputs: intermediates,
+		Implicits:       headers,
+		Args: map[string]string{
+			"cFlags":       strings.Join([]string{builderFlags.globalCommonFlags, builderFlags.globalCFlags, builderFlags.globalCppFlags}, " "),
+			"stubIncludes": includeFlags(stub.exportedDirs(), stub.exportedSystemDirs()),
+			"implIncludes": includeFlags(deps.LlndkImplIncludeDirs, deps.LlndkImplSystemIncludeDirs),
+			"libName":      ctx.baseModuleName(),
+		},
+	})
+	return timestamp
 }
 
 func (stub *llndkStubDecorator) nativeCoverage() bool {
-	return false
+	return Bool(stub.Properties.Allow_native_coverage)
 }
 
 func NewLLndkStubLibrary() *Module {
@@ -206,12 +566,75 @@ func NewLLndkStubLibrary() *Module {
 //        symbol_file: "libfoo.map.txt",
 //        export_include_dirs: ["include_vndk"],
 //    }
+//
+// Deprecated: new libraries should set the llndk property on the implementation
+// cc_library or cc_library_shared instead.
 func LlndkLibraryFactory() android.Module {
 	module := NewLLndkStubLibrary()
 	android.InitAndroidArchModule(module, android.DeviceSupported, android.MultilibBoth)
 	return module
 }
 
+type overrideLlndkProperties struct {
+	// Relative path to the symbol map that replaces the symbol_file of the overridden
+	// llndk_library.
+	Symbol_file *string
+
+	// list of directories relative to the Blueprints file that replace the
+	// export_include_dirs of the overridden llndk_library.
+	Export_include_dirs []string
+}
+
+// overrideLlndkModule shares the base property of the android override modules, but it doesn't
+// embed android.OverrideModuleBase: the override mutators require an override module to have
+// the same variants as its base, while the llndk_library stubs are split by the arch, image
+// and link mutators.
+type overrideLlndkModule struct {
+	android.ModuleBase
+
+	overrideProperties android.OverrideModuleProperties
+	properties         overrideLlndkProperties
+
+	symbolFile        android.OptionalPath
+	exportIncludeDirs android.Paths
+}
+
+// GetOverriddenModuleName returns the name of the llndk_library that the module overrides.
+func (o *overrideLlndkModule) GetOverriddenModuleName() string {
+	return String(o.overrideProperties.Base)
+}
+
+func (o *overrideLlndkModule) overridesExportIncludeDirs() bool {
+	return o.properties.Export_include_dirs != nil
+}
+
+func (o *overrideLlndkModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if o.overrideProperties.Base == nil {
+		ctx.PropertyErrorf("base", "missing base module")
+	}
+	if o.properties.Symbol_file != nil {
+		o.symbolFile = android.OptionalPathForPath(android.PathForModuleSrc(ctx, *o.properties.Symbol_file))
+	}
+	o.exportIncludeDirs = android.PathsForModuleSrc(ctx, o.properties.Export_include_dirs)
+}
+
+// override_llndk replaces the symbol file or the exported include directories of an
+// llndk_library for the products that select it through PRODUCT_LLNDK_OVERRIDES, e.g.
+// "libfoo:libfoo_device_overrides". Example:
+//
+//    override_llndk {
+//        name: "libfoo_device_overrides",
+//        base: "libfoo",
+//        symbol_file: "libfoo.map.txt",
+//    }
+//
+func OverrideLlndkFactory() android.Module {
+	module := &overrideLlndkModule{}
+	module.AddProperties(&module.overrideProperties, &module.properties)
+	android.InitAndroidModule(module)
+	return module
+}
+
 type llndkHeadersDecorator struct {
 	*libraryDecorator
 }
@@ -247,4 +670,5 @@ func llndkHeadersFactory() android.Module {
 func init() {
 	android.RegisterModuleType("llndk_library", LlndkLibraryFactory)
 	android.RegisterModuleType("llndk_headers", llndkHeadersFactory)
+	android.RegisterModuleType("override_llndk", OverrideLlndkFactory)
 }
@@ -0,0 +1,129 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+// This file contains the singleton that packages the LLNDK stub libraries and their exported
+// headers into a zip, so that vendor modules can be built against the LLNDK outside of a full
+// platform checkout, in the same way as NDK modules are built against the NDK sysroot.
+
+import (
+	"path/filepath"
+	"sort"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterSingletonType("llndk-sdk", LlndkSdkSingleton)
+}
+
+func LlndkSdkSingleton() android.Singleton {
+	return &llndkSdkSingleton{}
+}
+
+type llndkSdkSingleton struct {
+	llndkSdkZipFile android.OptionalPath
+}
+
+// isLlndkSdkModule returns whether m is an LLNDK stub library variant that is packaged into
+// the LLNDK SDK. Only the vendor variants for the platform VNDK version are packaged.
+func isLlndkSdkModule(ctx android.SingletonContext, m *Module) bool {
+	if _, ok := m.linker.(*llndkStubDecorator); !ok {
+		return false
+	}
+	if !m.Enabled() || m.Target().Os.Class != android.Device {
+		return false
+	}
+	if m.Target().NativeBridge == android.NativeBridgeEnabled {
+		return false
+	}
+	return m.inVendor() && m.VndkVersion() == ctx.DeviceConfig().PlatformVndkVersion() &&
+		m.outputFile.Valid()
+}
+
+func (c *llndkSdkSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	// There are no vendor variants of the LLNDK stub libraries unless BOARD_VNDK_VERSION is set.
+	if ctx.DeviceConfig().VndkVersion() == "" {
+		return
+	}
+
+	/*
+		LLNDK SDK zipped artifacts directory structure:
+		arch-{TARGET_ARCH}-{TARGET_ARCH_VARIANT}/
+			{VNDK_VERSION}/
+				(LLNDK stub .so libraries, their version scripts and their json files)
+		arch-{TARGET_2ND_ARCH}-{TARGET_2ND_ARCH_VARIANT}/
+			{VNDK_VERSION}/
+				(LLNDK stub .so libraries, their version scripts and their json files)
+		include/
+			(header files of same directory structure with source tree)
+	*/
+
+	sdkDir := "llndk-sdk"
+	includeDir := filepath.Join(sdkDir, "include")
+
+	var sdkOutputs android.Paths
+	var headers android.Paths
+
+	ctx.VisitAllModules(func(module android.Module) {
+		m, ok := module.(*Module)
+		if !ok || !isLlndkSdkModule(ctx, m) {
+			return
+		}
+
+		stub := m.linker.(*llndkStubDecorator)
+		sdkOutputs = append(sdkOutputs, installLlndkStubLibrary(ctx, m, stub, sdkDir, "")...)
+		headers = append(headers, stub.snapshotHeaders()...)
+	})
+
+	// install all headers after removing duplicates
+	for _, header := range android.FirstUniquePaths(headers) {
+		sdkOutputs = append(sdkOutputs, copyFile(
+			ctx, header, filepath.Join(includeDir, header.String())))
+	}
+
+	// All artifacts are ready. Sort them to normalize ninja and then zip.
+	sort.Slice(sdkOutputs, func(i, j int) bool {
+		return sdkOutputs[i].String() < sdkOutputs[j].String()
+	})
+
+	zipName := "llndk-sdk-" + ctx.DeviceConfig().PlatformVndkVersion()
+	zipPath := android.PathForOutput(ctx, sdkDir, zipName+".zip")
+	zipRule := android.NewRuleBuilder()
+
+	// filenames in rspfile from FlagWithRspFileInputList might be single-quoted. Remove it with tr
+	sdkOutputList := android.PathForOutput(ctx, sdkDir, zipName+"_list")
+	zipRule.Command().
+		Text("tr").
+		FlagWithArg("-d ", "\\'").
+		FlagWithRspFileInputList("< ", sdkOutputs).
+		FlagWithOutput("> ", sdkOutputList)
+
+	zipRule.Temporary(sdkOutputList)
+
+	zipRule.Command().
+		BuiltTool(ctx, "soong_zip").
+		FlagWithOutput("-o ", zipPath).
+		FlagWithArg("-C ", android.PathForOutput(ctx, sdkDir).String()).
+		FlagWithInput("-l ", sdkOutputList)
+
+	zipRule.Build(pctx, ctx, zipPath.String(), "llndk sdk "+zipPath.String())
+	zipRule.DeleteTemporaryFiles()
+	c.llndkSdkZipFile = android.OptionalPathForPath(zipPath)
+}
+
+func (c *llndkSdkSingleton) MakeVars(ctx android.MakeVarsContext) {
+	ctx.Strict("SOONG_LLNDK_SDK_ZIP", c.llndkSdkZipFile.String())
+}
@@ -16,6 +16,7 @@ package cc
 
 import (
 	"android/soong/android"
+	"android/soong/cc/config"
 )
 
 // LTO (link-time optimization) allows the compiler to optimize and generate
@@ -35,21 +36,33 @@ import (
 //
 // This file adds support to soong to automatically propogate LTO options to a
 // new variant of all static dependencies for each module with LTO enabled.
+// Conversely, static dependencies built with LTO of a module linked without
+// LTO get a new variant that is compiled to native object files, so that
+// modules that disable LTO never link bitcode.
 
 type LTOProperties struct {
 	// Lto must violate capitialization style for acronyms so that it can be
 	// referred to in blueprint files as "lto"
 	Lto struct {
+		// Never build this module with LTO, even as a static dependency of an LTO module or
+		// when ThinLTO is enabled for all modules with GLOBAL_THINLTO.
 		Never *bool `android:"arch_variant"`
 		Full  *bool `android:"arch_variant"`
 		Thin  *bool `android:"arch_variant"`
 	} `android:"arch_variant"`
 
+	// GlobalThin is set when the module is built with ThinLTO because GLOBAL_THINLTO is set.
+	GlobalThin bool `blueprint:"mutated"`
+
 	// Dep properties indicate that this module needs to be built with LTO
 	// since it is an object dependency of an LTO module.
 	FullDep bool `blueprint:"mutated"`
 	ThinDep bool `blueprint:"mutated"`
 
+	// NoLtoDep indicates that this module needs to be built without LTO
+	// since it is an object dependency of a module linked without LTO.
+	NoLtoDep bool `blueprint:"mutated"`
+
 	// Use clang lld instead of gnu ld.
 	Use_clang_lld *bool
 }
@@ -65,6 +78,10 @@ func (lto *lto) props() []interface{} {
 func (lto *lto) begin(ctx BaseModuleContext) {
 	if ctx.Config().IsEnvTrue("DISABLE_LTO") {
 		lto.Properties.Lto.Never = boolPtr(true)
+	} else if config.GlobalThinLTO(ctx.Config()) && ctx.Device() && lto.useClangLld(ctx) &&
+		!lto.Disabled() && !Bool(lto.Properties.Lto.Full) && lto.Properties.Lto.Thin == nil {
+		lto.Properties.Lto.Thin = boolPtr(true)
+		lto.Properties.GlobalThin = true
 	}
 }
 
@@ -99,15 +116,13 @@ func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
 
 		if ctx.Config().IsEnvTrue("USE_THINLTO_CACHE") && Bool(lto.Properties.Lto.Thin) && lto.useClangLld(ctx) {
 			// Set appropriate ThinLTO cache policy
-			cacheDirFormat := "-Wl,--thinlto-cache-dir="
-			cacheDir := android.PathForOutput(ctx, "thinlto-cache").String()
-			flags.Local.LdFlags = append(flags.Local.LdFlags, cacheDirFormat+cacheDir)
-
-			// Limit the size of the ThinLTO cache to the lesser of 10% of available
-			// disk space and 10GB.
-			cachePolicyFormat := "-Wl,--thinlto-cache-policy="
-			policy := "cache_size=10%:cache_size_bytes=10g"
-			flags.Local.LdFlags = append(flags.Local.LdFlags, cachePolicyFormat+policy)
+			flags.Local.LdFlags = append(flags.Local.LdFlags,
+				"-Wl,--thinlto-cache-dir=${config.ThinLTOCacheDir}",
+				"-Wl,--thinlto-cache-policy=${config.ThinLTOCachePolicy}")
+		}
+
+		if lto.Properties.GlobalThin {
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "${config.ThinLTOGlobalLdflags}")
 		}
 
 		// If the module does not have a profile, be conservative and do not inline
@@ -164,6 +179,23 @@ func ltoDepsMutator(mctx android.TopDownMutatorContext) {
 				return true
 			}
 
+			// Do not recurse down non-static dependencies
+			return false
+		})
+	} else if ok && m.lto != nil && m.linker != nil && !m.static() && !m.object() {
+		// Modules linked without LTO need native variants of their LTO static dependencies
+		mctx.WalkDeps(func(dep android.Module, parent android.Module) bool {
+			tag := mctx.OtherModuleDependencyTag(dep)
+			switch tag {
+			case StaticDepTag, staticExportDepTag, lateStaticDepTag, wholeStaticDepTag, objDepTag, reuseObjTag:
+				if dep, ok := dep.(*Module); ok && dep.lto.LTO() {
+					dep.lto.Properties.NoLtoDep = true
+				}
+
+				// Recursively walk static dependencies
+				return true
+			}
+
 			// Do not recurse down non-static dependencies
 			return false
 		})
@@ -173,22 +205,32 @@ func ltoDepsMutator(mctx android.TopDownMutatorContext) {
 // Create lto variants for modules that need them
 func ltoMutator(mctx android.BottomUpMutatorContext) {
 	if m, ok := mctx.Module().(*Module); ok && m.lto != nil {
+		// A module built with LTO that is a static dependency of a
+		// module linked without LTO is built without LTO in the
+		// default variation, and with its own LTO type in the
+		// variation named after it.
+		noLto := m.lto.Properties.NoLtoDep && m.lto.LTO()
+
 		// Create variations for LTO types required as static
 		// dependencies
 		variationNames := []string{""}
-		if m.lto.Properties.FullDep && !Bool(m.lto.Properties.Lto.Full) {
+		if m.lto.Properties.FullDep && !Bool(m.lto.Properties.Lto.Full) ||
+			noLto && Bool(m.lto.Properties.Lto.Full) {
 			variationNames = append(variationNames, "lto-full")
 		}
-		if m.lto.Properties.ThinDep && !Bool(m.lto.Properties.Lto.Thin) {
+		if m.lto.Properties.ThinDep && !Bool(m.lto.Properties.Lto.Thin) ||
+			noLto && Bool(m.lto.Properties.Lto.Thin) {
 			variationNames = append(variationNames, "lto-thin")
 		}
 
 		// Use correct dependencies if LTO property is explicitly set
-		// (mutually exclusive)
-		if Bool(m.lto.Properties.Lto.Full) {
+		// (mutually exclusive).  The variations created below select
+		// their own dependencies when the default variation is built
+		// without LTO.
+		if Bool(m.lto.Properties.Lto.Full) && !noLto {
 			mctx.SetDependencyVariation("lto-full")
 		}
-		if Bool(m.lto.Properties.Lto.Thin) {
+		if Bool(m.lto.Properties.Lto.Thin) && !noLto {
 			mctx.SetDependencyVariation("lto-thin")
 		}
 
@@ -200,6 +242,11 @@ func ltoMutator(mctx android.BottomUpMutatorContext) {
 				// installed. Variation set above according to
 				// explicit LTO properties
 				if name == "" {
+					if noLto {
+						variation.lto.Properties.Lto.Full = boolPtr(false)
+						variation.lto.Properties.Lto.Thin = boolPtr(false)
+						variation.lto.Properties.GlobalThin = false
+					}
 					continue
 				}
 
@@ -207,6 +254,7 @@ func ltoMutator(mctx android.BottomUpMutatorContext) {
 				if name == "lto-full" {
 					variation.lto.Properties.Lto.Full = boolPtr(true)
 					variation.lto.Properties.Lto.Thin = boolPtr(false)
+					variation.lto.Properties.GlobalThin = false
 				}
 				if name == "lto-thin" {
 					variation.lto.Properties.Lto.Full = boolPtr(false)
@@ -216,6 +264,7 @@ func ltoMutator(mctx android.BottomUpMutatorContext) {
 				variation.Properties.HideFromMake = true
 				variation.lto.Properties.FullDep = false
 				variation.lto.Properties.ThinDep = false
+				variation.lto.Properties.NoLtoDep = false
 			}
 		}
 	}
@@ -25,9 +25,10 @@ import (
 )
 
 var (
-	modulesAddedWallKey          = android.NewOnceKey("ModulesAddedWall")
-	modulesUsingWnoErrorKey      = android.NewOnceKey("ModulesUsingWnoError")
-	modulesMissingProfileFileKey = android.NewOnceKey("ModulesMissingProfileFile")
+	modulesAddedWallKey            = android.NewOnceKey("ModulesAddedWall")
+	modulesUsingWnoErrorKey        = android.NewOnceKey("ModulesUsingWnoError")
+	modulesMissingProfileFileKey   = android.NewOnceKey("ModulesMissingProfileFile")
+	modulesDroppingUnknownFlagsKey = android.NewOnceKey("ModulesDroppingUnknownFlags")
 )
 
 func init() {
@@ -40,7 +41,7 @@ func getNamedMapForConfig(config android.Config, key android.OnceKey) *sync.Map
 	}).(*sync.Map)
 }
 
-func makeStringOfKeys(ctx android.MakeVarsContext, key android.OnceKey) string {
+func sortedKeysOfNamedMap(ctx android.MakeVarsContext, key android.OnceKey) []string {
 	set := getNamedMapForConfig(ctx.Config(), key)
 	keys := []string{}
 	set.Range(func(key interface{}, value interface{}) bool {
@@ -48,7 +49,34 @@ func makeStringOfKeys(ctx android.MakeVarsContext, key android.OnceKey) string {
 		return true
 	})
 	sort.Strings(keys)
-	return strings.Join(keys, " ")
+	return keys
+}
+
+func makeStringOfKeys(ctx android.MakeVarsContext, key android.OnceKey) string {
+	return strings.Join(sortedKeysOfNamedMap(ctx, key), " ")
+}
+
+// reportDroppedUnknownFlags reports each flag that was dropped from modules because clang doesn't
+// accept it once, along with the modules that set it, so that it gets removed from them.
+func reportDroppedUnknownFlags(ctx android.MakeVarsContext) {
+	var flags []string
+	modules := make(map[string][]string)
+	for _, dropped := range sortedKeysOfNamedMap(ctx, modulesDroppingUnknownFlagsKey) {
+		// The keys are <blueprint file>:<module>:<flag>.
+		parts := strings.SplitN(dropped, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		flag := parts[2]
+		if _, ok := modules[flag]; !ok {
+			flags = append(flags, flag)
+		}
+		modules[flag] = append(modules[flag], parts[0]+":"+parts[1])
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		ctx.Errorf("clang doesn't support %q, remove it from: %s", flag, strings.Join(modules[flag], ", "))
+	}
 }
 
 func makeStringOfWarningAllowedProjects() string {
@@ -84,7 +112,11 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 	ctx.Strict("LLVM_OBJCOPY", "${config.ClangBin}/llvm-objcopy")
 	ctx.Strict("LLVM_STRIP", "${config.ClangBin}/llvm-strip")
 	ctx.Strict("PATH_TO_CLANG_TIDY", "${config.ClangBin}/clang-tidy")
-	ctx.StrictSorted("CLANG_CONFIG_UNKNOWN_CFLAGS", strings.Join(config.ClangUnknownCflags, " "))
+	unknownFlags, err := config.ClangUnknownFlagsFor(ctx)
+	if err != nil {
+		ctx.Errorf("%s", err)
+	}
+	ctx.StrictSorted("CLANG_CONFIG_UNKNOWN_CFLAGS", strings.Join(unknownFlags.Cflags, " "))
 
 	ctx.Strict("RS_LLVM_PREBUILTS_VERSION", "${config.RSClangVersion}")
 	ctx.Strict("RS_LLVM_PREBUILTS_BASE", "${config.RSClangBase}")
@@ -123,6 +155,8 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 	ctx.Strict("SOONG_MODULES_ADDED_WALL", makeStringOfKeys(ctx, modulesAddedWallKey))
 	ctx.Strict("SOONG_MODULES_USING_WNO_ERROR", makeStringOfKeys(ctx, modulesUsingWnoErrorKey))
 	ctx.Strict("SOONG_MODULES_MISSING_PGO_PROFILE_FILE", makeStringOfKeys(ctx, modulesMissingProfileFileKey))
+	ctx.Strict("SOONG_MODULES_DROPPING_UNKNOWN_CLANG_FLAGS", makeStringOfKeys(ctx, modulesDroppingUnknownFlagsKey))
+	reportDroppedUnknownFlags(ctx)
 
 	ctx.Strict("ADDRESS_SANITIZER_CONFIG_EXTRA_CFLAGS", strings.Join(asanCflags, " "))
 	ctx.Strict("ADDRESS_SANITIZER_CONFIG_EXTRA_LDFLAGS", strings.Join(asanLdflags, " "))
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 )
@@ -29,28 +30,70 @@ var (
 		blueprint.RuleParams{
 			// The `&& touch $out` isn't really necessary, but Blueprint won't
 			// let us have only implicit outputs.
-			Command:     "$versionerCmd -o $outDir $srcDir $depsPath && touch $out",
+			Command:     "$versionerCmd $flags -o $outDir $srcDir $depsPath && touch $out",
 			CommandDeps: []string{"$versionerCmd"},
 		},
-		"depsPath", "srcDir", "outDir")
+		"depsPath", "srcDir", "outDir", "flags")
 
 	preprocessNdkHeader = pctx.AndroidStaticRule("preprocessNdkHeader",
 		blueprint.RuleParams{
-			Command:     "$preprocessor -o $out $in",
+			Command:     "$preprocessor $args -o $out $in",
 			CommandDeps: []string{"$preprocessor"},
 		},
-		"preprocessor")
+		"preprocessor", "args")
 )
 
+type preprocessorDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var preprocessorDepTag = preprocessorDependencyTag{}
+
 func init() {
 	pctx.SourcePathVariable("versionerCmd", "prebuilts/clang-tools/${config.HostPrebuiltTag}/bin/versioner")
 }
 
 // Returns the NDK base include path for use with sdk_version current. Usable with -I.
-func getCurrentIncludePath(ctx android.ModuleContext) android.InstallPath {
+func getCurrentIncludePath(ctx android.PathContext) android.InstallPath {
 	return getNdkSysrootBase(ctx).Join(ctx, "usr/include")
 }
 
+// ndkHeaderLicenses holds the license metadata of an NDK header module, collected from
+// its license and licenses properties.
+type ndkHeaderLicenses struct {
+	texts android.Paths
+	kinds []string
+}
+
+// ndkHeaderNamespace returns the path of the soong namespace that defines an NDK header
+// module, "." for the root namespace. Module names are only unique within a namespace,
+// so the NDK singleton uses it to qualify the per-module outputs it generates.
+func ndkHeaderNamespace(ctx android.ModuleContext) string {
+	if namespace, ok := ctx.Namespace().(*android.Namespace); ok {
+		return namespace.Path
+	}
+	return "."
+}
+
+// collectNdkHeaderLicenses returns the license metadata for an NDK header module from the
+// raw license file and the license modules referenced by the licenses property.
+func collectNdkHeaderLicenses(ctx android.ModuleContext, license *string) ndkHeaderLicenses {
+	var ret ndkHeaderLicenses
+	if String(license) != "" {
+		ret.texts = append(ret.texts, android.PathForModuleSrc(ctx, String(license)))
+	}
+	for _, l := range android.LicenseModulesForModule(ctx) {
+		ret.texts = append(ret.texts, l.LicenseTexts()...)
+		ret.kinds = append(ret.kinds, l.LicenseKinds()...)
+	}
+	if len(ret.texts) == 0 {
+		ctx.PropertyErrorf("license", "field is required unless licenses provides license text")
+	}
+	ret.texts = android.FirstUniquePaths(ret.texts)
+	ret.kinds = android.FirstUniqueStrings(ret.kinds)
+	return ret
+}
+
 type headerProperties struct {
 	// Base directory of the headers being installed. As an example:
 	//
@@ -74,7 +117,8 @@ type headerProperties struct {
 	// Source paths that should be excluded from the srcs glob.
 	Exclude_srcs []string `android:"path"`
 
-	// Path to the NOTICE file associated with the headers.
+	// Path to the NOTICE file associated with the headers. Not required if licenses
+	// is set.
 	License *string `android:"path"`
 
 	// True if this API is not yet ready to be shipped in the NDK. It will be
@@ -89,7 +133,8 @@ type headerModule struct {
 	properties headerProperties
 
 	installPaths android.Paths
-	licensePath  android.Path
+	licenses     ndkHeaderLicenses
+	namespace    string
 }
 
 func getHeaderInstallDir(ctx android.ModuleContext, header android.Path, from string,
@@ -125,11 +170,8 @@ func getHeaderInstallDir(ctx android.ModuleContext, header android.Path, from st
 }
 
 func (m *headerModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	if String(m.properties.License) == "" {
-		ctx.PropertyErrorf("license", "field is required")
-	}
-
-	m.licensePath = android.PathForModuleSrc(ctx, String(m.properties.License))
+	m.licenses = collectNdkHeaderLicenses(ctx, m.properties.License)
+	m.namespace = ndkHeaderNamespace(ctx)
 
 	// When generating NDK prebuilts, skip installing MIPS headers,
 	// but keep them when doing regular platform build.
@@ -160,7 +202,8 @@ func (m *headerModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 // ndk_headers installs the sets of ndk headers defined in the srcs property
 // to the sysroot base + "usr/include" + to directory + directory component.
-// ndk_headers requires the license file to be specified. Example:
+// ndk_headers requires either a license file or license modules to be specified.
+// Example:
 //
 //    Given:
 //    sysroot base = "ndk/sysroot"
@@ -191,7 +234,8 @@ type versionedHeaderProperties struct {
 	// Install path within the sysroot. This is relative to usr/include.
 	To *string
 
-	// Path to the NOTICE file associated with the headers.
+	// Path to the NOTICE file associated with the headers. Not required if licenses
+	// is set.
 	License *string
 
 	// True if this API is not yet ready to be shipped in the NDK. It will be
@@ -213,15 +257,13 @@ type versionedHeaderModule struct {
 	properties versionedHeaderProperties
 
 	installPaths android.Paths
-	licensePath  android.Path
+	licenses     ndkHeaderLicenses
+	namespace    string
 }
 
 func (m *versionedHeaderModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	if String(m.properties.License) == "" {
-		ctx.PropertyErrorf("license", "field is required")
-	}
-
-	m.licensePath = android.PathForModuleSrc(ctx, String(m.properties.License))
+	m.licenses = collectNdkHeaderLicenses(ctx, m.properties.License)
+	m.namespace = ndkHeaderNamespace(ctx)
 
 	fromSrcPath := android.PathForModuleSrc(ctx, String(m.properties.From))
 	toOutputPath := getCurrentIncludePath(ctx).Join(ctx, String(m.properties.To))
@@ -238,11 +280,14 @@ func (m *versionedHeaderModule) GenerateAndroidBuildActions(ctx android.ModuleCo
 		ctx.ModuleErrorf("glob %q matched zero files", String(m.properties.From))
 	}
 
-	processHeadersWithVersioner(ctx, fromSrcPath, toOutputPath, srcFiles, installPaths)
+	processHeadersWithVersioner(ctx, fromSrcPath, toOutputPath, srcFiles, installPaths, nil)
 }
 
+// processHeadersWithVersioner preprocesses the headers in srcDir into outDir with the versioner.
+// flags are passed to the versioner in addition to the include paths of the versioner
+// dependencies directory.
 func processHeadersWithVersioner(ctx android.ModuleContext, srcDir, outDir android.Path,
-	srcFiles android.Paths, installPaths []android.WritablePath) android.Path {
+	srcFiles android.Paths, installPaths []android.WritablePath, flags []string) android.Path {
 	// The versioner depends on a dependencies directory to simplify determining include paths
 	// when parsing headers. This directory contains architecture specific directories as well
 	// as a common directory, each of which contains symlinks to the actually directories to
@@ -273,6 +318,7 @@ func processHeadersWithVersioner(ctx android.ModuleContext, srcDir, outDir andro
 			"depsPath": depsPath.String(),
 			"srcDir":   srcDir.String(),
 			"outDir":   outDir.String(),
+			"flags":    strings.Join(flags, " "),
 		},
 	})
 
@@ -297,18 +343,24 @@ func versionedNdkHeadersFactory() android.Module {
 // preprocessed_ndk_header {
 //     name: "foo",
 //     preprocessor: "foo.sh",
+//     args: ["--foo"],
 //     srcs: [...],
 //     to: "android",
 // }
 //
 // Will invoke the preprocessor as:
-//     $preprocessor -o $SYSROOT/usr/include/android/needs_preproc.h $src
+//     $preprocessor $args -o $SYSROOT/usr/include/android/needs_preproc.h $src
 // For each src in srcs.
 type preprocessedHeadersProperties struct {
-	// The preprocessor to run. Must be a program inside the source directory
-	// with no dependencies.
+	// The preprocessor to run. Either a program inside the source directory
+	// with no dependencies, or a reference to a host tool module of the form
+	// ":module".
 	Preprocessor *string
 
+	// Additional arguments to pass to the preprocessor before the output and
+	// input files.
+	Args []string
+
 	// Source path to the files to be preprocessed.
 	Srcs []string
 
@@ -318,7 +370,8 @@ type preprocessedHeadersProperties struct {
 	// Install path within the sysroot. This is relative to usr/include.
 	To *string
 
-	// Path to the NOTICE file associated with the headers.
+	// Path to the NOTICE file associated with the headers. Not required if licenses
+	// is set.
 	License *string
 
 	// True if this API is not yet ready to be shipped in the NDK. It will be
@@ -333,17 +386,45 @@ type preprocessedHeadersModule struct {
 	properties preprocessedHeadersProperties
 
 	installPaths android.Paths
-	licensePath  android.Path
+	licenses     ndkHeaderLicenses
+	namespace    string
 }
 
-func (m *preprocessedHeadersModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	if String(m.properties.License) == "" {
-		ctx.PropertyErrorf("license", "field is required")
+func (m *preprocessedHeadersModule) DepsMutator(ctx android.BottomUpMutatorContext) {
+	if tool := android.SrcIsModule(String(m.properties.Preprocessor)); tool != "" {
+		ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(),
+			preprocessorDepTag, tool)
+	}
+}
+
+// preprocessorPath returns the path to the preprocessor, resolving references to
+// host tool modules.  It returns nil after reporting an error if the module is not
+// a host tool, a missing module is reported by the dependency.
+func (m *preprocessedHeadersModule) preprocessorPath(ctx android.ModuleContext) android.Path {
+	if android.SrcIsModule(String(m.properties.Preprocessor)) == "" {
+		return android.PathForModuleSrc(ctx, String(m.properties.Preprocessor))
 	}
 
-	preprocessor := android.PathForModuleSrc(ctx, String(m.properties.Preprocessor))
-	m.licensePath = android.PathForModuleSrc(ctx, String(m.properties.License))
+	var path android.Path
+	ctx.VisitDirectDepsWithTag(preprocessorDepTag, func(dep android.Module) {
+		if hostTool, ok := dep.(android.HostToolProvider); !ok || !hostTool.HostToolPath().Valid() {
+			ctx.PropertyErrorf("preprocessor", "module %q is not a host tool provider",
+				ctx.OtherModuleName(dep))
+		} else {
+			path = hostTool.HostToolPath().Path()
+		}
+	})
+	return path
+}
 
+func (m *preprocessedHeadersModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	m.licenses = collectNdkHeaderLicenses(ctx, m.properties.License)
+	m.namespace = ndkHeaderNamespace(ctx)
+
+	preprocessor := m.preprocessorPath(ctx)
+	if preprocessor == nil {
+		return
+	}
 	srcFiles := android.PathsForModuleSrcExcludes(ctx, m.properties.Srcs, m.properties.Exclude_srcs)
 	installDir := getCurrentIncludePath(ctx).Join(ctx, String(m.properties.To))
 	for _, src := range srcFiles {
@@ -357,6 +438,7 @@ func (m *preprocessedHeadersModule) GenerateAndroidBuildActions(ctx android.Modu
 			Output:      installPath,
 			Args: map[string]string{
 				"preprocessor": preprocessor.String(),
+				"args":         strings.Join(proptools.ShellEscapeList(m.properties.Args), " "),
 			},
 		})
 	}
@@ -0,0 +1,224 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"android/soong/android"
+)
+
+func testNdkHeaders(t *testing.T, bp string) (*android.TestContext, []error) {
+	t.Helper()
+	return testNdkHeadersWithFs(t, bp, nil)
+}
+
+// testNdkHeadersWithFs is like testNdkHeaders, but also parses the Android.bp files
+// in fs, which may define soong namespaces.
+func testNdkHeadersWithFs(t *testing.T, bp string, fs map[string][]byte) (*android.TestContext, []error) {
+	t.Helper()
+
+	config := TestConfig(buildDir, android.Android, nil, bp, fs)
+	ctx := CreateTestContext()
+	android.RegisterLicenseBuildComponents(ctx)
+	ctx.RegisterModuleType("soong_namespace", android.NamespaceFactory)
+	ctx.RegisterModuleType("ndk_headers", ndkHeadersFactory)
+	ctx.RegisterModuleType("preprocessed_ndk_headers", preprocessedNdkHeadersFactory)
+	ctx.RegisterSingletonType("ndk", NdkSingleton)
+	ctx.PreArchMutators(android.RegisterNamespaceMutator)
+	ctx.Register(config)
+
+	bpFiles := []string{"Android.bp"}
+	for file := range fs {
+		if filepath.Base(file) == "Android.bp" {
+			bpFiles = append(bpFiles, file)
+		}
+	}
+	sort.Strings(bpFiles[1:])
+	_, errs := ctx.ParseFileList(".", bpFiles)
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
+
+func TestPreprocessedNdkHeadersHostTool(t *testing.T) {
+	ctx, errs := testNdkHeaders(t, `
+		cc_binary_host {
+			name: "preprocessor",
+			stl: "none",
+		}
+
+		preprocessed_ndk_headers {
+			name: "foo_headers",
+			preprocessor: ":preprocessor",
+			args: ["--foo", "--bar=a b"],
+			srcs: ["foo.h"],
+			to: "android",
+			license: "NOTICE",
+		}
+	`)
+	android.FailIfErrored(t, errs)
+
+	preprocessor := ctx.ModuleForTests("preprocessor", android.BuildOs.String()+"_x86_64").Module()
+	preprocessorPath := preprocessor.(android.HostToolProvider).HostToolPath().String()
+
+	header := ctx.ModuleForTests("foo_headers", "").Rule("preprocessNdkHeader")
+	if got := header.Args["preprocessor"]; got != preprocessorPath {
+		t.Errorf("expected preprocessor %q, got %q", preprocessorPath, got)
+	}
+	if got, want := header.Args["args"], "--foo '--bar=a b'"; got != want {
+		t.Errorf("expected args %q, got %q", want, got)
+	}
+}
+
+func TestPreprocessedNdkHeadersNotHostTool(t *testing.T) {
+	_, errs := testNdkHeaders(t, `
+		cc_library_host_static {
+			name: "libpreprocessor",
+			stl: "none",
+		}
+
+		preprocessed_ndk_headers {
+			name: "foo_headers",
+			preprocessor: ":libpreprocessor",
+			srcs: ["foo.h"],
+			to: "android",
+			license: "NOTICE",
+		}
+	`)
+	android.FailIfNoMatchingErrors(t, `preprocessor: module "libpreprocessor" is not a host tool provider`, errs)
+	if len(errs) != 1 {
+		t.Errorf("expected the error to be reported once, got %q", errs)
+	}
+}
+
+func TestNdkHeadersLicenses(t *testing.T) {
+	ctx, errs := testNdkHeaders(t, `
+		license {
+			name: "foo_license",
+			license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+			license_text: ["LICENSE"],
+		}
+
+		ndk_headers {
+			name: "foo_headers",
+			from: "include",
+			to: "",
+			srcs: ["include/foo.h"],
+			license: "NOTICE",
+			licenses: ["foo_license"],
+		}
+	`)
+	android.FailIfErrored(t, errs)
+
+	foo := ctx.ModuleForTests("foo_headers", "").Module().(*headerModule)
+	if got, expected := foo.licenses.texts.Strings(), []string{"NOTICE", "LICENSE"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected license texts %q, got %q", expected, got)
+	}
+
+	content := ctx.SingletonForTests("ndk").Description("generate license_metadata.json").Args["content"]
+	var licenseMetadata []ndkLicenseMetadata
+	if err := json.Unmarshal([]byte(content), &licenseMetadata); err != nil {
+		t.Fatalf("failed to parse license_metadata.json: %s", err)
+	}
+	expected := []ndkLicenseMetadata{
+		{
+			Module:       "foo_headers",
+			LicenseKinds: []string{"SPDX-license-identifier-Apache-2.0"},
+			LicenseTexts: []string{"NOTICE", "LICENSE"},
+		},
+	}
+	if !reflect.DeepEqual(licenseMetadata, expected) {
+		t.Errorf("expected license metadata %#v, got %#v", expected, licenseMetadata)
+	}
+}
+
+func TestNdkHeadersMissingLicense(t *testing.T) {
+	_, errs := testNdkHeaders(t, `
+		license {
+			name: "foo_license",
+			license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+		}
+
+		ndk_headers {
+			name: "foo_headers",
+			from: "include",
+			to: "",
+			srcs: ["include/foo.h"],
+			licenses: ["foo_license"],
+		}
+	`)
+	android.FailIfNoMatchingErrors(t, `license: field is required unless licenses provides license text`, errs)
+}
+
+func TestNdkHeaderManifestsNamespaces(t *testing.T) {
+	ndkHeaders := func(to string) []byte {
+		return []byte(`
+			soong_namespace {
+			}
+
+			ndk_headers {
+				name: "foo_headers",
+				from: "include",
+				to: "` + to + `",
+				srcs: ["include/foo.h"],
+				license: "NOTICE",
+			}
+		`)
+	}
+	ctx, errs := testNdkHeadersWithFs(t, "", map[string][]byte{
+		"a/Android.bp": ndkHeaders("a"),
+		"b/Android.bp": ndkHeaders("b"),
+	})
+	android.FailIfErrored(t, errs)
+
+	ndk := ctx.SingletonForTests("ndk")
+	for _, ns := range []string{"a", "b"} {
+		manifest := ndk.Output(filepath.Join("ndk_header_manifests", ns, "foo_headers.txt"))
+		if got, expected := manifest.Args["module"], "//"+ns+":foo_headers"; got != expected {
+			t.Errorf("expected module %q, got %q", expected, got)
+		}
+		if got, expected := manifest.Args["headers"], ns+"/foo.h"; got != expected {
+			t.Errorf("expected headers %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestNdkHeaderInstallsNamespaces(t *testing.T) {
+	ndkHeaders := []byte(`
+		soong_namespace {
+		}
+
+		ndk_headers {
+			name: "foo_headers",
+			from: "include",
+			to: "",
+			srcs: ["include/foo.h"],
+			license: "NOTICE",
+		}
+	`)
+	_, errs := testNdkHeadersWithFs(t, "", map[string][]byte{
+		"a/Android.bp": ndkHeaders,
+		"b/Android.bp": ndkHeaders,
+	})
+	android.FailIfNoMatchingErrors(t,
+		`header "foo.h" is installed by both module "//(a|b):foo_headers" and module "//(a|b):foo_headers"`, errs)
+}
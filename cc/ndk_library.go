@@ -35,6 +35,13 @@ var (
 			CommandDeps: []string{"$toolPath"},
 		}, "arch", "apiLevel", "apiMap", "flags")
 
+	genStubSymbolList = pctx.AndroidStaticRule("genStubSymbolList",
+		blueprint.RuleParams{
+			Command: "$toolPath --arch $arch --api $apiLevel --api-map " +
+				"$apiMap $flags --symbol-list $out $in $out.c $out.map",
+			CommandDeps: []string{"$toolPath"},
+		}, "arch", "apiLevel", "apiMap", "flags")
+
 	ndkLibrarySuffix = ".ndk"
 
 	ndkPrebuiltSharedLibs = []string{
@@ -133,12 +140,13 @@ func normalizeNdkApiLevel(ctx android.BaseModuleContext, apiLevel string,
 
 	minVersion := ctx.Config().MinSupportedSdkVersion()
 	firstArchVersions := map[android.ArchType]int{
-		android.Arm:    minVersion,
-		android.Arm64:  21,
-		android.Mips:   minVersion,
-		android.Mips64: 21,
-		android.X86:    minVersion,
-		android.X86_64: 21,
+		android.Arm:     minVersion,
+		android.Arm64:   21,
+		android.Mips:    minVersion,
+		android.Mips64:  21,
+		android.Riscv64: android.FutureApiLevel,
+		android.X86:     minVersion,
+		android.X86_64:  21,
 	}
 
 	firstArchVersion, ok := firstArchVersions[arch.ArchType]
@@ -285,11 +293,19 @@ func (stub *stubDecorator) compilerFlags(ctx ModuleContext, flags Flags, deps Pa
 }
 
 func compileStubLibrary(ctx ModuleContext, flags Flags, symbolFile, apiLevel, genstubFlags string) (Objects, android.ModuleGenPath) {
+	return compileStubLibraryFromPath(ctx, flags, android.PathForModuleSrc(ctx, symbolFile), apiLevel,
+		genstubFlags)
+}
+
+// compileStubLibraryFromPath is like compileStubLibrary, but takes the path to the symbol file,
+// which may belong to another module.
+func compileStubLibraryFromPath(ctx ModuleContext, flags Flags, symbolFilePath android.Path, apiLevel,
+	genstubFlags string) (Objects, android.ModuleGenPath) {
+
 	arch := ctx.Arch().ArchType.String()
 
 	stubSrcPath := android.PathForModuleGen(ctx, "stub.c")
 	versionScriptPath := android.PathForModuleGen(ctx, "stub.map")
-	symbolFilePath := android.PathForModuleSrc(ctx, symbolFile)
 	apiLevelsJson := android.GetApiLevelsJson(ctx)
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        genStubSrc,
@@ -310,6 +326,34 @@ func compileStubLibrary(ctx ModuleContext, flags Flags, symbolFile, apiLevel, ge
 	return compileObjs(ctx, flagsToBuilderFlags(flags), subdir, srcs, nil, nil), versionScriptPath
 }
 
+// genStubSymbolList generates the list of symbols that the symbol file exports for the current
+// architecture at the given API level.  gen_stub_libs.py always writes the stub source and version
+// script too, so they are declared as implicit outputs next to the list.
+func genStubSymbolList(ctx ModuleContext, symbolFilePath android.Path, apiLevel,
+	genstubFlags string) android.ModuleGenPath {
+
+	symbolListPath := android.PathForModuleGen(ctx, "stub_symbols", "symbols.txt")
+	apiLevelsJson := android.GetApiLevelsJson(ctx)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        genStubSymbolList,
+		Description: "generate stub symbol list " + symbolFilePath.Rel(),
+		Output:      symbolListPath,
+		ImplicitOutputs: android.WritablePaths{
+			android.PathForModuleGen(ctx, "stub_symbols", "symbols.txt.c"),
+			android.PathForModuleGen(ctx, "stub_symbols", "symbols.txt.map"),
+		},
+		Input:     symbolFilePath,
+		Implicits: []android.Path{apiLevelsJson},
+		Args: map[string]string{
+			"arch":     ctx.Arch().ArchType.String(),
+			"apiLevel": apiLevel,
+			"apiMap":   apiLevelsJson.String(),
+			"flags":    genstubFlags,
+		},
+	})
+	return symbolListPath
+}
+
 func (c *stubDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) Objects {
 	if !strings.HasSuffix(String(c.properties.Symbol_file), ".map.txt") {
 		ctx.PropertyErrorf("symbol_file", "must end with .map.txt")
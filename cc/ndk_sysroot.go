@@ -53,9 +53,26 @@ package cc
 // TODO(danalbert): Write `ndk_static_library` rule.
 
 import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
 	"android/soong/android"
 )
 
+var (
+	ndkHeaderManifest = pctx.AndroidStaticRule("ndkHeaderManifest",
+		blueprint.RuleParams{
+			Command: "rm -f $out && touch $out && for f in $headers; do " +
+				"echo \"$$f $module $$(sha256sum $includeDir/$$f | cut -d' ' -f1)\" >> $out; done",
+			Description: "generate NDK header manifest $out",
+		},
+		"includeDir", "headers", "module")
+)
+
 func init() {
 	android.RegisterModuleType("ndk_headers", ndkHeadersFactory)
 	android.RegisterModuleType("ndk_library", NdkLibraryFactory)
@@ -71,7 +88,16 @@ func getNdkInstallBase(ctx android.PathContext) android.InstallPath {
 }
 
 // Returns the main install directory for the NDK sysroot. Usable with --sysroot.
+//
+// When packaging the NDK for a specific host OS (Ndk_sysroot_host_os), the sysroot
+// is placed in a per-host directory so that sysroots for several hosts can be
+// generated side by side:
+//
+//    $NDK_INSTALL_BASE/<host os>/sysroot
 func getNdkSysrootBase(ctx android.PathContext) android.InstallPath {
+	if hostOs := ctx.Config().NdkSysrootHostOs(); hostOs != android.NoOsType {
+		return getNdkInstallBase(ctx).Join(ctx, hostOs.Name, "sysroot")
+	}
 	return getNdkInstallBase(ctx).Join(ctx, "sysroot")
 }
 
@@ -94,40 +120,166 @@ func NdkSingleton() android.Singleton {
 
 type ndkSingleton struct{}
 
+// ndkHeaderInstall records a header installed into the NDK sysroot include
+// directory by an ndk_headers-like module.
+type ndkHeaderInstall struct {
+	// Name of the module qualified with its namespace, see ndkQualifiedModuleName.
+	module string
+	// Install path relative to the sysroot include directory.
+	path string
+}
+
+// checkNdkHeaderInstalls validates the full set of headers installed into the
+// NDK sysroot. It reports headers that are installed outside of the include
+// directory, headers installed by more than one module, and headers whose
+// paths differ only by case, which collide on case-insensitive filesystems
+// used by macOS and Windows NDK users.
+func checkNdkHeaderInstalls(installs []ndkHeaderInstall) []error {
+	var errs []error
+	byPath := make(map[string]ndkHeaderInstall)
+	byLowerPath := make(map[string]ndkHeaderInstall)
+	for _, install := range installs {
+		if filepath.IsAbs(install.path) || install.path == ".." ||
+			strings.HasPrefix(install.path, "../") {
+			errs = append(errs, fmt.Errorf(
+				"module %q installs header %q outside of the NDK sysroot include directory",
+				install.module, install.path))
+			continue
+		}
+
+		if prev, ok := byPath[install.path]; ok {
+			if prev.module != install.module {
+				errs = append(errs, fmt.Errorf(
+					"header %q is installed by both module %q and module %q",
+					install.path, prev.module, install.module))
+			}
+			continue
+		}
+		byPath[install.path] = install
+
+		lowerPath := strings.ToLower(install.path)
+		if prev, ok := byLowerPath[lowerPath]; ok {
+			errs = append(errs, fmt.Errorf(
+				"header %q (module %q) and header %q (module %q) differ only by case",
+				prev.path, prev.module, install.path, install.module))
+			continue
+		}
+		byLowerPath[lowerPath] = install
+	}
+	return errs
+}
+
+// ndkLicenseMetadata describes the licenses of the headers installed into the NDK
+// sysroot by a single module. The full list is written to license_metadata.json in the
+// sysroot for use by license tooling.
+type ndkLicenseMetadata struct {
+	Module       string   `json:"module"`
+	LicenseKinds []string `json:"license_kinds,omitempty"`
+	LicenseTexts []string `json:"license_texts"`
+}
+
+// ndkQualifiedModuleName returns the name of an NDK header module qualified with the path
+// of its namespace, as module names are only unique within a namespace.
+func ndkQualifiedModuleName(ctx android.SingletonContext, module android.Module, namespace string) string {
+	if namespace == "." {
+		return ctx.ModuleName(module)
+	}
+	return "//" + namespace + ":" + ctx.ModuleName(module)
+}
+
 func (n *ndkSingleton) GenerateBuildActions(ctx android.SingletonContext) {
 	var staticLibInstallPaths android.Paths
 	var installPaths android.Paths
 	var licensePaths android.Paths
+	var headerInstalls []ndkHeaderInstall
+
+	includeDir := getCurrentIncludePath(ctx).String()
+	addHeaderInstalls := func(module android.Module, namespace string, paths android.Paths) {
+		for _, path := range paths {
+			rel, err := filepath.Rel(includeDir, path.String())
+			if err != nil {
+				ctx.ModuleErrorf(module, "filepath.Rel(%q, %q) failed: %s", includeDir,
+					path.String(), err)
+				continue
+			}
+			headerInstalls = append(headerInstalls, ndkHeaderInstall{
+				module: ndkQualifiedModuleName(ctx, module, namespace),
+				path:   rel,
+			})
+		}
+	}
+
+	// Each module that installs headers into the sysroot gets a manifest fragment
+	// listing the installed path, the module name and the sha256 of each header.
+	// Module names are only unique within a namespace, so both the fragment path and
+	// the listed name are qualified with the namespace of the module.
+	var headerManifests android.Paths
+	addHeaderManifest := func(module android.Module, namespace string, paths android.Paths) {
+		var headers []string
+		for _, path := range paths {
+			headers = append(headers, android.Rel(ctx, includeDir, path.String()))
+		}
+		manifest := android.PathForOutput(ctx, "ndk_header_manifests", namespace,
+			ctx.ModuleName(module)+".txt")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:      ndkHeaderManifest,
+			Output:    manifest,
+			Implicits: paths,
+			Args: map[string]string{
+				"includeDir": includeDir,
+				"headers":    strings.Join(headers, " "),
+				"module":     ndkQualifiedModuleName(ctx, module, namespace),
+			},
+		})
+		headerManifests = append(headerManifests, manifest)
+	}
+
+	var licenseMetadata []ndkLicenseMetadata
+	addLicenses := func(module android.Module, licenses ndkHeaderLicenses) {
+		licensePaths = append(licensePaths, licenses.texts...)
+		licenseMetadata = append(licenseMetadata, ndkLicenseMetadata{
+			Module:       ctx.ModuleName(module),
+			LicenseKinds: licenses.kinds,
+			LicenseTexts: licenses.texts.Strings(),
+		})
+	}
+
 	ctx.VisitAllModules(func(module android.Module) {
 		if m, ok := module.(android.Module); ok && !m.Enabled() {
 			return
 		}
 
 		if m, ok := module.(*headerModule); ok {
+			addHeaderInstalls(m, m.namespace, m.installPaths)
 			if ctx.Config().ExcludeDraftNdkApis() && m.properties.Draft {
 				return
 			}
 
 			installPaths = append(installPaths, m.installPaths...)
-			licensePaths = append(licensePaths, m.licensePath)
+			addHeaderManifest(m, m.namespace, m.installPaths)
+			addLicenses(m, m.licenses)
 		}
 
 		if m, ok := module.(*versionedHeaderModule); ok {
+			addHeaderInstalls(m, m.namespace, m.installPaths)
 			if ctx.Config().ExcludeDraftNdkApis() && m.properties.Draft {
 				return
 			}
 
 			installPaths = append(installPaths, m.installPaths...)
-			licensePaths = append(licensePaths, m.licensePath)
+			addHeaderManifest(m, m.namespace, m.installPaths)
+			addLicenses(m, m.licenses)
 		}
 
 		if m, ok := module.(*preprocessedHeadersModule); ok {
+			addHeaderInstalls(m, m.namespace, m.installPaths)
 			if ctx.Config().ExcludeDraftNdkApis() && m.properties.Draft {
 				return
 			}
 
 			installPaths = append(installPaths, m.installPaths...)
-			licensePaths = append(licensePaths, m.licensePath)
+			addHeaderManifest(m, m.namespace, m.installPaths)
+			addLicenses(m, m.licenses)
 		}
 
 		if m, ok := module.(*Module); ok {
@@ -148,6 +300,10 @@ func (n *ndkSingleton) GenerateBuildActions(ctx android.SingletonContext) {
 		}
 	})
 
+	for _, err := range checkNdkHeaderInstalls(headerInstalls) {
+		ctx.Errorf("%s", err)
+	}
+
 	// Include only a single copy of each license file. The Bionic NOTICE is
 	// long and is referenced by multiple Bionic modules.
 	licensePaths = android.FirstUniquePaths(licensePaths)
@@ -160,7 +316,33 @@ func (n *ndkSingleton) GenerateBuildActions(ctx android.SingletonContext) {
 		Inputs:      licensePaths,
 	})
 
-	baseDepPaths := append(installPaths, combinedLicense)
+	licenseMetadataFile := getNdkSysrootBase(ctx).Join(ctx, "license_metadata.json")
+	licenseMetadataJson, err := json.Marshal(licenseMetadata)
+	if err != nil {
+		ctx.Errorf("json marshal to %q failed: %#v", licenseMetadataFile, err)
+		return
+	}
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Description: "generate " + licenseMetadataFile.Base(),
+		Output:      licenseMetadataFile,
+		Args: map[string]string{
+			"content": string(licenseMetadataJson),
+		},
+	})
+
+	// The header manifest records every header installed into the sysroot include
+	// directory so that prebuilt NDK drops can be verified against the platform
+	// sources they were built from.
+	headerManifest := getNdkSysrootBase(ctx).Join(ctx, "header_manifest.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Cat,
+		Description: "combine NDK header manifests",
+		Output:      headerManifest,
+		Inputs:      headerManifests,
+	})
+
+	baseDepPaths := append(installPaths, combinedLicense, licenseMetadataFile, headerManifest)
 
 	// There's a dummy "ndk" rule defined in ndk/Android.mk that depends on
 	// this. `m ndk` will build the sysroots.
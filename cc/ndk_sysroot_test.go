@@ -0,0 +1,107 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+func TestCheckNdkHeaderInstalls(t *testing.T) {
+	testCases := []struct {
+		name     string
+		installs []ndkHeaderInstall
+		expected []string
+	}{
+		{
+			name: "valid",
+			installs: []ndkHeaderInstall{
+				{module: "libfoo_headers", path: "foo/foo.h"},
+				{module: "libfoo_headers", path: "foo/bar.h"},
+				{module: "libbar_headers", path: "bar/bar.h"},
+			},
+		},
+		{
+			name: "same module twice",
+			installs: []ndkHeaderInstall{
+				{module: "libfoo_headers", path: "foo/foo.h"},
+				{module: "libfoo_headers", path: "foo/foo.h"},
+			},
+		},
+		{
+			name: "duplicate",
+			installs: []ndkHeaderInstall{
+				{module: "libfoo_headers", path: "foo/foo.h"},
+				{module: "libbar_headers", path: "foo/foo.h"},
+			},
+			expected: []string{
+				`header "foo/foo.h" is installed by both module "libfoo_headers" and module "libbar_headers"`,
+			},
+		},
+		{
+			name: "case collision",
+			installs: []ndkHeaderInstall{
+				{module: "libfoo_headers", path: "foo/Foo.h"},
+				{module: "libbar_headers", path: "foo/foo.h"},
+			},
+			expected: []string{
+				`header "foo/Foo.h" (module "libfoo_headers") and header "foo/foo.h" (module "libbar_headers") differ only by case`,
+			},
+		},
+		{
+			name: "escape",
+			installs: []ndkHeaderInstall{
+				{module: "libfoo_headers", path: "../foo.h"},
+				{module: "libbar_headers", path: "/foo.h"},
+			},
+			expected: []string{
+				`module "libfoo_headers" installs header "../foo.h" outside of the NDK sysroot include directory`,
+				`module "libbar_headers" installs header "/foo.h" outside of the NDK sysroot include directory`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var errs []string
+			for _, err := range checkNdkHeaderInstalls(testCase.installs) {
+				errs = append(errs, err.Error())
+			}
+			if !reflect.DeepEqual(errs, testCase.expected) {
+				t.Errorf("incorrect errors\nexpected: %q\n     got: %q", testCase.expected, errs)
+			}
+		})
+	}
+}
+
+func TestNdkSysrootBase(t *testing.T) {
+	config := TestConfig(buildDir, android.Android, nil, "", nil)
+	ctx := android.PathContextForTesting(config)
+	ndkInstallBase := getNdkInstallBase(ctx).String()
+
+	if got, expected := getNdkSysrootBase(ctx).String(), filepath.Join(ndkInstallBase, "sysroot"); got != expected {
+		t.Errorf("expected sysroot %q, got %q", expected, got)
+	}
+
+	config.TestProductVariables.Ndk_sysroot_host_os = proptools.StringPtr("darwin")
+	if got, expected := getNdkSysrootBase(ctx).String(), filepath.Join(ndkInstallBase, "darwin", "sysroot"); got != expected {
+		t.Errorf("expected sysroot %q for darwin, got %q", expected, got)
+	}
+}
@@ -16,6 +16,7 @@ package cc
 
 import (
 	"fmt"
+	"strings"
 
 	"android/soong/android"
 )
@@ -41,6 +42,20 @@ var ccObjectSdkMemberType = &librarySdkMemberType{
 type objectLinker struct {
 	*baseLinker
 	Properties ObjectLinkerProperties
+
+	// the objects compiled from the sources of this module, in the order of the sources.
+	objFiles android.Paths
+
+	// the partially linked objects of partial_links, by name.
+	partialLinkOutputs map[string]android.Path
+}
+
+type ObjectPartialLinkProperties struct {
+	// name of the partially linked object, used as its file name and its output tag.
+	Name *string
+
+	// list of sources of this module whose objects are linked into the partially linked object.
+	Srcs []string `android:"path"`
 }
 
 type ObjectLinkerProperties struct {
@@ -55,6 +70,11 @@ type ObjectLinkerProperties struct {
 
 	// if set, the path to a linker script to pass to ld -r when combining multiple object files.
 	Linker_script *string `android:"path,arch_variant"`
+
+	// groups of sources to partially link into separate objects, in addition to the object of the
+	// module.  Each object is available with the name of its group as the output tag, for example
+	// ":module{.name}".  The objects of all sources are available with the ".objs" output tag.
+	Partial_links []ObjectPartialLinkProperties `android:"arch_variant"`
 }
 
 func newObject() *Module {
@@ -115,11 +135,14 @@ func (object *objectLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 func (object *objectLinker) link(ctx ModuleContext,
 	flags Flags, deps PathDeps, objs Objects) android.Path {
 
+	object.objFiles = objs.objFiles
 	objs = objs.Append(deps.Objs)
 
 	var outputFile android.Path
 	builderFlags := flagsToBuilderFlags(flags)
 
+	object.linkPartialLinks(ctx, builderFlags, flags.LdFlagsDeps)
+
 	if len(objs.objFiles) == 1 && String(object.Properties.Linker_script) == "" {
 		outputFile = objs.objFiles[0]
 
@@ -147,6 +170,61 @@ func (object *objectLinker) link(ctx ModuleContext,
 	return outputFile
 }
 
+// linkPartialLinks partially links the objects of each group of sources in partial_links.
+func (object *objectLinker) linkPartialLinks(ctx ModuleContext, builderFlags builderFlags, deps android.Paths) {
+	object.partialLinkOutputs = make(map[string]android.Path)
+	for i, partialLink := range object.Properties.Partial_links {
+		property := fmt.Sprintf("partial_links[%d]", i)
+
+		name := String(partialLink.Name)
+		if name == "" {
+			ctx.PropertyErrorf(property+".name", "must be set")
+			continue
+		}
+		if name == "objs" || strings.ContainsAny(name, "/.") {
+			ctx.PropertyErrorf(property+".name", "invalid name %q", name)
+			continue
+		}
+		if _, exists := object.partialLinkOutputs[name]; exists {
+			ctx.PropertyErrorf(property+".name", "%q is listed more than once", name)
+			continue
+		}
+
+		var objFiles android.Paths
+		for _, src := range android.PathsForModuleSrc(ctx, partialLink.Srcs) {
+			objFile := android.ObjPathWithExt(ctx, "", src, "o")
+			if !android.InList(objFile.String(), object.objFiles.Strings()) {
+				ctx.PropertyErrorf(property+".srcs", "%q is not in the srcs of this module", src.Rel())
+				continue
+			}
+			objFiles = append(objFiles, objFile)
+		}
+		if len(objFiles) == 0 {
+			ctx.PropertyErrorf(property+".srcs", "must not be empty")
+			continue
+		}
+
+		output := android.PathForModuleOut(ctx, "partial", name+objectExtension)
+		TransformObjsToObj(ctx, objFiles, builderFlags, output, deps)
+		object.partialLinkOutputs[name] = output
+	}
+}
+
+// taggedOutputFiles returns the objects of the sources of the module for the ".objs" tag, or the
+// partially linked object of the group in partial_links with the name of the tag.
+func (object *objectLinker) taggedOutputFiles(tag string) (android.Paths, bool) {
+	if tag == ".objs" {
+		return object.objFiles, true
+	}
+	if !strings.HasPrefix(tag, ".") {
+		return nil, false
+	}
+	if output, ok := object.partialLinkOutputs[strings.TrimPrefix(tag, ".")]; ok {
+		return android.Paths{output}, true
+	}
+	return nil, false
+}
+
 func (object *objectLinker) unstrippedOutputFilePath() android.Path {
 	return nil
 }
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -29,3 +30,73 @@ func TestLinkerScript(t *testing.T) {
 	})
 
 }
+
+func TestPartialLinks(t *testing.T) {
+	ctx := testCc(t, `
+		cc_object {
+			name: "foo",
+			srcs: ["a.c", "b.c", "c.c"],
+			partial_links: [
+				{
+					name: "ab",
+					srcs: ["a.c", "b.c"],
+				},
+			],
+		}`)
+
+	foo := ctx.ModuleForTests("foo", "android_arm64_armv8-a")
+	ab := foo.Output("partial/ab.o")
+	if inputs := ab.Inputs.Strings(); len(inputs) != 2 ||
+		inputs[0] != foo.Output("obj/a.o").Output.String() ||
+		inputs[1] != foo.Output("obj/b.o").Output.String() {
+		t.Errorf("expected partial link of a.o and b.o, got %q", inputs)
+	}
+
+	module := foo.Module().(*Module)
+	outputs, err := module.OutputFiles(".ab")
+	if err != nil {
+		t.Fatalf("unexpected error for the .ab output tag: %s", err)
+	}
+	if !reflect.DeepEqual(outputs.Strings(), []string{ab.Output.String()}) {
+		t.Errorf("expected the .ab output tag to be %q, got %q", ab.Output.String(), outputs.Strings())
+	}
+	if _, err := module.OutputFiles("ab"); err == nil {
+		t.Errorf("expected an error for the ab output tag without the leading \".\"")
+	}
+
+	objs, err := module.OutputFiles(".objs")
+	if err != nil {
+		t.Fatalf("unexpected error for the .objs output tag: %s", err)
+	}
+	if len(objs) != 3 {
+		t.Errorf("expected the .objs output tag to contain 3 objects, got %q", objs.Strings())
+	}
+
+	testCcError(t, `partial_links\[0\].srcs: "d.c" is not in the srcs of this module`, `
+		cc_object {
+			name: "foo",
+			srcs: ["a.c"],
+			partial_links: [
+				{
+					name: "d",
+					srcs: ["d.c"],
+				},
+			],
+		}`)
+
+	testCcError(t, `partial_links\[1\].name: "a" is listed more than once`, `
+		cc_object {
+			name: "foo",
+			srcs: ["a.c", "b.c"],
+			partial_links: [
+				{
+					name: "a",
+					srcs: ["a.c"],
+				},
+				{
+					name: "a",
+					srcs: ["b.c"],
+				},
+			],
+		}`)
+}
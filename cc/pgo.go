@@ -186,6 +186,54 @@ func (props *PgoProperties) addProfileUseFlags(ctx ModuleContext, flags Flags) F
 	return flags
 }
 
+// getProductProfileFile returns the profile for this module from the PGO or AFDO profile
+// directories set by the product, and whether it is a sampling profile.
+func getProductProfileFile(ctx BaseModuleContext) (android.OptionalPath, bool) {
+	if dir := ctx.DeviceConfig().PgoProfileDir(); dir != "" {
+		if path := android.ExistentPathForSource(ctx, dir, ctx.ModuleName()+".profdata"); path.Valid() {
+			return path, false
+		}
+	}
+	if dir := ctx.DeviceConfig().AfdoProfileDir(); dir != "" {
+		if path := android.ExistentPathForSource(ctx, dir, ctx.ModuleName()+".afdo"); path.Valid() {
+			return path, true
+		}
+	}
+	return android.OptionalPath{}, false
+}
+
+// addProductProfileUseFlags adds the flags to use the profile the product provides for a module
+// that doesn't have a pgo property.
+func (props *PgoProperties) addProductProfileUseFlags(ctx ModuleContext, flags Flags) Flags {
+	if !props.PgoCompile {
+		return flags
+	}
+
+	profileFile, sampling := getProductProfileFile(ctx)
+	profileFilePath := profileFile.Path()
+
+	var profileUseFlags []string
+	if sampling {
+		profileUseFlags = append(profileUseFlags, fmt.Sprintf(profileUseSamplingFormat, profileFilePath))
+	} else {
+		profileUseFlags = append(profileUseFlags, fmt.Sprintf(profileUseInstrumentFormat, profileFilePath))
+	}
+	profileUseFlags = append(profileUseFlags, profileUseOtherFlags...)
+
+	flags.Local.CFlags = append(flags.Local.CFlags, profileUseFlags...)
+	flags.Local.LdFlags = append(flags.Local.LdFlags, profileUseFlags...)
+
+	// Update CFlagsDeps and LdFlagsDeps so the module is rebuilt
+	// if profileFile gets updated
+	flags.CFlagsDeps = append(flags.CFlagsDeps, profileFilePath)
+	flags.LdFlagsDeps = append(flags.LdFlagsDeps, profileFilePath)
+
+	if sampling {
+		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,-mllvm,-no-warn-sample-unused=true")
+	}
+	return flags
+}
+
 func (props *PgoProperties) isPGO(ctx BaseModuleContext) bool {
 	isInstrumentation := props.isInstrumentation()
 	isSampling := props.isSampling()
@@ -232,6 +280,15 @@ func (pgo *pgo) begin(ctx BaseModuleContext) {
 	pgo.Properties.PgoPresent = pgo.Properties.isPGO(ctx)
 
 	if !pgo.Properties.PgoPresent {
+		// Use the profile from the product's PGO or AFDO profile directory if there is one
+		// for this module.
+		if !ctx.DeviceConfig().ClangCoverageEnabled() &&
+			!ctx.Config().IsEnvTrue("ANDROID_PGO_NO_PROFILE_USE") &&
+			proptools.BoolDefault(pgo.Properties.Pgo.Enable_profile_use, true) {
+			if profileFile, _ := getProductProfileFile(ctx); profileFile.Valid() {
+				pgo.Properties.PgoCompile = true
+			}
+		}
 		return
 	}
 
@@ -296,7 +353,10 @@ func (pgo *pgo) flags(ctx ModuleContext, flags Flags) Flags {
 	}
 
 	if !ctx.Config().IsEnvTrue("ANDROID_PGO_NO_PROFILE_USE") {
-		return props.addProfileUseFlags(ctx, flags)
+		if props.PgoPresent {
+			return props.addProfileUseFlags(ctx, flags)
+		}
+		return props.addProductProfileUseFlags(ctx, flags)
 	}
 
 	return flags
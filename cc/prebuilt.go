@@ -15,6 +15,8 @@
 package cc
 
 import (
+	"github.com/google/blueprint"
+
 	"android/soong/android"
 )
 
@@ -65,17 +67,57 @@ type prebuiltLibraryInterface interface {
 	disablePrebuilt()
 }
 
+type prebuiltLibraryProperties struct {
+	Source_abi_check struct {
+		// Compare the symbols exported by the prebuilt shared library with the symbols exported
+		// by the source module with the same name, and fail the build if they differ.
+		Enabled *bool
+
+		// the header ABI dump of the prebuilt shared library.  If set, it is compared with the
+		// header ABI dump of the source module, which must create one.
+		Abi_dump *string `android:"path"`
+	}
+}
+
 type prebuiltLibraryLinker struct {
 	*libraryDecorator
 	prebuiltLinker
+
+	libraryProperties prebuiltLibraryProperties
+
+	// the outputs of the checks that the prebuilt matches its source module.
+	sourceAbiChecks android.Paths
 }
 
+// prebuiltSourceDependencyTag is the dependency tag from a prebuilt library to the source module
+// with the same name, used to compare their ABIs.
+type prebuiltSourceDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+// The dependency is on the source module even when the prebuilt is preferred.
+func (prebuiltSourceDependencyTag) ReplaceSourceWithPrebuilt() bool {
+	return false
+}
+
+var prebuiltSourceDepTag prebuiltSourceDependencyTag
+
+var _ android.ReplaceSourceWithPrebuilt = prebuiltSourceDepTag
+
 var _ prebuiltLinkerInterface = (*prebuiltLibraryLinker)(nil)
 var _ prebuiltLibraryInterface = (*prebuiltLibraryLinker)(nil)
 
 func (p *prebuiltLibraryLinker) linkerInit(ctx BaseModuleContext) {}
 
 func (p *prebuiltLibraryLinker) linkerDeps(ctx DepsContext, deps Deps) Deps {
+	if Bool(p.libraryProperties.Source_abi_check.Enabled) && p.shared() {
+		if p.Prebuilt.SourceExists() {
+			ctx.AddVariationDependencies(nil, prebuiltSourceDepTag, ctx.baseModuleName())
+		} else {
+			ctx.PropertyErrorf("source_abi_check.enabled", "there is no source module named %q",
+				ctx.baseModuleName())
+		}
+	}
 	return p.libraryDecorator.linkerDeps(ctx, deps)
 }
 
@@ -91,6 +133,7 @@ func (p *prebuiltLibraryLinker) link(ctx ModuleContext,
 	flags Flags, deps PathDeps, objs Objects) android.Path {
 
 	p.libraryDecorator.exportIncludes(ctx)
+	p.libraryDecorator.exportModuleMap(ctx)
 	p.libraryDecorator.reexportDirs(deps.ReexportedDirs...)
 	p.libraryDecorator.reexportSystemDirs(deps.ReexportedSystemDirs...)
 	p.libraryDecorator.reexportFlags(deps.ReexportedFlags...)
@@ -123,6 +166,10 @@ func (p *prebuiltLibraryLinker) link(ctx ModuleContext,
 			tocFile := android.PathForModuleOut(ctx, libName+".toc")
 			p.tocFile = android.OptionalPathForPath(tocFile)
 			TransformSharedObjectToToc(ctx, in, tocFile, builderFlags)
+
+			if Bool(p.libraryProperties.Source_abi_check.Enabled) {
+				p.checkSourceAbi(ctx, libName)
+			}
 		}
 
 		return in
@@ -131,6 +178,40 @@ func (p *prebuiltLibraryLinker) link(ctx ModuleContext,
 	return nil
 }
 
+// checkSourceAbi compares the exported symbols, and optionally the header ABI dump, of the prebuilt
+// shared library with those of its source module.
+func (p *prebuiltLibraryLinker) checkSourceAbi(ctx ModuleContext, libName string) {
+	ctx.VisitDirectDepsWithTag(prebuiltSourceDepTag, func(dep android.Module) {
+		source, ok := dep.(*Module)
+		if !ok || source.UnstrippedOutputFile() == nil {
+			ctx.PropertyErrorf("source_abi_check.enabled", "source module %q is not a shared library",
+				ctx.OtherModuleName(dep))
+			return
+		}
+
+		symbolsCheck := android.PathForModuleOut(ctx, "source_abi_check", libName+".symbols")
+		TransformPrebuiltSymbolsCheck(ctx, p.unstrippedOutputFile, source.UnstrippedOutputFile(), symbolsCheck)
+		p.sourceAbiChecks = append(p.sourceAbiChecks, symbolsCheck)
+
+		if p.libraryProperties.Source_abi_check.Abi_dump != nil {
+			abiDump := android.PathForModuleSrc(ctx, *p.libraryProperties.Source_abi_check.Abi_dump)
+			library, ok := source.linker.(*libraryDecorator)
+			if !ok || !library.sAbiOutputFile.Valid() {
+				ctx.PropertyErrorf("source_abi_check.abi_dump",
+					"source module %q does not create a header ABI dump", ctx.OtherModuleName(dep))
+				return
+			}
+			abiDiff := android.PathForModuleOut(ctx, "source_abi_check", libName+".abidiff")
+			TransformPrebuiltAbiDumpDiff(ctx, abiDump, library.sAbiOutputFile.Path(), abiDiff)
+			p.sourceAbiChecks = append(p.sourceAbiChecks, abiDiff)
+		}
+	})
+
+	for _, check := range p.sourceAbiChecks {
+		ctx.CheckbuildFile(check)
+	}
+}
+
 func (p *prebuiltLibraryLinker) prebuiltSrcs() []string {
 	srcs := p.properties.Srcs
 	if p.static() {
@@ -169,7 +250,7 @@ func NewPrebuiltLibrary(hod android.HostOrDeviceSupported) (*Module, *libraryDec
 	module.linker = prebuilt
 	module.installer = prebuilt
 
-	module.AddProperties(&prebuilt.properties)
+	module.AddProperties(&prebuilt.properties, &prebuilt.libraryProperties)
 
 	srcsSupplier := func() []string {
 		return prebuilt.prebuiltSrcs()
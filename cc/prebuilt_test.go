@@ -221,3 +221,55 @@ func TestPrebuiltLibrary(t *testing.T) {
 	static := ctx.ModuleForTests("libtest", "android_arm64_armv8-a_static").Module().(*Module)
 	assertString(t, static.OutputFile().String(), "libf.a")
 }
+
+func TestPrebuiltLibrarySourceAbiCheck(t *testing.T) {
+	ctx := testPrebuilt(t, `
+	cc_library_shared {
+		name: "libtest",
+	}
+
+	cc_prebuilt_library_shared {
+		name: "libtest",
+		srcs: ["libf.so"],
+		source_abi_check: {
+			enabled: true,
+		},
+	}
+	`)
+
+	prebuilt := ctx.ModuleForTests("prebuilt_libtest", "android_arm64_armv8-a_shared")
+	source := ctx.ModuleForTests("libtest", "android_arm64_armv8-a_shared")
+
+	check := prebuilt.Rule("prebuiltSymbolsCheck")
+	if check.Input.String() != "libf.so" {
+		t.Errorf("expected symbols check input libf.so, got %q", check.Input.String())
+	}
+	sourceLib := source.Module().(*Module).UnstrippedOutputFile()
+	if check.Implicit != sourceLib {
+		t.Errorf("expected symbols check to depend on %q, got %q", sourceLib, check.Implicit)
+	}
+	if check.Args["source"] != sourceLib.String() {
+		t.Errorf("expected source arg %q, got %q", sourceLib.String(), check.Args["source"])
+	}
+}
+
+func TestPrebuiltLibrarySourceAbiCheckMissingSource(t *testing.T) {
+	fs := map[string][]byte{
+		"libf.so": nil,
+	}
+	config := TestConfig(buildDir, android.Android, nil, `
+	cc_prebuilt_library_shared {
+		name: "libtest",
+		srcs: ["libf.so"],
+		source_abi_check: {
+			enabled: true,
+		},
+	}
+	`, fs)
+	ctx := CreateTestContext()
+	ctx.Register(config)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfNoMatchingErrors(t, `there is no source module named "libtest"`, errs)
+}
@@ -20,24 +20,31 @@ import (
 	"android/soong/android"
 )
 
-// genProto creates a rule to convert a .proto file to generated .pb.cc and .pb.h files and returns
-// the paths to the generated files.
-func genProto(ctx android.ModuleContext, protoFile android.Path, flags builderFlags) (cc, header android.WritablePath) {
-	var ccFile, headerFile android.ModuleGenPath
+// grpcCppPlugin is the proto plugin that generates gRPC C++ services in addition to the C++
+// messages.
+const grpcCppPlugin = "grpc-cpp"
+
+// grpcCppPluginModule is the host tool used as the grpc-cpp proto plugin.
+const grpcCppPluginModule = "grpc_cpp_plugin"
+
+// genProto creates a rule to convert a .proto file to generated .pb.cc and .pb.h files, and
+// .grpc.pb.cc and .grpc.pb.h files when using the grpc-cpp plugin, and returns the paths to the
+// generated sources and headers.  The first source is the .pb.cc file.
+func genProto(ctx android.ModuleContext, protoFile android.Path, flags builderFlags) (srcs, headers android.Paths) {
+	genPath := func(ext string) android.ModuleGenPath {
+		if flags.proto.CanonicalPathFromRoot {
+			return android.GenPathWithExt(ctx, "proto", protoFile, ext)
+		}
+		return android.PathForModuleGen(ctx, "proto", pathtools.ReplaceExtension(protoFile.Rel(), ext))
+	}
 
 	srcSuffix := ".cc"
 	if flags.protoC {
 		srcSuffix = ".c"
 	}
 
-	if flags.proto.CanonicalPathFromRoot {
-		ccFile = android.GenPathWithExt(ctx, "proto", protoFile, "pb"+srcSuffix)
-		headerFile = android.GenPathWithExt(ctx, "proto", protoFile, "pb.h")
-	} else {
-		rel := protoFile.Rel()
-		ccFile = android.PathForModuleGen(ctx, "proto", pathtools.ReplaceExtension(rel, "pb"+srcSuffix))
-		headerFile = android.PathForModuleGen(ctx, "proto", pathtools.ReplaceExtension(rel, "pb.h"))
-	}
+	ccFile := genPath("pb" + srcSuffix)
+	headerFile := genPath("pb.h")
 
 	protoDeps := flags.proto.Deps
 	if flags.protoOptionsFile {
@@ -49,6 +56,16 @@ func genProto(ctx android.ModuleContext, protoFile android.Path, flags builderFl
 	outDir := flags.proto.Dir
 	depFile := ccFile.ReplaceExtension(ctx, "d")
 	outputs := android.WritablePaths{ccFile, headerFile}
+	srcs = android.Paths{ccFile}
+	headers = android.Paths{headerFile}
+
+	if flags.protoGrpc {
+		grpcCcFile := genPath("grpc.pb.cc")
+		grpcHeaderFile := genPath("grpc.pb.h")
+		outputs = append(outputs, grpcCcFile, grpcHeaderFile)
+		srcs = append(srcs, grpcCcFile)
+		headers = append(headers, grpcHeaderFile)
+	}
 
 	rule := android.NewRuleBuilder()
 
@@ -56,7 +73,22 @@ func genProto(ctx android.ModuleContext, protoFile android.Path, flags builderFl
 
 	rule.Build(pctx, ctx, "protoc_"+protoFile.Rel(), "protoc "+protoFile.Rel())
 
-	return ccFile, headerFile
+	return srcs, headers
+}
+
+// protoPluginDeps adds the dependency on the proto plugin of a module.  The grpc-cpp plugin is
+// provided by grpc_cpp_plugin instead of a protoc-gen-grpc-cpp module.
+func protoPluginDeps(ctx DepsContext, p *android.ProtoProperties) {
+	if String(p.Proto.Plugin) != grpcCppPlugin {
+		android.ProtoDeps(ctx, p)
+		return
+	}
+
+	if String(p.Proto.Type) != "" {
+		ctx.ModuleErrorf("only one of proto.type and proto.plugin can be specified.")
+	}
+	ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(),
+		android.ProtoPluginDepTag, grpcCppPluginModule)
 }
 
 func protoDeps(ctx DepsContext, deps Deps, p *android.ProtoProperties, static bool) Deps {
@@ -108,6 +140,20 @@ func protoDeps(ctx DepsContext, deps Deps, p *android.ProtoProperties, static bo
 			deps.SharedLibs = append(deps.SharedLibs, lib)
 			deps.ReexportSharedLibHeaders = append(deps.ReexportSharedLibHeaders, lib)
 		}
+	} else if String(p.Proto.Plugin) == grpcCppPlugin {
+		// The generated services use the full protobuf runtime.
+		if ctx.useSdk() {
+			ctx.PropertyErrorf("proto.plugin", "%q is not supported with sdk_version", grpcCppPlugin)
+		}
+		libs := []string{"libprotobuf-cpp-full", "libgrpc++"}
+
+		if static {
+			deps.StaticLibs = append(deps.StaticLibs, libs...)
+			deps.ReexportStaticLibHeaders = append(deps.ReexportStaticLibHeaders, libs...)
+		} else {
+			deps.SharedLibs = append(deps.SharedLibs, libs...)
+			deps.ReexportSharedLibHeaders = append(deps.ReexportSharedLibHeaders, libs...)
+		}
 	}
 
 	return deps
@@ -151,6 +197,10 @@ func protoFlags(ctx ModuleContext, flags Flags, p *android.ProtoProperties) Flag
 			flags.proto.Deps = append(flags.proto.Deps, path)
 			flags.proto.Flags = append(flags.proto.Flags, "--plugin="+path.String())
 		}
+	} else if String(p.Proto.Plugin) == grpcCppPlugin {
+		// The plugin only generates the services, generate the messages alongside them.
+		flags.protoGrpc = true
+		flags.proto.Flags = append(flags.proto.Flags, "--cpp_out="+flags.proto.Dir.String())
 	}
 
 	return flags
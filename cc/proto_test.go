@@ -68,4 +68,66 @@ func TestProto(t *testing.T) {
 		}
 	})
 
+	t.Run("grpc-cpp", func(t *testing.T) {
+		ctx := testCc(t, `
+		cc_binary_host {
+			name: "grpc_cpp_plugin",
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libprotobuf-cpp-full",
+		}
+
+		cc_library {
+			name: "libgrpc++",
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["a.proto"],
+			proto: {
+				plugin: "grpc-cpp",
+				export_proto_headers: true,
+			},
+		}`)
+
+		buildOS := android.BuildOs.String()
+
+		libfoo := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_shared")
+		proto := libfoo.Output("proto/a.grpc.pb.cc")
+		grpcCppPlugin := ctx.ModuleForTests("grpc_cpp_plugin", buildOS+"_x86_64")
+
+		cmd := proto.RuleParams.Command
+		for _, w := range []string{"--grpc-cpp_out=", "--cpp_out="} {
+			if !strings.Contains(cmd, w) {
+				t.Errorf("expected %q in %q", w, cmd)
+			}
+		}
+
+		pluginPath := grpcCppPlugin.Module().(android.HostToolProvider).HostToolPath().String()
+		if w := "--plugin=protoc-gen-grpc-cpp=" + pluginPath; !strings.Contains(cmd, w) {
+			t.Errorf("expected %q in %q", w, cmd)
+		}
+
+		library := libfoo.Module().(*Module).linker.(*libraryDecorator)
+		var srcs []string
+		for _, src := range library.baseCompiler.srcs {
+			srcs = append(srcs, src.Rel())
+		}
+		for _, w := range []string{"proto/a.pb.cc", "proto/a.grpc.pb.cc"} {
+			if !android.InList(w, srcs) {
+				t.Errorf("expected %q in compiled sources %q", w, srcs)
+			}
+		}
+
+		var exportedDeps []string
+		for _, dep := range library.exportedDeps() {
+			exportedDeps = append(exportedDeps, dep.Rel())
+		}
+		if w := "proto/a.grpc.pb.h"; !android.InList(w, exportedDeps) {
+			t.Errorf("expected %q in exported deps %q", w, exportedDeps)
+		}
+	})
+
 }
@@ -81,6 +81,7 @@ func (sabimod *sabi) flags(ctx ModuleContext, flags Flags) Flags {
 func sabiDepsMutator(mctx android.TopDownMutatorContext) {
 	if c, ok := mctx.Module().(*Module); ok &&
 		((c.IsVndk() && c.UseVndk()) || c.isLlndk(mctx.Config()) ||
+			(c.isVendorAvailable() && c.inVendor()) ||
 			(c.sabi != nil && c.sabi.Properties.CreateSAbiDumps)) {
 		mctx.VisitDirectDeps(func(m android.Module) {
 			tag := mctx.OtherModuleDependencyTag(m)
@@ -97,6 +98,43 @@ func sabiDepsMutator(mctx android.TopDownMutatorContext) {
 	}
 }
 
+// abiLockedLibrariesKey caches the libraries listed in each ABI lock file.
+var abiLockedLibrariesKey = android.NewOnceKey("abiLockedLibraries")
+
+// abiLocked returns true if the library is listed in the ABI lock file of the reference ABI dump
+// directory dirName for the given version.  The ABI of a locked library must not change, so it
+// must have a reference ABI dump.  The lock file lists one library name per line, and lines
+// starting with # are comments.
+func abiLocked(ctx ModuleContext, dirName, version string) bool {
+	lockFile := android.PathForRefAbiLockFile(ctx, dirName, version)
+	if !lockFile.Valid() {
+		return false
+	}
+
+	lockFiles := ctx.Config().Once(abiLockedLibrariesKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+
+	libs, ok := lockFiles.Load(lockFile.String())
+	if !ok {
+		data, err := android.ReadSourceFile(ctx, lockFile.Path())
+		if err != nil {
+			ctx.ModuleErrorf("failed to read %s: %s", lockFile, err)
+			return false
+		}
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		libs, _ = lockFiles.LoadOrStore(lockFile.String(), lines)
+	}
+
+	return android.InList(ctx.baseModuleName(), libs.([]string))
+}
+
 func addLsdumpPath(lsdumpPath string) {
 	sabiLock.Lock()
 	lsdumpPaths = append(lsdumpPaths, lsdumpPath)
@@ -303,22 +303,20 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		}
 	}
 
-	// Enable CFI for all components in the include paths (for Aarch64 only)
-	if s.Cfi == nil && ctx.Config().CFIEnabledForPath(ctx.ModuleDir()) && ctx.Arch().ArchType == android.Arm64 {
-		s.Cfi = boolPtr(true)
-		if inList("cfi", ctx.Config().SanitizeDeviceDiag()) {
-			s.Diag.Cfi = boolPtr(true)
+	// Enable CFI and SCS for the components selected by the product.
+	if ctx.Device() {
+		if s.Cfi == nil && config.CfiEnabledByDefault(ctx.Config(), ctx.Arch().ArchType, ctx.ModuleDir()) {
+			s.Cfi = boolPtr(true)
+			if config.CfiDiagEnabledByDefault(ctx.Config()) {
+				s.Diag.Cfi = boolPtr(true)
+			}
+		}
+		if s.Scs == nil && config.ScsEnabledByDefault(ctx.Config(), ctx.Arch().ArchType, ctx.ModuleDir()) {
+			s.Scs = boolPtr(true)
 		}
 	}
 
-	// CFI needs gold linker, and mips toolchain does not have one.
-	if !ctx.Config().EnableCFI() || ctx.Arch().ArchType == android.Mips || ctx.Arch().ArchType == android.Mips64 {
-		s.Cfi = nil
-		s.Diag.Cfi = nil
-	}
-
-	// Also disable CFI for arm32 until b/35157333 is fixed.
-	if ctx.Arch().ArchType == android.Arm {
+	if !ctx.Config().EnableCFI() || !config.CfiSupportedArch(ctx.Arch().ArchType) {
 		s.Cfi = nil
 		s.Diag.Cfi = nil
 	}
@@ -328,8 +326,7 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		s.Hwaddress = nil
 	}
 
-	// SCS is only implemented on AArch64.
-	if ctx.Arch().ArchType != android.Arm64 {
+	if !config.ScsSupportedArch(ctx.Arch().ArchType) {
 		s.Scs = nil
 	}
 
@@ -443,18 +440,19 @@ func toDisableImplicitIntegerChange(flags []string) bool {
 
 func (sanitize *sanitize) flags(ctx ModuleContext, flags Flags) Flags {
 	minimalRuntimeLib := config.UndefinedBehaviorSanitizerMinimalRuntimeLibrary(ctx.toolchain()) + ".a"
-	minimalRuntimePath := "${config.ClangAsanLibDir}/" + minimalRuntimeLib
-	builtinsRuntimeLib := config.BuiltinsRuntimeLibrary(ctx.toolchain()) + ".a"
-	builtinsRuntimePath := "${config.ClangAsanLibDir}/" + builtinsRuntimeLib
 
 	if sanitize.Properties.MinimalRuntimeDep {
+		minimalRuntimePath := config.UndefinedBehaviorSanitizerMinimalRuntimeLibraryPath(ctx, ctx.toolchain())
 		flags.Local.LdFlags = append(flags.Local.LdFlags,
-			minimalRuntimePath,
+			minimalRuntimePath.String(),
 			"-Wl,--exclude-libs,"+minimalRuntimeLib)
+		flags.LdFlagsDeps = append(flags.LdFlagsDeps, minimalRuntimePath)
 	}
 
 	if sanitize.Properties.BuiltinsDep {
-		flags.libFlags = append([]string{builtinsRuntimePath}, flags.libFlags...)
+		builtinsRuntimePath := config.BuiltinsRuntimeLibraryPath(ctx, ctx.toolchain())
+		flags.libFlags = append([]string{builtinsRuntimePath.String()}, flags.libFlags...)
+		flags.LdFlagsDeps = append(flags.LdFlagsDeps, builtinsRuntimePath)
 	}
 
 	if !sanitize.Properties.SanitizerEnabled && !sanitize.Properties.UbsanRuntimeDep {
@@ -568,10 +566,14 @@ func (sanitize *sanitize) flags(ctx ModuleContext, flags Flags) Flags {
 
 		if enableMinimalRuntime(sanitize) {
 			flags.Local.CFlags = append(flags.Local.CFlags, strings.Join(minimalRuntimeFlags, " "))
-			flags.libFlags = append([]string{minimalRuntimePath}, flags.libFlags...)
+			minimalRuntimePath := config.UndefinedBehaviorSanitizerMinimalRuntimeLibraryPath(ctx, ctx.toolchain())
+			flags.libFlags = append([]string{minimalRuntimePath.String()}, flags.libFlags...)
 			flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--exclude-libs,"+minimalRuntimeLib)
+			flags.LdFlagsDeps = append(flags.LdFlagsDeps, minimalRuntimePath)
 			if !ctx.toolchain().Bionic() {
-				flags.libFlags = append([]string{builtinsRuntimePath}, flags.libFlags...)
+				builtinsRuntimePath := config.BuiltinsRuntimeLibraryPath(ctx, ctx.toolchain())
+				flags.libFlags = append([]string{builtinsRuntimePath.String()}, flags.libFlags...)
+				flags.LdFlagsDeps = append(flags.LdFlagsDeps, builtinsRuntimePath)
 			}
 		}
 
@@ -14,6 +14,10 @@
 package cc
 
 import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
 	"android/soong/android"
 )
 
@@ -63,6 +67,8 @@ func isSnapshotAware(ctx android.ModuleContext, m *Module) bool {
 		return ctx.Config().VndkSnapshotBuildArtifacts()
 	} else if isVendorSnapshotModule(m, ctx.ModuleDir()) {
 		return true
+	} else if isLlndkSnapshotModule(m) {
+		return true
 	}
 	return false
 }
@@ -93,3 +99,57 @@ func writeStringToFile(ctx android.SingletonContext, content, out string) androi
 	})
 	return outPath
 }
+
+// installLlndkStubLibrary copies an LLNDK stub library and its version script to
+// {dir}/arch-{arch}-{arch variant}/{subdir}/{version}/ along with a json file that describes them.
+// It is shared by the vendor snapshot and the LLNDK SDK.
+func installLlndkStubLibrary(ctx android.SingletonContext, m *Module, stub *llndkStubDecorator,
+	dir, subdir string) android.Paths {
+
+	targetArch := "arch-" + m.Target().Arch.ArchType.String()
+	if m.Target().Arch.ArchVariant != "" {
+		targetArch += "-" + m.Target().Arch.ArchVariant
+	}
+
+	version := stub.stubsVersion()
+	if version == "" {
+		version = "current"
+	}
+	libDir := filepath.Join(dir, targetArch, subdir, version)
+
+	var ret android.Paths
+
+	prop := struct {
+		ModuleName         string   `json:",omitempty"`
+		ExportedDirs       []string `json:",omitempty"`
+		ExportedSystemDirs []string `json:",omitempty"`
+		ExportedFlags      []string `json:",omitempty"`
+		VersionScript      string   `json:",omitempty"`
+	}{}
+
+	prop.ModuleName = strings.TrimSuffix(ctx.ModuleName(m), llndkLibrarySuffix)
+	prop.ExportedFlags = stub.exportedFlags()
+	for _, dir := range stub.exportedDirs() {
+		prop.ExportedDirs = append(prop.ExportedDirs, filepath.Join("include", dir.String()))
+	}
+	for _, dir := range stub.exportedSystemDirs() {
+		prop.ExportedSystemDirs = append(prop.ExportedSystemDirs, filepath.Join("include", dir.String()))
+	}
+
+	libPath := m.outputFile.Path()
+	stem := libPath.Base()
+	ret = append(ret, copyFile(ctx, libPath, filepath.Join(libDir, stem)))
+
+	prop.VersionScript = strings.TrimSuffix(stem, filepath.Ext(stem)) + ".map.txt"
+	ret = append(ret, copyFile(ctx, stub.versionScriptPath, filepath.Join(libDir, prop.VersionScript)))
+
+	propOut := filepath.Join(libDir, stem+".json")
+	j, err := json.Marshal(prop)
+	if err != nil {
+		ctx.Errorf("json marshal to %q failed: %#v", propOut, err)
+		return nil
+	}
+	ret = append(ret, writeStringToFile(ctx, string(j), propOut))
+
+	return ret
+}
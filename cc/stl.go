@@ -16,6 +16,7 @@ package cc
 
 import (
 	"android/soong/android"
+	"android/soong/cc/config"
 	"fmt"
 	"strconv"
 )
@@ -156,11 +157,7 @@ func needsLibAndroidSupport(ctx BaseModuleContext) bool {
 }
 
 func staticUnwinder(ctx android.BaseModuleContext) string {
-	if ctx.Arch().ArchType == android.Arm {
-		return "libunwind_llvm"
-	} else {
-		return "libgcc_stripped"
-	}
+	return config.StaticUnwinderLibrary(ctx.Config(), ctx.Arch().ArchType)
 }
 
 func (stl *stl) deps(ctx BaseModuleContext, deps Deps) Deps {
@@ -208,11 +205,7 @@ func (stl *stl) deps(ctx BaseModuleContext, deps Deps) Deps {
 		if needsLibAndroidSupport(ctx) {
 			deps.StaticLibs = append(deps.StaticLibs, "ndk_libandroid_support")
 		}
-		if ctx.Arch().ArchType == android.Arm {
-			deps.StaticLibs = append(deps.StaticLibs, "ndk_libunwind")
-		} else {
-			deps.StaticLibs = append(deps.StaticLibs, "libgcc_stripped")
-		}
+		deps.StaticLibs = append(deps.StaticLibs, config.NdkStaticUnwinderLibrary(ctx.Arch().ArchType))
 	default:
 		panic(fmt.Errorf("Unknown stl: %q", stl.Properties.SelectedStl))
 	}
@@ -258,7 +251,7 @@ func (stl *stl) flags(ctx ModuleContext, flags Flags) Flags {
 					"-D_LIBCPP_HAS_THREAD_API_WIN32")
 			}
 		} else {
-			if ctx.Arch().ArchType == android.Arm {
+			if config.UseCompilerRt(ctx.Config(), ctx.Arch().ArchType) {
 				flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--exclude-libs,libunwind_llvm.a")
 			}
 		}
@@ -27,6 +27,10 @@ type StripProperties struct {
 		Keep_symbols                 *bool    `android:"arch_variant"`
 		Keep_symbols_list            []string `android:"arch_variant"`
 		Keep_symbols_and_debug_frame *bool    `android:"arch_variant"`
+
+		// path to a file that lists the symbols to keep, one per line.  All other symbols are
+		// stripped.  Can be combined with keep_symbols_list.
+		Keep_symbols_list_file *string `android:"path,arch_variant"`
 	} `android:"arch_variant"`
 }
 
@@ -44,12 +48,21 @@ func (stripper *stripper) strip(ctx ModuleContext, in android.Path, out android.
 	if ctx.Darwin() {
 		TransformDarwinStrip(ctx, in, out)
 	} else {
+		keepSymbolsListFile := ctx.ExpandOptionalSource(stripper.StripProperties.Strip.Keep_symbols_list_file,
+			"strip.keep_symbols_list_file")
+		if keepSymbolsListFile.Valid() && (Bool(stripper.StripProperties.Strip.Keep_symbols) ||
+			Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame)) {
+			ctx.PropertyErrorf("strip.keep_symbols_list_file",
+				"cannot be used with strip.keep_symbols or strip.keep_symbols_and_debug_frame")
+		}
+
 		if Bool(stripper.StripProperties.Strip.Keep_symbols) {
 			flags.stripKeepSymbols = true
 		} else if Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame) {
 			flags.stripKeepSymbolsAndDebugFrame = true
-		} else if len(stripper.StripProperties.Strip.Keep_symbols_list) > 0 {
+		} else if len(stripper.StripProperties.Strip.Keep_symbols_list) > 0 || keepSymbolsListFile.Valid() {
 			flags.stripKeepSymbolsList = strings.Join(stripper.StripProperties.Strip.Keep_symbols_list, ",")
+			flags.stripKeepSymbolsListFile = keepSymbolsListFile
 		} else if !Bool(stripper.StripProperties.Strip.All) {
 			flags.stripKeepMiniDebugInfo = true
 		}
@@ -60,6 +73,13 @@ func (stripper *stripper) strip(ctx ModuleContext, in android.Path, out android.
 	}
 }
 
+// keepsSymbolsList returns true if the symbols listed in strip.keep_symbols_list or
+// strip.keep_symbols_list_file are kept and all other symbols are stripped.
+func (stripper *stripper) keepsSymbolsList() bool {
+	return stripper.StripProperties.Strip.Keep_symbols_list != nil ||
+		stripper.StripProperties.Strip.Keep_symbols_list_file != nil
+}
+
 func (stripper *stripper) stripExecutableOrSharedLib(ctx ModuleContext, in android.Path,
 	out android.ModuleOutPath, flags builderFlags) {
 	stripper.strip(ctx, in, out, flags, false)
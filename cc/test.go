@@ -384,6 +384,14 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 	test.binaryDecorator.baseInstaller.install(ctx, file)
 }
 
+func (test *testBinary) moduleInfoJSON(ctx ModuleContext, info *android.ModuleInfoJSON) {
+	info.Class = append(info.Class, "NATIVE_TESTS")
+	info.CompatibilitySuites = append(info.CompatibilitySuites, test.Properties.Test_suites...)
+	if test.testConfig != nil {
+		info.TestConfig = append(info.TestConfig, test.testConfig.String())
+	}
+}
+
 func NewTest(hod android.HostOrDeviceSupported) *Module {
 	module, binary := NewBinary(hod)
 	module.multilib = android.MultilibBoth
@@ -510,6 +518,14 @@ func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Pat
 	benchmark.binaryDecorator.baseInstaller.install(ctx, file)
 }
 
+func (benchmark *benchmarkDecorator) moduleInfoJSON(ctx ModuleContext, info *android.ModuleInfoJSON) {
+	info.Class = append(info.Class, "NATIVE_TESTS")
+	info.CompatibilitySuites = append(info.CompatibilitySuites, benchmark.Properties.Test_suites...)
+	if benchmark.testConfig != nil {
+		info.TestConfig = append(info.TestConfig, benchmark.testConfig.String())
+	}
+}
+
 func NewBenchmark(hod android.HostOrDeviceSupported) *Module {
 	module, binary := NewBinary(hod)
 	module.multilib = android.MultilibBoth
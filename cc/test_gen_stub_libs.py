@@ -118,6 +118,9 @@ class SymbolPresenceTest(unittest.TestCase):
 
         self.assertFalse(gsl.symbol_in_arch(['x86'], 'arm'))
 
+        self.assertTrue(gsl.symbol_in_arch(['riscv64'], 'riscv64'))
+        self.assertFalse(gsl.symbol_in_arch(['riscv64'], 'arm64'))
+
     def test_symbol_in_api(self):
         self.assertTrue(gsl.symbol_in_api([], 'arm', 9))
         self.assertTrue(gsl.symbol_in_api(['introduced=9'], 'arm', 9))
@@ -203,6 +206,30 @@ class OmitVersionTest(unittest.TestCase):
             gsl.should_omit_version(
                 gsl.Version('foo', None, ['apex'], []), 'arm', 9, False, True))
 
+    def test_omit_systemapi(self):
+        self.assertTrue(
+            gsl.should_omit_version(
+                gsl.Version('foo', None, ['systemapi'], []), 'arm', 9, True,
+                False, False))
+        self.assertTrue(
+            gsl.should_omit_version(
+                gsl.Version('foo', None, ['apex', 'systemapi'], []), 'arm', 9,
+                False, True, False))
+
+        # Variants that don't opt out keep the systemapi symbols.
+        self.assertFalse(
+            gsl.should_omit_version(
+                gsl.Version('foo', None, ['systemapi'], []), 'arm', 9, False,
+                False))
+        self.assertFalse(
+            gsl.should_omit_version(
+                gsl.Version('foo', None, ['systemapi'], []), 'arm', 9, True,
+                False))
+        self.assertFalse(
+            gsl.should_omit_version(
+                gsl.Version('foo', None, [], []), 'arm', 9, True, False,
+                False))
+
     def test_omit_arch(self):
         self.assertFalse(
             gsl.should_omit_version(
@@ -253,6 +280,31 @@ class OmitSymbolTest(unittest.TestCase):
             gsl.should_omit_symbol(
                 gsl.Symbol('foo', ['apex']), 'arm', 9, False, True))
 
+    def test_omit_systemapi(self):
+        self.assertTrue(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', ['systemapi']), 'arm', 9, True, False,
+                False))
+        self.assertTrue(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', ['llndk', 'systemapi']), 'arm', 9, True,
+                False, False))
+
+        # Variants that don't opt out keep the systemapi symbols.
+        self.assertFalse(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', ['systemapi']), 'arm', 9, False, False))
+        self.assertFalse(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', ['systemapi']), 'arm', 9, True, False))
+        self.assertFalse(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', ['apex', 'systemapi']), 'arm', 9, False,
+                True))
+        self.assertFalse(
+            gsl.should_omit_symbol(
+                gsl.Symbol('foo', []), 'arm', 9, True, False, False))
+
     def test_omit_arch(self):
         self.assertFalse(
             gsl.should_omit_symbol(gsl.Symbol('foo', []), 'arm', 9, False, False))
@@ -576,6 +628,54 @@ class GeneratorTest(unittest.TestCase):
         """)
         self.assertEqual(expected_version, version_file.getvalue())
 
+    def test_write_typed(self):
+        src_file = io.StringIO()
+        version_file = io.StringIO()
+        generator = gsl.Generator(src_file, version_file, 'arm', 9, False,
+                                  False, headers=['foo.h', 'bar/bar.h'])
+
+        versions = [
+            gsl.Version('VERSION_1', None, [], [
+                gsl.Symbol('foo', []),
+                gsl.Symbol('bar', ['var']),
+                gsl.Symbol('woodly', ['weak']),
+            ]),
+        ]
+
+        generator.write(versions)
+        expected_src = textwrap.dedent("""\
+            #include <foo.h>
+            #include <bar/bar.h>
+            void __typed_stub() {}
+            extern __typeof__(foo) foo __attribute__((alias("__typed_stub")));
+            __typeof__(bar) bar;
+            __attribute__((weak)) extern __typeof__(woodly) woodly __attribute__((alias("__typed_stub")));
+        """)
+        self.assertEqual(expected_src, src_file.getvalue())
+
+
+class ExportedSymbolsTest(unittest.TestCase):
+    def test_exported_symbols(self):
+        versions = [
+            gsl.Version('VERSION_1', None, [], [
+                gsl.Symbol('foo', []),
+                gsl.Symbol('bar', ['var']),
+                gsl.Symbol('baz', ['x86']),
+                gsl.Symbol('qux', ['introduced=14']),
+                gsl.Symbol('quux', ['apex']),
+            ]),
+            gsl.Version('VERSION_2', 'VERSION_1', ['arm64'], [
+                gsl.Symbol('woodly', []),
+            ]),
+        ]
+
+        self.assertEqual(['bar', 'foo'],
+                         gsl.exported_symbols(versions, 'arm', 9, False,
+                                              False))
+        self.assertEqual(['bar', 'foo', 'quux', 'qux'],
+                         gsl.exported_symbols(versions, 'arm', 14, False,
+                                              True))
+
 
 class IntegrationTest(unittest.TestCase):
     def test_integration(self):
@@ -798,6 +898,51 @@ class IntegrationTest(unittest.TestCase):
         """)
         self.assertEqual(expected_version, version_file.getvalue())
 
+    def test_integration_systemapi(self):
+        symbol_file = textwrap.dedent("""\
+            VERSION_1 {
+                global:
+                    foo;
+                    bar; # systemapi
+                    baz; # llndk systemapi
+                    qux; # apex systemapi
+                local:
+                    *;
+            };
+        """)
+
+        def generate(llndk, apex, systemapi=True):
+            parser = gsl.SymbolFileParser(io.StringIO(symbol_file), {}, 'arm',
+                                          9, llndk, apex, systemapi)
+            versions = parser.parse()
+            src_file = io.StringIO()
+            version_file = io.StringIO()
+            generator = gsl.Generator(src_file, version_file, 'arm', 9, llndk,
+                                      apex, systemapi)
+            generator.write(versions)
+            return src_file.getvalue()
+
+        # The NDK, LLNDK and APEX stubs keep the symbols tagged systemapi.
+        self.assertEqual(textwrap.dedent("""\
+            void foo() {}
+            void bar() {}
+        """), generate(False, False))
+        self.assertEqual(textwrap.dedent("""\
+            void foo() {}
+            void bar() {}
+            void baz() {}
+        """), generate(True, False))
+        self.assertEqual(textwrap.dedent("""\
+            void foo() {}
+            void bar() {}
+            void qux() {}
+        """), generate(False, True))
+
+        # Only stubs that opt out omit them.
+        self.assertEqual(textwrap.dedent("""\
+            void foo() {}
+        """), generate(True, False, False))
+
 def main():
     suite = unittest.TestLoader().loadTestsFromName(__name__)
     unittest.TextTestRunner(verbosity=3).run(suite)
@@ -86,7 +86,7 @@ func (library *toolchainLibraryDecorator) link(ctx ModuleContext,
 
 	srcPath := android.PathForSource(ctx, *library.Properties.Src)
 
-	if library.stripper.StripProperties.Strip.Keep_symbols_list != nil {
+	if library.stripper.keepsSymbolsList() {
 		fileName := ctx.ModuleName() + staticLibraryExtension
 		outputFile := android.PathForModuleOut(ctx, fileName)
 		buildFlags := flagsToBuilderFlags(flags)
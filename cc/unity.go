@@ -0,0 +1,111 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+// A unity build compiles the sources of a module in translation units that #include several
+// sources each, which reduces the number of compiler invocations and the time spent parsing the
+// same headers.  Sources that can't be merged with other sources, for example because they
+// define static functions with the same name, can be listed in unity.exclude_srcs.
+
+// defaultUnityMaxSrcs is the number of sources included in each unity translation unit if
+// unity.max_srcs is not set.
+const defaultUnityMaxSrcs = 16
+
+// unityLanguage returns the extension of the unity translation units that the source file can be
+// included in, or "" if it can't be included in one.
+func unityLanguage(src android.Path) string {
+	switch src.Ext() {
+	case ".c":
+		return ".c"
+	case ".cpp", ".cc", ".cxx":
+		return ".cpp"
+	}
+	return ""
+}
+
+// unitySources groups the sources of a module into unity translation units, and returns the
+// translation units followed by the sources that are compiled separately.  Only C and C++ files
+// in the source tree are grouped, generated sources may not exist when the translation units are
+// compiled.
+func unitySources(ctx android.ModuleContext, srcs android.Paths, properties *BaseCompilerProperties) android.Paths {
+	maxSrcs := defaultUnityMaxSrcs
+	if properties.Unity.Max_srcs != nil {
+		maxSrcs = int(*properties.Unity.Max_srcs)
+		if maxSrcs < 2 {
+			ctx.PropertyErrorf("unity.max_srcs", "must be at least 2, was %d", maxSrcs)
+			return srcs
+		}
+	}
+
+	excludeSrcs := android.PathsForModuleSrc(ctx, properties.Unity.Exclude_srcs).Strings()
+
+	var languages []string
+	groups := make(map[string]android.Paths)
+	var separateSrcs android.Paths
+	for _, src := range srcs {
+		language := unityLanguage(src)
+		if _, isSourcePath := src.(android.SourcePath); !isSourcePath || language == "" ||
+			android.InList(src.String(), excludeSrcs) {
+			separateSrcs = append(separateSrcs, src)
+			continue
+		}
+		if _, exists := groups[language]; !exists {
+			languages = append(languages, language)
+		}
+		groups[language] = append(groups[language], src)
+	}
+
+	var unitySrcs android.Paths
+	for _, language := range languages {
+		group := groups[language]
+		for i := 0; i < len(group); i += maxSrcs {
+			end := i + maxSrcs
+			if end > len(group) {
+				end = len(group)
+			}
+			chunk := group[i:end]
+			if len(chunk) == 1 {
+				separateSrcs = append(separateSrcs, chunk[0])
+				continue
+			}
+
+			unityFile := android.PathForModuleGen(ctx, "unity",
+				fmt.Sprintf("unity_%s_%d%s", strings.TrimPrefix(language, "."), i/maxSrcs, language))
+			var lines []string
+			for _, src := range chunk {
+				// The sources are found relative to the top of the source tree with "-iquote .".
+				lines = append(lines, fmt.Sprintf(`#include "%s"`, src.String()))
+			}
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        android.WriteFile,
+				Description: "unity " + unityFile.Rel(),
+				Output:      unityFile,
+				Args: map[string]string{
+					"content": strings.Join(lines, "\\n"),
+				},
+			})
+			unitySrcs = append(unitySrcs, unityFile)
+		}
+	}
+
+	return append(unitySrcs, separateSrcs...)
+}
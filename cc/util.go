@@ -76,6 +76,7 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		localLdFlags:         strings.Join(in.Local.LdFlags, " "),
 
 		aidlFlags:     strings.Join(in.aidlFlags, " "),
+		aidlDeps:      in.aidlDeps,
 		rsFlags:       strings.Join(in.rsFlags, " "),
 		libFlags:      strings.Join(in.libFlags, " "),
 		extraLibFlags: strings.Join(in.extraLibFlags, " "),
@@ -95,8 +96,12 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		proto:            in.proto,
 		protoC:           in.protoC,
 		protoOptionsFile: in.protoOptionsFile,
+		protoGrpc:        in.protoGrpc,
 
 		yacc: in.Yacc,
+		lex:  in.Lex,
+
+		clangModuleCache: in.clangModuleCache,
 	}
 }
 
@@ -552,6 +552,10 @@ func isVendorSnapshotModule(m *Module, moduleDir string) bool {
 	if _, ok := m.linker.(*kernelHeadersDecorator); ok {
 		return false
 	}
+	// LLNDK stubs are captured separately, see isLlndkSnapshotModule
+	if _, ok := m.linker.(*llndkStubDecorator); ok {
+		return false
+	}
 
 	// Libraries
 	if l, ok := m.linker.(snapshotLibraryInterface); ok {
@@ -588,6 +592,24 @@ func isVendorSnapshotModule(m *Module, moduleDir string) bool {
 	return false
 }
 
+// Determine if a module is an LLNDK stub library to be included in vendor snapshot.
+//
+// Vendor modules in the snapshot are linked against LLNDK stubs, so the stubs of every VNDK
+// version, along with their version scripts and exported headers, are captured to allow
+// rebuilding old vendor images against a newer platform.
+func isLlndkSnapshotModule(m *Module) bool {
+	if _, ok := m.linker.(*llndkStubDecorator); !ok {
+		return false
+	}
+	if !m.Enabled() || m.Target().Os.Class != android.Device {
+		return false
+	}
+	if m.Target().NativeBridge == android.NativeBridgeEnabled {
+		return false
+	}
+	return m.inVendor() && m.outputFile.Valid()
+}
+
 func (c *vendorSnapshotSingleton) GenerateBuildActions(ctx android.SingletonContext) {
 	// BOARD_VNDK_VERSION must be set to 'current' in order to generate a vendor snapshot.
 	if ctx.DeviceConfig().VndkVersion() != "current" {
@@ -610,6 +632,8 @@ func (c *vendorSnapshotSingleton) GenerateBuildActions(ctx android.SingletonCont
 					(executable binaries)
 				object/
 					(.o object files)
+				llndk/{VNDK_VERSION}/
+					(LLNDK stub .so libraries and their version scripts)
 			arch-{TARGET_2ND_ARCH}-{TARGET_2ND_ARCH_VARIANT}/
 				shared/
 					(.so shared libraries)
@@ -621,6 +645,8 @@ func (c *vendorSnapshotSingleton) GenerateBuildActions(ctx android.SingletonCont
 					(executable binaries)
 				object/
 					(.o object files)
+				llndk/{VNDK_VERSION}/
+					(LLNDK stub .so libraries and their version scripts)
 			NOTICE_FILES/
 				(notice files, e.g. libbase.txt)
 			configs/
@@ -784,6 +810,13 @@ func (c *vendorSnapshotSingleton) GenerateBuildActions(ctx android.SingletonCont
 			return
 		}
 
+		if isLlndkSnapshotModule(m) {
+			stub := m.linker.(*llndkStubDecorator)
+			snapshotOutputs = append(snapshotOutputs, installLlndkStubLibrary(ctx, m, stub, snapshotArchDir, "llndk")...)
+			headers = append(headers, stub.snapshotHeaders()...)
+			return
+		}
+
 		moduleDir := ctx.ModuleDir(module)
 		if !isVendorSnapshotModule(m, moduleDir) {
 			return
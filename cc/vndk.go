@@ -133,10 +133,17 @@ func (vndk *vndkdep) vndkCheckLinkType(ctx android.ModuleContext, to *Module, ta
 	}
 	if !vndk.isVndk() {
 		// Non-VNDK modules (those installed to /vendor, /product, or /system/product) can't depend
-		// on modules marked with vendor_available: false.
+		// on modules marked with vendor_available: false, and non-VNDK product modules can't
+		// depend on LLNDK stubs marked with product_available: false.
 		violation := false
-		if lib, ok := to.linker.(*llndkStubDecorator); ok && !Bool(lib.Properties.Vendor_available) {
-			violation = true
+		if lib, ok := to.linker.(*llndkStubDecorator); ok {
+			if !lib.availableTo(to) {
+				if to.inProduct() && lib.Properties.Product_available != nil {
+					ctx.ModuleErrorf("Product module that is not VNDK should not link to %q which is marked as `product_available: false`", to.Name())
+					return
+				}
+				violation = true
+			}
 		} else {
 			if _, ok := to.linker.(libraryInterface); ok && to.VendorProperties.Vendor_available != nil && !Bool(to.VendorProperties.Vendor_available) {
 				// Vendor_available == nil && !Bool(Vendor_available) should be okay since
@@ -516,6 +523,10 @@ func VndkSnapshotSingleton() android.Singleton {
 type vndkSnapshotSingleton struct {
 	vndkLibrariesFile   android.OutputPath
 	vndkSnapshotZipFile android.OptionalPath
+
+	// The llndk.libraries.VER.txt file listing the LLNDK libraries that are vendor_available,
+	// generated from the llndk_library modules in the tree.
+	llndkLibrariesFile android.OutputPath
 }
 
 func isVndkSnapshotLibrary(config android.DeviceConfig, m *Module) (i snapshotLibraryInterface, vndkType string, isVndkSnapshotLib bool) {
@@ -807,6 +818,31 @@ func (c *vndkSnapshotSingleton) buildVndkLibrariesTxtFiles(ctx android.Singleton
 			"content": strings.Join(merged, "\\n"),
 		},
 	})
+
+	// LLNDK libraries that are not vendor_available are only listed as VNDK-private.
+	var publicLlndk []string
+	llndkMap := llndkLibraries(ctx.Config())
+	vndkPrivateMap := vndkPrivateLibraries(ctx.Config())
+	for _, name := range android.SortedStringKeys(llndkMap) {
+		if _, ok := vndkPrivateMap[name]; ok || strings.HasPrefix(name, "libclang_rt.") {
+			continue
+		}
+		publicLlndk = append(publicLlndk, llndkMap[name])
+	}
+	sort.Strings(publicLlndk)
+	llndkFilename := llndkLibrariesTxt
+	if vndkVersion := ctx.DeviceConfig().PlatformVndkVersion(); vndkVersion != "" {
+		llndkFilename = insertVndkVersion(llndkFilename, vndkVersion)
+	}
+	c.llndkLibrariesFile = android.PathForOutput(ctx, "vndk", llndkFilename)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Output:      c.llndkLibrariesFile,
+		Description: "Writing " + c.llndkLibrariesFile.String(),
+		Args: map[string]string{
+			"content": strings.Join(publicLlndk, "\\n"),
+		},
+	})
 }
 
 func (c *vndkSnapshotSingleton) MakeVars(ctx android.MakeVarsContext) {
@@ -841,5 +877,6 @@ func (c *vndkSnapshotSingleton) MakeVars(ctx android.MakeVarsContext) {
 	ctx.Strict("VNDK_USING_CORE_VARIANT_LIBRARIES", strings.Join(android.SortedStringKeys(vndkUsingCoreVariantLibraries(ctx.Config())), " "))
 
 	ctx.Strict("VNDK_LIBRARIES_FILE", c.vndkLibrariesFile.String())
+	ctx.Strict("LLNDK_LIBRARIES_FILE", c.llndkLibrariesFile.String())
 	ctx.Strict("SOONG_VNDK_SNAPSHOT_ZIP", c.vndkSnapshotZipFile.String())
 }
@@ -0,0 +1,166 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool generates a linker version script that exports the symbols declared with an export
+// macro in C and C++ sources and headers, for example:
+//
+//   #define EXPORT __attribute__((visibility("default")))
+//   EXPORT int foo(int x);
+//
+// Only function and variable declarations are exported, the macro is ignored on type declarations
+// such as structs, classes and typedefs.  Only the names of the declarations are used, so the
+// symbols must have C linkage.  Assembly sources are not scanned, symbols defined in assembly
+// must be declared with the macro in a C or C++ header.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	commentRegexp    = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+	directiveRegexp  = regexp.MustCompile(`(?m)^[ \t]*#.*$`)
+	identifierRegexp = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// typeKeywords are the keywords that introduce a type name.
+var typeKeywords = []string{"struct", "class", "union", "enum"}
+
+// nonSymbolKeywords are the keywords of declarations that don't declare a symbol.
+var nonSymbolKeywords = []string{"typedef", "using", "namespace"}
+
+func inList(s string, list []string) bool {
+	for _, l := range list {
+		if s == l {
+			return true
+		}
+	}
+	return false
+}
+
+// scanExportedSymbols returns the names of the function and variable declarations in src that are
+// annotated with macro.  The name of a declaration is the last identifier before the parameter
+// list of a function or the end, initializer or array dimensions of a variable.  Annotated type
+// declarations, for example "struct EXPORT foo {" or "EXPORT typedef int foo;", are skipped.
+func scanExportedSymbols(src []byte, macro string) []string {
+	src = commentRegexp.ReplaceAll(src, []byte(" "))
+	// Skip preprocessor directives, including the definition of the macro itself.
+	src = directiveRegexp.ReplaceAll(src, nil)
+
+	declRegexp := regexp.MustCompile(`(?:\b(` + strings.Join(typeKeywords, "|") + `)\s+)?\b` +
+		regexp.QuoteMeta(macro) + `\b([^;(=\[{]*)[;(=\[{]`)
+
+	var symbols []string
+	for _, match := range declRegexp.FindAllSubmatch(src, -1) {
+		if len(match[1]) > 0 {
+			// The macro annotates a type, for example "class EXPORT Foo {".
+			continue
+		}
+		var identifiers []string
+		for _, identifier := range identifierRegexp.FindAll(match[2], -1) {
+			identifiers = append(identifiers, string(identifier))
+		}
+		if symbol, ok := declarationSymbol(identifiers); ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// declarationSymbol returns the name of the symbol declared by a declaration made of identifiers,
+// or false if it is a type declaration.
+func declarationSymbol(identifiers []string) (string, bool) {
+	if len(identifiers) == 0 {
+		return "", false
+	}
+	for _, identifier := range identifiers {
+		if inList(identifier, nonSymbolKeywords) {
+			return "", false
+		}
+	}
+	// A name directly after struct, class, union or enum is the name of a type, for example in
+	// "EXPORT struct foo {" or "EXPORT enum class foo;", while a variable of the type has its
+	// own name, as in "EXPORT struct foo bar;".
+	name := len(identifiers) - 1
+	if name > 0 && inList(identifiers[name-1], typeKeywords) {
+		return "", false
+	}
+	return identifiers[name], true
+}
+
+// versionNodeName returns the name of the version node for a library, for example LIBFOO for
+// libfoo.
+func versionNodeName(lib string) string {
+	return strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(lib, "_"))
+}
+
+// writeVersionScript writes a version script that exports symbols and hides all other symbols.
+func writeVersionScript(w io.Writer, lib string, symbols []string) {
+	fmt.Fprintf(w, "%s {\n", versionNodeName(lib))
+	if len(symbols) > 0 {
+		fmt.Fprintln(w, "  global:")
+		for _, symbol := range symbols {
+			fmt.Fprintf(w, "    %s;\n", symbol)
+		}
+	}
+	fmt.Fprintln(w, "  local:")
+	fmt.Fprintln(w, "    *;")
+	fmt.Fprintln(w, "};")
+}
+
+func main() {
+	var out, lib, macro string
+
+	flag.StringVar(&out, "o", "", "Path to save the version script")
+	flag.StringVar(&lib, "lib", "", "Name of the library")
+	flag.StringVar(&macro, "macro", "EXPORT", "Name of the macro that annotates exported declarations")
+	flag.Parse()
+
+	if out == "" || lib == "" {
+		fmt.Fprintln(os.Stderr, "-o and -lib are required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, src := range flag.Args() {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			log.Fatalf("Error reading %q: %v", src, err)
+		}
+		for _, symbol := range scanExportedSymbols(data, macro) {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	sort.Strings(symbols)
+
+	buf := &bytes.Buffer{}
+	writeVersionScript(buf, lib, symbols)
+	if err := ioutil.WriteFile(out, buf.Bytes(), 0666); err != nil {
+		log.Fatalf("Error writing %q: %v", out, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestScanExportedSymbols(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      string
+		macro    string
+		expected []string
+	}{
+		{
+			name: "functions and variables",
+			src: `
+				#define EXPORT __attribute__((visibility("default")))
+				EXPORT int foo(int x);
+				EXPORT const char* bar(void) { return "bar"; }
+				EXPORT extern int counter;
+				EXPORT int table[16] = {};
+				EXPORT struct config default_config = {0};
+			`,
+			macro:    "EXPORT",
+			expected: []string{"foo", "bar", "counter", "table", "default_config"},
+		},
+		{
+			name: "multi-line declaration",
+			src: `
+				EXPORT
+				unsigned long
+				    long_declaration(int a,
+				                     int b);
+			`,
+			macro:    "EXPORT",
+			expected: []string{"long_declaration"},
+		},
+		{
+			name: "comments and other macros",
+			src: `
+				// EXPORT int commented(void);
+				/* EXPORT int also_commented(void); */
+				MY_EXPORT int other_macro(void);
+				EXPORTED int similar_macro(void);
+				int not_exported(void);
+			`,
+			macro:    "MY_EXPORT",
+			expected: []string{"other_macro"},
+		},
+		{
+			name: "type declarations",
+			src: `
+				EXPORT struct config { int x; };
+				struct EXPORT options { int y; };
+				class EXPORT Foo {
+				 public:
+				  int method();
+				};
+				EXPORT class Bar;
+				EXPORT enum class mode { A, B };
+				EXPORT union value { int i; float f; };
+				EXPORT typedef int (*callback)(int);
+				EXPORT typedef struct handle handle_t;
+				EXPORT struct config global_config;
+				EXPORT int after_types(void);
+			`,
+			macro:    "EXPORT",
+			expected: []string{"global_config", "after_types"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			symbols := scanExportedSymbols([]byte(tc.src), tc.macro)
+			if !reflect.DeepEqual(symbols, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, symbols)
+			}
+		})
+	}
+}
+
+func TestWriteVersionScript(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeVersionScript(buf, "libfoo-bar", []string{"bar", "foo"})
+
+	expected := "LIBFOO_BAR {\n" +
+		"  global:\n" +
+		"    bar;\n" +
+		"    foo;\n" +
+		"  local:\n" +
+		"    *;\n" +
+		"};\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
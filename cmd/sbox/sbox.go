@@ -19,6 +19,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -31,13 +32,15 @@ import (
 )
 
 var (
-	sandboxesRoot string
-	rawCommand    string
-	outputRoot    string
-	keepOutDir    bool
-	copyAllOutput bool
-	depfileOut    string
-	inputHash     string
+	sandboxesRoot  string
+	rawCommand     string
+	outputRoot     string
+	keepOutDir     bool
+	copyAllOutput  bool
+	depfileOut     string
+	inputHash      string
+	inputManifest  string
+	keepOutputRoot bool
 )
 
 func init() {
@@ -57,6 +60,11 @@ func init() {
 
 	flag.StringVar(&inputHash, "input-hash", "",
 		"This option is ignored. Typical usage is to supply a hash of the list of input names so that the module will be rebuilt if the list (and thus the hash) changes.")
+
+	flag.StringVar(&inputManifest, "input-manifest", "",
+		"file containing a whitespace separated list of the inputs of the command. If set, the inputs are copied into a sandbox directory and the command is run from there, so that it can only read the declared inputs")
+	flag.BoolVar(&keepOutputRoot, "keep-output-root", false,
+		"whether to keep the existing contents of <outputRoot> instead of deleting it, for output roots that are shared with other rules")
 }
 
 func usageViolation(violation string) {
@@ -65,9 +73,10 @@ func usageViolation(violation string) {
 	}
 
 	fmt.Fprintf(os.Stderr,
-		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> [--depfile-out depFile] [--input-hash hash] <outputFile> [<outputFile>...]\n"+
+		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> [--depfile-out depFile] [--input-hash hash] [--input-manifest manifest] [--keep-output-root] <outputFile> [<outputFile>...]\n"+
 			"\n"+
 			"Deletes <outputRoot>,"+
+			"copies the inputs listed in <manifest> into a sandbox,"+
 			"runs <commandToRun>,"+
 			"and moves each <outputFile> out of <sandboxPath> and into <outputRoot>\n")
 
@@ -135,9 +144,11 @@ func run() error {
 	if err != nil {
 		return err
 	}
-	err = os.RemoveAll(outputRoot)
-	if err != nil {
-		return err
+	if !keepOutputRoot {
+		err = os.RemoveAll(outputRoot)
+		if err != nil {
+			return err
+		}
 	}
 	err = os.MkdirAll(outputRoot, 0777)
 	if err != nil {
@@ -145,6 +156,18 @@ func run() error {
 	}
 
 	tempDir, err := ioutil.TempDir(sandboxesRoot, "sbox")
+	if err != nil {
+		return fmt.Errorf("Failed to create temp dir: %s", err)
+	}
+
+	// When the inputs are sandboxed the command runs from the input sandbox, so the output
+	// sandbox must be referred to by its absolute path.
+	if inputManifest != "" {
+		tempDir, err = filepath.Abs(tempDir)
+		if err != nil {
+			return err
+		}
+	}
 
 	for i, filePath := range outputsVarEntries {
 		if !strings.HasPrefix(filePath, "__SBOX_OUT_DIR__/") {
@@ -165,10 +188,6 @@ func run() error {
 
 	}
 
-	if err != nil {
-		return fmt.Errorf("Failed to create temp dir: %s", err)
-	}
-
 	// In the common case, the following line of code is what removes the sandbox
 	// If a fatal error occurs (such as if our Go process is killed unexpectedly),
 	// then at the beginning of the next build, Soong will retry the cleanup
@@ -179,6 +198,24 @@ func run() error {
 		}
 	}()
 
+	var inputDir string
+	if inputManifest != "" {
+		inputDir, err = ioutil.TempDir(sandboxesRoot, "sbox_in")
+		if err != nil {
+			return fmt.Errorf("Failed to create input sandbox: %s", err)
+		}
+		defer func() {
+			if !keepOutDir {
+				os.RemoveAll(inputDir)
+			}
+		}()
+
+		err = copyInputsToSandbox(inputManifest, inputDir)
+		if err != nil {
+			return err
+		}
+	}
+
 	if strings.Contains(rawCommand, "__SBOX_OUT_DIR__") {
 		rawCommand = strings.Replace(rawCommand, "__SBOX_OUT_DIR__", tempDir, -1)
 	}
@@ -205,6 +242,7 @@ func run() error {
 	commandDescription := rawCommand
 
 	cmd := exec.Command("bash", "-c", rawCommand)
+	cmd.Dir = inputDir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -220,7 +258,8 @@ func run() error {
 	var missingOutputErrors []string
 	for _, filePath := range allOutputs {
 		tempPath := filepath.Join(tempDir, filePath)
-		fileInfo, err := os.Stat(tempPath)
+		// Symlinks may point to paths that only exist outside of the sandbox.
+		fileInfo, err := os.Lstat(tempPath)
 		if err != nil {
 			missingOutputErrors = append(missingOutputErrors, fmt.Sprintf("%s: does not exist", filePath))
 			continue
@@ -280,11 +319,14 @@ func run() error {
 		}
 
 		// Update the timestamp of the output file in case the tool wrote an old timestamp (for example, tar can extract
-		// files with old timestamps).
-		now := time.Now()
-		err = os.Chtimes(tempPath, now, now)
-		if err != nil {
-			return err
+		// files with old timestamps).  Chtimes follows symlinks, which may point outside of the sandbox, and ninja
+		// uses the timestamp of the symlink target anyway.
+		if fileInfo, err := os.Lstat(tempPath); err == nil && fileInfo.Mode()&os.ModeSymlink == 0 {
+			now := time.Now()
+			err = os.Chtimes(tempPath, now, now)
+			if err != nil {
+				return err
+			}
 		}
 
 		err = os.Rename(tempPath, destPath)
@@ -316,3 +358,65 @@ func run() error {
 	// TODO(jeffrygaston) if a process creates more output files than it declares, should there be a warning?
 	return nil
 }
+
+// copyInputsToSandbox copies each of the files listed in manifest into inputDir, at the same
+// relative path.  Symlinks are copied as symlinks rather than followed, so that rules that copy or
+// create symlinks behave the same inside and outside of the sandbox.
+func copyInputsToSandbox(manifest, inputDir string) error {
+	contents, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return err
+	}
+
+	for _, input := range strings.Fields(string(contents)) {
+		if filepath.IsAbs(input) || strings.HasPrefix(filepath.Clean(input), "../") {
+			return fmt.Errorf("input %q must be a relative path inside the source tree", input)
+		}
+
+		err := copyFile(input, filepath.Join(inputDir, input))
+		if err != nil {
+			return fmt.Errorf("failed to copy input %q into sandbox: %s", input, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	fromInfo, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if fromInfo.IsDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	err = os.MkdirAll(filepath.Dir(to), 0777)
+	if err != nil {
+		return err
+	}
+
+	if fromInfo.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, to)
+	}
+
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fromInfo.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
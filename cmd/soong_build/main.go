@@ -111,6 +111,8 @@ func main() {
 
 	ctx.SetNameInterface(newNameResolver(configuration))
 
+	ctx.SetFs(configuration.Fs())
+
 	ctx.SetAllowMissingDependencies(configuration.AllowMissingDependencies())
 
 	extraNinjaDeps := []string{configuration.ConfigFileName, configuration.ProductVariablesFileName}
@@ -123,8 +125,17 @@ func main() {
 		extraNinjaDeps = append(extraNinjaDeps, filepath.Join(configuration.BuildDir(), "always_rerun_for_delve"))
 	}
 
+	// Read SOONG_MIPS_TOOLCHAINS again through configuration so that soong_build will rerun
+	// and register the mips toolchains when it changes.
+	configuration.Getenv("SOONG_MIPS_TOOLCHAINS")
+
 	bootstrap.Main(ctx.Context, configuration, extraNinjaDeps...)
 
+	// The glob cache is only an optimization, failing to write it should not fail the build.
+	if err := configuration.SaveGlobCache(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write glob cache: %s\n", err)
+	}
+
 	if docFile != "" {
 		if err := writeDocs(ctx, docFile); err != nil {
 			fmt.Fprintf(os.Stderr, "%s", err)
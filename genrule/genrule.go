@@ -70,6 +70,12 @@ type SourceFileGenerator interface {
 	GeneratedDeps() android.Paths
 }
 
+// HeaderListGenerator is implemented by generator modules that declare the headers they export,
+// as they are spelled in #include directives.
+type HeaderListGenerator interface {
+	ExportedHeaders() []string
+}
+
 // Alias for android.HostToolProvider
 // Deprecated: use android.HostToolProvider instead.
 type HostToolProvider interface {
@@ -112,6 +118,11 @@ type generatorProperties struct {
 	// List of directories to export generated headers from
 	Export_include_dirs []string
 
+	// List of the generated headers that cc modules may include, as they are spelled in #include
+	// directives.  Each header must be an output of this module.  Unless export_include_dirs is
+	// set, the directories to export generated headers from are derived from these headers.
+	Export_headers []string
+
 	// list of input files
 	Srcs []string `android:"path,arch_variant"`
 
@@ -175,6 +186,24 @@ func (g *Module) GeneratedDeps() android.Paths {
 	return g.outputDeps
 }
 
+func (g *Module) ExportedHeaders() []string {
+	return g.properties.Export_headers
+}
+
+// exportedHeaderDir returns the directory, relative to the generated directory of the module,
+// that an output must be included from to be spelled as header in #include directives.
+func exportedHeaderDir(outputFiles android.Paths, header string) (string, bool) {
+	for _, out := range outputFiles {
+		if out.Rel() == header {
+			return "", true
+		}
+		if strings.HasSuffix(out.Rel(), "/"+header) {
+			return strings.TrimSuffix(out.Rel(), "/"+header), true
+		}
+	}
+	return "", false
+}
+
 func toolDepsMutator(ctx android.BottomUpMutatorContext) {
 	if g, ok := ctx.Module().(*Module); ok {
 		for _, tool := range g.properties.Tools {
@@ -454,6 +483,21 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	g.outputFiles = outputFiles.Paths()
 
+	if len(g.properties.Export_headers) > 0 {
+		var headerDirs android.Paths
+		for _, header := range g.properties.Export_headers {
+			dir, ok := exportedHeaderDir(g.outputFiles, header)
+			if !ok {
+				ctx.PropertyErrorf("export_headers", "%q is not an output of this module", header)
+				continue
+			}
+			headerDirs = append(headerDirs, android.PathForModuleGen(ctx, dir))
+		}
+		if len(g.properties.Export_include_dirs) == 0 {
+			g.exportedIncludeDirs = android.FirstUniquePaths(headerDirs)
+		}
+	}
+
 	// For <= 6 outputs, just embed those directly in the users. Right now, that covers >90% of
 	// the genrules on AOSP. That will make things simpler to look at the graph in the common
 	// case. For larger sets of outputs, inject a phony target in between to limit ninja file
@@ -17,6 +17,7 @@ package genrule
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -721,6 +722,82 @@ func TestGenruleDefaults(t *testing.T) {
 	}
 }
 
+func TestGenruleExportHeaders(t *testing.T) {
+	testcases := []struct {
+		name string
+		prop string
+
+		err    string
+		expect []string
+	}{
+		{
+			name: "default",
+			prop: `
+				out: ["include/foo/foo.h", "bar.h"],
+			`,
+			expect: []string{""},
+		},
+		{
+			name: "derived",
+			prop: `
+				out: ["include/foo/foo.h", "include/foo/bar.h", "baz.h"],
+				export_headers: ["foo/foo.h", "foo/bar.h", "baz.h"],
+			`,
+			expect: []string{"include", ""},
+		},
+		{
+			name: "export_include_dirs",
+			prop: `
+				out: ["include/foo/foo.h"],
+				export_include_dirs: ["include"],
+				export_headers: ["foo/foo.h"],
+			`,
+			expect: []string{"include"},
+		},
+		{
+			name: "missing",
+			prop: `
+				out: ["include/foo/foo.h"],
+				export_headers: ["foo.h"],
+			`,
+			err: `export_headers: "foo.h" is not an output of this module`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			bp := "genrule {\n"
+			bp += "name: \"gen\",\n"
+			bp += "cmd: \"touch $(out)\",\n"
+			bp += test.prop
+			bp += "}\n"
+
+			config := testConfig(bp, nil)
+			ctx := testContext(config)
+
+			_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+			if errs == nil {
+				_, errs = ctx.PrepareBuildActions(config)
+			}
+			if test.err != "" {
+				android.FailIfNoMatchingErrors(t, test.err, errs)
+				return
+			}
+			android.FailIfErrored(t, errs)
+
+			gen := ctx.ModuleForTests("gen", "").Module().(*Module)
+			genDir := filepath.Join(buildDir, ".intermediates", "gen", "gen")
+			var expect []string
+			for _, dir := range test.expect {
+				expect = append(expect, filepath.Join(genDir, dir))
+			}
+			if got := gen.GeneratedHeaderDirs().Strings(); !reflect.DeepEqual(expect, got) {
+				t.Errorf("expected exported include dirs %q, got %q", expect, got)
+			}
+		})
+	}
+}
+
 type testTool struct {
 	android.ModuleBase
 	outputFile android.Path
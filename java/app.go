@@ -421,6 +421,7 @@ func (a *AndroidTestHelperApp) GenerateAndroidBuildActions(ctx android.ModuleCon
 func (a *AndroidApp) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	a.checkAppSdkVersions(ctx)
 	a.generateAndroidBuildActions(ctx)
+	ctx.ModuleInfoJSON().Class = append(ctx.ModuleInfoJSON().Class, "APPS")
 }
 
 func (a *AndroidApp) checkAppSdkVersions(ctx android.ModuleContext) {
@@ -1071,6 +1072,13 @@ func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		a.testProperties.Test_config_template, a.manifestPath, a.testProperties.Test_suites, a.testProperties.Auto_gen_config, configs)
 	a.testConfig = a.FixTestConfig(ctx, testConfig)
 	a.data = android.PathsForModuleSrc(ctx, a.testProperties.Data)
+
+	info := ctx.ModuleInfoJSON()
+	info.Class = append(info.Class, "APPS")
+	info.CompatibilitySuites = append(info.CompatibilitySuites, a.testProperties.Test_suites...)
+	if a.testConfig != nil {
+		info.TestConfig = append(info.TestConfig, a.testConfig.String())
+	}
 }
 
 func (a *AndroidTest) FixTestConfig(ctx android.ModuleContext, testConfig android.Path) android.Path {
@@ -81,6 +81,38 @@ var (
 	_ = pctx.VariableFunc("kytheCuEncoding",
 		func(ctx android.PackageVarContext) string { return ctx.Config().XrefCuEncoding() })
 	_ = pctx.SourcePathVariable("kytheVnames", "build/soong/vnames.json")
+
+	// Compiling with Error Prone through errorprone_baseline collects the diagnostics, so that
+	// errors listed in a baseline file don't fail the compilation, and the findings can be
+	// written to a SARIF report.  The exit code of javac is passed to errorprone_baseline, which
+	// fails unless the failure is explained by Error Prone errors that it suppresses.
+	errorproneBaseline = pctx.AndroidStaticRule("errorproneBaseline",
+		blueprint.RuleParams{
+			Command: `rm -rf "$outDir" "$annoDir" "$srcJarDir" $out.log && ` +
+				`mkdir -p "$outDir" "$annoDir" "$srcJarDir" && ` +
+				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+				`(if [ -s $srcJarDir/list ] || [ -s $out.rsp ] ; then ` +
+				`${config.SoongJavacWrapper} ${config.JavacCmd} ` +
+				`${config.JavacHeapFlags} ${config.JavacVmFlags} ${config.CommonJdkFlags} ` +
+				`$processorpath $processor $javacFlags $bootClasspath $classpath ` +
+				`-source $javaVersion -target $javaVersion -Xmaxerrs 100000 -Xmaxwarns 100000 ` +
+				`-d $outDir -s $annoDir @$out.rsp @$srcJarDir/list > $out.log 2>&1 ; fi ) ; rc=$$? ; ` +
+				`${config.ErrorProneBaselineCmd} $baselineFlags --javac-exit-code $$rc --log $out.log && ` +
+				`${config.SoongZipCmd} -jar -o $out -C $outDir -D $outDir && ` +
+				`rm -rf "$srcJarDir" $out.log`,
+			CommandDeps: []string{
+				"${config.JavacCmd}",
+				"${config.SoongZipCmd}",
+				"${config.ZipSyncCmd}",
+				"${config.ErrorProneBaselineCmd}",
+			},
+			CommandOrderOnly: []string{"${config.SoongJavacWrapper}"},
+			Rspfile:          "$out.rsp",
+			RspfileContent:   "$in",
+		},
+		"javacFlags", "bootClasspath", "classpath", "processorpath", "processor", "srcJars", "srcJarDir",
+		"outDir", "annoDir", "javaVersion", "baselineFlags")
+
 	// Run it with -add-opens=java.base/java.nio=ALL-UNNAMED to avoid JDK9's warning about
 	// "Illegal reflective access by com.google.protobuf.Utf8$UnsafeProcessor ...
 	// to field java.nio.Buffer.address"
@@ -249,8 +281,16 @@ type javaBuilderFlags struct {
 	aidlDeps       android.Paths
 	javaVersion    javaVersion
 
+	// sarifReport is written with the Error Prone findings of the compilation if it is set.
+	sarifReport android.WritablePath
+
+	// errorProneJavacFlags replaces javacFlags when running Error Prone.
+	errorProneJavacFlags      string
 	errorProneExtraJavacFlags string
 	errorProneProcessorPath   classpath
+	// errorProneBaseline lists the pre-existing Error Prone errors that shouldn't fail the
+	// compilation.
+	errorProneBaseline android.OptionalPath
 
 	kotlincFlags     string
 	kotlincClasspath classpath
@@ -267,13 +307,29 @@ func TransformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 		desc += strconv.Itoa(shardIdx)
 	}
 
+	// The Error Prone baseline only applies when running Error Prone.
+	flags.errorProneBaseline = android.OptionalPath{}
+
 	transformJavaToClasses(ctx, outputFile, shardIdx, srcFiles, srcJars, flags, deps, "javac", desc)
 }
 
-func RunErrorProne(ctx android.ModuleContext, outputFile android.WritablePath,
-	srcFiles, srcJars android.Paths, flags javaBuilderFlags) {
+// RunErrorProne checks the sources with Error Prone, and returns the SARIF report of the findings,
+// or nil if reports aren't enabled.
+func RunErrorProne(ctx android.ModuleContext, outputFile android.WritablePath, shardIdx int,
+	srcFiles, srcJars android.Paths, flags javaBuilderFlags) android.Path {
 
 	flags.processorPath = append(flags.errorProneProcessorPath, flags.processorPath...)
+	flags.javacFlags = flags.errorProneJavacFlags
+
+	var report android.WritablePath
+	if ctx.Config().ErrorProneReports() {
+		report = android.PathForModuleOut(ctx, "errorprone", "errorprone.sarif")
+		if shardIdx >= 0 {
+			report = android.PathForModuleOut(ctx, "errorprone", "shard"+strconv.Itoa(shardIdx),
+				"errorprone.sarif")
+		}
+		flags.sarifReport = report
+	}
 
 	if len(flags.errorProneExtraJavacFlags) > 0 {
 		if len(flags.javacFlags) > 0 {
@@ -283,8 +339,18 @@ func RunErrorProne(ctx android.ModuleContext, outputFile android.WritablePath,
 		}
 	}
 
-	transformJavaToClasses(ctx, outputFile, -1, srcFiles, srcJars, flags, nil,
-		"errorprone", "errorprone")
+	desc := "errorprone"
+	if shardIdx >= 0 {
+		desc += strconv.Itoa(shardIdx)
+	}
+
+	transformJavaToClasses(ctx, outputFile, shardIdx, srcFiles, srcJars, flags, nil,
+		"errorprone", desc)
+
+	if report == nil {
+		return nil
+	}
+	return report
 }
 
 // Emits the rule to generate Xref input file (.kzip file) for the given set of source files and source jars
@@ -455,7 +521,7 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 	if ctx.Config().IsEnvTrue("RBE_JAVAC") {
 		rule = javacRE
 	}
-	ctx.Build(pctx, android.BuildParams{
+	params := android.BuildParams{
 		Rule:        rule,
 		Description: desc,
 		Output:      outputFile,
@@ -473,7 +539,36 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 			"annoDir":       android.PathForModuleOut(ctx, intermediatesDir, annoDir).String(),
 			"javaVersion":   flags.javaVersion.String(),
 		},
-	})
+	}
+
+	if flags.errorProneBaseline.Valid() || flags.sarifReport != nil {
+		var baselineFlags []string
+		if flags.errorProneBaseline.Valid() {
+			// The updated baseline lists all the current errors, so that it can be copied over the
+			// baseline after fixing errors or when adding new ones is intended.
+			updatedBaseline := android.PathForModuleOut(ctx, intermediatesDir, "baseline.txt")
+			if shardIdx >= 0 {
+				updatedBaseline = android.PathForModuleOut(ctx, intermediatesDir,
+					"shard"+strconv.Itoa(shardIdx), "baseline.txt")
+			}
+			if ctx.Config().IsEnvTrue("ERROR_PRONE_UPDATE_BASELINE") {
+				baselineFlags = append(baselineFlags, "--update-baseline")
+			}
+			baselineFlags = append(baselineFlags,
+				"--baseline "+flags.errorProneBaseline.Path().String(),
+				"--updated-baseline "+updatedBaseline.String())
+			params.Implicits = append(params.Implicits, flags.errorProneBaseline.Path())
+			params.ImplicitOutputs = append(params.ImplicitOutputs, updatedBaseline)
+		}
+		if flags.sarifReport != nil {
+			baselineFlags = append(baselineFlags, "--sarif "+flags.sarifReport.String())
+			params.ImplicitOutputs = append(params.ImplicitOutputs, flags.sarifReport)
+		}
+		params.Rule = errorproneBaseline
+		params.Args["baselineFlags"] = strings.Join(baselineFlags, " ")
+	}
+
+	ctx.Build(pctx, params)
 }
 
 func TransformResourcesToJar(ctx android.ModuleContext, outputFile android.WritablePath,
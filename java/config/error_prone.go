@@ -15,39 +15,351 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"android/soong/android"
 )
 
 var (
-	// These will be filled out by external/error_prone/soong/error_prone.go if it is available
+	// These will be filled out by external/error_prone/soong/error_prone.go if it is available.
+	// The file set by the ErrorProneConfig product variable overrides them.
 	ErrorProneClasspath             []string
 	ErrorProneChecksError           []string
 	ErrorProneChecksWarning         []string
 	ErrorProneChecksDefaultDisabled []string
 	ErrorProneChecksOff             []string
 	ErrorProneFlags                 []string
+	NullAwayClasspath               []string
+
+	// ErrorProneChecksExternal relax the checks for third-party code, which the tree doesn't own
+	// and can't easily fix. They replace ErrorProneChecksError and ErrorProneChecksWarning, so
+	// only the checks Error Prone reports as errors by default fail the build, and warnings
+	// aren't reported.
+	ErrorProneChecksExternal = []string{
+		"-XepDisableAllWarnings",
+	}
 )
 
-// Wrapper that grabs value of val late so it can be initialized by a later module's init function
-func errorProneVar(name string, val *[]string, sep string) {
-	pctx.VariableFunc(name, func(android.PackageVarContext) string {
-		return strings.Join(*val, sep)
+// ErrorProneConfig is the Error Prone configuration of the build. A product can check in a json
+// file that sets any of the fields with the ErrorProneConfig product variable, for example:
+//
+//   {
+//     "ChecksError": ["-Xep:MissingOverride:ERROR"],
+//     "Flags": ["-XepDisableWarningsInGeneratedCode"],
+//     "ExcludeJavacflags": ["-Werror"],
+//     "PathSeverities": [
+//       {
+//         "Path": "frameworks/base/services",
+//         "WarningsAsErrors": ["ReturnValueIgnored"]
+//       }
+//     ]
+//   }
+//
+// The fields the file doesn't set are taken from the variables filled out by
+// external/error_prone/soong/error_prone.go.
+type ErrorProneConfig struct {
+	Classpath             []string
+	ChecksError           []string
+	ChecksWarning         []string
+	ChecksDefaultDisabled []string
+	ChecksOff             []string
+	ChecksExternal        []string
+	Flags                 []string
+
+	// ExtraJavacflags are passed to the javac invocations that run Error Prone after the javac
+	// flags of the module, and ExcludeJavacflags are removed from the javac flags of the module
+	// in those invocations, so that they can be tuned independently of the regular compilation.
+	ExtraJavacflags   []string
+	ExcludeJavacflags []string
+
+	// PathSeverities adjust the severity of checks for the modules in a directory and its
+	// subdirectories, sorted so that overlays for more specific paths come last.
+	PathSeverities []ErrorPronePathSeverities
+}
+
+// ErrorPronePathSeverities sets the severity of Error Prone checks for the modules under Path.
+type ErrorPronePathSeverities struct {
+	Path             string
+	EnabledChecks    []string
+	WarningsAsErrors []string
+	DisabledChecks   []string
+
+	flags []string
+}
+
+// PathSeverityFlags returns the flags that adjust the severity of checks for modules in dir.
+func (c ErrorProneConfig) PathSeverityFlags(dir string) []string {
+	var flags []string
+	for _, overlay := range c.PathSeverities {
+		if dir == overlay.Path || strings.HasPrefix(dir, overlay.Path+"/") {
+			flags = append(flags, overlay.flags...)
+		}
+	}
+	return flags
+}
+
+// parseErrorProneConfig parses and validates the contents of an Error Prone config file.
+func parseErrorProneConfig(data []byte) (ErrorProneConfig, error) {
+	var config ErrorProneConfig
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return ErrorProneConfig{}, err
+	}
+
+	for _, flags := range [][]string{config.ChecksError, config.ChecksWarning,
+		config.ChecksDefaultDisabled, config.ChecksOff, config.ChecksExternal, config.Flags,
+		config.ExtraJavacflags,
+		config.ExcludeJavacflags} {
+		for _, flag := range flags {
+			if !strings.HasPrefix(flag, "-") {
+				return ErrorProneConfig{}, fmt.Errorf("flag %q must start with '-'", flag)
+			}
+		}
+	}
+
+	for i := range config.PathSeverities {
+		overlay := &config.PathSeverities[i]
+		overlay.Path = filepath.Clean(overlay.Path)
+		if overlay.Path == "." || filepath.IsAbs(overlay.Path) || strings.HasPrefix(overlay.Path, "../") {
+			return ErrorProneConfig{}, fmt.Errorf("path severities: invalid path %q", overlay.Path)
+		}
+		flags, err := ErrorProneSeverityFlags(overlay.EnabledChecks, overlay.WarningsAsErrors,
+			overlay.DisabledChecks)
+		if err != nil {
+			return ErrorProneConfig{}, fmt.Errorf("path severities for %q: %s", overlay.Path, err)
+		}
+		overlay.flags = flags
+	}
+	sort.SliceStable(config.PathSeverities, func(i, j int) bool {
+		return len(config.PathSeverities[i].Path) < len(config.PathSeverities[j].Path)
+	})
+
+	return config, nil
+}
+
+var errorProneConfigKey = android.NewOnceKey("errorProneConfig")
+
+type loadedErrorProneConfig struct {
+	config ErrorProneConfig
+	err    error
+}
+
+// ErrorProneConfigFor returns the Error Prone configuration of the build.
+func ErrorProneConfigFor(ctx android.PathContext) (ErrorProneConfig, error) {
+	loaded := ctx.Config().Once(errorProneConfigKey, func() interface{} {
+		config := ErrorProneConfig{
+			Classpath:             ErrorProneClasspath,
+			ChecksError:           ErrorProneChecksError,
+			ChecksWarning:         ErrorProneChecksWarning,
+			ChecksDefaultDisabled: ErrorProneChecksDefaultDisabled,
+			ChecksOff:             ErrorProneChecksOff,
+			ChecksExternal:        ErrorProneChecksExternal,
+			Flags:                 ErrorProneFlags,
+		}
+
+		file := ctx.Config().ErrorProneConfig()
+		if file == "" {
+			return loadedErrorProneConfig{config, nil}
+		}
+
+		path := android.ExistentPathForSource(ctx, file)
+		if !path.Valid() {
+			return loadedErrorProneConfig{config, fmt.Errorf("Error Prone config %q does not exist", file)}
+		}
+		data, err := android.ReadSourceFile(ctx, path.Path())
+		if err != nil {
+			return loadedErrorProneConfig{config, err}
+		}
+		fileConfig, err := parseErrorProneConfig(data)
+		if err != nil {
+			return loadedErrorProneConfig{config, fmt.Errorf("Error Prone config %s: %s", file, err)}
+		}
+
+		for _, field := range []struct {
+			dst *[]string
+			src []string
+		}{
+			{&config.Classpath, fileConfig.Classpath},
+			{&config.ChecksError, fileConfig.ChecksError},
+			{&config.ChecksWarning, fileConfig.ChecksWarning},
+			{&config.ChecksDefaultDisabled, fileConfig.ChecksDefaultDisabled},
+			{&config.ChecksOff, fileConfig.ChecksOff},
+			{&config.ChecksExternal, fileConfig.ChecksExternal},
+			{&config.Flags, fileConfig.Flags},
+			{&config.ExtraJavacflags, fileConfig.ExtraJavacflags},
+			{&config.ExcludeJavacflags, fileConfig.ExcludeJavacflags},
+		} {
+			if field.src != nil {
+				*field.dst = field.src
+			}
+		}
+		config.PathSeverities = fileConfig.PathSeverities
+
+		return loadedErrorProneConfig{config, nil}
+	}).(loadedErrorProneConfig)
+
+	return loaded.config, loaded.err
+}
+
+// Wrapper that grabs the value late so it can be initialized by a later module's init function or
+// loaded from the product's config file
+func errorProneVar(name string, val func(ErrorProneConfig) []string, sep string) {
+	pctx.VariableFunc(name, func(ctx android.PackageVarContext) string {
+		config, err := ErrorProneConfigFor(ctx)
+		if err != nil {
+			ctx.Errorf("%s", err)
+		}
+		return strings.Join(val(config), sep)
 	})
 }
 
 func init() {
-	errorProneVar("ErrorProneClasspath", &ErrorProneClasspath, ":")
-	errorProneVar("ErrorProneChecksError", &ErrorProneChecksError, " ")
-	errorProneVar("ErrorProneChecksWarning", &ErrorProneChecksWarning, " ")
-	errorProneVar("ErrorProneChecksDefaultDisabled", &ErrorProneChecksDefaultDisabled, " ")
-	errorProneVar("ErrorProneChecksOff", &ErrorProneChecksOff, " ")
-	errorProneVar("ErrorProneFlags", &ErrorProneFlags, " ")
+	errorProneVar("ErrorProneClasspath", func(c ErrorProneConfig) []string { return c.Classpath }, ":")
+	errorProneVar("ErrorProneChecksError", func(c ErrorProneConfig) []string { return c.ChecksError }, " ")
+	errorProneVar("ErrorProneChecksWarning", func(c ErrorProneConfig) []string { return c.ChecksWarning }, " ")
+	errorProneVar("ErrorProneChecksDefaultDisabled",
+		func(c ErrorProneConfig) []string { return c.ChecksDefaultDisabled }, " ")
+	errorProneVar("ErrorProneChecksOff", func(c ErrorProneConfig) []string { return c.ChecksOff }, " ")
+	errorProneVar("ErrorProneChecksExternal", func(c ErrorProneConfig) []string { return c.ChecksExternal }, " ")
+	errorProneVar("ErrorProneFlags", func(c ErrorProneConfig) []string { return c.Flags }, " ")
+	errorProneVar("ErrorProneExtraJavacflags", func(c ErrorProneConfig) []string { return c.ExtraJavacflags }, " ")
+	pctx.VariableFunc("NullAwayClasspath", func(android.PackageVarContext) string {
+		return strings.Join(NullAwayClasspath, ":")
+	})
+	pctx.HostBinToolVariable("ErrorProneBaselineCmd", "errorprone_baseline")
+	pctx.VariableFunc("ErrorProneModeFlags", func(ctx android.PackageVarContext) string {
+		flags, err := ErrorProneModeFlags(ctx.Config().ErrorProneMode())
+		if err != nil {
+			ctx.Errorf("%s", err)
+		}
+		return strings.Join(flags, " ")
+	})
 	pctx.StaticVariable("ErrorProneChecks", strings.Join([]string{
 		"${ErrorProneChecksOff}",
 		"${ErrorProneChecksError}",
 		"${ErrorProneChecksWarning}",
 		"${ErrorProneChecksDefaultDisabled}",
+		"${ErrorProneModeFlags}",
+	}, " "))
+	pctx.StaticVariable("ErrorProneExternalChecks", strings.Join([]string{
+		"${ErrorProneChecksOff}",
+		"${ErrorProneChecksExternal}",
+		"${ErrorProneChecksDefaultDisabled}",
+		"${ErrorProneModeFlags}",
 	}, " "))
 }
+
+// errorProneProfiles maps the names of the Error Prone check profiles to the checks they enable.
+var errorProneProfiles = map[string]string{
+	"default": "${config.ErrorProneChecks}",
+	// The relaxed profile for third-party code.
+	"external": "${config.ErrorProneExternalChecks}",
+}
+
+// ErrorProneProfile returns profile, or the Error Prone check profile for modules in dir if profile
+// is empty.
+func ErrorProneProfile(profile, dir string) string {
+	if profile != "" {
+		return profile
+	}
+	if dir == "external" || strings.HasPrefix(dir, "external/") {
+		return "external"
+	}
+	return "default"
+}
+
+// ErrorProneProfileChecks returns the checks of the Error Prone check profile.
+func ErrorProneProfileChecks(profile string) (string, error) {
+	if checks, ok := errorProneProfiles[profile]; ok {
+		return checks, nil
+	}
+	return "", fmt.Errorf("unknown Error Prone profile %q, must be default or external", profile)
+}
+
+// ErrorProneShardSize returns the number of source files each Error Prone action checks, set with
+// the ERROR_PRONE_SHARD_SIZE environment variable for iterative development, or 0 if it isn't set.
+func ErrorProneShardSize(config android.Config) (int, error) {
+	value := config.Getenv("ERROR_PRONE_SHARD_SIZE")
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid ERROR_PRONE_SHARD_SIZE %q, must be a non-negative integer", value)
+	}
+	return size, nil
+}
+
+var errorProneModeFlags = map[string][]string{
+	"errors": nil,
+	// Report all errors as warnings, so that builds that only collect the findings don't fail.
+	"warnings": []string{"-XepAllErrorsAsWarnings"},
+}
+
+// ErrorProneModeFlags returns the flags that select how Error Prone reports errors.
+func ErrorProneModeFlags(mode string) ([]string, error) {
+	if flags, ok := errorProneModeFlags[mode]; ok {
+		return flags, nil
+	}
+	return nil, fmt.Errorf("unknown Error Prone mode %q, must be errors or warnings", mode)
+}
+
+var errorProneCheckNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+var javaPackageRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// NullAwayFlags returns the flags that enable the NullAway check for the code in
+// annotatedPackages and their subpackages.
+func NullAwayFlags(annotatedPackages []string) ([]string, error) {
+	if len(annotatedPackages) == 0 {
+		return nil, fmt.Errorf("NullAway requires at least one annotated package")
+	}
+	for _, pkg := range annotatedPackages {
+		if !javaPackageRegexp.MatchString(pkg) {
+			return nil, fmt.Errorf("invalid java package %q", pkg)
+		}
+	}
+	return []string{
+		"-Xep:NullAway:ERROR",
+		"-XepOpt:NullAway:AnnotatedPackages=" + strings.Join(annotatedPackages, ","),
+	}, nil
+}
+
+// ErrorProneSeverityFlags returns the flags that set the severity of individual Error Prone checks
+// for a module. Error Prone uses the last severity passed for a check, so the flags override
+// ${ErrorProneChecks} when they are passed after it.
+func ErrorProneSeverityFlags(warnings, errors, off []string) ([]string, error) {
+	var flags []string
+	seen := make(map[string]bool)
+
+	for _, checks := range []struct {
+		names    []string
+		severity string
+	}{
+		{warnings, "WARN"},
+		{errors, "ERROR"},
+		{off, "OFF"},
+	} {
+		for _, name := range checks.names {
+			if !errorProneCheckNameRegexp.MatchString(name) {
+				return nil, fmt.Errorf("invalid check name %q", name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("check %q is listed more than once", name)
+			}
+			seen[name] = true
+			flags = append(flags, "-Xep:"+name+":"+checks.severity)
+		}
+	}
+
+	return flags, nil
+}
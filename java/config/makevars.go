@@ -48,9 +48,16 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 	ctx.Strict("TURBINE", "${TurbineJar}")
 
 	if ctx.Config().RunErrorProne() {
-		ctx.Strict("ERROR_PRONE_JARS", strings.Join(ErrorProneClasspath, " "))
+		errorProneConfig, err := ErrorProneConfigFor(ctx)
+		if err != nil {
+			ctx.Errorf("%s", err)
+		}
+		ctx.Strict("ERROR_PRONE_JARS", strings.Join(errorProneConfig.Classpath, " "))
 		ctx.Strict("ERROR_PRONE_FLAGS", "${ErrorProneFlags}")
+		ctx.Strict("ERROR_PRONE_EXTRA_JAVACFLAGS", "${ErrorProneExtraJavacflags}")
+		ctx.Strict("ERROR_PRONE_EXCLUDE_JAVACFLAGS", strings.Join(errorProneConfig.ExcludeJavacflags, " "))
 		ctx.Strict("ERROR_PRONE_CHECKS", "${ErrorProneChecks}")
+		ctx.Strict("ERROR_PRONE_EXTERNAL_CHECKS", "${ErrorProneExternalChecks}")
 	}
 
 	ctx.Strict("TARGET_JAVAC", "${JavacCmd}  ${JavacVmFlags} ${CommonJdkFlags}")
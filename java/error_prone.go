@@ -0,0 +1,210 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+)
+
+// errorProneReporter is implemented by modules that write the Error Prone findings of their
+// compilation to SARIF reports.
+type errorProneReporter interface {
+	ErrorProneReports() android.Paths
+}
+
+func errorProneReportSingletonFactory() android.Singleton {
+	return &errorProneReportSingleton{}
+}
+
+// errorProneReportSingleton merges the SARIF reports of all the modules checked with Error Prone
+// into a single SARIF report and an html summary, built by the errorprone-report goal.
+type errorProneReportSingleton struct {
+	sarif android.WritablePath
+	html  android.WritablePath
+}
+
+func (e *errorProneReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().ErrorProneReports() {
+		return
+	}
+
+	var reports android.Paths
+	ctx.VisitAllModules(func(m android.Module) {
+		if ctx.Config().EmbeddedInMake() && !m.ExportedToMake() {
+			return
+		}
+		if r, ok := m.(errorProneReporter); ok {
+			reports = append(reports, r.ErrorProneReports()...)
+		}
+	})
+	reports = android.SortedUniquePaths(reports)
+
+	e.sarif = android.PathForOutput(ctx, "errorprone-report.sarif")
+	e.html = android.PathForOutput(ctx, "errorprone-report.html")
+
+	rule := android.NewRuleBuilder()
+	rule.Command().BuiltTool(ctx, "merge_errorprone_reports").
+		FlagWithOutput("--sarif ", e.sarif).
+		FlagWithOutput("--html ", e.html).
+		FlagWithRspFileInputList("@", reports)
+	rule.Build(pctx, ctx, "errorprone_report", "merge Error Prone reports")
+
+	ctx.Phony("errorprone-report", e.sarif, e.html)
+}
+
+func (e *errorProneReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if e.sarif != nil {
+		ctx.DistForGoal("errorprone-report", e.sarif, e.html)
+	}
+}
+
+var _ android.SingletonMakeVarsProvider = (*errorProneReportSingleton)(nil)
+
+// errorProneInfo records how a module is checked with Error Prone. The flags may reference ninja
+// variables.
+type errorProneInfo struct {
+	profile   string
+	classpath classpath
+
+	// checks are the arguments of -Xplugin:ErrorProne that select the checks and their severity.
+	checks []string
+
+	// javacflags are the extra javac flags of the Error Prone invocation.
+	javacflags []string
+}
+
+type errorProneConfigProvider interface {
+	errorProneConfig() *errorProneInfo
+}
+
+// errorProneModuleConfig is the fully resolved Error Prone configuration of a module variant, as
+// written to the json file.
+type errorProneModuleConfig struct {
+	Name       string
+	Variant    string
+	Profile    string
+	Classpath  []string
+	Checks     []string
+	Severities map[string]string
+	Javacflags []string
+}
+
+const (
+	// Environment variable that enables writing the Error Prone configuration of all modules.
+	envVariableCollectErrorProneConfig = "SOONG_COLLECT_ERROR_PRONE_CONFIG"
+	errorProneConfigJsonFileName       = "module_bp_errorprone_config.json"
+)
+
+func errorProneConfigSingletonFactory() android.Singleton {
+	return &errorProneConfigSingleton{}
+}
+
+// errorProneConfigSingleton writes the Error Prone configuration of every module checked with Error
+// Prone to $OUT_DIR/soong/module_bp_errorprone_config.json, so that IDEs and other analysis runners can
+// check the code the same way the build does.
+type errorProneConfigSingleton struct{}
+
+func (e *errorProneConfigSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().RunErrorProne() || !ctx.Config().IsEnvTrue(envVariableCollectErrorProneConfig) {
+		return
+	}
+
+	var configs []errorProneModuleConfig
+	ctx.VisitAllModules(func(m android.Module) {
+		if !m.Enabled() {
+			return
+		}
+		provider, ok := m.(errorProneConfigProvider)
+		if !ok || provider.errorProneConfig() == nil {
+			return
+		}
+		config, err := resolveErrorProneConfig(provider.errorProneConfig(), func(s string) (string, error) {
+			return ctx.Eval(pctx, s)
+		})
+		if err != nil {
+			ctx.Errorf("%s: %s", ctx.ModuleName(m), err)
+			return
+		}
+		config.Name = ctx.ModuleName(m)
+		config.Variant = ctx.ModuleSubDir(m)
+		configs = append(configs, config)
+	})
+
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Name != configs[j].Name {
+			return configs[i].Name < configs[j].Name
+		}
+		return configs[i].Variant < configs[j].Variant
+	})
+
+	android.WriteJSONReport(ctx, android.PathForOutput(ctx, errorProneConfigJsonFileName), configs,
+		"errorprone-config")
+}
+
+// resolveErrorProneConfig expands the ninja variables in the flags of info with eval, and computes
+// the resulting severity of each check that the flags set.
+func resolveErrorProneConfig(info *errorProneInfo,
+	eval func(string) (string, error)) (errorProneModuleConfig, error) {
+
+	expand := func(flags []string) ([]string, error) {
+		var ret []string
+		for _, flag := range flags {
+			value, err := eval(flag)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, strings.Fields(value)...)
+		}
+		return ret, nil
+	}
+
+	checks, err := expand(info.checks)
+	if err != nil {
+		return errorProneModuleConfig{}, err
+	}
+	javacflags, err := expand(info.javacflags)
+	if err != nil {
+		return errorProneModuleConfig{}, err
+	}
+
+	// Error Prone uses the last severity passed for a check.
+	severities := make(map[string]string)
+	for _, flag := range checks {
+		if !strings.HasPrefix(flag, "-Xep:") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(flag, "-Xep:"), ":")
+		switch len(parts) {
+		case 1:
+			severities[parts[0]] = "DEFAULT"
+		case 2:
+			severities[parts[0]] = parts[1]
+		default:
+			return errorProneModuleConfig{}, fmt.Errorf("invalid Error Prone flag %q", flag)
+		}
+	}
+
+	return errorProneModuleConfig{
+		Profile:    info.profile,
+		Classpath:  info.classpath.Strings(),
+		Checks:     checks,
+		Severities: severities,
+		Javacflags: javacflags,
+	}, nil
+}
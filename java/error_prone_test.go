@@ -0,0 +1,900 @@
+// Copyright 2020 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+	"android/soong/java/config"
+)
+
+// testErrorProneConfig returns a config that runs Error Prone. The returned function restores the
+// Error Prone classpath, which is normally set by external/error_prone.
+func testErrorProneConfig(env map[string]string, bp string, fs map[string][]byte) (android.Config, func()) {
+	savedClasspath := config.ErrorProneClasspath
+	savedNullAwayClasspath := config.NullAwayClasspath
+	config.ErrorProneClasspath = []string{"external/error_prone/error_prone_core.jar"}
+	config.NullAwayClasspath = []string{"external/error_prone/nullaway.jar"}
+
+	if env == nil {
+		env = make(map[string]string)
+	}
+	env["RUN_ERROR_PRONE"] = "true"
+	return testConfig(env, bp, fs), func() {
+		config.ErrorProneClasspath = savedClasspath
+		config.NullAwayClasspath = savedNullAwayClasspath
+	}
+}
+
+func TestErrorProneSeverityOverrides(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				enabled_checks: ["MissingOverride"],
+				warnings_as_errors: ["ReturnValueIgnored"],
+				disabled_checks: ["DeadException"],
+				javacflags: ["-Xep:DeadException:ERROR"],
+			},
+		}
+	`
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, nil)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	javacFlags := ctx.ModuleForTests("foo", "android_common").Description("errorprone").Args["javacFlags"]
+	expected := "${config.ErrorProneChecks} -Xep:MissingOverride:WARN -Xep:ReturnValueIgnored:ERROR " +
+		"-Xep:DeadException:OFF -Xep:DeadException:ERROR"
+	if !strings.Contains(javacFlags, expected) {
+		t.Errorf("expected errorprone flags to contain %q, got %q", expected, javacFlags)
+	}
+}
+
+func TestErrorProneJavacflags(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			javacflags: ["-Werror", "-Xlint:all"],
+			errorprone: {
+				extra_javacflags: ["-Xmaxwarns 10"],
+				exclude_javacflags: ["-Werror"],
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			javacflags: ["-Werror"],
+		}
+	`
+	fs := map[string][]byte{
+		"errorprone.json": []byte(`{
+			"ExtraJavacflags": ["-XDcompilePolicy=byfile"],
+			"ExcludeJavacflags": ["-Xlint:all"]
+		}`),
+	}
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, fs)
+	defer restore()
+	errorProneConfig.TestProductVariables.ErrorProneConfig = proptools.StringPtr("errorprone.json")
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	javacFlags := foo.Description("errorprone").Args["javacFlags"]
+	for _, flag := range []string{"-Werror", "-Xlint:all"} {
+		if strings.Contains(javacFlags, flag) {
+			t.Errorf("expected foo errorprone flags not to contain %q, got %q", flag, javacFlags)
+		}
+	}
+	expected := "${config.ErrorProneExtraJavacflags} -Xmaxwarns 10 '-Xplugin:ErrorProne"
+	if !strings.Contains(javacFlags, expected) {
+		t.Errorf("expected foo errorprone flags to contain %q, got %q", expected, javacFlags)
+	}
+	if javac := foo.Rule("javac").Args["javacFlags"]; strings.Contains(javac, "-Xmaxwarns") {
+		t.Errorf("expected foo javac flags not to contain the Error Prone javac flags, got %q", javac)
+	}
+
+	// Only the config file's exclusions apply to bar.
+	bar := ctx.ModuleForTests("bar", "android_common").Description("errorprone").Args["javacFlags"]
+	if !strings.Contains(bar, "-Werror") {
+		t.Errorf("expected bar errorprone flags to contain -Werror, got %q", bar)
+	}
+
+	loaded, err := config.ErrorProneConfigFor(android.PathContextForTesting(errorProneConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.ExtraJavacflags, []string{"-XDcompilePolicy=byfile"}) {
+		t.Errorf("expected the extra javac flags from the config file, got %q", loaded.ExtraJavacflags)
+	}
+}
+
+func TestErrorProneProfiles(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			errorprone: {
+				profile: "external",
+			},
+		}
+	`
+	fs := map[string][]byte{
+		"external/baz/Android.bp": []byte(`
+			java_library {
+				name: "baz",
+				srcs: ["c.java"],
+			}
+
+			java_library {
+				name: "qux",
+				srcs: ["d.java"],
+				errorprone: {
+					profile: "default",
+				},
+			}
+		`),
+		"external/baz/c.java": nil,
+		"external/baz/d.java": nil,
+	}
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, fs)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	for _, testCase := range []struct {
+		module string
+		checks string
+	}{
+		{"foo", "${config.ErrorProneChecks}"},
+		{"bar", "${config.ErrorProneExternalChecks}"},
+		{"baz", "${config.ErrorProneExternalChecks}"},
+		{"qux", "${config.ErrorProneChecks}"},
+	} {
+		javacFlags := ctx.ModuleForTests(testCase.module, "android_common").Description("errorprone").Args["javacFlags"]
+		expected := "'-Xplugin:ErrorProne " + testCase.checks
+		if !strings.Contains(javacFlags, expected) {
+			t.Errorf("expected %s errorprone flags to contain %q, got %q", testCase.module, expected, javacFlags)
+		}
+	}
+}
+
+func TestErrorProneUnknownProfile(t *testing.T) {
+	errorProneConfig, restore := testErrorProneConfig(nil, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				profile: "relaxed",
+			},
+		}
+	`, nil)
+	defer restore()
+	testJavaErrorWithConfig(t, `unknown Error Prone profile "relaxed"`, errorProneConfig)
+}
+
+func TestErrorProneSeverityOverridesErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		errorprone string
+		err        string
+	}{
+		{
+			name:       "invalid name",
+			errorprone: `enabled_checks: ["-XepAllErrorsAsWarnings"]`,
+			err:        `invalid check name "-XepAllErrorsAsWarnings"`,
+		},
+		{
+			name: "listed twice",
+			errorprone: `
+				enabled_checks: ["MissingOverride"],
+				disabled_checks: ["MissingOverride"],
+			`,
+			err: `check "MissingOverride" is listed more than once`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			errorProneConfig, restore := testErrorProneConfig(nil, `
+				java_library {
+					name: "foo",
+					srcs: ["a.java"],
+					errorprone: {
+						`+testCase.errorprone+`
+					},
+				}
+			`, nil)
+			defer restore()
+			testJavaErrorWithConfig(t, testCase.err, errorProneConfig)
+		})
+	}
+}
+
+func TestNullAway(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				nullaway: true,
+				nullaway_annotated_packages: ["com.android.foo"],
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, nil)
+	defer restore()
+	errorProneConfig.TestProductVariables.NullAwayAnnotatedPackages = []string{"android.util"}
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	expected := "-Xep:NullAway:ERROR -XepOpt:NullAway:AnnotatedPackages=android.util,com.android.foo"
+	if !strings.Contains(foo.Args["javacFlags"], expected) {
+		t.Errorf("expected foo errorprone flags to contain %q, got %q", expected, foo.Args["javacFlags"])
+	}
+	if !strings.Contains(foo.Args["processorpath"], "external/error_prone/nullaway.jar") {
+		t.Errorf("expected foo processorpath to contain the NullAway jar, got %q", foo.Args["processorpath"])
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common").Description("errorprone")
+	if strings.Contains(bar.Args["javacFlags"], "NullAway") {
+		t.Errorf("expected bar errorprone flags not to enable NullAway, got %q", bar.Args["javacFlags"])
+	}
+	if strings.Contains(bar.Args["processorpath"], "nullaway.jar") {
+		t.Errorf("expected bar processorpath not to contain the NullAway jar, got %q", bar.Args["processorpath"])
+	}
+}
+
+func TestNullAwayWithoutAnnotatedPackages(t *testing.T) {
+	errorProneConfig, restore := testErrorProneConfig(nil, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				nullaway: true,
+			},
+		}
+	`, nil)
+	defer restore()
+	testJavaErrorWithConfig(t, "NullAway requires at least one annotated package", errorProneConfig)
+}
+
+func TestErrorProneBaseline(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				baseline_file: "errorprone-baseline.txt",
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`
+	fs := map[string][]byte{
+		"errorprone-baseline.txt": nil,
+	}
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, fs)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	if foo.Rule != errorproneBaseline {
+		t.Errorf("expected foo to be compiled with the errorproneBaseline rule, got %s", foo.Rule)
+	}
+	baselineFlags := foo.Args["baselineFlags"]
+	if !strings.Contains(baselineFlags, "--baseline errorprone-baseline.txt") {
+		t.Errorf("expected foo baseline errorprone-baseline.txt, got %q", baselineFlags)
+	}
+	if !strings.Contains(baselineFlags, "/errorprone/baseline.txt") {
+		t.Errorf("expected foo updated baseline in the errorprone directory, got %q", baselineFlags)
+	}
+	if strings.Contains(baselineFlags, "--update-baseline") {
+		t.Errorf("expected foo baseline not to be updated, got %q", baselineFlags)
+	}
+	if strings.Contains(baselineFlags, "--sarif") {
+		t.Errorf("expected no SARIF report without ERROR_PRONE_REPORTS, got %q", baselineFlags)
+	}
+
+	// The regular compilation doesn't use the baseline.
+	if javac := ctx.ModuleForTests("foo", "android_common").Rule("javac"); javac.Rule == errorproneBaseline {
+		t.Errorf("expected foo javac not to use the baseline")
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common").Description("errorprone")
+	if bar.Rule == errorproneBaseline {
+		t.Errorf("expected bar not to be compiled with the errorproneBaseline rule")
+	}
+}
+
+func TestErrorProneUpdateBaseline(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				baseline_file: "errorprone-baseline.txt",
+			},
+		}
+	`
+	fs := map[string][]byte{
+		"errorprone-baseline.txt": nil,
+	}
+
+	env := map[string]string{"ERROR_PRONE_UPDATE_BASELINE": "true"}
+	errorProneConfig, restore := testErrorProneConfig(env, bp, fs)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	if !strings.Contains(foo.Args["baselineFlags"], "--update-baseline") {
+		t.Errorf("expected --update-baseline, got %q", foo.Args["baselineFlags"])
+	}
+}
+
+func TestErrorProneReports(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.java", "c.java"],
+			errorprone: {
+				shard_size: 2,
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["d.java"],
+		}
+	`
+
+	env := map[string]string{"ERROR_PRONE_REPORTS": "true"}
+	errorProneConfig, restore := testErrorProneConfig(env, bp, nil)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	for i := 0; i < 2; i++ {
+		shard := foo.Description("errorprone" + strconv.Itoa(i))
+		if shard.Rule != errorproneBaseline {
+			t.Errorf("expected foo shard %d to be compiled with the errorproneBaseline rule, got %s",
+				i, shard.Rule)
+		}
+		report := "errorprone/shard" + strconv.Itoa(i) + "/errorprone.sarif"
+		if !strings.Contains(shard.Args["baselineFlags"], "--sarif ") ||
+			!strings.HasSuffix(shard.Args["baselineFlags"], report) {
+			t.Errorf("expected foo shard %d to write %s, got %q", i, report, shard.Args["baselineFlags"])
+		}
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common").Description("errorprone")
+	if !strings.HasSuffix(bar.Args["baselineFlags"], "errorprone/errorprone.sarif") {
+		t.Errorf("expected bar to write errorprone/errorprone.sarif, got %q", bar.Args["baselineFlags"])
+	}
+
+	merge := ctx.SingletonForTests("errorprone_report").Output("errorprone-report.sarif")
+	var reports []string
+	for _, input := range merge.Inputs {
+		reports = append(reports, strings.TrimPrefix(input.String(), buildDir+"/"))
+	}
+	expected := []string{
+		".intermediates/bar/android_common/errorprone/errorprone.sarif",
+		".intermediates/foo/android_common/errorprone/shard0/errorprone.sarif",
+		".intermediates/foo/android_common/errorprone/shard1/errorprone.sarif",
+	}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("expected merged reports %q, got %q", expected, reports)
+	}
+}
+
+func TestErrorProneReportsDisabled(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+	`
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, nil)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	if foo.Rule == errorproneBaseline {
+		t.Errorf("expected foo not to be compiled with the errorproneBaseline rule")
+	}
+	if merge := ctx.SingletonForTests("errorprone_report").MaybeOutput("errorprone-report.sarif"); merge.Rule != nil {
+		t.Errorf("expected no merged Error Prone report without ERROR_PRONE_REPORTS")
+	}
+}
+
+func TestErrorPronePlugins(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				plugins: ["module-checks"],
+			},
+		}
+
+		java_library_host {
+			name: "foo-host",
+			srcs: ["a.java"],
+		}
+
+		java_plugin {
+			name: "module-checks",
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "product-checks",
+			srcs: ["c.java"],
+		}
+	`
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, nil)
+	defer restore()
+	errorProneConfig.TestProductVariables.ErrorPronePlugins = []string{"product-checks"}
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	buildOS := android.BuildOs.String()
+	moduleChecks := ctx.ModuleForTests("module-checks", buildOS+"_common").Description("javac").Output.String()
+	productChecks := ctx.ModuleForTests("product-checks", buildOS+"_common").Description("javac").Output.String()
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	for _, jar := range []string{moduleChecks, productChecks} {
+		if !strings.Contains(foo.Args["processorpath"], jar) {
+			t.Errorf("expected foo processorpath to contain %q, got %q", jar, foo.Args["processorpath"])
+		}
+		if !inList(jar, foo.Implicits.Strings()) {
+			t.Errorf("expected foo implicits to contain %q, got %q", jar, foo.Implicits.Strings())
+		}
+	}
+
+	// The regular compilation doesn't use the Error Prone plugins.
+	javac := ctx.ModuleForTests("foo", "android_common").Description("javac")
+	if strings.Contains(javac.Args["processorpath"], moduleChecks) {
+		t.Errorf("expected foo javac processorpath not to contain %q, got %q", moduleChecks, javac.Args["processorpath"])
+	}
+
+	// The product's plugins are only used for device modules.
+	fooHost := ctx.ModuleForTests("foo-host", buildOS+"_common").Description("errorprone")
+	if strings.Contains(fooHost.Args["processorpath"], productChecks) {
+		t.Errorf("expected foo-host processorpath not to contain %q, got %q", productChecks, fooHost.Args["processorpath"])
+	}
+}
+
+func TestErrorPronePluginNotPlugin(t *testing.T) {
+	errorProneConfig, restore := testErrorProneConfig(nil, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				plugins: ["bar"],
+			},
+		}
+
+		java_library_host {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`, nil)
+	defer restore()
+	testJavaErrorWithConfig(t, `"bar" is not a java_plugin module`, errorProneConfig)
+}
+
+func TestRunErrorProneProductVariable(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+	`
+
+	savedClasspath := config.ErrorProneClasspath
+	config.ErrorProneClasspath = []string{"external/error_prone/error_prone_core.jar"}
+	defer func() { config.ErrorProneClasspath = savedClasspath }()
+
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		product  *bool
+		expected bool
+	}{
+		{
+			name:     "default",
+			expected: false,
+		},
+		{
+			name:     "product",
+			product:  proptools.BoolPtr(true),
+			expected: true,
+		},
+		{
+			name:     "env overrides product",
+			env:      map[string]string{"RUN_ERROR_PRONE": "false"},
+			product:  proptools.BoolPtr(true),
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := testConfig(testCase.env, bp, nil)
+			config.TestProductVariables.RunErrorProne = testCase.product
+			ctx, _ := testJavaWithConfig(t, config)
+
+			errorprone := ctx.ModuleForTests("foo", "android_common").MaybeDescription("errorprone")
+			if (errorprone.Rule != nil) != testCase.expected {
+				t.Errorf("expected Error Prone to run: %t, got %t", testCase.expected, errorprone.Rule != nil)
+			}
+		})
+	}
+}
+
+func TestErrorProneModeFlags(t *testing.T) {
+	if flags, err := config.ErrorProneModeFlags("errors"); err != nil || len(flags) != 0 {
+		t.Errorf("expected no flags for errors mode, got %q, %v", flags, err)
+	}
+	if flags, err := config.ErrorProneModeFlags("warnings"); err != nil || !inList("-XepAllErrorsAsWarnings", flags) {
+		t.Errorf("expected -XepAllErrorsAsWarnings for warnings mode, got %q, %v", flags, err)
+	}
+	if _, err := config.ErrorProneModeFlags("strict"); err == nil {
+		t.Errorf("expected an error for an unknown mode")
+	}
+}
+
+func TestErrorProneConfigFile(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+	`
+	fs := map[string][]byte{
+		"build/errorprone/config.json": []byte(`{
+			"Classpath": ["prebuilts/errorprone/error_prone_core.jar"],
+			"ChecksError": ["-Xep:MissingOverride:ERROR"]
+		}`),
+	}
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, fs)
+	defer restore()
+	errorProneConfig.TestProductVariables.ErrorProneConfig = proptools.StringPtr("build/errorprone/config.json")
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common").Description("errorprone")
+	if !strings.Contains(foo.Args["processorpath"], "prebuilts/errorprone/error_prone_core.jar") {
+		t.Errorf("expected foo processorpath to contain the configured jar, got %q", foo.Args["processorpath"])
+	}
+	if strings.Contains(foo.Args["processorpath"], "external/error_prone/error_prone_core.jar") {
+		t.Errorf("expected the config file to override the Error Prone classpath, got %q", foo.Args["processorpath"])
+	}
+
+	loaded, err := config.ErrorProneConfigFor(android.PathContextForTesting(errorProneConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.ChecksError, []string{"-Xep:MissingOverride:ERROR"}) {
+		t.Errorf("expected the error checks from the config file, got %q", loaded.ChecksError)
+	}
+}
+
+func TestErrorProneConfigFileErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "unknown field",
+			config: `{"ChecksFatal": ["-Xep:MissingOverride:ERROR"]}`,
+			err:    `unknown field "ChecksFatal"`,
+		},
+		{
+			name:   "not a flag",
+			config: `{"Flags": ["XepDisableWarningsInGeneratedCode"]}`,
+			err:    `flag "XepDisableWarningsInGeneratedCode" must start with '-'`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			errorProneConfig, restore := testErrorProneConfig(nil, `
+				java_library {
+					name: "foo",
+					srcs: ["a.java"],
+				}
+			`, map[string][]byte{
+				"errorprone.json": []byte(testCase.config),
+			})
+			defer restore()
+			errorProneConfig.TestProductVariables.ErrorProneConfig = proptools.StringPtr("errorprone.json")
+			testJavaErrorWithConfig(t, testCase.err, errorProneConfig)
+		})
+	}
+}
+
+func TestErrorProneSharding(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.java", "c.java"],
+			errorprone: {
+				shard_size: 2,
+			},
+		}
+	`
+
+	errorProneConfig, restore := testErrorProneConfig(nil, bp, nil)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	headerJar := foo.Output("turbine-combined/foo.jar").Output.String()
+
+	expectedSrcs := [][]string{{"a.java", "b.java"}, {"c.java"}}
+	for idx, srcs := range expectedSrcs {
+		errorprone := foo.Description("errorprone" + strconv.Itoa(idx))
+		if !reflect.DeepEqual(errorprone.Inputs.Strings(), srcs) {
+			t.Errorf("expected errorprone shard %d inputs %q, got %q", idx, srcs, errorprone.Inputs.Strings())
+		}
+		if !strings.Contains(errorprone.Args["classpath"], headerJar) {
+			t.Errorf("expected errorprone shard %d classpath to contain %q, got %q", idx,
+				headerJar, errorprone.Args["classpath"])
+		}
+	}
+	if foo.MaybeDescription("errorprone").Rule != nil {
+		t.Errorf("expected no unsharded errorprone action")
+	}
+
+	// The environment overrides the module's shard size.
+	env := map[string]string{"ERROR_PRONE_SHARD_SIZE": "1"}
+	errorProneConfig, restore = testErrorProneConfig(env, bp, nil)
+	defer restore()
+	ctx, _ = testJavaWithConfig(t, errorProneConfig)
+	if ctx.ModuleForTests("foo", "android_common").MaybeDescription("errorprone2").Rule == nil {
+		t.Errorf("expected 3 errorprone shards with ERROR_PRONE_SHARD_SIZE=1")
+	}
+}
+
+func TestErrorPronePathSeverities(t *testing.T) {
+	fs := map[string][]byte{
+		"errorprone.json": []byte(`{
+			"PathSeverities": [
+				{
+					"Path": "frameworks/base/services/core",
+					"DisabledChecks": ["MissingOverride"]
+				},
+				{
+					"Path": "frameworks/base/services",
+					"WarningsAsErrors": ["ReturnValueIgnored", "MissingOverride"]
+				}
+			]
+		}`),
+		"frameworks/base/services/a.java":       nil,
+		"frameworks/base/services/core/a.java":  nil,
+		"frameworks/base/services-other/a.java": nil,
+		"frameworks/base/services/Android.bp": []byte(`
+			java_library {
+				name: "services",
+				srcs: ["a.java"],
+			}
+		`),
+		"frameworks/base/services/core/Android.bp": []byte(`
+			java_library {
+				name: "services.core",
+				srcs: ["a.java"],
+			}
+		`),
+		"frameworks/base/services-other/Android.bp": []byte(`
+			java_library {
+				name: "services-other",
+				srcs: ["a.java"],
+			}
+		`),
+	}
+
+	errorProneConfig, restore := testErrorProneConfig(nil, "", fs)
+	defer restore()
+	errorProneConfig.TestProductVariables.ErrorProneConfig = proptools.StringPtr("errorprone.json")
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	testCases := []struct {
+		module   string
+		expected string
+	}{
+		{
+			module:   "services",
+			expected: "${config.ErrorProneChecks} -Xep:ReturnValueIgnored:ERROR -Xep:MissingOverride:ERROR'",
+		},
+		{
+			// The overlay for the more specific path comes last, so it wins.
+			module: "services.core",
+			expected: "${config.ErrorProneChecks} -Xep:ReturnValueIgnored:ERROR -Xep:MissingOverride:ERROR " +
+				"-Xep:MissingOverride:OFF'",
+		},
+		{
+			module:   "services-other",
+			expected: "${config.ErrorProneChecks}'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		javacFlags := ctx.ModuleForTests(testCase.module, "android_common").Description("errorprone").Args["javacFlags"]
+		if !strings.Contains(javacFlags, testCase.expected) {
+			t.Errorf("expected %s errorprone flags to contain %q, got %q", testCase.module, testCase.expected, javacFlags)
+		}
+	}
+}
+
+func TestErrorPronePathSeveritiesErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "invalid path",
+			config: `{"PathSeverities": [{"Path": "../vendor", "EnabledChecks": ["MissingOverride"]}]}`,
+			err:    `path severities: invalid path "../vendor"`,
+		},
+		{
+			name:   "invalid check",
+			config: `{"PathSeverities": [{"Path": "vendor", "EnabledChecks": ["-XepAllErrorsAsWarnings"]}]}`,
+			err:    `path severities for "vendor": invalid check name "-XepAllErrorsAsWarnings"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			errorProneConfig, restore := testErrorProneConfig(nil, `
+				java_library {
+					name: "foo",
+					srcs: ["a.java"],
+				}
+			`, map[string][]byte{
+				"errorprone.json": []byte(testCase.config),
+			})
+			defer restore()
+			errorProneConfig.TestProductVariables.ErrorProneConfig = proptools.StringPtr("errorprone.json")
+			testJavaErrorWithConfig(t, testCase.err, errorProneConfig)
+		})
+	}
+}
+
+func TestResolveErrorProneConfig(t *testing.T) {
+	vars := map[string]string{
+		"${config.ErrorProneChecks}": "-Xep:MissingOverride:ERROR -Xep:DeadException:WARN",
+		"${config.ErrorProneFlags}":  "-XDcompilePolicy=simple",
+	}
+	eval := func(s string) (string, error) {
+		if strings.HasPrefix(s, "$") {
+			if value, ok := vars[s]; ok {
+				return value, nil
+			}
+			return "", fmt.Errorf("no such variable %s", s)
+		}
+		return s, nil
+	}
+
+	info := &errorProneInfo{
+		profile:    "default",
+		classpath:  classpath(android.PathsForTesting("error_prone_core.jar")),
+		checks:     []string{"${config.ErrorProneChecks}", "-Xep:DeadException:OFF", "-Xep:NullAway"},
+		javacflags: []string{"${config.ErrorProneFlags}", "-Xmaxwarns 10"},
+	}
+	config, err := resolveErrorProneConfig(info, eval)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := errorProneModuleConfig{
+		Profile:   "default",
+		Classpath: []string{"error_prone_core.jar"},
+		Checks: []string{"-Xep:MissingOverride:ERROR", "-Xep:DeadException:WARN", "-Xep:DeadException:OFF",
+			"-Xep:NullAway"},
+		Severities: map[string]string{
+			"MissingOverride": "ERROR",
+			"DeadException":   "OFF",
+			"NullAway":        "DEFAULT",
+		},
+		Javacflags: []string{"-XDcompilePolicy=simple", "-Xmaxwarns", "10"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected %#v, got %#v", expected, config)
+	}
+
+	info.checks = []string{"-Xep:MissingOverride:ERROR:WARN"}
+	if _, err := resolveErrorProneConfig(info, eval); err == nil {
+		t.Errorf("expected an error for an invalid flag")
+	}
+}
+
+func TestErrorProneConfigJson(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			errorprone: {
+				profile: "external",
+				warnings_as_errors: ["ReturnValueIgnored"],
+			},
+		}
+	`
+
+	env := map[string]string{"SOONG_COLLECT_ERROR_PRONE_CONFIG": "true"}
+	errorProneConfig, restore := testErrorProneConfig(env, bp, nil)
+	defer restore()
+	ctx, _ := testJavaWithConfig(t, errorProneConfig)
+
+	ctx.SingletonForTests("errorprone_config").Output(errorProneConfigJsonFileName)
+
+	data, err := ioutil.ReadFile(filepath.Join(buildDir, errorProneConfigJsonFileName))
+	if err != nil {
+		t.Fatalf("failed to read the Error Prone config: %s", err)
+	}
+	var configs []errorProneModuleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		t.Fatalf("failed to parse the Error Prone config: %s", err)
+	}
+
+	if len(configs) != 1 || configs[0].Name != "foo" {
+		t.Fatalf("expected the Error Prone config of foo, got %#v", configs)
+	}
+	foo := configs[0]
+	if foo.Profile != "external" {
+		t.Errorf("expected foo profile external, got %q", foo.Profile)
+	}
+	if !android.InList("external/error_prone/error_prone_core.jar", foo.Classpath) {
+		t.Errorf("expected foo classpath to contain the Error Prone jar, got %q", foo.Classpath)
+	}
+	if !android.InList("-XepDisableAllWarnings", foo.Checks) {
+		t.Errorf("expected foo checks to contain the external profile, got %q", foo.Checks)
+	}
+	if foo.Severities["ReturnValueIgnored"] != "ERROR" {
+		t.Errorf("expected ReturnValueIgnored to be an error, got %q", foo.Severities["ReturnValueIgnored"])
+	}
+}
@@ -84,6 +84,8 @@ func RegisterJavaBuildComponents(ctx android.RegistrationContext) {
 
 	ctx.RegisterSingletonType("logtags", LogtagsSingleton)
 	ctx.RegisterSingletonType("kythe_java_extract", kytheExtractJavaFactory)
+	ctx.RegisterSingletonType("errorprone_report", errorProneReportSingletonFactory)
+	ctx.RegisterSingletonType("errorprone_config", errorProneConfigSingletonFactory)
 }
 
 func (j *Module) CheckStableSdkVersion() error {
@@ -242,6 +244,51 @@ type CompilerProperties struct {
 	Errorprone struct {
 		// List of javac flags that should only be used when running errorprone.
 		Javacflags []string
+
+		// The Error Prone check profile, "default" or "external".  The external profile only
+		// fails the build on the checks Error Prone reports as errors by default, and is meant
+		// for third-party code.  Defaults to external for modules under external/, and to
+		// default otherwise.
+		Profile *string
+
+		// List of javac flags to pass to the javac invocation that runs Error Prone after the
+		// javac flags of the module, outside of the Error Prone plugin arguments.
+		Extra_javacflags []string
+
+		// List of javac flags of the module, like -Werror, to leave out of the javac invocation
+		// that runs Error Prone.
+		Exclude_javacflags []string
+
+		// List of Error Prone checks to enable as warnings for this module, overriding the
+		// global severity of the checks.
+		Enabled_checks []string
+
+		// List of Error Prone checks to report as errors for this module.
+		Warnings_as_errors []string
+
+		// List of Error Prone checks to disable for this module.
+		Disabled_checks []string
+
+		// If true, run the NullAway check on this module.  Defaults to false.
+		Nullaway *bool
+
+		// List of java packages to treat as annotated for nullness, in addition to the packages
+		// set by the product.
+		Nullaway_annotated_packages []string
+
+		// List of java_plugin modules that provide extra Error Prone checks.
+		Plugins []string
+
+		// The number of Java source entries each Error Prone instance checks.  Only used when
+		// the module is compiled with turbine, whose header jar is used as the classpath for
+		// the other sources of the module.  The ERROR_PRONE_SHARD_SIZE environment variable
+		// overrides it.
+		Shard_size *int64
+
+		// Path to a baseline file listing pre-existing Error Prone errors, one
+		// "<check> <file>" entry per line, that don't fail the build.  Run with
+		// ERROR_PRONE_UPDATE_BASELINE=true to generate an updated baseline.
+		Baseline_file *string `android:"path"`
 	}
 
 	Proto struct {
@@ -489,6 +536,12 @@ type Module struct {
 	// list of the xref extraction files
 	kytheFiles android.Paths
 
+	// SARIF reports of the Error Prone findings
+	errorProneReports android.Paths
+
+	// how the module is checked with Error Prone, or nil if it isn't
+	errorProneInfo *errorProneInfo
+
 	distFile android.Path
 }
 
@@ -550,6 +603,14 @@ func (j *Module) XrefJavaFiles() android.Paths {
 	return j.kytheFiles
 }
 
+func (j *Module) ErrorProneReports() android.Paths {
+	return j.errorProneReports
+}
+
+func (j *Module) errorProneConfig() *errorProneInfo {
+	return j.errorProneInfo
+}
+
 func InitJavaModule(module android.DefaultableModule, hod android.HostOrDeviceSupported) {
 	android.InitAndroidArchModule(module, hod, android.MultilibCommon)
 	android.InitDefaultableModule(module)
@@ -560,10 +621,25 @@ type dependencyTag struct {
 	name string
 }
 
+// IncludeInLicenseMetadata returns true for static libraries, which are included in the jar of
+// the module.
+func (d dependencyTag) IncludeInLicenseMetadata() bool {
+	return d == staticLibTag
+}
+
+var _ android.IncludeInLicenseMetadataTag = dependencyTag{}
+
 type jniDependencyTag struct {
 	blueprint.BaseDependencyTag
 }
 
+// IncludeInLicenseMetadata returns true, as jni libraries are installed with the module.
+func (d jniDependencyTag) IncludeInLicenseMetadata() bool {
+	return true
+}
+
+var _ android.IncludeInLicenseMetadataTag = &jniDependencyTag{}
+
 func IsJniDepTag(depTag blueprint.DependencyTag) bool {
 	_, ok := depTag.(*jniDependencyTag)
 	return ok
@@ -575,6 +651,7 @@ var (
 	java9LibTag           = dependencyTag{name: "java9lib"}
 	pluginTag             = dependencyTag{name: "plugin"}
 	exportedPluginTag     = dependencyTag{name: "exported-plugin"}
+	errorPronePluginTag   = dependencyTag{name: "errorprone-plugin"}
 	bootClasspathTag      = dependencyTag{name: "bootclasspath"}
 	systemModulesTag      = dependencyTag{name: "system modules"}
 	frameworkResTag       = dependencyTag{name: "framework-res"}
@@ -755,6 +832,17 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), pluginTag, j.properties.Plugins...)
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), exportedPluginTag, j.properties.Exported_plugins...)
 
+	if ctx.Config().RunErrorProne() {
+		errorPronePlugins := j.properties.Errorprone.Plugins
+		if ctx.Device() {
+			// The product's plugins are host modules, so only device modules use them to avoid
+			// dependency cycles.
+			errorPronePlugins = append(android.CopyOf(ctx.Config().ErrorPronePlugins()), errorPronePlugins...)
+		}
+		ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), errorPronePluginTag,
+			android.FirstUniqueStrings(errorPronePlugins)...)
+	}
+
 	android.ProtoDeps(ctx, &j.protoProperties)
 	if j.hasSrcExt(".proto") {
 		protoDeps(ctx, &j.protoProperties)
@@ -845,6 +933,7 @@ type deps struct {
 	bootClasspath      classpath
 	processorPath      classpath
 	processorClasses   []string
+	errorPronePlugins  classpath
 	staticJars         android.Paths
 	staticHeaderJars   android.Paths
 	staticResourceJars android.Paths
@@ -1058,6 +1147,12 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				} else {
 					ctx.PropertyErrorf("plugins", "%q is not a java_plugin module", otherName)
 				}
+			case errorPronePluginTag:
+				if plugin, ok := dep.(*Plugin); ok {
+					deps.errorPronePlugins = append(deps.errorPronePlugins, plugin.ImplementationAndResourcesJars()...)
+				} else {
+					ctx.PropertyErrorf("errorprone.plugins", "%q is not a java_plugin module", otherName)
+				}
 			case exportedPluginTag:
 				if plugin, ok := dep.(*Plugin); ok {
 					if plugin.pluginProperties.Generates_api != nil && *plugin.pluginProperties.Generates_api {
@@ -1219,20 +1314,67 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 	}
 	javacFlags = append(javacFlags, "-Xlint:-dep-ann")
 
+	var errorProneExcludeJavacflags []string
 	if ctx.Config().RunErrorProne() {
-		if config.ErrorProneClasspath == nil {
+		errorProneConfig, err := config.ErrorProneConfigFor(ctx)
+		if err != nil {
+			ctx.ModuleErrorf("%s", err)
+		} else if errorProneConfig.Classpath == nil {
 			ctx.ModuleErrorf("cannot build with Error Prone, missing external/error_prone?")
 		}
 
+		errorProneProfile := config.ErrorProneProfile(String(j.properties.Errorprone.Profile),
+			ctx.ModuleDir())
+		errorProneChecks, err := config.ErrorProneProfileChecks(errorProneProfile)
+		if err != nil {
+			ctx.PropertyErrorf("errorprone.profile", "%s", err)
+		}
+
 		errorProneFlags := []string{
 			"-Xplugin:ErrorProne",
-			"${config.ErrorProneChecks}",
+			errorProneChecks,
+		}
+		errorProneFlags = append(errorProneFlags, errorProneConfig.PathSeverityFlags(ctx.ModuleDir())...)
+		errorProneClasspath := errorProneConfig.Classpath
+		if Bool(j.properties.Errorprone.Nullaway) {
+			if config.NullAwayClasspath == nil {
+				ctx.ModuleErrorf("cannot build with NullAway, missing external/error_prone?")
+			}
+			annotatedPackages := append(android.CopyOf(ctx.Config().NullAwayAnnotatedPackages()),
+				j.properties.Errorprone.Nullaway_annotated_packages...)
+			nullAwayFlags, err := config.NullAwayFlags(annotatedPackages)
+			if err != nil {
+				ctx.PropertyErrorf("errorprone.nullaway_annotated_packages", "%s", err)
+			}
+			errorProneFlags = append(errorProneFlags, nullAwayFlags...)
+			errorProneClasspath = append(android.CopyOf(errorProneClasspath), config.NullAwayClasspath...)
 		}
+		severityFlags, err := config.ErrorProneSeverityFlags(j.properties.Errorprone.Enabled_checks,
+			j.properties.Errorprone.Warnings_as_errors, j.properties.Errorprone.Disabled_checks)
+		if err != nil {
+			ctx.PropertyErrorf("errorprone", "%s", err)
+		}
+		errorProneFlags = append(errorProneFlags, severityFlags...)
 		errorProneFlags = append(errorProneFlags, j.properties.Errorprone.Javacflags...)
 
+		errorProneExtraJavacflags := append([]string{"${config.ErrorProneExtraJavacflags}"},
+			j.properties.Errorprone.Extra_javacflags...)
+		errorProneExcludeJavacflags = append(android.CopyOf(errorProneConfig.ExcludeJavacflags),
+			j.properties.Errorprone.Exclude_javacflags...)
+
 		flags.errorProneExtraJavacFlags = "${config.ErrorProneFlags} " +
+			strings.Join(errorProneExtraJavacflags, " ") + " " +
 			"'" + strings.Join(errorProneFlags, " ") + "'"
-		flags.errorProneProcessorPath = classpath(android.PathsForSource(ctx, config.ErrorProneClasspath))
+		flags.errorProneProcessorPath = classpath(android.PathsForSource(ctx, errorProneClasspath))
+		flags.errorProneProcessorPath = append(flags.errorProneProcessorPath, deps.errorPronePlugins...)
+		flags.errorProneBaseline = android.OptionalPathForModuleSrc(ctx, j.properties.Errorprone.Baseline_file)
+
+		j.errorProneInfo = &errorProneInfo{
+			profile:    errorProneProfile,
+			classpath:  flags.errorProneProcessorPath,
+			checks:     errorProneFlags[1:],
+			javacflags: append([]string{"${config.ErrorProneFlags}"}, errorProneExtraJavacflags...),
+		}
 	}
 
 	// classpath
@@ -1293,6 +1435,15 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 		flags.javacFlags = "$javacFlags"
 	}
 
+	if ctx.Config().RunErrorProne() {
+		if len(errorProneExcludeJavacflags) > 0 {
+			flags.errorProneJavacFlags = strings.Join(
+				android.RemoveListFromList(javacFlags, errorProneExcludeJavacflags), " ")
+		} else {
+			flags.errorProneJavacFlags = flags.javacFlags
+		}
+	}
+
 	return flags
 }
 
@@ -1429,9 +1580,8 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 			// a rebuild when error-prone is turned off).
 			// TODO(ccross): Once we always compile with javac9 we may be able to conditionally
 			//    enable error-prone without affecting the output class files.
-			errorprone := android.PathForModuleOut(ctx, "errorprone", jarName)
-			RunErrorProne(ctx, errorprone, uniqueSrcFiles, srcJars, flags)
-			extraJarDeps = append(extraJarDeps, errorprone)
+			extraJarDeps = j.runErrorProne(ctx, jarName, uniqueSrcFiles, srcJars, flags,
+				headerJarFileWithoutJarjar)
 		}
 
 		if enable_sharding {
@@ -1718,6 +1868,56 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 	j.outputFile = outputFile.WithoutRel()
 }
 
+// runErrorProne checks the sources of the module with Error Prone, and returns the outputs of the
+// checks.  If the module has a header jar and an Error Prone shard size is set, each shard of
+// sources is checked in a separate action against the header jar, so that changing a source file
+// only checks its shard again.
+func (j *Module) runErrorProne(ctx android.ModuleContext, jarName string,
+	srcFiles, srcJars android.Paths, flags javaBuilderFlags, headerJar android.Path) android.Paths {
+
+	shardSize, err := config.ErrorProneShardSize(ctx.Config())
+	if err != nil {
+		ctx.ModuleErrorf("%s", err)
+	}
+	if shardSize == 0 && j.properties.Errorprone.Shard_size != nil {
+		shardSize = int(*j.properties.Errorprone.Shard_size)
+	}
+
+	// The updated baseline of a shard only lists the errors in its sources, so baselines are
+	// always updated from a single action.
+	if shardSize <= 0 || headerJar == nil || ctx.Config().IsEnvTrue("ERROR_PRONE_UPDATE_BASELINE") {
+		errorprone := android.PathForModuleOut(ctx, "errorprone", jarName)
+		j.addErrorProneReport(RunErrorProne(ctx, errorprone, -1, srcFiles, srcJars, flags))
+		return android.Paths{errorprone}
+	}
+
+	flags.classpath = append(append(classpath(nil), flags.classpath...), headerJar)
+
+	var outputs android.Paths
+	var shardSrcs []android.Paths
+	if len(srcFiles) > 0 {
+		shardSrcs = android.ShardPaths(srcFiles, shardSize)
+		for idx, shardSrc := range shardSrcs {
+			errorprone := android.PathForModuleOut(ctx, "errorprone", jarName+strconv.Itoa(idx))
+			j.addErrorProneReport(RunErrorProne(ctx, errorprone, idx, shardSrc, nil, flags))
+			outputs = append(outputs, errorprone)
+		}
+	}
+	if len(srcJars) > 0 {
+		idx := len(shardSrcs)
+		errorprone := android.PathForModuleOut(ctx, "errorprone", jarName+strconv.Itoa(idx))
+		j.addErrorProneReport(RunErrorProne(ctx, errorprone, idx, nil, srcJars, flags))
+		outputs = append(outputs, errorprone)
+	}
+	return outputs
+}
+
+func (j *Module) addErrorProneReport(report android.Path) {
+	if report != nil {
+		j.errorProneReports = append(j.errorProneReports, report)
+	}
+}
+
 func (j *Module) compileJavaClasses(ctx android.ModuleContext, jarName string, idx int,
 	srcFiles, srcJars android.Paths, flags javaBuilderFlags, extraJarDeps android.Paths) android.WritablePath {
 
@@ -2004,6 +2204,8 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		}
 		j.distFile = distFiles[0]
 	}
+
+	ctx.ModuleInfoJSON().Class = append(ctx.ModuleInfoJSON().Class, "JAVA_LIBRARIES")
 }
 
 func (j *Library) DepsMutator(ctx android.BottomUpMutatorContext) {
@@ -2228,6 +2430,12 @@ func (j *Test) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	j.data = android.PathsForModuleSrc(ctx, j.testProperties.Data)
 
 	j.Library.GenerateAndroidBuildActions(ctx)
+
+	info := ctx.ModuleInfoJSON()
+	info.CompatibilitySuites = append(info.CompatibilitySuites, j.testProperties.Test_suites...)
+	if j.testConfig != nil {
+		info.TestConfig = append(info.TestConfig, j.testConfig.String())
+	}
 }
 
 func (j *TestHelperLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
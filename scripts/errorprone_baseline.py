@@ -0,0 +1,228 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2020 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for processing the diagnostics of an Error Prone compilation.
+
+It can suppress pre-existing Error Prone errors listed in a baseline file, and
+write the Error Prone findings to a SARIF report.
+
+The baseline file lists one "<check> <file>" entry per line.  Lines starting with
+'#' are ignored.  Errors are matched by check and file, so that unrelated edits to
+a file don't invalidate its entries.
+"""
+
+from __future__ import print_function
+
+import argparse
+import json
+import os
+import re
+import sys
+
+DIAGNOSTIC_RE = re.compile(
+    r'^(?P<file>[^:\s]+\.java):(?P<line>\d+): (?P<severity>error|warning): '
+    r'(?:\[(?P<check>\w+)\] )?(?P<message>.*)$')
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--baseline', dest='baseline',
+                      help='baseline file listing the errors to suppress')
+  parser.add_argument('--log', dest='log', required=True,
+                      help='output of the Error Prone compilation')
+  parser.add_argument('--javac-exit-code', dest='javac_exit_code', type=int,
+                      default=0, help='exit code of the Error Prone compilation')
+  parser.add_argument('--updated-baseline', dest='updated_baseline',
+                      help='output baseline file listing all the current errors')
+  parser.add_argument('--update-baseline', dest='update_baseline',
+                      action='store_true',
+                      help='don\'t fail on errors missing from the baseline')
+  parser.add_argument('--sarif', dest='sarif',
+                      help='output SARIF report listing the Error Prone findings')
+  args = parser.parse_args()
+  if args.baseline and not args.updated_baseline:
+    parser.error('--baseline requires --updated-baseline')
+  return args
+
+
+def parse_baseline(lines):
+  """Returns the set of (check, file) entries in a baseline file."""
+
+  entries = set()
+  for line in lines:
+    line = line.strip()
+    if not line or line.startswith('#'):
+      continue
+    fields = line.split()
+    if len(fields) != 2:
+      raise ValueError('invalid baseline entry "%s", expected "<check> <file>"' % line)
+    entries.add((fields[0], fields[1]))
+  return entries
+
+
+def parse_findings(lines):
+  """Returns the Error Prone errors and warnings in a compilation log."""
+
+  findings = []
+  for line in lines:
+    match = DIAGNOSTIC_RE.match(line.rstrip('\n'))
+    if match and match.group('check'):
+      findings.append({
+          'check': match.group('check'),
+          'file': match.group('file'),
+          'line': int(match.group('line')),
+          'severity': match.group('severity'),
+          'message': match.group('message'),
+      })
+  return findings
+
+
+def parse_errors(lines):
+  """Returns the Error Prone errors and the other compile errors in a compilation log."""
+
+  errorprone_errors = []
+  compile_errors = []
+  for line in lines:
+    if line.startswith('error: '):
+      # Errors that aren't about a source file, like invalid flags.
+      compile_errors.append(line.rstrip('\n'))
+      continue
+    match = DIAGNOSTIC_RE.match(line.rstrip('\n'))
+    if not match or match.group('severity') != 'error':
+      continue
+    if match.group('check'):
+      errorprone_errors.append((match.group('check'), match.group('file')))
+    else:
+      compile_errors.append(line.rstrip('\n'))
+  return errorprone_errors, compile_errors
+
+
+def unexplained_failure(exit_code, errorprone_errors, compile_errors):
+  """Returns whether javac failed without reporting the errors that failed it.
+
+  A failure like a crash or running out of memory may leave incomplete classes
+  behind, so it fails the compilation even if the baseline lists every reported
+  error.
+  """
+
+  return exit_code != 0 and not errorprone_errors and not compile_errors
+
+
+def format_sarif(findings, baseline=None):
+  """Returns a SARIF 2.1.0 log listing the Error Prone findings.
+
+  If a baseline is given, each result records whether it is listed in it.
+  """
+
+  results = []
+  for finding in findings:
+    result = {
+        'ruleId': finding['check'],
+        'level': finding['severity'],
+        'message': {'text': finding['message']},
+        'locations': [{
+            'physicalLocation': {
+                'artifactLocation': {'uri': finding['file']},
+                'region': {'startLine': finding['line']},
+            },
+        }],
+    }
+    if baseline is not None:
+      if (finding['check'], finding['file']) in baseline:
+        result['baselineState'] = 'unchanged'
+      else:
+        result['baselineState'] = 'new'
+    results.append(result)
+
+  return {
+      'version': '2.1.0',
+      '$schema': 'https://json.schemastore.org/sarif-2.1.0.json',
+      'runs': [{
+          'tool': {'driver': {'name': 'Error Prone'}},
+          'results': results,
+      }],
+  }
+
+
+def format_baseline(errors):
+  return ''.join('%s %s\n' % error for error in sorted(set(errors)))
+
+
+def read_lines(path):
+  if not os.path.exists(path):
+    return []
+  with open(path) as f:
+    return f.readlines()
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  log = read_lines(args.log)
+  # Pass the compiler output through so that warnings are still shown.
+  sys.stdout.write(''.join(log))
+
+  baseline = None
+  if args.baseline:
+    try:
+      baseline = parse_baseline(read_lines(args.baseline))
+    except ValueError as err:
+      print('error: %s: %s' % (args.baseline, err), file=sys.stderr)
+      sys.exit(1)
+
+  errorprone_errors, compile_errors = parse_errors(log)
+
+  if unexplained_failure(args.javac_exit_code, errorprone_errors, compile_errors):
+    print('error: javac failed with exit code %d without reporting any errors' %
+          args.javac_exit_code, file=sys.stderr)
+    sys.exit(1)
+
+  if args.sarif:
+    with open(args.sarif, 'w') as f:
+      json.dump(format_sarif(parse_findings(log), baseline), f, indent=2, sort_keys=True)
+
+  if baseline is None:
+    # Without a baseline any error fails the compilation.
+    if args.javac_exit_code != 0 or errorprone_errors or compile_errors:
+      sys.exit(1)
+    return
+
+  with open(args.updated_baseline, 'w') as f:
+    f.write(format_baseline(errorprone_errors))
+
+  if compile_errors:
+    sys.exit(1)
+
+  new_errors = [e for e in errorprone_errors if e not in baseline]
+  if new_errors and not args.update_baseline:
+    print('error: %d Error Prone error(s) are not listed in the baseline %s:' %
+          (len(new_errors), args.baseline), file=sys.stderr)
+    for error in sorted(set(new_errors)):
+      print('  %s %s' % error, file=sys.stderr)
+    print('Fix the errors, or if they are expected, copy %s to %s.' %
+          (args.updated_baseline, args.baseline), file=sys.stderr)
+    sys.exit(1)
+
+  if args.update_baseline:
+    print('Updated Error Prone baseline written to %s, copy it to %s.' %
+          (args.updated_baseline, args.baseline), file=sys.stderr)
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,124 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2020 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for errorprone_baseline.py."""
+
+import sys
+import unittest
+
+import errorprone_baseline
+
+sys.dont_write_bytecode = True
+
+
+class ParseBaselineTest(unittest.TestCase):
+  """Unit tests for parse_baseline function."""
+
+  def test_entries(self):
+    baseline = errorprone_baseline.parse_baseline([
+        '# Pre-existing errors\n',
+        '\n',
+        'MissingOverride frameworks/foo/Foo.java\n',
+        'ReturnValueIgnored frameworks/foo/Bar.java\n',
+    ])
+    self.assertEqual(baseline, set([
+        ('MissingOverride', 'frameworks/foo/Foo.java'),
+        ('ReturnValueIgnored', 'frameworks/foo/Bar.java'),
+    ]))
+
+  def test_invalid_entry(self):
+    with self.assertRaises(ValueError):
+      errorprone_baseline.parse_baseline(['MissingOverride\n'])
+
+
+class ParseErrorsTest(unittest.TestCase):
+  """Unit tests for parse_errors function."""
+
+  def test_errors(self):
+    errorprone_errors, compile_errors = errorprone_baseline.parse_errors([
+        'frameworks/foo/Foo.java:12: error: [MissingOverride] foo overrides method in Object\n',
+        '  public String toString() {\n',
+        'frameworks/foo/Bar.java:3: warning: [DeadException] Exception created but not thrown\n',
+        'frameworks/foo/Bar.java:7: error: cannot find symbol\n',
+        'error: invalid flag: -Xfoo\n',
+        '3 errors\n',
+    ])
+    self.assertEqual(errorprone_errors, [('MissingOverride', 'frameworks/foo/Foo.java')])
+    self.assertEqual(compile_errors, [
+        'frameworks/foo/Bar.java:7: error: cannot find symbol',
+        'error: invalid flag: -Xfoo',
+    ])
+
+  def test_format_baseline(self):
+    self.assertEqual(
+        errorprone_baseline.format_baseline([
+            ('ReturnValueIgnored', 'frameworks/foo/Bar.java'),
+            ('MissingOverride', 'frameworks/foo/Foo.java'),
+            ('ReturnValueIgnored', 'frameworks/foo/Bar.java'),
+        ]),
+        'MissingOverride frameworks/foo/Foo.java\n'
+        'ReturnValueIgnored frameworks/foo/Bar.java\n')
+
+
+class UnexplainedFailureTest(unittest.TestCase):
+  """Unit tests for unexplained_failure function."""
+
+  def test_success(self):
+    self.assertFalse(errorprone_baseline.unexplained_failure(0, [], []))
+
+  def test_errors(self):
+    self.assertFalse(errorprone_baseline.unexplained_failure(
+        1, [('MissingOverride', 'frameworks/foo/Foo.java')], []))
+    self.assertFalse(errorprone_baseline.unexplained_failure(
+        1, [], ['frameworks/foo/Bar.java:7: error: cannot find symbol']))
+
+  def test_crash(self):
+    self.assertTrue(errorprone_baseline.unexplained_failure(1, [], []))
+    self.assertTrue(errorprone_baseline.unexplained_failure(137, [], []))
+
+
+class FormatSarifTest(unittest.TestCase):
+  """Unit tests for parse_findings and format_sarif functions."""
+
+  def test_sarif(self):
+    findings = errorprone_baseline.parse_findings([
+        'frameworks/foo/Foo.java:12: error: [MissingOverride] foo overrides method in Object\n',
+        'frameworks/foo/Bar.java:3: warning: [DeadException] Exception created but not thrown\n',
+        'frameworks/foo/Bar.java:7: error: cannot find symbol\n',
+    ])
+    sarif = errorprone_baseline.format_sarif(
+        findings, set([('MissingOverride', 'frameworks/foo/Foo.java')]))
+
+    self.assertEqual(sarif['version'], '2.1.0')
+    self.assertEqual(len(sarif['runs']), 1)
+    results = sarif['runs'][0]['results']
+    self.assertEqual([r['ruleId'] for r in results], ['MissingOverride', 'DeadException'])
+    self.assertEqual([r['level'] for r in results], ['error', 'warning'])
+    self.assertEqual([r['baselineState'] for r in results], ['unchanged', 'new'])
+    location = results[1]['locations'][0]['physicalLocation']
+    self.assertEqual(location['artifactLocation']['uri'], 'frameworks/foo/Bar.java')
+    self.assertEqual(location['region']['startLine'], 3)
+
+  def test_sarif_without_baseline(self):
+    findings = errorprone_baseline.parse_findings([
+        'frameworks/foo/Foo.java:12: error: [MissingOverride] foo overrides method in Object\n',
+    ])
+    results = errorprone_baseline.format_sarif(findings)['runs'][0]['results']
+    self.assertNotIn('baselineState', results[0])
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
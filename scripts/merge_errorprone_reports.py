@@ -0,0 +1,128 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2020 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for merging the SARIF reports of Error Prone compilations.
+
+It writes a single SARIF report with all the findings, and an html summary
+listing them by check.
+"""
+
+from __future__ import print_function
+
+import argparse
+import cgi
+import collections
+import json
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--sarif', dest='sarif', required=True,
+                      help='output merged SARIF report')
+  parser.add_argument('--html', dest='html', required=True,
+                      help='output html summary of the findings')
+  parser.add_argument('reports', nargs='*',
+                      help='SARIF reports to merge, or @<file> to read them from a file')
+  return parser.parse_args()
+
+
+def expand_rsp_files(args):
+  """Replaces @<file> arguments with the whitespace separated paths listed in the file."""
+
+  paths = []
+  for arg in args:
+    if arg.startswith('@'):
+      with open(arg[1:]) as f:
+        paths.extend(f.read().split())
+    else:
+      paths.append(arg)
+  return paths
+
+
+def merge_reports(reports):
+  """Returns a SARIF log with a single run holding the results of all the reports."""
+
+  results = []
+  for report in reports:
+    for run in report.get('runs', []):
+      results.extend(run.get('results', []))
+
+  return {
+      'version': '2.1.0',
+      '$schema': 'https://json.schemastore.org/sarif-2.1.0.json',
+      'runs': [{
+          'tool': {'driver': {'name': 'Error Prone'}},
+          'results': results,
+      }],
+  }
+
+
+def result_location(result):
+  location = result['locations'][0]['physicalLocation']
+  return '%s:%d' % (location['artifactLocation']['uri'], location['region']['startLine'])
+
+
+def format_html(sarif):
+  """Returns an html page listing the findings of a SARIF log by check."""
+
+  by_check = collections.defaultdict(list)
+  for result in sarif['runs'][0]['results']:
+    by_check[result['ruleId']].append(result)
+
+  lines = [
+      '<html>',
+      '<head><title>Error Prone report</title></head>',
+      '<body>',
+      '<h1>Error Prone report</h1>',
+  ]
+  if not by_check:
+    lines.append('<p>No findings.</p>')
+  for check in sorted(by_check):
+    results = sorted(by_check[check], key=result_location)
+    lines.append('<h2>%s (%d)</h2>' % (cgi.escape(check), len(results)))
+    lines.append('<table>')
+    for result in results:
+      lines.append('<tr><td>%s</td><td>%s</td><td>%s</td></tr>' % (
+          cgi.escape(result['level']),
+          cgi.escape(result_location(result)),
+          cgi.escape(result['message']['text'])))
+    lines.append('</table>')
+  lines.extend(['</body>', '</html>'])
+  return '\n'.join(lines) + '\n'
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  reports = []
+  for path in expand_rsp_files(args.reports):
+    with open(path) as f:
+      reports.append(json.load(f))
+
+  sarif = merge_reports(reports)
+
+  with open(args.sarif, 'w') as f:
+    json.dump(sarif, f, indent=2, sort_keys=True)
+
+  with open(args.html, 'w') as f:
+    f.write(format_html(sarif))
+
+
+if __name__ == '__main__':
+  main()
@@ -25,6 +25,7 @@
 #   -o ${file}: output file (required)
 #   -d ${file}: deps file (required)
 #   -k symbols: Symbols to keep (optional)
+#   -K ${file}: File that lists symbols to keep, one per line (optional)
 #   --add-gnu-debuglink
 #   --keep-mini-debug-info
 #   --keep-symbols
@@ -33,11 +34,11 @@
 
 set -o pipefail
 
-OPTSTRING=d:i:o:k:-:
+OPTSTRING=d:i:o:k:K:-:
 
 usage() {
     cat <<EOF
-Usage: strip.sh [options] -k symbols -i in-file -o out-file -d deps-file
+Usage: strip.sh [options] -k symbols -K symbols-file -i in-file -o out-file -d deps-file
 Options:
         --add-gnu-debuglink             Add a gnu-debuglink section to out-file
         --keep-mini-debug-info          Keep compressed debug info in out-file
@@ -66,6 +67,9 @@ do_strip_keep_symbols() {
 
 do_strip_keep_symbol_list() {
     echo "${symbols_to_keep}" | tr ',' '\n' > "${outfile}.symbolList"
+    if [ ! -z "${symbols_to_keep_file}" ]; then
+        cat "${symbols_to_keep_file}" >> "${outfile}.symbolList"
+    fi
 
     KEEP_SYMBOLS="--strip-unneeded-symbol=* --keep-symbols="
     KEEP_SYMBOLS+="${outfile}.symbolList"
@@ -114,6 +118,7 @@ while getopts $OPTSTRING opt; do
         i) infile="${OPTARG}" ;;
         o) outfile="${OPTARG}" ;;
         k) symbols_to_keep="${OPTARG}" ;;
+        K) symbols_to_keep_file="${OPTARG}" ;;
         -)
             case "${OPTARG}" in
                 add-gnu-debuglink) add_gnu_debuglink=true ;;
@@ -163,6 +168,11 @@ if [ ! -z "${symbols_to_keep}" -a ! -z "${keep_symbols}" ]; then
     usage
 fi
 
+if [ ! -z "${symbols_to_keep_file}" -a ! -z "${keep_symbols}" ]; then
+    echo "--keep-symbols and -K cannot be used together"
+    usage
+fi
+
 if [ ! -z "${add_gnu_debuglink}" -a ! -z "${keep_mini_debug_info}" ]; then
     echo "--add-gnu-debuglink cannot be used with --keep-mini-debug-info"
     usage
@@ -172,7 +182,7 @@ rm -f "${outfile}.tmp"
 
 if [ ! -z "${keep_symbols}" ]; then
     do_strip_keep_symbols
-elif [ ! -z "${symbols_to_keep}" ]; then
+elif [ ! -z "${symbols_to_keep}" -o ! -z "${symbols_to_keep_file}" ]; then
     do_strip_keep_symbol_list
 elif [ ! -z "${keep_mini_debug_info}" ]; then
     do_strip_keep_mini_debug_info
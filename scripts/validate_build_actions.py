@@ -0,0 +1,150 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2020 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""
+Validates the build actions declared by Soong after they have run.
+Reads the manifest written by Soong when SOONG_VALIDATE_BUILD_ACTIONS=true,
+which lists the declared outputs and depfile of every build action, and
+reports declared outputs that were not created, depfiles that list paths
+outside of the source tree, and files written next to the declared outputs that
+are not declared as an output of any build action.
+
+Only the directories that contain declared outputs are checked for undeclared
+files, without recursing into their subdirectories, and files older than the
+manifest are left over from earlier builds and ignored.
+"""
+
+from __future__ import print_function
+
+import argparse
+import fnmatch
+import json
+import os
+import sys
+
+# Files that tools are allowed to leave behind without declaring them.
+ALLOWED_UNDECLARED = [
+    '*.rsp',
+]
+
+# Scratch directories that rules fill with temporary files, like the classes and
+# annotation processor outputs of javac.
+SCRATCH_DIRS = [
+    'anno',
+    'classes',
+    'srcjars',
+]
+
+
+def get_args():
+  parser = argparse.ArgumentParser(description='Validate build actions.')
+  parser.add_argument('--manifest', required=True,
+                      help='build action validation manifest written by Soong.')
+  parser.add_argument('--stamp', required=True,
+                      help='file to write when validation succeeds.')
+  return parser.parse_args()
+
+
+def parse_depfile(depfile):
+  """Returns the paths listed in a Makefile style depfile."""
+  with open(depfile) as f:
+    contents = f.read().replace('\\\n', ' ')
+
+  paths = []
+  for line in contents.splitlines():
+    _, sep, deps = line.partition(': ')
+    if not sep:
+      continue
+    # Paths containing spaces are escaped with a backslash.
+    for path in deps.replace('\\ ', '\0').split():
+      paths.append(path.replace('\0', ' '))
+  return paths
+
+
+def in_source_tree(path, src_dir):
+  """Returns true if path is inside src_dir, which includes the out dir."""
+  path = os.path.normpath(os.path.join(src_dir, path))
+  return path == src_dir or path.startswith(src_dir + os.sep)
+
+
+def is_scratch_dir(path):
+  """Returns true if path is inside one of the scratch directories."""
+  return any(part in SCRATCH_DIRS for part in os.path.normpath(path).split(os.sep))
+
+
+def validate(manifest, src_dir, since):
+  """Returns the errors in the build actions listed in manifest.
+
+  Undeclared files are only reported if they were modified after since.
+  """
+  errors = []
+  declared = set()
+
+  for action in manifest['Actions']:
+    outputs = action.get('Outputs') or []
+    declared.update(os.path.normpath(o) for o in outputs)
+    for output in outputs:
+      if not os.path.lexists(output):
+        errors.append('declared output %s was not created' % output)
+
+    depfile = action.get('Depfile')
+    if not depfile:
+      continue
+    declared.add(os.path.normpath(depfile))
+    # Depfiles of rules with deps = gcc are deleted by ninja once it has read them.
+    if not os.path.exists(depfile):
+      continue
+    for path in parse_depfile(depfile):
+      if not in_source_tree(path, src_dir):
+        errors.append('depfile %s lists %s, which is outside of the source tree' %
+                      (depfile, path))
+
+  output_dirs = set(os.path.dirname(path) for path in declared)
+  for output_dir in sorted(output_dirs):
+    if is_scratch_dir(output_dir) or not os.path.isdir(output_dir):
+      continue
+    for name in os.listdir(output_dir):
+      path = os.path.normpath(os.path.join(output_dir, name))
+      if path in declared or not os.path.isfile(path):
+        continue
+      if any(fnmatch.fnmatch(name, pattern) for pattern in ALLOWED_UNDECLARED):
+        continue
+      if os.path.getmtime(path) < since:
+        continue
+      errors.append('%s was written but is not a declared output of any build action' % path)
+
+  return errors
+
+
+def main():
+  args = get_args()
+
+  with open(args.manifest) as f:
+    manifest = json.load(f)
+
+  errors = validate(manifest, os.path.realpath(os.getcwd()),
+                    os.path.getmtime(args.manifest))
+  if errors:
+    for error in sorted(errors):
+      print('error: %s' % error, file=sys.stderr)
+    sys.exit(1)
+
+  with open(args.stamp, 'w'):
+    pass
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,127 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2020 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for validate_build_actions.py."""
+
+import os
+import shutil
+import sys
+import tempfile
+import unittest
+
+import validate_build_actions
+
+sys.dont_write_bytecode = True
+
+
+class ParseDepfileTest(unittest.TestCase):
+  """Unit tests for parse_depfile function."""
+
+  def setUp(self):
+    self.tmp_dir = tempfile.mkdtemp()
+
+  def tearDown(self):
+    shutil.rmtree(self.tmp_dir)
+
+  def test_continuation_and_escapes(self):
+    depfile = os.path.join(self.tmp_dir, 'foo.d')
+    with open(depfile, 'w') as f:
+      f.write('out/foo.o: a.c \\\n  b.h dir\\ with\\ spaces/c.h\n')
+    self.assertEqual(validate_build_actions.parse_depfile(depfile),
+                     ['a.c', 'b.h', 'dir with spaces/c.h'])
+
+
+class ValidateTest(unittest.TestCase):
+  """Unit tests for validate function."""
+
+  def setUp(self):
+    self.tmp_dir = os.path.realpath(tempfile.mkdtemp())
+    self.old_cwd = os.getcwd()
+    os.chdir(self.tmp_dir)
+    os.makedirs(os.path.join('out', 'foo', 'classes'))
+
+  def tearDown(self):
+    os.chdir(self.old_cwd)
+    shutil.rmtree(self.tmp_dir)
+
+  def write(self, path, contents='', mtime=None):
+    with open(path, 'w') as f:
+      f.write(contents)
+    if mtime is not None:
+      os.utime(path, (mtime, mtime))
+
+  def validate(self, actions, since=0):
+    return validate_build_actions.validate({'Actions': actions}, self.tmp_dir, since)
+
+  def test_valid(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/foo.d', 'out/foo/foo.jar: a.java\n')
+    errors = self.validate([{
+        'Outputs': ['out/foo/foo.jar'],
+        'Depfile': 'out/foo/foo.d',
+    }])
+    self.assertEqual(errors, [])
+
+  def test_missing_output(self):
+    errors = self.validate([{'Outputs': ['out/foo/foo.jar']}])
+    self.assertEqual(errors, ['declared output out/foo/foo.jar was not created'])
+
+  def test_depfile_outside_source_tree(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/foo.d', 'out/foo/foo.jar: /usr/include/stdio.h\n')
+    errors = self.validate([{
+        'Outputs': ['out/foo/foo.jar'],
+        'Depfile': 'out/foo/foo.d',
+    }])
+    self.assertEqual(errors, [
+        'depfile out/foo/foo.d lists /usr/include/stdio.h, which is outside of the source tree',
+    ])
+
+  def test_undeclared_output(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/foo.jar.tmp')
+    errors = self.validate([{'Outputs': ['out/foo/foo.jar']}])
+    self.assertEqual(errors, [
+        'out/foo/foo.jar.tmp was written but is not a declared output of any build action',
+    ])
+
+  def test_allowed_undeclared_output(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/foo.jar.rsp')
+    errors = self.validate([{'Outputs': ['out/foo/foo.jar']}])
+    self.assertEqual(errors, [])
+
+  def test_scratch_dir(self):
+    self.write('out/foo/classes/foo.jar')
+    self.write('out/foo/classes/Foo.class')
+    errors = self.validate([{'Outputs': ['out/foo/classes/foo.jar']}])
+    self.assertEqual(errors, [])
+
+  def test_subdirectories_not_checked(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/classes/Foo.class')
+    errors = self.validate([{'Outputs': ['out/foo/foo.jar']}])
+    self.assertEqual(errors, [])
+
+  def test_stale_file(self):
+    self.write('out/foo/foo.jar')
+    self.write('out/foo/old.jar', mtime=100)
+    errors = self.validate([{'Outputs': ['out/foo/foo.jar']}], since=200)
+    self.assertEqual(errors, [])
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
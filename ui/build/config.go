@@ -615,6 +615,8 @@ func (c *configImpl) Tapas(ctx Context, apps []string, arch, variant string) {
 		product = "aosp_mips"
 	case "mips64":
 		product = "aosp_mips64"
+	case "riscv64":
+		product = "aosp_riscv64"
 	case "x86":
 		product = "aosp_x86"
 	case "x86_64":
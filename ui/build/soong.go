@@ -124,6 +124,17 @@ func runSoong(ctx Context, config Config) {
 		cmd.RunAndStreamOrFatal()
 	}
 
+	// soong_build only writes the analysis metrics when it runs, remove any left over from a
+	// previous build so they aren't recorded again.
+	analysisMetrics := filepath.Join(config.SoongOutDir(), "soong_analysis_metrics.pb")
+	os.Remove(analysisMetrics)
+
 	ninja("minibootstrap", ".minibootstrap/build.ninja")
 	ninja("bootstrap", ".bootstrap/build.ninja")
+
+	if ctx.Metrics != nil {
+		if err := ctx.Metrics.SetSoongAnalysisMetrics(analysisMetrics); err != nil {
+			ctx.Fatalln("Failed to read soong analysis metrics:", err)
+		}
+	}
 }
@@ -140,6 +140,23 @@ func (m *Metrics) SetBuildDateTime(date_time string) {
 	}
 }
 
+// SetSoongAnalysisMetrics reads the analysis metrics that soong_build wrote to the file at path,
+// if it exists.
+func (m *Metrics) SetSoongAnalysisMetrics(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	analysisMetrics := &soong_metrics_proto.SoongAnalysisMetrics{}
+	if err := proto.Unmarshal(data, analysisMetrics); err != nil {
+		return err
+	}
+	m.metrics.SoongAnalysisMetrics = analysisMetrics
+	return nil
+}
+
 // exports the output to the file at outputPath
 func (m *Metrics) Dump(outputPath string) (err error) {
 	return writeMessageToFile(&m.metrics, outputPath)
@@ -197,10 +197,13 @@ type MetricsBase struct {
 	// The metrics for calling Ninja.
 	NinjaRuns []*PerfInfo `protobuf:"bytes,20,rep,name=ninja_runs,json=ninjaRuns" json:"ninja_runs,omitempty"`
 	// The metrics for the whole build
-	Total                *PerfInfo `protobuf:"bytes,21,opt,name=total" json:"total,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	Total *PerfInfo `protobuf:"bytes,21,opt,name=total" json:"total,omitempty"`
+	// The metrics for the analysis of the modules by soong_build, only recorded if
+	// SOONG_ANALYSIS_METRICS=true.
+	SoongAnalysisMetrics *SoongAnalysisMetrics `protobuf:"bytes,22,opt,name=soong_analysis_metrics,json=soongAnalysisMetrics" json:"soong_analysis_metrics,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *MetricsBase) Reset()         { *m = MetricsBase{} }
@@ -380,6 +383,13 @@ func (m *MetricsBase) GetTotal() *PerfInfo {
 	return nil
 }
 
+func (m *MetricsBase) GetSoongAnalysisMetrics() *SoongAnalysisMetrics {
+	if m != nil {
+		return m.SoongAnalysisMetrics
+	}
+	return nil
+}
+
 type PerfInfo struct {
 	// The description for the phase/action/part while the tool running.
 	Desc *string `protobuf:"bytes,1,opt,name=desc" json:"desc,omitempty"`
@@ -607,6 +617,225 @@ func (m *CriticalUserJourneysMetrics) GetCujs() []*CriticalUserJourneyMetrics {
 	return nil
 }
 
+type SoongAnalysisMetrics struct {
+	// The time spent in each mutator by all modules, the slowest first.
+	Mutators []*MutatorAnalysisMetrics `protobuf:"bytes,1,rep,name=mutators" json:"mutators,omitempty"`
+	// The metrics for each module, aggregated over its variants, the slowest first.
+	Modules              []*ModuleAnalysisMetrics `protobuf:"bytes,2,rep,name=modules" json:"modules,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *SoongAnalysisMetrics) Reset()         { *m = SoongAnalysisMetrics{} }
+func (m *SoongAnalysisMetrics) String() string { return proto.CompactTextString(m) }
+func (*SoongAnalysisMetrics) ProtoMessage()    {}
+func (*SoongAnalysisMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{5}
+}
+
+func (m *SoongAnalysisMetrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SoongAnalysisMetrics.Unmarshal(m, b)
+}
+func (m *SoongAnalysisMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SoongAnalysisMetrics.Marshal(b, m, deterministic)
+}
+func (m *SoongAnalysisMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SoongAnalysisMetrics.Merge(m, src)
+}
+func (m *SoongAnalysisMetrics) XXX_Size() int {
+	return xxx_messageInfo_SoongAnalysisMetrics.Size(m)
+}
+func (m *SoongAnalysisMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_SoongAnalysisMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SoongAnalysisMetrics proto.InternalMessageInfo
+
+func (m *SoongAnalysisMetrics) GetMutators() []*MutatorAnalysisMetrics {
+	if m != nil {
+		return m.Mutators
+	}
+	return nil
+}
+
+func (m *SoongAnalysisMetrics) GetModules() []*ModuleAnalysisMetrics {
+	if m != nil {
+		return m.Modules
+	}
+	return nil
+}
+
+type MutatorAnalysisMetrics struct {
+	// The name of the mutator.
+	Name *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// The number of nanoseconds spent in the mutator.
+	Time                 *uint64  `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MutatorAnalysisMetrics) Reset()         { *m = MutatorAnalysisMetrics{} }
+func (m *MutatorAnalysisMetrics) String() string { return proto.CompactTextString(m) }
+func (*MutatorAnalysisMetrics) ProtoMessage()    {}
+func (*MutatorAnalysisMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{6}
+}
+
+func (m *MutatorAnalysisMetrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MutatorAnalysisMetrics.Unmarshal(m, b)
+}
+func (m *MutatorAnalysisMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MutatorAnalysisMetrics.Marshal(b, m, deterministic)
+}
+func (m *MutatorAnalysisMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MutatorAnalysisMetrics.Merge(m, src)
+}
+func (m *MutatorAnalysisMetrics) XXX_Size() int {
+	return xxx_messageInfo_MutatorAnalysisMetrics.Size(m)
+}
+func (m *MutatorAnalysisMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_MutatorAnalysisMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MutatorAnalysisMetrics proto.InternalMessageInfo
+
+func (m *MutatorAnalysisMetrics) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MutatorAnalysisMetrics) GetTime() uint64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+type ModuleAnalysisMetrics struct {
+	// The name of the module.
+	Name *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// The directory of the Android.bp file that defines the module.
+	Dir *string `protobuf:"bytes,2,opt,name=dir" json:"dir,omitempty"`
+	// The module type, eg. java_library, cc_binary, and etc.
+	ModuleType *string `protobuf:"bytes,3,opt,name=module_type,json=moduleType" json:"module_type,omitempty"`
+	// The number of variants of the module.
+	Variants *uint32 `protobuf:"varint,4,opt,name=variants" json:"variants,omitempty"`
+	// The number of nanoseconds spent in the mutators and GenerateAndroidBuildActions.
+	TotalTime *uint64 `protobuf:"varint,5,opt,name=total_time,json=totalTime" json:"total_time,omitempty"`
+	// The number of nanoseconds spent in the mutators.
+	MutatorTime *uint64 `protobuf:"varint,6,opt,name=mutator_time,json=mutatorTime" json:"mutator_time,omitempty"`
+	// The number of nanoseconds spent in each mutator, the slowest first.
+	MutatorTimes []*MutatorAnalysisMetrics `protobuf:"bytes,7,rep,name=mutator_times,json=mutatorTimes" json:"mutator_times,omitempty"`
+	// The number of nanoseconds spent in GenerateAndroidBuildActions.
+	GenerateBuildActionsTime *uint64 `protobuf:"varint,8,opt,name=generate_build_actions_time,json=generateBuildActionsTime" json:"generate_build_actions_time,omitempty"`
+	// The number of rules created by the module.
+	Rules *uint32 `protobuf:"varint,9,opt,name=rules" json:"rules,omitempty"`
+	// The number of build actions created by the module.
+	BuildActions         *uint32  `protobuf:"varint,10,opt,name=build_actions,json=buildActions" json:"build_actions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModuleAnalysisMetrics) Reset()         { *m = ModuleAnalysisMetrics{} }
+func (m *ModuleAnalysisMetrics) String() string { return proto.CompactTextString(m) }
+func (*ModuleAnalysisMetrics) ProtoMessage()    {}
+func (*ModuleAnalysisMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{7}
+}
+
+func (m *ModuleAnalysisMetrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModuleAnalysisMetrics.Unmarshal(m, b)
+}
+func (m *ModuleAnalysisMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModuleAnalysisMetrics.Marshal(b, m, deterministic)
+}
+func (m *ModuleAnalysisMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModuleAnalysisMetrics.Merge(m, src)
+}
+func (m *ModuleAnalysisMetrics) XXX_Size() int {
+	return xxx_messageInfo_ModuleAnalysisMetrics.Size(m)
+}
+func (m *ModuleAnalysisMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModuleAnalysisMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModuleAnalysisMetrics proto.InternalMessageInfo
+
+func (m *ModuleAnalysisMetrics) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *ModuleAnalysisMetrics) GetDir() string {
+	if m != nil && m.Dir != nil {
+		return *m.Dir
+	}
+	return ""
+}
+
+func (m *ModuleAnalysisMetrics) GetModuleType() string {
+	if m != nil && m.ModuleType != nil {
+		return *m.ModuleType
+	}
+	return ""
+}
+
+func (m *ModuleAnalysisMetrics) GetVariants() uint32 {
+	if m != nil && m.Variants != nil {
+		return *m.Variants
+	}
+	return 0
+}
+
+func (m *ModuleAnalysisMetrics) GetTotalTime() uint64 {
+	if m != nil && m.TotalTime != nil {
+		return *m.TotalTime
+	}
+	return 0
+}
+
+func (m *ModuleAnalysisMetrics) GetMutatorTime() uint64 {
+	if m != nil && m.MutatorTime != nil {
+		return *m.MutatorTime
+	}
+	return 0
+}
+
+func (m *ModuleAnalysisMetrics) GetMutatorTimes() []*MutatorAnalysisMetrics {
+	if m != nil {
+		return m.MutatorTimes
+	}
+	return nil
+}
+
+func (m *ModuleAnalysisMetrics) GetGenerateBuildActionsTime() uint64 {
+	if m != nil && m.GenerateBuildActionsTime != nil {
+		return *m.GenerateBuildActionsTime
+	}
+	return 0
+}
+
+func (m *ModuleAnalysisMetrics) GetRules() uint32 {
+	if m != nil && m.Rules != nil {
+		return *m.Rules
+	}
+	return 0
+}
+
+func (m *ModuleAnalysisMetrics) GetBuildActions() uint32 {
+	if m != nil && m.BuildActions != nil {
+		return *m.BuildActions
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_BuildVariant", MetricsBase_BuildVariant_name, MetricsBase_BuildVariant_value)
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_Arch", MetricsBase_Arch_name, MetricsBase_Arch_value)
@@ -616,63 +845,80 @@ func init() {
 	proto.RegisterType((*ModuleTypeInfo)(nil), "soong_build_metrics.ModuleTypeInfo")
 	proto.RegisterType((*CriticalUserJourneyMetrics)(nil), "soong_build_metrics.CriticalUserJourneyMetrics")
 	proto.RegisterType((*CriticalUserJourneysMetrics)(nil), "soong_build_metrics.CriticalUserJourneysMetrics")
+	proto.RegisterType((*SoongAnalysisMetrics)(nil), "soong_build_metrics.SoongAnalysisMetrics")
+	proto.RegisterType((*MutatorAnalysisMetrics)(nil), "soong_build_metrics.MutatorAnalysisMetrics")
+	proto.RegisterType((*ModuleAnalysisMetrics)(nil), "soong_build_metrics.ModuleAnalysisMetrics")
 }
 
 func init() { proto.RegisterFile("metrics.proto", fileDescriptor_6039342a2ba47b72) }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 847 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0xdd, 0x6e, 0xdb, 0x36,
-	0x14, 0xae, 0x12, 0x25, 0xb6, 0x8e, 0x62, 0x57, 0x65, 0x52, 0x54, 0x5d, 0x11, 0xcc, 0x10, 0xd6,
-	0x21, 0x17, 0xab, 0x5b, 0x78, 0x45, 0x50, 0x18, 0xc5, 0x80, 0xc4, 0x31, 0x8a, 0x2e, 0xb0, 0x5d,
-	0x28, 0x71, 0x57, 0x6c, 0x17, 0x02, 0x23, 0xd1, 0x8d, 0x3a, 0x4b, 0x14, 0x48, 0xaa, 0x98, 0x1f,
-	0x62, 0x0f, 0xb9, 0x8b, 0xbd, 0xc7, 0xc0, 0x43, 0xc9, 0x51, 0x00, 0x0f, 0x09, 0x7a, 0x47, 0x9d,
-	0xef, 0x87, 0xdf, 0xa1, 0xc4, 0x23, 0xe8, 0x64, 0x4c, 0x89, 0x34, 0x96, 0xfd, 0x42, 0x70, 0xc5,
-	0xc9, 0xbe, 0xe4, 0x3c, 0xff, 0x1c, 0x5d, 0x95, 0xe9, 0x32, 0x89, 0x2a, 0x28, 0xf8, 0xc7, 0x01,
-	0x77, 0x62, 0xd6, 0xa7, 0x54, 0x32, 0xf2, 0x0a, 0x0e, 0x0c, 0x21, 0xa1, 0x8a, 0x45, 0x2a, 0xcd,
-	0x98, 0x54, 0x34, 0x2b, 0x7c, 0xab, 0x67, 0x1d, 0x6d, 0x87, 0x04, 0xb1, 0x33, 0xaa, 0xd8, 0x65,
-	0x8d, 0x90, 0xa7, 0xd0, 0x36, 0x8a, 0x34, 0xf1, 0xb7, 0x7a, 0xd6, 0x91, 0x13, 0xb6, 0xf0, 0xf9,
-	0x7d, 0x42, 0x86, 0xf0, 0xb4, 0x58, 0x52, 0xb5, 0xe0, 0x22, 0x8b, 0xbe, 0x32, 0x21, 0x53, 0x9e,
-	0x47, 0x31, 0x4f, 0x58, 0x4e, 0x33, 0xe6, 0x6f, 0x23, 0xf7, 0x49, 0x4d, 0xf8, 0x68, 0xf0, 0x51,
-	0x05, 0x93, 0xe7, 0xd0, 0x55, 0x54, 0x7c, 0x66, 0x2a, 0x2a, 0x04, 0x4f, 0xca, 0x58, 0xf9, 0x36,
-	0x0a, 0x3a, 0xa6, 0xfa, 0xc1, 0x14, 0x49, 0x02, 0x07, 0x15, 0xcd, 0x84, 0xf8, 0x4a, 0x45, 0x4a,
-	0x73, 0xe5, 0xef, 0xf4, 0xac, 0xa3, 0xee, 0xe0, 0x45, 0x7f, 0x43, 0xcf, 0xfd, 0x46, 0xbf, 0xfd,
-	0x53, 0x8d, 0x7c, 0x34, 0xa2, 0xe1, 0xf6, 0x78, 0xfa, 0x2e, 0x24, 0xc6, 0xaf, 0x09, 0x90, 0x19,
-	0xb8, 0xd5, 0x2e, 0x54, 0xc4, 0xd7, 0xfe, 0x2e, 0x9a, 0x3f, 0xbf, 0xd3, 0xfc, 0x44, 0xc4, 0xd7,
-	0xc3, 0xd6, 0x7c, 0x7a, 0x3e, 0x9d, 0xfd, 0x36, 0x0d, 0xc1, 0x58, 0xe8, 0x22, 0xe9, 0xc3, 0x7e,
-	0xc3, 0x70, 0x9d, 0xba, 0x85, 0x2d, 0x3e, 0xba, 0x21, 0xd6, 0x01, 0x7e, 0x82, 0x2a, 0x56, 0x14,
-	0x17, 0xe5, 0x9a, 0xde, 0x46, 0xba, 0x67, 0x90, 0x51, 0x51, 0xd6, 0xec, 0x73, 0x70, 0xae, 0xb9,
-	0xac, 0xc2, 0x3a, 0xdf, 0x14, 0xb6, 0xad, 0x0d, 0x30, 0x6a, 0x08, 0x1d, 0x34, 0x1b, 0xe4, 0x89,
-	0x31, 0x84, 0x6f, 0x32, 0x74, 0xb5, 0xc9, 0x20, 0x4f, 0xd0, 0xf3, 0x09, 0xb4, 0xd0, 0x93, 0x4b,
-	0xdf, 0xc5, 0x1e, 0x76, 0xf5, 0xe3, 0x4c, 0x92, 0xa0, 0xda, 0x8c, 0xcb, 0x88, 0xfd, 0xa5, 0x04,
-	0xf5, 0xf7, 0x10, 0x76, 0x0d, 0x3c, 0xd6, 0xa5, 0x35, 0x27, 0x16, 0x5c, 0x4a, 0x6d, 0xd1, 0xb9,
-	0xe1, 0x8c, 0x74, 0x6d, 0x26, 0xc9, 0x8f, 0xf0, 0xb0, 0xc1, 0xc1, 0xd8, 0x5d, 0xf3, 0xf9, 0xac,
-	0x59, 0x18, 0xe4, 0x05, 0xec, 0x37, 0x78, 0xeb, 0x16, 0x1f, 0x9a, 0x83, 0x5d, 0x73, 0x1b, 0xb9,
-	0x79, 0xa9, 0xa2, 0x24, 0x15, 0xbe, 0x67, 0x72, 0xf3, 0x52, 0x9d, 0xa5, 0x82, 0xfc, 0x02, 0xae,
-	0x64, 0xaa, 0x2c, 0x22, 0xc5, 0xf9, 0x52, 0xfa, 0x8f, 0x7a, 0xdb, 0x47, 0xee, 0xe0, 0x70, 0xe3,
-	0x11, 0x7d, 0x60, 0x62, 0xf1, 0x3e, 0x5f, 0xf0, 0x10, 0x50, 0x71, 0xa9, 0x05, 0x64, 0x08, 0xce,
-	0x9f, 0x54, 0xa5, 0x91, 0x28, 0x73, 0xe9, 0x93, 0xfb, 0xa8, 0xdb, 0x9a, 0x1f, 0x96, 0xb9, 0x24,
-	0x6f, 0x01, 0x0c, 0x13, 0xc5, 0xfb, 0xf7, 0x11, 0x3b, 0x88, 0xd6, 0xea, 0x3c, 0xcd, 0xbf, 0x50,
-	0xa3, 0x3e, 0xb8, 0x97, 0x1a, 0x05, 0xa8, 0xfe, 0x19, 0x76, 0x14, 0x57, 0x74, 0xe9, 0x3f, 0xee,
-	0x59, 0x77, 0x0b, 0x0d, 0x37, 0x78, 0x05, 0x7b, 0xb7, 0x6e, 0x57, 0x1b, 0xec, 0xf9, 0xc5, 0x38,
-	0xf4, 0x1e, 0x90, 0x0e, 0x38, 0x7a, 0x75, 0x36, 0x3e, 0x9d, 0xbf, 0xf3, 0x2c, 0xd2, 0x02, 0x7d,
-	0x23, 0xbd, 0xad, 0xe0, 0x2d, 0xd8, 0x78, 0xfe, 0x2e, 0xd4, 0xdf, 0x93, 0xf7, 0x40, 0xa3, 0x27,
-	0xe1, 0xc4, 0xb3, 0x88, 0x03, 0x3b, 0x27, 0xe1, 0xe4, 0xf8, 0xb5, 0xb7, 0xa5, 0x6b, 0x9f, 0xde,
-	0x1c, 0x7b, 0xdb, 0x04, 0x60, 0xf7, 0xd3, 0x9b, 0xe3, 0xe8, 0xf8, 0xb5, 0x67, 0x07, 0x7f, 0x5b,
-	0xd0, 0xae, 0x33, 0x10, 0x02, 0x76, 0xc2, 0x64, 0x8c, 0x03, 0xcd, 0x09, 0x71, 0xad, 0x6b, 0x38,
-	0x92, 0xcc, 0xf8, 0xc2, 0x35, 0x39, 0x04, 0x90, 0x8a, 0x0a, 0x85, 0x33, 0x10, 0x87, 0x95, 0x1d,
-	0x3a, 0x58, 0xd1, 0xa3, 0x8f, 0x3c, 0x03, 0x47, 0x30, 0xba, 0x34, 0xa8, 0x8d, 0x68, 0x5b, 0x17,
-	0x10, 0x3c, 0x04, 0xc8, 0x58, 0xc6, 0xc5, 0x2a, 0x2a, 0x25, 0xc3, 0x51, 0x64, 0x87, 0x8e, 0xa9,
-	0xcc, 0x25, 0x0b, 0xfe, 0xb5, 0xa0, 0x3b, 0xe1, 0x49, 0xb9, 0x64, 0x97, 0xab, 0x82, 0x61, 0xaa,
-	0x3f, 0x60, 0xcf, 0x9c, 0x99, 0x5c, 0x49, 0xc5, 0x32, 0x4c, 0xd7, 0x1d, 0xbc, 0xdc, 0x7c, 0xc7,
-	0x6e, 0x49, 0xcd, 0x04, 0xbb, 0x40, 0x59, 0xe3, 0xb6, 0x5d, 0xdd, 0x54, 0xc9, 0xf7, 0xe0, 0x66,
-	0xa8, 0x89, 0xd4, 0xaa, 0xa8, 0xbb, 0x84, 0x6c, 0x6d, 0x43, 0x7e, 0x80, 0x6e, 0x5e, 0x66, 0x11,
-	0x5f, 0x44, 0xa6, 0x28, 0xb1, 0xdf, 0x4e, 0xb8, 0x97, 0x97, 0xd9, 0x6c, 0x61, 0xf6, 0x93, 0xc1,
-	0x4b, 0x70, 0x1b, 0x7b, 0xdd, 0x7e, 0x17, 0x0e, 0xec, 0x5c, 0xcc, 0x66, 0x53, 0xfd, 0xd2, 0xda,
-	0x60, 0x4f, 0x4e, 0xce, 0xc7, 0xde, 0x56, 0xb0, 0x84, 0xef, 0x46, 0x22, 0x55, 0x69, 0x4c, 0x97,
-	0x73, 0xc9, 0xc4, 0xaf, 0xbc, 0x14, 0x39, 0x5b, 0x55, 0x23, 0x62, 0x7d, 0xe8, 0x56, 0xe3, 0xd0,
-	0x87, 0xd0, 0xaa, 0xba, 0xc4, 0x94, 0xee, 0xa0, 0x77, 0xd7, 0x94, 0x09, 0x6b, 0x41, 0x70, 0x05,
-	0xcf, 0x36, 0xec, 0x26, 0xeb, 0xed, 0x46, 0x60, 0xc7, 0xe5, 0x17, 0xe9, 0x5b, 0xf8, 0x85, 0x6f,
-	0x3e, 0xd9, 0xff, 0x4f, 0x1b, 0xa2, 0xf8, 0xf4, 0xf1, 0xef, 0xd5, 0x4f, 0xb4, 0x52, 0x44, 0xf8,
-	0x67, 0xfd, 0x2f, 0x00, 0x00, 0xff, 0xff, 0xc4, 0xbd, 0xe2, 0xb1, 0x69, 0x07, 0x00, 0x00,
+	// 1066 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xed, 0x4e, 0xdc, 0x46,
+	0x14, 0x8d, 0x77, 0x17, 0xd6, 0xbe, 0xde, 0xdd, 0x38, 0xc3, 0x42, 0x9c, 0x20, 0xd4, 0xad, 0xdb,
+	0x54, 0xf4, 0x23, 0x24, 0xa2, 0x11, 0x8a, 0x50, 0x5a, 0x75, 0xf9, 0x10, 0x4a, 0x11, 0x2c, 0x32,
+	0x90, 0x46, 0xed, 0x0f, 0xcb, 0xd8, 0x03, 0x38, 0x5d, 0x7b, 0x56, 0x33, 0xe3, 0xa8, 0xfb, 0x06,
+	0xfd, 0xd3, 0xc7, 0xe8, 0x8b, 0xf4, 0x5d, 0xfa, 0x1e, 0xd5, 0xdc, 0xb1, 0x17, 0x43, 0xdd, 0x42,
+	0xf2, 0x6f, 0x7c, 0xef, 0x39, 0x67, 0xce, 0xcc, 0xdc, 0x7b, 0x77, 0xa1, 0x9b, 0x52, 0xc9, 0x93,
+	0x48, 0xac, 0x4d, 0x38, 0x93, 0x8c, 0x2c, 0x08, 0xc6, 0xb2, 0x8b, 0xe0, 0x2c, 0x4f, 0xc6, 0x71,
+	0x50, 0xa4, 0xbc, 0xbf, 0x00, 0xec, 0x03, 0xbd, 0xde, 0x0a, 0x05, 0x25, 0xcf, 0xa1, 0xaf, 0x01,
+	0x71, 0x28, 0x69, 0x20, 0x93, 0x94, 0x0a, 0x19, 0xa6, 0x13, 0xd7, 0x18, 0x18, 0xab, 0x4d, 0x9f,
+	0x60, 0x6e, 0x27, 0x94, 0xf4, 0xa4, 0xcc, 0x90, 0x47, 0x60, 0x6a, 0x46, 0x12, 0xbb, 0x8d, 0x81,
+	0xb1, 0x6a, 0xf9, 0x6d, 0xfc, 0x7e, 0x1d, 0x93, 0x4d, 0x78, 0x34, 0x19, 0x87, 0xf2, 0x9c, 0xf1,
+	0x34, 0x78, 0x4f, 0xb9, 0x48, 0x58, 0x16, 0x44, 0x2c, 0xa6, 0x59, 0x98, 0x52, 0xb7, 0x89, 0xd8,
+	0x87, 0x25, 0xe0, 0x8d, 0xce, 0x6f, 0x17, 0x69, 0xf2, 0x04, 0x7a, 0x32, 0xe4, 0x17, 0x54, 0x06,
+	0x13, 0xce, 0xe2, 0x3c, 0x92, 0x6e, 0x0b, 0x09, 0x5d, 0x1d, 0x3d, 0xd2, 0x41, 0x12, 0x43, 0xbf,
+	0x80, 0x69, 0x13, 0xef, 0x43, 0x9e, 0x84, 0x99, 0x74, 0xe7, 0x06, 0xc6, 0x6a, 0x6f, 0xfd, 0xe9,
+	0x5a, 0xcd, 0x99, 0xd7, 0x2a, 0xe7, 0x5d, 0xdb, 0x52, 0x99, 0x37, 0x9a, 0xb4, 0xd9, 0xdc, 0x3d,
+	0xdc, 0xf3, 0x89, 0xd6, 0xab, 0x26, 0xc8, 0x08, 0xec, 0x62, 0x97, 0x90, 0x47, 0x97, 0xee, 0x3c,
+	0x8a, 0x3f, 0xb9, 0x55, 0x7c, 0xc8, 0xa3, 0xcb, 0xcd, 0xf6, 0xe9, 0xe1, 0xfe, 0xe1, 0xe8, 0xa7,
+	0x43, 0x1f, 0xb4, 0x84, 0x0a, 0x92, 0x35, 0x58, 0xa8, 0x08, 0xce, 0x5c, 0xb7, 0xf1, 0x88, 0x0f,
+	0xae, 0x80, 0xa5, 0x81, 0x6f, 0xa0, 0xb0, 0x15, 0x44, 0x93, 0x7c, 0x06, 0x37, 0x11, 0xee, 0xe8,
+	0xcc, 0xf6, 0x24, 0x2f, 0xd1, 0xfb, 0x60, 0x5d, 0x32, 0x51, 0x98, 0xb5, 0x3e, 0xca, 0xac, 0xa9,
+	0x04, 0xd0, 0xaa, 0x0f, 0x5d, 0x14, 0x5b, 0xcf, 0x62, 0x2d, 0x08, 0x1f, 0x25, 0x68, 0x2b, 0x91,
+	0xf5, 0x2c, 0x46, 0xcd, 0x87, 0xd0, 0x46, 0x4d, 0x26, 0x5c, 0x1b, 0xcf, 0x30, 0xaf, 0x3e, 0x47,
+	0x82, 0x78, 0xc5, 0x66, 0x4c, 0x04, 0xf4, 0x37, 0xc9, 0x43, 0xb7, 0x83, 0x69, 0x5b, 0xa7, 0x77,
+	0x55, 0x68, 0x86, 0x89, 0x38, 0x13, 0x42, 0x49, 0x74, 0xaf, 0x30, 0xdb, 0x2a, 0x36, 0x12, 0xe4,
+	0x0b, 0xb8, 0x5f, 0xc1, 0xa0, 0xed, 0x9e, 0x2e, 0x9f, 0x19, 0x0a, 0x8d, 0x3c, 0x85, 0x85, 0x0a,
+	0x6e, 0x76, 0xc4, 0xfb, 0xfa, 0x62, 0x67, 0xd8, 0x8a, 0x6f, 0x96, 0xcb, 0x20, 0x4e, 0xb8, 0xeb,
+	0x68, 0xdf, 0x2c, 0x97, 0x3b, 0x09, 0x27, 0xdf, 0x83, 0x2d, 0xa8, 0xcc, 0x27, 0x81, 0x64, 0x6c,
+	0x2c, 0xdc, 0x07, 0x83, 0xe6, 0xaa, 0xbd, 0xbe, 0x52, 0x7b, 0x45, 0x47, 0x94, 0x9f, 0xbf, 0xce,
+	0xce, 0x99, 0x0f, 0xc8, 0x38, 0x51, 0x04, 0xb2, 0x09, 0xd6, 0xaf, 0xa1, 0x4c, 0x02, 0x9e, 0x67,
+	0xc2, 0x25, 0x77, 0x61, 0x9b, 0x0a, 0xef, 0xe7, 0x99, 0x20, 0xaf, 0x00, 0x34, 0x12, 0xc9, 0x0b,
+	0x77, 0x21, 0x5b, 0x98, 0x2d, 0xd9, 0x59, 0x92, 0xbd, 0x0b, 0x35, 0xbb, 0x7f, 0x27, 0x36, 0x12,
+	0x90, 0xfd, 0x2d, 0xcc, 0x49, 0x26, 0xc3, 0xb1, 0xbb, 0x38, 0x30, 0x6e, 0x27, 0x6a, 0x2c, 0x09,
+	0x60, 0x49, 0xc3, 0xc2, 0x2c, 0x1c, 0x4f, 0x45, 0x22, 0x4a, 0xa4, 0xbb, 0x84, 0x2a, 0x5f, 0xd6,
+	0xaa, 0x1c, 0xab, 0xd8, 0xb0, 0x60, 0x14, 0x75, 0xe6, 0xf7, 0x45, 0x4d, 0xd4, 0x7b, 0x0e, 0x9d,
+	0x6b, 0xed, 0x6b, 0x42, 0xeb, 0xf4, 0x78, 0xd7, 0x77, 0xee, 0x91, 0x2e, 0x58, 0x6a, 0xb5, 0xb3,
+	0xbb, 0x75, 0xba, 0xe7, 0x18, 0xa4, 0x0d, 0xaa, 0xe5, 0x9d, 0x86, 0xf7, 0x0a, 0x5a, 0xf8, 0xc0,
+	0x36, 0x94, 0x05, 0xeb, 0xdc, 0x53, 0xd9, 0xa1, 0x7f, 0xe0, 0x18, 0xc4, 0x82, 0xb9, 0xa1, 0x7f,
+	0xb0, 0xf1, 0xc2, 0x69, 0xa8, 0xd8, 0xdb, 0x97, 0x1b, 0x4e, 0x93, 0x00, 0xcc, 0xbf, 0x7d, 0xb9,
+	0x11, 0x6c, 0xbc, 0x70, 0x5a, 0xde, 0x1f, 0x06, 0x98, 0xe5, 0x21, 0x09, 0x81, 0x56, 0x4c, 0x45,
+	0x84, 0x13, 0xd3, 0xf2, 0x71, 0xad, 0x62, 0x38, 0xf3, 0xf4, 0x7c, 0xc4, 0x35, 0x59, 0x01, 0x10,
+	0x32, 0xe4, 0x12, 0x87, 0x2c, 0x4e, 0xc3, 0x96, 0x6f, 0x61, 0x44, 0xcd, 0x56, 0xb2, 0x0c, 0x16,
+	0xa7, 0xe1, 0x58, 0x67, 0x5b, 0x98, 0x35, 0x55, 0x00, 0x93, 0x2b, 0x00, 0x29, 0x4d, 0x19, 0x9f,
+	0x06, 0xb9, 0xa0, 0x38, 0xeb, 0x5a, 0xbe, 0xa5, 0x23, 0xa7, 0x82, 0x7a, 0x7f, 0x1b, 0xd0, 0x3b,
+	0x60, 0x71, 0x3e, 0xa6, 0x27, 0xd3, 0x09, 0x45, 0x57, 0xbf, 0x40, 0x47, 0x5f, 0xa7, 0x98, 0x0a,
+	0x49, 0x53, 0x74, 0xd7, 0x5b, 0x7f, 0x56, 0xdf, 0xc4, 0xd7, 0xa8, 0x7a, 0x44, 0x1e, 0x23, 0xad,
+	0xd2, 0xce, 0x67, 0x57, 0x51, 0xf2, 0x09, 0xd8, 0x29, 0x72, 0x02, 0x39, 0x9d, 0x94, 0xa7, 0x84,
+	0x74, 0x26, 0x43, 0x3e, 0x87, 0x5e, 0x96, 0xa7, 0x01, 0x3b, 0x0f, 0x74, 0x50, 0xe0, 0x79, 0xbb,
+	0x7e, 0x27, 0xcb, 0xd3, 0xd1, 0xb9, 0xde, 0x4f, 0x78, 0xcf, 0xc0, 0xae, 0xec, 0x75, 0xfd, 0x2d,
+	0x2c, 0x98, 0x3b, 0x1e, 0x8d, 0x0e, 0xd5, 0xa3, 0x99, 0xd0, 0x3a, 0x18, 0xee, 0xef, 0x3a, 0x0d,
+	0x6f, 0x0c, 0x8f, 0xb7, 0x79, 0x22, 0x93, 0x28, 0x1c, 0x9f, 0x0a, 0xca, 0x7f, 0x64, 0x39, 0xcf,
+	0xe8, 0xb4, 0xa8, 0x82, 0xd9, 0xa5, 0x1b, 0x95, 0x4b, 0xdf, 0x84, 0x76, 0x59, 0x6b, 0x0d, 0xac,
+	0xb5, 0xc1, 0x6d, 0x63, 0xcc, 0x2f, 0x09, 0xde, 0x19, 0x2c, 0xd7, 0xec, 0x56, 0x16, 0x1d, 0xd9,
+	0x86, 0x56, 0x94, 0xbf, 0x13, 0xae, 0x81, 0x2d, 0x54, 0x7f, 0xb3, 0xff, 0xed, 0xd6, 0x47, 0xb2,
+	0xf7, 0xa7, 0x01, 0xfd, 0xba, 0x42, 0x27, 0x7b, 0x60, 0xa6, 0xb9, 0x0c, 0x25, 0xe3, 0xe5, 0x0e,
+	0x5f, 0xd7, 0x3b, 0xd7, 0xa0, 0x9b, 0x7d, 0x32, 0x23, 0x93, 0x1d, 0x68, 0x97, 0x6f, 0xd0, 0x40,
+	0x9d, 0xaf, 0xfe, 0xa7, 0x06, 0x6e, 0xca, 0x94, 0x54, 0xef, 0x07, 0x58, 0xaa, 0xdf, 0xa9, 0xf6,
+	0xd6, 0x09, 0xb4, 0xb0, 0x8c, 0x1b, 0x58, 0xa8, 0xb8, 0xf6, 0x7e, 0x6f, 0xc2, 0x62, 0xed, 0x26,
+	0xb5, 0x0a, 0x0e, 0x34, 0xd5, 0xd0, 0xd5, 0x95, 0xa5, 0x96, 0x37, 0x6b, 0xae, 0xf9, 0xaf, 0x9a,
+	0x7b, 0x0c, 0x66, 0xf1, 0x3b, 0x29, 0xb0, 0x7f, 0xba, 0xfe, 0xec, 0x5b, 0xf5, 0x0f, 0x8e, 0x22,
+	0xdd, 0x5d, 0x45, 0xff, 0x60, 0x04, 0xdb, 0xeb, 0x53, 0xe8, 0x14, 0xf7, 0xa5, 0x01, 0xf3, 0x08,
+	0xb0, 0x8b, 0x18, 0x42, 0x8e, 0xa0, 0x5b, 0x85, 0x08, 0xb7, 0xfd, 0xe1, 0x8f, 0xd2, 0xa9, 0x08,
+	0x0a, 0xf2, 0x1d, 0x2c, 0x5f, 0xd0, 0x8c, 0x72, 0xf5, 0xbf, 0x4b, 0xd3, 0xc3, 0x48, 0x26, 0x2c,
+	0x13, 0xda, 0x83, 0x89, 0x1e, 0xdc, 0x12, 0x82, 0x8d, 0x32, 0xd4, 0x00, 0x34, 0xd4, 0x87, 0x39,
+	0x8e, 0xaf, 0x6a, 0xe1, 0x59, 0xf5, 0x07, 0xf9, 0x0c, 0xba, 0xd7, 0xb4, 0xf0, 0xc7, 0xbb, 0xeb,
+	0x77, 0xce, 0x2a, 0xf4, 0xad, 0xc5, 0x9f, 0x8b, 0xbf, 0x86, 0x85, 0xdf, 0x00, 0xff, 0x2f, 0xfe,
+	0x33, 0x00, 0x6b, 0x69, 0x1a, 0x4a, 0x3f, 0x0a, 0x00, 0x00,
 }